@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/anonymize"
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+)
+
+// Anonymizace bývalého člena (GDPR, právo být zapomenut)
+//
+// Smaže jméno, přezdívku, telefon a alternativní kontakt, email nahradí zástupným
+// a platbám člena smaže protiúčet a surová bankovní data; stejně jako tlačítko
+// v admin profilu člena. Částky a data plateb, příspěvků a faktur zůstanou. Jen pro
+// členy ve stavu exmember / rejected. Bez --confirm jen vypíše, co by se smazalo;
+// pro provedení je potřeba zopakovat email člena.
+//
+// Použití:
+//   # Náhled
+//   go run cmd/cron/anonymize_member.go --user 42
+//
+//   go run cmd/cron/anonymize_member.go --user 42 --confirm jan@example.com
+
+func main() {
+	userFlag := flag.String("user", "", "ID or email of the former member")
+	confirm := flag.String("confirm", "", "email of the member, required to anonymize (without it only a preview is shown)")
+	flag.Parse()
+
+	if *userFlag == "" {
+		log.Fatal("--user is required")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+	ctx := context.Background()
+
+	var user db.User
+	if id, err := strconv.ParseInt(*userFlag, 10, 64); err == nil {
+		user, err = queries.GetUserByID(ctx, id)
+		if err != nil {
+			log.Fatalf("Failed to get user %d: %v", id, err)
+		}
+	} else {
+		user, err = queries.GetUserByEmail(ctx, *userFlag)
+		if err != nil {
+			log.Fatalf("Failed to get user %s: %v", *userFlag, err)
+		}
+	}
+
+	dryRun := *confirm == ""
+	if !dryRun && !strings.EqualFold(strings.TrimSpace(*confirm), user.Email) {
+		log.Fatalf("--confirm does not match the email of user #%d", user.ID)
+	}
+
+	summary, err := anonymize.Member(ctx, database, queries, user.ID, "cli", dryRun)
+	if err != nil {
+		log.Fatalf("Failed to anonymize user #%d: %v", user.ID, err)
+	}
+
+	log.Printf("User #%d (%s, %s):", user.ID, user.Email, user.State)
+	log.Printf("  Payments without bank data: %d", summary.Payments)
+	log.Printf("  Expenses without bank data: %d", summary.Expenses)
+	log.Printf("  Reimbursements without account: %d", summary.Reimbursements)
+	log.Printf("  Applications: %d", summary.Applications)
+	log.Printf("  Tickets: %d (messages: %d)", summary.Tickets, summary.TicketMessages)
+	log.Printf("  Campaign recipients: %d", summary.CampaignRecipients)
+	log.Printf("  Log entries: %d (audit log: %d)", summary.Logs, summary.AuditLogs)
+	log.Printf("  Deleted payment rules: %d, wall entries: %d, API tokens: %d, sessions: %d, billing details: %d, admin notes: %d",
+		summary.MatchRules, summary.WallEntries, summary.APITokens, summary.Sessions, summary.BillingDetails, summary.Notes)
+
+	if dryRun {
+		log.Printf("✓ Preview only - run again with --confirm %s to anonymize", user.Email)
+		return
+	}
+
+	encoded, _ := json.Marshal(summary)
+	queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "warning",
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Former member #%d anonymized from the command line", user.ID),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"target_user_id":%d,"summary":%s}`, user.ID, encoded), Valid: true},
+	})
+
+	log.Printf("✓ User #%d anonymized as %s", user.ID, anonymize.Email(user.ID))
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/email"
+	"github.com/base48/member-portal/internal/matrix"
+	"github.com/base48/member-portal/internal/milestone"
+	"github.com/base48/member-portal/internal/qrpay"
+)
+
+// Gratulace členům k výročí členství a ke 100. platbě
+//
+// Použití:
+//   go run cmd/cron/celebrate_milestones.go
+//   go run cmd/cron/celebrate_milestones.go --dry-run
+//
+// Nebo v crontab (denně):
+//   0 10 * * * cd /path/to/portal && ./celebrate_milestones >> logs/milestones.log 2>&1
+//
+// Přijatým členům pošle gratulační email a s nastaveným MATRIX_* i zmínku do komunitní
+// Matrix místnosti. Obojí si člen může vypnout v profilu (sekce Upozornění). Vynechané
+// dny se dohání v rámci --window dní, oceněné milníky se evidují a neopakují.
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only print milestones that would be celebrated")
+	window := flag.Int("window", 7, "days to catch up when the job did not run")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// SendTemplated silently skips when SMTP is missing - milestones would be recorded as emailed
+	if cfg.SMTPHost == "" && !*dryRun {
+		log.Fatal("SMTP not configured")
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+	qrService := qrpay.NewService(cfg.BankIBAN, cfg.BankBIC)
+	emailClient := email.New(cfg, queries, qrService)
+	ctx := context.Background()
+
+	var announcer *matrix.Client
+	if cfg.MatrixHomeserverURL != "" && cfg.MatrixAccessToken != "" && cfg.MatrixRoomID != "" {
+		announcer = matrix.NewClient(cfg.MatrixHomeserverURL, cfg.MatrixAccessToken, cfg.MatrixRoomID)
+	} else {
+		log.Println("Matrix not configured, milestones are not announced")
+	}
+
+	today := time.Now()
+
+	users, err := queries.ListUsersByState(ctx, "accepted")
+	if err != nil {
+		log.Fatalf("Failed to list users: %v", err)
+	}
+
+	counts, err := queries.ListUserPaymentCounts(ctx, today.AddDate(0, 0, -*window))
+	if err != nil {
+		log.Fatalf("Failed to count payments: %v", err)
+	}
+	paymentCounts := make(map[int64]db.ListUserPaymentCountsRow, len(counts))
+	for _, c := range counts {
+		paymentCounts[c.UserID.Int64] = c
+	}
+
+	celebrated, err := queries.ListMemberMilestones(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list milestones: %v", err)
+	}
+	alreadyCelebrated := make(map[string]bool, len(celebrated))
+	for _, m := range celebrated {
+		alreadyCelebrated[fmt.Sprintf("%d/%s", m.UserID, m.Milestone)] = true
+	}
+
+	emailOptOut, err := optOuts(ctx, queries, milestone.NotificationEmail)
+	if err != nil {
+		log.Fatalf("Failed to list notification preferences: %v", err)
+	}
+	announceOptOut, err := optOuts(ctx, queries, milestone.NotificationAnnounce)
+	if err != nil {
+		log.Fatalf("Failed to list notification preferences: %v", err)
+	}
+
+	reached := 0
+	emailed := 0
+	announced := 0
+	errors := 0
+
+	for _, user := range users {
+		var milestones []milestone.Milestone
+		if m, ok := milestone.Anniversary(user.DateJoined, today, *window); ok {
+			milestones = append(milestones, m)
+		}
+		if c, ok := paymentCounts[user.ID]; ok {
+			if m, ok := milestone.Payments(c.Total, c.Recent); ok {
+				milestones = append(milestones, m)
+			}
+		}
+
+		for _, m := range milestones {
+			if alreadyCelebrated[fmt.Sprintf("%d/%s", user.ID, m.ID())] {
+				continue
+			}
+			reached++
+
+			if *dryRun {
+				log.Printf("  [dry-run] %s: %s (email: %t, announce: %t)",
+					user.Email, m.ID(), !emailOptOut[user.ID], announcer != nil && !announceOptOut[user.ID])
+				continue
+			}
+
+			sent := false
+			if !emailOptOut[user.ID] {
+				if err := emailClient.SendMilestone(ctx, &user, m); err != nil {
+					// Not recorded, retried on the next run
+					log.Printf("  ✗ Failed to email %s: %v", user.Email, err)
+					errors++
+					continue
+				}
+				sent = true
+				emailed++
+			}
+
+			posted := false
+			if name := displayName(&user); announcer != nil && !announceOptOut[user.ID] && name != "" {
+				if err := announcer.SendText(ctx, announcement(name, m)); err != nil {
+					log.Printf("  ⚠ Failed to announce %s in Matrix: %v", user.Email, err)
+					errors++
+				} else {
+					posted = true
+					announced++
+				}
+			}
+
+			if err := queries.CreateMemberMilestone(ctx, db.CreateMemberMilestoneParams{
+				UserID:    user.ID,
+				Milestone: m.ID(),
+				Emailed:   sent,
+				Announced: posted,
+			}); err != nil {
+				log.Printf("  ⚠ Celebrated %s for %s but failed to record it: %v", m.ID(), user.Email, err)
+				errors++
+				continue
+			}
+			log.Printf("  🎉 %s: %s (email: %t, announced: %t)", user.Email, m.ID(), sent, posted)
+		}
+	}
+
+	if *dryRun {
+		log.Printf("Dry run, %d milestones reached, nothing sent", reached)
+		return
+	}
+
+	log.Printf("\nSummary:")
+	log.Printf("  Milestones reached: %d", reached)
+	log.Printf("  Emails sent: %d", emailed)
+	log.Printf("  Matrix announcements: %d", announced)
+	log.Printf("  Errors: %d", errors)
+
+	level := "success"
+	if errors > 0 {
+		level = "warning"
+	}
+	queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "cron",
+		Level:     level,
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Member milestones: %d reached, %d emails, %d Matrix announcements", reached, emailed, announced),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"reached":%d,"emailed":%d,"announced":%d,"errors":%d}`, reached, emailed, announced, errors),
+			Valid:  true,
+		},
+	})
+
+	if errors > 0 {
+		log.Fatal("Job completed with errors")
+	}
+
+	log.Println("✓ Job completed successfully")
+}
+
+// optOuts returns IDs of members who switched the notification off
+func optOuts(ctx context.Context, queries *db.Queries, notification string) (map[int64]bool, error) {
+	userIDs, err := queries.ListNotificationOptOuts(ctx, notification)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		set[id] = true
+	}
+	return set, nil
+}
+
+// displayName is the name used in the public announcement; members without a
+// name or username are not announced
+func displayName(user *db.User) string {
+	if user.Realname.Valid && user.Realname.String != "" {
+		return user.Realname.String
+	}
+	return user.Username.String
+}
+
+func announcement(name string, m milestone.Milestone) string {
+	if m.Kind == milestone.KindPayments {
+		return fmt.Sprintf("🎉 %s právě poslal(a) %d. členskou platbu pro Base48. Díky!", name, m.Count)
+	}
+	years := fmt.Sprintf("%d let", m.Count)
+	switch {
+	case m.Count == 1:
+		years = "1 rok"
+	case m.Count <= 4:
+		years = fmt.Sprintf("%d roky", m.Count)
+	}
+	return fmt.Sprintf("🎉 %s je členem Base48 už %s. Díky!", name, years)
+}
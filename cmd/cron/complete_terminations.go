@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/auth"
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fees"
+	"github.com/base48/member-portal/internal/keycloak"
+)
+
+// Dokončení ukončených členství
+//
+// Členům, kteří si v profilu ukončili členství a jejichž poslední den členství už
+// minul, nastaví stav exmember, zablokuje jim Keycloak účet (role stavu řeší
+// sync_membership_roles) a ukončení označí jako dokončené. Každý krok jde do system
+// logu. Poplatky po datu konce nevytváří už create_monthly_fees.
+//
+// Použití:
+//   # Náhled bez změn
+//   go run cmd/cron/complete_terminations.go --dry-run
+//
+// Nebo v crontab (denně):
+//   15 3 * * * cd /path/to/portal && ./complete_terminations >> logs/cron.log 2>&1
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only list memberships that would be ended")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.KeycloakServiceAccountClientID == "" || cfg.KeycloakServiceAccountClientSecret == "" {
+		log.Fatal("KEYCLOAK_SERVICE_ACCOUNT_CLIENT_ID and KEYCLOAK_SERVICE_ACCOUNT_CLIENT_SECRET are required")
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+	ctx := context.Background()
+
+	terminations, err := queries.ListScheduledTerminations(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list membership terminations: %v", err)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	log.Printf("Checking %d scheduled membership terminations...", len(terminations))
+
+	var kcClient *keycloak.Client
+	if !*dryRun {
+		serviceClient, err := auth.NewServiceAccountClient(
+			ctx,
+			cfg,
+			cfg.KeycloakServiceAccountClientID,
+			cfg.KeycloakServiceAccountClientSecret,
+		)
+		if err != nil {
+			log.Fatalf("Failed to create service account: %v", err)
+		}
+		log.Println("✓ Service account authenticated")
+
+		kcClient = keycloak.NewClientWithTokenProvider(cfg, serviceClient)
+	}
+
+	completed := 0
+	errors := 0
+
+	for _, termination := range terminations {
+		if !fees.TerminationPassed(termination, today) {
+			continue
+		}
+		endDate := termination.EndDate.Format("2006-01-02")
+
+		user, err := queries.GetUserByID(ctx, termination.UserID)
+		if err != nil {
+			log.Printf("⚠ Error getting user %d: %v", termination.UserID, err)
+			errors++
+			continue
+		}
+
+		if *dryRun {
+			log.Printf("  - %s: would end membership (last day %s)", user.Email, endDate)
+			completed++
+			continue
+		}
+
+		if err := queries.UpdateUserState(ctx, db.UpdateUserStateParams{
+			State: "exmember",
+			ID:    user.ID,
+		}); err != nil {
+			log.Printf("✗ Failed to end membership of %s: %v", user.Email, err)
+			errors++
+			continue
+		}
+		if _, err := queries.CompleteMembershipTermination(ctx, termination.ID); err != nil {
+			log.Printf("✗ Failed to complete termination #%d of %s: %v", termination.ID, user.Email, err)
+			errors++
+			continue
+		}
+		log.Printf("✓ Ended membership of %s (last day %s)", user.Email, endDate)
+		completed++
+		queries.CreateLog(ctx, db.CreateLogParams{
+			Subsystem: "membership",
+			Level:     "info",
+			UserID:    sql.NullInt64{Int64: user.ID, Valid: true},
+			Message:   fmt.Sprintf("Membership of %s ended on %s at the member's request", user.Email, endDate),
+			Metadata:  sql.NullString{String: fmt.Sprintf(`{"termination_id":%d,"end_date":%q,"previous_state":%q}`, termination.ID, endDate, user.State), Valid: true},
+		})
+
+		if user.KeycloakID.Valid && user.KeycloakID.String != "" {
+			if err := kcClient.DisableUser(ctx, user.KeycloakID.String); err != nil {
+				log.Printf("  ✗ Failed to disable Keycloak account of %s: %v", user.Email, err)
+				errors++
+				queries.CreateLog(ctx, db.CreateLogParams{
+					Subsystem: "keycloak",
+					Level:     "error",
+					UserID:    sql.NullInt64{Int64: user.ID, Valid: true},
+					Message:   fmt.Sprintf("Failed to disable Keycloak account of former member %s: %v", user.Email, err),
+					Metadata:  sql.NullString{String: fmt.Sprintf(`{"keycloak_id":%q}`, user.KeycloakID.String), Valid: true},
+				})
+			} else {
+				log.Printf("  ✓ Disabled Keycloak account")
+				queries.CreateLog(ctx, db.CreateLogParams{
+					Subsystem: "keycloak",
+					Level:     "info",
+					UserID:    sql.NullInt64{Int64: user.ID, Valid: true},
+					Message:   fmt.Sprintf("Keycloak account of former member %s disabled", user.Email),
+					Metadata:  sql.NullString{String: fmt.Sprintf(`{"keycloak_id":%q}`, user.KeycloakID.String), Valid: true},
+				})
+			}
+		}
+	}
+
+	log.Printf("\nSummary:")
+	log.Printf("  Scheduled terminations: %d", len(terminations))
+	log.Printf("  Ended: %d", completed)
+	log.Printf("  Errors: %d", errors)
+
+	if *dryRun {
+		log.Println("✓ Dry run - no changes made")
+		return
+	}
+
+	level := "success"
+	if errors > 0 {
+		level = "warning"
+	}
+	queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "cron",
+		Level:     level,
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Membership terminations: %d ended, %d errors", completed, errors),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"ended":%d,"errors":%d}`, completed, errors), Valid: true},
+	})
+
+	if errors > 0 {
+		log.Fatal("Job completed with errors")
+	}
+
+	log.Println("✓ Job completed successfully")
+}
@@ -3,28 +3,44 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/joho/godotenv"
 	_ "modernc.org/sqlite"
 
+	"github.com/base48/member-portal/internal/balance"
 	"github.com/base48/member-portal/internal/config"
 	"github.com/base48/member-portal/internal/db"
 	"github.com/base48/member-portal/internal/email"
+	"github.com/base48/member-portal/internal/fees"
+	"github.com/base48/member-portal/internal/money"
 	"github.com/base48/member-portal/internal/qrpay"
 )
 
 // Automatické vytváření měsíčních poplatků pro všechny aktivní členy
 //
+// Jeden poplatek na člena a období hlídá i databáze (unikátní index), takže souběžný
+// druhý běh nikoho nezaúčtuje dvakrát - konflikty se jen vypíšou v souhrnu.
+//
 // Použití:
 //   go run cmd/cron/create_monthly_fees.go
 //
+//   # Náhled po změně úrovní / konfigurace: vypíše, jaké poplatky vzniknou a komu půjde
+//   # upomínka, nic nezapíše ani neodešle (běží na kopii databáze)
+//   go run cmd/cron/create_monthly_fees.go --dry-run
+//
 // Nebo v crontab (běží první den v měsíci):
 //   0 0 1 * * cd /path/to/portal && ./create_monthly_fees >> logs/fees.log 2>&1
 
 func main() {
+	dryRun := flag.Bool("dry-run", false, "only print the fees and reminder emails, on a copy of the database")
+	flag.Parse()
+
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
@@ -40,10 +56,34 @@ func main() {
 	}
 	defer database.Close()
 
+	ctx := context.Background()
+
+	// Náhled běží celý na kopii databáze - změny příspěvků a úrovní, konce pauz
+	// i poplatky vyjdou přesně jako v ostrém běhu, ale do databáze portálu se nic
+	// nezapíše a emaily se jen vypíšou
+	if *dryRun {
+		dir, err := os.MkdirTemp("", "create_monthly_fees")
+		if err != nil {
+			log.Fatalf("Failed to create temporary directory: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		snapshot := filepath.Join(dir, "portal.db")
+		if _, err := database.ExecContext(ctx, "VACUUM INTO ?", snapshot); err != nil {
+			log.Fatalf("Failed to copy database for dry run: %v", err)
+		}
+		database.Close()
+		database, err = sql.Open("sqlite", snapshot)
+		if err != nil {
+			log.Fatalf("Failed to open database copy: %v", err)
+		}
+		defer database.Close()
+		log.Println("Dry run - working on a copy of the database, no emails are sent")
+	}
+
 	queries := db.New(database)
 	qrService := qrpay.NewService(cfg.BankIBAN, cfg.BankBIC)
 	emailClient := email.New(cfg, queries, qrService)
-	ctx := context.Background()
 
 	// Získáme první den aktuálního měsíce
 	now := time.Now()
@@ -51,6 +91,135 @@ func main() {
 
 	log.Printf("Creating fees for period: %s", periodStart.Format("2006-01"))
 
+	// Poplatky mění zůstatky - čekáme, až doběhnou ostatní zápisy (server, sync plateb).
+	// Při log.Fatal zámek vyprší sám.
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	lock, err := balance.Acquire(waitCtx, queries, balance.LockName, "create_monthly_fees")
+	cancel()
+	if err != nil {
+		log.Fatalf("Failed to acquire balance lock: %v", err)
+	}
+	defer lock.Release(ctx)
+
+	// Plánované změny příspěvků účinné od tohoto měsíce - přepneme částky dřív, než vzniknou poplatky
+	applied, err := fees.ApplyDueChanges(ctx, database, queries, periodStart)
+	for _, change := range applied {
+		log.Printf("Applied fee change #%d: %s %s → %s Kč (%d members updated)",
+			change.ChangeID, change.LevelName, change.OldAmount, change.NewAmount, change.UsersUpdated)
+		queries.CreateLog(ctx, db.CreateLogParams{
+			Subsystem: "cron",
+			Level:     "info",
+			UserID:    sql.NullInt64{},
+			Message:   fmt.Sprintf("Fee change #%d applied: %s %s → %s Kč", change.ChangeID, change.LevelName, change.OldAmount, change.NewAmount),
+			Metadata: sql.NullString{String: fmt.Sprintf(`{"change_id":%d,"level":%q,"old_amount":%q,"new_amount":%q,"users_updated":%d}`,
+				change.ChangeID, change.LevelName, change.OldAmount, change.NewAmount, change.UsersUpdated), Valid: true},
+		})
+	}
+	if err != nil {
+		log.Fatalf("Failed to apply planned fee changes: %v", err)
+	}
+
+	// Schválené změny úrovní členů účinné od tohoto měsíce - poplatek se vytvoří podle
+	// úrovně platné pro dané období
+	levelChanges, err := fees.ApplyDueLevelChanges(ctx, database, queries, periodStart)
+	for _, change := range levelChanges {
+		log.Printf("Applied level change #%d: %s → %s (%s Kč)", change.HistoryID, change.Email, change.LevelName, change.Amount)
+		queries.CreateLog(ctx, db.CreateLogParams{
+			Subsystem: "cron",
+			Level:     "info",
+			UserID:    sql.NullInt64{Int64: change.UserID, Valid: true},
+			Message:   fmt.Sprintf("Level changed to %s (%s Kč) from %s", change.LevelName, change.Amount, periodStart.Format("2006-01")),
+			Metadata: sql.NullString{String: fmt.Sprintf(`{"history_id":%d,"old_level_id":%d,"new_level_id":%d,"amount":%q}`,
+				change.HistoryID, change.OldLevelID, change.NewLevelID, change.Amount), Valid: true},
+		})
+	}
+	if err != nil {
+		log.Fatalf("Failed to apply level changes: %v", err)
+	}
+
+	// Prahy upomínek (výchozí z konfigurace, admin je může změnit v nastavení)
+	ladder, err := fees.LoadDebtLadder(ctx, queries, fees.DefaultDebtLadder(cfg))
+	if err != nil {
+		log.Fatalf("Failed to load debt thresholds: %v", err)
+	}
+
+	// Pozastavená členství, kterým skončilo období - členství se automaticky obnoví
+	// a od tohoto měsíce se zase vytváří poplatky
+	pauses, err := queries.ListOpenMembershipPauses(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list membership pauses: %v", err)
+	}
+	paused := map[int64]db.MembershipPause{}
+	for _, pause := range pauses {
+		if !fees.PauseExpired(pause, periodStart) {
+			paused[pause.UserID] = pause
+			continue
+		}
+		if err := queries.EndMembershipPause(ctx, pause.ID); err != nil {
+			log.Printf("  ⚠ Failed to end membership pause #%d: %v", pause.ID, err)
+			continue
+		}
+		log.Printf("Membership pause #%d of user %d ended on %s", pause.ID, pause.UserID, pause.EndDate.Time.Format("2006-01-02"))
+		queries.CreateLog(ctx, db.CreateLogParams{
+			Subsystem: "cron",
+			Level:     "info",
+			UserID:    sql.NullInt64{Int64: pause.UserID, Valid: true},
+			Message:   fmt.Sprintf("Membership pause ended on %s, fees resumed", pause.EndDate.Time.Format("2006-01-02")),
+			Metadata:  sql.NullString{String: fmt.Sprintf(`{"pause_id":%d,"end_date":"%s"}`, pause.ID, pause.EndDate.Time.Format("2006-01-02")), Valid: true},
+		})
+	}
+
+	// Naplánovaná ukončení členství - po datu konce se poplatky nevytváří
+	scheduledTerminations, err := queries.ListScheduledTerminations(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list membership terminations: %v", err)
+	}
+	terminations := map[int64]db.MembershipTermination{}
+	for _, termination := range scheduledTerminations {
+		terminations[termination.UserID] = termination
+	}
+
+	// Splátkové kalendáře - po poslední splátce a bez dluhu je ukončíme, ostatním
+	// členům připomínáme splátku místo celého dluhu
+	activePlans, err := queries.ListActivePaymentPlans(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list payment plans: %v", err)
+	}
+	plans := map[int64]db.PaymentPlan{}
+	for _, plan := range activePlans {
+		if !fees.PlanFinished(plan, periodStart) {
+			plans[plan.UserID] = plan
+			continue
+		}
+		balance, err := queries.GetUserBalance(ctx, db.GetUserBalanceParams{
+			UserID:   sql.NullInt64{Int64: plan.UserID, Valid: true},
+			UserID_2: plan.UserID,
+			UserID_3: sql.NullInt64{Int64: plan.UserID, Valid: true},
+			UserID_4: plan.UserID,
+			UserID_5: plan.UserID,
+		})
+		if err != nil {
+			log.Printf("  ⚠ Failed to get balance of user %d: %v", plan.UserID, err)
+			continue
+		}
+		if balance < 0 {
+			plans[plan.UserID] = plan
+			continue
+		}
+		if err := queries.EndPaymentPlans(ctx, plan.UserID); err != nil {
+			log.Printf("  ⚠ Failed to end payment plan #%d: %v", plan.ID, err)
+			continue
+		}
+		log.Printf("Payment plan #%d of user %d completed", plan.ID, plan.UserID)
+		queries.CreateLog(ctx, db.CreateLogParams{
+			Subsystem: "cron",
+			Level:     "info",
+			UserID:    sql.NullInt64{Int64: plan.UserID, Valid: true},
+			Message:   fmt.Sprintf("Payment plan completed: %s Kč paid off", plan.TotalDebt),
+			Metadata:  sql.NullString{String: fmt.Sprintf(`{"plan_id":%d,"total_debt":%q}`, plan.ID, plan.TotalDebt), Valid: true},
+		})
+	}
+
 	// Načteme všechny accepted členy s jejich úrovněmi
 	users, err := queries.ListAcceptedUsersForFees(ctx)
 	if err != nil {
@@ -61,76 +230,154 @@ func main() {
 
 	created := 0
 	skipped := 0
+	conflicts := 0
 	errors := 0
 	emailsSent := 0
 
 	for _, user := range users {
-		// Zkontrolujeme, jestli už fee pro tento měsíc neexistuje
-		existingFee, err := queries.GetFeeByUserAndPeriod(ctx, db.GetFeeByUserAndPeriodParams{
-			UserID:      user.ID,
-			PeriodStart: periodStart,
-		})
-
-		if err == nil && existingFee.ID > 0 {
+		// Zkontrolujeme, jestli tento měsíc už nepokrývá existující fee (čtvrtletní / roční
+		// poplatek pokrývá víc měsíců)
+		lastFee, err := queries.GetLastFeeByUser(ctx, user.ID)
+		if err == nil && fees.CoveredUntil(lastFee).After(periodStart) {
 			log.Printf("  ⊘ Skipping %s - fee already exists for %s", user.Email, periodStart.Format("2006-01"))
 			skipped++
 			continue
 		}
 
+		// Pozastavené členství - měsíc, jehož první den spadá do pauzy, se neplatí
+		if pause, ok := paused[user.ID]; ok && fees.PauseCovers(pause, periodStart) {
+			log.Printf("  ⊘ Skipping %s - membership paused since %s", user.Email, pause.StartDate.Format("2006-01-02"))
+			skipped++
+			continue
+		}
+
 		// Určíme částku - vždy používáme level_actual_amount, fallback na level.amount
-		feeAmount := user.LevelActualAmount
-		if feeAmount == "0" || feeAmount == "" {
-			feeAmount = user.LevelAmount
-			log.Printf("  ⚠ User %s has no level_actual_amount, using level default: %s", user.Email, feeAmount)
+		monthlyAmount := user.LevelActualAmount
+		if monthlyAmount == 0 {
+			monthlyAmount = user.LevelAmount
+			log.Printf("  ⚠ User %s has no level_actual_amount, using level default: %s", user.Email, monthlyAmount)
+		}
+
+		// Za měsíc vstupu se platí poměrná část podle FEE_PRORATION
+		firstMonth := fees.ProratedAmount(monthlyAmount, periodStart, user.DateJoined, cfg.FeeProration)
+		if firstMonth == 0 {
+			log.Printf("  ⊘ Skipping %s - joins %s", user.Email, user.DateJoined.Format("2006-01-02"))
+			skipped++
+			continue
+		}
+		if firstMonth != monthlyAmount {
+			log.Printf("  ℹ Prorated fee for %s (joined %s): %s of %s Kč", user.Email, user.DateJoined.Format("2006-01-02"), firstMonth, monthlyAmount)
+		}
+
+		// Čtvrtletní / roční platba: fee do konce čtvrtletí / roku
+		months := fees.PeriodMonths(user.BillingCycle, periodStart)
+		if termination, ok := terminations[user.ID]; ok {
+			months = fees.TerminationMonths(termination, periodStart, months)
+			if months == 0 {
+				log.Printf("  ⊘ Skipping %s - membership ends %s", user.Email, termination.EndDate.Format("2006-01-02"))
+				skipped++
+				continue
+			}
 		}
+		feeAmount := firstMonth + monthlyAmount*money.Amount(months-1)
 
-		// Vytvoříme fee záznam
-		fee, err := queries.CreateFee(ctx, db.CreateFeeParams{
+		// Vytvoříme fee záznam; pokud ho mezitím vytvořil jiný běh, databáze vložení
+		// ignoruje a nevrátí žádný řádek
+		fee, err := queries.CreateFeeIfMissing(ctx, db.CreateFeeIfMissingParams{
 			UserID:      user.ID,
 			LevelID:     user.LevelID,
 			PeriodStart: periodStart,
 			Amount:      feeAmount,
+			Months:      int64(months),
 		})
 
+		if err == sql.ErrNoRows {
+			log.Printf("  ⊘ Skipping %s - fee for %s created concurrently by another run", user.Email, periodStart.Format("2006-01"))
+			conflicts++
+			continue
+		}
 		if err != nil {
 			log.Printf("  ✗ Failed to create fee for %s: %v", user.Email, err)
 			errors++
 			continue
 		}
 
-		log.Printf("  ✓ Created fee for %s: %s Kč (fee_id: %d)", user.Email, fee.Amount, fee.ID)
+		if *dryRun {
+			log.Printf("  - Would create fee for %s: %s Kč for %s", user.Email, fee.Amount, fees.PeriodLabel(periodStart, months))
+		} else {
+			log.Printf("  ✓ Created fee for %s: %s Kč for %s (fee_id: %d)", user.Email, fee.Amount, fees.PeriodLabel(periodStart, months), fee.ID)
+		}
 		created++
 
 		// Po vytvoření fee zkontrolujeme balance a případně pošleme upozornění
 		balance, err := queries.GetUserBalance(ctx, db.GetUserBalanceParams{
 			UserID:   sql.NullInt64{Int64: user.ID, Valid: true},
 			UserID_2: user.ID,
+			UserID_3: sql.NullInt64{Int64: user.ID, Valid: true},
+			UserID_4: user.ID,
+			UserID_5: user.ID,
 		})
 		if err != nil {
 			log.Printf("  ⚠ Failed to get balance for %s: %v", user.Email, err)
 			continue
 		}
 
-		// Pokud je balance záporná a větší než 2x měsíční poplatek, pošleme warning
-		var monthlyFee float64
-		fmt.Sscanf(feeAmount, "%f", &monthlyFee)
-		balanceFloat := float64(balance)
+		// Upomínka podle nejvyššího překročeného prahu (u měsíční platby výchozí
+		// 1x / 2x měsíční poplatek, předepsané měsíce dopředu se nepočítají);
+		// pozastavení řeší suspend_debtors, tady dostane člen aspoň warning
+		monthlyFee := monthlyAmount.Float64()
+		balanceFloat := money.Amount(balance).Float64()
+		step := ladder.Step(money.Amount(-balance), monthlyAmount, fee)
+		if step == "" {
+			continue
+		}
 
-		if balanceFloat < -(2 * monthlyFee) {
-			// Načteme celý user záznam pro email
-			fullUser, err := queries.GetUserByID(ctx, user.ID)
-			if err != nil {
-				log.Printf("  ⚠ Failed to get user record for email: %v", err)
+		// Načteme celý user záznam pro email
+		fullUser, err := queries.GetUserByID(ctx, user.ID)
+		if err != nil {
+			log.Printf("  ⚠ Failed to get user record for email: %v", err)
+			continue
+		}
+
+		// Se splátkovým kalendářem připomeneme splátku (a příspěvek), ne celý dluh
+		if plan, ok := plans[user.ID]; ok {
+			debt := money.Amount(-balance)
+			amountDue := fees.PlanAmountDue(plan, debt, periodStart)
+			if amountDue == 0 {
 				continue
 			}
-
-			// Pošleme debt warning email (gracefully - necrashne když selže)
-			if err := emailClient.SendDebtWarning(ctx, &fullUser, balanceFloat, monthlyFee); err != nil {
-				log.Printf("  ⚠ Failed to send debt warning email: %v", err)
+			remaining := fees.PlanRemaining(plan, fees.PlanInstallmentsDue(plan, periodStart.AddDate(0, 1, -1)))
+			if *dryRun {
+				log.Printf("  ✉ Would send payment plan reminder to %s (%s Kč due this month)", fullUser.Email, amountDue)
+				emailsSent++
+				continue
+			}
+			if err := emailClient.SendPaymentPlanReminder(ctx, &fullUser, plan, amountDue, remaining, fees.OnPlan(plan, debt, periodStart)); err != nil {
+				log.Printf("  ⚠ Failed to send payment plan reminder: %v", err)
 			} else {
-				log.Printf("  ✉ Sent debt warning email (balance: %.0f Kč)", balanceFloat)
+				log.Printf("  ✉ Sent payment plan reminder (%s Kč due this month)", amountDue)
 				emailsSent++
 			}
+			continue
+		}
+
+		if *dryRun {
+			log.Printf("  ✉ Would send debt %s email to %s (balance: %.0f Kč)", step, fullUser.Email, balanceFloat)
+			emailsSent++
+			continue
+		}
+
+		// Pošleme email (gracefully - necrashne když selže)
+		if step == fees.DebtNotice {
+			err = emailClient.SendNegativeBalance(ctx, &fullUser, balanceFloat)
+		} else {
+			err = emailClient.SendDebtWarning(ctx, &fullUser, balanceFloat, monthlyFee)
+		}
+		if err != nil {
+			log.Printf("  ⚠ Failed to send debt %s email: %v", step, err)
+		} else {
+			log.Printf("  ✉ Sent debt %s email (balance: %.0f Kč)", step, balanceFloat)
+			emailsSent++
 		}
 	}
 
@@ -138,21 +385,31 @@ func main() {
 	log.Printf("  Period: %s", periodStart.Format("2006-01"))
 	log.Printf("  Total users: %d", len(users))
 	log.Printf("  Created: %d", created)
-	log.Printf("  Skipped (already exists): %d", skipped)
-	log.Printf("  Debt warning emails sent: %d", emailsSent)
+	log.Printf("  Skipped (already exists, not joined yet, paused or terminated): %d", skipped)
+	log.Printf("  Conflicts (created by a concurrent run): %d", conflicts)
+	if *dryRun {
+		log.Printf("  Debt reminder emails (not sent): %d", emailsSent)
+	} else {
+		log.Printf("  Debt reminder emails sent: %d", emailsSent)
+	}
 	log.Printf("  Errors: %d", errors)
 
+	if *dryRun {
+		log.Println("✓ Dry run - no changes made")
+		return
+	}
+
 	// Log cron job completion
 	level := "success"
-	if errors > 0 {
+	if errors > 0 || conflicts > 0 {
 		level = "warning"
 	}
 	queries.CreateLog(ctx, db.CreateLogParams{
 		Subsystem: "cron",
 		Level:     level,
 		UserID:    sql.NullInt64{},
-		Message:   fmt.Sprintf("Monthly fees created for %s: %d fees, %d emails sent", periodStart.Format("2006-01"), created, emailsSent),
-		Metadata:  sql.NullString{String: fmt.Sprintf(`{"period":"%s","created":%d,"skipped":%d,"emails":%d,"errors":%d}`, periodStart.Format("2006-01"), created, skipped, emailsSent, errors), Valid: true},
+		Message:   fmt.Sprintf("Monthly fees created for %s: %d fees, %d conflicts, %d emails sent", periodStart.Format("2006-01"), created, conflicts, emailsSent),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"period":"%s","created":%d,"skipped":%d,"conflicts":%d,"emails":%d,"errors":%d}`, periodStart.Format("2006-01"), created, skipped, conflicts, emailsSent, errors), Valid: true},
 	})
 
 	if errors > 0 {
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/balance"
+	"github.com/base48/member-portal/internal/bankimport"
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+)
+
+// Import plateb z výpisu staženého z banky (FIO CSV, GPC/ABO nebo camt.053)
+//
+// Použití:
+//   go run cmd/cron/import_bank_statement.go --file vypis.csv
+//   go run cmd/cron/import_bank_statement.go --file vypis.gpc --format gpc
+//   go run cmd/cron/import_bank_statement.go --file vypis.xml --format camt053
+//   go run cmd/cron/import_bank_statement.go --file vypis.csv --dry-run
+//
+// Pro doplnění plateb starších než 90 dní, které FIO API nevrátí. Pohyby prochází
+// stejným párováním jako sync_fio_payments (VS, faktury, pravidla, účet odesílatele,
+// vrácené platby, výdaje) a podle ID pohybu FIO se neduplikují - výpis lze importovat
+// opakovaně i přes období, které už stáhl sync. Formát se pozná podle přípony
+// (.csv, .gpc, .abo, .xml) nebo obsahu.
+//
+// Výpisy camt.053 (ISO 20022) jiných bank (ČSOB, KB, ...) se ukládají jako platby
+// druhu "camt" s referencí banky místo ID pohybu - opakovaný import stejného výpisu
+// je také bez duplicit.
+
+func main() {
+	file := flag.String("file", "", "Statement file to import")
+	format := flag.String("format", "", "Statement format: fio-csv, gpc or camt053 (default: detect)")
+	dryRun := flag.Bool("dry-run", false, "Only parse the statement and print the transactions")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("--file is required")
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("Failed to read statement: %v", err)
+	}
+
+	if *format == "" {
+		*format = bankimport.DetectFormat(*file, data)
+		if *format == "" {
+			log.Fatalf("Unknown statement format, use --format %s, %s or %s", bankimport.FormatFIOCSV, bankimport.FormatGPC, bankimport.FormatCAMT053)
+		}
+	}
+
+	transactions, err := bankimport.Parse(*format, data)
+	if err != nil {
+		log.Fatalf("Failed to parse statement: %v", err)
+	}
+	log.Printf("Read %d transactions from %s (%s)", len(transactions), *file, *format)
+
+	if *dryRun {
+		for _, tx := range transactions {
+			log.Printf("  %s %10.2f %s  VS %-10s %s/%s %s (ID %s)", tx.Date, tx.Amount, tx.Currency,
+				tx.VariableSymbol, tx.AccountNumber, tx.BankCode, tx.AccountName, tx.KindID)
+		}
+		log.Println("Dry run, nothing imported")
+		return
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+	ctx := context.Background()
+
+	// Writes are serialized with the server and other balance jobs
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	lock, err := balance.Acquire(waitCtx, queries, balance.LockName, "import_bank_statement")
+	cancel()
+	if err != nil {
+		log.Fatalf("Failed to acquire balance lock: %v", err)
+	}
+	defer lock.Release(ctx)
+
+	summary, err := bankimport.Import(ctx, queries, transactions, bankimport.Options{
+		AutoLinkByAccount: cfg.FIOAutoLinkByAccount,
+	})
+	if err != nil {
+		lock.Release(ctx) // log.Fatal skips deferred calls
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	summary.LogReport("IMPORT SUMMARY")
+
+	queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "fio_sync",
+		Level:     summary.Level(),
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Bank statement %s imported: %s", filepath.Base(*file), summary.Message()),
+		Metadata:  sql.NullString{String: summary.Metadata(), Valid: true},
+	})
+
+	if summary.Errors > 0 {
+		lock.Release(ctx) // log.Fatal skips deferred calls
+		log.Fatalf("%d of %d transactions failed, fix the cause and import the statement again", summary.Errors, len(transactions))
+	}
+
+	log.Println("✓ Import completed successfully")
+}
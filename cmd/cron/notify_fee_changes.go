@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/email"
+	"github.com/base48/member-portal/internal/fees"
+	"github.com/base48/member-portal/internal/qrpay"
+)
+
+// Upozornění členů na plánovanou změnu výše členského příspěvku
+//
+// Použití:
+//   go run cmd/cron/notify_fee_changes.go
+//   go run cmd/cron/notify_fee_changes.go --dry-run
+//
+// Nebo v crontab (denně):
+//   0 9 * * * cd /path/to/portal && ./notify_fee_changes >> logs/fee_changes.log 2>&1
+//
+// Změnu naplánovanou v /admin/settings oznámí FEE_CHANGE_NOTICE_WEEKS týdnů před účinností
+// (výchozí 4) přijatým členům, kterým se příspěvek opravdu změní. Odeslaná upozornění se
+// evidují, opakované spuštění je nepošle znovu. Částky přepne create_monthly_fees.
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only print who would be notified")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// SendTemplated silently skips when SMTP is missing - members would be marked as notified
+	if cfg.SMTPHost == "" && !*dryRun {
+		log.Fatal("SMTP not configured")
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+	qrService := qrpay.NewService(cfg.BankIBAN, cfg.BankBIC)
+	emailClient := email.New(cfg, queries, qrService)
+	ctx := context.Background()
+
+	noticeUntil := time.Now().UTC().AddDate(0, 0, 7*cfg.FeeChangeNoticeWeeks)
+	changes, err := queries.ListLevelPriceChangesToNotify(ctx, noticeUntil)
+	if err != nil {
+		log.Fatalf("Failed to list planned fee changes: %v", err)
+	}
+
+	if len(changes) == 0 {
+		log.Println("No fee changes to announce")
+		return
+	}
+
+	sent := 0
+	errors := 0
+
+	for _, change := range changes {
+		log.Printf("Change #%d: %s %s → %s Kč from %s",
+			change.ID, change.LevelName, change.LevelAmount, change.NewAmount, change.EffectiveFrom.Format("2006-01"))
+
+		users, err := queries.ListUsersByLevel(ctx, change.LevelID)
+		if err != nil {
+			log.Fatalf("Failed to list users: %v", err)
+		}
+		notified, err := queries.ListLevelPriceChangeNotices(ctx, change.ID)
+		if err != nil {
+			log.Fatalf("Failed to list sent notices: %v", err)
+		}
+		alreadyNotified := make(map[int64]bool, len(notified))
+		for _, userID := range notified {
+			alreadyNotified[userID] = true
+		}
+
+		changeErrors := 0
+		for _, user := range users {
+			if user.State != "accepted" || alreadyNotified[user.ID] {
+				continue
+			}
+			newActual, affected := fees.AmountAfterChange(user.LevelActualAmount, change.LevelAmount, change.NewAmount)
+			if !affected {
+				continue
+			}
+
+			oldAmount := fees.EffectiveAmount(user.LevelActualAmount, change.LevelAmount)
+			newAmount := fees.EffectiveAmount(newActual, change.NewAmount)
+
+			if *dryRun {
+				log.Printf("  [dry-run] %s: %s → %s Kč", user.Email, oldAmount, newAmount)
+				continue
+			}
+
+			if err := emailClient.SendFeeChange(ctx, &user, change.LevelName, oldAmount, newAmount, change.EffectiveFrom, change.Note.String); err != nil {
+				log.Printf("  ✗ Failed to notify %s: %v", user.Email, err)
+				changeErrors++
+				continue
+			}
+			if err := queries.CreateLevelPriceChangeNotice(ctx, db.CreateLevelPriceChangeNoticeParams{
+				ChangeID: change.ID,
+				UserID:   user.ID,
+			}); err != nil {
+				log.Printf("  ⚠ Notified %s but failed to record it: %v", user.Email, err)
+				changeErrors++
+				continue
+			}
+			log.Printf("  ✉ %s: %s → %s Kč", user.Email, oldAmount, newAmount)
+			sent++
+		}
+
+		errors += changeErrors
+		if *dryRun || changeErrors > 0 {
+			continue
+		}
+		if err := queries.MarkLevelPriceChangeNotified(ctx, change.ID); err != nil {
+			log.Printf("  ⚠ Failed to mark change #%d as notified: %v", change.ID, err)
+			errors++
+		}
+	}
+
+	if *dryRun {
+		log.Println("Dry run, no emails sent")
+		return
+	}
+
+	log.Printf("\nSummary:")
+	log.Printf("  Changes: %d", len(changes))
+	log.Printf("  Emails sent: %d", sent)
+	log.Printf("  Errors: %d", errors)
+
+	level := "success"
+	if errors > 0 {
+		level = "warning"
+	}
+	queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "cron",
+		Level:     level,
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Fee change notices: %d emails sent for %d planned changes", sent, len(changes)),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"changes":%d,"sent":%d,"errors":%d}`, len(changes), sent, errors), Valid: true},
+	})
+
+	if errors > 0 {
+		log.Fatal("Job completed with errors (failed recipients are retried on the next run)")
+	}
+
+	log.Println("✓ Job completed successfully")
+}
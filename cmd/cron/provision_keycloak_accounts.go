@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/auth"
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/keycloak"
+)
+
+// Založení Keycloak účtů pro importované členy bez keycloak_id
+//
+// Pro každého člena bez účtu vytvoří uživatele v Keycloaku (email, jméno), pošle mu
+// email s nastavením hesla a uloží ID účtu do users.keycloak_id. Pokud účet se stejným
+// emailem už existuje, pouze se propojí (bez emailu).
+//
+// Použití:
+//   # Náhled bez změn
+//   go run cmd/cron/provision_keycloak_accounts.go --dry-run
+//
+//   # Založení účtů pro aktivní členy (max 20 najednou)
+//   go run cmd/cron/provision_keycloak_accounts.go --state accepted --limit 20
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only list members that would get an account")
+	state := flag.String("state", "accepted", "only members in this state (empty = all states)")
+	limit := flag.Int("limit", 0, "maximum number of accounts to provision (0 = no limit)")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.KeycloakServiceAccountClientID == "" || cfg.KeycloakServiceAccountClientSecret == "" {
+		log.Fatal("KEYCLOAK_SERVICE_ACCOUNT_CLIENT_ID and KEYCLOAK_SERVICE_ACCOUNT_CLIENT_SECRET are required")
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+
+	ctx := context.Background()
+
+	users, err := queries.ListUsersWithoutKeycloakID(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list users: %v", err)
+	}
+
+	var candidates []db.User
+	for _, user := range users {
+		if *state != "" && user.State != *state {
+			continue
+		}
+		if *limit > 0 && len(candidates) >= *limit {
+			break
+		}
+		candidates = append(candidates, user)
+	}
+
+	log.Printf("Members without Keycloak account: %d (selected: %d)", len(users), len(candidates))
+
+	if *dryRun {
+		for _, user := range candidates {
+			log.Printf("  - %s (%s, %s)", user.Email, user.Realname.String, user.State)
+		}
+		log.Println("✓ Dry run - no changes made")
+		return
+	}
+
+	serviceClient, err := auth.NewServiceAccountClient(
+		ctx,
+		cfg,
+		cfg.KeycloakServiceAccountClientID,
+		cfg.KeycloakServiceAccountClientSecret,
+	)
+	if err != nil {
+		log.Fatalf("Failed to create service account: %v", err)
+	}
+
+	log.Println("✓ Service account authenticated")
+
+	kcClient := keycloak.NewClientWithTokenProvider(cfg, serviceClient)
+
+	created := 0
+	linked := 0
+	errors := 0
+
+	for _, user := range candidates {
+		keycloakID, isNew, err := kcClient.ProvisionUser(ctx, user.Email, user.Username.String, user.Realname.String)
+		if keycloakID == "" {
+			log.Printf("✗ %s: %v", user.Email, err)
+			errors++
+			continue
+		}
+		if err != nil {
+			// Account exists, only the password email failed - link it anyway
+			log.Printf("⚠ %s: %v", user.Email, err)
+			errors++
+		}
+
+		if _, err := queries.LinkKeycloakID(ctx, db.LinkKeycloakIDParams{
+			KeycloakID: sql.NullString{String: keycloakID, Valid: true},
+			Email:      user.Email,
+		}); err != nil {
+			log.Printf("✗ %s: failed to link Keycloak account %s: %v", user.Email, keycloakID, err)
+			errors++
+			continue
+		}
+
+		if isNew {
+			log.Printf("✓ Created account for %s", user.Email)
+			created++
+		} else {
+			log.Printf("↻ Linked existing account for %s", user.Email)
+			linked++
+		}
+	}
+
+	log.Println(strings.Repeat("=", 60))
+	log.Printf("Created: %d, linked existing: %d, errors: %d", created, linked, errors)
+
+	level := "success"
+	if errors > 0 {
+		level = "warning"
+	}
+	queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "keycloak_provision",
+		Level:     level,
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Keycloak provisioning: %d created, %d linked, %d errors", created, linked, errors),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"created":%d,"linked":%d,"errors":%d}`, created, linked, errors), Valid: true},
+	})
+
+	if errors > 0 {
+		log.Fatal("Job completed with errors")
+	}
+
+	log.Println("✓ Job completed successfully")
+}
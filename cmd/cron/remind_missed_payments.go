@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/email"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/base48/member-portal/internal/paymentanalytics"
+	"github.com/base48/member-portal/internal/qrpay"
+)
+
+// Připomínka vynechané platby trvalého příkazu
+//
+// Použití:
+//   go run cmd/cron/remind_missed_payments.go
+//   go run cmd/cron/remind_missed_payments.go --days 10 --dry-run
+//
+// Nebo v crontab (denně, po sync_fio_payments):
+//   30 7 * * * cd /path/to/portal && ./remind_missed_payments >> logs/reminders.log 2>&1
+//
+// Z historie plateb za poslední rok pozná členy, kteří platí trvalým příkazem
+// (aspoň 3 platby podobné částky v měsíčních odstupech). Když očekávaná platba
+// nedorazí ani --days dní po obvyklém termínu, pošle členovi vlídnou připomínku
+// s QR kódem. Na jednu očekávanou platbu jde nejvýš jedna připomínka; platby,
+// které přestaly chodit před víc než --window dny po termínu, se nepřipomínají.
+// Členové s kladným zůstatkem, který pokryje obvyklou platbu, připomínku nedostanou.
+// Člen si připomínky může vypnout v profilu (sekce Upozornění).
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only print reminders that would be sent")
+	days := flag.Int("days", 7, "days after the expected payment date before reminding")
+	window := flag.Int("window", 14, "days to catch up when the job did not run")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// SendTemplated silently skips when SMTP is missing - reminders would be recorded as sent
+	if cfg.SMTPHost == "" && !*dryRun {
+		log.Fatal("SMTP not configured")
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+	qrService := qrpay.NewService(cfg.BankIBAN, cfg.BankBIC)
+	emailClient := email.New(cfg, queries, qrService)
+	ctx := context.Background()
+
+	today := time.Now()
+
+	rows, err := queries.ListMembershipPaymentsSince(ctx, today.AddDate(-1, 0, 0))
+	if err != nil {
+		log.Fatalf("Failed to list payments: %v", err)
+	}
+	history := make(map[int64][]paymentanalytics.Payment)
+	for _, row := range rows {
+		history[row.UserID.Int64] = append(history[row.UserID.Int64], paymentanalytics.Payment{
+			Date:   row.Date,
+			Amount: row.Amount,
+		})
+	}
+
+	reminders, err := queries.ListPaymentReminders(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list reminders: %v", err)
+	}
+	alreadyReminded := make(map[string]bool, len(reminders))
+	for _, r := range reminders {
+		alreadyReminded[fmt.Sprintf("%d/%s", r.UserID, r.DueDate)] = true
+	}
+
+	optOutIDs, err := queries.ListNotificationOptOuts(ctx, email.NotificationMissedPayment)
+	if err != nil {
+		log.Fatalf("Failed to list notification preferences: %v", err)
+	}
+	optedOut := make(map[int64]bool, len(optOutIDs))
+	for _, id := range optOutIDs {
+		optedOut[id] = true
+	}
+
+	users, err := queries.ListUsersByState(ctx, "accepted")
+	if err != nil {
+		log.Fatalf("Failed to list users: %v", err)
+	}
+
+	orders := 0
+	missed := 0
+	sent := 0
+	errors := 0
+
+	for _, user := range users {
+		order, ok := paymentanalytics.DetectStandingOrder(history[user.ID])
+		if !ok {
+			continue
+		}
+		orders++
+
+		if !order.Missed(today, *days) || order.Missed(today, *days+*window) {
+			continue
+		}
+		dueDate := order.NextDue().Format("2006-01-02")
+		if alreadyReminded[fmt.Sprintf("%d/%s", user.ID, dueDate)] || optedOut[user.ID] {
+			continue
+		}
+
+		balance, err := queries.GetUserBalance(ctx, db.GetUserBalanceParams{
+			UserID:   sql.NullInt64{Int64: user.ID, Valid: true},
+			UserID_2: user.ID,
+			UserID_3: sql.NullInt64{Int64: user.ID, Valid: true},
+			UserID_4: user.ID,
+			UserID_5: user.ID,
+		})
+		if err != nil {
+			log.Printf("  ⚠ Failed to get balance for %s: %v", user.Email, err)
+			errors++
+			continue
+		}
+		if money.Amount(balance) >= order.Amount {
+			continue
+		}
+		missed++
+
+		if *dryRun {
+			log.Printf("  [dry-run] %s: %s Kč expected %s (last payment %s, %d in a row)",
+				user.Email, order.Amount, dueDate, order.LastPayment.Format("2006-01-02"), order.Payments)
+			continue
+		}
+
+		if err := emailClient.SendMissedPayment(ctx, &user, order); err != nil {
+			// Not recorded, retried on the next run
+			log.Printf("  ✗ Failed to email %s: %v", user.Email, err)
+			errors++
+			continue
+		}
+		sent++
+
+		if err := queries.CreatePaymentReminder(ctx, db.CreatePaymentReminderParams{
+			UserID:  user.ID,
+			DueDate: dueDate,
+			Amount:  order.Amount,
+		}); err != nil {
+			log.Printf("  ⚠ Reminded %s but failed to record it: %v", user.Email, err)
+			errors++
+			continue
+		}
+		log.Printf("  ✉ %s: %s Kč expected %s", user.Email, order.Amount, dueDate)
+	}
+
+	if *dryRun {
+		log.Printf("Dry run, %d standing orders, %d missed payments, nothing sent", orders, missed)
+		return
+	}
+
+	log.Printf("\nSummary:")
+	log.Printf("  Standing orders: %d", orders)
+	log.Printf("  Missed payments: %d", missed)
+	log.Printf("  Reminders sent: %d", sent)
+	log.Printf("  Errors: %d", errors)
+
+	level := "success"
+	if errors > 0 {
+		level = "warning"
+	}
+	queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "cron",
+		Level:     level,
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Missed payment reminders: %d standing orders, %d reminders sent", orders, sent),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"standing_orders":%d,"missed":%d,"sent":%d,"errors":%d}`, orders, missed, sent, errors),
+			Valid:  true,
+		},
+	})
+
+	if errors > 0 {
+		log.Fatal("Job completed with errors")
+	}
+
+	log.Println("✓ Job completed successfully")
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/base48/member-portal/internal/config"
 	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
 )
 
 // Report payments that have a variable symbol but are not matched to any user
@@ -56,7 +57,7 @@ func main() {
 	log.Printf("Analyzing %d unassigned payments...\n", len(unassignedPayments))
 
 	var problematic []UnmatchedPayment
-	totalAmount := 0.0
+	var totalAmount money.Amount
 
 	for _, payment := range unassignedPayments {
 		// Skip if no identification (VS)
@@ -66,10 +67,7 @@ func main() {
 				Reason:     "Empty variable symbol",
 			})
 
-			// Parse amount
-			if amount, err := strconv.ParseFloat(payment.Amount, 64); err == nil {
-				totalAmount += amount
-			}
+			totalAmount += payment.Amount
 			continue
 		}
 
@@ -81,9 +79,7 @@ func main() {
 				Reason:     fmt.Sprintf("VS '%s' is not a valid user ID", payment.Identification),
 			})
 
-			if amount, err := strconv.ParseFloat(payment.Amount, 64); err == nil {
-				totalAmount += amount
-			}
+			totalAmount += payment.Amount
 			continue
 		}
 
@@ -97,9 +93,7 @@ func main() {
 				Reason:     fmt.Sprintf("User ID %d does not exist", vsAsID),
 			})
 
-			if amount, err := strconv.ParseFloat(payment.Amount, 64); err == nil {
-				totalAmount += amount
-			}
+			totalAmount += payment.Amount
 			continue
 		} else if err != nil {
 			log.Printf("⚠ Error checking user %d: %v", vsAsID, err)
@@ -114,9 +108,7 @@ func main() {
 			Reason:     fmt.Sprintf("User ID %d EXISTS but payment not assigned (sync bug?)", vsAsID),
 		})
 
-		if amount, err := strconv.ParseFloat(payment.Amount, 64); err == nil {
-			totalAmount += amount
-		}
+		totalAmount += payment.Amount
 	}
 
 	// Print report
@@ -125,7 +117,7 @@ func main() {
 	fmt.Println(repeat("=", 120))
 	fmt.Printf("\nTotal unassigned payments: %d\n", len(unassignedPayments))
 	fmt.Printf("Problematic payments: %d\n", len(problematic))
-	fmt.Printf("Total unmatched amount: %.2f CZK\n\n", totalAmount)
+	fmt.Printf("Total unmatched amount: %s CZK\n\n", totalAmount)
 
 	if len(problematic) == 0 {
 		fmt.Println("✓ No problematic payments found!")
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/email"
+	"github.com/base48/member-portal/internal/qrpay"
+)
+
+// Hromadné emailové kampaně s evidencí doručení pro každého příjemce
+//
+// Použití:
+//   # Vytvoření kampaně (příjemci se zafixují podle stavu členství)
+//   go run cmd/cron/send_email_campaign.go --create --name "Valná hromada 2026" \
+//       --subject "Pozvánka na valnou hromadu" --body-file pozvanka.txt --audience accepted
+//
+//   # Odeslání / pokračování přerušené kampaně (odeslaným se znovu neposílá)
+//   go run cmd/cron/send_email_campaign.go --campaign 3 --delay 2s
+//
+//   # Přehled kampaní
+//   go run cmd/cron/send_email_campaign.go --list
+//
+// Příjemci ve stavu 'sending' (pád uprostřed odesílání) se automaticky neopakují,
+// protože email mohl odejít. Po ověření je lze vrátit do fronty přes --requeue-interrupted.
+
+func main() {
+	create := flag.Bool("create", false, "create a new campaign")
+	list := flag.Bool("list", false, "list campaigns with delivery stats")
+	name := flag.String("name", "", "campaign name (with --create)")
+	subject := flag.String("subject", "", "email subject (with --create)")
+	templateName := flag.String("template", "campaign.html", "email template in web/templates/email (with --create)")
+	bodyFile := flag.String("body-file", "", "text file with the email body (with --create)")
+	audience := flag.String("audience", "accepted", "user state to send to (with --create)")
+	campaignID := flag.Int64("campaign", 0, "campaign ID to send or resume")
+	delay := flag.Duration("delay", 2*time.Second, "pause between emails")
+	maxAttempts := flag.Int64("max-attempts", 3, "retry failed recipients until this many attempts")
+	requeue := flag.Bool("requeue-interrupted", false, "retry recipients interrupted mid-send (may double-send)")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+
+	// Ctrl+C stops after the current recipient, the campaign can be resumed later
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch {
+	case *list:
+		listCampaigns(ctx, queries)
+
+	case *create:
+		if *name == "" || *subject == "" {
+			log.Fatal("--name and --subject are required")
+		}
+
+		var body sql.NullString
+		if *bodyFile != "" {
+			content, err := os.ReadFile(*bodyFile)
+			if err != nil {
+				log.Fatalf("Failed to read body file: %v", err)
+			}
+			body = sql.NullString{String: string(content), Valid: true}
+		}
+
+		campaign, err := queries.CreateEmailCampaign(ctx, db.CreateEmailCampaignParams{
+			Name:         *name,
+			Subject:      *subject,
+			TemplateName: *templateName,
+			Body:         body,
+			Audience:     *audience,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create campaign: %v", err)
+		}
+
+		count, err := queries.AddEmailCampaignRecipients(ctx, db.AddEmailCampaignRecipientsParams{
+			CampaignID: campaign.ID,
+			State:      *audience,
+		})
+		if err != nil {
+			log.Fatalf("Failed to add recipients: %v", err)
+		}
+
+		log.Printf("✓ Created campaign #%d '%s' with %d recipients", campaign.ID, campaign.Name, count)
+		log.Printf("  Send it with: go run cmd/cron/send_email_campaign.go --campaign %d", campaign.ID)
+
+	case *campaignID > 0:
+		if *requeue {
+			count, err := queries.RequeueInterruptedEmailCampaignRecipients(ctx, *campaignID)
+			if err != nil {
+				log.Fatalf("Failed to requeue recipients: %v", err)
+			}
+			log.Printf("↻ Requeued %d interrupted recipients", count)
+		}
+
+		qrService := qrpay.NewService(cfg.BankIBAN, cfg.BankBIC)
+		emailClient := email.New(cfg, queries, qrService)
+
+		result, err := emailClient.RunCampaign(ctx, *campaignID, email.CampaignOptions{
+			Delay:       *delay,
+			MaxAttempts: *maxAttempts,
+		})
+		if err != nil {
+			log.Fatalf("Campaign failed: %v", err)
+		}
+
+		log.Println("\nSummary:")
+		log.Printf("  Sent: %d", result.Sent)
+		log.Printf("  Failed: %d", result.Failed)
+		if result.Interrupted > 0 {
+			log.Printf("  ⚠ Interrupted mid-send: %d (check delivery, then use --requeue-interrupted)", result.Interrupted)
+		}
+		if result.Completed {
+			log.Println("  ✓ Campaign completed")
+		} else {
+			log.Println("  ⏸ Campaign not finished - run again to resume")
+		}
+
+		level := "success"
+		if result.Failed > 0 || !result.Completed {
+			level = "warning"
+		}
+		queries.CreateLog(context.WithoutCancel(ctx), db.CreateLogParams{
+			Subsystem: "cron",
+			Level:     level,
+			UserID:    sql.NullInt64{},
+			Message:   fmt.Sprintf("Email campaign #%d run: %d sent, %d failed", *campaignID, result.Sent, result.Failed),
+			Metadata:  sql.NullString{String: fmt.Sprintf(`{"campaign_id":%d,"sent":%d,"failed":%d,"interrupted":%d,"completed":%t}`, *campaignID, result.Sent, result.Failed, result.Interrupted, result.Completed), Valid: true},
+		})
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// listCampaigns prints all campaigns with per-status recipient counts
+func listCampaigns(ctx context.Context, queries *db.Queries) {
+	campaigns, err := queries.ListEmailCampaigns(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list campaigns: %v", err)
+	}
+
+	for _, campaign := range campaigns {
+		stats, err := queries.GetEmailCampaignStats(ctx, campaign.ID)
+		if err != nil {
+			log.Fatalf("Failed to load stats: %v", err)
+		}
+
+		counts := ""
+		for _, s := range stats {
+			counts += fmt.Sprintf(" %s=%d", s.Status, s.Count)
+		}
+
+		log.Printf("#%d [%s] %s (%s, %s):%s", campaign.ID, campaign.Status, campaign.Name,
+			campaign.Audience, campaign.CreatedAt.Format("2006-01-02"), counts)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/email"
+	"github.com/base48/member-portal/internal/invoice"
+	"github.com/base48/member-portal/internal/qrpay"
+	"github.com/base48/member-portal/internal/statement"
+)
+
+// Roční přehled plateb členům
+//
+// Každému, kdo v daném roce něco zaplatil nebo měl příspěvek či poplatek (i bývalým
+// členům), pošle přehled plateb a příspěvků za rok s PDF v příloze. Odeslané přehledy
+// se evidují v payment_statements, úloha tak může v lednu běžet denně a dohánět
+// neúspěšné pokusy. Člen si přehled může vypnout v profilu (sekce Upozornění),
+// stáhnout si ho může kdykoliv v profilu.
+//
+// Použití:
+//   go run cmd/cron/send_payment_statements.go
+//   go run cmd/cron/send_payment_statements.go --year 2025 --dry-run
+//
+// Nebo v crontab (denně v lednu, za předchozí rok):
+//   0 9 * 1 * cd /path/to/portal && ./send_payment_statements >> logs/cron.log 2>&1
+
+func main() {
+	year := flag.Int("year", time.Now().Year()-1, "year of the statements")
+	dryRun := flag.Bool("dry-run", false, "only print members who would get a statement")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// SendTemplated silently skips when SMTP is missing - statements would be recorded as sent
+	if cfg.SMTPHost == "" && !*dryRun {
+		log.Fatal("SMTP not configured")
+	}
+
+	if *year >= time.Now().Year() {
+		log.Fatalf("Year %d has not ended yet", *year)
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+	qrService := qrpay.NewService(cfg.BankIBAN, cfg.BankBIC)
+	emailClient := email.New(cfg, queries, qrService)
+	issuer := invoice.IssuerFromConfig(cfg)
+	ctx := context.Background()
+
+	sentStatements, err := queries.ListPaymentStatementsByYear(ctx, int64(*year))
+	if err != nil {
+		log.Fatalf("Failed to list sent statements: %v", err)
+	}
+	alreadySent := make(map[int64]bool, len(sentStatements))
+	for _, s := range sentStatements {
+		alreadySent[s.UserID] = true
+	}
+
+	optOutIDs, err := queries.ListNotificationOptOuts(ctx, email.NotificationPaymentStatement)
+	if err != nil {
+		log.Fatalf("Failed to list notification preferences: %v", err)
+	}
+	optedOut := make(map[int64]bool, len(optOutIDs))
+	for _, id := range optOutIDs {
+		optedOut[id] = true
+	}
+
+	users, err := queries.ListUsers(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list users: %v", err)
+	}
+
+	log.Printf("Sending payment statements for %d...", *year)
+
+	sent := 0
+	skipped := 0
+	errors := 0
+	for _, user := range users {
+		if alreadySent[user.ID] {
+			continue
+		}
+
+		s, err := statement.Load(ctx, queries, user, *year)
+		if err != nil {
+			log.Printf("  ✗ Failed to load statement of %s: %v", user.Email, err)
+			errors++
+			continue
+		}
+		if s.Empty() {
+			continue
+		}
+		if optedOut[user.ID] {
+			skipped++
+			continue
+		}
+
+		if *dryRun {
+			log.Printf("  [dry-run] %s: paid %s Kč, fees %s Kč, closing balance %s Kč", user.Email, s.Paid, s.Charged, s.Closing)
+			sent++
+			continue
+		}
+
+		if err := emailClient.SendPaymentStatement(ctx, &user, s, statement.RenderPDF(s, issuer)); err != nil {
+			// Not recorded, retried on the next run
+			log.Printf("  ✗ Failed to email %s: %v", user.Email, err)
+			errors++
+			continue
+		}
+		if err := queries.CreatePaymentStatement(ctx, db.CreatePaymentStatementParams{
+			UserID: user.ID,
+			Year:   int64(*year),
+		}); err != nil {
+			log.Printf("  ⚠ Sent statement to %s but failed to record it: %v", user.Email, err)
+			errors++
+			continue
+		}
+		log.Printf("  ✓ %s", user.Email)
+		sent++
+	}
+
+	if *dryRun {
+		log.Printf("Dry run, %d statements would be sent (%d opted out), nothing sent", sent, skipped)
+		return
+	}
+
+	log.Printf("\nSummary:")
+	log.Printf("  Statements sent: %d", sent)
+	log.Printf("  Already sent before: %d", len(sentStatements))
+	log.Printf("  Opted out: %d", skipped)
+	log.Printf("  Errors: %d", errors)
+
+	level := "success"
+	if errors > 0 {
+		level = "warning"
+	}
+	queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "cron",
+		Level:     level,
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Payment statements %d: %d sent, %d opted out, %d errors", *year, sent, skipped, errors),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"year":%d,"sent":%d,"opted_out":%d,"errors":%d}`, *year, sent, skipped, errors),
+			Valid:  true,
+		},
+	})
+
+	if errors > 0 {
+		log.Fatal("Job completed with errors")
+	}
+
+	log.Println("✓ Job completed successfully")
+}
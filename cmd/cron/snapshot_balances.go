@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/balance"
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+)
+
+// Měsíční snímky bilance všech členů
+//
+// Pro každého člena přepočítá z celé historie (platby, příspěvky, další poplatky,
+// úpravy bilance) zůstatek na konci každého uzavřeného měsíce a uloží ho do
+// balance_snapshots. Seznam členů v adminu pak sčítá jen snímek a položky od začátku
+// aktuálního měsíce; zpětně zapsané položky se do snímků promítnou dalším během.
+//
+// Použití:
+//   go run cmd/cron/snapshot_balances.go
+//
+// Nebo v crontab (denně po bankovním sync):
+//   45 3 * * * cd /path/to/portal && ./snapshot_balances >> logs/cron.log 2>&1
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+	ctx := context.Background()
+
+	// Poslední uzavřený měsíc - aktuální měsíc se dopočítává při zobrazení
+	now := time.Now().UTC()
+	lastMonth := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+
+	users, err := queries.ListUsers(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list users: %v", err)
+	}
+
+	log.Printf("Snapshotting balances of %d users through %s...", len(users), lastMonth.Format("2006-01"))
+
+	snapshots := 0
+	errors := 0
+	for _, user := range users {
+		months, err := balance.Snapshot(ctx, database, queries, user.ID, lastMonth)
+		if err != nil {
+			log.Printf("✗ Failed to snapshot balance of %s: %v", user.Email, err)
+			errors++
+			continue
+		}
+		snapshots += months
+	}
+
+	log.Printf("\nSummary:")
+	log.Printf("  Users: %d", len(users))
+	log.Printf("  Monthly snapshots: %d", snapshots)
+	log.Printf("  Errors: %d", errors)
+
+	level := "success"
+	if errors > 0 {
+		level = "warning"
+	}
+	queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "cron",
+		Level:     level,
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Balance snapshots through %s: %d users, %d errors", lastMonth.Format("2006-01"), len(users), errors),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"month":"%s","users":%d,"snapshots":%d,"errors":%d}`, lastMonth.Format("2006-01"), len(users), snapshots, errors), Valid: true},
+	})
+
+	if errors > 0 {
+		log.Fatal("Job completed with errors")
+	}
+
+	log.Println("✓ Job completed successfully")
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/auth"
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/email"
+	"github.com/base48/member-portal/internal/fees"
+	"github.com/base48/member-portal/internal/keycloak"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/base48/member-portal/internal/qrpay"
+)
+
+// Automatické pozastavení členství dlužníků
+//
+// Aktivní členy (accepted), jejichž dluh přesahuje práh pozastavení v měsíčních
+// příspěvcích (SUSPENSION_DEBT_MONTHS nebo admin nastavení; u čtvrtletní / roční platby
+// navíc předepsané měsíce dopředu), přepne do stavu suspended, zablokuje jim Keycloak
+// účet a pošle email. Každý krok jde do system logu. Členové s výjimkou (admin v profilu
+// člena nebo --exempt) a členové, kteří dodržují splátkový kalendář, se přeskočí.
+//
+// Použití:
+//   # Náhled bez změn
+//   go run cmd/cron/suspend_debtors.go --dry-run
+//
+//   go run cmd/cron/suspend_debtors.go --exempt jan@example.com,42
+//
+// Nebo v crontab (denně po bankovním sync):
+//   30 3 * * * cd /path/to/portal && ./suspend_debtors >> logs/cron.log 2>&1
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only list members that would be suspended")
+	exemptFlag := flag.String("exempt", "", "comma-separated emails or user IDs to skip in this run")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.KeycloakServiceAccountClientID == "" || cfg.KeycloakServiceAccountClientSecret == "" {
+		log.Fatal("KEYCLOAK_SERVICE_ACCOUNT_CLIENT_ID and KEYCLOAK_SERVICE_ACCOUNT_CLIENT_SECRET are required")
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+	ctx := context.Background()
+
+	// Výjimky: trvalé z DB a jednorázové z příkazové řádky
+	exemptIDs := make(map[int64]string)
+	exemptions, err := queries.ListSuspensionExemptions(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list suspension exemptions: %v", err)
+	}
+	for _, exemption := range exemptions {
+		exemptIDs[exemption.UserID] = exemption.Reason
+	}
+	exemptEmails := make(map[string]bool)
+	for _, value := range strings.Split(*exemptFlag, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+			exemptIDs[id] = "--exempt"
+		} else {
+			exemptEmails[strings.ToLower(value)] = true
+		}
+	}
+
+	ladder, err := fees.LoadDebtLadder(ctx, queries, fees.DefaultDebtLadder(cfg))
+	if err != nil {
+		log.Fatalf("Failed to load debt thresholds: %v", err)
+	}
+	months := ladder.Suspension
+
+	// Splátkové kalendáře
+	activePlans, err := queries.ListActivePaymentPlans(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list payment plans: %v", err)
+	}
+	plans := make(map[int64]db.PaymentPlan)
+	for _, plan := range activePlans {
+		plans[plan.UserID] = plan
+	}
+
+	users, err := queries.ListUsersByState(ctx, "accepted")
+	if err != nil {
+		log.Fatalf("Failed to list users: %v", err)
+	}
+
+	log.Printf("Checking %d accepted members (limit: %d monthly fees)...", len(users), months)
+
+	var kcClient *keycloak.Client
+	var emailClient *email.Client
+	if !*dryRun {
+		serviceClient, err := auth.NewServiceAccountClient(
+			ctx,
+			cfg,
+			cfg.KeycloakServiceAccountClientID,
+			cfg.KeycloakServiceAccountClientSecret,
+		)
+		if err != nil {
+			log.Fatalf("Failed to create service account: %v", err)
+		}
+		log.Println("✓ Service account authenticated")
+
+		kcClient = keycloak.NewClientWithTokenProvider(cfg, serviceClient)
+		emailClient = email.New(cfg, queries, qrpay.NewService(cfg.BankIBAN, cfg.BankBIC))
+	}
+
+	levels := make(map[int64]db.Level)
+	suspended := 0
+	exempted := 0
+	onPlan := 0
+	errors := 0
+
+	for _, user := range users {
+		balance, err := queries.GetUserBalance(ctx, db.GetUserBalanceParams{
+			UserID:   sql.NullInt64{Int64: user.ID, Valid: true},
+			UserID_2: user.ID,
+			UserID_3: sql.NullInt64{Int64: user.ID, Valid: true},
+			UserID_4: user.ID,
+			UserID_5: user.ID,
+		})
+		if err != nil {
+			log.Printf("⚠ Error getting balance for %s: %v", user.Email, err)
+			errors++
+			continue
+		}
+		if balance >= 0 {
+			continue
+		}
+
+		level, ok := levels[user.LevelID]
+		if !ok {
+			level, err = queries.GetLevel(ctx, user.LevelID)
+			if err != nil {
+				log.Printf("⚠ Error getting level of %s: %v", user.Email, err)
+				errors++
+				continue
+			}
+			levels[user.LevelID] = level
+		}
+		monthly := fees.EffectiveAmount(user.LevelActualAmount, level.Amount)
+		if monthly == 0 {
+			continue // free level, no fees to owe
+		}
+
+		lastFee, err := queries.GetLastFeeByUser(ctx, user.ID)
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("⚠ Error getting last fee of %s: %v", user.Email, err)
+			errors++
+			continue
+		}
+
+		debt := money.Amount(-balance)
+		limit := fees.DebtLimit(monthly, months, lastFee)
+		if debt <= limit {
+			continue
+		}
+
+		reason, exempt := exemptIDs[user.ID]
+		if exemptEmails[strings.ToLower(user.Email)] {
+			exempt, reason = true, "--exempt"
+		}
+		if exempt {
+			if reason == "" {
+				reason = "no reason given"
+			}
+			log.Printf("  ⊘ %s: debt %s Kč over limit %s Kč, exempt (%s)", user.Email, debt, limit, reason)
+			exempted++
+			continue
+		}
+
+		plan, hasPlan := plans[user.ID]
+		if hasPlan && fees.OnPlan(plan, debt, time.Now()) {
+			log.Printf("  ⊘ %s: debt %s Kč over limit %s Kč, keeps to payment plan #%d", user.Email, debt, limit, plan.ID)
+			onPlan++
+			continue
+		}
+		if hasPlan {
+			log.Printf("  ⚠ %s: payment plan #%d not kept", user.Email, plan.ID)
+		}
+
+		if *dryRun {
+			log.Printf("  - %s: would suspend (debt %s Kč, limit %s Kč)", user.Email, debt, limit)
+			suspended++
+			continue
+		}
+
+		if err := queries.UpdateUserState(ctx, db.UpdateUserStateParams{
+			State: "suspended",
+			ID:    user.ID,
+		}); err != nil {
+			log.Printf("✗ Failed to suspend %s: %v", user.Email, err)
+			errors++
+			continue
+		}
+		log.Printf("✓ Suspended %s (debt %s Kč, limit %s Kč)", user.Email, debt, limit)
+		suspended++
+		queries.CreateLog(ctx, db.CreateLogParams{
+			Subsystem: "membership",
+			Level:     "warning",
+			UserID:    sql.NullInt64{Int64: user.ID, Valid: true},
+			Message:   fmt.Sprintf("Membership of %s suspended for debt: %s Kč (limit %s Kč)", user.Email, debt, limit),
+			Metadata:  sql.NullString{String: fmt.Sprintf(`{"debt":%q,"limit":%q,"months":%d}`, debt, limit, months), Valid: true},
+		})
+
+		// Keycloak účet - bez něj se člen do služeb nepřihlásí (role stavu řeší sync_membership_roles)
+		if user.KeycloakID.Valid && user.KeycloakID.String != "" {
+			if err := kcClient.DisableUser(ctx, user.KeycloakID.String); err != nil {
+				log.Printf("  ✗ Failed to disable Keycloak account of %s: %v", user.Email, err)
+				errors++
+				queries.CreateLog(ctx, db.CreateLogParams{
+					Subsystem: "keycloak",
+					Level:     "error",
+					UserID:    sql.NullInt64{Int64: user.ID, Valid: true},
+					Message:   fmt.Sprintf("Failed to disable Keycloak account of suspended member %s: %v", user.Email, err),
+					Metadata:  sql.NullString{String: fmt.Sprintf(`{"keycloak_id":%q}`, user.KeycloakID.String), Valid: true},
+				})
+			} else {
+				log.Printf("  ✓ Disabled Keycloak account")
+				queries.CreateLog(ctx, db.CreateLogParams{
+					Subsystem: "keycloak",
+					Level:     "info",
+					UserID:    sql.NullInt64{Int64: user.ID, Valid: true},
+					Message:   fmt.Sprintf("Keycloak account of suspended member %s disabled", user.Email),
+					Metadata:  sql.NullString{String: fmt.Sprintf(`{"keycloak_id":%q}`, user.KeycloakID.String), Valid: true},
+				})
+			}
+		}
+
+		// Email (odeslání i chybu loguje email klient)
+		reason = fmt.Sprintf("Dluh na členském příspěvku %s Kč přesahuje povolený limit %s Kč.", debt, limit)
+		if err := emailClient.SendMembershipSuspended(ctx, &user, reason); err != nil {
+			log.Printf("  ⚠ Failed to send suspension email: %v", err)
+		} else {
+			log.Printf("  ✉ Sent suspension email")
+		}
+	}
+
+	log.Printf("\nSummary:")
+	log.Printf("  Accepted members: %d", len(users))
+	log.Printf("  Suspended: %d", suspended)
+	log.Printf("  Exempt: %d", exempted)
+	log.Printf("  On payment plan: %d", onPlan)
+	log.Printf("  Errors: %d", errors)
+
+	if *dryRun {
+		log.Println("✓ Dry run - no changes made")
+		return
+	}
+
+	level := "success"
+	if errors > 0 {
+		level = "warning"
+	}
+	queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "cron",
+		Level:     level,
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Debtor suspension: %d suspended, %d exempt, %d on payment plan, %d errors", suspended, exempted, onPlan, errors),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"suspended":%d,"exempt":%d,"on_plan":%d,"errors":%d,"months":%d}`, suspended, exempted, onPlan, errors, months), Valid: true},
+	})
+
+	if errors > 0 {
+		log.Fatal("Job completed with errors")
+	}
+
+	log.Println("✓ Job completed successfully")
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fakturoid"
+	"github.com/base48/member-portal/internal/invoice"
+)
+
+// Fakturoid - faktury na příspěvky členům, za které platí firma
+//
+// Členům s fakturací přes Fakturoid (zapíná admin v profilu člena, člen musí mít
+// vyplněné fakturační údaje) vystaví ve Fakturoidu fakturu na každý členský příspěvek
+// vzniklý po zapnutí a u otevřených faktur stáhne stav placení. Faktura nese VS člena,
+// platba firmy se tak bankovní synchronizací připíše členovi jako každá jiná.
+// Firma se ve Fakturoidu založí jako kontakt s první fakturou.
+//
+// Použití:
+//   go run cmd/cron/sync_fakturoid.go
+//   go run cmd/cron/sync_fakturoid.go --dry-run
+//
+// Nebo v crontab (denně po synchronizaci plateb):
+//   0 4 * * * cd /path/to/portal && ./sync_fakturoid >> logs/cron.log 2>&1
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only print fees that would be invoiced, do not call Fakturoid")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.FakturoidEnabled() {
+		log.Fatal("Fakturoid not configured (FAKTUROID_SLUG, FAKTUROID_CLIENT_ID, FAKTUROID_CLIENT_SECRET)")
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+	client := fakturoid.NewClient(cfg.FakturoidSlug, cfg.FakturoidClientID, cfg.FakturoidClientSecret, cfg.SupportEmail)
+	ctx := context.Background()
+
+	fees, err := queries.ListFeesToInvoiceInFakturoid(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list fees to invoice: %v", err)
+	}
+
+	log.Printf("Invoicing %d fees in Fakturoid...", len(fees))
+
+	issued := 0
+	errors := 0
+	subjects := make(map[int64]int64) // user ID -> subject ID created in this run
+	for _, fee := range fees {
+		memberName := fee.Email
+		if fee.Realname.Valid && fee.Realname.String != "" {
+			memberName = fee.Realname.String
+		}
+		item := invoice.FeeItem(db.Fee{PeriodStart: fee.PeriodStart, Months: fee.Months}) + " - " + memberName
+
+		if *dryRun {
+			log.Printf("  [dry-run] %s (%s): %s, %s Kč", fee.CompanyName, fee.Email, item, fee.Amount)
+			issued++
+			continue
+		}
+
+		customID := fmt.Sprintf("fee-%d", fee.FeeID)
+		inv, err := client.FindInvoice(ctx, customID)
+		if err != nil {
+			log.Printf("  ✗ Failed to look up invoice %s: %v", customID, err)
+			errors++
+			continue
+		}
+
+		if inv == nil {
+			subjectID, ok := subjects[fee.UserID]
+			if !ok && fee.FakturoidSubjectID.Valid {
+				subjectID, ok = fee.FakturoidSubjectID.Int64, true
+			}
+			if !ok {
+				street, zip, city := fakturoid.SplitAddress(fee.Address)
+				subjectID, err = client.CreateSubject(ctx, fakturoid.Subject{
+					Name:           fee.CompanyName,
+					Street:         street,
+					City:           city,
+					Zip:            zip,
+					RegistrationNo: fee.CompanyID,
+					VatNo:          fee.VatID,
+					Email:          fee.Email,
+				})
+				if err != nil {
+					log.Printf("  ✗ Failed to create contact %s for %s: %v", fee.CompanyName, fee.Email, err)
+					errors++
+					continue
+				}
+				subjects[fee.UserID] = subjectID
+				if err := queries.SetBillingFakturoidSubject(ctx, db.SetBillingFakturoidSubjectParams{
+					FakturoidSubjectID: sql.NullInt64{Int64: subjectID, Valid: true},
+					UserID:             fee.UserID,
+				}); err != nil {
+					log.Printf("  ⚠ Failed to record contact %d of %s: %v", subjectID, fee.Email, err)
+				}
+			}
+
+			inv, err = client.CreateInvoice(ctx, fakturoid.InvoiceParams{
+				SubjectID:      subjectID,
+				CustomID:       customID,
+				VariableSymbol: fee.PaymentsID.String, // the payment counts in the member's balance
+				Due:            cfg.InvoiceDueDays,
+				Lines:          []fakturoid.Line{{Name: item, Quantity: 1, UnitPrice: fee.Amount}},
+			})
+			if err != nil {
+				log.Printf("  ✗ Failed to invoice fee #%d of %s: %v", fee.FeeID, fee.Email, err)
+				errors++
+				continue
+			}
+		}
+
+		if err := queries.CreateFakturoidInvoice(ctx, db.CreateFakturoidInvoiceParams{
+			FeeID:       fee.FeeID,
+			UserID:      fee.UserID,
+			FakturoidID: inv.ID,
+			Number:      inv.Number,
+			Status:      inv.Status,
+			PublicUrl:   inv.PublicHTMLURL,
+			PaidOn:      paidOn(inv),
+		}); err != nil {
+			// Picked up by FindInvoice on the next run
+			log.Printf("  ⚠ Issued invoice %s to %s but failed to record it: %v", inv.Number, fee.Email, err)
+			errors++
+			continue
+		}
+		log.Printf("  ✓ %s: invoice %s (%s Kč)", fee.Email, inv.Number, fee.Amount)
+		issued++
+	}
+
+	if *dryRun {
+		log.Printf("Dry run, %d fees would be invoiced, nothing sent to Fakturoid", issued)
+		return
+	}
+
+	// Payment status of the invoices that are still open
+	open, err := queries.ListOpenFakturoidInvoices(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list open invoices: %v", err)
+	}
+	paid := 0
+	for _, stored := range open {
+		inv, err := client.GetInvoice(ctx, stored.FakturoidID)
+		if err != nil {
+			log.Printf("  ✗ Failed to fetch invoice %s: %v", stored.Number, err)
+			errors++
+			continue
+		}
+		if err := queries.UpdateFakturoidInvoiceStatus(ctx, db.UpdateFakturoidInvoiceStatusParams{
+			Status: inv.Status,
+			PaidOn: paidOn(inv),
+			FeeID:  stored.FeeID,
+		}); err != nil {
+			log.Printf("  ✗ Failed to update invoice %s: %v", stored.Number, err)
+			errors++
+			continue
+		}
+		if inv.Status != stored.Status {
+			log.Printf("  ✓ Invoice %s: %s → %s", stored.Number, stored.Status, inv.Status)
+		}
+		if inv.Status == fakturoid.StatusPaid {
+			paid++
+		}
+	}
+
+	log.Printf("\nSummary:")
+	log.Printf("  Invoices issued: %d", issued)
+	log.Printf("  Open invoices checked: %d (%d paid since the last run)", len(open), paid)
+	log.Printf("  Errors: %d", errors)
+
+	level := "success"
+	if errors > 0 {
+		level = "warning"
+	}
+	queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "cron",
+		Level:     level,
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Fakturoid sync: %d invoices issued, %d paid, %d errors", issued, paid, errors),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"issued":%d,"checked":%d,"paid":%d,"errors":%d}`, issued, len(open), paid, errors),
+			Valid:  true,
+		},
+	})
+
+	if errors > 0 {
+		log.Fatal("Job completed with errors")
+	}
+
+	log.Println("✓ Job completed successfully")
+}
+
+// paidOn is the payment date of a paid invoice
+func paidOn(inv *fakturoid.Invoice) sql.NullTime {
+	date, err := time.Parse("2006-01-02", inv.PaidOn)
+	if err != nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: date, Valid: true}
+}
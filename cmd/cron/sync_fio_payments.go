@@ -3,30 +3,50 @@ package main
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	_ "modernc.org/sqlite"
 
+	"github.com/base48/member-portal/internal/balance"
+	"github.com/base48/member-portal/internal/bank"
+	"github.com/base48/member-portal/internal/bankimport"
 	"github.com/base48/member-portal/internal/config"
 	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/email"
 	"github.com/base48/member-portal/internal/fio"
+	"github.com/base48/member-portal/internal/matrix"
+	"github.com/base48/member-portal/internal/money"
 )
 
-// Sync payments from FIO Bank API to local database
+// Sync payments from the bank API to local database (FIO, or Raiffeisenbank
+// with BANK_PROVIDER=raiffeisen)
 //
 // Usage:
-//   go run cmd/cron/sync_fio_payments.go
+//   go run cmd/cron/sync_fio_payments.go              # Fetch last 85 days
 //   go run cmd/cron/sync_fio_payments.go --since-last  # Fetch only new transactions
 //   go run cmd/cron/sync_fio_payments.go --days 7      # Fetch last 7 days
 //
 // Nebo v crontab (každý den ve 3:00):
 //   0 3 * * * cd /path/to/portal && ./sync_fio_payments --since-last >> logs/fio-sync.log 2>&1
+//
+// Zarážku FIO ("poslední stažení") posouvá jen plně úspěšný běh: po stažení za období
+// se nastaví na předchozí den, --since-last ji posune samo stažením. Při chybě zůstane
+// zarážka tam, kde byla (--since-last ji vrátí před stažené pohyby), a do system logu
+// se zapíše chyba - další --since-last běh stáhne stejné pohyby znovu.
+//
+// Raiffeisenbank zarážku nemá: stahuje se vždy období (--days), už uložené pohyby
+// se podle reference banky jen aktualizují.
 
 func main() {
+	sinceLast := flag.Bool("since-last", false, "Fetch only transactions since the FIO checkpoint (last download)")
+	days := flag.Int("days", 85, "Fetch transactions of the last N days (FIO API allows at most 90)")
+	flag.Parse()
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
@@ -37,9 +57,15 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// Check FIO token
-	if cfg.BankFIOToken == "" {
-		log.Fatal("BANK_FIO_TOKEN is required")
+	// Bank account provider (BANK_PROVIDER, FIO by default)
+	provider, err := bank.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up bank provider: %v", err)
+	}
+	// Only FIO keeps a checkpoint of downloaded transactions
+	checkpointer, hasCheckpoint := provider.(bank.CheckpointProvider)
+	if *sinceLast && !hasCheckpoint {
+		log.Fatalf("--since-last is not supported by bank provider %s, use --days", provider.Name())
 	}
 
 	// Connect to database
@@ -52,254 +78,301 @@ func main() {
 	queries := db.New(database)
 	ctx := context.Background()
 
-	// Create FIO API client
-	fioClient := fio.NewClient(cfg.BankFIOToken)
-
 	// Determine which transactions to fetch
-	var transactions []fio.Transaction
+	var transactions []bank.Transaction
 	var fetchErr error
 
 	// Default: fetch last 85 days (FIO API limit is 90, using 85 for safety margin)
-	// You can modify this based on command line arguments
-	daysBack := 85
-	dateFrom := time.Now().AddDate(0, 0, -daysBack)
+	dateFrom := time.Now().AddDate(0, 0, -*days)
 	dateTo := time.Now()
 
-	log.Printf("Fetching FIO transactions from %s to %s...",
-		fio.FormatDate(dateFrom), fio.FormatDate(dateTo))
-
-	transactions, fetchErr = fioClient.FetchTransactionsByPeriod(
-		ctx,
-		fio.FormatDate(dateFrom),
-		fio.FormatDate(dateTo),
-	)
+	if *sinceLast {
+		log.Println("Fetching FIO transactions since the last download...")
+		transactions, fetchErr = checkpointer.FetchSinceCheckpoint(ctx)
+	} else {
+		log.Printf("Fetching %s transactions from %s to %s...",
+			provider.Name(), fio.FormatDate(dateFrom), fio.FormatDate(dateTo))
+		transactions, fetchErr = provider.FetchTransactions(ctx, dateFrom, dateTo)
+	}
 
 	if fetchErr != nil {
 		log.Fatalf("Failed to fetch transactions: %v", fetchErr)
 	}
 
-	log.Printf("Fetched %d transactions from FIO API", len(transactions))
+	log.Printf("Fetched %d transactions from %s", len(transactions), provider.Name())
+
+	// fail keeps the FIO checkpoint where it was before this run, records the failure
+	// in the system log (admin logs) and exits with an error
+	fail := func(message string) {
+		checkpoint := "no checkpoint"
+		if hasCheckpoint {
+			checkpoint = "FIO checkpoint left unchanged"
+		}
+		if *sinceLast {
+			if err := restoreCheckpoint(ctx, checkpointer, transactions); err != nil {
+				checkpoint = fmt.Sprintf("failed to restore FIO checkpoint (%v), run sync_fio_payments --days %d to catch up", err, *days)
+			} else {
+				checkpoint = "FIO checkpoint restored, the next run retries the same transactions"
+			}
+		}
+		log.Printf("✗ %s; %s", message, checkpoint)
+		queries.CreateLog(ctx, db.CreateLogParams{
+			Subsystem: "fio_sync",
+			Level:     "error",
+			UserID:    sql.NullInt64{},
+			Message:   fmt.Sprintf("Bank sync (%s) failed: %s; %s", provider.Name(), message, checkpoint),
+			Metadata:  sql.NullString{String: fmt.Sprintf(`{"since_last":%t,"fetched":%d}`, *sinceLast, len(transactions)), Valid: true},
+		})
+		log.Fatal("Job completed with errors")
+	}
 
 	if len(transactions) == 0 {
+		if !*sinceLast && hasCheckpoint {
+			advanceCheckpoint(ctx, queries, checkpointer, dateTo)
+		}
 		log.Println("✓ No new transactions to sync")
 		return
 	}
 
-	// Process transactions
-	inserted := 0
-	updated := 0
-	skipped := 0
-	errors := 0
-	unmatchedVS := []fio.Transaction{}
-	emptyVS := []fio.Transaction{}
+	// Writes are serialized with the server and other balance jobs (the lock
+	// expires on its own if the job dies on log.Fatal)
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	lock, err := balance.Acquire(waitCtx, queries, balance.LockName, "sync_fio_payments")
+	cancel()
+	if err != nil {
+		fail(fmt.Sprintf("failed to acquire balance lock: %v", err))
+	}
+	defer lock.Release(ctx)
 
-	for _, tx := range transactions {
-		// Skip transactions with zero or negative amounts (outgoing payments, fees, etc.)
-		// Only process incoming payments (positive amounts)
-		if tx.Amount <= 0 {
-			skipped++
-			continue
-		}
+	summary, err := bankimport.Import(ctx, queries, transactions, bankimport.Options{
+		AutoLinkByAccount: cfg.FIOAutoLinkByAccount,
+	})
+	if err != nil {
+		lock.Release(ctx) // log.Fatal skips deferred calls
+		fail(err.Error())
+	}
 
-		// Try to match user by variable symbol (payments_id)
-		// IMPORTANT: VS is NOT the user.id, it's the user.payments_id!
-		// Edge case: Some users put VS in Message field instead of VS field
-		variableSymbol := tx.VariableSymbol
-		if variableSymbol == "" && tx.Message != "" {
-			// Check if Message contains only digits (likely a VS)
-			isNumeric := true
-			for _, ch := range tx.Message {
-				if ch < '0' || ch > '9' {
-					isNumeric = false
-					break
-				}
-			}
-			if isNumeric {
-				variableSymbol = tx.Message
-				log.Printf("ℹ Using Message field as VS: '%s' (%.2f CZK from %s)", variableSymbol, tx.Amount, tx.AccountName)
-			}
-		}
+	summary.LogReport("SYNC SUMMARY")
 
-		var userID sql.NullInt64
-		if variableSymbol != "" {
-			// Look up user by payments_id (VS), not by user.id
-			if user, err := queries.GetUserByPaymentsID(ctx, sql.NullString{String: variableSymbol, Valid: true}); err == nil {
-				userID = sql.NullInt64{Int64: user.ID, Valid: true}
-			} else if err == sql.ErrNoRows {
-				log.Printf("⚠ User with payments_id (VS) '%s' not found in database (%.2f CZK from %s)",
-					variableSymbol, tx.Amount, tx.AccountName)
-				unmatchedVS = append(unmatchedVS, tx)
-			} else {
-				log.Printf("⚠ Database error looking up user by payments_id '%s': %v", variableSymbol, err)
-				errors++
-			}
-		} else {
-			if tx.Amount > 0 {
-				log.Printf("⚠ Empty VS - %.2f CZK from %s", tx.Amount, tx.AccountName)
-				emptyVS = append(emptyVS, tx)
-			}
-		}
+	// Log bank sync completion
+	queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "fio_sync",
+		Level:     summary.Level(),
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Bank sync (%s) completed: %s", provider.Name(), summary.Message()),
+		Metadata:  sql.NullString{String: summary.Metadata(), Valid: true},
+	})
 
-		// Parse transaction date
-		txDate, err := fio.ParseDate(tx.Date)
+	// Payments that failed to import are retried by the next run, the ones
+	// stored now are reported right away
+	emailClient := email.New(cfg, queries, nil)
+	sendPaymentConfirmations(ctx, queries, emailClient, summary.Assigned)
+	notifyUnmatchedPayments(ctx, cfg, emailClient, summary.NewUnmatched)
+	notifyProjectTargets(ctx, cfg, queries, emailClient)
+
+	if summary.Errors > 0 {
+		lock.Release(ctx) // log.Fatal skips deferred calls
+		fail(fmt.Sprintf("%d of %d transactions failed", summary.Errors, len(transactions)))
+	}
+
+	if !*sinceLast && hasCheckpoint {
+		advanceCheckpoint(ctx, queries, checkpointer, dateTo)
+	}
+
+	log.Println("✓ Job completed successfully")
+}
+
+// sendPaymentConfirmations emails members whose payment was assigned in this run,
+// unless they switched the confirmation off. A failed email is only logged (the
+// email client records it in the system log).
+func sendPaymentConfirmations(ctx context.Context, queries *db.Queries, emailClient *email.Client, assigned []bankimport.AssignedPayment) {
+	if len(assigned) == 0 {
+		return
+	}
+
+	optOuts, err := queries.ListNotificationOptOuts(ctx, email.NotificationPaymentReceived)
+	if err != nil {
+		log.Printf("⚠ Failed to load notification settings, payment confirmations not sent: %v", err)
+		return
+	}
+	skip := make(map[int64]bool, len(optOuts))
+	for _, id := range optOuts {
+		skip[id] = true
+	}
+
+	sent := 0
+	for _, p := range assigned {
+		if skip[p.UserID] {
+			continue
+		}
+		user, err := queries.GetUserByID(ctx, p.UserID)
 		if err != nil {
-			log.Printf("⚠ Failed to parse date %s: %v", tx.Date, err)
-			txDate = time.Now() // fallback
+			log.Printf("⚠ Failed to load user %d for confirmation of payment #%d: %v", p.UserID, p.PaymentID, err)
+			continue
 		}
-
-		// Prepare raw data JSON
-		rawDataJSON, err := json.Marshal(tx)
+		balance, err := queries.GetUserBalance(ctx, db.GetUserBalanceParams{
+			UserID:   sql.NullInt64{Int64: p.UserID, Valid: true},
+			UserID_2: p.UserID,
+			UserID_3: sql.NullInt64{Int64: p.UserID, Valid: true},
+			UserID_4: p.UserID,
+			UserID_5: p.UserID,
+		})
 		if err != nil {
-			log.Printf("⚠ Failed to marshal transaction data: %v", err)
-			rawDataJSON = []byte("{}")
+			log.Printf("⚠ Failed to get balance of %s for confirmation of payment #%d: %v", user.Email, p.PaymentID, err)
+			continue
 		}
-
-		// Build remote account string (account + bank code)
-		remoteAccount := tx.AccountNumber
-		if tx.BankCode != "" {
-			remoteAccount = fmt.Sprintf("%s/%s", tx.AccountNumber, tx.BankCode)
+		if err := emailClient.SendPaymentReceived(ctx, &user, p.Amount, p.Date, money.Amount(balance)); err != nil {
+			continue
 		}
+		sent++
+	}
+	log.Printf("✉ Payment confirmations sent: %d of %d", sent, len(assigned))
+}
 
-		// Check if payment already exists
-		existingPayment, err := queries.GetPaymentByKindAndID(ctx, db.GetPaymentByKindAndIDParams{
-			Kind:   "fio",
-			KindID: fmt.Sprintf("%d", tx.ID),
-		})
+// notifyUnmatchedPayments sends admins the digest of payments stored unassigned in
+// this run: by email to ADMIN_NOTIFY_EMAIL and to the admin Matrix room, if set
+func notifyUnmatchedPayments(ctx context.Context, cfg *config.Config, emailClient *email.Client, transactions []bank.Transaction) {
+	if len(transactions) == 0 {
+		return
+	}
 
-		if err == sql.ErrNoRows {
-			// Insert new payment
-			_, err = queries.UpsertPayment(ctx, db.UpsertPaymentParams{
-				UserID:         userID,
-				ProjectID:      sql.NullInt64{}, // Not set during FIO import
-				Date:           txDate,
-				Amount:         fmt.Sprintf("%.2f", tx.Amount),
-				Kind:           "fio",
-				KindID:         fmt.Sprintf("%d", tx.ID),
-				LocalAccount:   "FIO", // Could be parsed from API info
-				RemoteAccount:  remoteAccount,
-				Identification: variableSymbol,
-				RawData:        sql.NullString{String: string(rawDataJSON), Valid: true},
-				StaffComment:   sql.NullString{},
-			})
-
-			if err != nil {
-				log.Printf("✗ Failed to insert payment (FIO ID %d): %v", tx.ID, err)
-				errors++
-			} else {
-				log.Printf("✓ Inserted payment: %.2f CZK from %s (VS: %s, FIO ID: %d)",
-					tx.Amount, tx.AccountName, tx.VariableSymbol, tx.ID)
-				inserted++
-			}
-		} else if err != nil {
-			log.Printf("⚠ Error checking existing payment: %v", err)
-			errors++
-		} else {
-			// Payment exists - check if it needs update
-			needsUpdate := false
-
-			// Check if user_id changed (manual assignment)
-			if userID.Valid && (!existingPayment.UserID.Valid || existingPayment.UserID.Int64 != userID.Int64) {
-				needsUpdate = true
+	payments := make([]email.UnmatchedPayment, 0, len(transactions))
+	var total money.Amount
+	for _, tx := range transactions {
+		date, err := fio.ParseDate(tx.Date)
+		if err != nil {
+			date = time.Now()
+		}
+		sender := tx.AccountName
+		if sender == "" {
+			sender = tx.AccountNumber
+			if tx.BankCode != "" {
+				sender += "/" + tx.BankCode
 			}
+		}
+		p := email.UnmatchedPayment{
+			Date:           date,
+			Amount:         money.FromFloat(tx.Amount),
+			VariableSymbol: tx.VariableSymbol,
+			Sender:         sender,
+			Message:        tx.Message,
+		}
+		total += p.Amount
+		payments = append(payments, p)
+	}
 
-			if needsUpdate {
-				_, err = queries.UpsertPayment(ctx, db.UpsertPaymentParams{
-					UserID:         userID,
-					ProjectID:      existingPayment.ProjectID, // Preserve project assignment
-					Date:           txDate,
-					Amount:         fmt.Sprintf("%.2f", tx.Amount),
-					Kind:           "fio",
-					KindID:         fmt.Sprintf("%d", tx.ID),
-					LocalAccount:   "FIO",
-					RemoteAccount:  remoteAccount,
-					Identification: variableSymbol,
-					RawData:        sql.NullString{String: string(rawDataJSON), Valid: true},
-					StaffComment:   existingPayment.StaffComment, // Preserve staff comment
-				})
-
-				if err != nil {
-					log.Printf("✗ Failed to update payment (FIO ID %d): %v", tx.ID, err)
-					errors++
-				} else {
-					log.Printf("↻ Updated payment: %.2f CZK (FIO ID: %d)", tx.Amount, tx.ID)
-					updated++
-				}
-			} else {
-				// No changes needed
-				skipped++
-			}
+	if cfg.AdminNotifyEmail != "" {
+		if err := emailClient.SendUnmatchedPayments(ctx, cfg.AdminNotifyEmail, payments); err != nil {
+			log.Printf("⚠ Failed to send unmatched payments digest: %v", err)
 		}
 	}
 
-	log.Println("\n" + repeat("=", 80))
-	log.Println("SYNC SUMMARY")
-	log.Println(repeat("=", 80))
-	log.Printf("Total transactions fetched: %d", len(transactions))
-	log.Printf("  ✓ Inserted: %d", inserted)
-	log.Printf("  ↻ Updated: %d", updated)
-	log.Printf("  - Skipped (negative/zero): %d", skipped)
-	log.Printf("  ✗ Errors: %d", errors)
-	log.Println(repeat("-", 80))
-
-	// Report problematic payments
-	totalUnmatched := len(unmatchedVS) + len(emptyVS)
-	if totalUnmatched > 0 {
-		log.Printf("\n⚠️  PROBLEMATIC PAYMENTS: %d", totalUnmatched)
-
-		if len(emptyVS) > 0 {
-			totalAmount := 0.0
-			log.Printf("\n  📝 Empty variable symbol: %d payments", len(emptyVS))
-			for _, tx := range emptyVS {
-				totalAmount += tx.Amount
-				log.Printf("     - %.2f CZK from %s on %s", tx.Amount, tx.AccountName, tx.Date[:10])
+	if cfg.MatrixHomeserverURL != "" && cfg.MatrixAccessToken != "" && cfg.MatrixAdminRoomID != "" {
+		var text strings.Builder
+		fmt.Fprintf(&text, "⚠ Nové nespárované platby: %d (%s Kč)\n", len(payments), total)
+		for _, p := range payments {
+			vs := p.VariableSymbol
+			if vs == "" {
+				vs = "bez VS"
 			}
-			log.Printf("     Total: %.2f CZK", totalAmount)
+			fmt.Fprintf(&text, "- %s Kč od %s, %s, %s\n", p.Amount, p.Sender, vs, p.Date.Format("2. 1. 2006"))
 		}
+		fmt.Fprintf(&text, "%s/admin/payments/unmatched", cfg.BaseURL)
 
-		if len(unmatchedVS) > 0 {
-			totalAmount := 0.0
-			log.Printf("\n  ❌ User not found: %d payments", len(unmatchedVS))
-			for _, tx := range unmatchedVS {
-				totalAmount += tx.Amount
-				log.Printf("     - %.2f CZK (VS/payments_id: %s) from %s", tx.Amount, tx.VariableSymbol, tx.AccountName)
-			}
-			log.Printf("     Total: %.2f CZK", totalAmount)
-			log.Println("\n     💡 These payments have VS that doesn't match any user's payments_id.")
-			log.Println("        Check if users need to be imported or VS is incorrect.")
+		room := matrix.NewClient(cfg.MatrixHomeserverURL, cfg.MatrixAccessToken, cfg.MatrixAdminRoomID)
+		if err := room.SendText(ctx, text.String()); err != nil {
+			log.Printf("⚠ Failed to post unmatched payments to Matrix: %v", err)
 		}
+	}
+}
 
-		log.Printf("\n💡 Run 'go run cmd/cron/report_unmatched_payments.go' for detailed report")
+// notifyProjectTargets tells admins about projects that reached their funding goal,
+// once per goal: by email to ADMIN_NOTIFY_EMAIL and to the admin Matrix room, if set.
+// Runs after every sync, so payments assigned to projects by hand are caught too.
+func notifyProjectTargets(ctx context.Context, cfg *config.Config, queries *db.Queries, emailClient *email.Client) {
+	projects, err := queries.ListProjectsWithOpenTarget(ctx)
+	if err != nil {
+		log.Printf("⚠ Failed to check project targets: %v", err)
+		return
 	}
 
-	log.Println("\n" + repeat("=", 80))
+	for _, project := range projects {
+		income, err := queries.GetProjectIncome(ctx, sql.NullInt64{Int64: project.ID, Valid: true})
+		if err != nil {
+			log.Printf("⚠ Failed to get income of project %s: %v", project.Name, err)
+			continue
+		}
+		total := money.Amount(income)
+		if total < project.Goal {
+			continue
+		}
 
-	// Log FIO sync completion
-	level := "success"
-	if errors > 0 {
-		level = "warning"
-	} else if totalUnmatched > 0 {
-		level = "info"
-	}
-	queries.CreateLog(ctx, db.CreateLogParams{
-		Subsystem: "fio_sync",
-		Level:     level,
-		UserID:    sql.NullInt64{},
-		Message:   fmt.Sprintf("FIO sync completed: %d new, %d updated, %d unmatched", inserted, updated, totalUnmatched),
-		Metadata:  sql.NullString{String: fmt.Sprintf(`{"inserted":%d,"updated":%d,"skipped":%d,"unmatched":%d,"errors":%d}`, inserted, updated, skipped, totalUnmatched, errors), Valid: true},
-	})
+		// Marked first: a failed notification is not worth repeating on every sync
+		if n, err := queries.MarkProjectTargetReached(ctx, project.ID); err != nil || n == 0 {
+			continue
+		}
+		log.Printf("🎯 Project %s reached its goal: %s of %s Kč", project.Name, total, project.Goal)
+		queries.CreateLog(ctx, db.CreateLogParams{
+			Subsystem: "projects",
+			Level:     "info",
+			UserID:    sql.NullInt64{},
+			Message:   fmt.Sprintf("Project %s reached its goal: %s of %s Kč", project.Name, total, project.Goal),
+		})
 
-	if errors > 0 {
-		log.Fatal("Job completed with errors")
+		if cfg.AdminNotifyEmail != "" {
+			if err := emailClient.SendProjectTargetReached(ctx, cfg.AdminNotifyEmail, project, total); err != nil {
+				log.Printf("⚠ Failed to send project target notification: %v", err)
+			}
+		}
+
+		if cfg.MatrixHomeserverURL != "" && cfg.MatrixAccessToken != "" && cfg.MatrixAdminRoomID != "" {
+			text := fmt.Sprintf("🎯 Projekt %s dosáhl cíle: vybráno %s Kč z %s Kč\n%s/admin/projects", project.Name, total, project.Goal, cfg.BaseURL)
+			room := matrix.NewClient(cfg.MatrixHomeserverURL, cfg.MatrixAccessToken, cfg.MatrixAdminRoomID)
+			if err := room.SendText(ctx, text); err != nil {
+				log.Printf("⚠ Failed to post project target to Matrix: %v", err)
+			}
+		}
 	}
+}
 
-	log.Println("✓ Job completed successfully")
+// advanceCheckpoint moves the FIO checkpoint after a fully successful period sync, so
+// the next --since-last run continues from there. The checkpoint is set to the day
+// before dateTo: transactions booked later that day are downloaded again rather than
+// missed (re-imports only update existing payments). A failure is only a warning -
+// the next --since-last run then downloads more than needed.
+func advanceCheckpoint(ctx context.Context, queries *db.Queries, provider bank.CheckpointProvider, dateTo time.Time) {
+	date := fio.FormatDate(dateTo.AddDate(0, 0, -1))
+	if err := provider.SetCheckpoint(ctx, dateTo.AddDate(0, 0, -1)); err != nil {
+		log.Printf("⚠ Failed to set FIO checkpoint to %s: %v", date, err)
+		queries.CreateLog(ctx, db.CreateLogParams{
+			Subsystem: "fio_sync",
+			Level:     "warning",
+			UserID:    sql.NullInt64{},
+			Message:   fmt.Sprintf("Failed to set FIO checkpoint to %s: %v", date, err),
+		})
+		return
+	}
+	log.Printf("✓ FIO checkpoint set to %s", date)
 }
 
-// Helper to repeat strings (since strings.Repeat might not be imported)
-func repeat(s string, count int) string {
-	result := ""
-	for i := 0; i < count; i++ {
-		result += s
+// restoreCheckpoint moves the FIO checkpoint back before the earliest downloaded
+// transaction after a failed --since-last run (the download itself moved it), so the
+// next run downloads the whole batch again
+func restoreCheckpoint(ctx context.Context, provider bank.CheckpointProvider, transactions []bank.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	var earliest time.Time
+	for _, tx := range transactions {
+		txDate, err := fio.ParseDate(tx.Date)
+		if err != nil {
+			return fmt.Errorf("failed to parse date %s: %w", tx.Date, err)
+		}
+		if earliest.IsZero() || txDate.Before(earliest) {
+			earliest = txDate
+		}
 	}
-	return result
+
+	return provider.SetCheckpoint(ctx, earliest.AddDate(0, 0, -1))
 }
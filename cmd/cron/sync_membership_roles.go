@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/auth"
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/keycloak"
+)
+
+// Synchronizace Keycloak rolí podle stavu členství
+//
+// Každý člen s keycloak_id má právě tu roli, která odpovídá jeho stavu v DB
+// (MEMBERSHIP_STATE_ROLES, výchozí accepted:member_active). Při pozastavení nebo
+// ukončení členství role zmizí a navazující služby (wiki, dveře) přestanou pouštět.
+//
+// Použití:
+//   # Náhled změn bez zápisu do Keycloaku
+//   go run cmd/cron/sync_membership_roles.go --dry-run
+//
+//   go run cmd/cron/sync_membership_roles.go
+//
+// Nebo v crontab (každou hodinu):
+//   15 * * * * cd /path/to/portal && ./sync_membership_roles >> logs/cron.log 2>&1
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only print the changes that would be made")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.KeycloakServiceAccountClientID == "" || cfg.KeycloakServiceAccountClientSecret == "" {
+		log.Fatal("KEYCLOAK_SERVICE_ACCOUNT_CLIENT_ID and KEYCLOAK_SERVICE_ACCOUNT_CLIENT_SECRET are required")
+	}
+	if len(cfg.MembershipStateRoles) == 0 {
+		log.Fatal("MEMBERSHIP_STATE_ROLES is empty, nothing to sync")
+	}
+
+	database, err := sql.Open("sqlite", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	queries := db.New(database)
+
+	ctx := context.Background()
+
+	serviceClient, err := auth.NewServiceAccountClient(
+		ctx,
+		cfg,
+		cfg.KeycloakServiceAccountClientID,
+		cfg.KeycloakServiceAccountClientSecret,
+	)
+	if err != nil {
+		log.Fatalf("Failed to create service account: %v", err)
+	}
+
+	log.Println("✓ Service account authenticated")
+
+	kcClient := keycloak.NewClientWithTokenProvider(cfg, serviceClient)
+
+	// Managed roles and their current holders (one request per role, not per user)
+	var managedRoles []string
+	holders := make(map[string]map[string]bool)
+	for _, role := range cfg.MembershipStateRoles {
+		if _, seen := holders[role]; seen {
+			continue
+		}
+
+		if _, err := kcClient.GetRoleByName(ctx, role); err != nil {
+			log.Fatalf("Role %s does not exist in Keycloak: %v", role, err)
+		}
+
+		userIDs, err := kcClient.GetRoleUserIDs(ctx, role)
+		if err != nil {
+			log.Fatalf("Failed to get users of role %s: %v", role, err)
+		}
+
+		holders[role] = make(map[string]bool, len(userIDs))
+		for _, id := range userIDs {
+			holders[role][id] = true
+		}
+		managedRoles = append(managedRoles, role)
+	}
+	sort.Strings(managedRoles)
+
+	log.Printf("Managed roles: %s", strings.Join(managedRoles, ", "))
+
+	users, err := queries.ListUsers(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list users: %v", err)
+	}
+
+	log.Printf("Processing %d users...", len(users))
+
+	assigned := 0
+	removed := 0
+	errors := 0
+	linked := make(map[string]bool)
+
+	for _, user := range users {
+		if !user.KeycloakID.Valid || user.KeycloakID.String == "" {
+			continue
+		}
+
+		keycloakID := user.KeycloakID.String
+		linked[keycloakID] = true
+		wanted := cfg.MembershipStateRoles[user.State]
+
+		for _, role := range managedRoles {
+			has := holders[role][keycloakID]
+
+			switch {
+			case role == wanted && !has:
+				if *dryRun {
+					log.Printf("  + %s: would assign %s (state: %s)", user.Email, role, user.State)
+					assigned++
+					continue
+				}
+				if err := kcClient.AssignRoleToUser(ctx, keycloakID, role); err != nil {
+					log.Printf("✗ Failed to assign %s to %s: %v", role, user.Email, err)
+					errors++
+					continue
+				}
+				log.Printf("✓ Assigned %s to %s (state: %s)", role, user.Email, user.State)
+				assigned++
+
+			case role != wanted && has:
+				if *dryRun {
+					log.Printf("  - %s: would remove %s (state: %s)", user.Email, role, user.State)
+					removed++
+					continue
+				}
+				if err := kcClient.RemoveRoleFromUser(ctx, keycloakID, role); err != nil {
+					log.Printf("✗ Failed to remove %s from %s: %v", role, user.Email, err)
+					errors++
+					continue
+				}
+				log.Printf("✓ Removed %s from %s (state: %s)", role, user.Email, user.State)
+				removed++
+			}
+		}
+	}
+
+	// Accounts not linked to any member are only reported - they may be service
+	// accounts or members who have not logged in yet
+	for _, role := range managedRoles {
+		for keycloakID := range holders[role] {
+			if !linked[keycloakID] {
+				log.Printf("⚠ Keycloak user %s has %s but is not linked to any member", keycloakID, role)
+			}
+		}
+	}
+
+	log.Printf("\nSummary:")
+	log.Printf("  Total users: %d", len(users))
+	log.Printf("  Assigned: %d", assigned)
+	log.Printf("  Removed: %d", removed)
+	log.Printf("  Errors: %d", errors)
+
+	if *dryRun {
+		log.Println("✓ Dry run - no changes made")
+		return
+	}
+
+	if assigned > 0 || removed > 0 || errors > 0 {
+		level := "success"
+		if errors > 0 {
+			level = "warning"
+		}
+		queries.CreateLog(ctx, db.CreateLogParams{
+			Subsystem: "keycloak_role_sync",
+			Level:     level,
+			UserID:    sql.NullInt64{},
+			Message:   fmt.Sprintf("Membership role sync: %d assigned, %d removed, %d errors", assigned, removed, errors),
+			Metadata:  sql.NullString{String: fmt.Sprintf(`{"assigned":%d,"removed":%d,"errors":%d}`, assigned, removed, errors), Valid: true},
+		})
+	}
+
+	if errors > 0 {
+		log.Fatal("Job completed with errors")
+	}
+
+	log.Println("✓ Job completed successfully")
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/base48/member-portal/internal/config"
 	"github.com/base48/member-portal/internal/db"
 	"github.com/base48/member-portal/internal/keycloak"
+	"github.com/base48/member-portal/internal/money"
 )
 
 // Příklad cron jobu: Automatická aktualizace role in_debt na základě balance
@@ -94,6 +95,9 @@ func main() {
 		balance, err := queries.GetUserBalance(ctx, db.GetUserBalanceParams{
 			UserID:   sql.NullInt64{Int64: user.ID, Valid: true},
 			UserID_2: user.ID,
+			UserID_3: sql.NullInt64{Int64: user.ID, Valid: true},
+			UserID_4: user.ID,
+			UserID_5: user.ID,
 		})
 		if err != nil {
 			log.Printf("⚠ Error getting balance for user %s: %v", user.Email, err)
@@ -119,7 +123,7 @@ func main() {
 				log.Printf("✗ Failed to assign in_debt to %s: %v", user.Email, err)
 				errors++
 			} else {
-				log.Printf("✓ Assigned in_debt to %s (balance: %d)", user.Email, balance)
+				log.Printf("✓ Assigned in_debt to %s (balance: %s)", user.Email, money.Amount(balance))
 				updated++
 			}
 		} else if !shouldHaveDebt && hasDebtRole {
@@ -128,7 +132,7 @@ func main() {
 				log.Printf("✗ Failed to remove in_debt from %s: %v", user.Email, err)
 				errors++
 			} else {
-				log.Printf("✓ Removed in_debt from %s (balance: %d)", user.Email, balance)
+				log.Printf("✓ Removed in_debt from %s (balance: %s)", user.Email, money.Amount(balance))
 				updated++
 			}
 		}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/money"
 )
 
 type OldUser struct {
@@ -92,14 +94,20 @@ func importLevels(oldDB, newDB *sql.DB) error {
 			activeInt = 1
 		}
 
-		_, err := newDB.Exec(`
+		// The old database keeps amounts as text in CZK
+		amount, err := money.Parse(level.Amount)
+		if err != nil {
+			return fmt.Errorf("level %d: %w", level.ID, err)
+		}
+
+		_, err = newDB.Exec(`
 			INSERT INTO levels (id, name, amount, active)
 			VALUES (?, ?, ?, ?)
 			ON CONFLICT(id) DO UPDATE SET
 				name = excluded.name,
 				amount = excluded.amount,
 				active = excluded.active
-		`, level.ID, level.Name, level.Amount, activeInt)
+		`, level.ID, level.Name, amount, activeInt)
 
 		if err != nil {
 			return fmt.Errorf("insert level %d: %w", level.ID, err)
@@ -172,6 +180,11 @@ func importUsers(oldDB, newDB *sql.DB) error {
 			isStaff = 1
 		}
 
+		levelActualAmount, err := money.Parse(user.LevelActualAmount)
+		if err != nil {
+			return fmt.Errorf("user %s: %w", user.Email, err)
+		}
+
 		// Insert user (OR IGNORE on duplicate email)
 		result, err := newDB.Exec(`
 			INSERT OR IGNORE INTO users (
@@ -182,7 +195,7 @@ func importUsers(oldDB, newDB *sql.DB) error {
 			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`,
 			user.Email, user.Realname, user.Phone, user.AltContact,
-			levelID, user.LevelActualAmount, paymentsID,
+			levelID, levelActualAmount, paymentsID,
 			user.DateJoined, state, isCouncil, isStaff,
 			nil, // NULL keycloak_id, will be linked on first login via LinkKeycloakID
 		)
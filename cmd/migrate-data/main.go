@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "modernc.org/sqlite"
+)
+
+// Přenos dat ze SQLite do PostgreSQL (jednorázový přechod)
+//
+// Použití:
+//   go run ./cmd/migrate-data --target "postgres://portal@localhost/portal" --dry-run
+//   go run ./cmd/migrate-data --source "file:./data/portal.db" --target "postgres://portal@localhost/portal"
+//
+// Zkopíruje všechny tabulky ze SQLite (--source, výchozí DATABASE_URL) do prázdného
+// schématu v PostgreSQL (--target) se stejnými tabulkami a sloupci; schéma se musí
+// založit předem, nástroj přenáší jen data. Do PostgreSQL zapisuje přes psql
+// (COPY v jedné transakci), Go ovladač PostgreSQL není potřeba.
+//
+// Po dobu přenosu drží zdroj jen pro čtení: zapisovací zámek SQLite zablokuje
+// zápisy serveru i cron úloh (dostanou "database is locked"), čtení běží dál. Před
+// během zapněte režim údržby a cron úlohy zastavte.
+//
+// Před potvrzením transakce se v PostgreSQL porovnají počty řádků všech tabulek,
+// součty částek plateb, poplatků, splátek, dalších poplatků a úprav bilance a
+// celková bilance členů se zdrojem; při rozdílu se nic nezapíše. Exit kód 1 = chyba.
+// S --dry-run jen vypíše tabulky, počty a kontrolní součty zdroje.
+
+// sourceBusyTimeout is how long to wait for a writer holding the SQLite lock
+const sourceBusyTimeout = 30 * time.Second
+
+// progressEvery is how often (in rows) the copy progress of a table is printed
+const progressEvery = 10000
+
+type table struct {
+	name     string
+	columns  []string
+	serialID bool // INTEGER PRIMARY KEY id, a serial column in PostgreSQL
+	rows     int64
+}
+
+// check is an integrity check: a query returning one integer, written so it runs
+// unchanged on SQLite and PostgreSQL
+type check struct {
+	name  string
+	query string
+	value int64 // result on the source
+}
+
+// balanceChecks compare money totals; members' balance is GetUserBalance summed
+// over all members
+var balanceChecks = []check{
+	{name: "payments total", query: `SELECT COALESCE(SUM(CAST(amount AS BIGINT)), 0) FROM payments`},
+	{name: "fees total", query: `SELECT COALESCE(SUM(CAST(amount AS BIGINT)), 0) FROM fees`},
+	{name: "payment splits total", query: `SELECT COALESCE(SUM(CAST(amount AS BIGINT)), 0) FROM payment_splits`},
+	{name: "charges total", query: `SELECT COALESCE(SUM(CAST(amount AS BIGINT)), 0) FROM charges`},
+	{name: "balance adjustments total", query: `SELECT COALESCE(SUM(CAST(amount AS BIGINT)), 0) FROM balance_adjustments`},
+	{name: "members' balance total", query: `SELECT
+    COALESCE((
+        SELECT SUM(CAST(p.amount AS BIGINT))
+        FROM payments p
+        JOIN users u ON p.user_id = u.id
+        WHERE p.identification = u.payments_id
+        AND p.classification != 'donation'
+        AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+    ), 0) -
+    COALESCE((SELECT SUM(CAST(f.amount AS BIGINT)) FROM fees f JOIN users u ON f.user_id = u.id), 0) +
+    COALESCE((SELECT SUM(CAST(s.amount AS BIGINT)) FROM payment_splits s JOIN users u ON s.user_id = u.id WHERE s.classification = 'fee'), 0) -
+    COALESCE((SELECT SUM(CAST(c.amount AS BIGINT)) FROM charges c JOIN users u ON c.user_id = u.id), 0) +
+    COALESCE((SELECT SUM(CAST(a.amount AS BIGINT)) FROM balance_adjustments a JOIN users u ON a.user_id = u.id), 0)`},
+}
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	defaultSource := os.Getenv("DATABASE_URL")
+	if defaultSource == "" || strings.HasPrefix(defaultSource, "postgres") {
+		defaultSource = "file:./data/portal.db?_fk=1"
+	}
+	source := flag.String("source", defaultSource, "SQLite database to copy from")
+	target := flag.String("target", "", "PostgreSQL connection string (URL or conninfo) to copy to")
+	psql := flag.String("psql", "psql", "psql binary")
+	dryRun := flag.Bool("dry-run", false, "Only print the tables, row counts and check totals of the source")
+	flag.Parse()
+
+	if *target == "" && !*dryRun {
+		log.Fatal("--target is required")
+	}
+
+	ctx := context.Background()
+
+	database, err := sql.Open("sqlite", *source)
+	if err != nil {
+		log.Fatalf("Failed to open source database: %v", err)
+	}
+	defer database.Close()
+
+	// Everything is read through one connection inside one transaction, so counts,
+	// totals and the copied rows are the same snapshot
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		log.Fatalf("Failed to open source database: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("PRAGMA busy_timeout = %d", sourceBusyTimeout.Milliseconds())); err != nil {
+		log.Fatalf("Failed to configure source database: %v", err)
+	}
+
+	// BEGIN IMMEDIATE takes the SQLite write lock: other processes can still read,
+	// but cannot write until the copy is done. Nothing is written through it, the
+	// transaction is rolled back at the end.
+	begin := "BEGIN IMMEDIATE"
+	if *dryRun {
+		begin = "BEGIN"
+	}
+	if _, err := conn.ExecContext(ctx, begin); err != nil {
+		log.Fatalf("Failed to lock source database: %v", err)
+	}
+	defer conn.ExecContext(context.Background(), "ROLLBACK")
+	if !*dryRun {
+		log.Printf("Source %s is read-only until the copy finishes", *source)
+	}
+
+	tables, err := listTables(ctx, conn)
+	if err != nil {
+		log.Fatalf("Failed to read source schema: %v", err)
+	}
+
+	var totalRows int64
+	for i := range tables {
+		t := &tables[i]
+		if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quoteIdent(t.name)).Scan(&t.rows); err != nil {
+			log.Fatalf("Failed to count rows of %s: %v", t.name, err)
+		}
+		totalRows += t.rows
+	}
+
+	checks := make([]check, len(balanceChecks))
+	copy(checks, balanceChecks)
+	for i := range checks {
+		if err := conn.QueryRowContext(ctx, checks[i].query).Scan(&checks[i].value); err != nil {
+			log.Fatalf("Failed to compute %s: %v", checks[i].name, err)
+		}
+	}
+
+	log.Printf("Source: %d tables, %d rows", len(tables), totalRows)
+	for _, t := range tables {
+		log.Printf("  %-32s %8d rows", t.name, t.rows)
+	}
+	for _, c := range checks {
+		log.Printf("  %-32s %8d", c.name, c.value)
+	}
+
+	if *dryRun {
+		log.Println("Dry run - nothing copied")
+		return
+	}
+
+	if err := migrate(ctx, conn, *psql, *target, tables, checks); err != nil {
+		log.Fatalf("Migration failed, nothing was committed to PostgreSQL: %v", err)
+	}
+
+	log.Printf("Copied %d rows of %d tables; row counts and %d balance totals match", totalRows, len(tables), len(checks))
+}
+
+// listTables returns the tables of the source with their columns, parents before
+// the tables referencing them, so foreign keys hold while copying
+func listTables(ctx context.Context, conn *sql.Conn) ([]table, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tables := make(map[string]*table, len(names))
+	parents := make(map[string][]string, len(names))
+	for _, name := range names {
+		t := &table{name: name}
+		columnRows, err := conn.QueryContext(ctx, `SELECT name, upper(type), pk FROM pragma_table_info(?) ORDER BY cid`, name)
+		if err != nil {
+			return nil, err
+		}
+		for columnRows.Next() {
+			var column, columnType string
+			var pk int
+			if err := columnRows.Scan(&column, &columnType, &pk); err != nil {
+				columnRows.Close()
+				return nil, err
+			}
+			t.columns = append(t.columns, column)
+			if column == "id" && columnType == "INTEGER" && pk == 1 {
+				t.serialID = true
+			}
+		}
+		columnRows.Close()
+		if err := columnRows.Err(); err != nil {
+			return nil, err
+		}
+		tables[name] = t
+
+		fkRows, err := conn.QueryContext(ctx, `SELECT DISTINCT "table" FROM pragma_foreign_key_list(?)`, name)
+		if err != nil {
+			return nil, err
+		}
+		for fkRows.Next() {
+			var parent string
+			if err := fkRows.Scan(&parent); err != nil {
+				fkRows.Close()
+				return nil, err
+			}
+			// Rows referencing their own table are copied in rowid order
+			if parent != name {
+				parents[name] = append(parents[name], parent)
+			}
+		}
+		fkRows.Close()
+		if err := fkRows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Tables whose parents are all placed go next, in creation order
+	var ordered []table
+	placed := make(map[string]bool, len(names))
+	for len(ordered) < len(names) {
+		progress := false
+		for _, name := range names {
+			if placed[name] {
+				continue
+			}
+			ready := true
+			for _, parent := range parents[name] {
+				if tables[parent] != nil && !placed[parent] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, *tables[name])
+				placed[name] = true
+				progress = true
+			}
+		}
+		if !progress {
+			var cycle []string
+			for _, name := range names {
+				if !placed[name] {
+					cycle = append(cycle, name)
+				}
+			}
+			return nil, fmt.Errorf("foreign keys form a cycle between %s", strings.Join(cycle, ", "))
+		}
+	}
+	return ordered, nil
+}
+
+// migrate feeds the copy script to psql and waits for it. The script commits only
+// after all checks passed; when it is cut short, psql exits without COMMIT and
+// PostgreSQL rolls the transaction back.
+func migrate(ctx context.Context, conn *sql.Conn, psql, target string, tables []table, checks []check) error {
+	cmd := exec.CommandContext(ctx, psql, "-X", "-q", "-v", "ON_ERROR_STOP=1", "-d", target)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", psql, err)
+	}
+
+	w := bufio.NewWriterSize(stdin, 1<<16)
+	writeErr := writeScript(ctx, conn, w, tables, checks)
+	if writeErr == nil {
+		writeErr = w.Flush()
+	}
+	stdin.Close()
+
+	// A failed statement makes psql exit, which also breaks the pipe; its error is
+	// the one worth reporting
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("psql: %w", err)
+	}
+	return writeErr
+}
+
+// writeScript writes the SQL script copying all tables in one transaction
+func writeScript(ctx context.Context, conn *sql.Conn, w *bufio.Writer, tables []table, checks []check) error {
+	fmt.Fprintln(w, "SET client_encoding = 'UTF8';")
+	fmt.Fprintln(w, "BEGIN;")
+
+	// The target must be empty, rows already there would break the counts
+	fmt.Fprintln(w, "DO $$ BEGIN")
+	for _, t := range tables {
+		fmt.Fprintf(w, "IF EXISTS (SELECT 1 FROM %s) THEN RAISE EXCEPTION 'target table %% is not empty', %s; END IF;\n",
+			quoteIdent(t.name), quoteLiteral(t.name))
+	}
+	fmt.Fprintln(w, "END $$;")
+
+	for i, t := range tables {
+		log.Printf("[%d/%d] %s: %d rows", i+1, len(tables), t.name, t.rows)
+		if err := copyTable(ctx, conn, w, t); err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+
+	// Serial IDs continue after the copied ones (no-op for tables without a sequence)
+	for _, t := range tables {
+		if t.serialID {
+			fmt.Fprintf(w, "SELECT setval(pg_get_serial_sequence(%s, 'id'), MAX(id)) FROM %s HAVING MAX(id) IS NOT NULL;\n",
+				quoteLiteral(quoteIdent(t.name)), quoteIdent(t.name))
+		}
+	}
+
+	log.Println("Comparing row counts and balance totals...")
+	fmt.Fprintln(w, "DO $$")
+	fmt.Fprintln(w, "DECLARE n numeric;")
+	fmt.Fprintln(w, "BEGIN")
+	for _, t := range tables {
+		fmt.Fprintf(w, "SELECT COUNT(*) INTO n FROM %s;\n", quoteIdent(t.name))
+		fmt.Fprintf(w, "IF n <> %d THEN RAISE EXCEPTION '%%: %% rows in PostgreSQL, %d in SQLite', %s, n; END IF;\n",
+			t.rows, t.rows, quoteLiteral(t.name))
+	}
+	for _, c := range checks {
+		fmt.Fprintf(w, "SELECT (%s) INTO n;\n", c.query)
+		fmt.Fprintf(w, "IF n IS DISTINCT FROM %d THEN RAISE EXCEPTION '%%: %% in PostgreSQL, %d in SQLite', %s, n; END IF;\n",
+			c.value, c.value, quoteLiteral(c.name))
+	}
+	fmt.Fprintln(w, "END $$;")
+
+	fmt.Fprintln(w, "COMMIT;")
+	return nil
+}
+
+// copyTable writes a COPY statement with all rows of the table in rowid order
+func copyTable(ctx context.Context, conn *sql.Conn, w *bufio.Writer, t table) error {
+	columns := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		columns[i] = quoteIdent(column)
+	}
+	list := strings.Join(columns, ", ")
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s ORDER BY rowid", list, quoteIdent(t.name)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	fmt.Fprintf(w, "COPY %s (%s) FROM STDIN;\n", quoteIdent(t.name), list)
+
+	values := make([]interface{}, len(t.columns))
+	pointers := make([]interface{}, len(t.columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	var copied int64
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		for i, value := range values {
+			if i > 0 {
+				w.WriteByte('\t')
+			}
+			w.WriteString(copyValue(value))
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+
+		copied++
+		if copied%progressEvery == 0 {
+			log.Printf("  %s: %d/%d rows", t.name, copied, t.rows)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if copied != t.rows {
+		return fmt.Errorf("read %d rows, counted %d", copied, t.rows)
+	}
+
+	_, err = w.WriteString("\\.\n")
+	return err
+}
+
+// goTimeLayout is how time.Time values written by the Go driver end up in TEXT columns
+const goTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// copyValue formats a value for COPY text format
+func copyValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return `\N`
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "t"
+		}
+		return "f"
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999999Z07:00")
+	case []byte:
+		return copyEscape(`\x` + fmt.Sprintf("%x", v))
+	case string:
+		// PostgreSQL does not read Go's time.String format
+		if t, err := time.Parse(goTimeLayout, v); err == nil {
+			return t.Format("2006-01-02 15:04:05.999999999Z07:00")
+		}
+		return copyEscape(v)
+	default:
+		return copyEscape(fmt.Sprint(v))
+	}
+}
+
+var copyEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// copyEscape escapes the characters that are special in COPY text format
+func copyEscape(s string) string {
+	return copyEscaper.Replace(s)
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// Naplnění čisté databáze ukázkovými daty pro lokální vývoj
+//
+// Použití:
+//   # Nová databáze včetně migrací
+//   go run ./cmd/seed --db "file:./data/dev.db?_fk=1" --migrate
+//
+//   # Vlastní fixtures
+//   go run ./cmd/seed --fixtures my_fixtures.yaml
+//
+// Formát fixtures viz cmd/seed/sample.yaml. Platby mají kind 'seed', takže je lze
+// snadno odlišit od skutečných (a opakované spuštění s --append je neduplikuje).
+
+// text accepts both quoted and unquoted YAML scalars (e.g. payments_id: 1001)
+type text string
+
+func (t *text) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = text(s)
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v != nil {
+		*t = text(strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+// Fixtures describes the sample data loaded by the seed command
+type Fixtures struct {
+	Levels   []LevelFixture   `json:"levels"`
+	Users    []UserFixture    `json:"users"`
+	Projects []ProjectFixture `json:"projects"`
+	Payments []PaymentFixture `json:"payments"`
+}
+
+// LevelFixture is a membership level; existing levels (by name) are kept
+type LevelFixture struct {
+	Name   text    `json:"name"`
+	Amount float64 `json:"amount"`
+	Active *bool   `json:"active"`
+}
+
+// UserFixture is a member with optionally generated fee and payment history
+type UserFixture struct {
+	Email      text    `json:"email"`
+	Username   text    `json:"username"`
+	Realname   text    `json:"realname"`
+	Phone      text    `json:"phone"`
+	KeycloakID text    `json:"keycloak_id"` // set to log in as this user with a local Keycloak
+	Level      text    `json:"level"`       // level name
+	CustomFee  float64 `json:"custom_fee"`  // level_actual_amount, 0 = level amount
+	PaymentsID text    `json:"payments_id"` // variable symbol
+	State      text    `json:"state"`
+	Joined     text    `json:"joined"` // YYYY-MM-DD
+	Council    bool    `json:"council"`
+	Staff      bool    `json:"staff"`
+
+	// Generated history relative to today
+	FeeMonths  int `json:"fee_months"`  // monthly fees for the last N months
+	PaidMonths int `json:"paid_months"` // fee-sized payments for the oldest N of those months
+}
+
+// ProjectFixture is a fundraising project
+type ProjectFixture struct {
+	Name        text   `json:"name"`
+	PaymentsID  text   `json:"payments_id"`
+	Description text   `json:"description"`
+	ExtraVS     []text `json:"extra_vs"` // additional VS in project_vs
+}
+
+// PaymentFixture is a single incoming payment. Without user/project it stays unmatched.
+type PaymentFixture struct {
+	ID             text    `json:"id"`      // kind_id, generated when empty
+	User           text    `json:"user"`    // email - assigns the payment and defaults identification to payments_id
+	Project        text    `json:"project"` // name - identification defaults to the project VS
+	Identification text    `json:"identification"`
+	Date           text    `json:"date"` // YYYY-MM-DD
+	Amount         float64 `json:"amount"`
+	RemoteAccount  text    `json:"remote_account"`
+	Message        text    `json:"message"`
+}
+
+// seeder holds lookups shared by the individual seed steps
+type seeder struct {
+	queries  *db.Queries
+	tx       *sql.Tx
+	levels   map[string]db.Level
+	users    map[string]db.User
+	projects map[string]db.Project
+	counts   map[string]int
+}
+
+func main() {
+	dbURL := flag.String("db", getEnv("DATABASE_URL", "file:./data/portal.db?_fk=1"), "database URL")
+	fixturesPath := flag.String("fixtures", "cmd/seed/sample.yaml", "YAML fixture file")
+	migrate := flag.Bool("migrate", false, "apply migrations/*.sql before seeding (fresh database)")
+	appendData := flag.Bool("append", false, "seed even if the database already contains users")
+	flag.Parse()
+
+	data, err := os.ReadFile(*fixturesPath)
+	if err != nil {
+		log.Fatalf("Failed to read fixtures: %v", err)
+	}
+
+	fixtures, err := loadFixtures(string(data))
+	if err != nil {
+		log.Fatalf("Invalid fixtures %s: %v", *fixturesPath, err)
+	}
+
+	database, err := sql.Open("sqlite", *dbURL)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+
+	if *migrate {
+		if err := applyMigrations(ctx, database); err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
+		}
+	}
+
+	// Refuse to mix sample data into a real database by accident
+	var userCount int
+	if err := database.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&userCount); err != nil {
+		log.Fatalf("Failed to inspect database (run with --migrate for a new one?): %v", err)
+	}
+	if userCount > 0 && !*appendData {
+		log.Fatalf("Database already contains %d users - use a fresh database or --append", userCount)
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		log.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	s := &seeder{
+		queries:  db.New(database).WithTx(tx),
+		tx:       tx,
+		levels:   make(map[string]db.Level),
+		users:    make(map[string]db.User),
+		projects: make(map[string]db.Project),
+		counts:   make(map[string]int),
+	}
+
+	steps := []struct {
+		name string
+		run  func(context.Context, *Fixtures) error
+	}{
+		{"levels", s.seedLevels},
+		{"users", s.seedUsers},
+		{"projects", s.seedProjects},
+		{"fees and history", s.seedHistory},
+		{"payments", s.seedPayments},
+	}
+	for _, step := range steps {
+		if err := step.run(ctx, fixtures); err != nil {
+			log.Fatalf("Failed to seed %s: %v", step.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Failed to commit: %v", err)
+	}
+
+	log.Printf("✓ Seeded %d levels, %d users, %d projects, %d fees, %d payments",
+		s.counts["levels"], s.counts["users"], s.counts["projects"], s.counts["fees"], s.counts["payments"])
+}
+
+func loadFixtures(data string) (*Fixtures, error) {
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML values map directly onto JSON, so the structs can use encoding/json
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures Fixtures
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fixtures); err != nil {
+		return nil, err
+	}
+	return &fixtures, nil
+}
+
+// applyMigrations runs schema migrations in order; 002 imports production data and is skipped
+func applyMigrations(ctx context.Context, database *sql.DB) error {
+	files, err := filepath.Glob("migrations/[0-9]*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		if strings.HasSuffix(file, "002_import_old_data.sql") {
+			continue
+		}
+
+		schema, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if _, err := database.ExecContext(ctx, string(schema)); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		log.Printf("✓ Applied %s", file)
+	}
+	return nil
+}
+
+func (s *seeder) seedLevels(ctx context.Context, f *Fixtures) error {
+	for _, fixture := range f.Levels {
+		active := fixture.Active == nil || *fixture.Active
+		result, err := s.tx.ExecContext(ctx,
+			"INSERT INTO levels (name, amount, active) VALUES (?, ?, ?) ON CONFLICT(name) DO NOTHING",
+			string(fixture.Name), money.FromFloat(fixture.Amount), active)
+		if err != nil {
+			return fmt.Errorf("level %s: %w", fixture.Name, err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			s.counts["levels"]++
+		}
+	}
+
+	levels, err := s.queries.ListAllLevels(ctx)
+	if err != nil {
+		return err
+	}
+	for _, level := range levels {
+		s.levels[level.Name] = level
+	}
+	return nil
+}
+
+func (s *seeder) seedUsers(ctx context.Context, f *Fixtures) error {
+	for _, fixture := range f.Users {
+		email := string(fixture.Email)
+		if email == "" {
+			return fmt.Errorf("user without email")
+		}
+
+		if existing, err := s.queries.GetUserByEmail(ctx, email); err == nil {
+			s.users[email] = existing
+			continue
+		}
+
+		level, ok := s.levels[string(fixture.Level)]
+		if !ok {
+			return fmt.Errorf("user %s: unknown level %q", email, fixture.Level)
+		}
+
+		state := string(fixture.State)
+		if state == "" {
+			state = "accepted"
+		}
+
+		user, err := s.queries.CreateUser(ctx, db.CreateUserParams{
+			KeycloakID:        nullString(fixture.KeycloakID),
+			Email:             email,
+			Username:          nullString(fixture.Username),
+			Realname:          nullString(fixture.Realname),
+			Phone:             nullString(fixture.Phone),
+			AltContact:        sql.NullString{},
+			LevelID:           level.ID,
+			LevelActualAmount: money.FromFloat(fixture.CustomFee),
+			PaymentsID:        nullString(fixture.PaymentsID),
+			State:             state,
+			IsCouncil:         fixture.Council,
+			IsStaff:           fixture.Staff,
+		})
+		if err != nil {
+			return fmt.Errorf("user %s: %w", email, err)
+		}
+
+		// CreateUser always uses the current time
+		if fixture.Joined != "" {
+			joined, err := time.Parse("2006-01-02", string(fixture.Joined))
+			if err != nil {
+				return fmt.Errorf("user %s: invalid joined date: %w", email, err)
+			}
+			if _, err := s.tx.ExecContext(ctx, "UPDATE users SET date_joined = ? WHERE id = ?", joined, user.ID); err != nil {
+				return err
+			}
+			user.DateJoined = joined
+		}
+
+		s.users[email] = user
+		s.counts["users"]++
+	}
+	return nil
+}
+
+func (s *seeder) seedProjects(ctx context.Context, f *Fixtures) error {
+	existing, err := s.queries.ListProjects(ctx)
+	if err != nil {
+		return err
+	}
+	for _, project := range existing {
+		s.projects[project.Name] = project
+	}
+
+	for _, fixture := range f.Projects {
+		name := string(fixture.Name)
+		if _, ok := s.projects[name]; ok {
+			continue
+		}
+
+		project, err := s.queries.CreateProject(ctx, db.CreateProjectParams{
+			Name:        name,
+			PaymentsID:  nullString(fixture.PaymentsID),
+			Description: nullString(fixture.Description),
+		})
+		if err != nil {
+			return fmt.Errorf("project %s: %w", name, err)
+		}
+
+		for _, vs := range append([]text{fixture.PaymentsID}, fixture.ExtraVS...) {
+			if vs == "" {
+				continue
+			}
+			if _, err := s.queries.AddProjectVS(ctx, db.AddProjectVSParams{
+				ProjectID: project.ID,
+				Vs:        string(vs),
+				Note:      sql.NullString{String: "seed", Valid: true},
+			}); err != nil {
+				return fmt.Errorf("project %s VS %s: %w", name, vs, err)
+			}
+		}
+
+		s.projects[name] = project
+		s.counts["projects"]++
+	}
+	return nil
+}
+
+// seedHistory generates monthly fees and matching payments so balances look realistic
+func (s *seeder) seedHistory(ctx context.Context, f *Fixtures) error {
+	now := time.Now()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	for _, fixture := range f.Users {
+		user := s.users[string(fixture.Email)]
+		level := s.levels[string(fixture.Level)]
+
+		amount := money.FromFloat(fixture.CustomFee)
+		if amount == 0 {
+			amount = level.Amount
+		}
+
+		for i := 0; i < fixture.FeeMonths; i++ {
+			// Oldest month first
+			period := thisMonth.AddDate(0, i-fixture.FeeMonths+1, 0)
+
+			if _, err := s.queries.GetFeeByUserAndPeriod(ctx, db.GetFeeByUserAndPeriodParams{
+				UserID:      user.ID,
+				PeriodStart: period,
+			}); err == sql.ErrNoRows {
+				if _, err := s.queries.CreateFee(ctx, db.CreateFeeParams{
+					UserID:      user.ID,
+					LevelID:     level.ID,
+					PeriodStart: period,
+					Amount:      amount,
+					Months:      1,
+				}); err != nil {
+					return fmt.Errorf("fee for %s: %w", user.Email, err)
+				}
+				s.counts["fees"]++
+			} else if err != nil {
+				return err
+			}
+
+			if i >= fixture.PaidMonths || !user.PaymentsID.Valid {
+				continue
+			}
+
+			if err := s.upsertPayment(ctx, db.UpsertPaymentParams{
+				UserID:         sql.NullInt64{Int64: user.ID, Valid: true},
+				Date:           period.AddDate(0, 0, 9),
+				Amount:         amount,
+				KindID:         fmt.Sprintf("fee-%d-%s", user.ID, period.Format("2006-01")),
+				RemoteAccount:  fmt.Sprintf("%09d/2010", 100000+user.ID),
+				Identification: user.PaymentsID.String,
+			}, "CLENSKY PRISPEVEK"); err != nil {
+				return fmt.Errorf("payment for %s: %w", user.Email, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *seeder) seedPayments(ctx context.Context, f *Fixtures) error {
+	for i, fixture := range f.Payments {
+		date, err := time.Parse("2006-01-02", string(fixture.Date))
+		if err != nil {
+			return fmt.Errorf("payment %d: invalid date: %w", i+1, err)
+		}
+
+		params := db.UpsertPaymentParams{
+			Date:           date,
+			Amount:         money.FromFloat(fixture.Amount),
+			KindID:         string(fixture.ID),
+			RemoteAccount:  string(fixture.RemoteAccount),
+			Identification: string(fixture.Identification),
+		}
+		if params.KindID == "" {
+			params.KindID = fmt.Sprintf("payment-%d", i+1)
+		}
+		if params.RemoteAccount == "" {
+			params.RemoteAccount = "2900000000/2010"
+		}
+
+		if fixture.User != "" {
+			user, ok := s.users[string(fixture.User)]
+			if !ok {
+				return fmt.Errorf("payment %d: unknown user %s", i+1, fixture.User)
+			}
+			params.UserID = sql.NullInt64{Int64: user.ID, Valid: true}
+			if params.Identification == "" && user.PaymentsID.Valid {
+				params.Identification = user.PaymentsID.String
+			}
+		}
+
+		if fixture.Project != "" {
+			project, ok := s.projects[string(fixture.Project)]
+			if !ok {
+				return fmt.Errorf("payment %d: unknown project %s", i+1, fixture.Project)
+			}
+			params.ProjectID = sql.NullInt64{Int64: project.ID, Valid: true}
+			if params.Identification == "" && project.PaymentsID.Valid {
+				params.Identification = project.PaymentsID.String
+			}
+		}
+
+		if err := s.upsertPayment(ctx, params, string(fixture.Message)); err != nil {
+			return fmt.Errorf("payment %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// upsertPayment stores a payment in the same shape as the FIO sync does
+func (s *seeder) upsertPayment(ctx context.Context, params db.UpsertPaymentParams, message string) error {
+	rawData, err := json.Marshal(map[string]interface{}{
+		"seed":           true,
+		"amount":         params.Amount,
+		"variableSymbol": params.Identification,
+		"message":        message,
+	})
+	if err != nil {
+		return err
+	}
+
+	params.Kind = "seed"
+	params.LocalAccount = "SEED"
+	params.RawData = sql.NullString{String: string(rawData), Valid: true}
+
+	if _, err := s.queries.UpsertPayment(ctx, params); err != nil {
+		return err
+	}
+	s.counts["payments"]++
+	return nil
+}
+
+func nullString(t text) sql.NullString {
+	return sql.NullString{String: string(t), Valid: t != ""}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
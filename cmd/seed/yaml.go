@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Minimal YAML reader for fixture files, so the seed command needs no extra dependency.
+// Supported: nested maps and lists (block style), "- key: value" list items,
+// inline lists ([a, b]), quoted strings, numbers, booleans, null and # comments.
+// Anchors, multi-line strings and flow maps are not supported.
+
+type yamlLine struct {
+	num    int // 1-based line number for error messages
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+var yamlKeyRe = regexp.MustCompile(`^([A-Za-z0-9_\-]+):(\s+|$)`)
+
+// parseYAML parses a document into map[string]interface{}, []interface{} and scalar values
+// (string, int64, float64, bool, nil)
+func parseYAML(data string) (interface{}, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimLeft(raw, " "), "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		text := strings.TrimRight(stripYAMLComment(raw), " \t")
+		if strings.TrimSpace(text) == "" || text == "---" {
+			continue
+		}
+		trimmed := strings.TrimLeft(text, " ")
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	value, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return value, nil
+}
+
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	if isYAMLListItem(p.lines[p.pos].text) {
+		return p.parseList(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func (p *yamlParser) parseList(indent int) ([]interface{}, error) {
+	list := []interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent || !isYAMLListItem(line.text) {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+
+		rest := strings.TrimLeft(line.text[1:], " ")
+		switch {
+		case rest == "":
+			// Item content is on the following, more indented lines
+			p.pos++
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+				list = append(list, nil)
+				continue
+			}
+			item, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		case yamlKeyRe.MatchString(rest) || isYAMLListItem(rest):
+			// "- key: value" starts a nested block aligned with the text after the dash
+			p.lines[p.pos] = yamlLine{num: line.num, indent: line.indent + len(line.text) - len(rest), text: rest}
+			item, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		default:
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line.num, err)
+			}
+			list = append(list, value)
+			p.pos++
+		}
+	}
+	return list, nil
+}
+
+func (p *yamlParser) parseMap(indent int) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent || isYAMLListItem(line.text) {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+
+		match := yamlKeyRe.FindStringSubmatch(line.text)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", line.num)
+		}
+		key := match[1]
+		if _, exists := m[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		rest := strings.TrimSpace(line.text[len(match[0]):])
+		p.pos++
+
+		if rest != "" {
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line.num, err)
+			}
+			m[key] = value
+			continue
+		}
+
+		// Nested block - lists may start at the same indentation as their key
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent || (next.indent == indent && isYAMLListItem(next.text)) {
+				value, err := p.parseBlock(next.indent)
+				if err != nil {
+					return nil, err
+				}
+				m[key] = value
+				continue
+			}
+		}
+		m[key] = nil
+	}
+	return m, nil
+}
+
+func isYAMLListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func parseYAMLScalar(s string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		value, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted string %s", s)
+		}
+		return value, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, fmt.Errorf("invalid quoted string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated inline list %s", s)
+		}
+		list := []interface{}{}
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		if inner == "" {
+			return list, nil
+		}
+		for _, part := range strings.Split(inner, ",") {
+			value, err := parseYAMLScalar(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+
+	switch s {
+	case "null", "~":
+		return nil, nil
+	case "true", "yes":
+		return true, nil
+	case "false", "no":
+		return false, nil
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return s, nil
+}
+
+// stripYAMLComment removes a trailing # comment that is not inside quotes
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, ch := range line {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -65,10 +65,12 @@ func main() {
 	r := chi.NewRouter()
 
 	// Middleware
-	r.Use(middleware.RealIP)
+	r.Use(h.RealIPMiddleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(h.RateLimitMiddleware)
+	r.Use(h.MaintenanceMiddleware)
 
 	// Static files
 	fileServer := http.FileServer(http.Dir(filepath.Join(cfg.WebRoot, "static")))
@@ -76,50 +78,212 @@ func main() {
 
 	// Public routes
 	r.Get("/", h.HomeHandler)
+	r.With(h.LoadDBUser).Get("/projects/{id}", h.ProjectPageHandler)
+	r.Get("/projects/{id}/qr.png", h.ProjectQRImageHandler)
+	r.Get("/api/projects/{id}/wall", h.ProjectWallAPIHandler)
+	r.Get("/qr/signed/payment.png", h.SignedPaymentQRImageHandler)
+	r.Get("/apply", h.ApplyHandler)
+	r.Post("/api/applications", h.SubmitApplicationHandler)
 
 	// Auth routes
 	r.Route("/auth", func(r chi.Router) {
 		r.Get("/login", authenticator.LoginHandler)
 		r.Get("/callback", authenticator.CallbackHandler)
 		r.Get("/logout", authenticator.LogoutHandler)
+		r.Post("/backchannel-logout", authenticator.BackchannelLogoutHandler)
 	})
 
 	// Protected routes
 	r.Group(func(r chi.Router) {
-		r.Use(authenticator.RequireAuth)
+		r.Use(authenticator.RequireAuth, h.LoadDBUser, h.Impersonation)
 		r.Get("/profile", h.ProfileHandler)
 		r.Post("/profile", h.ProfileHandler)
+		r.Get("/profile/payments.csv", h.ProfilePaymentsCSVHandler)
+		r.Get("/profile/statements/{year}", h.ProfileStatementHandler)
+		r.Get("/profile/statements/{year}/pdf", h.ProfileStatementPDFHandler)
+		r.Get("/invoices/{id}/pdf", h.InvoicePDFHandler)
+		r.Get("/reimbursements/{id}/receipts/{receiptID}", h.ReimbursementReceiptHandler)
+		r.Get("/qr/payment.png", h.PaymentQRImageHandler)
 	})
 
+	// Member API routes (JSON 401 instead of redirecting; session or personal API token)
+	r.Route("/api/me", func(r chi.Router) {
+		r.Use(h.APITokenAuth, authenticator.RequireAPIAuth, h.LoadDBUser, h.Impersonation)
+		r.Get("/upcoming", h.MeUpcomingHandler)
+		r.Get("/payments", h.MePaymentsHandler)
+		r.Get("/balance", h.MeBalanceHandler)
+		r.Get("/ledger", h.MeLedgerHandler)
+		r.Post("/stripe/checkout", h.MeStripeCheckoutHandler)
+		r.Post("/btcpay/invoice", h.MeBTCPayInvoiceHandler)
+		r.Get("/widgets", h.MeWidgetsHandler)
+		r.Post("/widgets", h.MeWidgetSettingsHandler)
+		r.Get("/widgets/payments-year", h.MePaymentsYearWidgetHandler)
+		r.Get("/widgets/balance-trend", h.MeBalanceTrendWidgetHandler)
+		r.Get("/widgets/occupancy", h.MeOccupancyWidgetHandler)
+		r.Get("/onboarding", h.MeOnboardingHandler)
+		r.Get("/termination", h.MeTerminationHandler)
+		r.Post("/termination", h.MeRequestTerminationHandler)
+		r.Delete("/termination", h.MeCancelTerminationHandler)
+		r.Get("/notifications", h.MeNotificationsHandler)
+		r.Post("/notifications", h.MeNotificationSettingsHandler)
+		r.Post("/qr-format", h.MeQRFormatHandler)
+		r.Post("/billing-cycle", h.MeBillingCycleHandler)
+		r.Get("/level-change", h.MeLevelChangesHandler)
+		r.Post("/level-change", h.MeRequestLevelChangeHandler)
+		r.Get("/tokens", h.MeAPITokensHandler)
+		r.Post("/tokens", h.MeCreateAPITokenHandler)
+		r.Delete("/tokens/{id}", h.MeRevokeAPITokenHandler)
+		r.Get("/projects", h.MeProjectsHandler)
+		r.Post("/projects/{id}/members", h.MeJoinProjectHandler)
+		r.Delete("/projects/{id}/members", h.MeLeaveProjectHandler)
+		r.Post("/projects/{id}/wall", h.MeJoinProjectWallHandler)
+		r.Delete("/projects/{id}/wall", h.MeLeaveProjectWallHandler)
+		r.Get("/applications", h.MeApplicationsHandler)
+		r.Post("/applications/{id}/vouch", h.MeVouchApplicationHandler)
+		r.Delete("/applications/{id}/vouch", h.MeUnvouchApplicationHandler)
+		r.Get("/billing", h.MeBillingHandler)
+		r.Post("/billing", h.MeUpdateBillingHandler)
+		r.Get("/invoices", h.MeInvoicesHandler)
+		r.Post("/invoices", h.MeRequestInvoiceHandler)
+		r.Post("/invoices/items", h.MeItemInvoiceHandler)
+		r.Get("/reimbursements", h.MeReimbursementsHandler)
+		r.Post("/reimbursements", h.MeSubmitReimbursementHandler)
+		r.Get("/tickets", h.MeTicketsHandler)
+		r.Post("/tickets", h.MeCreateTicketHandler)
+		r.Post("/tickets/{id}/reply", h.MeReplyTicketHandler)
+	})
+
+	// Payment ingestion for external collectors (per-source token, no session)
+	r.Post("/api/ingest/payments", h.IngestPaymentsHandler)
+
+	// Inbound support emails from the mail provider (SUPPORT_INBOUND_TOKEN, no session)
+	r.Post("/api/ingest/email", h.InboundEmailHandler)
+
+	// Stripe webhook events (signed with STRIPE_WEBHOOK_SECRET, no session)
+	r.Post("/api/stripe/webhook", h.StripeWebhookHandler)
+
+	// BTCPay Server webhook (signed with BTCPAY_WEBHOOK_SECRET, no session)
+	r.Post("/api/btcpay/webhook", h.BTCPayWebhookHandler)
+
 	// Admin routes (requires memberportal_admin role)
 	r.Route("/admin", func(r chi.Router) {
-		r.Use(authenticator.RequireAuth)
-		r.Get("/users", h.RequireAdmin(h.AdminUsersHandler))
-		r.Get("/users/{id}", h.RequireAdmin(h.AdminUserProfileHandler))
-		r.Get("/payments/unmatched", h.RequireAdmin(h.AdminUnmatchedPaymentsHandler))
-		r.Get("/projects", h.RequireAdmin(h.AdminProjectsHandler))
-		r.Get("/logs", h.RequireAdmin(h.AdminLogsHandler))
-		r.Get("/settings", h.RequireAdmin(h.AdminSettingsHandler))
+		r.Use(authenticator.RequireAuth, auth.RequireRole(auth.RoleAdmin), h.LoadDBUser)
+		r.Get("/users", h.AdminUsersHandler)
+		r.Get("/users/{id}", h.AdminUserProfileHandler)
+		r.Get("/applications", h.AdminApplicationsHandler)
+		r.Get("/payments/unmatched", h.AdminUnmatchedPaymentsHandler)
+		r.Get("/projects", h.AdminProjectsHandler)
+		r.Get("/invoices", h.AdminInvoicesHandler)
+		r.Get("/reimbursements", h.AdminReimbursementsHandler)
+		r.Get("/expenses", h.AdminExpensesHandler)
+		r.Get("/donations", h.AdminDonationsHandler)
+		r.Get("/donations.csv", h.AdminDonationsCSVHandler)
+		r.Get("/donations/receipts/{userID}", h.AdminDonationReceiptHandler)
+		r.Get("/accounting/export", h.AdminAccountingExportHandler)
+		r.Get("/reimbursements/batches/{id}", h.AdminReimbursementBatchHandler)
+		r.Get("/tickets", h.AdminTicketsHandler)
+		r.Get("/tickets/{id}", h.AdminTicketHandler)
+		r.Get("/logs", h.AdminLogsHandler)
+		r.Get("/audit", h.AdminAuditHandler)
+		r.Get("/settings", h.AdminSettingsHandler)
 	})
 
-	// Admin API routes (requires memberportal_admin role)
+	// Admin API routes (requires memberportal_admin role; session or personal API token;
+	// mutating calls are recorded in the audit log)
 	r.Route("/api/admin", func(r chi.Router) {
-		r.Use(authenticator.RequireAuth)
-		r.Get("/users", h.RequireAdmin(h.AdminUsersAPIHandler))
-		r.Post("/roles/assign", h.RequireAdmin(h.AdminAssignRoleHandler))
-		r.Post("/roles/remove", h.RequireAdmin(h.AdminRemoveRoleHandler))
-		r.Get("/users/roles", h.RequireAdmin(h.AdminGetUserRolesHandler))
-		r.Post("/test-email", h.RequireAdmin(h.AdminTestEmailHandler))
-		r.Post("/payments/assign", h.RequireAdmin(h.AdminAssignPaymentHandler))
-		r.Post("/payments/update", h.RequireAdmin(h.AdminUpdatePaymentHandler))
-		r.Post("/payments/dismiss", h.RequireAdmin(h.AdminDismissPaymentHandler))
-		r.Post("/payments/undismiss", h.RequireAdmin(h.AdminUndismissPaymentHandler))
-		r.Get("/projects", h.RequireAdmin(h.AdminProjectsAPIHandler))
-		r.Post("/projects", h.RequireAdmin(h.AdminCreateProjectHandler))
-		r.Delete("/projects", h.RequireAdmin(h.AdminDeleteProjectHandler))
-		r.Get("/projects/payments", h.RequireAdmin(h.AdminProjectPaymentsHandler))
-		r.Post("/projects/vs", h.RequireAdmin(h.AdminAddProjectVSHandler))
-		r.Delete("/projects/vs", h.RequireAdmin(h.AdminRemoveProjectVSHandler))
+		r.Use(h.APITokenAuth, authenticator.RequireAuth, auth.RequireRole(auth.RoleAdmin), h.LoadDBUser, h.AdminAudit)
+		r.Get("/users", h.AdminUsersAPIHandler)
+		r.Post("/users", h.AdminCreateUserHandler)
+		r.Post("/graphql", h.AdminGraphQLHandler)
+		r.Post("/impersonate/{userID}", h.AdminImpersonateHandler)
+		r.Delete("/impersonate", h.AdminStopImpersonationHandler)
+		r.Post("/roles/assign", h.AdminAssignRoleHandler)
+		r.Post("/roles/remove", h.AdminRemoveRoleHandler)
+		r.Get("/users/roles", h.AdminGetUserRolesHandler)
+		r.Post("/users/merge", h.AdminMergeUsersHandler)
+		r.Post("/keycloak/refresh", h.AdminKeycloakRefreshHandler)
+		r.Post("/keycloak/otp-reminder", h.AdminOTPReminderHandler)
+		r.Put("/users/{id}", h.AdminUpdateUserHandler)
+		r.Post("/users/{id}/keycloak/enable", h.AdminEnableKeycloakUserHandler)
+		r.Post("/users/{id}/keycloak/disable", h.AdminDisableKeycloakUserHandler)
+		r.Post("/users/{id}/keycloak/provision", h.AdminProvisionKeycloakUserHandler)
+		r.Post("/users/{id}/keycloak/resolve", h.AdminResolveKeycloakDiffHandler)
+		r.Post("/users/{id}/pause", h.AdminSetMembershipPauseHandler)
+		r.Delete("/users/{id}/pause", h.AdminClearMembershipPauseHandler)
+		r.Post("/users/{id}/suspension-exemption", h.AdminSetSuspensionExemptionHandler)
+		r.Delete("/users/{id}/suspension-exemption", h.AdminDeleteSuspensionExemptionHandler)
+		r.Post("/users/{id}/payment-plan", h.AdminSetPaymentPlanHandler)
+		r.Delete("/users/{id}/payment-plan", h.AdminEndPaymentPlanHandler)
+		r.Post("/users/{id}/charges", h.AdminCreateChargeHandler)
+		r.Post("/users/{id}/invoices", h.AdminItemInvoiceHandler)
+		r.Post("/users/{id}/fakturoid", h.AdminSetFakturoidHandler)
+		r.Post("/users/{id}/onboarding", h.AdminSetOnboardingTaskHandler)
+		r.Delete("/users/{id}/termination", h.AdminCancelTerminationHandler)
+		r.Get("/users/{id}/anonymize", h.AdminAnonymizePreviewHandler)
+		r.Post("/users/{id}/anonymize", h.AdminAnonymizeHandler)
+		r.Post("/users/{id}/notes", h.AdminCreateUserNoteHandler)
+		r.Put("/users/{id}/notes/{noteID}", h.AdminUpdateUserNoteHandler)
+		r.Delete("/users/{id}/notes/{noteID}", h.AdminDeleteUserNoteHandler)
+		r.Delete("/charges/{id}", h.AdminDeleteChargeHandler)
+		r.Post("/fees/{id}/adjust", h.AdminAdjustFeeHandler)
+		r.Post("/users/{id}/credit", h.AdminCreditUserHandler)
+		r.Get("/reports/balances", h.AdminBalanceReportHandler)
+		r.Post("/donations/receipts/send", h.AdminSendDonationReceiptsHandler)
+		r.Post("/test-email", h.AdminTestEmailHandler)
+		r.Get("/maintenance", h.AdminMaintenanceHandler)
+		r.Post("/maintenance", h.AdminSetMaintenanceHandler)
+		r.Get("/debt-thresholds", h.AdminDebtThresholdsHandler)
+		r.Post("/debt-thresholds", h.AdminSetDebtThresholdsHandler)
+		r.Delete("/debt-thresholds", h.AdminResetDebtThresholdsHandler)
+		r.Post("/fee-changes", h.AdminCreateFeeChangeHandler)
+		r.Delete("/fee-changes/{id}", h.AdminDeleteFeeChangeHandler)
+		r.Get("/level-changes", h.AdminLevelChangesHandler)
+		r.Post("/level-changes/{id}/approve", h.AdminApproveLevelChangeHandler)
+		r.Post("/level-changes/{id}/reject", h.AdminRejectLevelChangeHandler)
+		r.Post("/payments", h.AdminCreateManualPaymentHandler)
+		r.Post("/payments/assign", h.AdminAssignPaymentHandler)
+		r.Post("/payments/update", h.AdminUpdatePaymentHandler)
+		r.Post("/payments/dismiss", h.AdminDismissPaymentHandler)
+		r.Post("/payments/undismiss", h.AdminUndismissPaymentHandler)
+		r.Post("/payments/reversal/link", h.AdminLinkReversalHandler)
+		r.Post("/payments/import", h.AdminImportBankStatementHandler)
+		r.Post("/payments/{id}/assign", h.AdminAssignPaymentByIDHandler)
+		r.Post("/payments/{id}/ignore", h.AdminIgnorePaymentHandler)
+		r.Post("/payments/{id}/suggestion/reject", h.AdminRejectPaymentSuggestionHandler)
+		r.Post("/payments/{id}/duplicate/merge", h.AdminMergeDuplicatePaymentHandler)
+		r.Post("/payments/{id}/duplicate/ignore", h.AdminIgnoreDuplicatePaymentHandler)
+		r.Get("/payments/{id}/splits", h.AdminPaymentSplitsHandler)
+		r.Post("/payments/{id}/splits", h.AdminSplitPaymentHandler)
+		r.Delete("/payments/{id}/splits", h.AdminDeletePaymentSplitsHandler)
+		r.Post("/payments/{id}/classification", h.AdminSetPaymentClassificationHandler)
+		r.Get("/payments/rules", h.AdminPaymentMatchRulesHandler)
+		r.Post("/payments/rules", h.AdminCreatePaymentMatchRuleHandler)
+		r.Post("/payments/rules/{id}", h.AdminUpdatePaymentMatchRuleHandler)
+		r.Delete("/payments/rules/{id}", h.AdminDeletePaymentMatchRuleHandler)
+		r.Get("/projects", h.AdminProjectsAPIHandler)
+		r.Post("/projects", h.AdminCreateProjectHandler)
+		r.Delete("/projects", h.AdminDeleteProjectHandler)
+		r.Get("/projects/payments", h.AdminProjectPaymentsHandler)
+		r.Get("/projects/ledger", h.AdminProjectLedgerHandler)
+		r.Post("/projects/public", h.AdminSetProjectPublicHandler)
+		r.Post("/projects/btcpay", h.AdminSetProjectBTCPayHandler)
+		r.Post("/projects/target", h.AdminSetProjectTargetHandler)
+		r.Post("/levels/btcpay", h.AdminSetLevelBTCPayHandler)
+		r.Get("/projects/members", h.AdminProjectMembersHandler)
+		r.Get("/projects/wall", h.AdminProjectWallHandler)
+		r.Post("/projects/wall", h.AdminModerateProjectWallHandler)
+		r.Post("/projects/vs", h.AdminAddProjectVSHandler)
+		r.Delete("/projects/vs", h.AdminRemoveProjectVSHandler)
+		r.Post("/applications/{id}/approve", h.AdminApproveApplicationHandler)
+		r.Post("/applications/{id}/reject", h.AdminRejectApplicationHandler)
+		r.Post("/invoices/{id}/approve", h.AdminApproveInvoiceHandler)
+		r.Post("/invoices/{id}/reject", h.AdminRejectInvoiceHandler)
+		r.Post("/reimbursements/{id}/approve", h.AdminApproveReimbursementHandler)
+		r.Post("/reimbursements/{id}/reject", h.AdminRejectReimbursementHandler)
+		r.Post("/reimbursements/export", h.AdminExportReimbursementsHandler)
+		r.Post("/expenses/{id}", h.AdminUpdateExpenseHandler)
+		r.Post("/tickets/{id}/reply", h.AdminReplyTicketHandler)
+		r.Post("/tickets/{id}/state", h.AdminTicketStateHandler)
 	})
 
 	// Create server
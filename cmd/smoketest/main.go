@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Smoke test běžící instance portálu (po každém nasazení)
+//
+// Použití:
+//   SMOKETEST_USERNAME=smoketest SMOKETEST_PASSWORD=... go run ./cmd/smoketest --url https://members.base48.cz
+//   go run ./cmd/smoketest --url http://localhost:4848 --skip-admin
+//
+// Přihlásí se přes Keycloak login formulář testovacím uživatelem (bez OTP), načte
+// profil včetně QR platby, zavolá členské API a s admin rolí i hlavní admin API.
+// Na stdout vypíše výsledek jako JSON, průběh jde na stderr. Exit kód 1 = něco selhalo.
+//
+// Testovací uživatel potřebuje v Keycloaku roli memberportal_admin (jinak --skip-admin)
+// a v portálu variabilní symbol, aby se na profilu zobrazil QR kód platby.
+
+// Check is the result of one step
+type Check struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"` // pass, fail, skip
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report is the machine-readable output
+type Report struct {
+	URL        string    `json:"url"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Passed     bool      `json:"passed"`
+	Checks     []Check   `json:"checks"`
+}
+
+var (
+	// loginFormAction finds the Keycloak login form (theme independent enough:
+	// the default and most custom themes keep the kc-form-login ID)
+	loginFormAction = regexp.MustCompile(`(?s)<form[^>]*id="kc-form-login"[^>]*action="([^"]+)"|<form[^>]*action="([^"]+)"[^>]*id="kc-form-login"`)
+	loginError      = regexp.MustCompile(`(?s)id="input-error[^"]*"[^>]*>\s*([^<]+)`)
+	paymentQRImage  = regexp.MustCompile(`<img src="(/qr/payment\.png[^"]*)" alt="QR platba"`)
+)
+
+type smoketest struct {
+	baseURL *url.URL
+	client  *http.Client
+	report  Report
+
+	profile []byte // profile page from the login step, reused by the QR check
+}
+
+func main() {
+	baseURL := flag.String("url", envOr("SMOKETEST_URL", "http://localhost:4848"), "portal base URL")
+	username := flag.String("username", os.Getenv("SMOKETEST_USERNAME"), "Keycloak test user")
+	password := flag.String("password", os.Getenv("SMOKETEST_PASSWORD"), "password of the test user")
+	skipAdmin := flag.Bool("skip-admin", false, "skip admin API checks (test user without admin role)")
+	timeout := flag.Duration("timeout", 15*time.Second, "timeout of a single request")
+	flag.Parse()
+
+	if *username == "" || *password == "" {
+		log.Fatal("SMOKETEST_USERNAME and SMOKETEST_PASSWORD (or --username/--password) are required")
+	}
+
+	u, err := url.Parse(strings.TrimRight(*baseURL, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		log.Fatalf("Invalid --url %q", *baseURL)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	t := &smoketest{
+		baseURL: u,
+		client:  &http.Client{Jar: jar, Timeout: *timeout},
+		report:  Report{URL: u.String(), StartedAt: time.Now().UTC(), Passed: true},
+	}
+
+	t.run("home", t.checkHome)
+	loggedIn := t.run("login", func() error { return t.checkLogin(*username, *password) })
+	if loggedIn {
+		t.run("payment_qr", t.checkPaymentQR)
+		t.run("api_me_upcoming", func() error { return t.checkJSON("GET", "/api/me/upcoming", nil) })
+		t.run("api_me_widgets", func() error { return t.checkJSON("GET", "/api/me/widgets", nil) })
+
+		adminChecks := []struct {
+			name   string
+			method string
+			path   string
+			body   interface{}
+		}{
+			{"api_admin_users", "GET", "/api/admin/users", nil},
+			{"api_admin_maintenance", "GET", "/api/admin/maintenance", nil},
+			{"api_admin_projects", "GET", "/api/admin/projects", nil},
+			{"api_admin_graphql", "POST", "/api/admin/graphql", map[string]string{"query": "{ levels { id name amount } }"}},
+		}
+		for _, c := range adminChecks {
+			if *skipAdmin {
+				t.skip(c.name)
+				continue
+			}
+			t.run(c.name, func() error { return t.checkJSON(c.method, c.path, c.body) })
+		}
+
+		t.run("logout", t.checkLogout)
+	} else {
+		for _, name := range []string{"payment_qr", "api_me_upcoming", "api_me_widgets", "api_admin_users",
+			"api_admin_maintenance", "api_admin_projects", "api_admin_graphql", "logout"} {
+			t.skip(name)
+		}
+	}
+
+	t.report.DurationMS = time.Since(t.report.StartedAt).Milliseconds()
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(t.report)
+
+	if !t.report.Passed {
+		os.Exit(1)
+	}
+}
+
+// run executes one check and records its result
+func (t *smoketest) run(name string, check func() error) bool {
+	start := time.Now()
+	err := check()
+	result := Check{Name: name, Status: "pass", DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = "fail"
+		result.Error = err.Error()
+		t.report.Passed = false
+		log.Printf("✗ %s: %v", name, err)
+	} else {
+		log.Printf("✓ %s (%d ms)", name, result.DurationMS)
+	}
+	t.report.Checks = append(t.report.Checks, result)
+	return err == nil
+}
+
+func (t *smoketest) skip(name string) {
+	log.Printf("- %s skipped", name)
+	t.report.Checks = append(t.report.Checks, Check{Name: name, Status: "skip"})
+}
+
+func (t *smoketest) url(path string) string {
+	return t.baseURL.String() + path
+}
+
+// get fetches a page and fails on anything but 200
+func (t *smoketest) get(path string) (*http.Response, []byte, error) {
+	resp, err := t.client.Get(t.url(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("GET %s: HTTP %d", path, resp.StatusCode)
+	}
+	return resp, body, nil
+}
+
+func (t *smoketest) checkHome() error {
+	_, body, err := t.get("/")
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(body, []byte("Base48")) {
+		return fmt.Errorf("home page does not look like the portal")
+	}
+	return nil
+}
+
+// checkLogin goes through /auth/login, submits the Keycloak login form and
+// expects to land on the profile page
+func (t *smoketest) checkLogin(username, password string) error {
+	resp, body, err := t.get("/auth/login")
+	if err != nil {
+		return err
+	}
+
+	match := loginFormAction.FindSubmatch(body)
+	if match == nil {
+		return fmt.Errorf("Keycloak login form not found at %s", resp.Request.URL.Host)
+	}
+	action := string(match[1])
+	if action == "" {
+		action = string(match[2])
+	}
+	formURL, err := resp.Request.URL.Parse(html.UnescapeString(action))
+	if err != nil {
+		return fmt.Errorf("invalid login form action: %w", err)
+	}
+
+	resp, err = t.client.PostForm(formURL.String(), url.Values{
+		"username":     {username},
+		"password":     {password},
+		"credentialId": {""},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// Wrong password or a required action (OTP, password update) keeps us at Keycloak
+	if resp.Request.URL.Host != t.baseURL.Host {
+		if match := loginError.FindSubmatch(body); match != nil {
+			return fmt.Errorf("login rejected: %s", strings.TrimSpace(html.UnescapeString(string(match[1]))))
+		}
+		return fmt.Errorf("login did not return to the portal (OTP or another required action set for the test user?)")
+	}
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.Path != "/profile" {
+		return fmt.Errorf("expected profile after login, got HTTP %d at %s", resp.StatusCode, resp.Request.URL.Path)
+	}
+
+	t.profile = body
+	return nil
+}
+
+// checkPaymentQR fetches the payment QR image linked from the profile page
+func (t *smoketest) checkPaymentQR() error {
+	match := paymentQRImage.FindSubmatch(t.profile)
+	if match == nil {
+		return fmt.Errorf("no payment QR code on the profile (BANK_IBAN set, test user has a variable symbol?)")
+	}
+	resp, png, err := t.get(html.UnescapeString(string(match[1])))
+	if err != nil {
+		return err
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "image/png" {
+		return fmt.Errorf("QR image served as %q", ct)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG\r\n\x1a\n")) {
+		return fmt.Errorf("QR image is not a PNG")
+	}
+	return nil
+}
+
+// checkJSON calls an API endpoint and expects a JSON answer that does not
+// report "success": false or GraphQL errors
+func (t *smoketest) checkJSON(method, path string, body interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, t.url(path), reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: HTTP %d", method, path, resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		// Typically an expired session redirected to the Keycloak login page
+		return fmt.Errorf("%s %s: expected JSON, got %q", method, path, resp.Header.Get("Content-Type"))
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("%s %s: invalid JSON: %w", method, path, err)
+	}
+	if success, ok := result["success"].(bool); ok && !success {
+		return fmt.Errorf("%s %s: %v", method, path, result["error"])
+	}
+	if errs, ok := result["errors"].([]interface{}); ok && len(errs) > 0 {
+		return fmt.Errorf("%s %s: %v", method, path, errs[0])
+	}
+	return nil
+}
+
+// checkLogout ends the portal and Keycloak session so test runs do not pile up
+// SSO sessions of the test user
+func (t *smoketest) checkLogout() error {
+	if _, _, err := t.get("/auth/logout"); err != nil {
+		return err
+	}
+	resp, err := t.client.Get(t.url("/profile"))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.Request.URL.Host == t.baseURL.Host && resp.Request.URL.Path == "/profile" {
+		return fmt.Errorf("profile still accessible after logout")
+	}
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
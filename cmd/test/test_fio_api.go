@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/joho/godotenv"
 
+	"github.com/base48/member-portal/internal/bank"
 	"github.com/base48/member-portal/internal/config"
 	"github.com/base48/member-portal/internal/fio"
 )
@@ -36,7 +38,7 @@ func main() {
 	log.Println("✓ FIO token loaded")
 
 	// Create FIO API client
-	fioClient := fio.NewClient(cfg.BankFIOToken)
+	fioClient := fio.NewClient(cfg.BankFIOToken, bank.FIOOptions(cfg))
 	ctx := context.Background()
 
 	// Fetch last 7 days of transactions as a test
@@ -52,6 +54,9 @@ func main() {
 		fio.FormatDate(dateTo),
 	)
 
+	if errors.Is(err, fio.ErrRateLimited) {
+		log.Fatalf("Failed to fetch transactions: %v - is sync_fio_payments running with the same token?", err)
+	}
 	if err != nil {
 		log.Fatalf("Failed to fetch transactions: %v", err)
 	}
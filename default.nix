@@ -22,7 +22,17 @@ pkgs.buildGoModule rec {
 
     go build -ldflags="-s -w" -o $out/bin/portal cmd/server/main.go
     go build -ldflags="-s -w" -o $out/bin/sync_fio_payments cmd/cron/sync_fio_payments.go
+    go build -ldflags="-s -w" -o $out/bin/import_bank_statement cmd/cron/import_bank_statement.go
     go build -ldflags="-s -w" -o $out/bin/update_debt_status cmd/cron/update_debt_status.go
+    go build -ldflags="-s -w" -o $out/bin/send_email_campaign cmd/cron/send_email_campaign.go
+    go build -ldflags="-s -w" -o $out/bin/provision_keycloak_accounts cmd/cron/provision_keycloak_accounts.go
+    go build -ldflags="-s -w" -o $out/bin/sync_membership_roles cmd/cron/sync_membership_roles.go
+    go build -ldflags="-s -w" -o $out/bin/suspend_debtors cmd/cron/suspend_debtors.go
+    go build -ldflags="-s -w" -o $out/bin/complete_terminations cmd/cron/complete_terminations.go
+    go build -ldflags="-s -w" -o $out/bin/anonymize_member cmd/cron/anonymize_member.go
+    go build -ldflags="-s -w" -o $out/bin/snapshot_balances cmd/cron/snapshot_balances.go
+    go build -ldflags="-s -w" -o $out/bin/send_payment_statements cmd/cron/send_payment_statements.go
+    go build -ldflags="-s -w" -o $out/bin/sync_fakturoid cmd/cron/sync_fakturoid.go
 
     cp -r web/templates $out/share/portal/web/
     cp -r web/static $out/share/portal/web/
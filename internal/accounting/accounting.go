@@ -0,0 +1,393 @@
+// Package accounting exports payments, fees and one-off charges of a period for
+// the treasurer's accounting system: CSV with a fixed column layout and the XML
+// imports of Pohoda and Money S3, so nothing has to be re-typed.
+package accounting
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fio"
+	"github.com/base48/member-portal/internal/invoice"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// Entry kinds (ListAccountingEntries entry_type)
+const (
+	KindPayment = "payment"
+	KindFee     = "fee"
+	KindCharge  = "charge"
+)
+
+// Formats of the export
+const (
+	FormatCSV    = "csv"
+	FormatPohoda = "pohoda"
+	FormatMoney  = "money"
+)
+
+// Entry is one record of the export
+type Entry struct {
+	Kind     string
+	ID       int64 // ID of the payment, fee or charge
+	Date     time.Time
+	Amount   money.Amount // payments as on the account, fees and charges positive
+	VS       string       // of the payment; the member's VS for fees and charges
+	Member   string       // name, or email of members without one; empty for unmatched payments
+	Email    string
+	Account  string // counter-account of a payment
+	Category string // see ListAccountingEntries
+	Text     string
+}
+
+// Document is the stable document number of the entry ("P123" for payment 123, "F45"
+// for fee 45, "C7" for charge 7); a repeated import can be matched on it
+func (e Entry) Document() string {
+	prefix := map[string]string{KindPayment: "P", KindFee: "F", KindCharge: "C"}[e.Kind]
+	return prefix + strconv.FormatInt(e.ID, 10)
+}
+
+// Label is the Czech name of the entry kind
+func (e Entry) Label() string {
+	switch e.Kind {
+	case KindPayment:
+		return "Platba"
+	case KindFee:
+		return "Příspěvek"
+	default:
+		return "Poplatek"
+	}
+}
+
+// Period is a calendar month, or the whole year when Month is 0
+type Period struct {
+	Year  int
+	Month int
+}
+
+// Range returns the first day of the period and the first day after it
+func (p Period) Range() (from, to time.Time) {
+	if p.Month == 0 {
+		from = time.Date(p.Year, 1, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, 0)
+	}
+	from = time.Date(p.Year, time.Month(p.Month), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 1, 0)
+}
+
+// String formats the period for file names and batch IDs ("2026" or "2026-03")
+func (p Period) String() string {
+	if p.Month == 0 {
+		return strconv.Itoa(p.Year)
+	}
+	return fmt.Sprintf("%d-%02d", p.Year, p.Month)
+}
+
+// Load returns the entries of the period, oldest first
+func Load(ctx context.Context, queries *db.Queries, period Period) ([]Entry, error) {
+	from, to := period.Range()
+	rows, err := queries.ListAccountingEntries(ctx, db.ListAccountingEntriesParams{
+		DateFrom: from.Format("2006-01-02"),
+		DateTo:   to.Format("2006-01-02"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(rows))
+	for _, row := range rows {
+		e := Entry{
+			Kind:     row.EntryType,
+			ID:       row.EntryID,
+			Date:     row.Date,
+			Amount:   row.Amount,
+			VS:       row.Vs,
+			Member:   row.Realname,
+			Email:    row.Email,
+			Account:  row.RemoteAccount,
+			Category: row.Category,
+		}
+		if e.Member == "" {
+			e.Member = row.Email
+		}
+		switch row.EntryType {
+		case KindPayment:
+			e.Text = "Přijatá platba"
+			if row.Note != "" {
+				e.Text += " - projekt " + row.Note
+			}
+		case KindFee:
+			e.Text = invoice.FeeItem(db.Fee{PeriodStart: row.Date, Months: row.Months})
+		default:
+			e.Text = row.Note
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// csvHeader is the column layout of the CSV export; columns are only ever appended
+var csvHeader = []string{"Doklad", "Typ", "Datum", "Částka (Kč)", "VS", "Člen", "E-mail", "Protiúčet", "Kategorie", "Text"}
+
+// EncodeCSV builds the CSV export in the format of the other exports (UTF-8 with
+// BOM, semicolons, decimal comma)
+func EncodeCSV(entries []Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	// Excel detects UTF-8 only with the byte order mark
+	buf.WriteString("\ufeff")
+
+	out := csv.NewWriter(&buf)
+	out.Comma = ';'
+	out.Write(csvHeader)
+	for _, e := range entries {
+		out.Write([]string{
+			e.Document(),
+			e.Label(),
+			e.Date.Format("2006-01-02"),
+			strings.Replace(e.Amount.String(), ".", ",", 1),
+			e.VS,
+			e.Member,
+			e.Email,
+			e.Account,
+			e.Category,
+			e.Text,
+		})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return nil, fmt.Errorf("failed to encode CSV export: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Pohoda XML import (dataPack version 2): payments become bank documents, fees and
+// charges other receivables. The amounts are without VAT (the association is not
+// a VAT payer).
+
+type pohodaPack struct {
+	XMLName     xml.Name         `xml:"dat:dataPack"`
+	XMLNSDat    string           `xml:"xmlns:dat,attr"`
+	XMLNSBnk    string           `xml:"xmlns:bnk,attr"`
+	XMLNSInv    string           `xml:"xmlns:inv,attr"`
+	XMLNSTyp    string           `xml:"xmlns:typ,attr"`
+	ID          string           `xml:"id,attr"`
+	ICO         string           `xml:"ico,attr"`
+	Application string           `xml:"application,attr"`
+	Version     string           `xml:"version,attr"`
+	Note        string           `xml:"note,attr"`
+	Items       []pohodaPackItem `xml:"dat:dataPackItem"`
+}
+
+type pohodaPackItem struct {
+	ID      string         `xml:"id,attr"`
+	Version string         `xml:"version,attr"`
+	Bank    *pohodaBank    `xml:"bnk:bank"`
+	Invoice *pohodaInvoice `xml:"inv:invoice"`
+}
+
+type pohodaPartner struct {
+	Name  string `xml:"typ:address>typ:name"`
+	Email string `xml:"typ:address>typ:email,omitempty"`
+}
+
+type pohodaAccount struct {
+	AccountNo string `xml:"typ:accountNo"`
+	BankCode  string `xml:"typ:bankCode,omitempty"`
+}
+
+type pohodaBank struct {
+	Version       string         `xml:"version,attr"`
+	BankType      string         `xml:"bnk:bankHeader>bnk:bankType"`
+	Number        string         `xml:"bnk:bankHeader>bnk:number>typ:numberRequested"`
+	SymVar        string         `xml:"bnk:bankHeader>bnk:symVar,omitempty"`
+	DateStatement string         `xml:"bnk:bankHeader>bnk:dateStatement"`
+	DatePayment   string         `xml:"bnk:bankHeader>bnk:datePayment"`
+	Text          string         `xml:"bnk:bankHeader>bnk:text"`
+	Partner       *pohodaPartner `xml:"bnk:bankHeader>bnk:partnerIdentity,omitempty"`
+	Account       *pohodaAccount `xml:"bnk:bankHeader>bnk:paymentAccount,omitempty"`
+	Price         string         `xml:"bnk:bankSummary>bnk:homeCurrency>typ:priceNone"`
+}
+
+type pohodaInvoice struct {
+	Version     string         `xml:"version,attr"`
+	InvoiceType string         `xml:"inv:invoiceHeader>inv:invoiceType"`
+	Number      string         `xml:"inv:invoiceHeader>inv:number>typ:numberRequested"`
+	SymVar      string         `xml:"inv:invoiceHeader>inv:symVar,omitempty"`
+	Date        string         `xml:"inv:invoiceHeader>inv:date"`
+	DateAccount string         `xml:"inv:invoiceHeader>inv:dateAccounting"`
+	Text        string         `xml:"inv:invoiceHeader>inv:text"`
+	Partner     *pohodaPartner `xml:"inv:invoiceHeader>inv:partnerIdentity,omitempty"`
+	Price       string         `xml:"inv:invoiceSummary>inv:homeCurrency>typ:priceNone"`
+}
+
+// EncodePohoda builds the Pohoda XML import of the entries; ico is the IČO of the
+// accounting unit, Pohoda refuses a data pack for another one
+func EncodePohoda(entries []Entry, period Period, ico string) ([]byte, error) {
+	pack := pohodaPack{
+		XMLNSDat:    "http://www.stormware.cz/schema/version_2/data.xsd",
+		XMLNSBnk:    "http://www.stormware.cz/schema/version_2/bank.xsd",
+		XMLNSInv:    "http://www.stormware.cz/schema/version_2/invoice.xsd",
+		XMLNSTyp:    "http://www.stormware.cz/schema/version_2/type.xsd",
+		ID:          "base48-" + period.String(),
+		ICO:         ico,
+		Application: "Base48 member portal",
+		Version:     "2.0",
+		Note:        "Platby, příspěvky a poplatky " + period.String(),
+	}
+	for _, e := range entries {
+		date := e.Date.Format("2006-01-02")
+		item := pohodaPackItem{ID: e.Document(), Version: "2.0"}
+		var partner *pohodaPartner
+		if e.Member != "" {
+			partner = &pohodaPartner{Name: e.Member, Email: e.Email}
+		}
+
+		if e.Kind == KindPayment {
+			bank := &pohodaBank{
+				Version:       "2.0",
+				BankType:      "receipt",
+				Number:        e.Document(),
+				SymVar:        e.VS,
+				DateStatement: date,
+				DatePayment:   date,
+				Text:          e.Text,
+				Partner:       partner,
+				Price:         e.Amount.String(),
+			}
+			if e.Amount < 0 {
+				bank.BankType = "expense"
+				bank.Price = (-e.Amount).String()
+			}
+			if e.Account != "" {
+				number, bankCode, err := fio.ParseAccount(e.Account)
+				if err != nil {
+					number, bankCode = e.Account, ""
+				}
+				bank.Account = &pohodaAccount{AccountNo: number, BankCode: bankCode}
+			}
+			item.Bank = bank
+		} else {
+			item.Invoice = &pohodaInvoice{
+				Version:     "2.0",
+				InvoiceType: "receivable",
+				Number:      e.Document(),
+				SymVar:      e.VS,
+				Date:        date,
+				DateAccount: date,
+				Text:        e.Text,
+				Partner:     partner,
+				Price:       e.Amount.String(),
+			}
+		}
+		pack.Items = append(pack.Items, item)
+	}
+
+	return encodeXML(pack, "Pohoda")
+}
+
+// Money S3 XML import (MoneyData): payments become bank documents, fees and charges
+// other receivables.
+
+type moneyData struct {
+	XMLName     xml.Name          `xml:"MoneyData"`
+	Description string            `xml:"description,attr"`
+	BankDocs    []moneyBankDoc    `xml:"SeznamBankDokl>BankDokl"`
+	Receivables []moneyReceivable `xml:"SeznamPohledavek>Pohledavka"`
+}
+
+type moneyAddress struct {
+	Name  string `xml:"ObchNazev"`
+	Email string `xml:"EMail,omitempty"`
+}
+
+type moneyBankDoc struct {
+	Expense     int           `xml:"Vydej"` // 0 = receipt, 1 = expense
+	Document    string        `xml:"Doklad"`
+	Description string        `xml:"Popis"`
+	DateIssued  string        `xml:"DatVyst"`
+	DatePaid    string        `xml:"DatPlat"`
+	DateAccount string        `xml:"DatUcPr"`
+	VS          string        `xml:"VarSym,omitempty"`
+	Account     string        `xml:"AdUcet,omitempty"`
+	BankCode    string        `xml:"AdKod,omitempty"`
+	Total       string        `xml:"Celkem"`
+	Address     *moneyAddress `xml:"Adresa,omitempty"`
+}
+
+type moneyReceivable struct {
+	Document    string        `xml:"Doklad"`
+	Description string        `xml:"Popis"`
+	DateIssued  string        `xml:"Vystaveno"`
+	DateAccount string        `xml:"DatUcPr"`
+	VS          string        `xml:"VarSymbol,omitempty"`
+	Total       string        `xml:"Celkem"`
+	Address     *moneyAddress `xml:"DodOdb,omitempty"`
+}
+
+// EncodeMoney builds the Money S3 XML import of the entries
+func EncodeMoney(entries []Entry, period Period) ([]byte, error) {
+	data := moneyData{Description: "Base48 - platby, příspěvky a poplatky " + period.String()}
+	for _, e := range entries {
+		date := e.Date.Format("2006-01-02")
+		var address *moneyAddress
+		if e.Member != "" {
+			address = &moneyAddress{Name: e.Member, Email: e.Email}
+		}
+
+		if e.Kind == KindPayment {
+			doc := moneyBankDoc{
+				Document:    e.Document(),
+				Description: e.Text,
+				DateIssued:  date,
+				DatePaid:    date,
+				DateAccount: date,
+				VS:          e.VS,
+				Total:       e.Amount.String(),
+				Address:     address,
+			}
+			if e.Amount < 0 {
+				doc.Expense = 1
+				doc.Total = (-e.Amount).String()
+			}
+			if e.Account != "" {
+				number, bankCode, err := fio.ParseAccount(e.Account)
+				if err != nil {
+					number, bankCode = e.Account, ""
+				}
+				doc.Account, doc.BankCode = number, bankCode
+			}
+			data.BankDocs = append(data.BankDocs, doc)
+			continue
+		}
+		data.Receivables = append(data.Receivables, moneyReceivable{
+			Document:    e.Document(),
+			Description: e.Text,
+			DateIssued:  date,
+			DateAccount: date,
+			VS:          e.VS,
+			Total:       e.Amount.String(),
+			Address:     address,
+		})
+	}
+
+	return encodeXML(data, "Money S3")
+}
+
+func encodeXML(v interface{}, name string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode %s export: %w", name, err)
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
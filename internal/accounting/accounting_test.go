@@ -0,0 +1,101 @@
+package accounting
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+var testEntries = []Entry{
+	{Kind: KindFee, ID: 45, Date: date(2026, 3, 1), Amount: 100000, VS: "1001", Member: "Jan Novák", Email: "jan@example.com", Category: "Full", Text: "Členský příspěvek Base48 2026-03"},
+	{Kind: KindPayment, ID: 123, Date: date(2026, 3, 5), Amount: 100000, VS: "1001", Member: "Jan Novák", Email: "jan@example.com", Account: "19-2000145399/0800", Category: "fee", Text: "Přijatá platba"},
+	{Kind: KindCharge, ID: 7, Date: date(2026, 3, 9), Amount: 15050, VS: "1001", Member: "Jan Novák", Email: "jan@example.com", Category: "3d_printing", Text: "PLA 250 g"},
+	{Kind: KindPayment, ID: 124, Date: date(2026, 3, 20), Amount: -5000, Account: "neznámý", Category: "unmatched", Text: "Přijatá platba"},
+}
+
+func TestPeriodRange(t *testing.T) {
+	from, to := Period{Year: 2026, Month: 12}.Range()
+	if !from.Equal(date(2026, 12, 1)) || !to.Equal(date(2027, 1, 1)) {
+		t.Errorf("Range() of 2026-12 = %v, %v", from, to)
+	}
+	from, to = Period{Year: 2026}.Range()
+	if !from.Equal(date(2026, 1, 1)) || !to.Equal(date(2027, 1, 1)) {
+		t.Errorf("Range() of 2026 = %v, %v", from, to)
+	}
+	if s := (Period{Year: 2026, Month: 3}).String(); s != "2026-03" {
+		t.Errorf("String() = %q, want 2026-03", s)
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	data, err := EncodeCSV(testEntries)
+	if err != nil {
+		t.Fatalf("EncodeCSV() error: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("\ufeff")) {
+		t.Error("CSV export does not start with the byte order mark")
+	}
+
+	in := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	in.Comma = ';'
+	records, err := in.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read the CSV export: %v", err)
+	}
+	if len(records) != len(testEntries)+1 || strings.Join(records[0], ";") != strings.Join(csvHeader, ";") {
+		t.Fatalf("CSV export = %v", records)
+	}
+	want := []string{"C7", "Poplatek", "2026-03-09", "150,50", "1001", "Jan Novák", "jan@example.com", "", "3d_printing", "PLA 250 g"}
+	if strings.Join(records[3], ";") != strings.Join(want, ";") {
+		t.Errorf("charge row = %v, want %v", records[3], want)
+	}
+}
+
+func TestEncodePohoda(t *testing.T) {
+	data, err := EncodePohoda(testEntries, Period{Year: 2026, Month: 3}, "12345678")
+	if err != nil {
+		t.Fatalf("EncodePohoda() error: %v", err)
+	}
+	xml := string(data)
+	for _, want := range []string{
+		`<dat:dataPack xmlns:dat="http://www.stormware.cz/schema/version_2/data.xsd"`,
+		`id="base48-2026-03" ico="12345678"`,
+		`<dat:dataPackItem id="F45" version="2.0">`,
+		`<inv:invoiceType>receivable</inv:invoiceType>`,
+		`<bnk:bankType>receipt</bnk:bankType>`,
+		`<typ:accountNo>19-2000145399</typ:accountNo>`,
+		`<typ:bankCode>0800</typ:bankCode>`,
+		`<bnk:bankType>expense</bnk:bankType>`,
+		`<typ:priceNone>50.00</typ:priceNone>`,
+		`<typ:name>Jan Novák</typ:name>`,
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("Pohoda export does not contain %s:\n%s", want, xml)
+		}
+	}
+	if strings.Count(xml, "<bnk:bank ") != 2 || strings.Count(xml, "<inv:invoice ") != 2 {
+		t.Errorf("Pohoda export has wrong document types:\n%s", xml)
+	}
+}
+
+func TestEncodeMoney(t *testing.T) {
+	data, err := EncodeMoney(testEntries, Period{Year: 2026})
+	if err != nil {
+		t.Fatalf("EncodeMoney() error: %v", err)
+	}
+	xml := string(data)
+	if strings.Count(xml, "<BankDokl>") != 2 || strings.Count(xml, "<Pohledavka>") != 2 {
+		t.Errorf("Money export has wrong document types:\n%s", xml)
+	}
+	for _, want := range []string{"<Doklad>P124</Doklad>", "<Vydej>1</Vydej>", "<Celkem>150.50</Celkem>", "<AdKod>0800</AdKod>"} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("Money export does not contain %s:\n%s", want, xml)
+		}
+	}
+}
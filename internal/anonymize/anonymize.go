@@ -0,0 +1,203 @@
+// Package anonymize scrubs the personal data of former members (GDPR right to be
+// forgotten). Payments, fees, charges and invoices stay with their amounts and
+// dates, so balances and the accounting totals do not change.
+package anonymize
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+var (
+	// ErrNotFormerMember is returned for members who are still (or again) active
+	ErrNotFormerMember = errors.New("only former and rejected members can be anonymized")
+	// ErrAlreadyAnonymized is returned when the member was anonymized before
+	ErrAlreadyAnonymized = errors.New("member is already anonymized")
+)
+
+// Summary counts the scrubbed records per table
+type Summary struct {
+	Payments           int64 `json:"payments"`
+	Expenses           int64 `json:"expenses"`
+	Reimbursements     int64 `json:"reimbursements"`
+	Applications       int64 `json:"applications"`
+	Tickets            int64 `json:"tickets"`
+	TicketMessages     int64 `json:"ticket_messages"`
+	CampaignRecipients int64 `json:"campaign_recipients"`
+	Logs               int64 `json:"logs"`
+	AuditLogs          int64 `json:"audit_logs"`
+	MatchRules         int64 `json:"match_rules"`
+	WallEntries        int64 `json:"wall_entries"`
+	APITokens          int64 `json:"api_tokens"`
+	Sessions           int64 `json:"sessions"`
+	BillingDetails     int64 `json:"billing_details"`
+	Notes              int64 `json:"notes"`
+}
+
+// Email returns the placeholder address of an anonymized member; the .invalid
+// domain never resolves, so nothing is delivered to it
+func Email(userID int64) string {
+	return fmt.Sprintf("anonymized-%d@anonymized.invalid", userID)
+}
+
+// Check reports whether the member can be anonymized
+func Check(ctx context.Context, queries *db.Queries, user db.User) error {
+	if user.State != "exmember" && user.State != "rejected" {
+		return ErrNotFormerMember
+	}
+	if _, err := queries.GetUserAnonymization(ctx, user.ID); err == nil {
+		return ErrAlreadyAnonymized
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+// Member scrubs the member's name, email, phone, alternative contact and the bank
+// data referencing them (counter accounts and raw bank data of their payments,
+// reimbursement accounts, outgoing payments to those accounts), replaces their
+// email in applications, tickets, campaigns and logs, and deletes their billing
+// details, payment rules, wall entries, API tokens and sessions. All in one
+// transaction; with dryRun it is rolled back, so the summary previews the changes.
+func Member(ctx context.Context, database *sql.DB, queries *db.Queries, userID int64, by string, dryRun bool) (Summary, error) {
+	var summary Summary
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return summary, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	user, err := qtx.GetUserByID(ctx, userID)
+	if err != nil {
+		return summary, err
+	}
+	if err := Check(ctx, qtx, user); err != nil {
+		return summary, err
+	}
+
+	anonymized := Email(user.ID)
+	nullUserID := sql.NullInt64{Int64: user.ID, Valid: true}
+
+	// Outgoing payments are found by account, so the accounts go before the payments
+	accounts, err := qtx.ListUserBankAccounts(ctx, nullUserID)
+	if err != nil {
+		return summary, fmt.Errorf("failed to list bank accounts: %w", err)
+	}
+	for _, account := range accounts {
+		n, err := qtx.AnonymizeExpensesByAccount(ctx, account)
+		if err != nil {
+			return summary, fmt.Errorf("failed to anonymize expenses: %w", err)
+		}
+		summary.Expenses += n
+	}
+
+	type step struct {
+		name  string
+		count *int64
+		run   func() (int64, error)
+	}
+	steps := []step{
+		{"payments", &summary.Payments, func() (int64, error) {
+			return qtx.AnonymizeUserPayments(ctx, nullUserID)
+		}},
+		{"reimbursements", &summary.Reimbursements, func() (int64, error) {
+			return qtx.AnonymizeUserReimbursements(ctx, user.ID)
+		}},
+		{"applications", &summary.Applications, func() (int64, error) {
+			return qtx.AnonymizeUserApplications(ctx, db.AnonymizeUserApplicationsParams{
+				AnonymizedEmail: anonymized,
+				UserID:          nullUserID,
+				Email:           user.Email,
+			})
+		}},
+		{"tickets", &summary.Tickets, func() (int64, error) {
+			return qtx.AnonymizeUserTickets(ctx, db.AnonymizeUserTicketsParams{
+				AnonymizedEmail: anonymized,
+				UserID:          nullUserID,
+				Email:           user.Email,
+			})
+		}},
+		{"ticket messages", &summary.TicketMessages, func() (int64, error) {
+			return qtx.AnonymizeTicketMessageAuthors(ctx, db.AnonymizeTicketMessageAuthorsParams{
+				AnonymizedEmail: anonymized,
+				Email:           user.Email,
+			})
+		}},
+		{"campaign recipients", &summary.CampaignRecipients, func() (int64, error) {
+			return qtx.AnonymizeUserCampaignRecipients(ctx, db.AnonymizeUserCampaignRecipientsParams{
+				Email:  anonymized,
+				UserID: user.ID,
+			})
+		}},
+		{"logs", &summary.Logs, func() (int64, error) {
+			return qtx.AnonymizeLogEmail(ctx, db.AnonymizeLogEmailParams{
+				Email:           user.Email,
+				AnonymizedEmail: anonymized,
+			})
+		}},
+		{"audit log", &summary.AuditLogs, func() (int64, error) {
+			return qtx.AnonymizeAuditLogEmail(ctx, db.AnonymizeAuditLogEmailParams{
+				Email:           user.Email,
+				AnonymizedEmail: anonymized,
+			})
+		}},
+		{"payment rules", &summary.MatchRules, func() (int64, error) {
+			return qtx.DeleteUserPaymentMatchRules(ctx, nullUserID)
+		}},
+		{"wall entries", &summary.WallEntries, func() (int64, error) {
+			return qtx.DeleteUserProjectWallEntries(ctx, user.ID)
+		}},
+		{"API tokens", &summary.APITokens, func() (int64, error) {
+			return qtx.DeleteUserAPITokens(ctx, user.ID)
+		}},
+		{"billing details", &summary.BillingDetails, func() (int64, error) {
+			return qtx.DeleteBillingDetails(ctx, user.ID)
+		}},
+		{"admin notes", &summary.Notes, func() (int64, error) {
+			return qtx.DeleteUserNotes(ctx, user.ID)
+		}},
+	}
+	if user.KeycloakID.Valid && user.KeycloakID.String != "" {
+		steps = append(steps, step{"sessions", &summary.Sessions, func() (int64, error) {
+			return qtx.DeleteAuthSessionsByKeycloakID(ctx, user.KeycloakID.String)
+		}})
+	}
+	for _, step := range steps {
+		n, err := step.run()
+		if err != nil {
+			return summary, fmt.Errorf("failed to anonymize %s: %w", step.name, err)
+		}
+		*step.count = n
+	}
+
+	if err := qtx.AnonymizeUser(ctx, db.AnonymizeUserParams{
+		Email: anonymized,
+		ID:    user.ID,
+	}); err != nil {
+		return summary, fmt.Errorf("failed to anonymize member: %w", err)
+	}
+
+	encoded, err := json.Marshal(summary)
+	if err != nil {
+		return summary, err
+	}
+	if err := qtx.CreateUserAnonymization(ctx, db.CreateUserAnonymizationParams{
+		UserID:       user.ID,
+		AnonymizedBy: by,
+		Summary:      string(encoded),
+	}); err != nil {
+		return summary, fmt.Errorf("failed to record anonymization: %w", err)
+	}
+
+	if dryRun {
+		return summary, nil
+	}
+	return summary, tx.Commit()
+}
@@ -6,9 +6,14 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
@@ -20,11 +25,42 @@ import (
 )
 
 const (
-	sessionName     = "base48-session"
-	sessionUserKey  = "user"
-	sessionStateKey = "oauth_state"
+	sessionName       = "base48-session"
+	sessionUserKey    = "user"
+	sessionStateKey   = "oauth_state"
+	sessionPKCEKey    = "pkce_verifier" // PKCE code_verifier between login and callback
+	sessionRefreshKey = "refresh_token" // encrypted, see encryptToken
+	sessionExpiryKey  = "token_expiry"  // unix time when the session is re-validated
+	sessionSIDKey     = "sid"           // Keycloak SSO session ID, see auth_sessions
+	sessionReturnKey  = "return_to"     // page to return to after login
+
+	sessionImpersonateKey = "impersonate_user_id" // member an admin views the portal as
 )
 
+// backchannelLogoutEvent is the event a back-channel logout token must carry
+const backchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// refreshTimeout bounds the token refresh done inside RequireAuth
+const refreshTimeout = 5 * time.Second
+
+// Keycloak realm roles used by the portal
+const (
+	RoleAdmin        = "memberportal_admin"
+	RoleActiveMember = "active_member"
+	RoleInDebt       = "in_debt"
+)
+
+// portalRoles are the only Keycloak roles the portal takes over into the session
+var portalRoles = map[string]bool{
+	RoleAdmin:        true,
+	RoleActiveMember: true,
+	RoleInDebt:       true,
+}
+
+type contextKey int
+
+const userContextKey contextKey = iota
+
 // User represents the authenticated user from Keycloak
 type User struct {
 	ID            string   `json:"sub"`
@@ -33,17 +69,28 @@ type User struct {
 	Name          string   `json:"name"`
 	PreferredName string   `json:"preferred_username"`
 	Roles         []string `json:"roles"`
+
+	// Impersonator is the admin viewing the portal as this user; set only on
+	// the user built for an impersonated request, never stored in the session
+	Impersonator string `json:"-"`
+
+	// APITokenID is the personal API token that authenticated the request;
+	// such requests have no session
+	APITokenID int64 `json:"-"`
 }
 
 // Authenticator handles Keycloak OIDC authentication
 type Authenticator struct {
-	provider     *oidc.Provider
-	oauth2Config oauth2.Config
-	verifier     *oidc.IDTokenVerifier
-	store        *sessions.CookieStore
-	config       *config.Config
-	queries      *db.Queries
-	disabled     bool // true if Keycloak is unavailable
+	provider       *oidc.Provider
+	oauth2Config   oauth2.Config
+	verifier       *oidc.IDTokenVerifier
+	logoutVerifier *oidc.IDTokenVerifier // back-channel logout tokens (no exp)
+	endSessionURL  string                // Keycloak end_session_endpoint, empty if not advertised
+	store          sessions.Store // cookie by default, server-side with SESSION_STORE
+	config         *config.Config
+	queries        *db.Queries
+	disabled       bool  // true if Keycloak is unavailable
+	devUser        *User // fake user signed in by LoginHandler with AUTH_DEV_MODE
 }
 
 func init() {
@@ -65,6 +112,10 @@ func New(ctx context.Context, cfg *config.Config, queries *db.Queries) (*Authent
 		},
 	}
 
+	if cfg.AuthDevMode {
+		return newDevAuthenticator(cfg, queries)
+	}
+
 	// Try to connect to Keycloak with timeout
 	providerCtx := context.WithValue(ctx, oauth2.HTTPClient, httpClient)
 	provider, err := oidc.NewProvider(providerCtx, cfg.KeycloakIssuerURL())
@@ -74,13 +125,15 @@ func New(ctx context.Context, cfg *config.Config, queries *db.Queries) (*Authent
 		fmt.Printf("⚠ Error: %v\n", err)
 		fmt.Println("⚠ Starting in LIMITED MODE - authentication will be unavailable")
 
-		store := sessions.NewCookieStore([]byte(cfg.SessionSecret))
-		store.Options = &sessions.Options{
+		store, err := newSessionStore(cfg, queries, &sessions.Options{
 			Path:     "/",
 			MaxAge:   86400 * 7,
 			HttpOnly: true,
 			Secure:   false,
 			SameSite: http.SameSiteLaxMode,
+		})
+		if err != nil {
+			return nil, err
 		}
 
 		return &Authenticator{
@@ -98,50 +151,83 @@ func New(ctx context.Context, cfg *config.Config, queries *db.Queries) (*Authent
 		Endpoint:     provider.Endpoint(),
 		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
 	}
+	// Public client (no secret): send client_id in the token request body, PKCE
+	// takes the place of client authentication
+	if cfg.KeycloakClientSecret == "" {
+		oauth2Config.Endpoint.AuthStyle = oauth2.AuthStyleInParams
+	}
 
 	verifier := provider.Verifier(&oidc.Config{
 		ClientID: cfg.KeycloakClientID,
 	})
 
-	store := sessions.NewCookieStore([]byte(cfg.SessionSecret))
-	store.Options = &sessions.Options{
+	// Logout tokens are signed like ID tokens but are not required to expire
+	logoutVerifier := provider.Verifier(&oidc.Config{
+		ClientID:        cfg.KeycloakClientID,
+		SkipExpiryCheck: true,
+	})
+
+	var providerClaims struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	if err := provider.Claims(&providerClaims); err != nil {
+		fmt.Printf("⚠ WARNING: failed to read provider metadata: %v\n", err)
+	}
+
+	store, err := newSessionStore(cfg, queries, &sessions.Options{
 		Path:     "/",
 		MaxAge:   86400 * 7, // 7 days
 		HttpOnly: true,
 		Secure:   len(cfg.BaseURL) >= 5 && cfg.BaseURL[:5] == "https",
 		SameSite: http.SameSiteLaxMode,
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	fmt.Println("✓ Keycloak connection established")
 
 	return &Authenticator{
-		provider:     provider,
-		oauth2Config: oauth2Config,
-		verifier:     verifier,
-		store:        store,
-		config:       cfg,
-		queries:      queries,
-		disabled:     false,
+		provider:       provider,
+		oauth2Config:   oauth2Config,
+		verifier:       verifier,
+		logoutVerifier: logoutVerifier,
+		endSessionURL:  providerClaims.EndSessionEndpoint,
+		store:          store,
+		config:         cfg,
+		queries:        queries,
+		disabled:       false,
 	}, nil
 }
 
 // LoginHandler redirects to Keycloak login
 func (a *Authenticator) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	if a.devUser != nil {
+		a.devLogin(w, r)
+		return
+	}
 	if a.disabled {
 		http.Error(w, "Authentication unavailable - Identity Provider (Keycloak) is not accessible", http.StatusServiceUnavailable)
 		return
 	}
 
 	state := generateState()
+	verifier := oauth2.GenerateVerifier()
 
 	session, _ := a.store.Get(r, sessionName)
 	session.Values[sessionStateKey] = state
+	session.Values[sessionPKCEKey] = verifier
+	if next := safeReturnPath(r.URL.Query().Get("next")); next != "" {
+		session.Values[sessionReturnKey] = next
+	} else {
+		delete(session.Values, sessionReturnKey)
+	}
 	if err := session.Save(r, w); err != nil {
 		http.Error(w, "Failed to save session", http.StatusInternalServerError)
 		return
 	}
 
-	http.Redirect(w, r, a.oauth2Config.AuthCodeURL(state), http.StatusTemporaryRedirect)
+	http.Redirect(w, r, a.oauth2Config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), http.StatusTemporaryRedirect)
 }
 
 // CallbackHandler handles the OAuth2 callback from Keycloak
@@ -165,9 +251,16 @@ func (a *Authenticator) CallbackHandler(w http.ResponseWriter, r *http.Request)
 	}
 	delete(session.Values, sessionStateKey)
 
-	// Exchange code for token
+	verifier, ok := session.Values[sessionPKCEKey].(string)
+	if !ok || verifier == "" {
+		http.Error(w, "Invalid state parameter", http.StatusBadRequest)
+		return
+	}
+	delete(session.Values, sessionPKCEKey)
+
+	// Exchange code for token (with the PKCE verifier matching the challenge sent at login)
 	code := r.URL.Query().Get("code")
-	token, err := a.oauth2Config.Exchange(r.Context(), code)
+	token, err := a.oauth2Config.Exchange(r.Context(), code, oauth2.VerifierOption(verifier))
 	if err != nil {
 		http.Error(w, "Failed to exchange token", http.StatusInternalServerError)
 		return
@@ -180,16 +273,83 @@ func (a *Authenticator) CallbackHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Verify ID token
-	idToken, err := a.verifier.Verify(r.Context(), rawIDToken)
+	user, sid, err := a.userFromIDToken(r.Context(), rawIDToken)
 	if err != nil {
 		http.Error(w, "Failed to verify ID token", http.StatusInternalServerError)
 		return
 	}
 
+	// New session ID on login with a server-side store (session fixation)
+	if serverStore, ok := a.store.(*ServerStore); ok {
+		if err := serverStore.Regenerate(r, session); err != nil {
+			http.Error(w, "Failed to save session", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Store user in session together with the (encrypted) refresh token, not the
+	// full token set - it's too big for cookies. Admin operations use the service account.
+	returnTo, _ := session.Values[sessionReturnKey].(string)
+	delete(session.Values, sessionReturnKey)
+	session.Values[sessionUserKey] = user
+	session.Values[sessionSIDKey] = sid
+	delete(session.Values, sessionImpersonateKey)
+	if err := a.storeTokens(session, token); err != nil {
+		http.Error(w, "Failed to store token", http.StatusInternalServerError)
+		return
+	}
+	if err := session.Save(r, w); err != nil {
+		http.Error(w, "Failed to save session", http.StatusInternalServerError)
+		return
+	}
+
+	a.trackSession(r.Context(), sid, user.ID, rawIDToken)
+	if a.queries != nil {
+		_, _ = a.queries.DeleteStaleAuthSessions(r.Context())
+	}
+
+	// Log successful login
+	if a.queries != nil {
+		// Try to get user ID from database (may not exist yet for new users)
+		dbUser, err := a.queries.GetUserByKeycloakID(r.Context(), sql.NullString{
+			String: user.ID,
+			Valid:  true,
+		})
+
+		var userID sql.NullInt64
+		if err == nil {
+			userID = sql.NullInt64{Int64: dbUser.ID, Valid: true}
+		}
+
+		// Log login (gracefully - don't fail login if logging fails)
+		_, _ = a.queries.CreateLog(r.Context(), db.CreateLogParams{
+			Subsystem: "auth",
+			Level:     "info",
+			UserID:    userID,
+			Message:   fmt.Sprintf("User login: %s", user.Email),
+			Metadata:  sql.NullString{String: fmt.Sprintf(`{"keycloak_id":"%s","email":"%s"}`, user.ID, user.Email), Valid: true},
+		})
+	}
+
+	// Back to the page that required login, profile by default
+	if returnTo = safeReturnPath(returnTo); returnTo == "" {
+		returnTo = "/profile"
+	}
+	http.Redirect(w, r, returnTo, http.StatusTemporaryRedirect)
+}
+
+// userFromIDToken verifies a raw ID token and builds the session user from its
+// claims. It also returns the Keycloak session ID (sid claim).
+func (a *Authenticator) userFromIDToken(ctx context.Context, rawIDToken string) (*User, string, error) {
+	idToken, err := a.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to verify ID token: %w", err)
+	}
+
 	// Extract user info and roles
 	var claims struct {
 		Sub           string `json:"sub"`
+		Sid           string `json:"sid"`
 		Email         string `json:"email"`
 		EmailVerified bool   `json:"email_verified"`
 		Name          string `json:"name"`
@@ -203,22 +363,15 @@ func (a *Authenticator) CallbackHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := idToken.Claims(&claims); err != nil {
-		http.Error(w, "Failed to parse claims", http.StatusInternalServerError)
-		return
+		return nil, "", fmt.Errorf("failed to parse claims: %w", err)
 	}
 
 	// Extract only member portal roles (whitelist approach)
-	allowedRoles := map[string]bool{
-		"memberportal_admin": true,
-		"active_member":      true,
-		"in_debt":            true,
-	}
-
 	roles := make([]string, 0)
 
 	// Filter realm roles
 	for _, role := range claims.RealmAccess.Roles {
-		if allowedRoles[role] {
+		if portalRoles[role] {
 			roles = append(roles, role)
 		}
 	}
@@ -226,70 +379,275 @@ func (a *Authenticator) CallbackHandler(w http.ResponseWriter, r *http.Request)
 	// Add client-specific roles (from your Keycloak client)
 	if clientRoles, ok := claims.ResourceAccess[a.config.KeycloakClientID]; ok {
 		for _, role := range clientRoles.Roles {
-			if allowedRoles[role] {
+			if portalRoles[role] {
 				roles = append(roles, role)
 			}
 		}
 	}
 
-	user := User{
+	return &User{
 		ID:            claims.Sub,
 		Email:         claims.Email,
 		EmailVerified: claims.EmailVerified,
 		Name:          claims.Name,
 		PreferredName: claims.PreferredName,
 		Roles:         roles,
-	}
+	}, claims.Sid, nil
+}
 
-	// Store user in session (but NOT the full token - it's too big for cookies)
-	// For admin operations, we'll use service account instead
-	session.Values[sessionUserKey] = &user
-	if err := session.Save(r, w); err != nil {
-		http.Error(w, "Failed to save session", http.StatusInternalServerError)
+// trackSession records the Keycloak session with its latest ID token (needed as
+// id_token_hint on logout, too big for the cookie)
+func (a *Authenticator) trackSession(ctx context.Context, sid, keycloakID, rawIDToken string) {
+	if a.queries == nil || sid == "" {
 		return
 	}
+	if err := a.queries.UpsertAuthSession(ctx, db.UpsertAuthSessionParams{
+		Sid:        sid,
+		KeycloakID: keycloakID,
+		IDToken:    rawIDToken,
+	}); err != nil {
+		log.Printf("Failed to store auth session: %v", err)
+	}
+}
 
-	// Log successful login
-	if a.queries != nil {
-		// Try to get user ID from database (may not exist yet for new users)
-		dbUser, err := a.queries.GetUserByKeycloakID(r.Context(), sql.NullString{
-			String: user.ID,
-			Valid:  true,
-		})
+// sessionRevoked reports whether the session was ended by logout (possibly in another
+// application via back-channel logout). Database errors don't log anyone out.
+func (a *Authenticator) sessionRevoked(ctx context.Context, session *sessions.Session) bool {
+	sid, _ := session.Values[sessionSIDKey].(string)
+	if a.queries == nil || sid == "" {
+		return false
+	}
 
-		var userID sql.NullInt64
-		if err == nil {
-			userID = sql.NullInt64{Int64: dbUser.ID, Valid: true}
+	stored, err := a.queries.GetAuthSession(ctx, sid)
+	if err != nil {
+		return false
+	}
+	return stored.RevokedAt.Valid
+}
+
+// storeTokens puts the encrypted refresh token and the time of the next
+// re-validation (access token expiry) into the session
+func (a *Authenticator) storeTokens(session *sessions.Session, token *oauth2.Token) error {
+	encrypted, err := encryptToken(a.config.SessionSecret, token.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	expiry := token.Expiry
+	if expiry.IsZero() {
+		expiry = time.Now().Add(5 * time.Minute)
+	}
+
+	session.Values[sessionRefreshKey] = encrypted
+	session.Values[sessionExpiryKey] = expiry.Unix()
+	return nil
+}
+
+// renewSession re-validates the session against Keycloak with the refresh token once
+// the access token has expired, so role changes and disabled accounts take effect
+// within minutes instead of after the cookie expires. It returns the (possibly updated)
+// user, or false if Keycloak no longer accepts the session.
+func (a *Authenticator) renewSession(w http.ResponseWriter, r *http.Request, user *User) (*User, bool) {
+	// Without Keycloak there is nothing to re-validate against
+	if a.disabled {
+		return user, true
+	}
+
+	session, err := a.store.Get(r, sessionName)
+	if err != nil {
+		return nil, false
+	}
+
+	if a.sessionRevoked(r.Context(), session) {
+		a.clearSession(w, r)
+		return nil, false
+	}
+
+	expiry, _ := session.Values[sessionExpiryKey].(int64)
+	if time.Now().Unix() < expiry {
+		return user, true
+	}
+
+	// Sessions from before refresh tokens were stored have to log in again
+	encrypted, _ := session.Values[sessionRefreshKey].(string)
+	if encrypted == "" {
+		a.clearSession(w, r)
+		return nil, false
+	}
+	refreshToken, err := decryptToken(a.config.SessionSecret, encrypted)
+	if err != nil {
+		a.clearSession(w, r)
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), refreshTimeout)
+	defer cancel()
+
+	token, err := a.oauth2Config.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) {
+			// Keycloak rejected the token - SSO session ended, account disabled or token revoked
+			log.Printf("Session of %s ended: %v", user.Email, err)
+			a.clearSession(w, r)
+			return nil, false
 		}
+		// Keycloak unreachable - keep the session and try again on the next request
+		log.Printf("Failed to refresh session of %s: %v", user.Email, err)
+		return user, true
+	}
 
-		// Log login (gracefully - don't fail login if logging fails)
-		_, _ = a.queries.CreateLog(r.Context(), db.CreateLogParams{
-			Subsystem: "auth",
-			Level:     "info",
-			UserID:    userID,
-			Message:   fmt.Sprintf("User login: %s", user.Email),
-			Metadata:  sql.NullString{String: fmt.Sprintf(`{"keycloak_id":"%s","email":"%s"}`, user.ID, user.Email), Valid: true},
-		})
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		log.Printf("Session refresh of %s returned no ID token", user.Email)
+		a.clearSession(w, r)
+		return nil, false
+	}
+
+	renewed, sid, err := a.userFromIDToken(ctx, rawIDToken)
+	if err != nil {
+		log.Printf("Session refresh of %s: %v", user.Email, err)
+		a.clearSession(w, r)
+		return nil, false
+	}
+
+	// Keycloak rotates refresh tokens, keep the new one
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	session.Values[sessionUserKey] = renewed
+	if err := a.storeTokens(session, token); err != nil {
+		return nil, false
+	}
+	if err := session.Save(r, w); err != nil {
+		return nil, false
 	}
 
-	// Redirect to profile
-	http.Redirect(w, r, "/profile", http.StatusTemporaryRedirect)
+	a.trackSession(ctx, sid, renewed.ID, rawIDToken)
+
+	return renewed, true
 }
 
-// LogoutHandler clears the session
-func (a *Authenticator) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+// clearSession deletes the session cookie
+func (a *Authenticator) clearSession(w http.ResponseWriter, r *http.Request) {
 	session, _ := a.store.Get(r, sessionName)
 	session.Values = make(map[interface{}]interface{})
 	session.Options.MaxAge = -1
 	session.Save(r, w)
+}
+
+// LogoutHandler clears the session
+func (a *Authenticator) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var sid string
+	if session, err := a.store.Get(r, sessionName); err == nil {
+		sid, _ = session.Values[sessionSIDKey].(string)
+	}
+	a.clearSession(w, r)
+
+	if a.disabled || a.endSessionURL == "" || a.queries == nil || sid == "" {
+		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+		return
+	}
+
+	// End the Keycloak SSO session too, otherwise the next login goes straight through
+	logoutURL, err := url.Parse(a.endSessionURL)
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+		return
+	}
+	params := logoutURL.Query()
+	params.Set("client_id", a.config.KeycloakClientID)
+	params.Set("post_logout_redirect_uri", a.config.BaseURL+"/")
+	if stored, err := a.queries.GetAuthSession(ctx, sid); err == nil {
+		params.Set("id_token_hint", stored.IDToken)
+	}
+	logoutURL.RawQuery = params.Encode()
+
+	_, _ = a.queries.RevokeAuthSession(ctx, sid)
+
+	http.Redirect(w, r, logoutURL.String(), http.StatusTemporaryRedirect)
+}
+
+// BackchannelLogoutHandler receives OIDC back-channel logout requests from Keycloak
+// (logout in the admin console or another client) and revokes the matching sessions.
+// Revoked sessions are dropped on their next request by RequireAuth.
+// POST /auth/backchannel-logout (form field logout_token)
+func (a *Authenticator) BackchannelLogoutHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Cache-Control", "no-store")
+
+	if a.disabled || a.queries == nil {
+		http.Error(w, "Back-channel logout not available", http.StatusNotImplemented)
+		return
+	}
+
+	rawLogoutToken := r.PostFormValue("logout_token")
+	if rawLogoutToken == "" {
+		http.Error(w, "Missing logout_token", http.StatusBadRequest)
+		return
+	}
+
+	token, err := a.logoutVerifier.Verify(ctx, rawLogoutToken)
+	if err != nil {
+		http.Error(w, "Invalid logout_token", http.StatusBadRequest)
+		return
+	}
+
+	var claims struct {
+		Sub    string                     `json:"sub"`
+		Sid    string                     `json:"sid"`
+		Nonce  string                     `json:"nonce"`
+		Events map[string]json.RawMessage `json:"events"`
+	}
+	if err := token.Claims(&claims); err != nil {
+		http.Error(w, "Invalid logout_token claims", http.StatusBadRequest)
+		return
+	}
+	if _, ok := claims.Events[backchannelLogoutEvent]; !ok || claims.Nonce != "" || (claims.Sid == "" && claims.Sub == "") {
+		http.Error(w, "Invalid logout_token claims", http.StatusBadRequest)
+		return
+	}
+
+	var revoked int64
+	if claims.Sid != "" {
+		revoked, err = a.queries.RevokeAuthSession(ctx, claims.Sid)
+	} else {
+		revoked, err = a.queries.RevokeAuthSessionsByKeycloakID(ctx, claims.Sub)
+	}
+	if err != nil {
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
 
-	// Redirect to Keycloak logout (optional)
-	// For now, just redirect to home
-	http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+	var userID sql.NullInt64
+	if claims.Sub != "" {
+		if dbUser, err := a.queries.GetUserByKeycloakID(ctx, sql.NullString{String: claims.Sub, Valid: true}); err == nil {
+			userID = sql.NullInt64{Int64: dbUser.ID, Valid: true}
+		}
+	}
+	_, _ = a.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "auth",
+		Level:     "info",
+		UserID:    userID,
+		Message:   fmt.Sprintf("Back-channel logout from Keycloak, %d session(s) revoked", revoked),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"sid":%q,"sub":%q}`, claims.Sid, claims.Sub),
+			Valid:  true,
+		},
+	})
+
+	w.WriteHeader(http.StatusOK)
 }
 
 // GetUser returns the authenticated user from session, or nil if not authenticated
 func (a *Authenticator) GetUser(r *http.Request) *User {
+	// Already loaded by RequireAuth
+	if user := UserFromContext(r.Context()); user != nil {
+		return user
+	}
+
 	session, err := a.store.Get(r, sessionName)
 	if err != nil {
 		return nil
@@ -303,18 +661,141 @@ func (a *Authenticator) GetUser(r *http.Request) *User {
 	return user
 }
 
-// RequireAuth is a middleware that ensures the user is authenticated
+// RequireAuth is a middleware that ensures the user is authenticated. Once the
+// access token expires, the session is renewed with the refresh token first.
 func (a *Authenticator) RequireAuth(next http.Handler) http.Handler {
+	return a.requireAuth(next, redirectToLogin)
+}
+
+// RequireAPIAuth is RequireAuth for JSON APIs: a missing, revoked or expired
+// session gets a JSON 401 instead of a redirect to the login page
+func (a *Authenticator) RequireAPIAuth(next http.Handler) http.Handler {
+	return a.requireAuth(next, unauthorizedJSON)
+}
+
+// requireAuth checks (and renews) the session, calling unauthorized without one
+func (a *Authenticator) requireAuth(next http.Handler, unauthorized http.HandlerFunc) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := a.GetUser(r)
 		if user == nil {
-			http.Redirect(w, r, "/auth/login", http.StatusTemporaryRedirect)
+			unauthorized(w, r)
+			return
+		}
+
+		// API token requests were authenticated by the token, there is no session to renew
+		if user.APITokenID != 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, ok := a.renewSession(w, r, user)
+		if !ok {
+			unauthorized(w, r)
 			return
 		}
-		next.ServeHTTP(w, r)
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
+	})
+}
+
+// unauthorizedJSON answers an API request without a valid session
+func unauthorizedJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error":   "Unauthorized",
 	})
 }
 
+// redirectToLogin sends the user to the login page, remembering the requested page
+// for GET requests of pages (a form submission or an API call cannot be replayed)
+func redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	loginURL := "/auth/login"
+	if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/api/") {
+		loginURL += "?next=" + url.QueryEscape(r.URL.RequestURI())
+	}
+	http.Redirect(w, r, loginURL, http.StatusTemporaryRedirect)
+}
+
+// safeReturnPath returns path if it is a local page to return to after login,
+// otherwise "" (no open redirects to other hosts, no login loops)
+func safeReturnPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return ""
+	}
+	u, err := url.Parse(path)
+	if err != nil || u.Scheme != "" || u.Host != "" || strings.HasPrefix(u.Path, "/auth/") {
+		return ""
+	}
+	return path
+}
+
+// WithUser returns a context in which GetUser returns user instead of the session
+// user (used for admin impersonation and API tokens)
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// ImpersonatedUserID returns the ID of the member the session user views the
+// portal as, or 0
+func (a *Authenticator) ImpersonatedUserID(r *http.Request) int64 {
+	session, err := a.store.Get(r, sessionName)
+	if err != nil {
+		return 0
+	}
+	userID, _ := session.Values[sessionImpersonateKey].(int64)
+	return userID
+}
+
+// SetImpersonation starts viewing the portal as the member with the given ID;
+// 0 ends the impersonation
+func (a *Authenticator) SetImpersonation(w http.ResponseWriter, r *http.Request, userID int64) error {
+	session, err := a.store.Get(r, sessionName)
+	if err != nil {
+		return err
+	}
+	if userID == 0 {
+		delete(session.Values, sessionImpersonateKey)
+	} else {
+		session.Values[sessionImpersonateKey] = userID
+	}
+	return session.Save(r, w)
+}
+
+// UserFromContext returns the user stored by RequireAuth, or nil
+func UserFromContext(ctx context.Context) *User {
+	user, _ := ctx.Value(userContextKey).(*User)
+	return user
+}
+
+// RequireRole is a middleware that allows only users with the given role.
+// It must run after RequireAuth.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return RequireAnyRole(role)
+}
+
+// RequireAnyRole is a middleware that allows only users with at least one of the
+// given roles. It must run after RequireAuth.
+func RequireAnyRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := UserFromContext(r.Context())
+			if user == nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !user.HasAnyRole(roles...) {
+				http.Error(w, "Forbidden - required role: "+strings.Join(roles, " or "), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // HasRole checks if user has a specific role
 func (u *User) HasRole(role string) bool {
 	if u == nil {
@@ -343,17 +824,17 @@ func (u *User) HasAnyRole(roles ...string) bool {
 
 // IsAdmin checks if user has memberportal_admin role
 func (u *User) IsAdmin() bool {
-	return u.HasRole("memberportal_admin")
+	return u.HasRole(RoleAdmin)
 }
 
 // IsActiveMember checks if user has active_member role
 func (u *User) IsActiveMember() bool {
-	return u.HasRole("active_member")
+	return u.HasRole(RoleActiveMember)
 }
 
 // IsInDebt checks if user has in_debt role
 func (u *User) IsInDebt() bool {
-	return u.HasRole("in_debt")
+	return u.HasRole(RoleInDebt)
 }
 
 // generateState creates a random state string for OAuth2
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	_ "modernc.org/sqlite"
+
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+)
+
+// testStore serves one fixed session, or none
+type testStore struct {
+	session *sessions.Session
+}
+
+func (s *testStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	if s.session == nil {
+		return sessions.NewSession(s, name), nil
+	}
+	return s.session, nil
+}
+
+func (s *testStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.NewSession(s, name), nil
+}
+
+func (s *testStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	return nil
+}
+
+func TestRequireAPIAuth(t *testing.T) {
+	database, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+
+	schema, err := os.ReadFile("../../migrations/013_auth_sessions.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(string(schema)); err != nil {
+		t.Fatal(err)
+	}
+	queries := db.New(database)
+
+	ctx := context.Background()
+	for _, sid := range []string{"active", "revoked"} {
+		if err := queries.UpsertAuthSession(ctx, db.UpsertAuthSessionParams{Sid: sid, KeycloakID: "kc-1", IDToken: "token"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := queries.RevokeAuthSession(ctx, "revoked"); err != nil {
+		t.Fatal(err)
+	}
+
+	sessionWith := func(sid string) *sessions.Session {
+		store := &testStore{}
+		session := sessions.NewSession(store, sessionName)
+		session.Values[sessionUserKey] = &User{ID: "kc-1", Email: "a@example.com"}
+		session.Values[sessionSIDKey] = sid
+		session.Values[sessionExpiryKey] = time.Now().Add(time.Hour).Unix()
+		return session
+	}
+
+	tests := []struct {
+		name    string
+		session *sessions.Session
+		want    int
+	}{
+		{"active session", sessionWith("active"), http.StatusOK},
+		{"revoked session", sessionWith("revoked"), http.StatusUnauthorized},
+		{"no session", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Authenticator{
+				store:   &testStore{session: tt.session},
+				config:  &config.Config{},
+				queries: queries,
+			}
+			handler := a.RequireAPIAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if UserFromContext(r.Context()) == nil {
+					t.Error("handler called without a user")
+				}
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/me/balance", nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q, want JSON", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/sessions"
+
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+)
+
+// newDevAuthenticator creates an authenticator for local development without Keycloak
+// (AUTH_DEV_MODE=1): LoginHandler signs in a fake user with the configured roles.
+// config.Load refuses dev mode unless BASE_URL points to localhost.
+func newDevAuthenticator(cfg *config.Config, queries *db.Queries) (*Authenticator, error) {
+	for _, role := range cfg.AuthDevRoles {
+		if !portalRoles[role] {
+			return nil, fmt.Errorf("AUTH_DEV_ROLES: unknown role %q", role)
+		}
+	}
+
+	store, err := newSessionStore(cfg, queries, &sessions.Options{
+		Path:     "/",
+		MaxAge:   86400 * 7,
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	username, _, _ := strings.Cut(cfg.AuthDevEmail, "@")
+	devUser := &User{
+		ID:            "dev:" + cfg.AuthDevEmail,
+		Email:         cfg.AuthDevEmail,
+		EmailVerified: true,
+		Name:          "Dev User",
+		PreferredName: username,
+		Roles:         cfg.AuthDevRoles,
+	}
+
+	fmt.Println("⚠ WARNING: AUTH_DEV_MODE is on - /auth/login signs in without Keycloak")
+	fmt.Printf("⚠ Dev user: %s, roles: %s\n", devUser.Email, strings.Join(devUser.Roles, ", "))
+
+	return &Authenticator{
+		store:    store,
+		config:   cfg,
+		queries:  queries,
+		disabled: true,
+		devUser:  devUser,
+	}, nil
+}
+
+// devLogin signs in the dev user and returns to the requested page
+func (a *Authenticator) devLogin(w http.ResponseWriter, r *http.Request) {
+	session, _ := a.store.Get(r, sessionName)
+	session.Values[sessionUserKey] = a.devUser
+	delete(session.Values, sessionImpersonateKey)
+	if err := session.Save(r, w); err != nil {
+		http.Error(w, "Failed to save session", http.StatusInternalServerError)
+		return
+	}
+
+	if a.queries != nil {
+		_, _ = a.queries.CreateLog(r.Context(), db.CreateLogParams{
+			Subsystem: "auth",
+			Level:     "warning",
+			Message:   fmt.Sprintf("Dev login (AUTH_DEV_MODE): %s", a.devUser.Email),
+			Metadata:  sql.NullString{String: fmt.Sprintf(`{"email":%q,"roles":%q}`, a.devUser.Email, strings.Join(a.devUser.Roles, ",")), Valid: true},
+		})
+	}
+
+	returnTo := safeReturnPath(r.URL.Query().Get("next"))
+	if returnTo == "" {
+		returnTo = "/profile"
+	}
+	http.Redirect(w, r, returnTo, http.StatusTemporaryRedirect)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -13,13 +14,23 @@ import (
 	"github.com/base48/member-portal/internal/config"
 )
 
-// ServiceAccountClient handles Keycloak service account authentication
+// tokenRefreshMargin is how long before expiry the cached token is renewed,
+// so a token never expires between GetAccessToken and the actual API call
+const tokenRefreshMargin = 30 * time.Second
+
+// ServiceAccountClient handles Keycloak service account authentication.
+// The access token is cached until shortly before it expires.
 type ServiceAccountClient struct {
 	config       *config.Config
 	oauth2Config *oauth2.Config
-	tokenSource  oauth2.TokenSource
 	username     string
 	password     string
+
+	// fetch obtains a new token from Keycloak (grant type specific)
+	fetch func(ctx context.Context, current *oauth2.Token) (*oauth2.Token, error)
+
+	mu    sync.Mutex
+	token *oauth2.Token
 }
 
 // NewServiceAccountClient creates a new service account client using client credentials flow
@@ -51,20 +62,20 @@ func NewServiceAccountClient(ctx context.Context, cfg *config.Config, clientID,
 		Scopes:       []string{"openid", "profile", "email"},
 	}
 
-	// Use custom HTTP client with timeout
-	ctxWithClient := context.WithValue(ctx, oauth2.HTTPClient, httpClient)
-	tokenSource := oauth2Config.TokenSource(ctxWithClient)
+	client := &ServiceAccountClient{
+		config: cfg,
+		fetch: func(ctx context.Context, _ *oauth2.Token) (*oauth2.Token, error) {
+			// Use custom HTTP client with timeout
+			return oauth2Config.Token(context.WithValue(ctx, oauth2.HTTPClient, httpClient))
+		},
+	}
 
 	// Test the connection by getting a token
-	_, err := tokenSource.Token()
-	if err != nil {
+	if _, err := client.GetAccessToken(ctx); err != nil {
 		return nil, fmt.Errorf("failed to authenticate service account: %w", err)
 	}
 
-	return &ServiceAccountClient{
-		config:      cfg,
-		tokenSource: tokenSource,
-	}, nil
+	return client, nil
 }
 
 // NewServiceAccountClientWithPassword creates a service account using username/password
@@ -91,31 +102,61 @@ func NewServiceAccountClientWithPassword(ctx context.Context, cfg *config.Config
 		return nil, fmt.Errorf("failed to authenticate with username/password: %w", err)
 	}
 
-	tokenSource := oauth2Config.TokenSource(ctx, token)
-
-	return &ServiceAccountClient{
+	client := &ServiceAccountClient{
 		config:       cfg,
 		oauth2Config: oauth2Config,
-		tokenSource:  tokenSource,
 		username:     username,
 		password:     password,
-	}, nil
+		token:        token,
+	}
+	client.fetch = func(ctx context.Context, current *oauth2.Token) (*oauth2.Token, error) {
+		// Prefer the refresh token, fall back to logging in again
+		if current != nil && current.RefreshToken != "" {
+			refreshed, err := oauth2Config.TokenSource(ctx, &oauth2.Token{RefreshToken: current.RefreshToken}).Token()
+			if err == nil {
+				return refreshed, nil
+			}
+		}
+		return oauth2Config.PasswordCredentialsToken(ctx, client.username, client.password)
+	}
+
+	return client, nil
 }
 
-// GetAccessToken returns a valid access token (automatically refreshes if needed)
+// GetAccessToken returns a cached access token, fetching a new one when it is
+// missing or about to expire
 func (s *ServiceAccountClient) GetAccessToken(ctx context.Context) (string, error) {
-	token, err := s.tokenSource.Token()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && s.token.AccessToken != "" &&
+		(s.token.Expiry.IsZero() || time.Until(s.token.Expiry) > tokenRefreshMargin) {
+		return s.token.AccessToken, nil
+	}
+
+	token, err := s.fetch(ctx, s.token)
 	if err != nil {
 		return "", fmt.Errorf("failed to get access token: %w", err)
 	}
+	s.token = token
+
 	return token.AccessToken, nil
 }
 
+// InvalidateToken drops the cached token, e.g. after Keycloak rejected it with 401
+// (revoked session, rotated client secret). The next GetAccessToken fetches a new one.
+func (s *ServiceAccountClient) InvalidateToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil {
+		// Keep the refresh token for the password grant, drop only the access token
+		s.token = &oauth2.Token{RefreshToken: s.token.RefreshToken}
+	}
+}
+
 // IsTokenValid checks if the current token is still valid
 func (s *ServiceAccountClient) IsTokenValid(ctx context.Context) bool {
-	token, err := s.tokenSource.Token()
-	if err != nil {
-		return false
-	}
-	return token.Valid()
+	_, err := s.GetAccessToken(ctx)
+	return err == nil
 }
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sessions need only GET, SET and DEL, so Redis is spoken to directly over RESP
+// instead of pulling in a client library.
+
+const (
+	redisKeyPrefix = "base48-portal:session:"
+	redisTimeout   = 3 * time.Second
+)
+
+// redisError is an error reply from the server; the connection stays usable
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisSessionBackend stores sessions as Redis keys with a TTL
+type redisSessionBackend struct {
+	addr      string
+	password  string
+	db        int
+	tlsConfig *tls.Config // rediss://
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// newRedisSessionBackend parses redis://[:password@]host:port[/db] (rediss:// for
+// TLS) and checks the server is reachable
+func newRedisSessionBackend(rawURL string) (*redisSessionBackend, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("REDIS_URL: %w", err)
+	}
+
+	b := &redisSessionBackend{addr: u.Host}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		b.tlsConfig = &tls.Config{ServerName: u.Hostname()}
+	default:
+		return nil, fmt.Errorf("REDIS_URL: unsupported scheme %q (redis or rediss)", u.Scheme)
+	}
+	if u.Port() == "" {
+		b.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		b.password, _ = u.User.Password()
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		b.db, err = strconv.Atoi(path)
+		if err != nil {
+			return nil, fmt.Errorf("REDIS_URL: invalid database %q", path)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if _, err := b.do(ctx, "PING"); err != nil {
+		return nil, fmt.Errorf("redis session store unavailable at %s: %w", b.addr, err)
+	}
+	return b, nil
+}
+
+func (b *redisSessionBackend) Load(ctx context.Context, id string) ([]byte, error) {
+	reply, err := b.do(ctx, "GET", redisKeyPrefix+id)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, errSessionNotFound
+	}
+	return data, nil
+}
+
+func (b *redisSessionBackend) Store(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	seconds := int64(ttl / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	_, err := b.do(ctx, "SET", redisKeyPrefix+id, string(data), "EX", strconv.FormatInt(seconds, 10))
+	return err
+}
+
+func (b *redisSessionBackend) Delete(ctx context.Context, id string) error {
+	_, err := b.do(ctx, "DEL", redisKeyPrefix+id)
+	return err
+}
+
+// do sends one command over the shared connection, reconnecting after I/O errors
+func (b *redisSessionBackend) do(ctx context.Context, args ...string) (interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil {
+		if err := b.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := b.roundTrip(ctx, args)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		b.conn.Close()
+		b.conn = nil
+	}
+	return reply, err
+}
+
+func (b *redisSessionBackend) connect(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if b.tlsConfig != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: b.tlsConfig}).DialContext(ctx, "tcp", b.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", b.addr)
+	}
+	if err != nil {
+		return err
+	}
+	b.conn = conn
+	b.rd = bufio.NewReader(conn)
+
+	if b.password != "" {
+		if _, err := b.roundTrip(ctx, []string{"AUTH", b.password}); err != nil {
+			b.conn.Close()
+			b.conn = nil
+			return err
+		}
+	}
+	if b.db != 0 {
+		if _, err := b.roundTrip(ctx, []string{"SELECT", strconv.Itoa(b.db)}); err != nil {
+			b.conn.Close()
+			b.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *redisSessionBackend) roundTrip(ctx context.Context, args []string) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	b.conn.SetDeadline(deadline)
+
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(b.conn, cmd.String()); err != nil {
+		return nil, err
+	}
+
+	return b.readReply()
+}
+
+// readReply reads a single RESP reply: simple string, error, integer or bulk string
+func (b *redisSessionBackend) readReply() (interface{}, error) {
+	line, err := b.rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(b.rd, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
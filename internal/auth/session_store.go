@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+)
+
+// errSessionNotFound is returned by session backends for unknown or expired sessions
+var errSessionNotFound = errors.New("session not found")
+
+// browserSessionTTL is how long server-side data of a session without MaxAge
+// (browser session cookie) is kept
+const browserSessionTTL = 24 * time.Hour
+
+// SessionBackend persists encoded session values by session ID
+type SessionBackend interface {
+	Load(ctx context.Context, id string) ([]byte, error)
+	Store(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+// ServerStore is a sessions.Store that keeps session values server-side. The cookie
+// carries only a random session ID, so the session size is not limited by the cookie
+// and deleting the data logs the user out.
+type ServerStore struct {
+	backend SessionBackend
+	Options *sessions.Options
+}
+
+// NewServerStore creates a server-side session store on top of a backend
+func NewServerStore(backend SessionBackend, options *sessions.Options) *ServerStore {
+	return &ServerStore{backend: backend, Options: options}
+}
+
+// newSessionStore creates the session store selected by SESSION_STORE
+func newSessionStore(cfg *config.Config, queries *db.Queries, options *sessions.Options) (sessions.Store, error) {
+	switch cfg.SessionStore {
+	case "sqlite":
+		return NewServerStore(newSQLiteSessionBackend(queries), options), nil
+	case "redis":
+		backend, err := newRedisSessionBackend(cfg.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		return NewServerStore(backend, options), nil
+	default:
+		store := sessions.NewCookieStore([]byte(cfg.SessionSecret))
+		store.Options = options
+		return store, nil
+	}
+}
+
+// Get returns the session for the request, loading it once per request
+func (s *ServerStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session referenced by the cookie, or returns a new empty session
+func (s *ServerStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := *s.Options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil || !validSessionID(cookie.Value) {
+		return session, nil
+	}
+
+	data, err := s.backend.Load(r.Context(), cookie.Value)
+	if errors.Is(err, errSessionNotFound) {
+		return session, nil
+	} else if err != nil {
+		return session, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		// Unreadable data (e.g. after a change of the stored types) - start over
+		session.Values = make(map[interface{}]interface{})
+		return session, nil
+	}
+
+	session.ID = cookie.Value
+	session.IsNew = false
+	return session, nil
+}
+
+// Save stores the session values and sets the cookie; MaxAge < 0 deletes the session
+func (s *ServerStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	ctx := r.Context()
+
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.backend.Delete(ctx, session.ID); err != nil {
+				return fmt.Errorf("failed to delete session: %w", err)
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(session.Values); err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	ttl := time.Duration(session.Options.MaxAge) * time.Second
+	if ttl == 0 {
+		ttl = browserSessionTTL
+	}
+	if err := s.backend.Store(ctx, session.ID, buf.Bytes(), ttl); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), session.ID, session.Options))
+	return nil
+}
+
+// Regenerate drops the stored session and makes the next Save issue a new ID.
+// Called after login so a session ID planted before login cannot be reused.
+func (s *ServerStore) Regenerate(r *http.Request, session *sessions.Session) error {
+	if session.ID == "" {
+		return nil
+	}
+	if err := s.backend.Delete(r.Context(), session.ID); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	session.ID = ""
+	return nil
+}
+
+// newSessionID returns a random 256-bit session ID
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// validSessionID filters out cookies that cannot be our session IDs (e.g. old
+// CookieStore cookies after switching SESSION_STORE)
+func validSessionID(id string) bool {
+	if len(id) != 43 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// sessionCleanupInterval is how often expired sessions are purged from SQLite
+const sessionCleanupInterval = time.Hour
+
+// sqliteSessionBackend stores sessions in the web_sessions table
+type sqliteSessionBackend struct {
+	queries *db.Queries
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+func newSQLiteSessionBackend(queries *db.Queries) *sqliteSessionBackend {
+	return &sqliteSessionBackend{queries: queries}
+}
+
+func (b *sqliteSessionBackend) Load(ctx context.Context, id string) ([]byte, error) {
+	data, err := b.queries.GetWebSession(ctx, db.GetWebSessionParams{
+		ID:  id,
+		Now: time.Now().UTC(),
+	})
+	if err == sql.ErrNoRows {
+		return nil, errSessionNotFound
+	}
+	return data, err
+}
+
+func (b *sqliteSessionBackend) Store(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	now := time.Now().UTC()
+	if err := b.queries.UpsertWebSession(ctx, db.UpsertWebSessionParams{
+		ID:        id,
+		Data:      data,
+		ExpiresAt: now.Add(ttl),
+	}); err != nil {
+		return err
+	}
+
+	// Expired rows are purged now and then on write, there is no separate job for it
+	b.mu.Lock()
+	cleanup := now.Sub(b.lastCleanup) > sessionCleanupInterval
+	if cleanup {
+		b.lastCleanup = now
+	}
+	b.mu.Unlock()
+	if cleanup {
+		if _, err := b.queries.DeleteExpiredWebSessions(ctx, now); err != nil {
+			log.Printf("Failed to delete expired sessions: %v", err)
+		}
+	}
+	return nil
+}
+
+func (b *sqliteSessionBackend) Delete(ctx context.Context, id string) error {
+	return b.queries.DeleteWebSession(ctx, id)
+}
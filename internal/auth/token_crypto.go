@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// The session cookie is only signed, so anyone holding it could read a plain
+// refresh token. It is encrypted with AES-GCM under a key derived from SESSION_SECRET.
+
+func tokenCipher(secret string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte("refresh-token:" + secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptToken encrypts a token for storage in the session cookie
+func encryptToken(secret, token string) (string, error) {
+	aead, err := tokenCipher(secret)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(token), nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// decryptToken reverses encryptToken
+func decryptToken(secret, encrypted string) (string, error) {
+	aead, err := tokenCipher(secret)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("encrypted token too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	token, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt token: %w", err)
+	}
+	return string(token), nil
+}
@@ -0,0 +1,110 @@
+package balance
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// LockName is the lock that serializes all writes changing member balances
+// (payment assignment, fee creation, payment import)
+const LockName = "balance"
+
+const (
+	// lockTTL is how long a lock stays valid without being extended. A crashed
+	// holder blocks others for at most this long.
+	lockTTL = 2 * time.Minute
+
+	// lockPollInterval is how often a waiting process retries
+	lockPollInterval = 100 * time.Millisecond
+)
+
+// Lock is a cross-process advisory lock stored in the locks table. It is kept
+// alive in the background until Release is called.
+type Lock struct {
+	queries *db.Queries
+	name    string
+	holder  string
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Acquire waits until the named lock is free and takes it. job identifies the
+// holder in the locks table (e.g. "create_monthly_fees"). Waiting is bounded by ctx.
+func Acquire(ctx context.Context, queries *db.Queries, name, job string) (*Lock, error) {
+	hostname, _ := os.Hostname()
+	l := &Lock{
+		queries: queries,
+		name:    name,
+		holder:  fmt.Sprintf("%s@%s:%d", job, hostname, os.Getpid()),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	for {
+		acquired, err := l.extend(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+		}
+		if acquired {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for lock %s: %w", name, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	go l.keepAlive()
+	return l, nil
+}
+
+// Release stops the keep-alive and frees the lock
+func (l *Lock) Release(ctx context.Context) error {
+	close(l.stop)
+	<-l.done
+
+	return l.queries.ReleaseLock(ctx, db.ReleaseLockParams{
+		Name:   l.name,
+		Holder: l.holder,
+	})
+}
+
+// extend takes the lock or pushes out its expiry if we already hold it
+func (l *Lock) extend(ctx context.Context) (bool, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	rows, err := l.queries.AcquireLock(ctx, db.AcquireLockParams{
+		Name:      l.name,
+		Holder:    l.holder,
+		ExpiresAt: now.Add(lockTTL),
+		Now:       now,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// keepAlive extends the lock so long-running jobs do not lose it
+func (l *Lock) keepAlive() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(lockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			// A failed extension is retried on the next tick; the lock only
+			// expires after lockTTL
+			l.extend(context.Background())
+		}
+	}
+}
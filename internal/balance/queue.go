@@ -0,0 +1,99 @@
+package balance
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// lockWait bounds how long a queued update waits for a cron job holding the lock
+const lockWait = 30 * time.Second
+
+// UpdateFunc changes balance inputs (payments, fees) using the given transaction-bound queries
+type UpdateFunc func(ctx context.Context, queries *db.Queries) error
+
+type update struct {
+	ctx    context.Context
+	fn     UpdateFunc
+	result chan error
+}
+
+// Queue runs balance updates of the server one at a time. Each update runs in its
+// own transaction while holding the balance lock, so it never interleaves with
+// another request or with a cron job (which take the same lock via Acquire).
+type Queue struct {
+	database *sql.DB
+	queries  *db.Queries
+	updates  chan update
+}
+
+// NewQueue creates a queue; call Start to begin processing
+func NewQueue(database *sql.DB) *Queue {
+	return &Queue{
+		database: database,
+		queries:  db.New(database),
+		updates:  make(chan update),
+	}
+}
+
+// Start runs the single writer until ctx is cancelled
+func (q *Queue) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case u := <-q.updates:
+				u.result <- q.run(u)
+			}
+		}
+	}()
+}
+
+// Do queues an update and waits for its result. The update is committed only when
+// fn returns nil.
+func (q *Queue) Do(ctx context.Context, fn UpdateFunc) error {
+	u := update{ctx: ctx, fn: fn, result: make(chan error, 1)}
+
+	select {
+	case q.updates <- u:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return <-u.result
+}
+
+func (q *Queue) run(u update) error {
+	// Request was cancelled while waiting in the queue - nothing was written yet
+	if err := u.ctx.Err(); err != nil {
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(u.ctx, lockWait)
+	defer cancel()
+
+	lock, err := Acquire(waitCtx, q.queries, LockName, "server")
+	if err != nil {
+		return err
+	}
+
+	// Once started, the update is finished even if the client goes away
+	ctx := context.WithoutCancel(u.ctx)
+	defer lock.Release(ctx)
+
+	tx, err := q.database.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := u.fn(ctx, q.queries.WithTx(tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
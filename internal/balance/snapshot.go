@@ -0,0 +1,83 @@
+package balance
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// MonthEnd is a member's balance at the end of a month
+type MonthEnd struct {
+	Month   time.Time // first day of the month
+	Balance money.Amount
+}
+
+// MonthEnds returns the running balance at the end of every month from the month of
+// the first ledger entry through last (first day of a month); entries dated after
+// last are left out. Nothing is returned without entries up to last.
+func MonthEnds(entries []db.ListUserLedgerRow, last time.Time) []MonthEnd {
+	sums := make(map[time.Time]money.Amount)
+	var first time.Time
+	for _, entry := range entries {
+		date := entry.Date.UTC()
+		month := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if month.After(last) {
+			continue
+		}
+		if first.IsZero() || month.Before(first) {
+			first = month
+		}
+		sums[month] += entry.Amount
+	}
+	if first.IsZero() {
+		return nil
+	}
+
+	var result []MonthEnd
+	var balance money.Amount
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		balance += sums[month]
+		result = append(result, MonthEnd{Month: month, Balance: balance})
+	}
+	return result
+}
+
+// Snapshot replaces the member's balance snapshots with month ends through last,
+// computed from the full ledger in one transaction. Returns the number of months.
+func Snapshot(ctx context.Context, database *sql.DB, queries *db.Queries, userID int64, last time.Time) (int, error) {
+	entries, err := queries.ListUserLedger(ctx, sql.NullInt64{Int64: userID, Valid: true})
+	if err != nil {
+		return 0, err
+	}
+	monthEnds := MonthEnds(entries, last)
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	if err := qtx.DeleteBalanceSnapshotsByUser(ctx, userID); err != nil {
+		return 0, err
+	}
+	for _, monthEnd := range monthEnds {
+		if err := qtx.CreateBalanceSnapshot(ctx, db.CreateBalanceSnapshotParams{
+			UserID:  userID,
+			Month:   monthEnd.Month,
+			Balance: monthEnd.Balance,
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return len(monthEnds), tx.Commit()
+}
+
+// SinceSnapshot is the date from which entries are added to a snapshot: the first
+// day after its month (YYYY-MM-DD, for GetUserBalanceSince)
+func SinceSnapshot(snapshot db.BalanceSnapshot) string {
+	return snapshot.Month.AddDate(0, 1, 0).Format("2006-01-02")
+}
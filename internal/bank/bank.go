@@ -0,0 +1,86 @@
+// Package bank abstracts the bank account the portal downloads payments from.
+// A Provider fetches booked transactions for a period; the FIO provider also keeps
+// the FIO download checkpoint. BANK_PROVIDER selects the implementation.
+package bank
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/fio"
+)
+
+// Provider downloads transactions of the space's bank account
+type Provider interface {
+	// Name identifies the provider in logs ("fio", "raiffeisen")
+	Name() string
+	// FetchTransactions returns the transactions booked from one day to another
+	// (both inclusive)
+	FetchTransactions(ctx context.Context, from, to time.Time) ([]Transaction, error)
+}
+
+// CheckpointProvider is a provider remembering on the bank side what was already
+// downloaded (FIO "last download")
+type CheckpointProvider interface {
+	Provider
+	// FetchSinceCheckpoint returns new transactions and moves the checkpoint
+	FetchSinceCheckpoint(ctx context.Context) ([]Transaction, error)
+	// SetCheckpoint moves the checkpoint to the end of the given day
+	SetCheckpoint(ctx context.Context, date time.Time) error
+}
+
+// Transaction is a bank movement. The fields follow the FIO API (the first
+// provider); Kind and KindID identify the movement among payments and expenses
+// (payments.kind, kind_id), so a movement downloaded again updates the existing
+// payment.
+type Transaction struct {
+	fio.Transaction
+	Kind         string `json:"-"` // "fio", "rb" or "camt"
+	KindID       string `json:"-"` // FIO movement ID, bank reference of the entry
+	LocalAccount string `json:"-"` // account of the space the movement belongs to
+}
+
+// FromFIO wraps transactions of the FIO account (API, CSV and GPC exports)
+func FromFIO(transactions []fio.Transaction) []Transaction {
+	result := make([]Transaction, len(transactions))
+	for i, tx := range transactions {
+		result[i] = Transaction{
+			Transaction:  tx,
+			Kind:         "fio",
+			KindID:       fmt.Sprintf("%d", tx.ID),
+			LocalAccount: "FIO",
+		}
+	}
+	return result
+}
+
+// New creates the provider selected by BANK_PROVIDER
+func New(cfg *config.Config) (Provider, error) {
+	switch cfg.BankProvider {
+	case "", "fio":
+		if cfg.BankFIOToken == "" {
+			return nil, fmt.Errorf("BANK_FIO_TOKEN is required")
+		}
+		return NewFIO(fio.NewClient(cfg.BankFIOToken, FIOOptions(cfg))), nil
+	case "raiffeisen":
+		return NewRaiffeisen(RaiffeisenConfig{
+			APIURL:   cfg.BankRBAPIURL,
+			ClientID: cfg.BankRBClientID,
+			CertFile: cfg.BankRBCertFile,
+			KeyFile:  cfg.BankRBKeyFile,
+			Account:  cfg.BankRBAccount,
+			Currency: cfg.BankRBCurrency,
+		})
+	}
+	return nil, fmt.Errorf("BANK_PROVIDER: unknown provider %q (fio or raiffeisen)", cfg.BankProvider)
+}
+
+// FIOOptions returns the FIO request pacing from the configuration
+func FIOOptions(cfg *config.Config) fio.Options {
+	return fio.Options{
+		MinInterval: time.Duration(cfg.FIOMinInterval) * time.Second,
+		MaxRetries:  cfg.FIOMaxRetries,
+	}
+}
@@ -0,0 +1,46 @@
+package bank
+
+import (
+	"context"
+	"time"
+
+	"github.com/base48/member-portal/internal/fio"
+)
+
+// FIO downloads transactions through the FIO Bank API
+type FIO struct {
+	client *fio.Client
+}
+
+// NewFIO creates the FIO provider
+func NewFIO(client *fio.Client) *FIO {
+	return &FIO{client: client}
+}
+
+// Name implements Provider
+func (p *FIO) Name() string {
+	return "fio"
+}
+
+// FetchTransactions implements Provider (the FIO API allows at most 90 days back)
+func (p *FIO) FetchTransactions(ctx context.Context, from, to time.Time) ([]Transaction, error) {
+	transactions, err := p.client.FetchTransactionsByPeriod(ctx, fio.FormatDate(from), fio.FormatDate(to))
+	if err != nil {
+		return nil, err
+	}
+	return FromFIO(transactions), nil
+}
+
+// FetchSinceCheckpoint implements CheckpointProvider
+func (p *FIO) FetchSinceCheckpoint(ctx context.Context) ([]Transaction, error) {
+	transactions, err := p.client.FetchTransactionsSinceLastDownload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return FromFIO(transactions), nil
+}
+
+// SetCheckpoint implements CheckpointProvider
+func (p *FIO) SetCheckpoint(ctx context.Context, date time.Time) error {
+	return p.client.SetLastDownloadDate(ctx, fio.FormatDate(date))
+}
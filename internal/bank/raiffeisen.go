@@ -0,0 +1,233 @@
+package bank
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/fio"
+)
+
+// raiffeisenMaxPages stops paging of a response that never reports the last page
+const raiffeisenMaxPages = 100
+
+// RaiffeisenConfig holds the access to the Raiffeisenbank Premium API
+type RaiffeisenConfig struct {
+	APIURL   string // https://api.rb.cz
+	ClientID string // X-IBM-Client-Id
+	CertFile string // client certificate (PEM) issued for the API
+	KeyFile  string
+	Account  string // account number without bank code
+	Currency string // account currency, CZK
+}
+
+// Raiffeisen downloads transactions through the Raiffeisenbank Premium API.
+// The API authenticates the client certificate; there is no checkpoint, the sync
+// downloads a period and already stored transactions are only updated.
+type Raiffeisen struct {
+	cfg        RaiffeisenConfig
+	httpClient *http.Client
+}
+
+// NewRaiffeisen creates the Raiffeisenbank provider
+func NewRaiffeisen(cfg RaiffeisenConfig) (*Raiffeisen, error) {
+	if cfg.ClientID == "" || cfg.CertFile == "" || cfg.KeyFile == "" || cfg.Account == "" {
+		return nil, fmt.Errorf("BANK_RB_CLIENT_ID, BANK_RB_CERT_FILE, BANK_RB_KEY_FILE and BANK_RB_ACCOUNT are required for BANK_PROVIDER=raiffeisen")
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Raiffeisenbank client certificate: %w", err)
+	}
+	if cfg.Currency == "" {
+		cfg.Currency = "CZK"
+	}
+
+	return &Raiffeisen{
+		cfg: cfg,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+			},
+		},
+	}, nil
+}
+
+// Name implements Provider
+func (p *Raiffeisen) Name() string {
+	return "raiffeisen"
+}
+
+// raiffeisenPage is one page of GET /accounts/{account}/{currency}/transactions
+type raiffeisenPage struct {
+	LastPage     bool                    `json:"lastPage"`
+	Transactions []raiffeisenTransaction `json:"transactions"`
+}
+
+type raiffeisenSymbols struct {
+	Variable string `json:"variable"`
+	Constant string `json:"constant"`
+	Specific string `json:"specific"`
+}
+
+type raiffeisenTransaction struct {
+	EntryReference string `json:"entryReference"`
+	Amount         struct {
+		Value    float64 `json:"value"`
+		Currency string  `json:"currency"`
+	} `json:"amount"`
+	CreditDebitIndication string `json:"creditDebitIndication"` // CRDT or DBIT
+	BookingDate           string `json:"bookingDate"`
+	EntryDetails          struct {
+		TransactionDetails struct {
+			References            raiffeisenSymbols `json:"references"`
+			RemittanceInformation struct {
+				Unstructured                 string            `json:"unstructured"`
+				CreditorReferenceInformation raiffeisenSymbols `json:"creditorReferenceInformation"`
+			} `json:"remittanceInformation"`
+			RelatedParties struct {
+				CounterParty struct {
+					Name    string `json:"name"`
+					Account struct {
+						AccountNumber string `json:"accountNumber"`
+						BankCode      string `json:"bankCode"`
+						IBAN          string `json:"iban"`
+					} `json:"account"`
+				} `json:"counterParty"`
+			} `json:"relatedParties"`
+			AdditionalTransactionInformation string `json:"additionalTransactionInformation"`
+		} `json:"transactionDetails"`
+	} `json:"entryDetails"`
+}
+
+// FetchTransactions implements Provider
+func (p *Raiffeisen) FetchTransactions(ctx context.Context, from, to time.Time) ([]Transaction, error) {
+	var transactions []Transaction
+	for page := 1; page <= raiffeisenMaxPages; page++ {
+		result, err := p.fetchPage(ctx, from, to, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, rt := range result.Transactions {
+			tx, err := p.convert(rt)
+			if err != nil {
+				return nil, err
+			}
+			transactions = append(transactions, tx)
+		}
+		if result.LastPage {
+			return transactions, nil
+		}
+	}
+	return nil, fmt.Errorf("more than %d pages of transactions, use a shorter period", raiffeisenMaxPages)
+}
+
+// fetchPage performs one API request
+func (p *Raiffeisen) fetchPage(ctx context.Context, from, to time.Time, page int) (*raiffeisenPage, error) {
+	query := url.Values{}
+	query.Set("from", fio.FormatDate(from))
+	query.Set("to", fio.FormatDate(to))
+	query.Set("page", fmt.Sprintf("%d", page))
+	endpoint := fmt.Sprintf("%s/rbcz/premium/api/accounts/%s/%s/transactions?%s",
+		strings.TrimSuffix(p.cfg.APIURL, "/"), url.PathEscape(p.cfg.Account), url.PathEscape(p.cfg.Currency), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-IBM-Client-Id", p.cfg.ClientID)
+	req.Header.Set("X-Request-Id", requestID())
+	req.Header.Set("PSU-IP-Address", "127.0.0.1") // requests are made by the server itself
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result raiffeisenPage
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return &result, nil
+}
+
+// convert maps an API transaction to the common fields
+func (p *Raiffeisen) convert(rt raiffeisenTransaction) (Transaction, error) {
+	if rt.EntryReference == "" {
+		return Transaction{}, fmt.Errorf("transaction without entryReference")
+	}
+	if len(rt.BookingDate) < 10 {
+		return Transaction{}, fmt.Errorf("transaction %s: invalid booking date %q", rt.EntryReference, rt.BookingDate)
+	}
+
+	details := rt.EntryDetails.TransactionDetails
+	counterParty := details.RelatedParties.CounterParty
+	tx := fio.Transaction{
+		Date:            rt.BookingDate[:10],
+		Amount:          rt.Amount.Value,
+		Currency:        rt.Amount.Currency,
+		AccountNumber:   counterParty.Account.AccountNumber,
+		AccountName:     counterParty.Name,
+		BankCode:        counterParty.Account.BankCode,
+		VariableSymbol:  strings.TrimLeft(details.References.Variable, "0"),
+		SpecificSymbol:  strings.TrimLeft(details.References.Specific, "0"),
+		Message:         details.RemittanceInformation.Unstructured,
+		Comment:         details.AdditionalTransactionInformation,
+		TransactionType: rt.CreditDebitIndication,
+	}
+	creditor := details.RemittanceInformation.CreditorReferenceInformation
+	if tx.VariableSymbol == "" {
+		tx.VariableSymbol = strings.TrimLeft(creditor.Variable, "0")
+	}
+	if tx.SpecificSymbol == "" {
+		tx.SpecificSymbol = strings.TrimLeft(creditor.Specific, "0")
+	}
+	if tx.AccountNumber == "" && counterParty.Account.IBAN != "" {
+		if number, err := fio.AccountFromIBAN(counterParty.Account.IBAN); err == nil {
+			tx.AccountNumber = number
+			tx.BankCode = strings.ToUpper(strings.ReplaceAll(counterParty.Account.IBAN, " ", ""))[4:8]
+		}
+	}
+
+	switch rt.CreditDebitIndication {
+	case "CRDT":
+	case "DBIT":
+		if tx.Amount > 0 {
+			tx.Amount = -tx.Amount
+		}
+	default:
+		return Transaction{}, fmt.Errorf("transaction %s: invalid creditDebitIndication %q", rt.EntryReference, rt.CreditDebitIndication)
+	}
+
+	return Transaction{
+		Transaction:  tx,
+		Kind:         "rb",
+		KindID:       rt.EntryReference,
+		LocalAccount: "RB",
+	}, nil
+}
+
+// requestID returns a random ID for the X-Request-Id header
+func requestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,234 @@
+// Package bankimport stores bank transactions as payments and expenses: matching
+// by VS, invoices, payment match rules and the sender account, linking reversals
+// and reimbursement payouts, flagging a transaction already imported from another
+// source as a suspected duplicate. It is shared by the FIO API sync and the import of
+// downloaded statements: FIO CSV and GPC/ABO (dedupe with the sync on the FIO
+// movement ID) and ISO 20022 camt.053 of other banks (kind "camt").
+package bankimport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/bank"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fio"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/base48/member-portal/internal/paymentrule"
+)
+
+// Statement formats
+const (
+	FormatFIOCSV  = "fio-csv"
+	FormatGPC     = "gpc"
+	FormatCAMT053 = "camt053"
+)
+
+// MaxStatementSize limits an uploaded statement
+const MaxStatementSize = 10 << 20
+
+// DetectFormat guesses the format of a statement by the file extension, then by
+// the content ("" if unknown)
+func DetectFormat(filename string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatFIOCSV
+	case ".gpc", ".abo":
+		return FormatGPC
+	case ".xml":
+		return FormatCAMT053
+	}
+
+	if bytes.HasPrefix(bytes.TrimPrefix(data, []byte("\ufeff")), []byte(gpcHeader)) {
+		return FormatGPC
+	}
+	if bytes.Contains(data, []byte("BkToCstmrStmt")) {
+		return FormatCAMT053
+	}
+	if bytes.Contains(data, []byte("ID pohybu")) {
+		return FormatFIOCSV
+	}
+	return ""
+}
+
+// Parse reads the transactions of a statement in the given format
+func Parse(format string, data []byte) ([]bank.Transaction, error) {
+	var transactions []fio.Transaction
+	var err error
+	switch format {
+	case FormatFIOCSV:
+		transactions, err = ParseFIOCSV(data)
+	case FormatGPC:
+		transactions, err = ParseGPC(data)
+	case FormatCAMT053:
+		return ParseCAMT053(data)
+	default:
+		return nil, fmt.Errorf("unknown statement format %q (use %s, %s or %s)", format, FormatFIOCSV, FormatGPC, FormatCAMT053)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bank.FromFIO(transactions), nil
+}
+
+// Options change how transactions are matched
+type Options struct {
+	AutoLinkByAccount bool // assign payments without VS by the sender account history (FIO_AUTO_LINK_BY_ACCOUNT)
+}
+
+// Summary counts what happened to the imported transactions
+type Summary struct {
+	Total              int `json:"total"`
+	Inserted           int `json:"inserted"`
+	Updated            int `json:"updated"`
+	Skipped            int `json:"skipped"`
+	Errors             int `json:"errors"`
+	ReversalsLinked    int `json:"reversals_linked"`
+	ReimbursementsPaid int `json:"reimbursements_paid"`
+	ExpensesImported   int `json:"expenses"`
+	RuleMatched        int `json:"rule_matched"`
+	AccountLinked      int `json:"account_linked"`
+	AccountSuggested   int `json:"account_suggested"`
+
+	UnmatchedVS     []bank.Transaction `json:"-"` // VS of no member, invoice or project
+	EmptyVS         []bank.Transaction `json:"-"` // incoming payments without VS
+	ReversalsReview []bank.Transaction `json:"-"` // reversals matching several payments
+	Duplicates      []bank.Transaction `json:"-"` // same content as a payment from another source
+
+	Assigned     []AssignedPayment  `json:"-"` // payments newly assigned to a member (confirmation emails)
+	NewUnmatched []bank.Transaction `json:"-"` // incoming payments stored unassigned in this run (admin digest)
+}
+
+// AssignedPayment is a payment the import assigned to a member
+type AssignedPayment struct {
+	PaymentID int64
+	UserID    int64
+	Amount    money.Amount
+	Date      time.Time
+}
+
+// Unmatched is the number of incoming payments left without a member
+func (s Summary) Unmatched() int {
+	return len(s.UnmatchedVS) + len(s.EmptyVS)
+}
+
+// Level is the system log level of the run
+func (s Summary) Level() string {
+	if s.Errors > 0 {
+		return "warning"
+	} else if s.Unmatched() > 0 || len(s.ReversalsReview) > 0 || len(s.Duplicates) > 0 {
+		return "info"
+	}
+	return "success"
+}
+
+// Message describes the result for the system log
+func (s Summary) Message() string {
+	return fmt.Sprintf("%d new, %d updated, %d unmatched, %d reversals, %d reimbursements paid, %d expenses, %d suspected duplicates",
+		s.Inserted, s.Updated, s.Unmatched(), s.ReversalsLinked+len(s.ReversalsReview), s.ReimbursementsPaid, s.ExpensesImported, len(s.Duplicates))
+}
+
+// Metadata is the JSON metadata of the system log record
+func (s Summary) Metadata() string {
+	return fmt.Sprintf(`{"inserted":%d,"updated":%d,"skipped":%d,"unmatched":%d,"reversals_linked":%d,"reversals_review":%d,"reimbursements_paid":%d,"expenses":%d,"rule_matched":%d,"account_linked":%d,"account_suggested":%d,"duplicates":%d,"errors":%d}`,
+		s.Inserted, s.Updated, s.Skipped, s.Unmatched(), s.ReversalsLinked, len(s.ReversalsReview), s.ReimbursementsPaid, s.ExpensesImported, s.RuleMatched, s.AccountLinked, s.AccountSuggested, len(s.Duplicates), s.Errors)
+}
+
+// LogReport prints the summary and the payments needing attention
+func (s Summary) LogReport(title string) {
+	log.Println("\n" + strings.Repeat("=", 80))
+	log.Println(title)
+	log.Println(strings.Repeat("=", 80))
+	log.Printf("Total transactions: %d", s.Total)
+	log.Printf("  ✓ Inserted: %d", s.Inserted)
+	log.Printf("  ↻ Updated: %d", s.Updated)
+	log.Printf("  ↩ Reversals linked: %d", s.ReversalsLinked)
+	log.Printf("  💸 Reimbursements paid: %d", s.ReimbursementsPaid)
+	log.Printf("  💳 Expenses imported: %d", s.ExpensesImported)
+	log.Printf("  🔗 Matched by rules: %d", s.RuleMatched)
+	log.Printf("  🏦 Assigned by sender account: %d", s.AccountLinked)
+	log.Printf("  💡 Suggested by sender account: %d", s.AccountSuggested)
+	log.Printf("  - Skipped (unchanged/zero): %d", s.Skipped)
+	log.Printf("  ✗ Errors: %d", s.Errors)
+	log.Println(strings.Repeat("-", 80))
+
+	// Report problematic payments
+	if s.Unmatched() > 0 {
+		log.Printf("\n⚠️  PROBLEMATIC PAYMENTS: %d", s.Unmatched())
+
+		if len(s.EmptyVS) > 0 {
+			totalAmount := 0.0
+			log.Printf("\n  📝 Empty variable symbol: %d payments", len(s.EmptyVS))
+			for _, tx := range s.EmptyVS {
+				totalAmount += tx.Amount
+				log.Printf("     - %.2f CZK from %s on %s", tx.Amount, tx.AccountName, tx.Date[:10])
+			}
+			log.Printf("     Total: %.2f CZK", totalAmount)
+		}
+
+		if len(s.UnmatchedVS) > 0 {
+			totalAmount := 0.0
+			log.Printf("\n  ❌ User not found: %d payments", len(s.UnmatchedVS))
+			for _, tx := range s.UnmatchedVS {
+				totalAmount += tx.Amount
+				log.Printf("     - %.2f CZK (VS/payments_id: %s) from %s", tx.Amount, tx.VariableSymbol, tx.AccountName)
+			}
+			log.Printf("     Total: %.2f CZK", totalAmount)
+			log.Println("\n     💡 These payments have VS that doesn't match any user's payments_id.")
+			log.Println("        Check if users need to be imported or VS is incorrect.")
+		}
+
+		log.Printf("\n💡 Run 'go run cmd/cron/report_unmatched_payments.go' for detailed report")
+	}
+
+	if len(s.ReversalsReview) > 0 {
+		log.Printf("\n↩️  REVERSALS WAITING FOR REVIEW: %d", len(s.ReversalsReview))
+		for _, tx := range s.ReversalsReview {
+			log.Printf("     - %.2f CZK to %s (VS: %s) - multiple matching payments", tx.Amount, tx.AccountName, tx.VariableSymbol)
+		}
+		log.Println("\n     💡 Link them manually in /admin/payments/unmatched")
+	}
+
+	if len(s.Duplicates) > 0 {
+		log.Printf("\n⧉  SUSPECTED DUPLICATES: %d", len(s.Duplicates))
+		for _, tx := range s.Duplicates {
+			log.Printf("     - %.2f CZK from %s on %s (%s %s)", tx.Amount, tx.AccountName, tx.Date[:10], tx.Kind, tx.KindID)
+		}
+		log.Println("\n     💡 Merge or keep them in /admin/payments/unmatched")
+	}
+
+	log.Println("\n" + strings.Repeat("=", 80))
+}
+
+// Import stores the transactions. Transactions already imported (same FIO movement
+// ID) only get a missing assignment filled in. A failed transaction is counted in
+// Summary.Errors and the rest continue; the error is returned only when the import
+// could not start. Callers hold the balance lock (or run in the balance queue).
+func Import(ctx context.Context, queries *db.Queries, transactions []bank.Transaction, opts Options) (Summary, error) {
+	summary := Summary{Total: len(transactions)}
+
+	// Rules for payments with a wrong or missing VS (a broken rule is skipped)
+	ruleRows, err := queries.ListActivePaymentMatchRules(ctx)
+	if err != nil {
+		return summary, fmt.Errorf("failed to load payment match rules: %w", err)
+	}
+	rules, err := paymentrule.Compile(ruleRows)
+	if err != nil {
+		log.Printf("⚠ Skipping invalid payment match rules: %v", err)
+	}
+
+	// Payments stored without a content hash are compared too
+	if err := backfillContentHashes(ctx, queries); err != nil {
+		return summary, fmt.Errorf("failed to compute payment content hashes: %w", err)
+	}
+
+	for _, tx := range transactions {
+		importTransaction(ctx, queries, rules, opts, tx, &summary)
+	}
+	return summary, nil
+}
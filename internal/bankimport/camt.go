@@ -0,0 +1,261 @@
+package bankimport
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/base48/member-portal/internal/bank"
+	"github.com/base48/member-portal/internal/fio"
+)
+
+// camtDocument is the part of an ISO 20022 camt.053 statement (bank to customer
+// statement) the import reads. Elements are matched by local name, so all message
+// versions (001.02 - 001.08) parse the same.
+type camtDocument struct {
+	Statements []struct {
+		Account camtAccount `xml:"Acct"`
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtAccount struct {
+	IBAN  string `xml:"Id>IBAN"`
+	Other string `xml:"Id>Othr>Id"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtEntry struct {
+	Ref         string     `xml:"NtryRef"`
+	Amount      camtAmount `xml:"Amt"`
+	CreditDebit string     `xml:"CdtDbtInd"`
+	Status      struct {
+		Value string `xml:",chardata"`
+		Code  string `xml:"Cd"` // 001.08 and later
+	} `xml:"Sts"`
+	BookingDate struct {
+		Date     string `xml:"Dt"`
+		DateTime string `xml:"DtTm"`
+	} `xml:"BookgDt"`
+	ServicerRef    string            `xml:"AcctSvcrRef"`
+	Details        []camtTransaction `xml:"NtryDtls>TxDtls"`
+	AdditionalInfo string            `xml:"AddtlNtryInf"`
+}
+
+type camtTransaction struct {
+	Amount       camtAmount  `xml:"Amt"`
+	TxAmount     camtAmount  `xml:"AmtDtls>TxAmt>Amt"`
+	ServicerRef  string      `xml:"Refs>AcctSvcrRef"`
+	EndToEndID   string      `xml:"Refs>EndToEndId"`
+	InstrID      string      `xml:"Refs>InstrId"`
+	Debtor       string      `xml:"RltdPties>Dbtr>Nm"`
+	DebtorAcct   camtAccount `xml:"RltdPties>DbtrAcct"`
+	Creditor     string      `xml:"RltdPties>Cdtr>Nm"`
+	CreditorAcct camtAccount `xml:"RltdPties>CdtrAcct"`
+	DebtorBank   string      `xml:"RltdAgts>DbtrAgt>FinInstnId>ClrSysMmbId>MmbId"`
+	CreditorBank string      `xml:"RltdAgts>CdtrAgt>FinInstnId>ClrSysMmbId>MmbId"`
+	Unstructured []string    `xml:"RmtInf>Ustrd"`
+	References   []struct {
+		Type string `xml:"Tp>CdOrPrtry>Prtry"`
+		Ref  string `xml:"Ref"`
+	} `xml:"RmtInf>Strd>CdtrRefInf"`
+	AdditionalInfo string `xml:"AddtlTxInf"`
+}
+
+// camtSymbol finds a Czech payment symbol written into a reference by the bank,
+// e.g. "/VS1234/SS/KS0308" or "VS:1234"
+var camtSymbol = regexp.MustCompile(`(?i)\b(VS|SS|KS)[:/ ]?(\d{1,10})\b`)
+
+// ParseCAMT053 reads an ISO 20022 camt.053 XML statement (ČSOB, KB and other Czech
+// banks). Only booked entries are imported; the bank's reference of the entry
+// (AcctSvcrRef) becomes payments.kind_id with kind "camt". An entry with several
+// transactions (batch) is split into them.
+func ParseCAMT053(data []byte) ([]bank.Transaction, error) {
+	var doc camtDocument
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = camtCharsetReader
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid camt.053 XML: %w", err)
+	}
+	if len(doc.Statements) == 0 {
+		return nil, fmt.Errorf("no statement found (expected BkToCstmrStmt/Stmt)")
+	}
+
+	var transactions []bank.Transaction
+	for _, stmt := range doc.Statements {
+		localAccount := stmt.Account.IBAN
+		if localAccount == "" {
+			localAccount = stmt.Account.Other
+		}
+
+		for n, entry := range stmt.Entries {
+			status := strings.TrimSpace(entry.Status.Value)
+			if entry.Status.Code != "" {
+				status = entry.Status.Code
+			}
+			if status != "" && status != "BOOK" {
+				continue // pending or informational entry
+			}
+
+			ref := entry.ServicerRef
+			if ref == "" && len(entry.Details) == 1 {
+				ref = entry.Details[0].ServicerRef
+			}
+			if ref == "" {
+				ref = entry.Ref
+			}
+			if ref == "" {
+				return nil, fmt.Errorf("entry %d: no bank reference (AcctSvcrRef or NtryRef)", n+1)
+			}
+
+			date := entry.BookingDate.Date
+			if date == "" && len(entry.BookingDate.DateTime) >= 10 {
+				date = entry.BookingDate.DateTime[:10]
+			}
+			if _, err := fio.ParseDate(date); err != nil {
+				return nil, fmt.Errorf("entry %s: invalid booking date %q", ref, date)
+			}
+
+			details := entry.Details
+			if len(details) == 0 {
+				details = []camtTransaction{{}}
+			}
+			for i, detail := range details {
+				amount := entry.Amount
+				kindID := ref
+				if len(details) > 1 {
+					// Batch: each transaction has its own amount and reference
+					if detail.Amount.Value != "" {
+						amount = detail.Amount
+					} else {
+						amount = detail.TxAmount
+					}
+					kindID = fmt.Sprintf("%s/%d", ref, i+1)
+					if detail.ServicerRef != "" {
+						kindID = detail.ServicerRef
+					}
+				}
+
+				tx, err := camtTransactionOf(entry, detail, amount)
+				if err != nil {
+					return nil, fmt.Errorf("entry %s: %w", ref, err)
+				}
+				tx.Date = date
+				transactions = append(transactions, bank.Transaction{
+					Transaction:  tx,
+					Kind:         "camt",
+					KindID:       kindID,
+					LocalAccount: localAccount,
+				})
+			}
+		}
+	}
+
+	if len(transactions) == 0 {
+		return nil, fmt.Errorf("no booked entries found")
+	}
+	return transactions, nil
+}
+
+// camtCharsetReader accepts statements declared as Windows-1250 besides UTF-8
+func camtCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "windows-1250", "cp1250":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(decodeText(data)), nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
+
+// camtTransactionOf fills the transaction fields from an entry and one of its
+// transaction details; the counterparty is the debtor of a credit and the
+// creditor of a debit
+func camtTransactionOf(entry camtEntry, detail camtTransaction, amount camtAmount) (fio.Transaction, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(amount.Value), 64)
+	if err != nil {
+		return fio.Transaction{}, fmt.Errorf("invalid amount %q", amount.Value)
+	}
+
+	tx := fio.Transaction{
+		Amount:   value,
+		Currency: amount.Currency,
+		Message:  strings.TrimSpace(strings.Join(detail.Unstructured, " ")),
+		Comment:  strings.TrimSpace(entry.AdditionalInfo),
+	}
+	if tx.Comment == "" {
+		tx.Comment = strings.TrimSpace(detail.AdditionalInfo)
+	}
+
+	account, bank := detail.DebtorAcct, detail.DebtorBank
+	tx.AccountName = strings.TrimSpace(detail.Debtor)
+	switch entry.CreditDebit {
+	case "CRDT":
+	case "DBIT":
+		tx.Amount = -tx.Amount
+		account, bank = detail.CreditorAcct, detail.CreditorBank
+		tx.AccountName = strings.TrimSpace(detail.Creditor)
+	default:
+		return fio.Transaction{}, fmt.Errorf("invalid credit/debit indicator %q", entry.CreditDebit)
+	}
+	tx.AccountNumber, tx.BankCode = camtCounterAccount(account, bank)
+
+	// Symbols: typed creditor references first, then references with "VS..."
+	for _, r := range detail.References {
+		switch strings.ToUpper(strings.TrimSpace(r.Type)) {
+		case "VS":
+			tx.VariableSymbol = strings.TrimLeft(strings.TrimSpace(r.Ref), "0")
+		case "SS":
+			tx.SpecificSymbol = strings.TrimLeft(strings.TrimSpace(r.Ref), "0")
+		}
+	}
+	sources := []string{detail.EndToEndID, detail.InstrID}
+	for _, r := range detail.References {
+		sources = append(sources, r.Ref)
+	}
+	for _, source := range sources {
+		for _, m := range camtSymbol.FindAllStringSubmatch(source, -1) {
+			symbol := strings.TrimLeft(m[2], "0")
+			switch strings.ToUpper(m[1]) {
+			case "VS":
+				if tx.VariableSymbol == "" {
+					tx.VariableSymbol = symbol
+				}
+			case "SS":
+				if tx.SpecificSymbol == "" {
+					tx.SpecificSymbol = symbol
+				}
+			}
+		}
+	}
+
+	return tx, nil
+}
+
+// camtCounterAccount returns the counter account as "[prefix-]number" and bank
+// code, from a Czech IBAN or a domestic "number/bank" identification
+func camtCounterAccount(account camtAccount, bank string) (string, string) {
+	if iban := strings.ToUpper(strings.ReplaceAll(account.IBAN, " ", "")); iban != "" {
+		if number, err := fio.AccountFromIBAN(iban); err == nil {
+			return number, iban[4:8]
+		}
+		return iban, "" // foreign account
+	}
+	if account.Other == "" {
+		return "", ""
+	}
+	if number, code, err := fio.ParseAccount(account.Other); err == nil {
+		return number, code
+	}
+	return strings.TrimSpace(account.Other), strings.TrimSpace(bank)
+}
@@ -0,0 +1,106 @@
+package bankimport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/fio"
+)
+
+// ParseFIOCSV reads a CSV export of the FIO internet banking (semicolon separated,
+// dates DD.MM.YYYY, amounts with a decimal comma). The export starts with a few
+// lines about the account, the transactions follow the header row "ID pohybu;...".
+func ParseFIOCSV(data []byte) ([]fio.Transaction, error) {
+	reader := csv.NewReader(strings.NewReader(decodeText(data)))
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	header := -1
+	for i, record := range records {
+		if len(record) > 0 && strings.TrimSpace(record[0]) == "ID pohybu" {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, fmt.Errorf("header row with \"ID pohybu\" not found (expected FIO CSV export)")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[header] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"ID pohybu", "Datum", "Objem"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column %q is missing", name)
+		}
+	}
+
+	var transactions []fio.Transaction
+	for i, record := range records[header+1:] {
+		line := header + i + 2
+		value := func(name string) string {
+			if c, ok := columns[name]; ok && c < len(record) {
+				return strings.TrimSpace(record[c])
+			}
+			return ""
+		}
+		if value("ID pohybu") == "" {
+			continue // blank or summary line
+		}
+
+		id, err := strconv.ParseInt(value("ID pohybu"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid ID pohybu %q", line, value("ID pohybu"))
+		}
+		date, err := time.Parse("2.1.2006", value("Datum"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", line, value("Datum"))
+		}
+		amount, err := parseCzechAmount(value("Objem"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount %q", line, value("Objem"))
+		}
+
+		transactions = append(transactions, fio.Transaction{
+			ID:              id,
+			Date:            fio.FormatDate(date),
+			Amount:          amount,
+			Currency:        value("Měna"),
+			AccountNumber:   value("Protiúčet"),
+			AccountName:     value("Název protiúčtu"),
+			BankCode:        value("Kód banky"),
+			BankName:        value("Název banky"),
+			VariableSymbol:  value("VS"),
+			SpecificSymbol:  value("SS"),
+			Message:         value("Zpráva pro příjemce"),
+			Comment:         value("Komentář"),
+			TransactionType: value("Typ"),
+			Identification:  value("Poznámka"),
+			ConstantSymbol:  value("KS"),
+			ExecutedBy:      value("Provedl"),
+			Specification:   value("Upřesnění"),
+			BIC:             value("BIC"),
+		})
+	}
+
+	if len(transactions) == 0 {
+		return nil, fmt.Errorf("no transactions found")
+	}
+	return transactions, nil
+}
+
+// parseCzechAmount parses "-1 234,50" (spaces as thousands separator, decimal comma)
+func parseCzechAmount(s string) (float64, error) {
+	s = strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(s)
+	return strconv.ParseFloat(s, 64)
+}
@@ -0,0 +1,99 @@
+package bankimport
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// ContentHash identifies a transaction by its content, so the same transaction
+// imported from two sources (API sync and a statement of another bank, ingest API)
+// can be recognized: booking date, amount, sender account and VS
+func ContentHash(date time.Time, amount money.Amount, remoteAccount, vs string) string {
+	key := fmt.Sprintf("%s|%d|%s|%s", date.Format("2006-01-02"), int64(amount),
+		normalizeAccount(remoteAccount), strings.TrimLeft(strings.TrimSpace(vs), "0"))
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeAccount drops spaces and leading zeros that differ between the formats
+// of banks ("000019-0002000145/0800" and "19-2000145/0800")
+func normalizeAccount(account string) string {
+	account = strings.ToUpper(strings.ReplaceAll(account, " ", ""))
+	number, bankCode, hasBank := strings.Cut(account, "/")
+	prefix, base, hasPrefix := strings.Cut(number, "-")
+	if hasPrefix {
+		number = strings.TrimLeft(prefix, "0") + "-" + strings.TrimLeft(base, "0")
+		if strings.HasPrefix(number, "-") {
+			number = number[1:]
+		}
+	} else {
+		number = strings.TrimLeft(number, "0")
+	}
+	if hasBank {
+		return number + "/" + bankCode
+	}
+	return number
+}
+
+// FindDuplicate returns the payment from another source with the same content
+// hash, or nil if the transaction is new
+func FindDuplicate(ctx context.Context, queries *db.Queries, hash, kind string) (*db.Payment, error) {
+	original, err := queries.FindDuplicatePayment(ctx, db.FindDuplicatePaymentParams{
+		ContentHash: hash,
+		Kind:        kind,
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &original, nil
+}
+
+// RecordDuplicate stores the content hash of a new payment and, if it repeats a
+// payment from another source, flags it for admin review
+func RecordDuplicate(ctx context.Context, queries *db.Queries, paymentID int64, hash string, original *db.Payment) error {
+	if err := queries.SetPaymentContentHash(ctx, db.SetPaymentContentHashParams{
+		ContentHash: hash,
+		ID:          paymentID,
+	}); err != nil {
+		return err
+	}
+	if original == nil {
+		return nil
+	}
+	return queries.CreatePaymentDuplicate(ctx, db.CreatePaymentDuplicateParams{
+		PaymentID:   paymentID,
+		DuplicateOf: original.ID,
+	})
+}
+
+// backfillContentHashes fills the content hash of payments stored before
+// migration 028 or by other paths (reversals, manual entries)
+func backfillContentHashes(ctx context.Context, queries *db.Queries) error {
+	payments, err := queries.ListPaymentsWithoutContentHash(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range payments {
+		if err := queries.SetPaymentContentHash(ctx, db.SetPaymentContentHashParams{
+			ContentHash: ContentHash(p.Date, p.Amount, p.RemoteAccount, p.Identification),
+			ID:          p.ID,
+		}); err != nil {
+			return err
+		}
+	}
+	if len(payments) > 0 {
+		log.Printf("ℹ Computed content hash of %d payments", len(payments))
+	}
+	return nil
+}
@@ -0,0 +1,726 @@
+package bankimport
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/base48/member-portal/internal/bank"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fio"
+	"github.com/base48/member-portal/internal/invoice"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/base48/member-portal/internal/paymentrule"
+	"github.com/base48/member-portal/internal/reimbursement"
+)
+
+// importTransaction stores one transaction and counts the result in s
+func importTransaction(ctx context.Context, queries *db.Queries, rules []paymentrule.Rule, opts Options, tx bank.Transaction, s *Summary) {
+	// Outgoing payments are imported as expenses, except reimbursement payouts
+	// (matched to the request by VS, also kept as an expense) and the bank
+	// returning an earlier incoming payment (chargeback, refund) - those are
+	// linked to the original
+	if tx.Amount < 0 {
+		if paid, err := syncReimbursement(ctx, queries, tx); err != nil {
+			log.Printf("✗ Failed to process reimbursement payout (%s %s): %v", tx.Kind, tx.KindID, err)
+			s.Errors++
+			return
+		} else if paid {
+			s.ReimbursementsPaid++
+			return
+		}
+
+		result, err := syncReversal(ctx, queries, tx)
+		switch {
+		case err != nil:
+			log.Printf("✗ Failed to process reversal (%s %s): %v", tx.Kind, tx.KindID, err)
+			s.Errors++
+		case result == reversalLinked:
+			s.ReversalsLinked++
+		case result == reversalAmbiguous:
+			s.ReversalsReview = append(s.ReversalsReview, tx)
+		case result == reversalNone:
+			if imported, err := syncExpense(ctx, queries, tx, "", sql.NullString{}); err != nil {
+				log.Printf("✗ Failed to import expense (%s %s): %v", tx.Kind, tx.KindID, err)
+				s.Errors++
+			} else if imported {
+				s.ExpensesImported++
+			} else {
+				s.Skipped++
+			}
+		default:
+			s.Skipped++
+		}
+		return
+	}
+
+	// Skip zero amounts, only process incoming payments (positive amounts)
+	if tx.Amount == 0 {
+		s.Skipped++
+		return
+	}
+
+	// Try to match user by variable symbol (payments_id)
+	// IMPORTANT: VS is NOT the user.id, it's the user.payments_id!
+	// Edge case: Some users put VS in Message field instead of VS field
+	variableSymbol := tx.VariableSymbol
+	if variableSymbol == "" && tx.Message != "" {
+		// Check if Message contains only digits (likely a VS)
+		isNumeric := true
+		for _, ch := range tx.Message {
+			if ch < '0' || ch > '9' {
+				isNumeric = false
+				break
+			}
+		}
+		if isNumeric {
+			variableSymbol = tx.Message
+			log.Printf("ℹ Using Message field as VS: '%s' (%.2f CZK from %s)", variableSymbol, tx.Amount, tx.AccountName)
+		}
+	}
+
+	// Build remote account string (account + bank code)
+	remoteAccount := tx.AccountNumber
+	if tx.BankCode != "" {
+		remoteAccount = fmt.Sprintf("%s/%s", tx.AccountNumber, tx.BankCode)
+	}
+
+	var userID, projectID sql.NullInt64
+	identification := variableSymbol
+	staffComment := sql.NullString{}
+	var matchedRuleID int64  // payment match rule that assigned the payment
+	var linkedByAccount bool // assigned by the sender account history
+	var suggestion *accountMatch
+	var paidInvoice *db.Invoice
+	if variableSymbol != "" {
+		// Look up user by payments_id (VS), not by user.id
+		if user, err := queries.GetUserByPaymentsID(ctx, sql.NullString{String: variableSymbol, Valid: true}); err == nil {
+			userID = sql.NullInt64{Int64: user.ID, Valid: true}
+		} else if inv, user, ok := matchInvoice(ctx, queries, variableSymbol); ok {
+			// Company paying an invoice - counted as the member's fee payment
+			// (same as an admin assignment, VS is set to the member's payments_id)
+			userID = sql.NullInt64{Int64: user.ID, Valid: true}
+			identification = user.PaymentsID.String
+			staffComment = sql.NullString{String: "Faktura " + variableSymbol, Valid: true}
+			paidInvoice = &inv
+			log.Printf("ℹ Payment for invoice %s matched to %s (%.2f CZK from %s)",
+				variableSymbol, user.Email, tx.Amount, tx.AccountName)
+		} else if err == sql.ErrNoRows {
+			if m, ok := matchRule(ctx, queries, rules, tx, remoteAccount, variableSymbol); ok {
+				userID, projectID, identification = m.userID, m.projectID, m.identification
+				staffComment = sql.NullString{String: m.comment + ", původní VS " + variableSymbol, Valid: true}
+				matchedRuleID = m.ruleID
+			} else {
+				log.Printf("⚠ User with payments_id (VS) '%s' not found in database (%.2f CZK from %s)",
+					variableSymbol, tx.Amount, tx.AccountName)
+				s.UnmatchedVS = append(s.UnmatchedVS, tx)
+			}
+		} else {
+			log.Printf("⚠ Database error looking up user by payments_id '%s': %v", variableSymbol, err)
+			s.Errors++
+		}
+	} else if m, ok := matchRule(ctx, queries, rules, tx, remoteAccount, ""); ok {
+		userID, projectID, identification = m.userID, m.projectID, m.identification
+		staffComment = sql.NullString{String: m.comment, Valid: true}
+		matchedRuleID = m.ruleID
+	} else if m, ok := matchAccountHistory(ctx, queries, remoteAccount); ok && opts.AutoLinkByAccount {
+		userID = sql.NullInt64{Int64: m.userID, Valid: true}
+		identification = m.paymentsID
+		staffComment = sql.NullString{String: fmt.Sprintf("Podle účtu odesílatele (%d dřívějších plateb)", m.paymentCount), Valid: true}
+		linkedByAccount = true
+		log.Printf("ℹ Payment %.2f CZK from %s assigned to %s by sender account", tx.Amount, remoteAccount, m.email)
+	} else {
+		if ok {
+			suggestion = &m
+		}
+		if tx.Amount > 0 {
+			log.Printf("⚠ Empty VS - %.2f CZK from %s", tx.Amount, tx.AccountName)
+			s.EmptyVS = append(s.EmptyVS, tx)
+		}
+	}
+
+	// Parse transaction date
+	txDate, err := fio.ParseDate(tx.Date)
+	if err != nil {
+		log.Printf("⚠ Failed to parse date %s: %v", tx.Date, err)
+		txDate = time.Now() // fallback
+	}
+
+	// Prepare raw data JSON
+	rawDataJSON, err := json.Marshal(tx)
+	if err != nil {
+		log.Printf("⚠ Failed to marshal transaction data: %v", err)
+		rawDataJSON = []byte("{}")
+	}
+
+	// Check if payment already exists
+	existingPayment, err := queries.GetPaymentByKindAndID(ctx, db.GetPaymentByKindAndIDParams{
+		Kind:   tx.Kind,
+		KindID: tx.KindID,
+	})
+
+	if err == sql.ErrNoRows {
+		// The same transaction from another source (other bank API, statement,
+		// ingest) is stored unassigned until an admin merges or keeps it
+		hash := ContentHash(txDate, money.FromFloat(tx.Amount), remoteAccount, variableSymbol)
+		original, err := FindDuplicate(ctx, queries, hash, tx.Kind)
+		if err != nil {
+			log.Printf("✗ Failed to check duplicates (%s %s): %v", tx.Kind, tx.KindID, err)
+			s.Errors++
+			return
+		}
+		if original != nil {
+			userID, projectID = sql.NullInt64{}, sql.NullInt64{}
+			identification = variableSymbol
+			staffComment = sql.NullString{String: fmt.Sprintf("Možná duplicita platby #%d (%s %s)", original.ID, original.Kind, original.KindID), Valid: true}
+			matchedRuleID, linkedByAccount, suggestion, paidInvoice = 0, false, nil, nil
+		}
+
+		// Insert new payment
+		payment, err := queries.UpsertPayment(ctx, db.UpsertPaymentParams{
+			UserID:         userID,
+			ProjectID:      projectID, // Only set by a payment match rule
+			Date:           txDate,
+			Amount:         money.FromFloat(tx.Amount),
+			Kind:           tx.Kind,
+			KindID:         tx.KindID,
+			LocalAccount:   tx.LocalAccount,
+			RemoteAccount:  remoteAccount,
+			Identification: identification,
+			RawData:        sql.NullString{String: string(rawDataJSON), Valid: true},
+			StaffComment:   staffComment,
+		})
+
+		if err != nil {
+			log.Printf("✗ Failed to insert payment (%s %s): %v", tx.Kind, tx.KindID, err)
+			s.Errors++
+		} else {
+			log.Printf("✓ Inserted payment: %.2f CZK from %s (VS: %s, %s %s)",
+				tx.Amount, tx.AccountName, tx.VariableSymbol, tx.Kind, tx.KindID)
+			s.Inserted++
+
+			if err := RecordDuplicate(ctx, queries, payment.ID, hash, original); err != nil {
+				log.Printf("⚠ Failed to record content hash of payment #%d: %v", payment.ID, err)
+			} else if original != nil {
+				log.Printf("⚠ Payment #%d looks like a duplicate of #%d (%s %s) - needs review",
+					payment.ID, original.ID, original.Kind, original.KindID)
+				s.Duplicates = append(s.Duplicates, tx)
+			}
+
+			if userID.Valid {
+				s.Assigned = append(s.Assigned, AssignedPayment{
+					PaymentID: payment.ID, UserID: userID.Int64, Amount: payment.Amount, Date: txDate,
+				})
+			} else if !projectID.Valid && original == nil {
+				s.NewUnmatched = append(s.NewUnmatched, tx)
+			}
+			if matchedRuleID != 0 {
+				recordRuleHit(ctx, queries, matchedRuleID)
+				s.RuleMatched++
+			}
+			if linkedByAccount {
+				s.AccountLinked++
+			}
+			if suggestion != nil && suggestPayment(ctx, queries, payment.ID, *suggestion) {
+				s.AccountSuggested++
+			}
+			if paidInvoice != nil {
+				markInvoicePaid(ctx, queries, *paidInvoice, payment)
+			}
+		}
+	} else if err != nil {
+		log.Printf("⚠ Error checking existing payment: %v", err)
+		s.Errors++
+	} else {
+		// Payment exists - check if it needs update
+		needsUpdate := false
+
+		// Check if user_id changed (manual assignment)
+		if userID.Valid && (!existingPayment.UserID.Valid || existingPayment.UserID.Int64 != userID.Int64) {
+			needsUpdate = true
+		}
+		if projectID.Valid && !existingPayment.ProjectID.Valid {
+			needsUpdate = true
+		}
+
+		// A match rule or the sender account only fills in payments nobody has
+		// handled yet (not reassigned, moved to a project or dismissed by an admin)
+		handled := existingPayment.UserID.Valid || existingPayment.ProjectID.Valid || existingPayment.DismissedAt != nil
+		if (matchedRuleID != 0 || linkedByAccount) && handled {
+			needsUpdate = false
+		}
+		if suggestion != nil && !handled && suggestPayment(ctx, queries, existingPayment.ID, *suggestion) {
+			s.AccountSuggested++
+		}
+
+		// Project assignment is preserved unless a match rule sets it
+		if !projectID.Valid {
+			projectID = existingPayment.ProjectID
+		}
+
+		if needsUpdate {
+			_, err = queries.UpsertPayment(ctx, db.UpsertPaymentParams{
+				UserID:         userID,
+				ProjectID:      projectID,
+				Date:           txDate,
+				Amount:         money.FromFloat(tx.Amount),
+				Kind:           tx.Kind,
+				KindID:         tx.KindID,
+				LocalAccount:   tx.LocalAccount,
+				RemoteAccount:  remoteAccount,
+				Identification: identification,
+				RawData:        sql.NullString{String: string(rawDataJSON), Valid: true},
+				StaffComment:   existingPayment.StaffComment, // Preserve staff comment
+			})
+
+			if err != nil {
+				log.Printf("✗ Failed to update payment (%s %s): %v", tx.Kind, tx.KindID, err)
+				s.Errors++
+			} else {
+				log.Printf("↻ Updated payment: %.2f CZK (%s %s)", tx.Amount, tx.Kind, tx.KindID)
+				s.Updated++
+
+				if userID.Valid && !existingPayment.UserID.Valid {
+					s.Assigned = append(s.Assigned, AssignedPayment{
+						PaymentID: existingPayment.ID, UserID: userID.Int64, Amount: existingPayment.Amount, Date: txDate,
+					})
+				}
+
+				if matchedRuleID != 0 {
+					recordRuleHit(ctx, queries, matchedRuleID)
+					s.RuleMatched++
+				}
+				if linkedByAccount {
+					s.AccountLinked++
+				}
+			}
+		} else {
+			// No changes needed
+			s.Skipped++
+		}
+	}
+}
+
+// Reversal processing results
+const (
+	reversalNone      = "none"      // no matching credit, regular outgoing payment
+	reversalExisting  = "existing"  // already imported in a previous run
+	reversalLinked    = "linked"    // linked to the original payment
+	reversalAmbiguous = "ambiguous" // multiple candidates, waiting for admin review
+)
+
+// syncReversal checks whether an outgoing transaction returns an earlier credit
+// (same remote account and amount, same VS preferred). A single match is stored
+// linked to the original payment so the member's balance nets out; multiple
+// matches are stored unassigned and flagged for admin review.
+func syncReversal(ctx context.Context, queries *db.Queries, tx bank.Transaction) (string, error) {
+	kindID := tx.KindID
+	if _, err := queries.GetPaymentByKindAndID(ctx, db.GetPaymentByKindAndIDParams{
+		Kind:   tx.Kind,
+		KindID: kindID,
+	}); err == nil {
+		return reversalExisting, nil
+	} else if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	// Card payments and bank fees have no counter account
+	if tx.AccountNumber == "" {
+		return reversalNone, nil
+	}
+	remoteAccount := tx.AccountNumber
+	if tx.BankCode != "" {
+		remoteAccount = fmt.Sprintf("%s/%s", tx.AccountNumber, tx.BankCode)
+	}
+
+	txDate, err := fio.ParseDate(tx.Date)
+	if err != nil {
+		log.Printf("⚠ Failed to parse date %s: %v", tx.Date, err)
+		txDate = time.Now() // fallback
+	}
+
+	candidates, err := queries.ListReversalCandidates(ctx, db.ListReversalCandidatesParams{
+		RemoteAccount: remoteAccount,
+		Amount:        money.FromFloat(-tx.Amount),
+		Date:          txDate,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return reversalNone, nil
+	}
+
+	// Narrow down by VS if the bank kept it on the reversal
+	if len(candidates) > 1 && tx.VariableSymbol != "" {
+		var sameVS []db.Payment
+		for _, c := range candidates {
+			if c.Identification == tx.VariableSymbol {
+				sameVS = append(sameVS, c)
+			}
+		}
+		if len(sameVS) > 0 {
+			candidates = sameVS
+		}
+	}
+
+	rawDataJSON, err := json.Marshal(tx)
+	if err != nil {
+		log.Printf("⚠ Failed to marshal transaction data: %v", err)
+		rawDataJSON = []byte("{}")
+	}
+
+	payment, err := queries.UpsertPayment(ctx, db.UpsertPaymentParams{
+		UserID:         sql.NullInt64{},
+		ProjectID:      sql.NullInt64{},
+		Date:           txDate,
+		Amount:         money.FromFloat(tx.Amount),
+		Kind:           tx.Kind,
+		KindID:         kindID,
+		LocalAccount:   tx.LocalAccount,
+		RemoteAccount:  remoteAccount,
+		Identification: tx.VariableSymbol,
+		RawData:        sql.NullString{String: string(rawDataJSON), Valid: true},
+		StaffComment:   sql.NullString{},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(candidates) > 1 {
+		if _, err := queries.FlagPaymentReversalReview(ctx, payment.ID); err != nil {
+			return "", err
+		}
+		log.Printf("⚠ Reversal %.2f CZK to %s matches %d payments - needs review (%s %s)",
+			tx.Amount, tx.AccountName, len(candidates), tx.Kind, tx.KindID)
+		return reversalAmbiguous, nil
+	}
+
+	original := candidates[0]
+	_, err = queries.LinkPaymentReversal(ctx, db.LinkPaymentReversalParams{
+		ReversalOf:     sql.NullInt64{Int64: original.ID, Valid: true},
+		UserID:         original.UserID,
+		ProjectID:      original.ProjectID,
+		Identification: original.Identification,
+		ID:             payment.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("↩ Linked reversal %.2f CZK to payment #%d (VS: %s, %s %s)",
+		tx.Amount, original.ID, original.Identification, tx.Kind, tx.KindID)
+	return reversalLinked, nil
+}
+
+// syncReimbursement matches an outgoing payment to an exported reimbursement by its
+// VS and amount. The payout is stored without a member (it is not a fee payment) and
+// dismissed right away so it does not show up among unmatched payments.
+func syncReimbursement(ctx context.Context, queries *db.Queries, tx bank.Transaction) (bool, error) {
+	id, ok := reimbursement.ParseVariableSymbol(tx.VariableSymbol)
+	if !ok {
+		return false, nil
+	}
+
+	item, err := queries.GetReimbursement(ctx, id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if item.State != reimbursement.StateExported {
+		return false, nil
+	}
+
+	if money.FromFloat(-tx.Amount) != item.Amount {
+		log.Printf("⚠ Payout %.2f CZK with VS %s does not match reimbursement #%d (%s CZK), leaving it open",
+			tx.Amount, tx.VariableSymbol, item.ID, item.Amount)
+		return false, nil
+	}
+
+	kindID := tx.KindID
+	if _, err := queries.GetPaymentByKindAndID(ctx, db.GetPaymentByKindAndIDParams{
+		Kind:   tx.Kind,
+		KindID: kindID,
+	}); err == nil {
+		return false, nil
+	} else if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	txDate, err := fio.ParseDate(tx.Date)
+	if err != nil {
+		log.Printf("⚠ Failed to parse date %s: %v", tx.Date, err)
+		txDate = time.Now() // fallback
+	}
+
+	rawDataJSON, err := json.Marshal(tx)
+	if err != nil {
+		log.Printf("⚠ Failed to marshal transaction data: %v", err)
+		rawDataJSON = []byte("{}")
+	}
+
+	remoteAccount := tx.AccountNumber
+	if tx.BankCode != "" {
+		remoteAccount = fmt.Sprintf("%s/%s", tx.AccountNumber, tx.BankCode)
+	}
+
+	comment := sql.NullString{String: fmt.Sprintf("Proplacení výdajů #%d", item.ID), Valid: true}
+
+	// Stored before the payment, so a failed run retries both
+	if _, err := syncExpense(ctx, queries, tx, expenseCategoryReimbursement, comment); err != nil {
+		return false, err
+	}
+
+	payment, err := queries.UpsertPayment(ctx, db.UpsertPaymentParams{
+		UserID:         sql.NullInt64{},
+		ProjectID:      sql.NullInt64{},
+		Date:           txDate,
+		Amount:         money.FromFloat(tx.Amount),
+		Kind:           tx.Kind,
+		KindID:         kindID,
+		LocalAccount:   tx.LocalAccount,
+		RemoteAccount:  remoteAccount,
+		Identification: tx.VariableSymbol,
+		RawData:        sql.NullString{String: string(rawDataJSON), Valid: true},
+		StaffComment:   comment,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := queries.DismissPayment(ctx, db.DismissPaymentParams{
+		DismissedBy:     nil,
+		DismissedReason: comment.String,
+		StaffComment:    comment,
+		ID:              payment.ID,
+	}); err != nil {
+		return false, err
+	}
+
+	if _, err := queries.MarkReimbursementPaid(ctx, db.MarkReimbursementPaidParams{
+		PaymentID: sql.NullInt64{Int64: payment.ID, Valid: true},
+		PaidAt:    sql.NullTime{Time: txDate, Valid: true},
+		ID:        item.ID,
+	}); err != nil {
+		return false, err
+	}
+
+	log.Printf("💸 Reimbursement #%d paid: %.2f CZK to %s (%s %s)", item.ID, tx.Amount, remoteAccount, tx.Kind, tx.KindID)
+	return true, nil
+}
+
+// expenseCategoryReimbursement tags reimbursement payouts among expenses
+const expenseCategoryReimbursement = "Proplácení"
+
+// syncExpense stores an outgoing transaction as an expense. An expense imported in
+// a previous run is left as is (keeps the tags set by admins) and reported as false.
+func syncExpense(ctx context.Context, queries *db.Queries, tx bank.Transaction, category string, comment sql.NullString) (bool, error) {
+	txDate, err := fio.ParseDate(tx.Date)
+	if err != nil {
+		log.Printf("⚠ Failed to parse date %s: %v", tx.Date, err)
+		txDate = time.Now() // fallback
+	}
+
+	rawDataJSON, err := json.Marshal(tx)
+	if err != nil {
+		log.Printf("⚠ Failed to marshal transaction data: %v", err)
+		rawDataJSON = []byte("{}")
+	}
+
+	remoteAccount := tx.AccountNumber
+	if tx.BankCode != "" {
+		remoteAccount = fmt.Sprintf("%s/%s", tx.AccountNumber, tx.BankCode)
+	}
+
+	// Card payments and bank fees carry their details in the comment
+	message := tx.Message
+	if message == "" {
+		message = tx.Comment
+	}
+
+	rows, err := queries.CreateExpense(ctx, db.CreateExpenseParams{
+		Kind:           tx.Kind,
+		KindID:         tx.KindID,
+		Date:           txDate,
+		Amount:         money.FromFloat(-tx.Amount),
+		RemoteAccount:  remoteAccount,
+		RemoteName:     tx.AccountName,
+		Identification: tx.VariableSymbol,
+		Message:        message,
+		Category:       category,
+		StaffComment:   comment,
+		RawData:        sql.NullString{String: string(rawDataJSON), Valid: true},
+	})
+	if err != nil {
+		return false, err
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	log.Printf("💳 Expense %.2f CZK to %s (%s, %s %s)", -tx.Amount, tx.AccountName, message, tx.Kind, tx.KindID)
+	return true, nil
+}
+
+// matchInvoice looks up an issued invoice by its number (VS) and the member it belongs to
+func matchInvoice(ctx context.Context, queries *db.Queries, variableSymbol string) (db.Invoice, db.User, bool) {
+	inv, err := queries.GetInvoiceByNumber(ctx, sql.NullString{String: variableSymbol, Valid: true})
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("⚠ Database error looking up invoice '%s': %v", variableSymbol, err)
+		}
+		return db.Invoice{}, db.User{}, false
+	}
+	if inv.State != invoice.StateApproved && inv.State != invoice.StatePaid {
+		return db.Invoice{}, db.User{}, false
+	}
+
+	user, err := queries.GetUserByID(ctx, inv.UserID)
+	if err != nil || !user.PaymentsID.Valid {
+		log.Printf("⚠ Invoice %s belongs to user %d without payments_id, leaving payment unmatched", variableSymbol, inv.UserID)
+		return db.Invoice{}, db.User{}, false
+	}
+
+	return inv, user, true
+}
+
+// ruleMatch is the assignment of a payment by a payment match rule
+type ruleMatch struct {
+	ruleID         int64
+	userID         sql.NullInt64
+	projectID      sql.NullInt64
+	identification string // payments_id of the member (project), so the payment counts
+	comment        string // staff comment
+}
+
+// matchRule assigns a payment that could not be matched by VS using the first
+// matching payment match rule. Payments with a project VS are left to the project.
+func matchRule(ctx context.Context, queries *db.Queries, rules []paymentrule.Rule, tx bank.Transaction, remoteAccount, variableSymbol string) (ruleMatch, bool) {
+	if len(rules) == 0 {
+		return ruleMatch{}, false
+	}
+	if variableSymbol != "" {
+		if _, err := queries.GetProjectByPaymentsID(ctx, variableSymbol); err != sql.ErrNoRows {
+			return ruleMatch{}, false
+		}
+	}
+
+	rule, ok := paymentrule.First(rules, paymentrule.Payment{
+		RemoteAccount:  remoteAccount,
+		Message:        tx.Message,
+		SpecificSymbol: tx.SpecificSymbol,
+		Amount:         tx.Amount,
+	})
+	if !ok {
+		return ruleMatch{}, false
+	}
+
+	m := ruleMatch{ruleID: rule.ID, comment: fmt.Sprintf("Pravidlo párování #%d", rule.ID)}
+	if rule.Name != "" {
+		m.comment += " (" + rule.Name + ")"
+	}
+
+	if rule.UserID.Valid {
+		user, err := queries.GetUserByID(ctx, rule.UserID.Int64)
+		if err != nil || !user.PaymentsID.Valid {
+			log.Printf("⚠ Match rule #%d points to user %d without payments_id, leaving payment unmatched", rule.ID, rule.UserID.Int64)
+			return ruleMatch{}, false
+		}
+		m.userID = rule.UserID
+		m.identification = user.PaymentsID.String
+		log.Printf("ℹ Payment %.2f CZK from %s matched to %s by rule #%d", tx.Amount, remoteAccount, user.Email, rule.ID)
+		return m, true
+	}
+
+	project, err := queries.GetProject(ctx, rule.ProjectID.Int64)
+	if err != nil {
+		log.Printf("⚠ Match rule #%d points to missing project %d, leaving payment unmatched", rule.ID, rule.ProjectID.Int64)
+		return ruleMatch{}, false
+	}
+	m.projectID = rule.ProjectID
+	m.identification = variableSymbol
+	if project.PaymentsID.Valid {
+		m.identification = project.PaymentsID.String
+	}
+	log.Printf("ℹ Payment %.2f CZK from %s matched to project '%s' by rule #%d", tx.Amount, remoteAccount, project.Name, rule.ID)
+	return m, true
+}
+
+// recordRuleHit counts a payment assigned by a match rule (shown to admins)
+func recordRuleHit(ctx context.Context, queries *db.Queries, ruleID int64) {
+	if err := queries.RecordPaymentMatchRuleHit(ctx, ruleID); err != nil {
+		log.Printf("⚠ Failed to record match of rule #%d: %v", ruleID, err)
+	}
+}
+
+// accountMatch is the member who paid from the sender account before
+type accountMatch struct {
+	userID       int64
+	email        string
+	paymentsID   string
+	paymentCount int64 // earlier payments from the account
+}
+
+// matchAccountHistory finds the member behind a payment without VS by the payments
+// previously assigned from the same account. Only an account used by a single
+// member with payments_id qualifies; a shared account (family, company) does not.
+func matchAccountHistory(ctx context.Context, queries *db.Queries, remoteAccount string) (accountMatch, bool) {
+	if remoteAccount == "" {
+		return accountMatch{}, false
+	}
+	users, err := queries.GetUsersByRemoteAccount(ctx, remoteAccount)
+	if err != nil {
+		log.Printf("⚠ Failed to look up payment history of account %s: %v", remoteAccount, err)
+		return accountMatch{}, false
+	}
+	if len(users) != 1 || !users[0].PaymentsID.Valid {
+		return accountMatch{}, false
+	}
+	return accountMatch{
+		userID:       users[0].ID,
+		email:        users[0].Email,
+		paymentsID:   users[0].PaymentsID.String,
+		paymentCount: users[0].PaymentCount,
+	}, true
+}
+
+// suggestPayment stores the member suggested for an unassigned payment (shown in
+// /admin/payments/unmatched); a suggestion rejected by an admin is kept rejected
+func suggestPayment(ctx context.Context, queries *db.Queries, paymentID int64, m accountMatch) bool {
+	err := queries.UpsertPaymentSuggestion(ctx, db.UpsertPaymentSuggestionParams{
+		PaymentID:    paymentID,
+		UserID:       m.userID,
+		PaymentCount: m.paymentCount,
+	})
+	if err != nil {
+		log.Printf("⚠ Failed to store suggestion for payment #%d: %v", paymentID, err)
+		return false
+	}
+	return true
+}
+
+// markInvoicePaid closes an invoice once a payment covering its amount arrives
+func markInvoicePaid(ctx context.Context, queries *db.Queries, inv db.Invoice, payment db.Payment) {
+	if inv.State != invoice.StateApproved {
+		return
+	}
+
+	if payment.Amount < inv.Amount {
+		log.Printf("⚠ Invoice %s: paid %s CZK of %s CZK, leaving it open", inv.Number.String, payment.Amount, inv.Amount)
+		return
+	}
+
+	if _, err := queries.MarkInvoicePaid(ctx, db.MarkInvoicePaidParams{
+		PaymentID: sql.NullInt64{Int64: payment.ID, Valid: true},
+		PaidAt:    sql.NullTime{Time: payment.Date, Valid: true},
+		ID:        inv.ID,
+	}); err != nil {
+		log.Printf("⚠ Failed to mark invoice %s as paid: %v", inv.Number.String, err)
+		return
+	}
+	log.Printf("✓ Invoice %s paid", inv.Number.String)
+}
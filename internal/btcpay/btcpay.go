@@ -0,0 +1,205 @@
+// Package btcpay creates invoices on a BTCPay Server store (Greenfield API) for
+// BTC/Lightning payments of membership fees and project donations, and verifies
+// the webhooks the server sends when an invoice is settled.
+package btcpay
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/money"
+)
+
+// EventInvoiceSettled is sent when an invoice is paid and confirmed
+const EventInvoiceSettled = "InvoiceSettled"
+
+// StatusSettled is the status of a paid and confirmed invoice
+const StatusSettled = "Settled"
+
+// Client calls the Greenfield API of one store
+type Client struct {
+	baseURL    string
+	apiKey     string
+	storeID    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the store; baseURL is the server root
+// (https://pay.example.org)
+func NewClient(baseURL, apiKey, storeID string) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		storeID:    storeID,
+		httpClient: &http.Client{Timeout: 20 * time.Second},
+	}
+}
+
+// InvoiceParams describe an invoice priced in CZK; the buyer pays the equivalent
+// in crypto at the rate of the moment
+type InvoiceParams struct {
+	Amount      money.Amount
+	OrderID     string // shown in the BTCPay store, e.g. "fee-12"
+	ItemDesc    string
+	BuyerEmail  string
+	RedirectURL string // where the checkout page sends the buyer after payment
+	Metadata    map[string]string
+}
+
+// Invoice is a BTCPay invoice (only the fields the portal uses)
+type Invoice struct {
+	ID           string                 `json:"id"`
+	Status       string                 `json:"status"` // New, Processing, Expired, Invalid, Settled
+	Amount       money.Amount           `json:"amount"`
+	Currency     string                 `json:"currency"`
+	CheckoutLink string                 `json:"checkoutLink"`
+	Metadata     map[string]interface{} `json:"metadata"`
+}
+
+// MetadataString returns a string value of the invoice metadata ("" if missing)
+func (i *Invoice) MetadataString(key string) string {
+	value, _ := i.Metadata[key].(string)
+	return value
+}
+
+// CreateInvoice creates an invoice and returns it with the checkout link
+func (c *Client) CreateInvoice(ctx context.Context, p InvoiceParams) (*Invoice, error) {
+	metadata := map[string]string{}
+	for key, value := range p.Metadata {
+		metadata[key] = value
+	}
+	if p.OrderID != "" {
+		metadata["orderId"] = p.OrderID
+	}
+	if p.ItemDesc != "" {
+		metadata["itemDesc"] = p.ItemDesc
+	}
+	if p.BuyerEmail != "" {
+		metadata["buyerEmail"] = p.BuyerEmail
+	}
+
+	body := map[string]interface{}{
+		"amount":   p.Amount.String(),
+		"currency": "CZK",
+		"metadata": metadata,
+	}
+	if p.RedirectURL != "" {
+		body["checkout"] = map[string]interface{}{
+			"redirectURL":           p.RedirectURL,
+			"redirectAutomatically": true,
+		}
+	}
+
+	var invoice Invoice
+	if err := c.do(ctx, http.MethodPost, "/invoices", body, &invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// GetInvoice fetches an invoice of the store
+func (c *Client) GetInvoice(ctx context.Context, invoiceID string) (*Invoice, error) {
+	var invoice Invoice
+	if err := c.do(ctx, http.MethodGet, "/invoices/"+url.PathEscape(invoiceID), nil, &invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	endpoint := c.baseURL + "/api/v1/stores/" + url.PathEscape(c.storeID) + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "token "+c.apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("btcpay request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read btcpay response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("btcpay: %s: %s", apiErr.Code, apiErr.Message)
+		}
+		return fmt.Errorf("btcpay: HTTP %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid btcpay response: %w", err)
+	}
+	return nil
+}
+
+// Event is a webhook delivery. It carries only the invoice ID; the invoice itself
+// is fetched from the API, so a forged event could not fake the amount.
+type Event struct {
+	DeliveryID   string `json:"deliveryId"`
+	WebhookID    string `json:"webhookId"`
+	IsRedelivery bool   `json:"isRedelivery"`
+	Type         string `json:"type"`
+	StoreID      string `json:"storeId"`
+	InvoiceID    string `json:"invoiceId"`
+}
+
+// ErrInvalidSignature is returned for a webhook not signed with the webhook secret
+var ErrInvalidSignature = errors.New("invalid btcpay signature")
+
+// ParseWebhook verifies the BTCPay-Sig header ("sha256=<hex HMAC-SHA256 of the
+// body>") and decodes the event
+func ParseWebhook(payload []byte, signatureHeader, secret string) (*Event, error) {
+	signature, ok := strings.CutPrefix(strings.TrimSpace(signatureHeader), "sha256=")
+	if !ok {
+		return nil, ErrInvalidSignature
+	}
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(decoded, mac.Sum(nil)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid btcpay event: %w", err)
+	}
+	return &event, nil
+}
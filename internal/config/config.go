@@ -2,7 +2,13 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/qrpay"
 )
 
 type Config struct {
@@ -17,19 +23,50 @@ type Config struct {
 	KeycloakURL          string
 	KeycloakRealm        string
 	KeycloakClientID     string
-	KeycloakClientSecret string
+	KeycloakClientSecret string // empty for a public client (PKCE only)
 
 	// Keycloak Service Account (for automated tasks)
 	KeycloakServiceAccountClientID     string
 	KeycloakServiceAccountClientSecret string
+	KeycloakRoleCacheTTL               int // Seconds to cache realm role mappings for admin user lists
+	KeycloakUserCacheTTL               int // Seconds between Keycloak user list refreshes
+
+	// Local development without Keycloak (AUTH_DEV_MODE=1): /auth/login signs in
+	// a fake user with these roles. Refused unless BASE_URL points to localhost.
+	AuthDevMode  bool
+	AuthDevEmail string
+	AuthDevRoles []string
+
+	// Membership state -> Keycloak realm role kept in sync by sync_membership_roles
+	// (downstream services like wiki or door check these roles)
+	MembershipStateRoles map[string]string
 
-	// FIO Bank
+	// Bank account the payments are downloaded from
+	BankProvider string // fio (default) or raiffeisen
 	BankFIOToken string
 	BankIBAN     string
 	BankBIC      string
+	BankName     string // account holder, shown in SEPA (EPC) QR codes
+	// Raiffeisenbank Premium API (BANK_PROVIDER=raiffeisen), client certificate
+	// and client ID from the RB developer portal
+	BankRBClientID string
+	BankRBCertFile string
+	BankRBKeyFile  string
+	BankRBAccount  string // account number without bank code
+	BankRBCurrency string
+	BankRBAPIURL   string
+	// Assign payments without VS to the only member who paid from the same
+	// account before (otherwise the match is only suggested to admins)
+	FIOAutoLinkByAccount bool
+	// FIO allows one request per token every 30 seconds; a request rejected with
+	// 409 (e.g. an overlapping sync) is retried with backoff
+	FIOMinInterval int // seconds between requests of one process, at least 30
+	FIOMaxRetries  int
 
 	// Session
 	SessionSecret string
+	SessionStore  string // cookie (default), sqlite or redis
+	RedisURL      string // redis://[:password@]host:port[/db], for SESSION_STORE=redis
 
 	// SMTP Email
 	SMTPHost     string
@@ -38,8 +75,79 @@ type Config struct {
 	SMTPPassword string
 	SMTPFrom     string
 
+	// Stripe card payments of membership fees (optional, both keys required)
+	StripeSecretKey     string
+	StripeWebhookSecret string // signing secret of the webhook endpoint (whsec_...)
+
+	// BTCPay Server crypto payments (optional, all four required); which levels and
+	// projects accept them is set by admins (levels.btcpay, projects.btcpay)
+	BTCPayURL           string // e.g. https://pay.base48.cz
+	BTCPayAPIKey        string // Greenfield API key with invoice create/view permissions
+	BTCPayStoreID       string
+	BTCPayWebhookSecret string
+
+	// Payment ingestion API (external collectors - bar, GitHub Sponsors, ...)
+	IngestTokens map[string]string // API token -> source name (payments.kind)
+
+	// Support tickets
+	SupportEmail        string // podpora@ address, Reply-To of ticket replies
+	SupportInboundToken string // Bearer token of the inbound email webhook
+
+	// Digest of new unmatched payments after each bank sync (optional)
+	AdminNotifyEmail  string
+	MatrixAdminRoomID string // admin Matrix room, uses the MATRIX_* bot
+
+	// Invoice issuer (proforma invoices for company-paid memberships)
+	InvoiceIssuerName      string
+	InvoiceIssuerAddress   string // multiple lines separated by "\n"
+	InvoiceIssuerCompanyID string // IČO
+	InvoiceDueDays         int
+	// Name and role printed under the signature of donation receipts
+	DonationReceiptSignatory string
+
+	// Fakturoid invoicing of fees of company-billed members (optional, all three
+	// required; sync_fakturoid issues the invoices and syncs their payment status)
+	FakturoidSlug         string // account slug from app.fakturoid.cz/<slug>
+	FakturoidClientID     string // API v3 client credentials (Settings → User account)
+	FakturoidClientSecret string
+
+	// Planned fee changes: members are notified this many weeks before the new amount applies
+	FeeChangeNoticeWeeks int
+	// Fee for the month a member joins: none (full), daily or half-month
+	FeeProration string
+	// Default debt reminder ladder in monthly fees (admin settings may override it):
+	// create_monthly_fees sends a notice / warning email, suspend_debtors suspends
+	DebtNoticeMonths     int
+	DebtWarningMonths    int
+	SuspensionDebtMonths int
+
+	// Vouches from existing members needed before an application can be approved
+	ApplicationVouches int
+
+	// SpaceAPI endpoint (https://spaceapi.io) used by the space occupancy widget
+	SpaceAPIURL string
+
+	// Community Matrix room for milestone announcements (optional)
+	MatrixHomeserverURL string
+	MatrixAccessToken   string // access token of the bot user, a member of the room
+	MatrixRoomID        string
+
+	// Rate limits in requests per minute (token bucket, 0 = off)
+	RateLimitAuth int // /auth/* per client IP (login brute force)
+	RateLimitAPI  int // /api/* per logged in user, otherwise per client IP
+
+	// Reverse proxies whose X-Forwarded-For / X-Real-IP headers give the client IP;
+	// the headers of other clients are ignored, so they cannot pick their own IP
+	TrustedProxies []netip.Prefix
+
+	// Maintenance mode at startup (admins can toggle it at runtime)
+	MaintenanceMode    bool
+	MaintenanceUntil   time.Time // zero = until turned off
+	MaintenanceMessage string
+
 	// Paths
-	WebRoot string // Base directory for web assets (templates, static files)
+	WebRoot             string // Base directory for web assets (templates, static files)
+	TemplateOverrideDir string // Deployment templates shadowing WebRoot/templates (optional)
 }
 
 func Load() (*Config, error) {
@@ -53,38 +161,171 @@ func Load() (*Config, error) {
 		KeycloakClientSecret:               getEnv("KEYCLOAK_CLIENT_SECRET", ""),
 		KeycloakServiceAccountClientID:     getEnv("KEYCLOAK_SERVICE_ACCOUNT_CLIENT_ID", ""),
 		KeycloakServiceAccountClientSecret: getEnv("KEYCLOAK_SERVICE_ACCOUNT_CLIENT_SECRET", ""),
+		KeycloakRoleCacheTTL:               getEnvInt("KEYCLOAK_ROLE_CACHE_TTL", 300),
+		KeycloakUserCacheTTL:               getEnvInt("KEYCLOAK_USER_CACHE_TTL", 300),
+		AuthDevMode:                        getEnv("AUTH_DEV_MODE", "") == "1",
+		AuthDevEmail:                       getEnv("AUTH_DEV_EMAIL", "dev@localhost"),
+		AuthDevRoles:                       splitList(getEnv("AUTH_DEV_ROLES", "memberportal_admin,active_member")),
+		BankProvider:                       getEnv("BANK_PROVIDER", "fio"),
 		BankFIOToken:                       getEnv("BANK_FIO_TOKEN", ""),
 		BankIBAN:                           getEnv("BANK_IBAN", ""),
 		BankBIC:                            getEnv("BANK_BIC", ""),
+		BankName:                           getEnv("BANK_NAME", "Base48, z.s."),
+		BankRBClientID:                     getEnv("BANK_RB_CLIENT_ID", ""),
+		BankRBCertFile:                     getEnv("BANK_RB_CERT_FILE", ""),
+		BankRBKeyFile:                      getEnv("BANK_RB_KEY_FILE", ""),
+		BankRBAccount:                      getEnv("BANK_RB_ACCOUNT", ""),
+		BankRBCurrency:                     getEnv("BANK_RB_CURRENCY", "CZK"),
+		BankRBAPIURL:                       getEnv("BANK_RB_API_URL", "https://api.rb.cz"),
+		FIOAutoLinkByAccount:               getEnv("FIO_AUTO_LINK_BY_ACCOUNT", "") == "true",
+		FIOMinInterval:                     getEnvInt("FIO_MIN_INTERVAL", 30),
+		FIOMaxRetries:                      getEnvInt("FIO_MAX_RETRIES", 3),
 		SessionSecret:                      getEnv("SESSION_SECRET", ""),
+		SessionStore:                       getEnv("SESSION_STORE", "cookie"),
+		RedisURL:                           getEnv("REDIS_URL", ""),
 		SMTPHost:                           getEnv("SMTP_HOST", ""),
 		SMTPPort:                           getEnvInt("SMTP_PORT", 587),
 		SMTPUsername:                       getEnv("SMTP_USERNAME", ""),
 		SMTPPassword:                       getEnv("SMTP_PASSWORD", ""),
 		SMTPFrom:                           getEnv("SMTP_FROM", ""),
+		StripeSecretKey:                    getEnv("STRIPE_SECRET_KEY", ""),
+		StripeWebhookSecret:                getEnv("STRIPE_WEBHOOK_SECRET", ""),
+		BTCPayURL:                          strings.TrimSuffix(getEnv("BTCPAY_URL", ""), "/"),
+		BTCPayAPIKey:                       getEnv("BTCPAY_API_KEY", ""),
+		BTCPayStoreID:                      getEnv("BTCPAY_STORE_ID", ""),
+		BTCPayWebhookSecret:                getEnv("BTCPAY_WEBHOOK_SECRET", ""),
+		SupportEmail:                       getEnv("SUPPORT_EMAIL", ""),
+		SupportInboundToken:                getEnv("SUPPORT_INBOUND_TOKEN", ""),
+		AdminNotifyEmail:                   getEnv("ADMIN_NOTIFY_EMAIL", ""),
+		MatrixAdminRoomID:                  getEnv("MATRIX_ADMIN_ROOM_ID", ""),
+		InvoiceIssuerName:                  getEnv("INVOICE_ISSUER_NAME", "Base48, z.s."),
+		InvoiceIssuerAddress:               strings.ReplaceAll(getEnv("INVOICE_ISSUER_ADDRESS", ""), `\n`, "\n"),
+		InvoiceIssuerCompanyID:             getEnv("INVOICE_ISSUER_COMPANY_ID", ""),
+		InvoiceDueDays:                     getEnvInt("INVOICE_DUE_DAYS", 14),
+		DonationReceiptSignatory:           getEnv("DONATION_RECEIPT_SIGNATORY", ""),
+		FakturoidSlug:                      getEnv("FAKTUROID_SLUG", ""),
+		FakturoidClientID:                  getEnv("FAKTUROID_CLIENT_ID", ""),
+		FakturoidClientSecret:              getEnv("FAKTUROID_CLIENT_SECRET", ""),
+		FeeChangeNoticeWeeks:               getEnvInt("FEE_CHANGE_NOTICE_WEEKS", 4),
+		FeeProration:                       getEnv("FEE_PRORATION", "none"),
+		DebtNoticeMonths:                   getEnvInt("DEBT_NOTICE_MONTHS", 1),
+		DebtWarningMonths:                  getEnvInt("DEBT_WARNING_MONTHS", 2),
+		SuspensionDebtMonths:               getEnvInt("SUSPENSION_DEBT_MONTHS", 3),
+		ApplicationVouches:                 getEnvInt("APPLICATION_VOUCHES", 2),
+		SpaceAPIURL:                        getEnv("SPACE_API_URL", ""),
+		MatrixHomeserverURL:                getEnv("MATRIX_HOMESERVER_URL", ""),
+		MatrixAccessToken:                  getEnv("MATRIX_ACCESS_TOKEN", ""),
+		MatrixRoomID:                       getEnv("MATRIX_ROOM_ID", ""),
+		RateLimitAuth:                      getEnvInt("RATE_LIMIT_AUTH", 30),
+		RateLimitAPI:                       getEnvInt("RATE_LIMIT_API", 300),
+		MaintenanceMode:                    getEnv("MAINTENANCE_MODE", "") == "true",
+		MaintenanceMessage:                 getEnv("MAINTENANCE_MESSAGE", ""),
 		WebRoot:                            getEnv("WEB_ROOT", "web"),
+		TemplateOverrideDir:                getEnv("TEMPLATE_OVERRIDE_DIR", ""),
+	}
+
+	// Validate required fields (the dev login does not talk to Keycloak)
+	if cfg.AuthDevMode {
+		if !isLocalURL(cfg.BaseURL) {
+			return nil, fmt.Errorf("AUTH_DEV_MODE is for local development only, refusing it with BASE_URL %s", cfg.BaseURL)
+		}
+	} else {
+		if cfg.KeycloakURL == "" {
+			return nil, fmt.Errorf("KEYCLOAK_URL is required")
+		}
+		if cfg.KeycloakRealm == "" {
+			return nil, fmt.Errorf("KEYCLOAK_REALM is required")
+		}
+		if cfg.KeycloakClientID == "" {
+			return nil, fmt.Errorf("KEYCLOAK_CLIENT_ID is required")
+		}
+	}
+	if cfg.SessionSecret == "" {
+		return nil, fmt.Errorf("SESSION_SECRET is required")
+	}
+	switch cfg.SessionStore {
+	case "cookie", "sqlite":
+	case "redis":
+		if cfg.RedisURL == "" {
+			return nil, fmt.Errorf("REDIS_URL is required for SESSION_STORE=redis")
+		}
+	default:
+		return nil, fmt.Errorf("SESSION_STORE: unknown store %q (cookie, sqlite or redis)", cfg.SessionStore)
 	}
 
-	// Validate required fields
-	if cfg.KeycloakURL == "" {
-		return nil, fmt.Errorf("KEYCLOAK_URL is required")
+	switch cfg.FeeProration {
+	case "none", "daily", "half-month":
+	default:
+		return nil, fmt.Errorf("FEE_PRORATION: unknown mode %q (none, daily or half-month)", cfg.FeeProration)
 	}
-	if cfg.KeycloakRealm == "" {
-		return nil, fmt.Errorf("KEYCLOAK_REALM is required")
+
+	if cfg.DebtNoticeMonths < 0 || cfg.DebtWarningMonths < 0 {
+		return nil, fmt.Errorf("DEBT_NOTICE_MONTHS and DEBT_WARNING_MONTHS must not be negative")
 	}
-	if cfg.KeycloakClientID == "" {
-		return nil, fmt.Errorf("KEYCLOAK_CLIENT_ID is required")
+	if cfg.KeycloakRoleCacheTTL < 1 {
+		return nil, fmt.Errorf("KEYCLOAK_ROLE_CACHE_TTL must be at least 1 second")
 	}
-	if cfg.KeycloakClientSecret == "" {
-		return nil, fmt.Errorf("KEYCLOAK_CLIENT_SECRET is required")
+	if cfg.KeycloakUserCacheTTL < 1 {
+		return nil, fmt.Errorf("KEYCLOAK_USER_CACHE_TTL must be at least 1 second")
 	}
-	if cfg.SessionSecret == "" {
-		return nil, fmt.Errorf("SESSION_SECRET is required")
+	if cfg.SuspensionDebtMonths < 1 {
+		return nil, fmt.Errorf("SUSPENSION_DEBT_MONTHS must be at least 1")
+	}
+
+	// BANK_IBAN may also be a Czech account number (2900086515/2010)
+	if cfg.BankIBAN != "" {
+		iban, err := qrpay.ParseIBAN(cfg.BankIBAN)
+		if err != nil {
+			return nil, fmt.Errorf("BANK_IBAN: %w", err)
+		}
+		cfg.BankIBAN = iban
+	}
+
+	ingestTokens, err := parseIngestTokens(getEnv("INGEST_TOKENS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.IngestTokens = ingestTokens
+
+	trustedProxies, err := parseTrustedProxies(getEnv("TRUSTED_PROXIES", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.TrustedProxies = trustedProxies
+
+	stateRoles, err := parseStateRoles(getEnv("MEMBERSHIP_STATE_ROLES", "accepted:member_active"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.MembershipStateRoles = stateRoles
+
+	if until := getEnv("MAINTENANCE_UNTIL", ""); until != "" {
+		cfg.MaintenanceUntil, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, fmt.Errorf("MAINTENANCE_UNTIL: expected RFC 3339 time (2026-01-31T18:00:00+01:00): %w", err)
+		}
 	}
 
 	return cfg, nil
 }
 
+// isLocalURL reports whether the URL points to this machine (localhost, 127.0.0.1 or ::1)
+func isLocalURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Hostname()) {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+func (c *Config) FakturoidEnabled() bool {
+	return c.FakturoidSlug != "" && c.FakturoidClientID != "" && c.FakturoidClientSecret != ""
+}
+
 func (c *Config) KeycloakIssuerURL() string {
 	return fmt.Sprintf("%s/realms/%s", c.KeycloakURL, c.KeycloakRealm)
 }
@@ -93,6 +334,88 @@ func (c *Config) OAuthCallbackURL() string {
 	return fmt.Sprintf("%s/auth/callback", c.BaseURL)
 }
 
+// parseIngestTokens parses "source:token,source:token" into a token -> source map
+func parseIngestTokens(value string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		source, token, ok := strings.Cut(entry, ":")
+		source, token = strings.TrimSpace(source), strings.TrimSpace(token)
+		if !ok || source == "" || token == "" {
+			return nil, fmt.Errorf("INGEST_TOKENS: invalid entry %q, expected source:token", entry)
+		}
+		// Bank payments are imported by sync_fio_payments and import_bank_statement only,
+		// manual payments are entered by admins, card and crypto payments come from
+		// the Stripe and BTCPay webhooks
+		if source == "fio" || source == "rb" || source == "camt" || source == "manual" || source == "stripe" || source == "btcpay" {
+			return nil, fmt.Errorf("INGEST_TOKENS: source name %q is reserved", source)
+		}
+		tokens[token] = source
+	}
+	return tokens, nil
+}
+
+// parseStateRoles parses "state:role,state:role" into a state -> role map
+func parseStateRoles(value string) (map[string]string, error) {
+	roles := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		state, role, ok := strings.Cut(entry, ":")
+		state, role = strings.TrimSpace(state), strings.TrimSpace(role)
+		if !ok || state == "" || role == "" {
+			return nil, fmt.Errorf("MEMBERSHIP_STATE_ROLES: invalid entry %q, expected state:role", entry)
+		}
+		switch state {
+		case "awaiting", "accepted", "rejected", "exmember", "suspended":
+		default:
+			return nil, fmt.Errorf("MEMBERSHIP_STATE_ROLES: unknown state %q", state)
+		}
+		roles[state] = role
+	}
+	return roles, nil
+}
+
+// parseTrustedProxies parses a comma separated list of IP addresses and CIDR ranges
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, entry := range splitList(value) {
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES: invalid range %q", entry)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: invalid address %q", entry)
+		}
+		addr = addr.Unmap()
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
+// splitList parses a comma separated list, skipping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
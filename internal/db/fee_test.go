@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/base48/member-portal/internal/money"
+)
+
+func TestCreateFeeIfMissing(t *testing.T) {
+	database, q := openTestDB(t)
+	ctx := context.Background()
+
+	mustExec(t, database, `INSERT INTO levels (id, name, amount) VALUES (100, 'Test', '100000')`)
+	mustExec(t, database, `INSERT INTO users (id, email, level_id) VALUES (1, 'a@example.com', 100)`)
+
+	params := CreateFeeIfMissingParams{
+		UserID:      1,
+		LevelID:     100,
+		PeriodStart: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		Amount:      money.Amount(100000),
+		Months:      1,
+	}
+	fee, err := q.CreateFeeIfMissing(ctx, params)
+	if err != nil {
+		t.Fatalf("CreateFeeIfMissing() error: %v", err)
+	}
+	if fee.ID == 0 || fee.Amount != params.Amount {
+		t.Errorf("CreateFeeIfMissing() = %+v", fee)
+	}
+
+	// A second run for the same period must not bill the member again
+	params.Amount = money.Amount(50000)
+	if _, err := q.CreateFeeIfMissing(ctx, params); err != sql.ErrNoRows {
+		t.Errorf("CreateFeeIfMissing() again error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := q.CreateFee(ctx, CreateFeeParams(params)); err == nil {
+		t.Error("CreateFee() for the same period succeeded, want a unique constraint error")
+	}
+
+	count, err := q.CountFeesByUser(ctx, 1)
+	if err != nil || count != 1 {
+		t.Errorf("CountFeesByUser() = %d, %v; want 1", count, err)
+	}
+
+	// Another period is fine
+	params.PeriodStart = params.PeriodStart.AddDate(0, 1, 0)
+	if _, err := q.CreateFeeIfMissing(ctx, params); err != nil {
+		t.Errorf("CreateFeeIfMissing() next month error: %v", err)
+	}
+}
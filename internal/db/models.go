@@ -7,30 +7,305 @@ package db
 import (
 	"database/sql"
 	"time"
+
+	"github.com/base48/member-portal/internal/money"
 )
 
+type AdminAuditLog struct {
+	ID          int64         `json:"id"`
+	ActorUserID sql.NullInt64 `json:"actor_user_id"`
+	ActorEmail  string        `json:"actor_email"`
+	ApiTokenID  sql.NullInt64 `json:"api_token_id"`
+	Method      string        `json:"method"`
+	Path        string        `json:"path"`
+	Target      string        `json:"target"`
+	Payload     string        `json:"payload"`
+	Status      int64         `json:"status"`
+	Error       string        `json:"error"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type ApiToken struct {
+	ID          int64        `json:"id"`
+	UserID      int64        `json:"user_id"`
+	Name        string       `json:"name"`
+	TokenHash   string       `json:"token_hash"`
+	TokenPrefix string       `json:"token_prefix"`
+	Scopes      string       `json:"scopes"`
+	ExpiresAt   sql.NullTime `json:"expires_at"`
+	LastUsedAt  sql.NullTime `json:"last_used_at"`
+	RevokedAt   sql.NullTime `json:"revoked_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type Application struct {
+	ID           int64          `json:"id"`
+	Realname     string         `json:"realname"`
+	Email        string         `json:"email"`
+	Motivation   string         `json:"motivation"`
+	LevelID      int64          `json:"level_id"`
+	State        string         `json:"state"`
+	UserID       sql.NullInt64  `json:"user_id"`
+	AdminComment sql.NullString `json:"admin_comment"`
+	DecidedBy    sql.NullString `json:"decided_by"`
+	DecidedAt    sql.NullTime   `json:"decided_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+type ApplicationVouch struct {
+	ApplicationID int64          `json:"application_id"`
+	UserID        int64          `json:"user_id"`
+	Note          sql.NullString `json:"note"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+type AuthSession struct {
+	Sid        string       `json:"sid"`
+	KeycloakID string       `json:"keycloak_id"`
+	IDToken    string       `json:"id_token"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+}
+
+type BalanceAdjustment struct {
+	ID        int64         `json:"id"`
+	UserID    int64         `json:"user_id"`
+	FeeID     sql.NullInt64 `json:"fee_id"`
+	Amount    money.Amount  `json:"amount"`
+	Reason    string        `json:"reason"`
+	CreatedBy string        `json:"created_by"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type BalanceSnapshot struct {
+	UserID     int64        `json:"user_id"`
+	Month      time.Time    `json:"month"`
+	Balance    money.Amount `json:"balance"`
+	ComputedAt time.Time    `json:"computed_at"`
+}
+
+type BillingDetail struct {
+	UserID             int64         `json:"user_id"`
+	CompanyName        string        `json:"company_name"`
+	CompanyID          string        `json:"company_id"`
+	VatID              string        `json:"vat_id"`
+	Address            string        `json:"address"`
+	UpdatedAt          time.Time     `json:"updated_at"`
+	Fakturoid          bool          `json:"fakturoid"`
+	FakturoidSince     sql.NullTime  `json:"fakturoid_since"`
+	FakturoidSubjectID sql.NullInt64 `json:"fakturoid_subject_id"`
+}
+
+type Charge struct {
+	ID          int64        `json:"id"`
+	UserID      int64        `json:"user_id"`
+	Description string       `json:"description"`
+	Amount      money.Amount `json:"amount"`
+	Category    string       `json:"category"`
+	Date        time.Time    `json:"date"`
+	CreatedBy   string       `json:"created_by"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type DebtThreshold struct {
+	Step      string    `json:"step"`
+	Months    int64     `json:"months"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type DonationReceipt struct {
+	UserID int64     `json:"user_id"`
+	Year   int64     `json:"year"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+type EmailCampaign struct {
+	ID           int64          `json:"id"`
+	Name         string         `json:"name"`
+	Subject      string         `json:"subject"`
+	TemplateName string         `json:"template_name"`
+	Body         sql.NullString `json:"body"`
+	Audience     string         `json:"audience"`
+	Status       string         `json:"status"`
+	CreatedBy    sql.NullInt64  `json:"created_by"`
+	CreatedAt    time.Time      `json:"created_at"`
+	CompletedAt  sql.NullTime   `json:"completed_at"`
+}
+
+type EmailCampaignRecipient struct {
+	ID         int64          `json:"id"`
+	CampaignID int64          `json:"campaign_id"`
+	UserID     int64          `json:"user_id"`
+	Email      string         `json:"email"`
+	Status     string         `json:"status"`
+	Attempts   int64          `json:"attempts"`
+	LastError  sql.NullString `json:"last_error"`
+	SentAt     sql.NullTime   `json:"sent_at"`
+}
+
+type Expense struct {
+	ID             int64          `json:"id"`
+	Kind           string         `json:"kind"`
+	KindID         string         `json:"kind_id"`
+	Date           time.Time      `json:"date"`
+	Amount         money.Amount   `json:"amount"`
+	RemoteAccount  string         `json:"remote_account"`
+	RemoteName     string         `json:"remote_name"`
+	Identification string         `json:"identification"`
+	Message        string         `json:"message"`
+	Category       string         `json:"category"`
+	ProjectID      sql.NullInt64  `json:"project_id"`
+	StaffComment   sql.NullString `json:"staff_comment"`
+	RawData        sql.NullString `json:"raw_data"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+type FakturoidInvoice struct {
+	FeeID       int64        `json:"fee_id"`
+	UserID      int64        `json:"user_id"`
+	FakturoidID int64        `json:"fakturoid_id"`
+	Number      string       `json:"number"`
+	Status      string       `json:"status"`
+	PublicUrl   string       `json:"public_url"`
+	PaidOn      sql.NullTime `json:"paid_on"`
+	CreatedAt   time.Time    `json:"created_at"`
+	SyncedAt    time.Time    `json:"synced_at"`
+}
+
 type Fee struct {
-	ID          int64     `json:"id"`
-	UserID      int64     `json:"user_id"`
-	LevelID     int64     `json:"level_id"`
-	PeriodStart time.Time `json:"period_start"`
-	Amount      string    `json:"amount"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          int64        `json:"id"`
+	UserID      int64        `json:"user_id"`
+	LevelID     int64        `json:"level_id"`
+	PeriodStart time.Time    `json:"period_start"`
+	Amount      money.Amount `json:"amount"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Months      int64        `json:"months"`
+}
+
+type Invoice struct {
+	ID           int64          `json:"id"`
+	UserID       int64          `json:"user_id"`
+	State        string         `json:"state"`
+	Number       sql.NullString `json:"number"`
+	Months       int64          `json:"months"`
+	Amount       money.Amount   `json:"amount"`
+	CompanyName  string         `json:"company_name"`
+	CompanyID    string         `json:"company_id"`
+	VatID        string         `json:"vat_id"`
+	Address      string         `json:"address"`
+	Note         sql.NullString `json:"note"`
+	AdminComment sql.NullString `json:"admin_comment"`
+	DecidedBy    sql.NullString `json:"decided_by"`
+	IssuedAt     sql.NullTime   `json:"issued_at"`
+	DueAt        sql.NullTime   `json:"due_at"`
+	PaymentID    sql.NullInt64  `json:"payment_id"`
+	PaidAt       sql.NullTime   `json:"paid_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	FeeID        sql.NullInt64  `json:"fee_id"`
+	ChargeID     sql.NullInt64  `json:"charge_id"`
+	Item         sql.NullString `json:"item"`
+}
+
+type InvoiceSequence struct {
+	Year       int64 `json:"year"`
+	LastNumber int64 `json:"last_number"`
 }
 
 type Level struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Amount    string    `json:"amount"`
-	Active    bool      `json:"active"`
+	ID        int64        `json:"id"`
+	Name      string       `json:"name"`
+	Amount    money.Amount `json:"amount"`
+	Active    bool         `json:"active"`
+	CreatedAt time.Time    `json:"created_at"`
+	Btcpay    bool         `json:"btcpay"`
+}
+
+type LevelChangeRequest struct {
+	ID            int64          `json:"id"`
+	UserID        int64          `json:"user_id"`
+	LevelID       int64          `json:"level_id"`
+	EffectiveFrom time.Time      `json:"effective_from"`
+	Note          sql.NullString `json:"note"`
+	State         string         `json:"state"`
+	AdminComment  sql.NullString `json:"admin_comment"`
+	DecidedBy     sql.NullString `json:"decided_by"`
+	DecidedAt     sql.NullTime   `json:"decided_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+type LevelHistory struct {
+	ID            int64         `json:"id"`
+	UserID        int64         `json:"user_id"`
+	LevelID       int64         `json:"level_id"`
+	EffectiveFrom time.Time     `json:"effective_from"`
+	RequestID     sql.NullInt64 `json:"request_id"`
+	CreatedBy     string        `json:"created_by"`
+	AppliedAt     sql.NullTime  `json:"applied_at"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+type LevelPriceChange struct {
+	ID            int64          `json:"id"`
+	LevelID       int64          `json:"level_id"`
+	NewAmount     money.Amount   `json:"new_amount"`
+	EffectiveFrom time.Time      `json:"effective_from"`
+	Note          sql.NullString `json:"note"`
+	CreatedBy     string         `json:"created_by"`
+	NotifiedAt    sql.NullTime   `json:"notified_at"`
+	AppliedAt     sql.NullTime   `json:"applied_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+type LevelPriceChangeNotice struct {
+	ChangeID int64     `json:"change_id"`
+	UserID   int64     `json:"user_id"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+type Lock struct {
+	Name       string    `json:"name"`
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type MemberMilestone struct {
+	UserID    int64     `json:"user_id"`
+	Milestone string    `json:"milestone"`
+	Emailed   bool      `json:"emailed"`
+	Announced bool      `json:"announced"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type MembershipPause struct {
+	ID        int64        `json:"id"`
+	UserID    int64        `json:"user_id"`
+	StartDate time.Time    `json:"start_date"`
+	EndDate   sql.NullTime `json:"end_date"`
+	Reason    string       `json:"reason"`
+	CreatedBy string       `json:"created_by"`
+	CreatedAt time.Time    `json:"created_at"`
+	EndedAt   sql.NullTime `json:"ended_at"`
+}
+
+type MembershipTermination struct {
+	ID          int64          `json:"id"`
+	UserID      int64          `json:"user_id"`
+	EndDate     time.Time      `json:"end_date"`
+	Reason      string         `json:"reason"`
+	State       string         `json:"state"`
+	CancelledBy sql.NullString `json:"cancelled_by"`
+	CreatedAt   time.Time      `json:"created_at"`
+	DecidedAt   sql.NullTime   `json:"decided_at"`
+}
+
 type Payment struct {
 	ID              int64          `json:"id"`
 	UserID          sql.NullInt64  `json:"user_id"`
 	Date            time.Time      `json:"date"`
-	Amount          string         `json:"amount"`
+	Amount          money.Amount   `json:"amount"`
 	Kind            string         `json:"kind"`
 	KindID          string         `json:"kind_id"`
 	LocalAccount    string         `json:"local_account"`
@@ -43,13 +318,104 @@ type Payment struct {
 	DismissedAt     interface{}    `json:"dismissed_at"`
 	DismissedBy     interface{}    `json:"dismissed_by"`
 	DismissedReason interface{}    `json:"dismissed_reason"`
+	ReversalOf      sql.NullInt64  `json:"reversal_of"`
+	ReversalReview  bool           `json:"reversal_review"`
+	ContentHash     string         `json:"content_hash"`
+	Classification  string         `json:"classification"`
+}
+
+type PaymentDuplicate struct {
+	PaymentID   int64          `json:"payment_id"`
+	DuplicateOf int64          `json:"duplicate_of"`
+	State       string         `json:"state"`
+	DecidedBy   sql.NullString `json:"decided_by"`
+	DecidedAt   sql.NullTime   `json:"decided_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+type PaymentMatchRule struct {
+	ID             int64           `json:"id"`
+	Name           string          `json:"name"`
+	Priority       int64           `json:"priority"`
+	RemoteAccount  string          `json:"remote_account"`
+	MessagePattern string          `json:"message_pattern"`
+	SpecificSymbol string          `json:"specific_symbol"`
+	AmountMin      sql.NullFloat64 `json:"amount_min"`
+	AmountMax      sql.NullFloat64 `json:"amount_max"`
+	UserID         sql.NullInt64   `json:"user_id"`
+	ProjectID      sql.NullInt64   `json:"project_id"`
+	Active         bool            `json:"active"`
+	MatchCount     int64           `json:"match_count"`
+	LastMatchedAt  sql.NullTime    `json:"last_matched_at"`
+	CreatedBy      string          `json:"created_by"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+type PaymentPlan struct {
+	ID          int64        `json:"id"`
+	UserID      int64        `json:"user_id"`
+	TotalDebt   money.Amount `json:"total_debt"`
+	Installment money.Amount `json:"installment"`
+	StartDate   time.Time    `json:"start_date"`
+	Note        string       `json:"note"`
+	CreatedBy   string       `json:"created_by"`
+	CreatedAt   time.Time    `json:"created_at"`
+	EndedAt     sql.NullTime `json:"ended_at"`
+}
+
+type PaymentReminder struct {
+	UserID    int64        `json:"user_id"`
+	DueDate   string       `json:"due_date"`
+	Amount    money.Amount `json:"amount"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type PaymentSplit struct {
+	ID             int64         `json:"id"`
+	PaymentID      int64         `json:"payment_id"`
+	UserID         sql.NullInt64 `json:"user_id"`
+	ProjectID      sql.NullInt64 `json:"project_id"`
+	Amount         money.Amount  `json:"amount"`
+	Note           string        `json:"note"`
+	CreatedBy      string        `json:"created_by"`
+	CreatedAt      time.Time     `json:"created_at"`
+	Classification string        `json:"classification"`
+}
+
+type PaymentStatement struct {
+	UserID int64     `json:"user_id"`
+	Year   int64     `json:"year"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+type PaymentSuggestion struct {
+	PaymentID    int64          `json:"payment_id"`
+	UserID       int64          `json:"user_id"`
+	PaymentCount int64          `json:"payment_count"`
+	State        string         `json:"state"`
+	DecidedBy    sql.NullString `json:"decided_by"`
+	DecidedAt    sql.NullTime   `json:"decided_at"`
+	CreatedAt    time.Time      `json:"created_at"`
 }
 
 type Project struct {
-	ID          int64          `json:"id"`
-	Name        string         `json:"name"`
-	PaymentsID  sql.NullString `json:"payments_id"`
-	Description sql.NullString `json:"description"`
+	ID              int64          `json:"id"`
+	Name            string         `json:"name"`
+	PaymentsID      sql.NullString `json:"payments_id"`
+	Description     sql.NullString `json:"description"`
+	Public          bool           `json:"public"`
+	Btcpay          bool           `json:"btcpay"`
+	Goal            money.Amount   `json:"goal"`
+	Deadline        sql.NullTime   `json:"deadline"`
+	TargetReachedAt sql.NullTime   `json:"target_reached_at"`
+}
+
+type ProjectMember struct {
+	ProjectID int64     `json:"project_id"`
+	UserID    int64     `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type ProjectV struct {
@@ -60,6 +426,57 @@ type ProjectV struct {
 	CreatedAt sql.NullTime   `json:"created_at"`
 }
 
+type ProjectWallEntry struct {
+	ProjectID   int64          `json:"project_id"`
+	UserID      int64          `json:"user_id"`
+	Nickname    string         `json:"nickname"`
+	State       string         `json:"state"`
+	ModeratedBy sql.NullString `json:"moderated_by"`
+	ModeratedAt sql.NullTime   `json:"moderated_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+type Reimbursement struct {
+	ID           int64          `json:"id"`
+	UserID       int64          `json:"user_id"`
+	State        string         `json:"state"`
+	Amount       money.Amount   `json:"amount"`
+	Description  string         `json:"description"`
+	Account      string         `json:"account"`
+	AdminComment sql.NullString `json:"admin_comment"`
+	DecidedBy    sql.NullString `json:"decided_by"`
+	DecidedAt    sql.NullTime   `json:"decided_at"`
+	BatchID      sql.NullInt64  `json:"batch_id"`
+	PaymentID    sql.NullInt64  `json:"payment_id"`
+	PaidAt       sql.NullTime   `json:"paid_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+type ReimbursementBatch struct {
+	ID        int64        `json:"id"`
+	CreatedBy string       `json:"created_by"`
+	Total     money.Amount `json:"total"`
+	Xml       string       `json:"xml"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type ReimbursementReceipt struct {
+	ID              int64     `json:"id"`
+	ReimbursementID int64     `json:"reimbursement_id"`
+	Filename        string    `json:"filename"`
+	ContentType     string    `json:"content_type"`
+	Data            []byte    `json:"data"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type SuspensionExemption struct {
+	UserID    int64     `json:"user_id"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type SystemLog struct {
 	ID        int64          `json:"id"`
 	Subsystem string         `json:"subsystem"`
@@ -70,6 +487,27 @@ type SystemLog struct {
 	CreatedAt time.Time      `json:"created_at"`
 }
 
+type Ticket struct {
+	ID        int64         `json:"id"`
+	UserID    sql.NullInt64 `json:"user_id"`
+	Email     string        `json:"email"`
+	Subject   string        `json:"subject"`
+	State     string        `json:"state"`
+	Source    string        `json:"source"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+type TicketMessage struct {
+	ID        int64          `json:"id"`
+	TicketID  int64          `json:"ticket_id"`
+	Direction string         `json:"direction"`
+	Author    string         `json:"author"`
+	Body      string         `json:"body"`
+	MessageID sql.NullString `json:"message_id"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
 type User struct {
 	ID                int64          `json:"id"`
 	KeycloakID        sql.NullString `json:"keycloak_id"`
@@ -79,7 +517,7 @@ type User struct {
 	Phone             sql.NullString `json:"phone"`
 	AltContact        sql.NullString `json:"alt_contact"`
 	LevelID           int64          `json:"level_id"`
-	LevelActualAmount string         `json:"level_actual_amount"`
+	LevelActualAmount money.Amount   `json:"level_actual_amount"`
 	PaymentsID        sql.NullString `json:"payments_id"`
 	DateJoined        time.Time      `json:"date_joined"`
 	KeysGranted       sql.NullTime   `json:"keys_granted"`
@@ -90,3 +528,55 @@ type User struct {
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 }
+
+type UserAnonymization struct {
+	UserID       int64     `json:"user_id"`
+	AnonymizedBy string    `json:"anonymized_by"`
+	Summary      string    `json:"summary"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type UserDashboardWidget struct {
+	UserID    int64     `json:"user_id"`
+	Widget    string    `json:"widget"`
+	Visible   bool      `json:"visible"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UserNote struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	Pinned    bool      `json:"pinned"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UserNotificationPreference struct {
+	UserID       int64     `json:"user_id"`
+	Notification string    `json:"notification"`
+	Enabled      bool      `json:"enabled"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type UserPaymentSetting struct {
+	UserID       int64     `json:"user_id"`
+	QrFormat     string    `json:"qr_format"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	BillingCycle string    `json:"billing_cycle"`
+}
+
+type UserTask struct {
+	UserID      int64          `json:"user_id"`
+	Task        string         `json:"task"`
+	CompletedAt time.Time      `json:"completed_at"`
+	CompletedBy sql.NullString `json:"completed_by"`
+}
+
+type WebSession struct {
+	ID        string    `json:"id"`
+	Data      []byte    `json:"data"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB returns queries on an in-memory database with all schema migrations
+// applied (002 imports production data and is skipped, like cmd/seed does)
+func openTestDB(t *testing.T) (*sql.DB, *Queries) {
+	t.Helper()
+	database, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1) // every connection would get its own in-memory database
+	t.Cleanup(func() { database.Close() })
+
+	files, err := filepath.Glob("../../migrations/[0-9]*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		if strings.HasSuffix(file, "002_import_old_data.sql") {
+			continue
+		}
+		schema, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := database.Exec(string(schema)); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+	return database, New(database)
+}
+
+// mustExec runs a fixture statement
+func mustExec(t *testing.T, database *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := database.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// createTestProject inserts a project with a VS and returns its ID
+func createTestProject(t *testing.T, database *sql.DB, name, vs string) int64 {
+	t.Helper()
+	result, err := database.Exec(`INSERT INTO projects (name, payments_id) VALUES (?, ?)`, name, vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, database, `INSERT INTO project_vs (project_id, vs) VALUES (?, ?)`, id, vs)
+	return id
+}
+
+func TestGetProjectBalanceEmpty(t *testing.T) {
+	database, q := openTestDB(t)
+	ctx := context.Background()
+	projectID := createTestProject(t, database, "Prázdný", "9001")
+
+	for _, id := range []int64{projectID, 999} { // no payments, unknown project
+		projectID := sql.NullInt64{Int64: id, Valid: true}
+		income, err := q.GetProjectIncome(ctx, projectID)
+		if err != nil || income != 0 {
+			t.Errorf("GetProjectIncome(%d) = %d, %v; want 0", id, income, err)
+		}
+		balance, err := q.GetProjectBalance(ctx, projectID)
+		if err != nil || balance != 0 {
+			t.Errorf("GetProjectBalance(%d) = %d, %v; want 0", id, balance, err)
+		}
+	}
+}
+
+func TestGetProjectBalanceMixed(t *testing.T) {
+	database, q := openTestDB(t)
+	ctx := context.Background()
+	projectID := createTestProject(t, database, "Laser", "9002")
+	otherID := createTestProject(t, database, "Jiný", "9003")
+
+	payment := `INSERT INTO payments (date, amount, kind, kind_id, local_account, remote_account, identification, project_id, content_hash)
+		VALUES ('2026-01-10', ?, 'fio', ?, 'local', 'remote', ?, ?, ?)`
+	// Amounts as integers and as TEXT (the column affinity), by VS and by assignment
+	mustExec(t, database, payment, int64(100000), "p1", "9002", nil, "h1")
+	mustExec(t, database, payment, "45050", "p2", "", projectID, "h2")
+	mustExec(t, database, payment, int64(-5000), "p3", "9002", nil, "h3") // reversal
+	mustExec(t, database, payment, int64(70000), "p4", "9003", nil, "h4") // other project
+	// Split payment: only the allocation to the project counts
+	mustExec(t, database, payment, int64(80000), "p5", "9002", nil, "h5")
+	mustExec(t, database, `INSERT INTO payment_splits (payment_id, project_id, amount, created_by) VALUES (5, ?, '30000', 'admin'), (5, ?, '50000', 'admin')`, projectID, otherID)
+
+	expense := `INSERT INTO expenses (kind, kind_id, date, amount, project_id) VALUES ('fio', ?, '2026-01-20', ?, ?)`
+	mustExec(t, database, expense, "e1", int64(20000), projectID)
+	mustExec(t, database, expense, "e2", "1050", projectID)
+	mustExec(t, database, expense, "e3", int64(99900), nil)
+
+	id := sql.NullInt64{Int64: projectID, Valid: true}
+	income, err := q.GetProjectIncome(ctx, id)
+	if err != nil {
+		t.Fatalf("GetProjectIncome() error: %v", err)
+	}
+	if want := int64(100000 + 45050 - 5000 + 30000); income != want {
+		t.Errorf("GetProjectIncome() = %d, want %d", income, want)
+	}
+
+	balance, err := q.GetProjectBalance(ctx, id)
+	if err != nil {
+		t.Fatalf("GetProjectBalance() error: %v", err)
+	}
+	if want := int64(100000 + 45050 - 5000 + 30000 - 20000 - 1050); balance != want {
+		t.Errorf("GetProjectBalance() = %d, want %d", balance, want)
+	}
+
+	other, err := q.GetProjectBalance(ctx, sql.NullInt64{Int64: otherID, Valid: true})
+	if err != nil || other != 70000+50000 {
+		t.Errorf("GetProjectBalance(other) = %d, %v; want %d", other, err, 70000+50000)
+	}
+}
@@ -23,6 +23,83 @@ SELECT * FROM users ORDER BY realname, email;
 -- name: ListUsersByState :many
 SELECT * FROM users WHERE state = ? ORDER BY realname, email;
 
+-- name: ListUsersPage :many
+-- One page of the admin user list, filtered and sorted in SQL. The balance is the
+-- latest snapshot plus the entries after its month (as listBalance); total is the
+-- number of users matching the filters. keycloak_ids (JSON array, NULL = any)
+-- narrows the list to accounts picked by their Keycloak status.
+WITH latest AS (
+    -- Newest balance snapshot of each user, entries after its month are added
+    -- (the stored month may carry a time zone suffix date() does not parse)
+    SELECT s.user_id, s.balance, date(substr(s.month, 1, 10), '+1 month') AS since
+    FROM balance_snapshots s
+    WHERE s.month = (SELECT MAX(month) FROM balance_snapshots WHERE user_id = s.user_id)
+),
+listed AS (
+    SELECT u.id, u.keycloak_id, u.email, u.username, u.realname, u.phone, u.alt_contact,
+        u.level_id, u.level_actual_amount, u.payments_id, u.date_joined, u.keys_granted,
+        u.keys_returned, u.state, u.is_council, u.is_staff, u.created_at, u.updated_at,
+        COALESCE(l.balance, 0) +
+        COALESCE((
+            SELECT SUM(p.amount)
+            FROM payments p
+            WHERE p.user_id = u.id
+            AND p.identification = u.payments_id
+            AND p.classification != 'donation'
+            AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+            AND (l.since IS NULL OR date(p.date) >= l.since)
+        ), 0) -
+        COALESCE((SELECT SUM(f.amount) FROM fees f WHERE f.user_id = u.id AND (l.since IS NULL OR date(f.period_start) >= l.since)), 0) +
+        COALESCE((
+            SELECT SUM(s.amount)
+            FROM payment_splits s
+            JOIN payments p ON p.id = s.payment_id
+            WHERE s.user_id = u.id AND s.classification = 'fee'
+            AND (l.since IS NULL OR date(p.date) >= l.since)
+        ), 0) -
+        COALESCE((SELECT SUM(c.amount) FROM charges c WHERE c.user_id = u.id AND (l.since IS NULL OR date(c.date) >= l.since)), 0) +
+        COALESCE((SELECT SUM(a.amount) FROM balance_adjustments a WHERE a.user_id = u.id AND (l.since IS NULL OR date(a.created_at) >= l.since)), 0) AS balance
+    FROM users u
+    LEFT JOIN latest l ON l.user_id = u.id
+)
+SELECT id, keycloak_id, email, username, realname, phone, alt_contact,
+    level_id, level_actual_amount, payments_id, date_joined, keys_granted,
+    keys_returned, state, is_council, is_staff, created_at, updated_at,
+    CAST(balance AS INTEGER) AS balance,
+    CAST(COUNT(*) OVER () AS INTEGER) AS total
+FROM listed
+WHERE (sqlc.arg(state) = '' OR state = sqlc.arg(state))
+  AND (sqlc.arg(search) = ''
+       OR email LIKE '%' || sqlc.arg(search) || '%'
+       OR realname LIKE '%' || sqlc.arg(search) || '%'
+       OR username LIKE '%' || sqlc.arg(search) || '%'
+       OR payments_id = sqlc.arg(search))
+  AND (sqlc.arg(linked) = ''
+       OR (sqlc.arg(linked) = 'linked' AND keycloak_id IS NOT NULL AND keycloak_id != '')
+       OR (sqlc.arg(linked) = 'not_linked' AND (keycloak_id IS NULL OR keycloak_id = '')))
+  AND (sqlc.arg(keycloak_ids) IS NULL OR keycloak_id IN (SELECT value FROM json_each(sqlc.arg(keycloak_ids))))
+  AND (sqlc.arg(balance) = ''
+       OR (sqlc.arg(balance) = 'positive' AND balance >= 0)
+       OR (sqlc.arg(balance) = 'negative' AND balance < 0))
+ORDER BY
+    CASE WHEN sqlc.arg(sort) = 'id_asc' THEN id END ASC,
+    CASE WHEN sqlc.arg(sort) = 'balance_asc' THEN balance END ASC,
+    CASE WHEN sqlc.arg(sort) = 'balance_desc' THEN balance END DESC,
+    CASE WHEN sqlc.arg(sort) = 'name' THEN COALESCE(NULLIF(realname, ''), email) END ASC,
+    id DESC
+LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);
+
+-- name: ListLinkedKeycloakIDs :many
+SELECT keycloak_id FROM users WHERE keycloak_id IS NOT NULL AND keycloak_id != '';
+
+-- name: ListUsersWithoutKeycloakID :many
+-- Imported members that never logged in (candidates for Keycloak account provisioning),
+-- anonymized former members are left out
+SELECT * FROM users
+WHERE keycloak_id IS NULL
+  AND id NOT IN (SELECT user_id FROM user_anonymizations)
+ORDER BY id;
+
 -- name: CreateUser :one
 INSERT INTO users (
     keycloak_id, email, username, realname, phone, alt_contact,
@@ -50,6 +127,15 @@ UPDATE users SET
 WHERE id = ?
 RETURNING *;
 
+-- name: UpdateUserPaymentsVS :execrows
+-- The member's payments carrying the old VS take the new one after a VS change,
+-- balances only count payments matching the member's VS
+UPDATE payments SET identification = sqlc.arg(new_payments_id)
+WHERE user_id = sqlc.arg(user_id) AND identification = sqlc.arg(old_payments_id);
+
+-- name: UpdateUserState :exec
+UPDATE users SET state = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?;
+
 -- name: UpdateUserProfile :one
 UPDATE users SET
     realname = ?,
@@ -73,6 +159,16 @@ UPDATE users SET
 WHERE id = ?
 RETURNING *;
 
+-- Overwrites the fields mirrored from Keycloak (admin "sync from Keycloak")
+-- name: UpdateUserIdentity :one
+UPDATE users SET
+    email = ?,
+    username = ?,
+    realname = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+RETURNING *;
+
 -- name: GetLevel :one
 SELECT * FROM levels WHERE id = ? LIMIT 1;
 
@@ -95,23 +191,49 @@ UPDATE levels SET
 WHERE id = ?
 RETURNING *;
 
+-- name: SetLevelBTCPay :exec
+UPDATE levels SET btcpay = ? WHERE id = ?;
+
 -- name: GetPayment :one
 SELECT * FROM payments WHERE id = ? LIMIT 1;
 
 -- name: ListPaymentsByUser :many
 SELECT * FROM payments WHERE user_id = ? ORDER BY date DESC;
 
+-- name: ListPaymentsByUserPage :many
+SELECT * FROM payments WHERE user_id = ? ORDER BY date DESC, id DESC LIMIT ? OFFSET ?;
+
+-- name: CountPaymentsByUser :one
+SELECT COUNT(*) FROM payments WHERE user_id = ?;
+
 -- name: ListMembershipPaymentsByUser :many
--- Only payments that match the user's membership VS (payments_id)
+-- Only payments that match the user's membership VS (payments_id) and are not
+-- donations; split payments count through ListPaymentSplitsByUser instead
 SELECT p.*
 FROM payments p
 JOIN users u ON p.user_id = u.id
 WHERE p.user_id = ?
 AND p.identification = u.payments_id
+AND p.classification != 'donation'
+AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
 ORDER BY p.date DESC;
 
 -- name: ListUnassignedPayments :many
-SELECT * FROM payments WHERE user_id IS NULL AND dismissed_at IS NULL ORDER BY date DESC;
+-- Suspected duplicates wait for review in ListSuspectedPaymentDuplicates
+SELECT * FROM payments p
+WHERE p.user_id IS NULL AND p.dismissed_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+AND NOT EXISTS (SELECT 1 FROM payment_duplicates d WHERE d.payment_id = p.id AND d.state != 'ignored')
+ORDER BY p.date DESC;
+
+-- name: GetUsersByRemoteAccount :many
+-- Members who paid from the account before, most payments first
+SELECT u.id, u.email, u.payments_id, COUNT(p.id) AS payment_count
+FROM payments p
+JOIN users u ON u.id = p.user_id
+WHERE p.remote_account = ? AND p.reversal_of IS NULL
+GROUP BY u.id, u.email, u.payments_id
+ORDER BY payment_count DESC, u.id;
 
 -- name: ListDismissedPayments :many
 SELECT * FROM payments WHERE dismissed_at IS NOT NULL ORDER BY dismissed_at DESC;
@@ -170,41 +292,213 @@ UPDATE payments SET
 WHERE id = ?
 RETURNING *;
 
+-- name: ListReversalCandidates :many
+-- Incoming payments from the same account and amount that have not been reversed yet
+SELECT p.*
+FROM payments p
+WHERE p.remote_account = sqlc.arg(remote_account)
+AND p.amount = sqlc.arg(amount)
+AND p.date <= sqlc.arg(date)
+AND p.reversal_of IS NULL
+AND NOT EXISTS (SELECT 1 FROM payments r WHERE r.reversal_of = p.id)
+ORDER BY p.date DESC;
+
+-- name: ListPendingReversals :many
+SELECT * FROM payments WHERE reversal_review = TRUE AND dismissed_at IS NULL ORDER BY date DESC;
+
+-- name: FlagPaymentReversalReview :one
+UPDATE payments SET reversal_review = TRUE WHERE id = ? RETURNING *;
+
+-- name: LinkPaymentReversal :one
+-- Reversal inherits owner and VS from the original so balances net out
+UPDATE payments SET
+    reversal_of = ?,
+    reversal_review = FALSE,
+    user_id = ?,
+    project_id = ?,
+    identification = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: GetPaymentReversal :one
+-- The payment already linked as the reversal of a payment
+SELECT * FROM payments WHERE reversal_of = ? LIMIT 1;
+
+-- name: CreateEmailCampaign :one
+INSERT INTO email_campaigns (
+    name, subject, template_name, body, audience, created_by
+) VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetEmailCampaign :one
+SELECT * FROM email_campaigns WHERE id = ? LIMIT 1;
+
+-- name: ListEmailCampaigns :many
+SELECT * FROM email_campaigns ORDER BY created_at DESC;
+
+-- name: UpdateEmailCampaignStatus :one
+UPDATE email_campaigns SET
+    status = ?,
+    completed_at = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: AddEmailCampaignRecipients :execrows
+-- Snapshot recipients when the campaign is created, so later state changes don't affect it
+INSERT OR IGNORE INTO email_campaign_recipients (campaign_id, user_id, email)
+SELECT sqlc.arg(campaign_id), u.id, u.email
+FROM users u
+WHERE u.state = sqlc.arg(state);
+
+-- name: ListEmailCampaignRecipientsToSend :many
+SELECT * FROM email_campaign_recipients
+WHERE campaign_id = sqlc.arg(campaign_id)
+AND (status = 'pending' OR (status = 'failed' AND attempts < sqlc.arg(max_attempts)))
+ORDER BY id;
+
+-- name: MarkEmailCampaignRecipientSending :exec
+UPDATE email_campaign_recipients SET
+    status = 'sending',
+    attempts = attempts + 1
+WHERE id = ?;
+
+-- name: MarkEmailCampaignRecipientSent :exec
+UPDATE email_campaign_recipients SET
+    status = 'sent',
+    sent_at = CURRENT_TIMESTAMP,
+    last_error = NULL
+WHERE id = ?;
+
+-- name: MarkEmailCampaignRecipientFailed :exec
+UPDATE email_campaign_recipients SET
+    status = 'failed',
+    last_error = ?
+WHERE id = ?;
+
+-- name: RequeueInterruptedEmailCampaignRecipients :execrows
+-- Explicit admin decision to retry recipients interrupted mid-send (may double-send)
+UPDATE email_campaign_recipients SET status = 'pending' WHERE campaign_id = ? AND status = 'sending';
+
+-- name: GetEmailCampaignStats :many
+SELECT status, COUNT(*) AS count
+FROM email_campaign_recipients
+WHERE campaign_id = ?
+GROUP BY status;
+
 -- name: GetFee :one
 SELECT * FROM fees WHERE id = ? LIMIT 1;
 
 -- name: ListFeesByUser :many
 SELECT * FROM fees WHERE user_id = ? ORDER BY period_start DESC;
 
+-- name: ListFeesByUserPage :many
+SELECT * FROM fees WHERE user_id = ? ORDER BY period_start DESC, id DESC LIMIT ? OFFSET ?;
+
+-- name: CountFeesByUser :one
+SELECT COUNT(*) FROM fees WHERE user_id = ?;
+
 -- name: ListFeesByPeriod :many
 SELECT * FROM fees WHERE period_start = ? ORDER BY user_id;
 
 -- name: CreateFee :one
-INSERT INTO fees (user_id, level_id, period_start, amount)
-VALUES (?, ?, ?, ?)
+INSERT INTO fees (user_id, level_id, period_start, amount, months)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: CreateFeeIfMissing :one
+-- Insert a fee unless the member already has one for the period (idx_fees_user_period);
+-- returns no row (sql.ErrNoRows) on the conflict
+INSERT OR IGNORE INTO fees (user_id, level_id, period_start, amount, months)
+VALUES (?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: GetFeeByUserAndPeriod :one
 SELECT * FROM fees WHERE user_id = ? AND period_start = ? LIMIT 1;
 
+-- name: GetLastFeeByUser :one
+SELECT * FROM fees WHERE user_id = ? ORDER BY period_start DESC, id DESC LIMIT 1;
+
 -- name: ListAcceptedUsersForFees :many
-SELECT u.*, l.amount as level_amount
+SELECT u.*, l.amount as level_amount, COALESCE(s.billing_cycle, 'monthly') AS billing_cycle
 FROM users u
 JOIN levels l ON u.level_id = l.id
+LEFT JOIN user_payment_settings s ON s.user_id = u.id
 WHERE u.state = 'accepted'
 ORDER BY u.id;
 
+-- name: ListUserLedger :many
+-- Everything GetUserBalance counts, oldest first: payments and fee allocations of
+-- split payments, fees and charges (negative), balance adjustments with their sign
+SELECT 'payment' AS entry_type, p.id AS entry_id, p.date, p.amount, 0 AS months,
+    p.remote_account AS note
+FROM payments p
+JOIN users u ON p.user_id = u.id
+WHERE p.user_id = sqlc.arg(user_id)
+  AND p.identification = u.payments_id
+  AND p.classification != 'donation'
+  AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+UNION ALL
+SELECT 'split', s.payment_id, p.date, s.amount, 0, s.note
+FROM payment_splits s
+JOIN payments p ON p.id = s.payment_id
+WHERE s.user_id = sqlc.arg(user_id) AND s.classification = 'fee'
+UNION ALL
+SELECT 'fee', f.id, f.period_start, -f.amount, f.months, ''
+FROM fees f
+WHERE f.user_id = sqlc.arg(user_id)
+UNION ALL
+SELECT 'charge', c.id, c.date, -c.amount, 0, c.description
+FROM charges c
+WHERE c.user_id = sqlc.arg(user_id)
+UNION ALL
+SELECT 'adjustment', a.id, a.created_at, a.amount, 0, a.reason
+FROM balance_adjustments a
+WHERE a.user_id = sqlc.arg(user_id)
+ORDER BY 3, 1, 2;
+
 -- name: GetUserBalance :one
--- Calculate membership fee balance (only payments matching user's payments_id VS)
+-- Calculate membership fee balance (only payments matching user's payments_id VS,
+-- a split payment counts by the allocations to the user; donations do not count).
+-- One-off charges count like fees, balance adjustments are added with their sign.
 SELECT
     COALESCE((
-        SELECT SUM(CAST(p.amount AS REAL))
+        SELECT SUM(p.amount)
         FROM payments p
         JOIN users u ON p.user_id = u.id
         WHERE p.user_id = ?
         AND p.identification = u.payments_id
+        AND p.classification != 'donation'
+        AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
     ), 0) -
-    COALESCE((SELECT SUM(CAST(f.amount AS REAL)) FROM fees f WHERE f.user_id = ?), 0) as balance;
+    COALESCE((SELECT SUM(f.amount) FROM fees f WHERE f.user_id = ?), 0) +
+    COALESCE((SELECT SUM(s.amount) FROM payment_splits s WHERE s.user_id = ? AND s.classification = 'fee'), 0) -
+    COALESCE((SELECT SUM(c.amount) FROM charges c WHERE c.user_id = ?), 0) +
+    COALESCE((SELECT SUM(a.amount) FROM balance_adjustments a WHERE a.user_id = ?), 0) as balance;
+
+-- name: GetUserBalanceSince :one
+-- Change of the balance (as in GetUserBalance) by the entries dated on or after since;
+-- added to the latest balance snapshot
+SELECT
+    COALESCE((
+        SELECT SUM(p.amount)
+        FROM payments p
+        JOIN users u ON p.user_id = u.id
+        WHERE p.user_id = sqlc.arg(user_id)
+        AND p.identification = u.payments_id
+        AND p.classification != 'donation'
+        AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+        AND date(p.date) >= CAST(sqlc.arg(since) AS TEXT)
+    ), 0) -
+    COALESCE((SELECT SUM(f.amount) FROM fees f WHERE f.user_id = sqlc.arg(user_id) AND date(f.period_start) >= CAST(sqlc.arg(since) AS TEXT)), 0) +
+    COALESCE((
+        SELECT SUM(s.amount)
+        FROM payment_splits s
+        JOIN payments p ON p.id = s.payment_id
+        WHERE s.user_id = sqlc.arg(user_id) AND s.classification = 'fee'
+        AND date(p.date) >= CAST(sqlc.arg(since) AS TEXT)
+    ), 0) -
+    COALESCE((SELECT SUM(c.amount) FROM charges c WHERE c.user_id = sqlc.arg(user_id) AND date(c.date) >= CAST(sqlc.arg(since) AS TEXT)), 0) +
+    COALESCE((SELECT SUM(a.amount) FROM balance_adjustments a WHERE a.user_id = sqlc.arg(user_id) AND date(a.created_at) >= CAST(sqlc.arg(since) AS TEXT)), 0) as balance;
 
 -- name: CountUsersByState :many
 SELECT state, COUNT(*) as count FROM users GROUP BY state;
@@ -268,6 +562,30 @@ RETURNING *;
 -- name: DeleteProject :exec
 DELETE FROM projects WHERE id = ?;
 
+-- name: SetProjectPublic :exec
+UPDATE projects SET public = ? WHERE id = ?;
+
+-- name: SetProjectBTCPay :exec
+UPDATE projects SET btcpay = ? WHERE id = ?;
+
+-- name: SetProjectTarget :exec
+-- A changed goal is a new target, admins are notified again when it is reached
+UPDATE projects SET
+    target_reached_at = CASE WHEN goal = sqlc.arg(goal) THEN target_reached_at END,
+    goal = sqlc.arg(goal),
+    deadline = sqlc.arg(deadline)
+WHERE id = sqlc.arg(id);
+
+-- name: ListProjectsWithOpenTarget :many
+-- Projects with a goal not reached yet, checked after each bank sync
+SELECT * FROM projects
+WHERE goal > 0 AND target_reached_at IS NULL
+ORDER BY id;
+
+-- name: MarkProjectTargetReached :execrows
+UPDATE projects SET target_reached_at = CURRENT_TIMESTAMP
+WHERE id = ? AND target_reached_at IS NULL;
+
 -- name: GetProjectPayments :many
 -- Get all payments for a project:
 -- 1. Payments explicitly assigned to project (project_id set)
@@ -278,14 +596,56 @@ WHERE p.project_id = sqlc.arg(project_id)
 ORDER BY p.date DESC;
 
 -- name: GetProjectBalance :one
--- Sum all payments for a project (by project_id OR by any VS in project_vs)
-SELECT COALESCE(SUM(CAST(amount AS REAL)), 0) as total
+-- Net balance of a project: income (as in GetProjectIncome) minus the expenses
+-- tagged with the project
+SELECT CAST(
+    COALESCE((
+        SELECT SUM(amount) FROM (
+            SELECT DISTINCT p.id, p.amount FROM payments p
+            WHERE (p.project_id = sqlc.arg(project_id)
+               OR p.identification IN (SELECT pv.vs FROM project_vs pv WHERE pv.project_id = sqlc.arg(project_id)))
+            AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+            UNION ALL
+            SELECT s.payment_id, s.amount FROM payment_splits s WHERE s.project_id = sqlc.arg(project_id)
+        ) income
+    ), 0)
+    - COALESCE((SELECT SUM(e.amount) FROM expenses e WHERE e.project_id = sqlc.arg(project_id)), 0)
+AS INTEGER) as total;
+
+-- name: GetProjectIncome :one
+-- Sum all payments for a project (by project_id OR by any VS in project_vs);
+-- a split payment counts by the allocations to the project
+SELECT CAST(COALESCE(SUM(amount), 0) AS INTEGER) as total
 FROM (
     SELECT DISTINCT p.id, p.amount FROM payments p
-    WHERE p.project_id = sqlc.arg(project_id)
-       OR p.identification IN (SELECT pv.vs FROM project_vs pv WHERE pv.project_id = sqlc.arg(project_id))
+    WHERE (p.project_id = sqlc.arg(project_id)
+       OR p.identification IN (SELECT pv.vs FROM project_vs pv WHERE pv.project_id = sqlc.arg(project_id)))
+    AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+    UNION ALL
+    SELECT s.payment_id, s.amount FROM payment_splits s WHERE s.project_id = sqlc.arg(project_id)
 ) sub;
 
+-- name: ListProjectLedger :many
+-- Income (as in GetProjectIncome) and expenses of a project, expenses with
+-- negative amounts, oldest first
+SELECT 'payment' AS entry_type, p.id AS entry_id, p.date, p.amount,
+    p.remote_account AS counterparty, COALESCE(p.staff_comment, '') AS note
+FROM payments p
+WHERE (p.project_id = sqlc.arg(project_id)
+   OR p.identification IN (SELECT pv.vs FROM project_vs pv WHERE pv.project_id = sqlc.arg(project_id)))
+  AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+UNION ALL
+SELECT 'split', s.payment_id, p.date, s.amount, p.remote_account, s.note
+FROM payment_splits s
+JOIN payments p ON p.id = s.payment_id
+WHERE s.project_id = sqlc.arg(project_id)
+UNION ALL
+SELECT 'expense', e.id, e.date, -e.amount,
+    COALESCE(NULLIF(e.remote_name, ''), e.remote_account), e.message
+FROM expenses e
+WHERE e.project_id = sqlc.arg(project_id)
+ORDER BY 3, 2;
+
 -- ============================================================================
 -- PROJECT VS (Multiple VS identifiers per project)
 -- ============================================================================
@@ -303,3 +663,1430 @@ DELETE FROM project_vs WHERE project_id = ? AND vs = ?;
 
 -- name: GetProjectVSByVS :one
 SELECT * FROM project_vs WHERE vs = ? LIMIT 1;
+
+-- name: ListUserDashboardWidgets :many
+SELECT * FROM user_dashboard_widgets WHERE user_id = ?;
+
+-- name: SetUserDashboardWidget :exec
+INSERT INTO user_dashboard_widgets (user_id, widget, visible, updated_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(user_id, widget) DO UPDATE SET
+    visible = excluded.visible,
+    updated_at = excluded.updated_at;
+
+-- name: AcquireLock :execrows
+-- Takes the lock if it is free, expired or already ours (extends it); 0 rows = held by someone else
+INSERT INTO locks (name, holder, acquired_at, expires_at)
+VALUES (sqlc.arg(name), sqlc.arg(holder), CURRENT_TIMESTAMP, sqlc.arg(expires_at))
+ON CONFLICT(name) DO UPDATE SET
+    holder = excluded.holder,
+    acquired_at = CASE WHEN locks.holder = excluded.holder THEN locks.acquired_at ELSE excluded.acquired_at END,
+    expires_at = excluded.expires_at
+WHERE locks.holder = excluded.holder OR locks.expires_at < sqlc.arg(now);
+
+-- name: ReleaseLock :exec
+DELETE FROM locks WHERE name = ? AND holder = ?;
+
+-- ============================================================================
+-- INVOICES (proforma invoices for company-paid memberships, invoices for fees and charges)
+-- ============================================================================
+
+-- name: GetBillingDetails :one
+SELECT * FROM billing_details WHERE user_id = ?;
+
+-- name: UpsertBillingDetails :one
+INSERT INTO billing_details (user_id, company_name, company_id, vat_id, address, updated_at)
+VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(user_id) DO UPDATE SET
+    company_name = excluded.company_name,
+    company_id = excluded.company_id,
+    vat_id = excluded.vat_id,
+    address = excluded.address,
+    updated_at = excluded.updated_at
+RETURNING *;
+
+-- name: CreateInvoiceRequest :one
+INSERT INTO invoices (user_id, months, amount, company_name, company_id, vat_id, address, note)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: CreateItemInvoice :one
+-- Invoice for a fee or a charge, issued right away (the amount is given)
+INSERT INTO invoices (user_id, state, number, months, amount, company_name, company_id, vat_id, address, note, item, fee_id, charge_id, decided_by, issued_at, due_at)
+VALUES (?, 'approved', ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetInvoice :one
+SELECT * FROM invoices WHERE id = ?;
+
+-- name: GetInvoiceByNumber :one
+SELECT * FROM invoices WHERE number = ?;
+
+-- name: ListInvoicesByUser :many
+SELECT * FROM invoices WHERE user_id = ? ORDER BY id DESC;
+
+-- name: ListInvoicesWithUsers :many
+-- Admin queue: pending requests first, then newest
+SELECT i.*, u.email, u.realname
+FROM invoices i
+JOIN users u ON i.user_id = u.id
+ORDER BY CASE WHEN i.state = 'requested' THEN 0 ELSE 1 END, i.id DESC
+LIMIT ?;
+
+-- name: NextInvoiceNumber :one
+INSERT INTO invoice_sequences (year, last_number)
+VALUES (?, 1)
+ON CONFLICT(year) DO UPDATE SET last_number = last_number + 1
+RETURNING last_number;
+
+-- name: ApproveInvoice :execrows
+UPDATE invoices SET
+    state = 'approved',
+    number = ?,
+    decided_by = ?,
+    issued_at = ?,
+    due_at = ?
+WHERE id = ? AND state = 'requested';
+
+-- name: RejectInvoice :execrows
+UPDATE invoices SET
+    state = 'rejected',
+    admin_comment = ?,
+    decided_by = ?
+WHERE id = ? AND state = 'requested';
+
+-- name: MarkInvoicePaid :execrows
+UPDATE invoices SET
+    state = 'paid',
+    payment_id = ?,
+    paid_at = ?
+WHERE id = ? AND state = 'approved';
+
+-- name: UpsertAuthSession :exec
+INSERT INTO auth_sessions (sid, keycloak_id, id_token)
+VALUES (?, ?, ?)
+ON CONFLICT(sid) DO UPDATE SET
+    id_token = excluded.id_token,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: GetAuthSession :one
+SELECT * FROM auth_sessions WHERE sid = ?;
+
+-- name: RevokeAuthSession :execrows
+UPDATE auth_sessions SET revoked_at = CURRENT_TIMESTAMP
+WHERE sid = ? AND revoked_at IS NULL;
+
+-- name: RevokeAuthSessionsByKeycloakID :execrows
+UPDATE auth_sessions SET revoked_at = CURRENT_TIMESTAMP
+WHERE keycloak_id = ? AND revoked_at IS NULL;
+
+-- name: DeleteStaleAuthSessions :execrows
+-- Sessions untouched for 30 days are far past the cookie lifetime (7 days)
+DELETE FROM auth_sessions WHERE updated_at < datetime('now', '-30 days');
+
+-- ============================================================================
+-- REIMBURSEMENTS (member expenses paid out by bank transfer)
+-- ============================================================================
+
+-- name: CreateReimbursement :one
+INSERT INTO reimbursements (user_id, amount, description, account)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: CreateReimbursementReceipt :exec
+INSERT INTO reimbursement_receipts (reimbursement_id, filename, content_type, data)
+VALUES (?, ?, ?, ?);
+
+-- name: GetReimbursement :one
+SELECT * FROM reimbursements WHERE id = ?;
+
+-- name: ListReimbursementsByUser :many
+SELECT * FROM reimbursements WHERE user_id = ? ORDER BY id DESC;
+
+-- name: ListReimbursementsWithUsers :many
+-- Admin queue: pending requests first, then newest
+SELECT r.*, u.email, u.realname
+FROM reimbursements r
+JOIN users u ON r.user_id = u.id
+ORDER BY CASE WHEN r.state = 'submitted' THEN 0 ELSE 1 END, r.id DESC
+LIMIT ?;
+
+-- name: ListReimbursementReceipts :many
+-- Receipt metadata without the file content
+SELECT id, reimbursement_id, filename, content_type, created_at
+FROM reimbursement_receipts
+WHERE reimbursement_id = ?
+ORDER BY id;
+
+-- name: GetReimbursementReceipt :one
+SELECT * FROM reimbursement_receipts WHERE id = ? AND reimbursement_id = ?;
+
+-- name: ApproveReimbursement :execrows
+UPDATE reimbursements SET
+    state = 'approved',
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'submitted';
+
+-- name: RejectReimbursement :execrows
+UPDATE reimbursements SET
+    state = 'rejected',
+    admin_comment = ?,
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'submitted';
+
+-- name: ListApprovedReimbursements :many
+SELECT * FROM reimbursements WHERE state = 'approved' ORDER BY id;
+
+-- name: CreateReimbursementBatch :one
+INSERT INTO reimbursement_batches (created_by, total, xml)
+VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: GetReimbursementBatch :one
+SELECT * FROM reimbursement_batches WHERE id = ?;
+
+-- name: ListReimbursementBatches :many
+SELECT id, created_by, total, created_at FROM reimbursement_batches ORDER BY id DESC LIMIT ?;
+
+-- name: MarkReimbursementExported :execrows
+UPDATE reimbursements SET
+    state = 'exported',
+    batch_id = ?
+WHERE id = ? AND state = 'approved';
+
+-- name: MarkReimbursementPaid :execrows
+UPDATE reimbursements SET
+    state = 'paid',
+    payment_id = ?,
+    paid_at = ?
+WHERE id = ? AND state = 'exported';
+
+-- ============================================================================
+-- WEB SESSIONS (server-side session store)
+-- ============================================================================
+
+-- name: GetWebSession :one
+SELECT data FROM web_sessions WHERE id = sqlc.arg(id) AND expires_at > sqlc.arg(now);
+
+-- name: UpsertWebSession :exec
+INSERT INTO web_sessions (id, data, expires_at, updated_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(id) DO UPDATE SET
+    data = excluded.data,
+    expires_at = excluded.expires_at,
+    updated_at = excluded.updated_at;
+
+-- name: DeleteWebSession :exec
+DELETE FROM web_sessions WHERE id = ?;
+
+-- name: DeleteExpiredWebSessions :execrows
+DELETE FROM web_sessions WHERE expires_at <= ?;
+
+-- ============================================================================
+-- SUPPORT TICKETS
+-- ============================================================================
+
+-- name: CreateTicket :one
+INSERT INTO tickets (user_id, email, subject, source)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetTicket :one
+SELECT * FROM tickets WHERE id = ?;
+
+-- name: ListTicketsByUser :many
+SELECT * FROM tickets WHERE user_id = ? ORDER BY updated_at DESC, id DESC;
+
+-- name: ListTickets :many
+-- Admin inbox: open tickets first, then by last activity
+SELECT t.*, u.realname,
+    (SELECT COUNT(*) FROM ticket_messages m WHERE m.ticket_id = t.id) AS message_count
+FROM tickets t
+LEFT JOIN users u ON t.user_id = u.id
+ORDER BY CASE t.state WHEN 'open' THEN 0 WHEN 'answered' THEN 1 ELSE 2 END, t.updated_at DESC
+LIMIT ?;
+
+-- name: UpdateTicketState :execrows
+UPDATE tickets SET state = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?;
+
+-- name: CreateTicketMessage :one
+INSERT INTO ticket_messages (ticket_id, direction, author, body, message_id)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: ListTicketMessages :many
+SELECT * FROM ticket_messages WHERE ticket_id = ? ORDER BY id;
+
+-- name: TicketMessageExists :one
+SELECT EXISTS(SELECT 1 FROM ticket_messages WHERE message_id = ?);
+
+-- ============================================================================
+-- PLANNED FEE CHANGES
+-- ============================================================================
+
+-- name: CreateLevelPriceChange :one
+INSERT INTO level_price_changes (level_id, new_amount, effective_from, note, created_by)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: ListLevelPriceChanges :many
+SELECT c.*, l.name AS level_name, l.amount AS level_amount
+FROM level_price_changes c
+JOIN levels l ON c.level_id = l.id
+ORDER BY c.applied_at IS NOT NULL, c.effective_from DESC
+LIMIT ?;
+
+-- name: ListLevelPriceChangesToNotify :many
+-- Pending changes whose notice period has started (effective_from <= now + notice)
+SELECT c.*, l.name AS level_name, l.amount AS level_amount
+FROM level_price_changes c
+JOIN levels l ON c.level_id = l.id
+WHERE c.applied_at IS NULL AND c.notified_at IS NULL AND c.effective_from <= ?
+ORDER BY c.effective_from;
+
+-- name: ListDueLevelPriceChanges :many
+SELECT c.*, l.name AS level_name, l.amount AS level_amount
+FROM level_price_changes c
+JOIN levels l ON c.level_id = l.id
+WHERE c.applied_at IS NULL AND c.effective_from <= ?
+ORDER BY c.effective_from;
+
+-- name: DeleteLevelPriceChange :execrows
+DELETE FROM level_price_changes WHERE id = ? AND applied_at IS NULL;
+
+-- name: MarkLevelPriceChangeNotified :exec
+UPDATE level_price_changes SET notified_at = CURRENT_TIMESTAMP WHERE id = ?;
+
+-- name: MarkLevelPriceChangeApplied :exec
+UPDATE level_price_changes SET applied_at = CURRENT_TIMESTAMP WHERE id = ?;
+
+-- name: UpdateLevelAmount :exec
+UPDATE levels SET amount = ? WHERE id = ?;
+
+-- name: ListUsersByLevel :many
+SELECT * FROM users WHERE level_id = ? ORDER BY id;
+
+-- name: ListLevelPriceChangeNotices :many
+SELECT user_id FROM level_price_change_notices WHERE change_id = ?;
+
+-- name: CreateLevelPriceChangeNotice :exec
+INSERT OR IGNORE INTO level_price_change_notices (change_id, user_id) VALUES (?, ?);
+
+-- name: ListSuspensionExemptions :many
+SELECT * FROM suspension_exemptions ORDER BY user_id;
+
+-- name: GetSuspensionExemption :one
+SELECT * FROM suspension_exemptions WHERE user_id = ?;
+
+-- name: SetSuspensionExemption :exec
+INSERT INTO suspension_exemptions (user_id, reason, created_by)
+VALUES (?, ?, ?)
+ON CONFLICT(user_id) DO UPDATE SET
+    reason = excluded.reason,
+    created_by = excluded.created_by,
+    created_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteSuspensionExemption :execrows
+DELETE FROM suspension_exemptions WHERE user_id = ?;
+
+-- name: CreateCharge :one
+INSERT INTO charges (user_id, description, amount, category, date, created_by)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetCharge :one
+SELECT * FROM charges WHERE id = ?;
+
+-- name: ListChargesByUser :many
+SELECT * FROM charges WHERE user_id = ? ORDER BY date DESC, id DESC;
+
+-- name: DeleteCharge :exec
+DELETE FROM charges WHERE id = ?;
+
+-- name: CreateBalanceAdjustment :one
+INSERT INTO balance_adjustments (user_id, fee_id, amount, reason, created_by)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: ListBalanceAdjustmentsByUser :many
+SELECT * FROM balance_adjustments WHERE user_id = ? ORDER BY created_at DESC, id DESC;
+
+-- name: GetFeeAdjustmentTotal :one
+-- Sum of the adjustments of a fee (positive = credited to the member)
+SELECT CAST(COALESCE(SUM(amount), 0) AS INTEGER) as total FROM balance_adjustments WHERE fee_id = ?;
+
+-- name: CreateBalanceSnapshot :exec
+INSERT INTO balance_snapshots (user_id, month, balance)
+VALUES (?, ?, ?);
+
+-- name: DeleteBalanceSnapshotsByUser :exec
+DELETE FROM balance_snapshots WHERE user_id = ?;
+
+-- name: ListLatestBalanceSnapshots :many
+-- Newest snapshot of each user
+SELECT * FROM balance_snapshots s
+WHERE s.month = (SELECT MAX(month) FROM balance_snapshots WHERE user_id = s.user_id);
+
+-- name: ListBalanceSnapshotTotals :many
+-- Per month: members in debt, their total debt and the total prepaid by others
+SELECT month,
+    CAST(SUM(CASE WHEN balance < 0 THEN 1 ELSE 0 END) AS INTEGER) AS debtors,
+    CAST(SUM(CASE WHEN balance < 0 THEN -balance ELSE 0 END) AS INTEGER) AS debt,
+    CAST(SUM(CASE WHEN balance > 0 THEN balance ELSE 0 END) AS INTEGER) AS credit
+FROM balance_snapshots
+GROUP BY month
+ORDER BY month;
+
+-- name: CreatePaymentPlan :one
+INSERT INTO payment_plans (user_id, total_debt, installment, start_date, note, created_by)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetActivePaymentPlan :one
+SELECT * FROM payment_plans
+WHERE user_id = ? AND ended_at IS NULL
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: ListActivePaymentPlans :many
+SELECT * FROM payment_plans WHERE ended_at IS NULL ORDER BY user_id, id;
+
+-- name: EndPaymentPlans :exec
+-- Ends the member's active plan (completed, replaced or cancelled)
+UPDATE payment_plans SET ended_at = CURRENT_TIMESTAMP
+WHERE user_id = ? AND ended_at IS NULL;
+
+-- name: ListDebtThresholds :many
+SELECT * FROM debt_thresholds ORDER BY step;
+
+-- name: SetDebtThreshold :exec
+INSERT INTO debt_thresholds (step, months, updated_by)
+VALUES (?, ?, ?)
+ON CONFLICT(step) DO UPDATE SET
+    months = excluded.months,
+    updated_by = excluded.updated_by,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteDebtThresholds :exec
+DELETE FROM debt_thresholds;
+
+-- ============================================================================
+-- MEMBER LEVEL CHANGES
+-- ============================================================================
+
+-- name: CreateLevelChangeRequest :one
+INSERT INTO level_change_requests (user_id, level_id, effective_from, note)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetLevelChangeRequest :one
+SELECT * FROM level_change_requests WHERE id = ?;
+
+-- name: GetPendingLevelChangeRequest :one
+SELECT * FROM level_change_requests WHERE user_id = ? AND state = 'requested' LIMIT 1;
+
+-- name: ListLevelChangeRequestsByUser :many
+SELECT r.*, l.name AS level_name, l.amount AS level_amount
+FROM level_change_requests r
+JOIN levels l ON r.level_id = l.id
+WHERE r.user_id = ?
+ORDER BY r.created_at DESC, r.id DESC;
+
+-- name: ListPendingLevelChangeRequests :many
+SELECT r.*, l.name AS level_name, l.amount AS level_amount,
+    u.email, u.realname, u.username, cl.name AS current_level_name
+FROM level_change_requests r
+JOIN levels l ON r.level_id = l.id
+JOIN users u ON r.user_id = u.id
+JOIN levels cl ON u.level_id = cl.id
+WHERE r.state = 'requested'
+ORDER BY r.created_at, r.id;
+
+-- name: ApproveLevelChangeRequest :one
+UPDATE level_change_requests SET
+    state = 'approved',
+    effective_from = ?,
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'requested'
+RETURNING *;
+
+-- name: RejectLevelChangeRequest :execrows
+UPDATE level_change_requests SET
+    state = 'rejected',
+    admin_comment = ?,
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'requested';
+
+-- name: CreateLevelHistory :one
+INSERT INTO level_history (user_id, level_id, effective_from, request_id, created_by)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: ListLevelHistoryByUser :many
+SELECT h.*, l.name AS level_name, l.amount AS level_amount
+FROM level_history h
+JOIN levels l ON h.level_id = l.id
+WHERE h.user_id = ?
+ORDER BY h.effective_from DESC, h.id DESC;
+
+-- name: GetScheduledLevelChange :one
+-- Latest approved change not applied yet that is effective at the given period start
+SELECT * FROM level_history
+WHERE user_id = ? AND applied_at IS NULL AND effective_from <= ?
+ORDER BY effective_from DESC, id DESC
+LIMIT 1;
+
+-- name: ListDueLevelChanges :many
+SELECT * FROM level_history
+WHERE applied_at IS NULL AND effective_from <= ?
+ORDER BY user_id, effective_from, id;
+
+-- name: MarkLevelHistoryApplied :exec
+UPDATE level_history SET applied_at = CURRENT_TIMESTAMP WHERE id = ?;
+
+-- name: UpdateUserLevel :exec
+-- The own amount starts at the new level amount (members paying more set it again)
+UPDATE users SET
+    level_id = ?,
+    level_actual_amount = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: ListUserNotificationPreferences :many
+SELECT * FROM user_notification_preferences WHERE user_id = ?;
+
+-- name: SetUserNotificationPreference :exec
+INSERT INTO user_notification_preferences (user_id, notification, enabled, updated_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(user_id, notification) DO UPDATE SET
+    enabled = excluded.enabled,
+    updated_at = excluded.updated_at;
+
+-- name: GetUserQRFormat :one
+SELECT qr_format FROM user_payment_settings WHERE user_id = ?;
+
+-- name: SetUserQRFormat :exec
+INSERT INTO user_payment_settings (user_id, qr_format, updated_at)
+VALUES (?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(user_id) DO UPDATE SET
+    qr_format = excluded.qr_format,
+    updated_at = excluded.updated_at;
+
+-- name: GetUserBillingCycle :one
+SELECT billing_cycle FROM user_payment_settings WHERE user_id = ?;
+
+-- name: SetUserBillingCycle :exec
+INSERT INTO user_payment_settings (user_id, billing_cycle, updated_at)
+VALUES (?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(user_id) DO UPDATE SET
+    billing_cycle = excluded.billing_cycle,
+    updated_at = excluded.updated_at;
+
+-- name: CreateMembershipPause :one
+INSERT INTO membership_pauses (user_id, start_date, end_date, reason, created_by)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetOpenMembershipPause :one
+-- The member's pause that has not been cleared or expired yet (at most one)
+SELECT * FROM membership_pauses WHERE user_id = ? AND ended_at IS NULL ORDER BY id DESC LIMIT 1;
+
+-- name: ListOpenMembershipPauses :many
+SELECT * FROM membership_pauses WHERE ended_at IS NULL ORDER BY user_id, id;
+
+-- name: EndMembershipPauses :exec
+-- Ends the member's open pause (cleared by an admin or replaced by a new one)
+UPDATE membership_pauses SET ended_at = CURRENT_TIMESTAMP WHERE user_id = ? AND ended_at IS NULL;
+
+-- name: EndMembershipPause :exec
+UPDATE membership_pauses SET ended_at = CURRENT_TIMESTAMP WHERE id = ? AND ended_at IS NULL;
+
+-- name: ListNotificationOptOuts :many
+SELECT user_id FROM user_notification_preferences WHERE notification = ? AND enabled = FALSE;
+
+-- name: ListUserPaymentCounts :many
+-- Positive membership-side payments per member, with how many arrived since the given date
+SELECT user_id, COUNT(*) AS total, COUNT(CASE WHEN date >= sqlc.arg(since) THEN 1 END) AS recent
+FROM payments
+WHERE user_id IS NOT NULL AND project_id IS NULL AND dismissed_at IS NULL AND amount > 0
+GROUP BY user_id;
+
+-- name: ListMemberMilestones :many
+SELECT * FROM member_milestones;
+
+-- name: CreateMemberMilestone :exec
+INSERT OR IGNORE INTO member_milestones (user_id, milestone, emailed, announced) VALUES (?, ?, ?, ?);
+
+-- name: ListPaymentStatementsByYear :many
+-- Members whose payment statement for the year was already emailed
+SELECT * FROM payment_statements WHERE year = ?;
+
+-- name: CreatePaymentStatement :exec
+INSERT OR IGNORE INTO payment_statements (user_id, year) VALUES (?, ?);
+
+-- name: ListMembershipPaymentsSince :many
+-- Incoming payments with the member's own VS (the ones counted in the balance)
+-- of accepted members, for recognizing standing orders
+SELECT p.user_id, p.date, p.amount
+FROM payments p
+JOIN users u ON u.id = p.user_id
+WHERE u.state = 'accepted'
+  AND p.identification = u.payments_id AND p.classification != 'donation'
+  AND p.project_id IS NULL AND p.dismissed_at IS NULL AND p.amount > 0
+  AND p.date >= ?
+ORDER BY p.user_id, p.date;
+
+-- name: ListPaymentReminders :many
+SELECT * FROM payment_reminders;
+
+-- name: CreatePaymentReminder :exec
+INSERT OR IGNORE INTO payment_reminders (user_id, due_date, amount) VALUES (?, ?, ?);
+
+-- ============================================================================
+-- API TOKENS (personal tokens for scripts)
+-- ============================================================================
+
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetAPITokenByHash :one
+SELECT * FROM api_tokens WHERE token_hash = ? AND revoked_at IS NULL;
+
+-- name: ListAPITokensByUser :many
+SELECT * FROM api_tokens WHERE user_id = ? AND revoked_at IS NULL ORDER BY created_at DESC, id DESC;
+
+-- name: RevokeAPIToken :execrows
+UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ? AND revoked_at IS NULL;
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP WHERE id = ?;
+
+-- ============================================================================
+-- PROJECT WALLS (contributor thank-you wall)
+-- ============================================================================
+
+-- name: GetProjectWallEntry :one
+SELECT * FROM project_wall_entries WHERE project_id = ? AND user_id = ?;
+
+-- name: UpsertProjectWallEntry :exec
+-- A changed nickname has to be approved again
+INSERT INTO project_wall_entries (project_id, user_id, nickname)
+VALUES (?, ?, ?)
+ON CONFLICT(project_id, user_id) DO UPDATE SET
+    state = CASE WHEN project_wall_entries.nickname = excluded.nickname THEN project_wall_entries.state ELSE 'pending' END,
+    nickname = excluded.nickname,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteProjectWallEntry :exec
+DELETE FROM project_wall_entries WHERE project_id = ? AND user_id = ?;
+
+-- name: ModerateProjectWallEntry :execrows
+UPDATE project_wall_entries SET
+    state = ?,
+    nickname = ?,
+    moderated_by = ?,
+    moderated_at = CURRENT_TIMESTAMP
+WHERE project_id = ? AND user_id = ?;
+
+-- name: ListProjectWall :many
+-- Wall entries with the member's contributions: project payments (by project_id or VS,
+-- like GetProjectIncome) assigned to the member or sent from one of their bank accounts
+-- known from membership payments (total in CZK)
+SELECT w.user_id, w.nickname, w.state, u.email,
+    CAST(COALESCE(SUM(p.amount), 0) AS REAL) / 100 AS total
+FROM project_wall_entries w
+JOIN users u ON u.id = w.user_id
+LEFT JOIN payments p ON (
+        p.project_id = w.project_id
+        OR p.identification IN (SELECT pv.vs FROM project_vs pv WHERE pv.project_id = w.project_id)
+    ) AND (
+        p.user_id = w.user_id
+        OR p.remote_account IN (SELECT m.remote_account FROM payments m WHERE m.user_id = w.user_id AND m.remote_account != '')
+    )
+WHERE w.project_id = ?
+GROUP BY w.user_id, w.nickname, w.state, u.email
+ORDER BY total DESC, w.nickname;
+
+-- name: UpsertProjectMember :exec
+-- Joining again only updates the role
+INSERT INTO project_members (project_id, user_id, role)
+VALUES (?, ?, ?)
+ON CONFLICT(project_id, user_id) DO UPDATE SET role = excluded.role;
+
+-- name: DeleteProjectMember :exec
+DELETE FROM project_members WHERE project_id = ? AND user_id = ?;
+
+-- name: ListMemberProjects :many
+-- All projects with the member's involvement, joined ones first
+SELECT p.id, p.name, p.description, p.public,
+    CAST(m.user_id IS NOT NULL AS BOOLEAN) AS joined, COALESCE(m.role, '') AS role
+FROM projects p
+LEFT JOIN project_members m ON m.project_id = p.id AND m.user_id = ?
+ORDER BY joined DESC, p.name;
+
+-- name: ListProjectMembers :many
+SELECT m.user_id, u.email, u.realname, u.username, u.state, m.role, m.created_at
+FROM project_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.project_id = ?
+ORDER BY m.created_at, m.user_id;
+
+-- name: CreateAdminAuditLog :exec
+INSERT INTO admin_audit_log (actor_user_id, actor_email, api_token_id, method, path, target, payload, status, error)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListAdminAuditLog :many
+SELECT * FROM admin_audit_log
+WHERE (? = '' OR path LIKE ?)
+  AND (? = 0 OR actor_user_id = ?)
+  AND (? = 0 OR status >= 400)
+ORDER BY created_at DESC, id DESC LIMIT ?;
+
+-- ============================================================================
+-- PAYMENT MATCH RULES (wrong or missing VS)
+-- ============================================================================
+
+-- name: ListActivePaymentMatchRules :many
+SELECT * FROM payment_match_rules WHERE active = TRUE ORDER BY priority, id;
+
+-- name: ListPaymentMatchRules :many
+SELECT r.id, r.name, r.priority, r.remote_account, r.message_pattern, r.specific_symbol,
+    r.amount_min, r.amount_max, r.user_id, r.project_id, r.active, r.match_count,
+    r.last_matched_at, r.created_by, r.created_at,
+    COALESCE(u.email, '') AS user_email, COALESCE(p.name, '') AS project_name
+FROM payment_match_rules r
+LEFT JOIN users u ON u.id = r.user_id
+LEFT JOIN projects p ON p.id = r.project_id
+ORDER BY r.priority, r.id;
+
+-- name: GetPaymentMatchRule :one
+SELECT * FROM payment_match_rules WHERE id = ?;
+
+-- name: GetAccountPaymentMatchRule :one
+-- Rule with only the sender account condition (remembered account)
+SELECT * FROM payment_match_rules
+WHERE remote_account = ? AND message_pattern = '' AND specific_symbol = ''
+  AND amount_min IS NULL AND amount_max IS NULL
+ORDER BY id LIMIT 1;
+
+-- name: CreatePaymentMatchRule :one
+INSERT INTO payment_match_rules (
+    name, priority, remote_account, message_pattern, specific_symbol,
+    amount_min, amount_max, user_id, project_id, active, created_by
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: UpdatePaymentMatchRule :execrows
+UPDATE payment_match_rules SET
+    name = ?,
+    priority = ?,
+    remote_account = ?,
+    message_pattern = ?,
+    specific_symbol = ?,
+    amount_min = ?,
+    amount_max = ?,
+    user_id = ?,
+    project_id = ?,
+    active = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: DeletePaymentMatchRule :execrows
+DELETE FROM payment_match_rules WHERE id = ?;
+
+-- name: RecordPaymentMatchRuleHit :exec
+UPDATE payment_match_rules SET
+    match_count = match_count + 1,
+    last_matched_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- ============================================================================
+-- PAYMENT SUGGESTIONS (match by sender account history)
+-- ============================================================================
+
+-- name: UpsertPaymentSuggestion :exec
+-- A rejected suggestion is not proposed again
+INSERT INTO payment_suggestions (payment_id, user_id, payment_count)
+VALUES (?, ?, ?)
+ON CONFLICT(payment_id) DO UPDATE SET
+    user_id = excluded.user_id,
+    payment_count = excluded.payment_count
+WHERE payment_suggestions.state = 'suggested';
+
+-- name: ListPaymentSuggestions :many
+-- Open suggestions of payments that are still unassigned
+SELECT s.payment_id, s.user_id, s.payment_count, u.email
+FROM payment_suggestions s
+JOIN users u ON u.id = s.user_id
+JOIN payments p ON p.id = s.payment_id
+WHERE s.state = 'suggested' AND p.user_id IS NULL AND p.project_id IS NULL AND p.dismissed_at IS NULL;
+
+-- name: RejectPaymentSuggestion :execrows
+UPDATE payment_suggestions SET
+    state = 'rejected',
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE payment_id = ? AND state = 'suggested';
+
+-- ============================================================================
+-- PAYMENT SPLITS (one payment divided among members / projects)
+-- ============================================================================
+
+-- name: ListPaymentSplits :many
+SELECT s.id, s.payment_id, s.user_id, s.project_id, s.amount, s.note, s.created_by, s.created_at,
+    s.classification, COALESCE(u.email, '') AS user_email, COALESCE(pr.name, '') AS project_name
+FROM payment_splits s
+LEFT JOIN users u ON u.id = s.user_id
+LEFT JOIN projects pr ON pr.id = s.project_id
+WHERE s.payment_id = ?
+ORDER BY s.id;
+
+-- name: ListPaymentSplitsByUser :many
+-- Fee allocations to the member with the date of the split payment
+SELECT s.id, s.payment_id, s.amount, s.note, p.date
+FROM payment_splits s
+JOIN payments p ON p.id = s.payment_id
+WHERE s.user_id = ? AND s.classification = 'fee'
+ORDER BY p.date DESC;
+
+-- name: CreatePaymentSplit :one
+INSERT INTO payment_splits (payment_id, user_id, project_id, amount, note, created_by, classification)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: DeletePaymentSplits :execrows
+DELETE FROM payment_splits WHERE payment_id = ?;
+
+-- name: SetPaymentClassification :exec
+UPDATE payments SET classification = ? WHERE id = ?;
+
+-- name: ListDonations :many
+-- Donations received in the period: whole payments classified as donations (explicitly,
+-- or by their project assignment or project VS) and donation parts of split payments
+SELECT p.id AS payment_id, p.date, p.amount, p.user_id,
+    COALESCE(p.project_id, (SELECT pv.project_id FROM project_vs pv WHERE pv.vs = p.identification LIMIT 1)) AS project_id,
+    p.remote_account, p.identification, CAST(0 AS BOOLEAN) AS split
+FROM payments p
+WHERE p.date >= sqlc.arg(date_from) AND p.date < sqlc.arg(date_to)
+  AND p.amount > 0 AND p.dismissed_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+  AND NOT EXISTS (SELECT 1 FROM payment_duplicates d WHERE d.payment_id = p.id AND d.state != 'ignored')
+  AND (p.classification = 'donation' OR (p.classification = '' AND (
+      p.project_id IS NOT NULL OR p.identification IN (SELECT pv.vs FROM project_vs pv))))
+UNION ALL
+SELECT s.payment_id, p.date, s.amount, s.user_id, s.project_id,
+    p.remote_account, p.identification, CAST(1 AS BOOLEAN) AS split
+FROM payment_splits s
+JOIN payments p ON p.id = s.payment_id
+WHERE p.date >= sqlc.arg(date_from) AND p.date < sqlc.arg(date_to)
+  AND p.dismissed_at IS NULL AND s.classification = 'donation'
+ORDER BY 2 DESC, 1 DESC;
+
+-- name: ListDonationReceiptsByYear :many
+-- Donors whose donation receipt for the year was already emailed
+SELECT * FROM donation_receipts WHERE year = ?;
+
+-- name: CreateDonationReceipt :exec
+INSERT OR IGNORE INTO donation_receipts (user_id, year) VALUES (?, ?);
+
+-- ============================================================================
+-- EXPENSES (outgoing bank transactions)
+-- ============================================================================
+
+-- name: CreateExpense :execrows
+-- An already imported transaction is left as is (keeps admin tags)
+INSERT INTO expenses (
+    kind, kind_id, date, amount, remote_account, remote_name,
+    identification, message, category, staff_comment, raw_data
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(kind, kind_id) DO NOTHING;
+
+-- name: GetExpense :one
+SELECT * FROM expenses WHERE id = ? LIMIT 1;
+
+-- name: ListExpenses :many
+SELECT e.id, e.kind, e.kind_id, e.date, e.amount, e.remote_account, e.remote_name,
+    e.identification, e.message, e.category, e.project_id, e.staff_comment,
+    COALESCE(p.name, '') AS project_name
+FROM expenses e
+LEFT JOIN projects p ON p.id = e.project_id
+WHERE e.date >= sqlc.arg(date_from) AND e.date < sqlc.arg(date_to)
+  AND (sqlc.arg(category) = '' OR e.category = sqlc.arg(category))
+  AND (sqlc.arg(project_id) = 0 OR e.project_id = sqlc.arg(project_id))
+ORDER BY e.date DESC, e.id DESC;
+
+-- name: ListExpenseCategories :many
+SELECT DISTINCT category FROM expenses WHERE category != '' ORDER BY category;
+
+-- name: UpdateExpenseTags :execrows
+UPDATE expenses SET
+    category = ?,
+    project_id = ?,
+    staff_comment = ?
+WHERE id = ?;
+
+-- ============================================================================
+-- PAYMENT DUPLICATES (same transaction imported from two sources)
+-- ============================================================================
+
+-- name: SetPaymentContentHash :exec
+UPDATE payments SET content_hash = ? WHERE id = ?;
+
+-- name: ListPaymentsWithoutContentHash :many
+SELECT id, date, amount, remote_account, identification
+FROM payments
+WHERE content_hash = '';
+
+-- name: FindDuplicatePayment :one
+-- The oldest payment with the same content from another source that is not a
+-- duplicate itself and has no duplicate from this source yet
+SELECT p.* FROM payments p
+WHERE p.content_hash = sqlc.arg(content_hash) AND p.kind != sqlc.arg(kind)
+AND NOT EXISTS (SELECT 1 FROM payment_duplicates d WHERE d.payment_id = p.id AND d.state != 'ignored')
+AND NOT EXISTS (
+    SELECT 1 FROM payment_duplicates d
+    JOIN payments dp ON dp.id = d.payment_id
+    WHERE d.duplicate_of = p.id AND d.state != 'ignored' AND dp.kind = sqlc.arg(kind)
+)
+ORDER BY p.id
+LIMIT 1;
+
+-- name: CreatePaymentDuplicate :exec
+INSERT INTO payment_duplicates (payment_id, duplicate_of)
+VALUES (?, ?)
+ON CONFLICT(payment_id) DO NOTHING;
+
+-- name: GetPaymentDuplicate :one
+SELECT * FROM payment_duplicates WHERE payment_id = ? LIMIT 1;
+
+-- name: ListSuspectedPaymentDuplicates :many
+SELECT * FROM payment_duplicates
+WHERE state = 'suspected'
+ORDER BY created_at DESC;
+
+-- name: DecidePaymentDuplicate :execrows
+UPDATE payment_duplicates SET
+    state = ?,
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE payment_id = ? AND state = 'suspected';
+
+-- ============================================================================
+-- FAKTUROID (invoices of fees of company-billed members)
+-- ============================================================================
+
+-- name: EnableBillingFakturoid :execrows
+-- Fees created from now on are invoiced in Fakturoid
+UPDATE billing_details SET fakturoid = TRUE, fakturoid_since = CURRENT_TIMESTAMP
+WHERE user_id = ? AND NOT fakturoid;
+
+-- name: DisableBillingFakturoid :execrows
+UPDATE billing_details SET fakturoid = FALSE, fakturoid_since = NULL
+WHERE user_id = ? AND fakturoid;
+
+-- name: SetBillingFakturoidSubject :exec
+UPDATE billing_details SET fakturoid_subject_id = ? WHERE user_id = ?;
+
+-- name: ListFeesToInvoiceInFakturoid :many
+-- Fees of company-billed members created since the flag was set that have neither
+-- a Fakturoid invoice nor an invoice issued in the portal
+SELECT f.id AS fee_id, f.user_id, f.period_start, f.amount, f.months,
+    u.email, u.realname, u.payments_id,
+    b.company_name, b.company_id, b.vat_id, b.address, b.fakturoid_subject_id
+FROM fees f
+JOIN billing_details b ON b.user_id = f.user_id
+JOIN users u ON u.id = f.user_id
+WHERE b.fakturoid AND f.created_at >= b.fakturoid_since AND f.amount > 0
+  AND NOT EXISTS (SELECT 1 FROM fakturoid_invoices fi WHERE fi.fee_id = f.id)
+  AND NOT EXISTS (SELECT 1 FROM invoices i WHERE i.fee_id = f.id)
+ORDER BY f.id;
+
+-- name: CreateFakturoidInvoice :exec
+INSERT INTO fakturoid_invoices (fee_id, user_id, fakturoid_id, number, status, public_url, paid_on)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetFakturoidInvoice :one
+SELECT * FROM fakturoid_invoices WHERE fee_id = ?;
+
+-- name: ListFakturoidInvoicesByUser :many
+SELECT * FROM fakturoid_invoices WHERE user_id = ? ORDER BY fee_id DESC;
+
+-- name: ListOpenFakturoidInvoices :many
+-- Invoices whose status can still change
+SELECT * FROM fakturoid_invoices
+WHERE status NOT IN ('paid', 'cancelled', 'uncollectible')
+ORDER BY fee_id;
+
+-- name: UpdateFakturoidInvoiceStatus :exec
+UPDATE fakturoid_invoices SET status = ?, paid_on = ?, synced_at = CURRENT_TIMESTAMP
+WHERE fee_id = ?;
+
+-- ============================================================================
+-- ACCOUNTING EXPORT
+-- ============================================================================
+
+-- name: ListAccountingEntries :many
+-- Payments received (without duplicate imports), fees and one-off charges of the
+-- period for the accounting export, oldest first. The category of a payment is
+-- split, donation, fee, unmatched or dismissed; of a fee its level name.
+-- Dates compare by their YYYY-MM-DD prefix (stored both as plain dates and timestamps).
+SELECT 'payment' AS entry_type, p.id AS entry_id, p.date, p.amount, p.identification AS vs,
+    p.user_id, COALESCE(u.email, '') AS email, COALESCE(u.realname, '') AS realname, p.remote_account,
+    CASE
+        WHEN p.dismissed_at IS NOT NULL THEN 'dismissed'
+        WHEN EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id) THEN 'split'
+        WHEN p.classification = 'donation' OR (p.classification = '' AND (
+            p.project_id IS NOT NULL OR p.identification IN (SELECT pv.vs FROM project_vs pv))) THEN 'donation'
+        WHEN p.user_id IS NULL THEN 'unmatched'
+        ELSE 'fee'
+    END AS category,
+    0 AS months, COALESCE(pr.name, '') AS note
+FROM payments p
+LEFT JOIN users u ON u.id = p.user_id
+LEFT JOIN projects pr ON pr.id = p.project_id
+WHERE substr(p.date, 1, 10) >= CAST(sqlc.arg(date_from) AS TEXT) AND substr(p.date, 1, 10) < CAST(sqlc.arg(date_to) AS TEXT)
+  AND NOT EXISTS (SELECT 1 FROM payment_duplicates d WHERE d.payment_id = p.id AND d.state != 'ignored')
+UNION ALL
+SELECT 'fee', f.id, f.period_start, f.amount, COALESCE(u.payments_id, ''),
+    f.user_id, u.email, COALESCE(u.realname, ''), '', COALESCE(l.name, ''), f.months, ''
+FROM fees f
+JOIN users u ON u.id = f.user_id
+LEFT JOIN levels l ON l.id = f.level_id
+WHERE substr(f.period_start, 1, 10) >= CAST(sqlc.arg(date_from) AS TEXT) AND substr(f.period_start, 1, 10) < CAST(sqlc.arg(date_to) AS TEXT)
+UNION ALL
+SELECT 'charge', c.id, c.date, c.amount, COALESCE(u.payments_id, ''),
+    c.user_id, u.email, COALESCE(u.realname, ''), '', c.category, 0, c.description
+FROM charges c
+JOIN users u ON u.id = c.user_id
+WHERE substr(c.date, 1, 10) >= CAST(sqlc.arg(date_from) AS TEXT) AND substr(c.date, 1, 10) < CAST(sqlc.arg(date_to) AS TEXT)
+ORDER BY 3, 1, 2;
+
+-- ============================================================================
+-- MEMBERSHIP APPLICATIONS
+-- ============================================================================
+
+-- name: CreateApplication :one
+INSERT INTO applications (realname, email, motivation, level_id)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetApplication :one
+SELECT * FROM applications WHERE id = ?;
+
+-- name: GetPendingApplicationByEmail :one
+SELECT * FROM applications WHERE email = ? AND state = 'pending' LIMIT 1;
+
+-- name: ListApplications :many
+-- Pending applications first, then the decided ones, newest first; vouch_count
+-- counts accepted members only
+SELECT a.*, l.name AS level_name, l.amount AS level_amount,
+    (
+        SELECT COUNT(*) FROM application_vouches v JOIN users u ON u.id = v.user_id
+        WHERE v.application_id = a.id AND u.state = 'accepted'
+    ) AS vouch_count
+FROM applications a
+JOIN levels l ON a.level_id = l.id
+ORDER BY a.state = 'pending' DESC, a.created_at DESC, a.id DESC
+LIMIT ?;
+
+-- name: ApproveApplication :execrows
+UPDATE applications SET
+    state = 'approved',
+    user_id = ?,
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'pending';
+
+-- name: RejectApplication :execrows
+UPDATE applications SET
+    state = 'rejected',
+    admin_comment = ?,
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'pending';
+
+-- name: NextPaymentsID :one
+-- The next free numeric VS for a new member: one above the highest numeric VS of
+-- members and projects (at least 1001)
+SELECT CAST(MAX(vs) + 1 AS TEXT) AS payments_id FROM (
+    SELECT CAST(payments_id AS INTEGER) AS vs FROM users
+    WHERE payments_id != '' AND payments_id NOT GLOB '*[^0-9]*'
+    UNION ALL
+    SELECT CAST(vs AS INTEGER) FROM project_vs
+    WHERE vs != '' AND vs NOT GLOB '*[^0-9]*'
+    UNION ALL
+    SELECT 1000
+);
+
+-- name: ListPendingApplicationsForMember :many
+-- Pending applications with their vouch count and whether the member vouched
+SELECT a.id, a.realname, a.motivation, a.created_at, l.name AS level_name,
+    (
+        SELECT COUNT(*) FROM application_vouches v JOIN users u ON u.id = v.user_id
+        WHERE v.application_id = a.id AND u.state = 'accepted'
+    ) AS vouch_count,
+    CAST(EXISTS (
+        SELECT 1 FROM application_vouches v WHERE v.application_id = a.id AND v.user_id = sqlc.arg(user_id)
+    ) AS BOOLEAN) AS vouched
+FROM applications a
+JOIN levels l ON a.level_id = l.id
+WHERE a.state = 'pending'
+ORDER BY a.created_at, a.id;
+
+-- name: UpsertApplicationVouch :exec
+-- Vouching again only updates the note
+INSERT INTO application_vouches (application_id, user_id, note)
+VALUES (?, ?, ?)
+ON CONFLICT(application_id, user_id) DO UPDATE SET note = excluded.note;
+
+-- name: DeleteApplicationVouch :execrows
+DELETE FROM application_vouches WHERE application_id = ? AND user_id = ?;
+
+-- name: ListApplicationVouches :many
+-- Vouches of all pending applications with the vouching members (only vouches of
+-- accepted members count towards APPLICATION_VOUCHES)
+SELECT v.application_id, v.user_id, u.email, u.realname, u.username, u.state, v.note, v.created_at
+FROM application_vouches v
+JOIN applications a ON a.id = v.application_id
+JOIN users u ON u.id = v.user_id
+WHERE a.state = 'pending'
+ORDER BY v.application_id, v.created_at;
+
+-- name: CountApplicationVouches :one
+-- Vouches of members who are still accepted (suspended and former members do not count)
+SELECT COUNT(*) FROM application_vouches v
+JOIN users u ON u.id = v.user_id
+WHERE v.application_id = ? AND u.state = 'accepted';
+
+-- ============================================================================
+-- ONBOARDING
+-- ============================================================================
+
+-- name: ListUserTasks :many
+SELECT * FROM user_tasks WHERE user_id = ? ORDER BY completed_at;
+
+-- name: CompleteUserTask :exec
+-- Ticking a task off again keeps the original completion time
+INSERT INTO user_tasks (user_id, task, completed_by)
+VALUES (?, ?, ?)
+ON CONFLICT(user_id, task) DO NOTHING;
+
+-- name: ReopenUserTask :exec
+DELETE FROM user_tasks WHERE user_id = ? AND task = ?;
+
+-- ============================================================================
+-- MEMBERSHIP TERMINATION
+-- ============================================================================
+
+-- name: CreateMembershipTermination :one
+INSERT INTO membership_terminations (user_id, end_date, reason)
+VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: GetScheduledTermination :one
+SELECT * FROM membership_terminations WHERE user_id = ? AND state = 'scheduled' LIMIT 1;
+
+-- name: ListScheduledTerminations :many
+SELECT * FROM membership_terminations WHERE state = 'scheduled' ORDER BY end_date, id;
+
+-- name: CancelMembershipTermination :execrows
+UPDATE membership_terminations SET
+    state = 'cancelled',
+    cancelled_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE user_id = ? AND state = 'scheduled';
+
+-- name: CompleteMembershipTermination :execrows
+UPDATE membership_terminations SET
+    state = 'completed',
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'scheduled';
+
+-- ============================================================================
+-- ANONYMIZATION (GDPR)
+-- ============================================================================
+
+-- name: GetUserAnonymization :one
+SELECT * FROM user_anonymizations WHERE user_id = ?;
+
+-- name: CreateUserAnonymization :exec
+INSERT INTO user_anonymizations (user_id, anonymized_by, summary)
+VALUES (?, ?, ?);
+
+-- name: AnonymizeUser :exec
+-- The Keycloak link goes too, a login with the old account no longer finds the member
+UPDATE users SET
+    email = ?,
+    keycloak_id = NULL,
+    username = NULL,
+    realname = NULL,
+    phone = NULL,
+    alt_contact = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: ListUserBankAccounts :many
+-- Counter accounts the member paid from or had reimbursements paid to
+SELECT remote_account AS account FROM payments WHERE user_id = sqlc.arg(user_id) AND remote_account != ''
+UNION
+SELECT account FROM reimbursements WHERE user_id = sqlc.arg(user_id) AND account != '';
+
+-- name: AnonymizeUserPayments :execrows
+-- Amounts, dates and VS stay for the accounting
+UPDATE payments SET
+    remote_account = '',
+    raw_data = NULL
+WHERE user_id = ? AND (remote_account != '' OR raw_data IS NOT NULL);
+
+-- name: AnonymizeExpensesByAccount :execrows
+UPDATE expenses SET
+    remote_account = '',
+    remote_name = '',
+    raw_data = NULL
+WHERE remote_account = ?;
+
+-- name: AnonymizeUserReimbursements :execrows
+UPDATE reimbursements SET account = '' WHERE user_id = ? AND account != '';
+
+-- name: AnonymizeUserApplications :execrows
+UPDATE applications SET
+    realname = '',
+    email = sqlc.arg(anonymized_email),
+    motivation = ''
+WHERE user_id = sqlc.arg(user_id) OR lower(email) = lower(sqlc.arg(email));
+
+-- name: AnonymizeUserTickets :execrows
+UPDATE tickets SET email = sqlc.arg(anonymized_email)
+WHERE user_id = sqlc.arg(user_id) OR lower(email) = lower(sqlc.arg(email));
+
+-- name: AnonymizeTicketMessageAuthors :execrows
+UPDATE ticket_messages SET author = sqlc.arg(anonymized_email)
+WHERE lower(author) = lower(sqlc.arg(email));
+
+-- name: AnonymizeUserCampaignRecipients :execrows
+UPDATE email_campaign_recipients SET email = ? WHERE user_id = ?;
+
+-- name: AnonymizeLogEmail :execrows
+UPDATE system_logs SET
+    message = REPLACE(message, sqlc.arg(email), sqlc.arg(anonymized_email)),
+    metadata = REPLACE(metadata, sqlc.arg(email), sqlc.arg(anonymized_email))
+WHERE instr(message, sqlc.arg(email)) > 0 OR instr(metadata, sqlc.arg(email)) > 0;
+
+-- name: AnonymizeAuditLogEmail :execrows
+UPDATE admin_audit_log SET
+    actor_email = REPLACE(actor_email, sqlc.arg(email), sqlc.arg(anonymized_email)),
+    target = REPLACE(target, sqlc.arg(email), sqlc.arg(anonymized_email)),
+    payload = REPLACE(payload, sqlc.arg(email), sqlc.arg(anonymized_email))
+WHERE instr(actor_email, sqlc.arg(email)) > 0 OR instr(target, sqlc.arg(email)) > 0 OR instr(payload, sqlc.arg(email)) > 0;
+
+-- name: DeleteUserPaymentMatchRules :execrows
+DELETE FROM payment_match_rules WHERE user_id = ?;
+
+-- name: DeleteUserProjectWallEntries :execrows
+DELETE FROM project_wall_entries WHERE user_id = ?;
+
+-- name: DeleteUserAPITokens :execrows
+DELETE FROM api_tokens WHERE user_id = ?;
+
+-- name: DeleteAuthSessionsByKeycloakID :execrows
+DELETE FROM auth_sessions WHERE keycloak_id = ?;
+
+-- name: DeleteBillingDetails :execrows
+DELETE FROM billing_details WHERE user_id = ?;
+
+-- ============================================================================
+-- USER NOTES
+-- ============================================================================
+
+-- name: ListUserNotes :many
+SELECT * FROM user_notes WHERE user_id = ? ORDER BY pinned DESC, created_at DESC, id DESC;
+
+-- name: CreateUserNote :one
+INSERT INTO user_notes (user_id, author, body, pinned)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: UpdateUserNote :one
+UPDATE user_notes SET
+    body = ?,
+    pinned = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND user_id = ?
+RETURNING *;
+
+-- name: DeleteUserNote :execrows
+DELETE FROM user_notes WHERE id = ? AND user_id = ?;
+
+-- name: DeleteUserNotes :execrows
+-- Notes are free text about the member, anonymization drops them
+DELETE FROM user_notes WHERE user_id = ?;
+
+-- ============================================================================
+-- USER MERGE
+-- ============================================================================
+
+-- name: ListMergeDuplicateFees :many
+-- Fees of the source user for periods the target user is billed for too; referenced =
+-- invoiced or adjusted, such a fee cannot be dropped
+SELECT f.id, f.period_start, f.amount,
+    CAST(EXISTS (SELECT 1 FROM invoices i WHERE i.fee_id = f.id)
+        OR EXISTS (SELECT 1 FROM fakturoid_invoices fi WHERE fi.fee_id = f.id)
+        OR EXISTS (SELECT 1 FROM balance_adjustments a WHERE a.fee_id = f.id) AS BOOLEAN) AS referenced
+FROM fees f
+WHERE f.user_id = sqlc.arg(from_user_id)
+  AND EXISTS (SELECT 1 FROM fees t WHERE t.user_id = sqlc.arg(to_user_id) AND t.period_start = f.period_start)
+ORDER BY f.period_start;
+
+-- name: MergeUserPaymentVS :execrows
+-- The duplicate's payments carrying its VS take the VS of the target, balances only
+-- count payments matching the member's VS (run before MergeUserPayments)
+UPDATE payments SET identification = sqlc.arg(to_payments_id)
+WHERE user_id = sqlc.arg(from_user_id) AND identification = sqlc.arg(from_payments_id);
+
+-- name: MergeUserPayments :execrows
+UPDATE payments SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserPaymentDismissals :execrows
+UPDATE payments SET dismissed_by = sqlc.arg(to_user_id) WHERE dismissed_by = sqlc.arg(from_user_id);
+
+-- name: MergeUserFees :execrows
+-- Fees for a period the target already has stay with the source (see ListMergeDuplicateFees)
+UPDATE OR IGNORE fees SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserLogs :execrows
+UPDATE system_logs SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserEmailCampaigns :execrows
+UPDATE email_campaigns SET created_by = sqlc.arg(to_user_id) WHERE created_by = sqlc.arg(from_user_id);
+
+-- name: MergeUserCampaignRecipients :execrows
+UPDATE OR IGNORE email_campaign_recipients SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserDashboardWidgets :execrows
+UPDATE OR IGNORE user_dashboard_widgets SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserBillingDetails :execrows
+UPDATE OR IGNORE billing_details SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserInvoices :execrows
+UPDATE invoices SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserReimbursements :execrows
+UPDATE reimbursements SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserTickets :execrows
+UPDATE tickets SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserPriceChangeNotices :execrows
+UPDATE OR IGNORE level_price_change_notices SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserNotificationPreferences :execrows
+UPDATE OR IGNORE user_notification_preferences SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserMilestones :execrows
+UPDATE OR IGNORE member_milestones SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserAPITokens :execrows
+UPDATE api_tokens SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserWallEntries :execrows
+UPDATE OR IGNORE project_wall_entries SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserAuditLog :execrows
+UPDATE admin_audit_log SET actor_user_id = sqlc.arg(to_user_id) WHERE actor_user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserPaymentMatchRules :execrows
+UPDATE payment_match_rules SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserPaymentSuggestions :execrows
+UPDATE payment_suggestions SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserPaymentSplits :execrows
+UPDATE payment_splits SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserPaymentReminders :execrows
+UPDATE OR IGNORE payment_reminders SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserPaymentSettings :execrows
+UPDATE OR IGNORE user_payment_settings SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserProjectMemberships :execrows
+UPDATE OR IGNORE project_members SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserPauses :execrows
+UPDATE membership_pauses SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserLevelChangeRequests :execrows
+UPDATE level_change_requests SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserLevelHistory :execrows
+UPDATE level_history SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserSuspensionExemption :execrows
+UPDATE OR IGNORE suspension_exemptions SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserPaymentPlans :execrows
+UPDATE payment_plans SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserCharges :execrows
+UPDATE charges SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserBalanceAdjustments :execrows
+UPDATE balance_adjustments SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserPaymentStatements :execrows
+UPDATE OR IGNORE payment_statements SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserDonationReceipts :execrows
+UPDATE OR IGNORE donation_receipts SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserFakturoidInvoices :execrows
+UPDATE fakturoid_invoices SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserApplications :execrows
+UPDATE applications SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserApplicationVouches :execrows
+UPDATE OR IGNORE application_vouches SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserTasks :execrows
+UPDATE OR IGNORE user_tasks SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserTerminations :execrows
+UPDATE OR IGNORE membership_terminations SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: MergeUserNotes :execrows
+UPDATE user_notes SET user_id = sqlc.arg(to_user_id) WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: DeleteFee :exec
+DELETE FROM fees WHERE id = ?;
+
+-- name: DeleteUserCampaignRecipients :exec
+DELETE FROM email_campaign_recipients WHERE user_id = ?;
+
+-- name: DeleteUserPriceChangeNotices :exec
+DELETE FROM level_price_change_notices WHERE user_id = ?;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = ?;
+
+-- name: UpdateMergedUser :exec
+-- Identity of the target user after a merge (fields the target was missing come from the source)
+UPDATE users SET
+    keycloak_id = ?,
+    username = ?,
+    realname = ?,
+    phone = ?,
+    alt_contact = ?,
+    payments_id = ?,
+    date_joined = ?,
+    keys_granted = ?,
+    keys_returned = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
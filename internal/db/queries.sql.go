@@ -9,8 +9,62 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/base48/member-portal/internal/money"
 )
 
+const acquireLock = `-- name: AcquireLock :execrows
+INSERT INTO locks (name, holder, acquired_at, expires_at)
+VALUES (?1, ?2, CURRENT_TIMESTAMP, ?3)
+ON CONFLICT(name) DO UPDATE SET
+    holder = excluded.holder,
+    acquired_at = CASE WHEN locks.holder = excluded.holder THEN locks.acquired_at ELSE excluded.acquired_at END,
+    expires_at = excluded.expires_at
+WHERE locks.holder = excluded.holder OR locks.expires_at < ?4
+`
+
+type AcquireLockParams struct {
+	Name      string    `json:"name"`
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Now       time.Time `json:"now"`
+}
+
+// Takes the lock if it is free, expired or already ours (extends it); 0 rows = held by someone else
+func (q *Queries) AcquireLock(ctx context.Context, arg AcquireLockParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, acquireLock,
+		arg.Name,
+		arg.Holder,
+		arg.ExpiresAt,
+		arg.Now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const addEmailCampaignRecipients = `-- name: AddEmailCampaignRecipients :execrows
+INSERT OR IGNORE INTO email_campaign_recipients (campaign_id, user_id, email)
+SELECT ?1, u.id, u.email
+FROM users u
+WHERE u.state = ?2
+`
+
+type AddEmailCampaignRecipientsParams struct {
+	CampaignID interface{} `json:"campaign_id"`
+	State      string      `json:"state"`
+}
+
+// Snapshot recipients when the campaign is created, so later state changes don't affect it
+func (q *Queries) AddEmailCampaignRecipients(ctx context.Context, arg AddEmailCampaignRecipientsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addEmailCampaignRecipients, arg.CampaignID, arg.State)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const addProjectVS = `-- name: AddProjectVS :one
 INSERT INTO project_vs (project_id, vs, note)
 VALUES (?, ?, ?)
@@ -36,12 +90,306 @@ func (q *Queries) AddProjectVS(ctx context.Context, arg AddProjectVSParams) (Pro
 	return i, err
 }
 
+const anonymizeAuditLogEmail = `-- name: AnonymizeAuditLogEmail :execrows
+UPDATE admin_audit_log SET
+    actor_email = REPLACE(actor_email, ?1, ?2),
+    target = REPLACE(target, ?1, ?2),
+    payload = REPLACE(payload, ?1, ?2)
+WHERE instr(actor_email, ?1) > 0 OR instr(target, ?1) > 0 OR instr(payload, ?1) > 0
+`
+
+type AnonymizeAuditLogEmailParams struct {
+	Email           string `json:"email"`
+	AnonymizedEmail string `json:"anonymized_email"`
+}
+
+func (q *Queries) AnonymizeAuditLogEmail(ctx context.Context, arg AnonymizeAuditLogEmailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, anonymizeAuditLogEmail, arg.Email, arg.AnonymizedEmail)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const anonymizeExpensesByAccount = `-- name: AnonymizeExpensesByAccount :execrows
+UPDATE expenses SET
+    remote_account = '',
+    remote_name = '',
+    raw_data = NULL
+WHERE remote_account = ?
+`
+
+func (q *Queries) AnonymizeExpensesByAccount(ctx context.Context, remoteAccount string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, anonymizeExpensesByAccount, remoteAccount)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const anonymizeLogEmail = `-- name: AnonymizeLogEmail :execrows
+UPDATE system_logs SET
+    message = REPLACE(message, ?1, ?2),
+    metadata = REPLACE(metadata, ?1, ?2)
+WHERE instr(message, ?1) > 0 OR instr(metadata, ?1) > 0
+`
+
+type AnonymizeLogEmailParams struct {
+	Email           string `json:"email"`
+	AnonymizedEmail string `json:"anonymized_email"`
+}
+
+func (q *Queries) AnonymizeLogEmail(ctx context.Context, arg AnonymizeLogEmailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, anonymizeLogEmail, arg.Email, arg.AnonymizedEmail)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const anonymizeTicketMessageAuthors = `-- name: AnonymizeTicketMessageAuthors :execrows
+UPDATE ticket_messages SET author = ?1
+WHERE lower(author) = lower(?2)
+`
+
+type AnonymizeTicketMessageAuthorsParams struct {
+	AnonymizedEmail string `json:"anonymized_email"`
+	Email           string `json:"email"`
+}
+
+func (q *Queries) AnonymizeTicketMessageAuthors(ctx context.Context, arg AnonymizeTicketMessageAuthorsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, anonymizeTicketMessageAuthors, arg.AnonymizedEmail, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const anonymizeUser = `-- name: AnonymizeUser :exec
+UPDATE users SET
+    email = ?,
+    keycloak_id = NULL,
+    username = NULL,
+    realname = NULL,
+    phone = NULL,
+    alt_contact = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`
+
+type AnonymizeUserParams struct {
+	Email string `json:"email"`
+	ID    int64  `json:"id"`
+}
+
+// The Keycloak link goes too, a login with the old account no longer finds the member
+func (q *Queries) AnonymizeUser(ctx context.Context, arg AnonymizeUserParams) error {
+	_, err := q.db.ExecContext(ctx, anonymizeUser, arg.Email, arg.ID)
+	return err
+}
+
+const anonymizeUserApplications = `-- name: AnonymizeUserApplications :execrows
+UPDATE applications SET
+    realname = '',
+    email = ?1,
+    motivation = ''
+WHERE user_id = ?2 OR lower(email) = lower(?3)
+`
+
+type AnonymizeUserApplicationsParams struct {
+	AnonymizedEmail string        `json:"anonymized_email"`
+	UserID          sql.NullInt64 `json:"user_id"`
+	Email           string        `json:"email"`
+}
+
+func (q *Queries) AnonymizeUserApplications(ctx context.Context, arg AnonymizeUserApplicationsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, anonymizeUserApplications, arg.AnonymizedEmail, arg.UserID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const anonymizeUserCampaignRecipients = `-- name: AnonymizeUserCampaignRecipients :execrows
+UPDATE email_campaign_recipients SET email = ? WHERE user_id = ?
+`
+
+type AnonymizeUserCampaignRecipientsParams struct {
+	Email  string `json:"email"`
+	UserID int64  `json:"user_id"`
+}
+
+func (q *Queries) AnonymizeUserCampaignRecipients(ctx context.Context, arg AnonymizeUserCampaignRecipientsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, anonymizeUserCampaignRecipients, arg.Email, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const anonymizeUserPayments = `-- name: AnonymizeUserPayments :execrows
+UPDATE payments SET
+    remote_account = '',
+    raw_data = NULL
+WHERE user_id = ? AND (remote_account != '' OR raw_data IS NOT NULL)
+`
+
+// Amounts, dates and VS stay for the accounting
+func (q *Queries) AnonymizeUserPayments(ctx context.Context, userID sql.NullInt64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, anonymizeUserPayments, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const anonymizeUserReimbursements = `-- name: AnonymizeUserReimbursements :execrows
+UPDATE reimbursements SET account = '' WHERE user_id = ? AND account != ''
+`
+
+func (q *Queries) AnonymizeUserReimbursements(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, anonymizeUserReimbursements, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const anonymizeUserTickets = `-- name: AnonymizeUserTickets :execrows
+UPDATE tickets SET email = ?1
+WHERE user_id = ?2 OR lower(email) = lower(?3)
+`
+
+type AnonymizeUserTicketsParams struct {
+	AnonymizedEmail string        `json:"anonymized_email"`
+	UserID          sql.NullInt64 `json:"user_id"`
+	Email           string        `json:"email"`
+}
+
+func (q *Queries) AnonymizeUserTickets(ctx context.Context, arg AnonymizeUserTicketsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, anonymizeUserTickets, arg.AnonymizedEmail, arg.UserID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const approveApplication = `-- name: ApproveApplication :execrows
+UPDATE applications SET
+    state = 'approved',
+    user_id = ?,
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'pending'
+`
+
+type ApproveApplicationParams struct {
+	UserID    sql.NullInt64  `json:"user_id"`
+	DecidedBy sql.NullString `json:"decided_by"`
+	ID        int64          `json:"id"`
+}
+
+func (q *Queries) ApproveApplication(ctx context.Context, arg ApproveApplicationParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, approveApplication, arg.UserID, arg.DecidedBy, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const approveInvoice = `-- name: ApproveInvoice :execrows
+UPDATE invoices SET
+    state = 'approved',
+    number = ?,
+    decided_by = ?,
+    issued_at = ?,
+    due_at = ?
+WHERE id = ? AND state = 'requested'
+`
+
+type ApproveInvoiceParams struct {
+	Number    sql.NullString `json:"number"`
+	DecidedBy sql.NullString `json:"decided_by"`
+	IssuedAt  sql.NullTime   `json:"issued_at"`
+	DueAt     sql.NullTime   `json:"due_at"`
+	ID        int64          `json:"id"`
+}
+
+func (q *Queries) ApproveInvoice(ctx context.Context, arg ApproveInvoiceParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, approveInvoice,
+		arg.Number,
+		arg.DecidedBy,
+		arg.IssuedAt,
+		arg.DueAt,
+		arg.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const approveLevelChangeRequest = `-- name: ApproveLevelChangeRequest :one
+UPDATE level_change_requests SET
+    state = 'approved',
+    effective_from = ?,
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'requested'
+RETURNING id, user_id, level_id, effective_from, note, state, admin_comment, decided_by, decided_at, created_at
+`
+
+type ApproveLevelChangeRequestParams struct {
+	EffectiveFrom time.Time      `json:"effective_from"`
+	DecidedBy     sql.NullString `json:"decided_by"`
+	ID            int64          `json:"id"`
+}
+
+func (q *Queries) ApproveLevelChangeRequest(ctx context.Context, arg ApproveLevelChangeRequestParams) (LevelChangeRequest, error) {
+	row := q.db.QueryRowContext(ctx, approveLevelChangeRequest, arg.EffectiveFrom, arg.DecidedBy, arg.ID)
+	var i LevelChangeRequest
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.LevelID,
+		&i.EffectiveFrom,
+		&i.Note,
+		&i.State,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const approveReimbursement = `-- name: ApproveReimbursement :execrows
+UPDATE reimbursements SET
+    state = 'approved',
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'submitted'
+`
+
+type ApproveReimbursementParams struct {
+	DecidedBy sql.NullString `json:"decided_by"`
+	ID        int64          `json:"id"`
+}
+
+func (q *Queries) ApproveReimbursement(ctx context.Context, arg ApproveReimbursementParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, approveReimbursement, arg.DecidedBy, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const assignPayment = `-- name: AssignPayment :one
 UPDATE payments SET
     user_id = ?,
     staff_comment = ?
 WHERE id = ?
-RETURNING id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason
+RETURNING id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification
 `
 
 type AssignPaymentParams struct {
@@ -70,10 +418,104 @@ func (q *Queries) AssignPayment(ctx context.Context, arg AssignPaymentParams) (P
 		&i.DismissedAt,
 		&i.DismissedBy,
 		&i.DismissedReason,
+		&i.ReversalOf,
+		&i.ReversalReview,
+		&i.ContentHash,
+		&i.Classification,
 	)
 	return i, err
 }
 
+const cancelMembershipTermination = `-- name: CancelMembershipTermination :execrows
+UPDATE membership_terminations SET
+    state = 'cancelled',
+    cancelled_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE user_id = ? AND state = 'scheduled'
+`
+
+type CancelMembershipTerminationParams struct {
+	CancelledBy sql.NullString `json:"cancelled_by"`
+	UserID      int64          `json:"user_id"`
+}
+
+func (q *Queries) CancelMembershipTermination(ctx context.Context, arg CancelMembershipTerminationParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelMembershipTermination, arg.CancelledBy, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const completeMembershipTermination = `-- name: CompleteMembershipTermination :execrows
+UPDATE membership_terminations SET
+    state = 'completed',
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'scheduled'
+`
+
+func (q *Queries) CompleteMembershipTermination(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, completeMembershipTermination, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const completeUserTask = `-- name: CompleteUserTask :exec
+INSERT INTO user_tasks (user_id, task, completed_by)
+VALUES (?, ?, ?)
+ON CONFLICT(user_id, task) DO NOTHING
+`
+
+type CompleteUserTaskParams struct {
+	UserID      int64          `json:"user_id"`
+	Task        string         `json:"task"`
+	CompletedBy sql.NullString `json:"completed_by"`
+}
+
+// Ticking a task off again keeps the original completion time
+func (q *Queries) CompleteUserTask(ctx context.Context, arg CompleteUserTaskParams) error {
+	_, err := q.db.ExecContext(ctx, completeUserTask, arg.UserID, arg.Task, arg.CompletedBy)
+	return err
+}
+
+const countApplicationVouches = `-- name: CountApplicationVouches :one
+SELECT COUNT(*) FROM application_vouches v
+JOIN users u ON u.id = v.user_id
+WHERE v.application_id = ? AND u.state = 'accepted'
+`
+
+// Vouches of members who are still accepted (suspended and former members do not count)
+func (q *Queries) CountApplicationVouches(ctx context.Context, applicationID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countApplicationVouches, applicationID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countFeesByUser = `-- name: CountFeesByUser :one
+SELECT COUNT(*) FROM fees WHERE user_id = ?
+`
+
+func (q *Queries) CountFeesByUser(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeesByUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPaymentsByUser = `-- name: CountPaymentsByUser :one
+SELECT COUNT(*) FROM payments WHERE user_id = ?
+`
+
+func (q *Queries) CountPaymentsByUser(ctx context.Context, userID sql.NullInt64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPaymentsByUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countUsersByState = `-- name: CountUsersByState :many
 SELECT state, COUNT(*) as count FROM users GROUP BY state
 `
@@ -106,356 +548,352 @@ func (q *Queries) CountUsersByState(ctx context.Context) ([]CountUsersByStateRow
 	return items, nil
 }
 
-const createFee = `-- name: CreateFee :one
-INSERT INTO fees (user_id, level_id, period_start, amount)
-VALUES (?, ?, ?, ?)
-RETURNING id, user_id, level_id, period_start, amount, created_at
+const createAPIToken = `-- name: CreateAPIToken :one
+INSERT INTO api_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING id, user_id, name, token_hash, token_prefix, scopes, expires_at, last_used_at, revoked_at, created_at
 `
 
-type CreateFeeParams struct {
-	UserID      int64     `json:"user_id"`
-	LevelID     int64     `json:"level_id"`
-	PeriodStart time.Time `json:"period_start"`
-	Amount      string    `json:"amount"`
+type CreateAPITokenParams struct {
+	UserID      int64        `json:"user_id"`
+	Name        string       `json:"name"`
+	TokenHash   string       `json:"token_hash"`
+	TokenPrefix string       `json:"token_prefix"`
+	Scopes      string       `json:"scopes"`
+	ExpiresAt   sql.NullTime `json:"expires_at"`
 }
 
-func (q *Queries) CreateFee(ctx context.Context, arg CreateFeeParams) (Fee, error) {
-	row := q.db.QueryRowContext(ctx, createFee,
+func (q *Queries) CreateAPIToken(ctx context.Context, arg CreateAPITokenParams) (ApiToken, error) {
+	row := q.db.QueryRowContext(ctx, createAPIToken,
 		arg.UserID,
-		arg.LevelID,
-		arg.PeriodStart,
-		arg.Amount,
+		arg.Name,
+		arg.TokenHash,
+		arg.TokenPrefix,
+		arg.Scopes,
+		arg.ExpiresAt,
 	)
-	var i Fee
+	var i ApiToken
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
-		&i.LevelID,
-		&i.PeriodStart,
-		&i.Amount,
+		&i.Name,
+		&i.TokenHash,
+		&i.TokenPrefix,
+		&i.Scopes,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const createLevel = `-- name: CreateLevel :one
-INSERT INTO levels (name, amount, active)
-VALUES (?, ?, ?)
-RETURNING id, name, amount, active, created_at
+const createAdminAuditLog = `-- name: CreateAdminAuditLog :exec
+INSERT INTO admin_audit_log (actor_user_id, actor_email, api_token_id, method, path, target, payload, status, error)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 `
 
-type CreateLevelParams struct {
-	Name   string `json:"name"`
-	Amount string `json:"amount"`
-	Active bool   `json:"active"`
+type CreateAdminAuditLogParams struct {
+	ActorUserID sql.NullInt64 `json:"actor_user_id"`
+	ActorEmail  string        `json:"actor_email"`
+	ApiTokenID  sql.NullInt64 `json:"api_token_id"`
+	Method      string        `json:"method"`
+	Path        string        `json:"path"`
+	Target      string        `json:"target"`
+	Payload     string        `json:"payload"`
+	Status      int64         `json:"status"`
+	Error       string        `json:"error"`
 }
 
-func (q *Queries) CreateLevel(ctx context.Context, arg CreateLevelParams) (Level, error) {
-	row := q.db.QueryRowContext(ctx, createLevel, arg.Name, arg.Amount, arg.Active)
-	var i Level
+func (q *Queries) CreateAdminAuditLog(ctx context.Context, arg CreateAdminAuditLogParams) error {
+	_, err := q.db.ExecContext(ctx, createAdminAuditLog,
+		arg.ActorUserID,
+		arg.ActorEmail,
+		arg.ApiTokenID,
+		arg.Method,
+		arg.Path,
+		arg.Target,
+		arg.Payload,
+		arg.Status,
+		arg.Error,
+	)
+	return err
+}
+
+const createApplication = `-- name: CreateApplication :one
+INSERT INTO applications (realname, email, motivation, level_id)
+VALUES (?, ?, ?, ?)
+RETURNING id, realname, email, motivation, level_id, state, user_id, admin_comment, decided_by, decided_at, created_at
+`
+
+type CreateApplicationParams struct {
+	Realname   string `json:"realname"`
+	Email      string `json:"email"`
+	Motivation string `json:"motivation"`
+	LevelID    int64  `json:"level_id"`
+}
+
+func (q *Queries) CreateApplication(ctx context.Context, arg CreateApplicationParams) (Application, error) {
+	row := q.db.QueryRowContext(ctx, createApplication,
+		arg.Realname,
+		arg.Email,
+		arg.Motivation,
+		arg.LevelID,
+	)
+	var i Application
 	err := row.Scan(
 		&i.ID,
-		&i.Name,
-		&i.Amount,
-		&i.Active,
+		&i.Realname,
+		&i.Email,
+		&i.Motivation,
+		&i.LevelID,
+		&i.State,
+		&i.UserID,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.DecidedAt,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const createLog = `-- name: CreateLog :one
-INSERT INTO system_logs (subsystem, level, user_id, message, metadata)
+const createBalanceAdjustment = `-- name: CreateBalanceAdjustment :one
+INSERT INTO balance_adjustments (user_id, fee_id, amount, reason, created_by)
 VALUES (?, ?, ?, ?, ?)
-RETURNING id, subsystem, level, user_id, message, metadata, created_at
+RETURNING id, user_id, fee_id, amount, reason, created_by, created_at
 `
 
-type CreateLogParams struct {
-	Subsystem string         `json:"subsystem"`
-	Level     string         `json:"level"`
-	UserID    sql.NullInt64  `json:"user_id"`
-	Message   string         `json:"message"`
-	Metadata  sql.NullString `json:"metadata"`
+type CreateBalanceAdjustmentParams struct {
+	UserID    int64         `json:"user_id"`
+	FeeID     sql.NullInt64 `json:"fee_id"`
+	Amount    money.Amount  `json:"amount"`
+	Reason    string        `json:"reason"`
+	CreatedBy string        `json:"created_by"`
 }
 
-func (q *Queries) CreateLog(ctx context.Context, arg CreateLogParams) (SystemLog, error) {
-	row := q.db.QueryRowContext(ctx, createLog,
-		arg.Subsystem,
-		arg.Level,
+func (q *Queries) CreateBalanceAdjustment(ctx context.Context, arg CreateBalanceAdjustmentParams) (BalanceAdjustment, error) {
+	row := q.db.QueryRowContext(ctx, createBalanceAdjustment,
 		arg.UserID,
-		arg.Message,
-		arg.Metadata,
+		arg.FeeID,
+		arg.Amount,
+		arg.Reason,
+		arg.CreatedBy,
 	)
-	var i SystemLog
+	var i BalanceAdjustment
 	err := row.Scan(
 		&i.ID,
-		&i.Subsystem,
-		&i.Level,
 		&i.UserID,
-		&i.Message,
-		&i.Metadata,
+		&i.FeeID,
+		&i.Amount,
+		&i.Reason,
+		&i.CreatedBy,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const createPayment = `-- name: CreatePayment :one
-INSERT INTO payments (
-    user_id, date, amount, kind, kind_id,
-    local_account, remote_account, identification, raw_data, staff_comment
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-RETURNING id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason
+const createBalanceSnapshot = `-- name: CreateBalanceSnapshot :exec
+INSERT INTO balance_snapshots (user_id, month, balance)
+VALUES (?, ?, ?)
 `
 
-type CreatePaymentParams struct {
-	UserID         sql.NullInt64  `json:"user_id"`
-	Date           time.Time      `json:"date"`
-	Amount         string         `json:"amount"`
-	Kind           string         `json:"kind"`
-	KindID         string         `json:"kind_id"`
-	LocalAccount   string         `json:"local_account"`
-	RemoteAccount  string         `json:"remote_account"`
-	Identification string         `json:"identification"`
-	RawData        sql.NullString `json:"raw_data"`
-	StaffComment   sql.NullString `json:"staff_comment"`
+type CreateBalanceSnapshotParams struct {
+	UserID  int64        `json:"user_id"`
+	Month   time.Time    `json:"month"`
+	Balance money.Amount `json:"balance"`
 }
 
-func (q *Queries) CreatePayment(ctx context.Context, arg CreatePaymentParams) (Payment, error) {
-	row := q.db.QueryRowContext(ctx, createPayment,
+func (q *Queries) CreateBalanceSnapshot(ctx context.Context, arg CreateBalanceSnapshotParams) error {
+	_, err := q.db.ExecContext(ctx, createBalanceSnapshot, arg.UserID, arg.Month, arg.Balance)
+	return err
+}
+
+const createCharge = `-- name: CreateCharge :one
+INSERT INTO charges (user_id, description, amount, category, date, created_by)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING id, user_id, description, amount, category, date, created_by, created_at
+`
+
+type CreateChargeParams struct {
+	UserID      int64        `json:"user_id"`
+	Description string       `json:"description"`
+	Amount      money.Amount `json:"amount"`
+	Category    string       `json:"category"`
+	Date        time.Time    `json:"date"`
+	CreatedBy   string       `json:"created_by"`
+}
+
+func (q *Queries) CreateCharge(ctx context.Context, arg CreateChargeParams) (Charge, error) {
+	row := q.db.QueryRowContext(ctx, createCharge,
 		arg.UserID,
-		arg.Date,
+		arg.Description,
 		arg.Amount,
-		arg.Kind,
-		arg.KindID,
-		arg.LocalAccount,
-		arg.RemoteAccount,
-		arg.Identification,
-		arg.RawData,
-		arg.StaffComment,
+		arg.Category,
+		arg.Date,
+		arg.CreatedBy,
 	)
-	var i Payment
+	var i Charge
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
-		&i.Date,
+		&i.Description,
 		&i.Amount,
-		&i.Kind,
-		&i.KindID,
-		&i.LocalAccount,
-		&i.RemoteAccount,
-		&i.Identification,
-		&i.RawData,
-		&i.StaffComment,
+		&i.Category,
+		&i.Date,
+		&i.CreatedBy,
 		&i.CreatedAt,
-		&i.ProjectID,
-		&i.DismissedAt,
-		&i.DismissedBy,
-		&i.DismissedReason,
 	)
 	return i, err
 }
 
-const createProject = `-- name: CreateProject :one
-INSERT INTO projects (name, payments_id, description)
-VALUES (?, ?, ?)
-RETURNING id, name, payments_id, description
+const createDonationReceipt = `-- name: CreateDonationReceipt :exec
+INSERT OR IGNORE INTO donation_receipts (user_id, year) VALUES (?, ?)
 `
 
-type CreateProjectParams struct {
-	Name        string         `json:"name"`
-	PaymentsID  sql.NullString `json:"payments_id"`
-	Description sql.NullString `json:"description"`
+type CreateDonationReceiptParams struct {
+	UserID int64 `json:"user_id"`
+	Year   int64 `json:"year"`
 }
 
-func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (Project, error) {
-	row := q.db.QueryRowContext(ctx, createProject, arg.Name, arg.PaymentsID, arg.Description)
-	var i Project
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.PaymentsID,
-		&i.Description,
-	)
-	return i, err
+func (q *Queries) CreateDonationReceipt(ctx context.Context, arg CreateDonationReceiptParams) error {
+	_, err := q.db.ExecContext(ctx, createDonationReceipt, arg.UserID, arg.Year)
+	return err
 }
 
-const createUser = `-- name: CreateUser :one
-INSERT INTO users (
-    keycloak_id, email, username, realname, phone, alt_contact,
-    level_id, level_actual_amount, payments_id, state,
-    is_council, is_staff
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-RETURNING id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at
+const createEmailCampaign = `-- name: CreateEmailCampaign :one
+INSERT INTO email_campaigns (
+    name, subject, template_name, body, audience, created_by
+) VALUES (?, ?, ?, ?, ?, ?)
+RETURNING id, name, subject, template_name, body, audience, status, created_by, created_at, completed_at
 `
 
-type CreateUserParams struct {
-	KeycloakID        sql.NullString `json:"keycloak_id"`
-	Email             string         `json:"email"`
-	Username          sql.NullString `json:"username"`
-	Realname          sql.NullString `json:"realname"`
-	Phone             sql.NullString `json:"phone"`
-	AltContact        sql.NullString `json:"alt_contact"`
-	LevelID           int64          `json:"level_id"`
-	LevelActualAmount string         `json:"level_actual_amount"`
-	PaymentsID        sql.NullString `json:"payments_id"`
-	State             string         `json:"state"`
-	IsCouncil         bool           `json:"is_council"`
-	IsStaff           bool           `json:"is_staff"`
+type CreateEmailCampaignParams struct {
+	Name         string         `json:"name"`
+	Subject      string         `json:"subject"`
+	TemplateName string         `json:"template_name"`
+	Body         sql.NullString `json:"body"`
+	Audience     string         `json:"audience"`
+	CreatedBy    sql.NullInt64  `json:"created_by"`
 }
 
-func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser,
-		arg.KeycloakID,
-		arg.Email,
-		arg.Username,
-		arg.Realname,
-		arg.Phone,
-		arg.AltContact,
-		arg.LevelID,
-		arg.LevelActualAmount,
-		arg.PaymentsID,
-		arg.State,
-		arg.IsCouncil,
-		arg.IsStaff,
+func (q *Queries) CreateEmailCampaign(ctx context.Context, arg CreateEmailCampaignParams) (EmailCampaign, error) {
+	row := q.db.QueryRowContext(ctx, createEmailCampaign,
+		arg.Name,
+		arg.Subject,
+		arg.TemplateName,
+		arg.Body,
+		arg.Audience,
+		arg.CreatedBy,
 	)
-	var i User
+	var i EmailCampaign
 	err := row.Scan(
 		&i.ID,
-		&i.KeycloakID,
-		&i.Email,
-		&i.Username,
-		&i.Realname,
-		&i.Phone,
-		&i.AltContact,
-		&i.LevelID,
-		&i.LevelActualAmount,
-		&i.PaymentsID,
-		&i.DateJoined,
-		&i.KeysGranted,
-		&i.KeysReturned,
-		&i.State,
-		&i.IsCouncil,
-		&i.IsStaff,
+		&i.Name,
+		&i.Subject,
+		&i.TemplateName,
+		&i.Body,
+		&i.Audience,
+		&i.Status,
+		&i.CreatedBy,
 		&i.CreatedAt,
-		&i.UpdatedAt,
+		&i.CompletedAt,
 	)
 	return i, err
 }
 
-const deleteProject = `-- name: DeleteProject :exec
-DELETE FROM projects WHERE id = ?
-`
-
-func (q *Queries) DeleteProject(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteProject, id)
-	return err
-}
-
-const dismissPayment = `-- name: DismissPayment :one
-UPDATE payments SET
-    dismissed_at = CURRENT_TIMESTAMP,
-    dismissed_by = ?,
-    dismissed_reason = ?,
-    staff_comment = ?
-WHERE id = ?
-RETURNING id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason
+const createExpense = `-- name: CreateExpense :execrows
+INSERT INTO expenses (
+    kind, kind_id, date, amount, remote_account, remote_name,
+    identification, message, category, staff_comment, raw_data
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(kind, kind_id) DO NOTHING
 `
 
-type DismissPaymentParams struct {
-	DismissedBy     interface{}    `json:"dismissed_by"`
-	DismissedReason interface{}    `json:"dismissed_reason"`
-	StaffComment    sql.NullString `json:"staff_comment"`
-	ID              int64          `json:"id"`
+type CreateExpenseParams struct {
+	Kind           string         `json:"kind"`
+	KindID         string         `json:"kind_id"`
+	Date           time.Time      `json:"date"`
+	Amount         money.Amount   `json:"amount"`
+	RemoteAccount  string         `json:"remote_account"`
+	RemoteName     string         `json:"remote_name"`
+	Identification string         `json:"identification"`
+	Message        string         `json:"message"`
+	Category       string         `json:"category"`
+	StaffComment   sql.NullString `json:"staff_comment"`
+	RawData        sql.NullString `json:"raw_data"`
 }
 
-func (q *Queries) DismissPayment(ctx context.Context, arg DismissPaymentParams) (Payment, error) {
-	row := q.db.QueryRowContext(ctx, dismissPayment,
-		arg.DismissedBy,
-		arg.DismissedReason,
+// An already imported transaction is left as is (keeps admin tags)
+func (q *Queries) CreateExpense(ctx context.Context, arg CreateExpenseParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createExpense,
+		arg.Kind,
+		arg.KindID,
+		arg.Date,
+		arg.Amount,
+		arg.RemoteAccount,
+		arg.RemoteName,
+		arg.Identification,
+		arg.Message,
+		arg.Category,
 		arg.StaffComment,
-		arg.ID,
-	)
-	var i Payment
-	err := row.Scan(
-		&i.ID,
-		&i.UserID,
-		&i.Date,
-		&i.Amount,
-		&i.Kind,
-		&i.KindID,
-		&i.LocalAccount,
-		&i.RemoteAccount,
-		&i.Identification,
-		&i.RawData,
-		&i.StaffComment,
-		&i.CreatedAt,
-		&i.ProjectID,
-		&i.DismissedAt,
-		&i.DismissedBy,
-		&i.DismissedReason,
+		arg.RawData,
 	)
-	return i, err
-}
-
-const getDistinctLevels = `-- name: GetDistinctLevels :many
-SELECT DISTINCT level FROM system_logs ORDER BY level
-`
-
-func (q *Queries) GetDistinctLevels(ctx context.Context) ([]string, error) {
-	rows, err := q.db.QueryContext(ctx, getDistinctLevels)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []string{}
-	for rows.Next() {
-		var level string
-		if err := rows.Scan(&level); err != nil {
-			return nil, err
-		}
-		items = append(items, level)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected()
 }
 
-const getDistinctSubsystems = `-- name: GetDistinctSubsystems :many
-SELECT DISTINCT subsystem FROM system_logs ORDER BY subsystem
+const createFakturoidInvoice = `-- name: CreateFakturoidInvoice :exec
+INSERT INTO fakturoid_invoices (fee_id, user_id, fakturoid_id, number, status, public_url, paid_on)
+VALUES (?, ?, ?, ?, ?, ?, ?)
 `
 
-func (q *Queries) GetDistinctSubsystems(ctx context.Context) ([]string, error) {
-	rows, err := q.db.QueryContext(ctx, getDistinctSubsystems)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []string{}
-	for rows.Next() {
-		var subsystem string
-		if err := rows.Scan(&subsystem); err != nil {
-			return nil, err
-		}
-		items = append(items, subsystem)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type CreateFakturoidInvoiceParams struct {
+	FeeID       int64        `json:"fee_id"`
+	UserID      int64        `json:"user_id"`
+	FakturoidID int64        `json:"fakturoid_id"`
+	Number      string       `json:"number"`
+	Status      string       `json:"status"`
+	PublicUrl   string       `json:"public_url"`
+	PaidOn      sql.NullTime `json:"paid_on"`
 }
 
-const getFee = `-- name: GetFee :one
-SELECT id, user_id, level_id, period_start, amount, created_at FROM fees WHERE id = ? LIMIT 1
+func (q *Queries) CreateFakturoidInvoice(ctx context.Context, arg CreateFakturoidInvoiceParams) error {
+	_, err := q.db.ExecContext(ctx, createFakturoidInvoice,
+		arg.FeeID,
+		arg.UserID,
+		arg.FakturoidID,
+		arg.Number,
+		arg.Status,
+		arg.PublicUrl,
+		arg.PaidOn,
+	)
+	return err
+}
+
+const createFee = `-- name: CreateFee :one
+INSERT INTO fees (user_id, level_id, period_start, amount, months)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, user_id, level_id, period_start, amount, created_at, months
 `
 
-func (q *Queries) GetFee(ctx context.Context, id int64) (Fee, error) {
-	row := q.db.QueryRowContext(ctx, getFee, id)
+type CreateFeeParams struct {
+	UserID      int64        `json:"user_id"`
+	LevelID     int64        `json:"level_id"`
+	PeriodStart time.Time    `json:"period_start"`
+	Amount      money.Amount `json:"amount"`
+	Months      int64        `json:"months"`
+}
+
+func (q *Queries) CreateFee(ctx context.Context, arg CreateFeeParams) (Fee, error) {
+	row := q.db.QueryRowContext(ctx, createFee,
+		arg.UserID,
+		arg.LevelID,
+		arg.PeriodStart,
+		arg.Amount,
+		arg.Months,
+	)
 	var i Fee
 	err := row.Scan(
 		&i.ID,
@@ -464,21 +902,35 @@ func (q *Queries) GetFee(ctx context.Context, id int64) (Fee, error) {
 		&i.PeriodStart,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Months,
 	)
 	return i, err
 }
 
-const getFeeByUserAndPeriod = `-- name: GetFeeByUserAndPeriod :one
-SELECT id, user_id, level_id, period_start, amount, created_at FROM fees WHERE user_id = ? AND period_start = ? LIMIT 1
+const createFeeIfMissing = `-- name: CreateFeeIfMissing :one
+INSERT OR IGNORE INTO fees (user_id, level_id, period_start, amount, months)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, user_id, level_id, period_start, amount, created_at, months
 `
 
-type GetFeeByUserAndPeriodParams struct {
-	UserID      int64     `json:"user_id"`
-	PeriodStart time.Time `json:"period_start"`
+type CreateFeeIfMissingParams struct {
+	UserID      int64        `json:"user_id"`
+	LevelID     int64        `json:"level_id"`
+	PeriodStart time.Time    `json:"period_start"`
+	Amount      money.Amount `json:"amount"`
+	Months      int64        `json:"months"`
 }
 
-func (q *Queries) GetFeeByUserAndPeriod(ctx context.Context, arg GetFeeByUserAndPeriodParams) (Fee, error) {
-	row := q.db.QueryRowContext(ctx, getFeeByUserAndPeriod, arg.UserID, arg.PeriodStart)
+// Insert a fee unless the member already has one for the period (idx_fees_user_period);
+// returns no row (sql.ErrNoRows) on the conflict
+func (q *Queries) CreateFeeIfMissing(ctx context.Context, arg CreateFeeIfMissingParams) (Fee, error) {
+	row := q.db.QueryRowContext(ctx, createFeeIfMissing,
+		arg.UserID,
+		arg.LevelID,
+		arg.PeriodStart,
+		arg.Amount,
+		arg.Months,
+	)
 	var i Fee
 	err := row.Scan(
 		&i.ID,
@@ -487,1172 +939,8641 @@ func (q *Queries) GetFeeByUserAndPeriod(ctx context.Context, arg GetFeeByUserAnd
 		&i.PeriodStart,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Months,
 	)
 	return i, err
 }
 
-const getLevel = `-- name: GetLevel :one
-SELECT id, name, amount, active, created_at FROM levels WHERE id = ? LIMIT 1
+const createInvoiceRequest = `-- name: CreateInvoiceRequest :one
+INSERT INTO invoices (user_id, months, amount, company_name, company_id, vat_id, address, note)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, user_id, state, number, months, amount, company_name, company_id, vat_id, address, note, admin_comment, decided_by, issued_at, due_at, payment_id, paid_at, created_at, fee_id, charge_id, item
 `
 
-func (q *Queries) GetLevel(ctx context.Context, id int64) (Level, error) {
-	row := q.db.QueryRowContext(ctx, getLevel, id)
-	var i Level
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Amount,
-		&i.Active,
+type CreateInvoiceRequestParams struct {
+	UserID      int64          `json:"user_id"`
+	Months      int64          `json:"months"`
+	Amount      money.Amount   `json:"amount"`
+	CompanyName string         `json:"company_name"`
+	CompanyID   string         `json:"company_id"`
+	VatID       string         `json:"vat_id"`
+	Address     string         `json:"address"`
+	Note        sql.NullString `json:"note"`
+}
+
+func (q *Queries) CreateInvoiceRequest(ctx context.Context, arg CreateInvoiceRequestParams) (Invoice, error) {
+	row := q.db.QueryRowContext(ctx, createInvoiceRequest,
+		arg.UserID,
+		arg.Months,
+		arg.Amount,
+		arg.CompanyName,
+		arg.CompanyID,
+		arg.VatID,
+		arg.Address,
+		arg.Note,
+	)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.State,
+		&i.Number,
+		&i.Months,
+		&i.Amount,
+		&i.CompanyName,
+		&i.CompanyID,
+		&i.VatID,
+		&i.Address,
+		&i.Note,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.IssuedAt,
+		&i.DueAt,
+		&i.PaymentID,
+		&i.PaidAt,
 		&i.CreatedAt,
+		&i.FeeID,
+		&i.ChargeID,
+		&i.Item,
 	)
 	return i, err
 }
 
-const getPayment = `-- name: GetPayment :one
-SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason FROM payments WHERE id = ? LIMIT 1
+const createItemInvoice = `-- name: CreateItemInvoice :one
+INSERT INTO invoices (user_id, state, number, months, amount, company_name, company_id, vat_id, address, note, item, fee_id, charge_id, decided_by, issued_at, due_at)
+VALUES (?, 'approved', ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, user_id, state, number, months, amount, company_name, company_id, vat_id, address, note, admin_comment, decided_by, issued_at, due_at, payment_id, paid_at, created_at, fee_id, charge_id, item
 `
 
-func (q *Queries) GetPayment(ctx context.Context, id int64) (Payment, error) {
-	row := q.db.QueryRowContext(ctx, getPayment, id)
-	var i Payment
+type CreateItemInvoiceParams struct {
+	UserID      int64          `json:"user_id"`
+	Number      sql.NullString `json:"number"`
+	Months      int64          `json:"months"`
+	Amount      money.Amount   `json:"amount"`
+	CompanyName string         `json:"company_name"`
+	CompanyID   string         `json:"company_id"`
+	VatID       string         `json:"vat_id"`
+	Address     string         `json:"address"`
+	Note        sql.NullString `json:"note"`
+	Item        sql.NullString `json:"item"`
+	FeeID       sql.NullInt64  `json:"fee_id"`
+	ChargeID    sql.NullInt64  `json:"charge_id"`
+	DecidedBy   sql.NullString `json:"decided_by"`
+	IssuedAt    sql.NullTime   `json:"issued_at"`
+	DueAt       sql.NullTime   `json:"due_at"`
+}
+
+// Invoice for a fee or a charge, issued right away (the amount is given)
+func (q *Queries) CreateItemInvoice(ctx context.Context, arg CreateItemInvoiceParams) (Invoice, error) {
+	row := q.db.QueryRowContext(ctx, createItemInvoice,
+		arg.UserID,
+		arg.Number,
+		arg.Months,
+		arg.Amount,
+		arg.CompanyName,
+		arg.CompanyID,
+		arg.VatID,
+		arg.Address,
+		arg.Note,
+		arg.Item,
+		arg.FeeID,
+		arg.ChargeID,
+		arg.DecidedBy,
+		arg.IssuedAt,
+		arg.DueAt,
+	)
+	var i Invoice
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
-		&i.Date,
+		&i.State,
+		&i.Number,
+		&i.Months,
 		&i.Amount,
-		&i.Kind,
-		&i.KindID,
-		&i.LocalAccount,
-		&i.RemoteAccount,
-		&i.Identification,
-		&i.RawData,
-		&i.StaffComment,
+		&i.CompanyName,
+		&i.CompanyID,
+		&i.VatID,
+		&i.Address,
+		&i.Note,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.IssuedAt,
+		&i.DueAt,
+		&i.PaymentID,
+		&i.PaidAt,
 		&i.CreatedAt,
-		&i.ProjectID,
-		&i.DismissedAt,
-		&i.DismissedBy,
-		&i.DismissedReason,
+		&i.FeeID,
+		&i.ChargeID,
+		&i.Item,
 	)
 	return i, err
 }
 
-const getPaymentByKindAndID = `-- name: GetPaymentByKindAndID :one
-SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason FROM payments WHERE kind = ? AND kind_id = ? LIMIT 1
+const createLevel = `-- name: CreateLevel :one
+INSERT INTO levels (name, amount, active)
+VALUES (?, ?, ?)
+RETURNING id, name, amount, active, created_at, btcpay
 `
 
-type GetPaymentByKindAndIDParams struct {
-	Kind   string `json:"kind"`
-	KindID string `json:"kind_id"`
+type CreateLevelParams struct {
+	Name   string       `json:"name"`
+	Amount money.Amount `json:"amount"`
+	Active bool         `json:"active"`
 }
 
-func (q *Queries) GetPaymentByKindAndID(ctx context.Context, arg GetPaymentByKindAndIDParams) (Payment, error) {
-	row := q.db.QueryRowContext(ctx, getPaymentByKindAndID, arg.Kind, arg.KindID)
-	var i Payment
+func (q *Queries) CreateLevel(ctx context.Context, arg CreateLevelParams) (Level, error) {
+	row := q.db.QueryRowContext(ctx, createLevel, arg.Name, arg.Amount, arg.Active)
+	var i Level
 	err := row.Scan(
 		&i.ID,
-		&i.UserID,
-		&i.Date,
+		&i.Name,
 		&i.Amount,
-		&i.Kind,
-		&i.KindID,
-		&i.LocalAccount,
-		&i.RemoteAccount,
-		&i.Identification,
-		&i.RawData,
-		&i.StaffComment,
+		&i.Active,
 		&i.CreatedAt,
-		&i.ProjectID,
-		&i.DismissedAt,
-		&i.DismissedBy,
-		&i.DismissedReason,
+		&i.Btcpay,
 	)
 	return i, err
 }
 
-const getProject = `-- name: GetProject :one
-SELECT id, name, payments_id, description FROM projects WHERE id = ? LIMIT 1
+const createLevelChangeRequest = `-- name: CreateLevelChangeRequest :one
+INSERT INTO level_change_requests (user_id, level_id, effective_from, note)
+VALUES (?, ?, ?, ?)
+RETURNING id, user_id, level_id, effective_from, note, state, admin_comment, decided_by, decided_at, created_at
 `
 
-func (q *Queries) GetProject(ctx context.Context, id int64) (Project, error) {
-	row := q.db.QueryRowContext(ctx, getProject, id)
-	var i Project
+type CreateLevelChangeRequestParams struct {
+	UserID        int64          `json:"user_id"`
+	LevelID       int64          `json:"level_id"`
+	EffectiveFrom time.Time      `json:"effective_from"`
+	Note          sql.NullString `json:"note"`
+}
+
+func (q *Queries) CreateLevelChangeRequest(ctx context.Context, arg CreateLevelChangeRequestParams) (LevelChangeRequest, error) {
+	row := q.db.QueryRowContext(ctx, createLevelChangeRequest,
+		arg.UserID,
+		arg.LevelID,
+		arg.EffectiveFrom,
+		arg.Note,
+	)
+	var i LevelChangeRequest
 	err := row.Scan(
 		&i.ID,
-		&i.Name,
-		&i.PaymentsID,
-		&i.Description,
+		&i.UserID,
+		&i.LevelID,
+		&i.EffectiveFrom,
+		&i.Note,
+		&i.State,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getProjectBalance = `-- name: GetProjectBalance :one
-SELECT COALESCE(SUM(CAST(amount AS REAL)), 0) as total
-FROM (
-    SELECT DISTINCT p.id, p.amount FROM payments p
-    WHERE p.project_id = ?1
-       OR p.identification IN (SELECT pv.vs FROM project_vs pv WHERE pv.project_id = ?1)
-) sub
+const createLevelHistory = `-- name: CreateLevelHistory :one
+INSERT INTO level_history (user_id, level_id, effective_from, request_id, created_by)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, user_id, level_id, effective_from, request_id, created_by, applied_at, created_at
 `
 
-// Sum all payments for a project (by project_id OR by any VS in project_vs)
-func (q *Queries) GetProjectBalance(ctx context.Context, projectID sql.NullInt64) (interface{}, error) {
-	row := q.db.QueryRowContext(ctx, getProjectBalance, projectID)
-	var total interface{}
-	err := row.Scan(&total)
-	return total, err
+type CreateLevelHistoryParams struct {
+	UserID        int64         `json:"user_id"`
+	LevelID       int64         `json:"level_id"`
+	EffectiveFrom time.Time     `json:"effective_from"`
+	RequestID     sql.NullInt64 `json:"request_id"`
+	CreatedBy     string        `json:"created_by"`
 }
 
-const getProjectByPaymentsID = `-- name: GetProjectByPaymentsID :one
-SELECT p.id, p.name, p.payments_id, p.description FROM projects p
-JOIN project_vs pv ON p.id = pv.project_id
-WHERE pv.vs = ? LIMIT 1
+func (q *Queries) CreateLevelHistory(ctx context.Context, arg CreateLevelHistoryParams) (LevelHistory, error) {
+	row := q.db.QueryRowContext(ctx, createLevelHistory,
+		arg.UserID,
+		arg.LevelID,
+		arg.EffectiveFrom,
+		arg.RequestID,
+		arg.CreatedBy,
+	)
+	var i LevelHistory
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.LevelID,
+		&i.EffectiveFrom,
+		&i.RequestID,
+		&i.CreatedBy,
+		&i.AppliedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createLevelPriceChange = `-- name: CreateLevelPriceChange :one
+INSERT INTO level_price_changes (level_id, new_amount, effective_from, note, created_by)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, level_id, new_amount, effective_from, note, created_by, notified_at, applied_at, created_at
 `
 
-// Find project by any of its VS identifiers (in project_vs table)
-func (q *Queries) GetProjectByPaymentsID(ctx context.Context, vs string) (Project, error) {
-	row := q.db.QueryRowContext(ctx, getProjectByPaymentsID, vs)
-	var i Project
+type CreateLevelPriceChangeParams struct {
+	LevelID       int64          `json:"level_id"`
+	NewAmount     money.Amount   `json:"new_amount"`
+	EffectiveFrom time.Time      `json:"effective_from"`
+	Note          sql.NullString `json:"note"`
+	CreatedBy     string         `json:"created_by"`
+}
+
+func (q *Queries) CreateLevelPriceChange(ctx context.Context, arg CreateLevelPriceChangeParams) (LevelPriceChange, error) {
+	row := q.db.QueryRowContext(ctx, createLevelPriceChange,
+		arg.LevelID,
+		arg.NewAmount,
+		arg.EffectiveFrom,
+		arg.Note,
+		arg.CreatedBy,
+	)
+	var i LevelPriceChange
 	err := row.Scan(
 		&i.ID,
-		&i.Name,
-		&i.PaymentsID,
-		&i.Description,
+		&i.LevelID,
+		&i.NewAmount,
+		&i.EffectiveFrom,
+		&i.Note,
+		&i.CreatedBy,
+		&i.NotifiedAt,
+		&i.AppliedAt,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getProjectPayments = `-- name: GetProjectPayments :many
-SELECT DISTINCT p.id, p.user_id, p.date, p.amount, p.kind, p.kind_id, p.local_account, p.remote_account, p.identification, p.raw_data, p.staff_comment, p.created_at, p.project_id, p.dismissed_at, p.dismissed_by, p.dismissed_reason FROM payments p
-WHERE p.project_id = ?1
-   OR p.identification IN (SELECT pv.vs FROM project_vs pv WHERE pv.project_id = ?1)
-ORDER BY p.date DESC
+const createLevelPriceChangeNotice = `-- name: CreateLevelPriceChangeNotice :exec
+INSERT OR IGNORE INTO level_price_change_notices (change_id, user_id) VALUES (?, ?)
 `
 
-// Get all payments for a project:
-// 1. Payments explicitly assigned to project (project_id set)
-// 2. Payments matching any of project's VS identifiers (from project_vs table)
-func (q *Queries) GetProjectPayments(ctx context.Context, projectID sql.NullInt64) ([]Payment, error) {
-	rows, err := q.db.QueryContext(ctx, getProjectPayments, projectID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []Payment{}
-	for rows.Next() {
-		var i Payment
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.Date,
-			&i.Amount,
-			&i.Kind,
-			&i.KindID,
-			&i.LocalAccount,
-			&i.RemoteAccount,
-			&i.Identification,
-			&i.RawData,
-			&i.StaffComment,
-			&i.CreatedAt,
-			&i.ProjectID,
-			&i.DismissedAt,
-			&i.DismissedBy,
-			&i.DismissedReason,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type CreateLevelPriceChangeNoticeParams struct {
+	ChangeID int64 `json:"change_id"`
+	UserID   int64 `json:"user_id"`
 }
 
-const getProjectVSByVS = `-- name: GetProjectVSByVS :one
-SELECT id, project_id, vs, note, created_at FROM project_vs WHERE vs = ? LIMIT 1
+func (q *Queries) CreateLevelPriceChangeNotice(ctx context.Context, arg CreateLevelPriceChangeNoticeParams) error {
+	_, err := q.db.ExecContext(ctx, createLevelPriceChangeNotice, arg.ChangeID, arg.UserID)
+	return err
+}
+
+const createLog = `-- name: CreateLog :one
+INSERT INTO system_logs (subsystem, level, user_id, message, metadata)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, subsystem, level, user_id, message, metadata, created_at
 `
 
-func (q *Queries) GetProjectVSByVS(ctx context.Context, vs string) (ProjectV, error) {
-	row := q.db.QueryRowContext(ctx, getProjectVSByVS, vs)
-	var i ProjectV
+type CreateLogParams struct {
+	Subsystem string         `json:"subsystem"`
+	Level     string         `json:"level"`
+	UserID    sql.NullInt64  `json:"user_id"`
+	Message   string         `json:"message"`
+	Metadata  sql.NullString `json:"metadata"`
+}
+
+func (q *Queries) CreateLog(ctx context.Context, arg CreateLogParams) (SystemLog, error) {
+	row := q.db.QueryRowContext(ctx, createLog,
+		arg.Subsystem,
+		arg.Level,
+		arg.UserID,
+		arg.Message,
+		arg.Metadata,
+	)
+	var i SystemLog
 	err := row.Scan(
 		&i.ID,
-		&i.ProjectID,
-		&i.Vs,
-		&i.Note,
+		&i.Subsystem,
+		&i.Level,
+		&i.UserID,
+		&i.Message,
+		&i.Metadata,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getUserBalance = `-- name: GetUserBalance :one
-SELECT
-    COALESCE((
-        SELECT SUM(CAST(p.amount AS REAL))
-        FROM payments p
-        JOIN users u ON p.user_id = u.id
-        WHERE p.user_id = ?
-        AND p.identification = u.payments_id
-    ), 0) -
-    COALESCE((SELECT SUM(CAST(f.amount AS REAL)) FROM fees f WHERE f.user_id = ?), 0) as balance
+const createMemberMilestone = `-- name: CreateMemberMilestone :exec
+INSERT OR IGNORE INTO member_milestones (user_id, milestone, emailed, announced) VALUES (?, ?, ?, ?)
 `
 
-type GetUserBalanceParams struct {
-	UserID   sql.NullInt64 `json:"user_id"`
-	UserID_2 int64         `json:"user_id_2"`
+type CreateMemberMilestoneParams struct {
+	UserID    int64  `json:"user_id"`
+	Milestone string `json:"milestone"`
+	Emailed   bool   `json:"emailed"`
+	Announced bool   `json:"announced"`
 }
 
-// Calculate membership fee balance (only payments matching user's payments_id VS)
-func (q *Queries) GetUserBalance(ctx context.Context, arg GetUserBalanceParams) (int64, error) {
-	row := q.db.QueryRowContext(ctx, getUserBalance, arg.UserID, arg.UserID_2)
-	var balance int64
-	err := row.Scan(&balance)
-	return balance, err
+func (q *Queries) CreateMemberMilestone(ctx context.Context, arg CreateMemberMilestoneParams) error {
+	_, err := q.db.ExecContext(ctx, createMemberMilestone,
+		arg.UserID,
+		arg.Milestone,
+		arg.Emailed,
+		arg.Announced,
+	)
+	return err
 }
 
-const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users WHERE email = ? LIMIT 1
+const createMembershipPause = `-- name: CreateMembershipPause :one
+INSERT INTO membership_pauses (user_id, start_date, end_date, reason, created_by)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, user_id, start_date, end_date, reason, created_by, created_at, ended_at
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
-	var i User
+type CreateMembershipPauseParams struct {
+	UserID    int64        `json:"user_id"`
+	StartDate time.Time    `json:"start_date"`
+	EndDate   sql.NullTime `json:"end_date"`
+	Reason    string       `json:"reason"`
+	CreatedBy string       `json:"created_by"`
+}
+
+func (q *Queries) CreateMembershipPause(ctx context.Context, arg CreateMembershipPauseParams) (MembershipPause, error) {
+	row := q.db.QueryRowContext(ctx, createMembershipPause,
+		arg.UserID,
+		arg.StartDate,
+		arg.EndDate,
+		arg.Reason,
+		arg.CreatedBy,
+	)
+	var i MembershipPause
 	err := row.Scan(
 		&i.ID,
-		&i.KeycloakID,
-		&i.Email,
-		&i.Username,
-		&i.Realname,
-		&i.Phone,
-		&i.AltContact,
-		&i.LevelID,
-		&i.LevelActualAmount,
-		&i.PaymentsID,
-		&i.DateJoined,
-		&i.KeysGranted,
-		&i.KeysReturned,
-		&i.State,
-		&i.IsCouncil,
-		&i.IsStaff,
+		&i.UserID,
+		&i.StartDate,
+		&i.EndDate,
+		&i.Reason,
+		&i.CreatedBy,
 		&i.CreatedAt,
-		&i.UpdatedAt,
+		&i.EndedAt,
 	)
 	return i, err
 }
 
-const getUserByID = `-- name: GetUserByID :one
-SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users WHERE id = ? LIMIT 1
+const createMembershipTermination = `-- name: CreateMembershipTermination :one
+INSERT INTO membership_terminations (user_id, end_date, reason)
+VALUES (?, ?, ?)
+RETURNING id, user_id, end_date, reason, state, cancelled_by, created_at, decided_at
 `
 
-func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByID, id)
-	var i User
+type CreateMembershipTerminationParams struct {
+	UserID  int64     `json:"user_id"`
+	EndDate time.Time `json:"end_date"`
+	Reason  string    `json:"reason"`
+}
+
+func (q *Queries) CreateMembershipTermination(ctx context.Context, arg CreateMembershipTerminationParams) (MembershipTermination, error) {
+	row := q.db.QueryRowContext(ctx, createMembershipTermination, arg.UserID, arg.EndDate, arg.Reason)
+	var i MembershipTermination
 	err := row.Scan(
 		&i.ID,
-		&i.KeycloakID,
-		&i.Email,
-		&i.Username,
-		&i.Realname,
-		&i.Phone,
-		&i.AltContact,
-		&i.LevelID,
-		&i.LevelActualAmount,
-		&i.PaymentsID,
-		&i.DateJoined,
-		&i.KeysGranted,
-		&i.KeysReturned,
+		&i.UserID,
+		&i.EndDate,
+		&i.Reason,
 		&i.State,
-		&i.IsCouncil,
-		&i.IsStaff,
+		&i.CancelledBy,
 		&i.CreatedAt,
-		&i.UpdatedAt,
+		&i.DecidedAt,
 	)
 	return i, err
 }
 
-const getUserByKeycloakID = `-- name: GetUserByKeycloakID :one
-SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users WHERE keycloak_id = ? LIMIT 1
+const createPayment = `-- name: CreatePayment :one
+INSERT INTO payments (
+    user_id, date, amount, kind, kind_id,
+    local_account, remote_account, identification, raw_data, staff_comment
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification
 `
 
-func (q *Queries) GetUserByKeycloakID(ctx context.Context, keycloakID sql.NullString) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByKeycloakID, keycloakID)
-	var i User
+type CreatePaymentParams struct {
+	UserID         sql.NullInt64  `json:"user_id"`
+	Date           time.Time      `json:"date"`
+	Amount         money.Amount   `json:"amount"`
+	Kind           string         `json:"kind"`
+	KindID         string         `json:"kind_id"`
+	LocalAccount   string         `json:"local_account"`
+	RemoteAccount  string         `json:"remote_account"`
+	Identification string         `json:"identification"`
+	RawData        sql.NullString `json:"raw_data"`
+	StaffComment   sql.NullString `json:"staff_comment"`
+}
+
+func (q *Queries) CreatePayment(ctx context.Context, arg CreatePaymentParams) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, createPayment,
+		arg.UserID,
+		arg.Date,
+		arg.Amount,
+		arg.Kind,
+		arg.KindID,
+		arg.LocalAccount,
+		arg.RemoteAccount,
+		arg.Identification,
+		arg.RawData,
+		arg.StaffComment,
+	)
+	var i Payment
 	err := row.Scan(
 		&i.ID,
-		&i.KeycloakID,
-		&i.Email,
-		&i.Username,
-		&i.Realname,
-		&i.Phone,
-		&i.AltContact,
-		&i.LevelID,
-		&i.LevelActualAmount,
-		&i.PaymentsID,
-		&i.DateJoined,
-		&i.KeysGranted,
-		&i.KeysReturned,
-		&i.State,
-		&i.IsCouncil,
-		&i.IsStaff,
+		&i.UserID,
+		&i.Date,
+		&i.Amount,
+		&i.Kind,
+		&i.KindID,
+		&i.LocalAccount,
+		&i.RemoteAccount,
+		&i.Identification,
+		&i.RawData,
+		&i.StaffComment,
 		&i.CreatedAt,
-		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.DismissedAt,
+		&i.DismissedBy,
+		&i.DismissedReason,
+		&i.ReversalOf,
+		&i.ReversalReview,
+		&i.ContentHash,
+		&i.Classification,
 	)
 	return i, err
 }
 
-const getUserByPaymentsID = `-- name: GetUserByPaymentsID :one
-SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users WHERE payments_id = ? LIMIT 1
+const createPaymentDuplicate = `-- name: CreatePaymentDuplicate :exec
+INSERT INTO payment_duplicates (payment_id, duplicate_of)
+VALUES (?, ?)
+ON CONFLICT(payment_id) DO NOTHING
 `
 
-func (q *Queries) GetUserByPaymentsID(ctx context.Context, paymentsID sql.NullString) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByPaymentsID, paymentsID)
-	var i User
+type CreatePaymentDuplicateParams struct {
+	PaymentID   int64 `json:"payment_id"`
+	DuplicateOf int64 `json:"duplicate_of"`
+}
+
+func (q *Queries) CreatePaymentDuplicate(ctx context.Context, arg CreatePaymentDuplicateParams) error {
+	_, err := q.db.ExecContext(ctx, createPaymentDuplicate, arg.PaymentID, arg.DuplicateOf)
+	return err
+}
+
+const createPaymentMatchRule = `-- name: CreatePaymentMatchRule :one
+INSERT INTO payment_match_rules (
+    name, priority, remote_account, message_pattern, specific_symbol,
+    amount_min, amount_max, user_id, project_id, active, created_by
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, name, priority, remote_account, message_pattern, specific_symbol, amount_min, amount_max, user_id, project_id, active, match_count, last_matched_at, created_by, created_at, updated_at
+`
+
+type CreatePaymentMatchRuleParams struct {
+	Name           string          `json:"name"`
+	Priority       int64           `json:"priority"`
+	RemoteAccount  string          `json:"remote_account"`
+	MessagePattern string          `json:"message_pattern"`
+	SpecificSymbol string          `json:"specific_symbol"`
+	AmountMin      sql.NullFloat64 `json:"amount_min"`
+	AmountMax      sql.NullFloat64 `json:"amount_max"`
+	UserID         sql.NullInt64   `json:"user_id"`
+	ProjectID      sql.NullInt64   `json:"project_id"`
+	Active         bool            `json:"active"`
+	CreatedBy      string          `json:"created_by"`
+}
+
+func (q *Queries) CreatePaymentMatchRule(ctx context.Context, arg CreatePaymentMatchRuleParams) (PaymentMatchRule, error) {
+	row := q.db.QueryRowContext(ctx, createPaymentMatchRule,
+		arg.Name,
+		arg.Priority,
+		arg.RemoteAccount,
+		arg.MessagePattern,
+		arg.SpecificSymbol,
+		arg.AmountMin,
+		arg.AmountMax,
+		arg.UserID,
+		arg.ProjectID,
+		arg.Active,
+		arg.CreatedBy,
+	)
+	var i PaymentMatchRule
 	err := row.Scan(
 		&i.ID,
-		&i.KeycloakID,
-		&i.Email,
-		&i.Username,
-		&i.Realname,
-		&i.Phone,
-		&i.AltContact,
-		&i.LevelID,
-		&i.LevelActualAmount,
-		&i.PaymentsID,
-		&i.DateJoined,
-		&i.KeysGranted,
-		&i.KeysReturned,
-		&i.State,
-		&i.IsCouncil,
-		&i.IsStaff,
+		&i.Name,
+		&i.Priority,
+		&i.RemoteAccount,
+		&i.MessagePattern,
+		&i.SpecificSymbol,
+		&i.AmountMin,
+		&i.AmountMax,
+		&i.UserID,
+		&i.ProjectID,
+		&i.Active,
+		&i.MatchCount,
+		&i.LastMatchedAt,
+		&i.CreatedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const linkKeycloakID = `-- name: LinkKeycloakID :one
-UPDATE users SET
-    keycloak_id = ?,
-    updated_at = CURRENT_TIMESTAMP
-WHERE email = ? AND keycloak_id IS NULL
-RETURNING id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at
+const createPaymentPlan = `-- name: CreatePaymentPlan :one
+INSERT INTO payment_plans (user_id, total_debt, installment, start_date, note, created_by)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING id, user_id, total_debt, installment, start_date, note, created_by, created_at, ended_at
 `
 
-type LinkKeycloakIDParams struct {
-	KeycloakID sql.NullString `json:"keycloak_id"`
-	Email      string         `json:"email"`
+type CreatePaymentPlanParams struct {
+	UserID      int64        `json:"user_id"`
+	TotalDebt   money.Amount `json:"total_debt"`
+	Installment money.Amount `json:"installment"`
+	StartDate   time.Time    `json:"start_date"`
+	Note        string       `json:"note"`
+	CreatedBy   string       `json:"created_by"`
 }
 
-func (q *Queries) LinkKeycloakID(ctx context.Context, arg LinkKeycloakIDParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, linkKeycloakID, arg.KeycloakID, arg.Email)
-	var i User
+func (q *Queries) CreatePaymentPlan(ctx context.Context, arg CreatePaymentPlanParams) (PaymentPlan, error) {
+	row := q.db.QueryRowContext(ctx, createPaymentPlan,
+		arg.UserID,
+		arg.TotalDebt,
+		arg.Installment,
+		arg.StartDate,
+		arg.Note,
+		arg.CreatedBy,
+	)
+	var i PaymentPlan
 	err := row.Scan(
 		&i.ID,
-		&i.KeycloakID,
-		&i.Email,
-		&i.Username,
-		&i.Realname,
-		&i.Phone,
-		&i.AltContact,
-		&i.LevelID,
-		&i.LevelActualAmount,
-		&i.PaymentsID,
-		&i.DateJoined,
-		&i.KeysGranted,
-		&i.KeysReturned,
-		&i.State,
-		&i.IsCouncil,
-		&i.IsStaff,
+		&i.UserID,
+		&i.TotalDebt,
+		&i.Installment,
+		&i.StartDate,
+		&i.Note,
+		&i.CreatedBy,
 		&i.CreatedAt,
-		&i.UpdatedAt,
+		&i.EndedAt,
 	)
 	return i, err
 }
 
-const listAcceptedUsersForFees = `-- name: ListAcceptedUsersForFees :many
-SELECT u.id, u.keycloak_id, u.email, u.username, u.realname, u.phone, u.alt_contact, u.level_id, u.level_actual_amount, u.payments_id, u.date_joined, u.keys_granted, u.keys_returned, u.state, u.is_council, u.is_staff, u.created_at, u.updated_at, l.amount as level_amount
-FROM users u
-JOIN levels l ON u.level_id = l.id
-WHERE u.state = 'accepted'
-ORDER BY u.id
+const createPaymentReminder = `-- name: CreatePaymentReminder :exec
+INSERT OR IGNORE INTO payment_reminders (user_id, due_date, amount) VALUES (?, ?, ?)
 `
 
-type ListAcceptedUsersForFeesRow struct {
-	ID                int64          `json:"id"`
-	KeycloakID        sql.NullString `json:"keycloak_id"`
-	Email             string         `json:"email"`
-	Username          sql.NullString `json:"username"`
-	Realname          sql.NullString `json:"realname"`
-	Phone             sql.NullString `json:"phone"`
-	AltContact        sql.NullString `json:"alt_contact"`
-	LevelID           int64          `json:"level_id"`
-	LevelActualAmount string         `json:"level_actual_amount"`
-	PaymentsID        sql.NullString `json:"payments_id"`
-	DateJoined        time.Time      `json:"date_joined"`
-	KeysGranted       sql.NullTime   `json:"keys_granted"`
-	KeysReturned      sql.NullTime   `json:"keys_returned"`
-	State             string         `json:"state"`
-	IsCouncil         bool           `json:"is_council"`
-	IsStaff           bool           `json:"is_staff"`
-	CreatedAt         time.Time      `json:"created_at"`
-	UpdatedAt         time.Time      `json:"updated_at"`
-	LevelAmount       string         `json:"level_amount"`
+type CreatePaymentReminderParams struct {
+	UserID  int64        `json:"user_id"`
+	DueDate string       `json:"due_date"`
+	Amount  money.Amount `json:"amount"`
 }
 
-func (q *Queries) ListAcceptedUsersForFees(ctx context.Context) ([]ListAcceptedUsersForFeesRow, error) {
-	rows, err := q.db.QueryContext(ctx, listAcceptedUsersForFees)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []ListAcceptedUsersForFeesRow{}
-	for rows.Next() {
-		var i ListAcceptedUsersForFeesRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.KeycloakID,
-			&i.Email,
-			&i.Username,
-			&i.Realname,
-			&i.Phone,
-			&i.AltContact,
-			&i.LevelID,
-			&i.LevelActualAmount,
-			&i.PaymentsID,
-			&i.DateJoined,
-			&i.KeysGranted,
-			&i.KeysReturned,
-			&i.State,
-			&i.IsCouncil,
-			&i.IsStaff,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.LevelAmount,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) CreatePaymentReminder(ctx context.Context, arg CreatePaymentReminderParams) error {
+	_, err := q.db.ExecContext(ctx, createPaymentReminder, arg.UserID, arg.DueDate, arg.Amount)
+	return err
 }
 
-const listAllLevels = `-- name: ListAllLevels :many
-SELECT id, name, amount, active, created_at FROM levels ORDER BY amount
+const createPaymentSplit = `-- name: CreatePaymentSplit :one
+INSERT INTO payment_splits (payment_id, user_id, project_id, amount, note, created_by, classification)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+RETURNING id, payment_id, user_id, project_id, amount, note, created_by, created_at, classification
 `
 
-func (q *Queries) ListAllLevels(ctx context.Context) ([]Level, error) {
-	rows, err := q.db.QueryContext(ctx, listAllLevels)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []Level{}
-	for rows.Next() {
-		var i Level
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.Amount,
-			&i.Active,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type CreatePaymentSplitParams struct {
+	PaymentID      int64         `json:"payment_id"`
+	UserID         sql.NullInt64 `json:"user_id"`
+	ProjectID      sql.NullInt64 `json:"project_id"`
+	Amount         money.Amount  `json:"amount"`
+	Note           string        `json:"note"`
+	CreatedBy      string        `json:"created_by"`
+	Classification string        `json:"classification"`
 }
 
-const listDismissedPayments = `-- name: ListDismissedPayments :many
-SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason FROM payments WHERE dismissed_at IS NOT NULL ORDER BY dismissed_at DESC
-`
-
-func (q *Queries) ListDismissedPayments(ctx context.Context) ([]Payment, error) {
-	rows, err := q.db.QueryContext(ctx, listDismissedPayments)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+func (q *Queries) CreatePaymentSplit(ctx context.Context, arg CreatePaymentSplitParams) (PaymentSplit, error) {
+	row := q.db.QueryRowContext(ctx, createPaymentSplit,
+		arg.PaymentID,
+		arg.UserID,
+		arg.ProjectID,
+		arg.Amount,
+		arg.Note,
+		arg.CreatedBy,
+		arg.Classification,
+	)
+	var i PaymentSplit
+	err := row.Scan(
+		&i.ID,
+		&i.PaymentID,
+		&i.UserID,
+		&i.ProjectID,
+		&i.Amount,
+		&i.Note,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.Classification,
+	)
+	return i, err
+}
+
+const createPaymentStatement = `-- name: CreatePaymentStatement :exec
+INSERT OR IGNORE INTO payment_statements (user_id, year) VALUES (?, ?)
+`
+
+type CreatePaymentStatementParams struct {
+	UserID int64 `json:"user_id"`
+	Year   int64 `json:"year"`
+}
+
+func (q *Queries) CreatePaymentStatement(ctx context.Context, arg CreatePaymentStatementParams) error {
+	_, err := q.db.ExecContext(ctx, createPaymentStatement, arg.UserID, arg.Year)
+	return err
+}
+
+const createProject = `-- name: CreateProject :one
+INSERT INTO projects (name, payments_id, description)
+VALUES (?, ?, ?)
+RETURNING id, name, payments_id, description, public, btcpay, goal, deadline, target_reached_at
+`
+
+type CreateProjectParams struct {
+	Name        string         `json:"name"`
+	PaymentsID  sql.NullString `json:"payments_id"`
+	Description sql.NullString `json:"description"`
+}
+
+func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (Project, error) {
+	row := q.db.QueryRowContext(ctx, createProject, arg.Name, arg.PaymentsID, arg.Description)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.PaymentsID,
+		&i.Description,
+		&i.Public,
+		&i.Btcpay,
+		&i.Goal,
+		&i.Deadline,
+		&i.TargetReachedAt,
+	)
+	return i, err
+}
+
+const createReimbursement = `-- name: CreateReimbursement :one
+INSERT INTO reimbursements (user_id, amount, description, account)
+VALUES (?, ?, ?, ?)
+RETURNING id, user_id, state, amount, description, account, admin_comment, decided_by, decided_at, batch_id, payment_id, paid_at, created_at
+`
+
+type CreateReimbursementParams struct {
+	UserID      int64        `json:"user_id"`
+	Amount      money.Amount `json:"amount"`
+	Description string       `json:"description"`
+	Account     string       `json:"account"`
+}
+
+func (q *Queries) CreateReimbursement(ctx context.Context, arg CreateReimbursementParams) (Reimbursement, error) {
+	row := q.db.QueryRowContext(ctx, createReimbursement,
+		arg.UserID,
+		arg.Amount,
+		arg.Description,
+		arg.Account,
+	)
+	var i Reimbursement
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.State,
+		&i.Amount,
+		&i.Description,
+		&i.Account,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.BatchID,
+		&i.PaymentID,
+		&i.PaidAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createReimbursementBatch = `-- name: CreateReimbursementBatch :one
+INSERT INTO reimbursement_batches (created_by, total, xml)
+VALUES (?, ?, ?)
+RETURNING id, created_by, total, xml, created_at
+`
+
+type CreateReimbursementBatchParams struct {
+	CreatedBy string       `json:"created_by"`
+	Total     money.Amount `json:"total"`
+	Xml       string       `json:"xml"`
+}
+
+func (q *Queries) CreateReimbursementBatch(ctx context.Context, arg CreateReimbursementBatchParams) (ReimbursementBatch, error) {
+	row := q.db.QueryRowContext(ctx, createReimbursementBatch, arg.CreatedBy, arg.Total, arg.Xml)
+	var i ReimbursementBatch
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedBy,
+		&i.Total,
+		&i.Xml,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createReimbursementReceipt = `-- name: CreateReimbursementReceipt :exec
+INSERT INTO reimbursement_receipts (reimbursement_id, filename, content_type, data)
+VALUES (?, ?, ?, ?)
+`
+
+type CreateReimbursementReceiptParams struct {
+	ReimbursementID int64  `json:"reimbursement_id"`
+	Filename        string `json:"filename"`
+	ContentType     string `json:"content_type"`
+	Data            []byte `json:"data"`
+}
+
+func (q *Queries) CreateReimbursementReceipt(ctx context.Context, arg CreateReimbursementReceiptParams) error {
+	_, err := q.db.ExecContext(ctx, createReimbursementReceipt,
+		arg.ReimbursementID,
+		arg.Filename,
+		arg.ContentType,
+		arg.Data,
+	)
+	return err
+}
+
+const createTicket = `-- name: CreateTicket :one
+INSERT INTO tickets (user_id, email, subject, source)
+VALUES (?, ?, ?, ?)
+RETURNING id, user_id, email, subject, state, source, created_at, updated_at
+`
+
+type CreateTicketParams struct {
+	UserID  sql.NullInt64 `json:"user_id"`
+	Email   string        `json:"email"`
+	Subject string        `json:"subject"`
+	Source  string        `json:"source"`
+}
+
+func (q *Queries) CreateTicket(ctx context.Context, arg CreateTicketParams) (Ticket, error) {
+	row := q.db.QueryRowContext(ctx, createTicket,
+		arg.UserID,
+		arg.Email,
+		arg.Subject,
+		arg.Source,
+	)
+	var i Ticket
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Email,
+		&i.Subject,
+		&i.State,
+		&i.Source,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createTicketMessage = `-- name: CreateTicketMessage :one
+INSERT INTO ticket_messages (ticket_id, direction, author, body, message_id)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, ticket_id, direction, author, body, message_id, created_at
+`
+
+type CreateTicketMessageParams struct {
+	TicketID  int64          `json:"ticket_id"`
+	Direction string         `json:"direction"`
+	Author    string         `json:"author"`
+	Body      string         `json:"body"`
+	MessageID sql.NullString `json:"message_id"`
+}
+
+func (q *Queries) CreateTicketMessage(ctx context.Context, arg CreateTicketMessageParams) (TicketMessage, error) {
+	row := q.db.QueryRowContext(ctx, createTicketMessage,
+		arg.TicketID,
+		arg.Direction,
+		arg.Author,
+		arg.Body,
+		arg.MessageID,
+	)
+	var i TicketMessage
+	err := row.Scan(
+		&i.ID,
+		&i.TicketID,
+		&i.Direction,
+		&i.Author,
+		&i.Body,
+		&i.MessageID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (
+    keycloak_id, email, username, realname, phone, alt_contact,
+    level_id, level_actual_amount, payments_id, state,
+    is_council, is_staff
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at
+`
+
+type CreateUserParams struct {
+	KeycloakID        sql.NullString `json:"keycloak_id"`
+	Email             string         `json:"email"`
+	Username          sql.NullString `json:"username"`
+	Realname          sql.NullString `json:"realname"`
+	Phone             sql.NullString `json:"phone"`
+	AltContact        sql.NullString `json:"alt_contact"`
+	LevelID           int64          `json:"level_id"`
+	LevelActualAmount money.Amount   `json:"level_actual_amount"`
+	PaymentsID        sql.NullString `json:"payments_id"`
+	State             string         `json:"state"`
+	IsCouncil         bool           `json:"is_council"`
+	IsStaff           bool           `json:"is_staff"`
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, createUser,
+		arg.KeycloakID,
+		arg.Email,
+		arg.Username,
+		arg.Realname,
+		arg.Phone,
+		arg.AltContact,
+		arg.LevelID,
+		arg.LevelActualAmount,
+		arg.PaymentsID,
+		arg.State,
+		arg.IsCouncil,
+		arg.IsStaff,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.KeycloakID,
+		&i.Email,
+		&i.Username,
+		&i.Realname,
+		&i.Phone,
+		&i.AltContact,
+		&i.LevelID,
+		&i.LevelActualAmount,
+		&i.PaymentsID,
+		&i.DateJoined,
+		&i.KeysGranted,
+		&i.KeysReturned,
+		&i.State,
+		&i.IsCouncil,
+		&i.IsStaff,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createUserAnonymization = `-- name: CreateUserAnonymization :exec
+INSERT INTO user_anonymizations (user_id, anonymized_by, summary)
+VALUES (?, ?, ?)
+`
+
+type CreateUserAnonymizationParams struct {
+	UserID       int64  `json:"user_id"`
+	AnonymizedBy string `json:"anonymized_by"`
+	Summary      string `json:"summary"`
+}
+
+func (q *Queries) CreateUserAnonymization(ctx context.Context, arg CreateUserAnonymizationParams) error {
+	_, err := q.db.ExecContext(ctx, createUserAnonymization, arg.UserID, arg.AnonymizedBy, arg.Summary)
+	return err
+}
+
+const createUserNote = `-- name: CreateUserNote :one
+INSERT INTO user_notes (user_id, author, body, pinned)
+VALUES (?, ?, ?, ?)
+RETURNING id, user_id, author, body, pinned, created_at, updated_at
+`
+
+type CreateUserNoteParams struct {
+	UserID int64  `json:"user_id"`
+	Author string `json:"author"`
+	Body   string `json:"body"`
+	Pinned bool   `json:"pinned"`
+}
+
+func (q *Queries) CreateUserNote(ctx context.Context, arg CreateUserNoteParams) (UserNote, error) {
+	row := q.db.QueryRowContext(ctx, createUserNote,
+		arg.UserID,
+		arg.Author,
+		arg.Body,
+		arg.Pinned,
+	)
+	var i UserNote
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Author,
+		&i.Body,
+		&i.Pinned,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const decidePaymentDuplicate = `-- name: DecidePaymentDuplicate :execrows
+UPDATE payment_duplicates SET
+    state = ?,
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE payment_id = ? AND state = 'suspected'
+`
+
+type DecidePaymentDuplicateParams struct {
+	State     string         `json:"state"`
+	DecidedBy sql.NullString `json:"decided_by"`
+	PaymentID int64          `json:"payment_id"`
+}
+
+func (q *Queries) DecidePaymentDuplicate(ctx context.Context, arg DecidePaymentDuplicateParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, decidePaymentDuplicate, arg.State, arg.DecidedBy, arg.PaymentID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteApplicationVouch = `-- name: DeleteApplicationVouch :execrows
+DELETE FROM application_vouches WHERE application_id = ? AND user_id = ?
+`
+
+type DeleteApplicationVouchParams struct {
+	ApplicationID int64 `json:"application_id"`
+	UserID        int64 `json:"user_id"`
+}
+
+func (q *Queries) DeleteApplicationVouch(ctx context.Context, arg DeleteApplicationVouchParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteApplicationVouch, arg.ApplicationID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteAuthSessionsByKeycloakID = `-- name: DeleteAuthSessionsByKeycloakID :execrows
+DELETE FROM auth_sessions WHERE keycloak_id = ?
+`
+
+func (q *Queries) DeleteAuthSessionsByKeycloakID(ctx context.Context, keycloakID string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAuthSessionsByKeycloakID, keycloakID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteBalanceSnapshotsByUser = `-- name: DeleteBalanceSnapshotsByUser :exec
+DELETE FROM balance_snapshots WHERE user_id = ?
+`
+
+func (q *Queries) DeleteBalanceSnapshotsByUser(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteBalanceSnapshotsByUser, userID)
+	return err
+}
+
+const deleteBillingDetails = `-- name: DeleteBillingDetails :execrows
+DELETE FROM billing_details WHERE user_id = ?
+`
+
+func (q *Queries) DeleteBillingDetails(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteBillingDetails, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteCharge = `-- name: DeleteCharge :exec
+DELETE FROM charges WHERE id = ?
+`
+
+func (q *Queries) DeleteCharge(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteCharge, id)
+	return err
+}
+
+const deleteDebtThresholds = `-- name: DeleteDebtThresholds :exec
+DELETE FROM debt_thresholds
+`
+
+func (q *Queries) DeleteDebtThresholds(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteDebtThresholds)
+	return err
+}
+
+const deleteExpiredWebSessions = `-- name: DeleteExpiredWebSessions :execrows
+DELETE FROM web_sessions WHERE expires_at <= ?
+`
+
+func (q *Queries) DeleteExpiredWebSessions(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredWebSessions, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFee = `-- name: DeleteFee :exec
+DELETE FROM fees WHERE id = ?
+`
+
+func (q *Queries) DeleteFee(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteFee, id)
+	return err
+}
+
+const deleteLevelPriceChange = `-- name: DeleteLevelPriceChange :execrows
+DELETE FROM level_price_changes WHERE id = ? AND applied_at IS NULL
+`
+
+func (q *Queries) DeleteLevelPriceChange(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteLevelPriceChange, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePaymentMatchRule = `-- name: DeletePaymentMatchRule :execrows
+DELETE FROM payment_match_rules WHERE id = ?
+`
+
+func (q *Queries) DeletePaymentMatchRule(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePaymentMatchRule, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePaymentSplits = `-- name: DeletePaymentSplits :execrows
+DELETE FROM payment_splits WHERE payment_id = ?
+`
+
+func (q *Queries) DeletePaymentSplits(ctx context.Context, paymentID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePaymentSplits, paymentID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteProject = `-- name: DeleteProject :exec
+DELETE FROM projects WHERE id = ?
+`
+
+func (q *Queries) DeleteProject(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteProject, id)
+	return err
+}
+
+const deleteProjectMember = `-- name: DeleteProjectMember :exec
+DELETE FROM project_members WHERE project_id = ? AND user_id = ?
+`
+
+type DeleteProjectMemberParams struct {
+	ProjectID int64 `json:"project_id"`
+	UserID    int64 `json:"user_id"`
+}
+
+func (q *Queries) DeleteProjectMember(ctx context.Context, arg DeleteProjectMemberParams) error {
+	_, err := q.db.ExecContext(ctx, deleteProjectMember, arg.ProjectID, arg.UserID)
+	return err
+}
+
+const deleteProjectWallEntry = `-- name: DeleteProjectWallEntry :exec
+DELETE FROM project_wall_entries WHERE project_id = ? AND user_id = ?
+`
+
+type DeleteProjectWallEntryParams struct {
+	ProjectID int64 `json:"project_id"`
+	UserID    int64 `json:"user_id"`
+}
+
+func (q *Queries) DeleteProjectWallEntry(ctx context.Context, arg DeleteProjectWallEntryParams) error {
+	_, err := q.db.ExecContext(ctx, deleteProjectWallEntry, arg.ProjectID, arg.UserID)
+	return err
+}
+
+const deleteStaleAuthSessions = `-- name: DeleteStaleAuthSessions :execrows
+DELETE FROM auth_sessions WHERE updated_at < datetime('now', '-30 days')
+`
+
+// Sessions untouched for 30 days are far past the cookie lifetime (7 days)
+func (q *Queries) DeleteStaleAuthSessions(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteStaleAuthSessions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteSuspensionExemption = `-- name: DeleteSuspensionExemption :execrows
+DELETE FROM suspension_exemptions WHERE user_id = ?
+`
+
+func (q *Queries) DeleteSuspensionExemption(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteSuspensionExemption, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users WHERE id = ?
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const deleteUserAPITokens = `-- name: DeleteUserAPITokens :execrows
+DELETE FROM api_tokens WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserAPITokens(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserAPITokens, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserCampaignRecipients = `-- name: DeleteUserCampaignRecipients :exec
+DELETE FROM email_campaign_recipients WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserCampaignRecipients(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserCampaignRecipients, userID)
+	return err
+}
+
+const deleteUserNote = `-- name: DeleteUserNote :execrows
+DELETE FROM user_notes WHERE id = ? AND user_id = ?
+`
+
+type DeleteUserNoteParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) DeleteUserNote(ctx context.Context, arg DeleteUserNoteParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserNote, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserNotes = `-- name: DeleteUserNotes :execrows
+DELETE FROM user_notes WHERE user_id = ?
+`
+
+// Notes are free text about the member, anonymization drops them
+func (q *Queries) DeleteUserNotes(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserNotes, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserPaymentMatchRules = `-- name: DeleteUserPaymentMatchRules :execrows
+DELETE FROM payment_match_rules WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserPaymentMatchRules(ctx context.Context, userID sql.NullInt64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserPaymentMatchRules, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserPriceChangeNotices = `-- name: DeleteUserPriceChangeNotices :exec
+DELETE FROM level_price_change_notices WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserPriceChangeNotices(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserPriceChangeNotices, userID)
+	return err
+}
+
+const deleteUserProjectWallEntries = `-- name: DeleteUserProjectWallEntries :execrows
+DELETE FROM project_wall_entries WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserProjectWallEntries(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserProjectWallEntries, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteWebSession = `-- name: DeleteWebSession :exec
+DELETE FROM web_sessions WHERE id = ?
+`
+
+func (q *Queries) DeleteWebSession(ctx context.Context, id string) error {
+	_, err := q.db.ExecContext(ctx, deleteWebSession, id)
+	return err
+}
+
+const disableBillingFakturoid = `-- name: DisableBillingFakturoid :execrows
+UPDATE billing_details SET fakturoid = FALSE, fakturoid_since = NULL
+WHERE user_id = ? AND fakturoid
+`
+
+func (q *Queries) DisableBillingFakturoid(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, disableBillingFakturoid, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const dismissPayment = `-- name: DismissPayment :one
+UPDATE payments SET
+    dismissed_at = CURRENT_TIMESTAMP,
+    dismissed_by = ?,
+    dismissed_reason = ?,
+    staff_comment = ?
+WHERE id = ?
+RETURNING id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification
+`
+
+type DismissPaymentParams struct {
+	DismissedBy     interface{}    `json:"dismissed_by"`
+	DismissedReason interface{}    `json:"dismissed_reason"`
+	StaffComment    sql.NullString `json:"staff_comment"`
+	ID              int64          `json:"id"`
+}
+
+func (q *Queries) DismissPayment(ctx context.Context, arg DismissPaymentParams) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, dismissPayment,
+		arg.DismissedBy,
+		arg.DismissedReason,
+		arg.StaffComment,
+		arg.ID,
+	)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Date,
+		&i.Amount,
+		&i.Kind,
+		&i.KindID,
+		&i.LocalAccount,
+		&i.RemoteAccount,
+		&i.Identification,
+		&i.RawData,
+		&i.StaffComment,
+		&i.CreatedAt,
+		&i.ProjectID,
+		&i.DismissedAt,
+		&i.DismissedBy,
+		&i.DismissedReason,
+		&i.ReversalOf,
+		&i.ReversalReview,
+		&i.ContentHash,
+		&i.Classification,
+	)
+	return i, err
+}
+
+const enableBillingFakturoid = `-- name: EnableBillingFakturoid :execrows
+UPDATE billing_details SET fakturoid = TRUE, fakturoid_since = CURRENT_TIMESTAMP
+WHERE user_id = ? AND NOT fakturoid
+`
+
+// Fees created from now on are invoiced in Fakturoid
+func (q *Queries) EnableBillingFakturoid(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, enableBillingFakturoid, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const endMembershipPause = `-- name: EndMembershipPause :exec
+UPDATE membership_pauses SET ended_at = CURRENT_TIMESTAMP WHERE id = ? AND ended_at IS NULL
+`
+
+func (q *Queries) EndMembershipPause(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, endMembershipPause, id)
+	return err
+}
+
+const endMembershipPauses = `-- name: EndMembershipPauses :exec
+UPDATE membership_pauses SET ended_at = CURRENT_TIMESTAMP WHERE user_id = ? AND ended_at IS NULL
+`
+
+// Ends the member's open pause (cleared by an admin or replaced by a new one)
+func (q *Queries) EndMembershipPauses(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, endMembershipPauses, userID)
+	return err
+}
+
+const endPaymentPlans = `-- name: EndPaymentPlans :exec
+UPDATE payment_plans SET ended_at = CURRENT_TIMESTAMP
+WHERE user_id = ? AND ended_at IS NULL
+`
+
+// Ends the member's active plan (completed, replaced or cancelled)
+func (q *Queries) EndPaymentPlans(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, endPaymentPlans, userID)
+	return err
+}
+
+const findDuplicatePayment = `-- name: FindDuplicatePayment :one
+SELECT p.id, p.user_id, p.date, p.amount, p.kind, p.kind_id, p.local_account, p.remote_account, p.identification, p.raw_data, p.staff_comment, p.created_at, p.project_id, p.dismissed_at, p.dismissed_by, p.dismissed_reason, p.reversal_of, p.reversal_review, p.content_hash, p.classification FROM payments p
+WHERE p.content_hash = ?1 AND p.kind != ?2
+AND NOT EXISTS (SELECT 1 FROM payment_duplicates d WHERE d.payment_id = p.id AND d.state != 'ignored')
+AND NOT EXISTS (
+    SELECT 1 FROM payment_duplicates d
+    JOIN payments dp ON dp.id = d.payment_id
+    WHERE d.duplicate_of = p.id AND d.state != 'ignored' AND dp.kind = ?2
+)
+ORDER BY p.id
+LIMIT 1
+`
+
+type FindDuplicatePaymentParams struct {
+	ContentHash string `json:"content_hash"`
+	Kind        string `json:"kind"`
+}
+
+// The oldest payment with the same content from another source that is not a
+// duplicate itself and has no duplicate from this source yet
+func (q *Queries) FindDuplicatePayment(ctx context.Context, arg FindDuplicatePaymentParams) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, findDuplicatePayment, arg.ContentHash, arg.Kind)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Date,
+		&i.Amount,
+		&i.Kind,
+		&i.KindID,
+		&i.LocalAccount,
+		&i.RemoteAccount,
+		&i.Identification,
+		&i.RawData,
+		&i.StaffComment,
+		&i.CreatedAt,
+		&i.ProjectID,
+		&i.DismissedAt,
+		&i.DismissedBy,
+		&i.DismissedReason,
+		&i.ReversalOf,
+		&i.ReversalReview,
+		&i.ContentHash,
+		&i.Classification,
+	)
+	return i, err
+}
+
+const flagPaymentReversalReview = `-- name: FlagPaymentReversalReview :one
+UPDATE payments SET reversal_review = TRUE WHERE id = ? RETURNING id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification
+`
+
+func (q *Queries) FlagPaymentReversalReview(ctx context.Context, id int64) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, flagPaymentReversalReview, id)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Date,
+		&i.Amount,
+		&i.Kind,
+		&i.KindID,
+		&i.LocalAccount,
+		&i.RemoteAccount,
+		&i.Identification,
+		&i.RawData,
+		&i.StaffComment,
+		&i.CreatedAt,
+		&i.ProjectID,
+		&i.DismissedAt,
+		&i.DismissedBy,
+		&i.DismissedReason,
+		&i.ReversalOf,
+		&i.ReversalReview,
+		&i.ContentHash,
+		&i.Classification,
+	)
+	return i, err
+}
+
+const getAPITokenByHash = `-- name: GetAPITokenByHash :one
+SELECT id, user_id, name, token_hash, token_prefix, scopes, expires_at, last_used_at, revoked_at, created_at FROM api_tokens WHERE token_hash = ? AND revoked_at IS NULL
+`
+
+func (q *Queries) GetAPITokenByHash(ctx context.Context, tokenHash string) (ApiToken, error) {
+	row := q.db.QueryRowContext(ctx, getAPITokenByHash, tokenHash)
+	var i ApiToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.TokenHash,
+		&i.TokenPrefix,
+		&i.Scopes,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAccountPaymentMatchRule = `-- name: GetAccountPaymentMatchRule :one
+SELECT id, name, priority, remote_account, message_pattern, specific_symbol, amount_min, amount_max, user_id, project_id, active, match_count, last_matched_at, created_by, created_at, updated_at FROM payment_match_rules
+WHERE remote_account = ? AND message_pattern = '' AND specific_symbol = ''
+  AND amount_min IS NULL AND amount_max IS NULL
+ORDER BY id LIMIT 1
+`
+
+// Rule with only the sender account condition (remembered account)
+func (q *Queries) GetAccountPaymentMatchRule(ctx context.Context, remoteAccount string) (PaymentMatchRule, error) {
+	row := q.db.QueryRowContext(ctx, getAccountPaymentMatchRule, remoteAccount)
+	var i PaymentMatchRule
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Priority,
+		&i.RemoteAccount,
+		&i.MessagePattern,
+		&i.SpecificSymbol,
+		&i.AmountMin,
+		&i.AmountMax,
+		&i.UserID,
+		&i.ProjectID,
+		&i.Active,
+		&i.MatchCount,
+		&i.LastMatchedAt,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getActivePaymentPlan = `-- name: GetActivePaymentPlan :one
+SELECT id, user_id, total_debt, installment, start_date, note, created_by, created_at, ended_at FROM payment_plans
+WHERE user_id = ? AND ended_at IS NULL
+ORDER BY id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetActivePaymentPlan(ctx context.Context, userID int64) (PaymentPlan, error) {
+	row := q.db.QueryRowContext(ctx, getActivePaymentPlan, userID)
+	var i PaymentPlan
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TotalDebt,
+		&i.Installment,
+		&i.StartDate,
+		&i.Note,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.EndedAt,
+	)
+	return i, err
+}
+
+const getApplication = `-- name: GetApplication :one
+SELECT id, realname, email, motivation, level_id, state, user_id, admin_comment, decided_by, decided_at, created_at FROM applications WHERE id = ?
+`
+
+func (q *Queries) GetApplication(ctx context.Context, id int64) (Application, error) {
+	row := q.db.QueryRowContext(ctx, getApplication, id)
+	var i Application
+	err := row.Scan(
+		&i.ID,
+		&i.Realname,
+		&i.Email,
+		&i.Motivation,
+		&i.LevelID,
+		&i.State,
+		&i.UserID,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAuthSession = `-- name: GetAuthSession :one
+SELECT sid, keycloak_id, id_token, created_at, updated_at, revoked_at FROM auth_sessions WHERE sid = ?
+`
+
+func (q *Queries) GetAuthSession(ctx context.Context, sid string) (AuthSession, error) {
+	row := q.db.QueryRowContext(ctx, getAuthSession, sid)
+	var i AuthSession
+	err := row.Scan(
+		&i.Sid,
+		&i.KeycloakID,
+		&i.IDToken,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getBillingDetails = `-- name: GetBillingDetails :one
+SELECT user_id, company_name, company_id, vat_id, address, updated_at, fakturoid, fakturoid_since, fakturoid_subject_id FROM billing_details WHERE user_id = ?
+`
+
+func (q *Queries) GetBillingDetails(ctx context.Context, userID int64) (BillingDetail, error) {
+	row := q.db.QueryRowContext(ctx, getBillingDetails, userID)
+	var i BillingDetail
+	err := row.Scan(
+		&i.UserID,
+		&i.CompanyName,
+		&i.CompanyID,
+		&i.VatID,
+		&i.Address,
+		&i.UpdatedAt,
+		&i.Fakturoid,
+		&i.FakturoidSince,
+		&i.FakturoidSubjectID,
+	)
+	return i, err
+}
+
+const getCharge = `-- name: GetCharge :one
+SELECT id, user_id, description, amount, category, date, created_by, created_at FROM charges WHERE id = ?
+`
+
+func (q *Queries) GetCharge(ctx context.Context, id int64) (Charge, error) {
+	row := q.db.QueryRowContext(ctx, getCharge, id)
+	var i Charge
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Description,
+		&i.Amount,
+		&i.Category,
+		&i.Date,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getDistinctLevels = `-- name: GetDistinctLevels :many
+SELECT DISTINCT level FROM system_logs ORDER BY level
+`
+
+func (q *Queries) GetDistinctLevels(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getDistinctLevels)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var level string
+		if err := rows.Scan(&level); err != nil {
+			return nil, err
+		}
+		items = append(items, level)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDistinctSubsystems = `-- name: GetDistinctSubsystems :many
+SELECT DISTINCT subsystem FROM system_logs ORDER BY subsystem
+`
+
+func (q *Queries) GetDistinctSubsystems(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getDistinctSubsystems)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var subsystem string
+		if err := rows.Scan(&subsystem); err != nil {
+			return nil, err
+		}
+		items = append(items, subsystem)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEmailCampaign = `-- name: GetEmailCampaign :one
+SELECT id, name, subject, template_name, body, audience, status, created_by, created_at, completed_at FROM email_campaigns WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetEmailCampaign(ctx context.Context, id int64) (EmailCampaign, error) {
+	row := q.db.QueryRowContext(ctx, getEmailCampaign, id)
+	var i EmailCampaign
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Subject,
+		&i.TemplateName,
+		&i.Body,
+		&i.Audience,
+		&i.Status,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const getEmailCampaignStats = `-- name: GetEmailCampaignStats :many
+SELECT status, COUNT(*) AS count
+FROM email_campaign_recipients
+WHERE campaign_id = ?
+GROUP BY status
+`
+
+type GetEmailCampaignStatsRow struct {
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+func (q *Queries) GetEmailCampaignStats(ctx context.Context, campaignID int64) ([]GetEmailCampaignStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEmailCampaignStats, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetEmailCampaignStatsRow{}
+	for rows.Next() {
+		var i GetEmailCampaignStatsRow
+		if err := rows.Scan(&i.Status, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpense = `-- name: GetExpense :one
+SELECT id, kind, kind_id, date, amount, remote_account, remote_name, identification, message, category, project_id, staff_comment, raw_data, created_at FROM expenses WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetExpense(ctx context.Context, id int64) (Expense, error) {
+	row := q.db.QueryRowContext(ctx, getExpense, id)
+	var i Expense
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.KindID,
+		&i.Date,
+		&i.Amount,
+		&i.RemoteAccount,
+		&i.RemoteName,
+		&i.Identification,
+		&i.Message,
+		&i.Category,
+		&i.ProjectID,
+		&i.StaffComment,
+		&i.RawData,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getFakturoidInvoice = `-- name: GetFakturoidInvoice :one
+SELECT fee_id, user_id, fakturoid_id, number, status, public_url, paid_on, created_at, synced_at FROM fakturoid_invoices WHERE fee_id = ?
+`
+
+func (q *Queries) GetFakturoidInvoice(ctx context.Context, feeID int64) (FakturoidInvoice, error) {
+	row := q.db.QueryRowContext(ctx, getFakturoidInvoice, feeID)
+	var i FakturoidInvoice
+	err := row.Scan(
+		&i.FeeID,
+		&i.UserID,
+		&i.FakturoidID,
+		&i.Number,
+		&i.Status,
+		&i.PublicUrl,
+		&i.PaidOn,
+		&i.CreatedAt,
+		&i.SyncedAt,
+	)
+	return i, err
+}
+
+const getFee = `-- name: GetFee :one
+SELECT id, user_id, level_id, period_start, amount, created_at, months FROM fees WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetFee(ctx context.Context, id int64) (Fee, error) {
+	row := q.db.QueryRowContext(ctx, getFee, id)
+	var i Fee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.LevelID,
+		&i.PeriodStart,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Months,
+	)
+	return i, err
+}
+
+const getFeeAdjustmentTotal = `-- name: GetFeeAdjustmentTotal :one
+SELECT CAST(COALESCE(SUM(amount), 0) AS INTEGER) as total FROM balance_adjustments WHERE fee_id = ?
+`
+
+// Sum of the adjustments of a fee (positive = credited to the member)
+func (q *Queries) GetFeeAdjustmentTotal(ctx context.Context, feeID sql.NullInt64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getFeeAdjustmentTotal, feeID)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const getFeeByUserAndPeriod = `-- name: GetFeeByUserAndPeriod :one
+SELECT id, user_id, level_id, period_start, amount, created_at, months FROM fees WHERE user_id = ? AND period_start = ? LIMIT 1
+`
+
+type GetFeeByUserAndPeriodParams struct {
+	UserID      int64     `json:"user_id"`
+	PeriodStart time.Time `json:"period_start"`
+}
+
+func (q *Queries) GetFeeByUserAndPeriod(ctx context.Context, arg GetFeeByUserAndPeriodParams) (Fee, error) {
+	row := q.db.QueryRowContext(ctx, getFeeByUserAndPeriod, arg.UserID, arg.PeriodStart)
+	var i Fee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.LevelID,
+		&i.PeriodStart,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Months,
+	)
+	return i, err
+}
+
+const getInvoice = `-- name: GetInvoice :one
+SELECT id, user_id, state, number, months, amount, company_name, company_id, vat_id, address, note, admin_comment, decided_by, issued_at, due_at, payment_id, paid_at, created_at, fee_id, charge_id, item FROM invoices WHERE id = ?
+`
+
+func (q *Queries) GetInvoice(ctx context.Context, id int64) (Invoice, error) {
+	row := q.db.QueryRowContext(ctx, getInvoice, id)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.State,
+		&i.Number,
+		&i.Months,
+		&i.Amount,
+		&i.CompanyName,
+		&i.CompanyID,
+		&i.VatID,
+		&i.Address,
+		&i.Note,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.IssuedAt,
+		&i.DueAt,
+		&i.PaymentID,
+		&i.PaidAt,
+		&i.CreatedAt,
+		&i.FeeID,
+		&i.ChargeID,
+		&i.Item,
+	)
+	return i, err
+}
+
+const getInvoiceByNumber = `-- name: GetInvoiceByNumber :one
+SELECT id, user_id, state, number, months, amount, company_name, company_id, vat_id, address, note, admin_comment, decided_by, issued_at, due_at, payment_id, paid_at, created_at, fee_id, charge_id, item FROM invoices WHERE number = ?
+`
+
+func (q *Queries) GetInvoiceByNumber(ctx context.Context, number sql.NullString) (Invoice, error) {
+	row := q.db.QueryRowContext(ctx, getInvoiceByNumber, number)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.State,
+		&i.Number,
+		&i.Months,
+		&i.Amount,
+		&i.CompanyName,
+		&i.CompanyID,
+		&i.VatID,
+		&i.Address,
+		&i.Note,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.IssuedAt,
+		&i.DueAt,
+		&i.PaymentID,
+		&i.PaidAt,
+		&i.CreatedAt,
+		&i.FeeID,
+		&i.ChargeID,
+		&i.Item,
+	)
+	return i, err
+}
+
+const getLastFeeByUser = `-- name: GetLastFeeByUser :one
+SELECT id, user_id, level_id, period_start, amount, created_at, months FROM fees WHERE user_id = ? ORDER BY period_start DESC, id DESC LIMIT 1
+`
+
+func (q *Queries) GetLastFeeByUser(ctx context.Context, userID int64) (Fee, error) {
+	row := q.db.QueryRowContext(ctx, getLastFeeByUser, userID)
+	var i Fee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.LevelID,
+		&i.PeriodStart,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Months,
+	)
+	return i, err
+}
+
+const getLevel = `-- name: GetLevel :one
+SELECT id, name, amount, active, created_at, btcpay FROM levels WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetLevel(ctx context.Context, id int64) (Level, error) {
+	row := q.db.QueryRowContext(ctx, getLevel, id)
+	var i Level
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Amount,
+		&i.Active,
+		&i.CreatedAt,
+		&i.Btcpay,
+	)
+	return i, err
+}
+
+const getLevelChangeRequest = `-- name: GetLevelChangeRequest :one
+SELECT id, user_id, level_id, effective_from, note, state, admin_comment, decided_by, decided_at, created_at FROM level_change_requests WHERE id = ?
+`
+
+func (q *Queries) GetLevelChangeRequest(ctx context.Context, id int64) (LevelChangeRequest, error) {
+	row := q.db.QueryRowContext(ctx, getLevelChangeRequest, id)
+	var i LevelChangeRequest
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.LevelID,
+		&i.EffectiveFrom,
+		&i.Note,
+		&i.State,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getOpenMembershipPause = `-- name: GetOpenMembershipPause :one
+SELECT id, user_id, start_date, end_date, reason, created_by, created_at, ended_at FROM membership_pauses WHERE user_id = ? AND ended_at IS NULL ORDER BY id DESC LIMIT 1
+`
+
+// The member's pause that has not been cleared or expired yet (at most one)
+func (q *Queries) GetOpenMembershipPause(ctx context.Context, userID int64) (MembershipPause, error) {
+	row := q.db.QueryRowContext(ctx, getOpenMembershipPause, userID)
+	var i MembershipPause
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.StartDate,
+		&i.EndDate,
+		&i.Reason,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.EndedAt,
+	)
+	return i, err
+}
+
+const getPayment = `-- name: GetPayment :one
+SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification FROM payments WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetPayment(ctx context.Context, id int64) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, getPayment, id)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Date,
+		&i.Amount,
+		&i.Kind,
+		&i.KindID,
+		&i.LocalAccount,
+		&i.RemoteAccount,
+		&i.Identification,
+		&i.RawData,
+		&i.StaffComment,
+		&i.CreatedAt,
+		&i.ProjectID,
+		&i.DismissedAt,
+		&i.DismissedBy,
+		&i.DismissedReason,
+		&i.ReversalOf,
+		&i.ReversalReview,
+		&i.ContentHash,
+		&i.Classification,
+	)
+	return i, err
+}
+
+const getPaymentByKindAndID = `-- name: GetPaymentByKindAndID :one
+SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification FROM payments WHERE kind = ? AND kind_id = ? LIMIT 1
+`
+
+type GetPaymentByKindAndIDParams struct {
+	Kind   string `json:"kind"`
+	KindID string `json:"kind_id"`
+}
+
+func (q *Queries) GetPaymentByKindAndID(ctx context.Context, arg GetPaymentByKindAndIDParams) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, getPaymentByKindAndID, arg.Kind, arg.KindID)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Date,
+		&i.Amount,
+		&i.Kind,
+		&i.KindID,
+		&i.LocalAccount,
+		&i.RemoteAccount,
+		&i.Identification,
+		&i.RawData,
+		&i.StaffComment,
+		&i.CreatedAt,
+		&i.ProjectID,
+		&i.DismissedAt,
+		&i.DismissedBy,
+		&i.DismissedReason,
+		&i.ReversalOf,
+		&i.ReversalReview,
+		&i.ContentHash,
+		&i.Classification,
+	)
+	return i, err
+}
+
+const getPaymentDuplicate = `-- name: GetPaymentDuplicate :one
+SELECT payment_id, duplicate_of, state, decided_by, decided_at, created_at FROM payment_duplicates WHERE payment_id = ? LIMIT 1
+`
+
+func (q *Queries) GetPaymentDuplicate(ctx context.Context, paymentID int64) (PaymentDuplicate, error) {
+	row := q.db.QueryRowContext(ctx, getPaymentDuplicate, paymentID)
+	var i PaymentDuplicate
+	err := row.Scan(
+		&i.PaymentID,
+		&i.DuplicateOf,
+		&i.State,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPaymentMatchRule = `-- name: GetPaymentMatchRule :one
+SELECT id, name, priority, remote_account, message_pattern, specific_symbol, amount_min, amount_max, user_id, project_id, active, match_count, last_matched_at, created_by, created_at, updated_at FROM payment_match_rules WHERE id = ?
+`
+
+func (q *Queries) GetPaymentMatchRule(ctx context.Context, id int64) (PaymentMatchRule, error) {
+	row := q.db.QueryRowContext(ctx, getPaymentMatchRule, id)
+	var i PaymentMatchRule
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Priority,
+		&i.RemoteAccount,
+		&i.MessagePattern,
+		&i.SpecificSymbol,
+		&i.AmountMin,
+		&i.AmountMax,
+		&i.UserID,
+		&i.ProjectID,
+		&i.Active,
+		&i.MatchCount,
+		&i.LastMatchedAt,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getPaymentReversal = `-- name: GetPaymentReversal :one
+SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification FROM payments WHERE reversal_of = ? LIMIT 1
+`
+
+// The payment already linked as the reversal of a payment
+func (q *Queries) GetPaymentReversal(ctx context.Context, reversalOf sql.NullInt64) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, getPaymentReversal, reversalOf)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Date,
+		&i.Amount,
+		&i.Kind,
+		&i.KindID,
+		&i.LocalAccount,
+		&i.RemoteAccount,
+		&i.Identification,
+		&i.RawData,
+		&i.StaffComment,
+		&i.CreatedAt,
+		&i.ProjectID,
+		&i.DismissedAt,
+		&i.DismissedBy,
+		&i.DismissedReason,
+		&i.ReversalOf,
+		&i.ReversalReview,
+		&i.ContentHash,
+		&i.Classification,
+	)
+	return i, err
+}
+
+const getPendingApplicationByEmail = `-- name: GetPendingApplicationByEmail :one
+SELECT id, realname, email, motivation, level_id, state, user_id, admin_comment, decided_by, decided_at, created_at FROM applications WHERE email = ? AND state = 'pending' LIMIT 1
+`
+
+func (q *Queries) GetPendingApplicationByEmail(ctx context.Context, email string) (Application, error) {
+	row := q.db.QueryRowContext(ctx, getPendingApplicationByEmail, email)
+	var i Application
+	err := row.Scan(
+		&i.ID,
+		&i.Realname,
+		&i.Email,
+		&i.Motivation,
+		&i.LevelID,
+		&i.State,
+		&i.UserID,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPendingLevelChangeRequest = `-- name: GetPendingLevelChangeRequest :one
+SELECT id, user_id, level_id, effective_from, note, state, admin_comment, decided_by, decided_at, created_at FROM level_change_requests WHERE user_id = ? AND state = 'requested' LIMIT 1
+`
+
+func (q *Queries) GetPendingLevelChangeRequest(ctx context.Context, userID int64) (LevelChangeRequest, error) {
+	row := q.db.QueryRowContext(ctx, getPendingLevelChangeRequest, userID)
+	var i LevelChangeRequest
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.LevelID,
+		&i.EffectiveFrom,
+		&i.Note,
+		&i.State,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getProject = `-- name: GetProject :one
+SELECT id, name, payments_id, description, public, btcpay, goal, deadline, target_reached_at FROM projects WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetProject(ctx context.Context, id int64) (Project, error) {
+	row := q.db.QueryRowContext(ctx, getProject, id)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.PaymentsID,
+		&i.Description,
+		&i.Public,
+		&i.Btcpay,
+		&i.Goal,
+		&i.Deadline,
+		&i.TargetReachedAt,
+	)
+	return i, err
+}
+
+const getProjectBalance = `-- name: GetProjectBalance :one
+SELECT CAST(
+    COALESCE((
+        SELECT SUM(amount) FROM (
+            SELECT DISTINCT p.id, p.amount FROM payments p
+            WHERE (p.project_id = ?1
+               OR p.identification IN (SELECT pv.vs FROM project_vs pv WHERE pv.project_id = ?1))
+            AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+            UNION ALL
+            SELECT s.payment_id, s.amount FROM payment_splits s WHERE s.project_id = ?1
+        ) income
+    ), 0)
+    - COALESCE((SELECT SUM(e.amount) FROM expenses e WHERE e.project_id = ?1), 0)
+AS INTEGER) as total
+`
+
+// Net balance of a project: income (as in GetProjectIncome) minus the expenses
+// tagged with the project
+func (q *Queries) GetProjectBalance(ctx context.Context, projectID sql.NullInt64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getProjectBalance, projectID)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const getProjectByPaymentsID = `-- name: GetProjectByPaymentsID :one
+SELECT p.id, p.name, p.payments_id, p.description, p.public, p.btcpay, p.goal, p.deadline, p.target_reached_at FROM projects p
+JOIN project_vs pv ON p.id = pv.project_id
+WHERE pv.vs = ? LIMIT 1
+`
+
+// Find project by any of its VS identifiers (in project_vs table)
+func (q *Queries) GetProjectByPaymentsID(ctx context.Context, vs string) (Project, error) {
+	row := q.db.QueryRowContext(ctx, getProjectByPaymentsID, vs)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.PaymentsID,
+		&i.Description,
+		&i.Public,
+		&i.Btcpay,
+		&i.Goal,
+		&i.Deadline,
+		&i.TargetReachedAt,
+	)
+	return i, err
+}
+
+const getProjectIncome = `-- name: GetProjectIncome :one
+SELECT CAST(COALESCE(SUM(amount), 0) AS INTEGER) as total
+FROM (
+    SELECT DISTINCT p.id, p.amount FROM payments p
+    WHERE (p.project_id = ?1
+       OR p.identification IN (SELECT pv.vs FROM project_vs pv WHERE pv.project_id = ?1))
+    AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+    UNION ALL
+    SELECT s.payment_id, s.amount FROM payment_splits s WHERE s.project_id = ?1
+) sub
+`
+
+// Sum all payments for a project (by project_id OR by any VS in project_vs);
+// a split payment counts by the allocations to the project
+func (q *Queries) GetProjectIncome(ctx context.Context, projectID sql.NullInt64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getProjectIncome, projectID)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const getProjectPayments = `-- name: GetProjectPayments :many
+SELECT DISTINCT p.id, p.user_id, p.date, p.amount, p.kind, p.kind_id, p.local_account, p.remote_account, p.identification, p.raw_data, p.staff_comment, p.created_at, p.project_id, p.dismissed_at, p.dismissed_by, p.dismissed_reason, p.reversal_of, p.reversal_review, p.content_hash, p.classification FROM payments p
+WHERE p.project_id = ?1
+   OR p.identification IN (SELECT pv.vs FROM project_vs pv WHERE pv.project_id = ?1)
+ORDER BY p.date DESC
+`
+
+// Get all payments for a project:
+// 1. Payments explicitly assigned to project (project_id set)
+// 2. Payments matching any of project's VS identifiers (from project_vs table)
+func (q *Queries) GetProjectPayments(ctx context.Context, projectID sql.NullInt64) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, getProjectPayments, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Payment{}
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Date,
+			&i.Amount,
+			&i.Kind,
+			&i.KindID,
+			&i.LocalAccount,
+			&i.RemoteAccount,
+			&i.Identification,
+			&i.RawData,
+			&i.StaffComment,
+			&i.CreatedAt,
+			&i.ProjectID,
+			&i.DismissedAt,
+			&i.DismissedBy,
+			&i.DismissedReason,
+			&i.ReversalOf,
+			&i.ReversalReview,
+			&i.ContentHash,
+			&i.Classification,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProjectVSByVS = `-- name: GetProjectVSByVS :one
+SELECT id, project_id, vs, note, created_at FROM project_vs WHERE vs = ? LIMIT 1
+`
+
+func (q *Queries) GetProjectVSByVS(ctx context.Context, vs string) (ProjectV, error) {
+	row := q.db.QueryRowContext(ctx, getProjectVSByVS, vs)
+	var i ProjectV
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Vs,
+		&i.Note,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getProjectWallEntry = `-- name: GetProjectWallEntry :one
+SELECT project_id, user_id, nickname, state, moderated_by, moderated_at, created_at, updated_at FROM project_wall_entries WHERE project_id = ? AND user_id = ?
+`
+
+type GetProjectWallEntryParams struct {
+	ProjectID int64 `json:"project_id"`
+	UserID    int64 `json:"user_id"`
+}
+
+func (q *Queries) GetProjectWallEntry(ctx context.Context, arg GetProjectWallEntryParams) (ProjectWallEntry, error) {
+	row := q.db.QueryRowContext(ctx, getProjectWallEntry, arg.ProjectID, arg.UserID)
+	var i ProjectWallEntry
+	err := row.Scan(
+		&i.ProjectID,
+		&i.UserID,
+		&i.Nickname,
+		&i.State,
+		&i.ModeratedBy,
+		&i.ModeratedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getReimbursement = `-- name: GetReimbursement :one
+SELECT id, user_id, state, amount, description, account, admin_comment, decided_by, decided_at, batch_id, payment_id, paid_at, created_at FROM reimbursements WHERE id = ?
+`
+
+func (q *Queries) GetReimbursement(ctx context.Context, id int64) (Reimbursement, error) {
+	row := q.db.QueryRowContext(ctx, getReimbursement, id)
+	var i Reimbursement
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.State,
+		&i.Amount,
+		&i.Description,
+		&i.Account,
+		&i.AdminComment,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.BatchID,
+		&i.PaymentID,
+		&i.PaidAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getReimbursementBatch = `-- name: GetReimbursementBatch :one
+SELECT id, created_by, total, xml, created_at FROM reimbursement_batches WHERE id = ?
+`
+
+func (q *Queries) GetReimbursementBatch(ctx context.Context, id int64) (ReimbursementBatch, error) {
+	row := q.db.QueryRowContext(ctx, getReimbursementBatch, id)
+	var i ReimbursementBatch
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedBy,
+		&i.Total,
+		&i.Xml,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getReimbursementReceipt = `-- name: GetReimbursementReceipt :one
+SELECT id, reimbursement_id, filename, content_type, data, created_at FROM reimbursement_receipts WHERE id = ? AND reimbursement_id = ?
+`
+
+type GetReimbursementReceiptParams struct {
+	ID              int64 `json:"id"`
+	ReimbursementID int64 `json:"reimbursement_id"`
+}
+
+func (q *Queries) GetReimbursementReceipt(ctx context.Context, arg GetReimbursementReceiptParams) (ReimbursementReceipt, error) {
+	row := q.db.QueryRowContext(ctx, getReimbursementReceipt, arg.ID, arg.ReimbursementID)
+	var i ReimbursementReceipt
+	err := row.Scan(
+		&i.ID,
+		&i.ReimbursementID,
+		&i.Filename,
+		&i.ContentType,
+		&i.Data,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getScheduledLevelChange = `-- name: GetScheduledLevelChange :one
+SELECT id, user_id, level_id, effective_from, request_id, created_by, applied_at, created_at FROM level_history
+WHERE user_id = ? AND applied_at IS NULL AND effective_from <= ?
+ORDER BY effective_from DESC, id DESC
+LIMIT 1
+`
+
+type GetScheduledLevelChangeParams struct {
+	UserID        int64     `json:"user_id"`
+	EffectiveFrom time.Time `json:"effective_from"`
+}
+
+// Latest approved change not applied yet that is effective at the given period start
+func (q *Queries) GetScheduledLevelChange(ctx context.Context, arg GetScheduledLevelChangeParams) (LevelHistory, error) {
+	row := q.db.QueryRowContext(ctx, getScheduledLevelChange, arg.UserID, arg.EffectiveFrom)
+	var i LevelHistory
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.LevelID,
+		&i.EffectiveFrom,
+		&i.RequestID,
+		&i.CreatedBy,
+		&i.AppliedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getScheduledTermination = `-- name: GetScheduledTermination :one
+SELECT id, user_id, end_date, reason, state, cancelled_by, created_at, decided_at FROM membership_terminations WHERE user_id = ? AND state = 'scheduled' LIMIT 1
+`
+
+func (q *Queries) GetScheduledTermination(ctx context.Context, userID int64) (MembershipTermination, error) {
+	row := q.db.QueryRowContext(ctx, getScheduledTermination, userID)
+	var i MembershipTermination
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.EndDate,
+		&i.Reason,
+		&i.State,
+		&i.CancelledBy,
+		&i.CreatedAt,
+		&i.DecidedAt,
+	)
+	return i, err
+}
+
+const getSuspensionExemption = `-- name: GetSuspensionExemption :one
+SELECT user_id, reason, created_by, created_at FROM suspension_exemptions WHERE user_id = ?
+`
+
+func (q *Queries) GetSuspensionExemption(ctx context.Context, userID int64) (SuspensionExemption, error) {
+	row := q.db.QueryRowContext(ctx, getSuspensionExemption, userID)
+	var i SuspensionExemption
+	err := row.Scan(
+		&i.UserID,
+		&i.Reason,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTicket = `-- name: GetTicket :one
+SELECT id, user_id, email, subject, state, source, created_at, updated_at FROM tickets WHERE id = ?
+`
+
+func (q *Queries) GetTicket(ctx context.Context, id int64) (Ticket, error) {
+	row := q.db.QueryRowContext(ctx, getTicket, id)
+	var i Ticket
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Email,
+		&i.Subject,
+		&i.State,
+		&i.Source,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserAnonymization = `-- name: GetUserAnonymization :one
+SELECT user_id, anonymized_by, summary, created_at FROM user_anonymizations WHERE user_id = ?
+`
+
+func (q *Queries) GetUserAnonymization(ctx context.Context, userID int64) (UserAnonymization, error) {
+	row := q.db.QueryRowContext(ctx, getUserAnonymization, userID)
+	var i UserAnonymization
+	err := row.Scan(
+		&i.UserID,
+		&i.AnonymizedBy,
+		&i.Summary,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserBalance = `-- name: GetUserBalance :one
+SELECT
+    COALESCE((
+        SELECT SUM(p.amount)
+        FROM payments p
+        JOIN users u ON p.user_id = u.id
+        WHERE p.user_id = ?
+        AND p.identification = u.payments_id
+        AND p.classification != 'donation'
+        AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+    ), 0) -
+    COALESCE((SELECT SUM(f.amount) FROM fees f WHERE f.user_id = ?), 0) +
+    COALESCE((SELECT SUM(s.amount) FROM payment_splits s WHERE s.user_id = ? AND s.classification = 'fee'), 0) -
+    COALESCE((SELECT SUM(c.amount) FROM charges c WHERE c.user_id = ?), 0) +
+    COALESCE((SELECT SUM(a.amount) FROM balance_adjustments a WHERE a.user_id = ?), 0) as balance
+`
+
+type GetUserBalanceParams struct {
+	UserID   sql.NullInt64 `json:"user_id"`
+	UserID_2 int64         `json:"user_id_2"`
+	UserID_3 sql.NullInt64 `json:"user_id_3"`
+	UserID_4 int64         `json:"user_id_4"`
+	UserID_5 int64         `json:"user_id_5"`
+}
+
+// Calculate membership fee balance (only payments matching user's payments_id VS,
+// a split payment counts by the allocations to the user; donations do not count).
+// One-off charges count like fees, balance adjustments are added with their sign.
+func (q *Queries) GetUserBalance(ctx context.Context, arg GetUserBalanceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getUserBalance,
+		arg.UserID,
+		arg.UserID_2,
+		arg.UserID_3,
+		arg.UserID_4,
+		arg.UserID_5,
+	)
+	var balance int64
+	err := row.Scan(&balance)
+	return balance, err
+}
+
+const getUserBalanceSince = `-- name: GetUserBalanceSince :one
+SELECT
+    COALESCE((
+        SELECT SUM(p.amount)
+        FROM payments p
+        JOIN users u ON p.user_id = u.id
+        WHERE p.user_id = ?1
+        AND p.identification = u.payments_id
+        AND p.classification != 'donation'
+        AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+        AND date(p.date) >= CAST(?2 AS TEXT)
+    ), 0) -
+    COALESCE((SELECT SUM(f.amount) FROM fees f WHERE f.user_id = ?1 AND date(f.period_start) >= CAST(?2 AS TEXT)), 0) +
+    COALESCE((
+        SELECT SUM(s.amount)
+        FROM payment_splits s
+        JOIN payments p ON p.id = s.payment_id
+        WHERE s.user_id = ?1 AND s.classification = 'fee'
+        AND date(p.date) >= CAST(?2 AS TEXT)
+    ), 0) -
+    COALESCE((SELECT SUM(c.amount) FROM charges c WHERE c.user_id = ?1 AND date(c.date) >= CAST(?2 AS TEXT)), 0) +
+    COALESCE((SELECT SUM(a.amount) FROM balance_adjustments a WHERE a.user_id = ?1 AND date(a.created_at) >= CAST(?2 AS TEXT)), 0) as balance
+`
+
+type GetUserBalanceSinceParams struct {
+	UserID sql.NullInt64 `json:"user_id"`
+	Since  string        `json:"since"`
+}
+
+// Change of the balance (as in GetUserBalance) by the entries dated on or after since;
+// added to the latest balance snapshot
+func (q *Queries) GetUserBalanceSince(ctx context.Context, arg GetUserBalanceSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getUserBalanceSince, arg.UserID, arg.Since)
+	var balance int64
+	err := row.Scan(&balance)
+	return balance, err
+}
+
+const getUserBillingCycle = `-- name: GetUserBillingCycle :one
+SELECT billing_cycle FROM user_payment_settings WHERE user_id = ?
+`
+
+func (q *Queries) GetUserBillingCycle(ctx context.Context, userID int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getUserBillingCycle, userID)
+	var billing_cycle string
+	err := row.Scan(&billing_cycle)
+	return billing_cycle, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users WHERE email = ? LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.KeycloakID,
+		&i.Email,
+		&i.Username,
+		&i.Realname,
+		&i.Phone,
+		&i.AltContact,
+		&i.LevelID,
+		&i.LevelActualAmount,
+		&i.PaymentsID,
+		&i.DateJoined,
+		&i.KeysGranted,
+		&i.KeysReturned,
+		&i.State,
+		&i.IsCouncil,
+		&i.IsStaff,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.KeycloakID,
+		&i.Email,
+		&i.Username,
+		&i.Realname,
+		&i.Phone,
+		&i.AltContact,
+		&i.LevelID,
+		&i.LevelActualAmount,
+		&i.PaymentsID,
+		&i.DateJoined,
+		&i.KeysGranted,
+		&i.KeysReturned,
+		&i.State,
+		&i.IsCouncil,
+		&i.IsStaff,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserByKeycloakID = `-- name: GetUserByKeycloakID :one
+SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users WHERE keycloak_id = ? LIMIT 1
+`
+
+func (q *Queries) GetUserByKeycloakID(ctx context.Context, keycloakID sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByKeycloakID, keycloakID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.KeycloakID,
+		&i.Email,
+		&i.Username,
+		&i.Realname,
+		&i.Phone,
+		&i.AltContact,
+		&i.LevelID,
+		&i.LevelActualAmount,
+		&i.PaymentsID,
+		&i.DateJoined,
+		&i.KeysGranted,
+		&i.KeysReturned,
+		&i.State,
+		&i.IsCouncil,
+		&i.IsStaff,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserByPaymentsID = `-- name: GetUserByPaymentsID :one
+SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users WHERE payments_id = ? LIMIT 1
+`
+
+func (q *Queries) GetUserByPaymentsID(ctx context.Context, paymentsID sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByPaymentsID, paymentsID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.KeycloakID,
+		&i.Email,
+		&i.Username,
+		&i.Realname,
+		&i.Phone,
+		&i.AltContact,
+		&i.LevelID,
+		&i.LevelActualAmount,
+		&i.PaymentsID,
+		&i.DateJoined,
+		&i.KeysGranted,
+		&i.KeysReturned,
+		&i.State,
+		&i.IsCouncil,
+		&i.IsStaff,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserQRFormat = `-- name: GetUserQRFormat :one
+SELECT qr_format FROM user_payment_settings WHERE user_id = ?
+`
+
+func (q *Queries) GetUserQRFormat(ctx context.Context, userID int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getUserQRFormat, userID)
+	var qr_format string
+	err := row.Scan(&qr_format)
+	return qr_format, err
+}
+
+const getUsersByRemoteAccount = `-- name: GetUsersByRemoteAccount :many
+SELECT u.id, u.email, u.payments_id, COUNT(p.id) AS payment_count
+FROM payments p
+JOIN users u ON u.id = p.user_id
+WHERE p.remote_account = ? AND p.reversal_of IS NULL
+GROUP BY u.id, u.email, u.payments_id
+ORDER BY payment_count DESC, u.id
+`
+
+type GetUsersByRemoteAccountRow struct {
+	ID           int64          `json:"id"`
+	Email        string         `json:"email"`
+	PaymentsID   sql.NullString `json:"payments_id"`
+	PaymentCount int64          `json:"payment_count"`
+}
+
+// Members who paid from the account before, most payments first
+func (q *Queries) GetUsersByRemoteAccount(ctx context.Context, remoteAccount string) ([]GetUsersByRemoteAccountRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByRemoteAccount, remoteAccount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetUsersByRemoteAccountRow{}
+	for rows.Next() {
+		var i GetUsersByRemoteAccountRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.PaymentsID,
+			&i.PaymentCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebSession = `-- name: GetWebSession :one
+SELECT data FROM web_sessions WHERE id = ?1 AND expires_at > ?2
+`
+
+type GetWebSessionParams struct {
+	ID  string    `json:"id"`
+	Now time.Time `json:"now"`
+}
+
+func (q *Queries) GetWebSession(ctx context.Context, arg GetWebSessionParams) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, getWebSession, arg.ID, arg.Now)
+	var data []byte
+	err := row.Scan(&data)
+	return data, err
+}
+
+const linkKeycloakID = `-- name: LinkKeycloakID :one
+UPDATE users SET
+    keycloak_id = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE email = ? AND keycloak_id IS NULL
+RETURNING id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at
+`
+
+type LinkKeycloakIDParams struct {
+	KeycloakID sql.NullString `json:"keycloak_id"`
+	Email      string         `json:"email"`
+}
+
+func (q *Queries) LinkKeycloakID(ctx context.Context, arg LinkKeycloakIDParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, linkKeycloakID, arg.KeycloakID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.KeycloakID,
+		&i.Email,
+		&i.Username,
+		&i.Realname,
+		&i.Phone,
+		&i.AltContact,
+		&i.LevelID,
+		&i.LevelActualAmount,
+		&i.PaymentsID,
+		&i.DateJoined,
+		&i.KeysGranted,
+		&i.KeysReturned,
+		&i.State,
+		&i.IsCouncil,
+		&i.IsStaff,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const linkPaymentReversal = `-- name: LinkPaymentReversal :one
+UPDATE payments SET
+    reversal_of = ?,
+    reversal_review = FALSE,
+    user_id = ?,
+    project_id = ?,
+    identification = ?
+WHERE id = ?
+RETURNING id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification
+`
+
+type LinkPaymentReversalParams struct {
+	ReversalOf     sql.NullInt64 `json:"reversal_of"`
+	UserID         sql.NullInt64 `json:"user_id"`
+	ProjectID      sql.NullInt64 `json:"project_id"`
+	Identification string        `json:"identification"`
+	ID             int64         `json:"id"`
+}
+
+// Reversal inherits owner and VS from the original so balances net out
+func (q *Queries) LinkPaymentReversal(ctx context.Context, arg LinkPaymentReversalParams) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, linkPaymentReversal,
+		arg.ReversalOf,
+		arg.UserID,
+		arg.ProjectID,
+		arg.Identification,
+		arg.ID,
+	)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Date,
+		&i.Amount,
+		&i.Kind,
+		&i.KindID,
+		&i.LocalAccount,
+		&i.RemoteAccount,
+		&i.Identification,
+		&i.RawData,
+		&i.StaffComment,
+		&i.CreatedAt,
+		&i.ProjectID,
+		&i.DismissedAt,
+		&i.DismissedBy,
+		&i.DismissedReason,
+		&i.ReversalOf,
+		&i.ReversalReview,
+		&i.ContentHash,
+		&i.Classification,
+	)
+	return i, err
+}
+
+const listAPITokensByUser = `-- name: ListAPITokensByUser :many
+SELECT id, user_id, name, token_hash, token_prefix, scopes, expires_at, last_used_at, revoked_at, created_at FROM api_tokens WHERE user_id = ? AND revoked_at IS NULL ORDER BY created_at DESC, id DESC
+`
+
+func (q *Queries) ListAPITokensByUser(ctx context.Context, userID int64) ([]ApiToken, error) {
+	rows, err := q.db.QueryContext(ctx, listAPITokensByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ApiToken{}
+	for rows.Next() {
+		var i ApiToken
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.TokenHash,
+			&i.TokenPrefix,
+			&i.Scopes,
+			&i.ExpiresAt,
+			&i.LastUsedAt,
+			&i.RevokedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAcceptedUsersForFees = `-- name: ListAcceptedUsersForFees :many
+SELECT u.id, u.keycloak_id, u.email, u.username, u.realname, u.phone, u.alt_contact, u.level_id, u.level_actual_amount, u.payments_id, u.date_joined, u.keys_granted, u.keys_returned, u.state, u.is_council, u.is_staff, u.created_at, u.updated_at, l.amount as level_amount, COALESCE(s.billing_cycle, 'monthly') AS billing_cycle
+FROM users u
+JOIN levels l ON u.level_id = l.id
+LEFT JOIN user_payment_settings s ON s.user_id = u.id
+WHERE u.state = 'accepted'
+ORDER BY u.id
+`
+
+type ListAcceptedUsersForFeesRow struct {
+	ID                int64          `json:"id"`
+	KeycloakID        sql.NullString `json:"keycloak_id"`
+	Email             string         `json:"email"`
+	Username          sql.NullString `json:"username"`
+	Realname          sql.NullString `json:"realname"`
+	Phone             sql.NullString `json:"phone"`
+	AltContact        sql.NullString `json:"alt_contact"`
+	LevelID           int64          `json:"level_id"`
+	LevelActualAmount money.Amount   `json:"level_actual_amount"`
+	PaymentsID        sql.NullString `json:"payments_id"`
+	DateJoined        time.Time      `json:"date_joined"`
+	KeysGranted       sql.NullTime   `json:"keys_granted"`
+	KeysReturned      sql.NullTime   `json:"keys_returned"`
+	State             string         `json:"state"`
+	IsCouncil         bool           `json:"is_council"`
+	IsStaff           bool           `json:"is_staff"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	LevelAmount       money.Amount   `json:"level_amount"`
+	BillingCycle      string         `json:"billing_cycle"`
+}
+
+func (q *Queries) ListAcceptedUsersForFees(ctx context.Context) ([]ListAcceptedUsersForFeesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAcceptedUsersForFees)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAcceptedUsersForFeesRow{}
+	for rows.Next() {
+		var i ListAcceptedUsersForFeesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.KeycloakID,
+			&i.Email,
+			&i.Username,
+			&i.Realname,
+			&i.Phone,
+			&i.AltContact,
+			&i.LevelID,
+			&i.LevelActualAmount,
+			&i.PaymentsID,
+			&i.DateJoined,
+			&i.KeysGranted,
+			&i.KeysReturned,
+			&i.State,
+			&i.IsCouncil,
+			&i.IsStaff,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LevelAmount,
+			&i.BillingCycle,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountingEntries = `-- name: ListAccountingEntries :many
+SELECT 'payment' AS entry_type, p.id AS entry_id, p.date, p.amount, p.identification AS vs,
+    p.user_id, COALESCE(u.email, '') AS email, COALESCE(u.realname, '') AS realname, p.remote_account,
+    CASE
+        WHEN p.dismissed_at IS NOT NULL THEN 'dismissed'
+        WHEN EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id) THEN 'split'
+        WHEN p.classification = 'donation' OR (p.classification = '' AND (
+            p.project_id IS NOT NULL OR p.identification IN (SELECT pv.vs FROM project_vs pv))) THEN 'donation'
+        WHEN p.user_id IS NULL THEN 'unmatched'
+        ELSE 'fee'
+    END AS category,
+    0 AS months, COALESCE(pr.name, '') AS note
+FROM payments p
+LEFT JOIN users u ON u.id = p.user_id
+LEFT JOIN projects pr ON pr.id = p.project_id
+WHERE substr(p.date, 1, 10) >= CAST(?1 AS TEXT) AND substr(p.date, 1, 10) < CAST(?2 AS TEXT)
+  AND NOT EXISTS (SELECT 1 FROM payment_duplicates d WHERE d.payment_id = p.id AND d.state != 'ignored')
+UNION ALL
+SELECT 'fee', f.id, f.period_start, f.amount, COALESCE(u.payments_id, ''),
+    f.user_id, u.email, COALESCE(u.realname, ''), '', COALESCE(l.name, ''), f.months, ''
+FROM fees f
+JOIN users u ON u.id = f.user_id
+LEFT JOIN levels l ON l.id = f.level_id
+WHERE substr(f.period_start, 1, 10) >= CAST(?1 AS TEXT) AND substr(f.period_start, 1, 10) < CAST(?2 AS TEXT)
+UNION ALL
+SELECT 'charge', c.id, c.date, c.amount, COALESCE(u.payments_id, ''),
+    c.user_id, u.email, COALESCE(u.realname, ''), '', c.category, 0, c.description
+FROM charges c
+JOIN users u ON u.id = c.user_id
+WHERE substr(c.date, 1, 10) >= CAST(?1 AS TEXT) AND substr(c.date, 1, 10) < CAST(?2 AS TEXT)
+ORDER BY 3, 1, 2
+`
+
+type ListAccountingEntriesParams struct {
+	DateFrom string `json:"date_from"`
+	DateTo   string `json:"date_to"`
+}
+
+type ListAccountingEntriesRow struct {
+	EntryType     string        `json:"entry_type"`
+	EntryID       int64         `json:"entry_id"`
+	Date          time.Time     `json:"date"`
+	Amount        money.Amount  `json:"amount"`
+	Vs            string        `json:"vs"`
+	UserID        sql.NullInt64 `json:"user_id"`
+	Email         string        `json:"email"`
+	Realname      string        `json:"realname"`
+	RemoteAccount string        `json:"remote_account"`
+	Category      string        `json:"category"`
+	Months        int64         `json:"months"`
+	Note          string        `json:"note"`
+}
+
+// Payments received (without duplicate imports), fees and one-off charges of the
+// period for the accounting export, oldest first. The category of a payment is
+// split, donation, fee, unmatched or dismissed; of a fee its level name.
+// Dates compare by their YYYY-MM-DD prefix (stored both as plain dates and timestamps).
+func (q *Queries) ListAccountingEntries(ctx context.Context, arg ListAccountingEntriesParams) ([]ListAccountingEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountingEntries, arg.DateFrom, arg.DateTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountingEntriesRow{}
+	for rows.Next() {
+		var i ListAccountingEntriesRow
+		if err := rows.Scan(
+			&i.EntryType,
+			&i.EntryID,
+			&i.Date,
+			&i.Amount,
+			&i.Vs,
+			&i.UserID,
+			&i.Email,
+			&i.Realname,
+			&i.RemoteAccount,
+			&i.Category,
+			&i.Months,
+			&i.Note,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listActivePaymentMatchRules = `-- name: ListActivePaymentMatchRules :many
+SELECT id, name, priority, remote_account, message_pattern, specific_symbol, amount_min, amount_max, user_id, project_id, active, match_count, last_matched_at, created_by, created_at, updated_at FROM payment_match_rules WHERE active = TRUE ORDER BY priority, id
+`
+
+func (q *Queries) ListActivePaymentMatchRules(ctx context.Context) ([]PaymentMatchRule, error) {
+	rows, err := q.db.QueryContext(ctx, listActivePaymentMatchRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PaymentMatchRule{}
+	for rows.Next() {
+		var i PaymentMatchRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Priority,
+			&i.RemoteAccount,
+			&i.MessagePattern,
+			&i.SpecificSymbol,
+			&i.AmountMin,
+			&i.AmountMax,
+			&i.UserID,
+			&i.ProjectID,
+			&i.Active,
+			&i.MatchCount,
+			&i.LastMatchedAt,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listActivePaymentPlans = `-- name: ListActivePaymentPlans :many
+SELECT id, user_id, total_debt, installment, start_date, note, created_by, created_at, ended_at FROM payment_plans WHERE ended_at IS NULL ORDER BY user_id, id
+`
+
+func (q *Queries) ListActivePaymentPlans(ctx context.Context) ([]PaymentPlan, error) {
+	rows, err := q.db.QueryContext(ctx, listActivePaymentPlans)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PaymentPlan{}
+	for rows.Next() {
+		var i PaymentPlan
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.TotalDebt,
+			&i.Installment,
+			&i.StartDate,
+			&i.Note,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.EndedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAdminAuditLog = `-- name: ListAdminAuditLog :many
+SELECT id, actor_user_id, actor_email, api_token_id, method, path, target, payload, status, error, created_at FROM admin_audit_log
+WHERE (? = '' OR path LIKE ?)
+  AND (? = 0 OR actor_user_id = ?)
+  AND (? = 0 OR status >= 400)
+ORDER BY created_at DESC, id DESC LIMIT ?
+`
+
+type ListAdminAuditLogParams struct {
+	Column1     interface{}   `json:"column_1"`
+	Path        string        `json:"path"`
+	Column3     interface{}   `json:"column_3"`
+	ActorUserID sql.NullInt64 `json:"actor_user_id"`
+	Column5     interface{}   `json:"column_5"`
+	Limit       int64         `json:"limit"`
+}
+
+func (q *Queries) ListAdminAuditLog(ctx context.Context, arg ListAdminAuditLogParams) ([]AdminAuditLog, error) {
+	rows, err := q.db.QueryContext(ctx, listAdminAuditLog,
+		arg.Column1,
+		arg.Path,
+		arg.Column3,
+		arg.ActorUserID,
+		arg.Column5,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AdminAuditLog{}
+	for rows.Next() {
+		var i AdminAuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.ActorUserID,
+			&i.ActorEmail,
+			&i.ApiTokenID,
+			&i.Method,
+			&i.Path,
+			&i.Target,
+			&i.Payload,
+			&i.Status,
+			&i.Error,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAllLevels = `-- name: ListAllLevels :many
+SELECT id, name, amount, active, created_at, btcpay FROM levels ORDER BY amount
+`
+
+func (q *Queries) ListAllLevels(ctx context.Context) ([]Level, error) {
+	rows, err := q.db.QueryContext(ctx, listAllLevels)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Level{}
+	for rows.Next() {
+		var i Level
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Amount,
+			&i.Active,
+			&i.CreatedAt,
+			&i.Btcpay,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listApplicationVouches = `-- name: ListApplicationVouches :many
+SELECT v.application_id, v.user_id, u.email, u.realname, u.username, u.state, v.note, v.created_at
+FROM application_vouches v
+JOIN applications a ON a.id = v.application_id
+JOIN users u ON u.id = v.user_id
+WHERE a.state = 'pending'
+ORDER BY v.application_id, v.created_at
+`
+
+type ListApplicationVouchesRow struct {
+	ApplicationID int64          `json:"application_id"`
+	UserID        int64          `json:"user_id"`
+	Email         string         `json:"email"`
+	Realname      sql.NullString `json:"realname"`
+	Username      sql.NullString `json:"username"`
+	State         string         `json:"state"`
+	Note          sql.NullString `json:"note"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// Vouches of all pending applications with the vouching members (only vouches of
+// accepted members count towards APPLICATION_VOUCHES)
+func (q *Queries) ListApplicationVouches(ctx context.Context) ([]ListApplicationVouchesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listApplicationVouches)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListApplicationVouchesRow{}
+	for rows.Next() {
+		var i ListApplicationVouchesRow
+		if err := rows.Scan(
+			&i.ApplicationID,
+			&i.UserID,
+			&i.Email,
+			&i.Realname,
+			&i.Username,
+			&i.State,
+			&i.Note,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listApplications = `-- name: ListApplications :many
+SELECT a.id, a.realname, a.email, a.motivation, a.level_id, a.state, a.user_id, a.admin_comment, a.decided_by, a.decided_at, a.created_at, l.name AS level_name, l.amount AS level_amount,
+    (
+        SELECT COUNT(*) FROM application_vouches v JOIN users u ON u.id = v.user_id
+        WHERE v.application_id = a.id AND u.state = 'accepted'
+    ) AS vouch_count
+FROM applications a
+JOIN levels l ON a.level_id = l.id
+ORDER BY a.state = 'pending' DESC, a.created_at DESC, a.id DESC
+LIMIT ?
+`
+
+type ListApplicationsRow struct {
+	ID           int64          `json:"id"`
+	Realname     string         `json:"realname"`
+	Email        string         `json:"email"`
+	Motivation   string         `json:"motivation"`
+	LevelID      int64          `json:"level_id"`
+	State        string         `json:"state"`
+	UserID       sql.NullInt64  `json:"user_id"`
+	AdminComment sql.NullString `json:"admin_comment"`
+	DecidedBy    sql.NullString `json:"decided_by"`
+	DecidedAt    sql.NullTime   `json:"decided_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	LevelName    string         `json:"level_name"`
+	LevelAmount  money.Amount   `json:"level_amount"`
+	VouchCount   int64          `json:"vouch_count"`
+}
+
+// Pending applications first, then the decided ones, newest first; vouch_count
+// counts accepted members only
+func (q *Queries) ListApplications(ctx context.Context, limit int64) ([]ListApplicationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listApplications, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListApplicationsRow{}
+	for rows.Next() {
+		var i ListApplicationsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Realname,
+			&i.Email,
+			&i.Motivation,
+			&i.LevelID,
+			&i.State,
+			&i.UserID,
+			&i.AdminComment,
+			&i.DecidedBy,
+			&i.DecidedAt,
+			&i.CreatedAt,
+			&i.LevelName,
+			&i.LevelAmount,
+			&i.VouchCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listApprovedReimbursements = `-- name: ListApprovedReimbursements :many
+SELECT id, user_id, state, amount, description, account, admin_comment, decided_by, decided_at, batch_id, payment_id, paid_at, created_at FROM reimbursements WHERE state = 'approved' ORDER BY id
+`
+
+func (q *Queries) ListApprovedReimbursements(ctx context.Context) ([]Reimbursement, error) {
+	rows, err := q.db.QueryContext(ctx, listApprovedReimbursements)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Reimbursement{}
+	for rows.Next() {
+		var i Reimbursement
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.State,
+			&i.Amount,
+			&i.Description,
+			&i.Account,
+			&i.AdminComment,
+			&i.DecidedBy,
+			&i.DecidedAt,
+			&i.BatchID,
+			&i.PaymentID,
+			&i.PaidAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBalanceAdjustmentsByUser = `-- name: ListBalanceAdjustmentsByUser :many
+SELECT id, user_id, fee_id, amount, reason, created_by, created_at FROM balance_adjustments WHERE user_id = ? ORDER BY created_at DESC, id DESC
+`
+
+func (q *Queries) ListBalanceAdjustmentsByUser(ctx context.Context, userID int64) ([]BalanceAdjustment, error) {
+	rows, err := q.db.QueryContext(ctx, listBalanceAdjustmentsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []BalanceAdjustment{}
+	for rows.Next() {
+		var i BalanceAdjustment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.FeeID,
+			&i.Amount,
+			&i.Reason,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBalanceSnapshotTotals = `-- name: ListBalanceSnapshotTotals :many
+SELECT month,
+    CAST(SUM(CASE WHEN balance < 0 THEN 1 ELSE 0 END) AS INTEGER) AS debtors,
+    CAST(SUM(CASE WHEN balance < 0 THEN -balance ELSE 0 END) AS INTEGER) AS debt,
+    CAST(SUM(CASE WHEN balance > 0 THEN balance ELSE 0 END) AS INTEGER) AS credit
+FROM balance_snapshots
+GROUP BY month
+ORDER BY month
+`
+
+type ListBalanceSnapshotTotalsRow struct {
+	Month   time.Time `json:"month"`
+	Debtors int64     `json:"debtors"`
+	Debt    int64     `json:"debt"`
+	Credit  int64     `json:"credit"`
+}
+
+// Per month: members in debt, their total debt and the total prepaid by others
+func (q *Queries) ListBalanceSnapshotTotals(ctx context.Context) ([]ListBalanceSnapshotTotalsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listBalanceSnapshotTotals)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListBalanceSnapshotTotalsRow{}
+	for rows.Next() {
+		var i ListBalanceSnapshotTotalsRow
+		if err := rows.Scan(
+			&i.Month,
+			&i.Debtors,
+			&i.Debt,
+			&i.Credit,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChargesByUser = `-- name: ListChargesByUser :many
+SELECT id, user_id, description, amount, category, date, created_by, created_at FROM charges WHERE user_id = ? ORDER BY date DESC, id DESC
+`
+
+func (q *Queries) ListChargesByUser(ctx context.Context, userID int64) ([]Charge, error) {
+	rows, err := q.db.QueryContext(ctx, listChargesByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Charge{}
+	for rows.Next() {
+		var i Charge
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Description,
+			&i.Amount,
+			&i.Category,
+			&i.Date,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDebtThresholds = `-- name: ListDebtThresholds :many
+SELECT step, months, updated_by, updated_at FROM debt_thresholds ORDER BY step
+`
+
+func (q *Queries) ListDebtThresholds(ctx context.Context) ([]DebtThreshold, error) {
+	rows, err := q.db.QueryContext(ctx, listDebtThresholds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DebtThreshold{}
+	for rows.Next() {
+		var i DebtThreshold
+		if err := rows.Scan(
+			&i.Step,
+			&i.Months,
+			&i.UpdatedBy,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDismissedPayments = `-- name: ListDismissedPayments :many
+SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification FROM payments WHERE dismissed_at IS NOT NULL ORDER BY dismissed_at DESC
+`
+
+func (q *Queries) ListDismissedPayments(ctx context.Context) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, listDismissedPayments)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Payment{}
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Date,
+			&i.Amount,
+			&i.Kind,
+			&i.KindID,
+			&i.LocalAccount,
+			&i.RemoteAccount,
+			&i.Identification,
+			&i.RawData,
+			&i.StaffComment,
+			&i.CreatedAt,
+			&i.ProjectID,
+			&i.DismissedAt,
+			&i.DismissedBy,
+			&i.DismissedReason,
+			&i.ReversalOf,
+			&i.ReversalReview,
+			&i.ContentHash,
+			&i.Classification,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDonationReceiptsByYear = `-- name: ListDonationReceiptsByYear :many
+SELECT user_id, year, sent_at FROM donation_receipts WHERE year = ?
+`
+
+// Donors whose donation receipt for the year was already emailed
+func (q *Queries) ListDonationReceiptsByYear(ctx context.Context, year int64) ([]DonationReceipt, error) {
+	rows, err := q.db.QueryContext(ctx, listDonationReceiptsByYear, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DonationReceipt{}
+	for rows.Next() {
+		var i DonationReceipt
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Year,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDonations = `-- name: ListDonations :many
+SELECT p.id AS payment_id, p.date, p.amount, p.user_id,
+    COALESCE(p.project_id, (SELECT pv.project_id FROM project_vs pv WHERE pv.vs = p.identification LIMIT 1)) AS project_id,
+    p.remote_account, p.identification, CAST(0 AS BOOLEAN) AS split
+FROM payments p
+WHERE p.date >= ?1 AND p.date < ?2
+  AND p.amount > 0 AND p.dismissed_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+  AND NOT EXISTS (SELECT 1 FROM payment_duplicates d WHERE d.payment_id = p.id AND d.state != 'ignored')
+  AND (p.classification = 'donation' OR (p.classification = '' AND (
+      p.project_id IS NOT NULL OR p.identification IN (SELECT pv.vs FROM project_vs pv))))
+UNION ALL
+SELECT s.payment_id, p.date, s.amount, s.user_id, s.project_id,
+    p.remote_account, p.identification, CAST(1 AS BOOLEAN) AS split
+FROM payment_splits s
+JOIN payments p ON p.id = s.payment_id
+WHERE p.date >= ?1 AND p.date < ?2
+  AND p.dismissed_at IS NULL AND s.classification = 'donation'
+ORDER BY 2 DESC, 1 DESC
+`
+
+type ListDonationsParams struct {
+	DateFrom time.Time `json:"date_from"`
+	DateTo   time.Time `json:"date_to"`
+}
+
+type ListDonationsRow struct {
+	PaymentID      int64         `json:"payment_id"`
+	Date           time.Time     `json:"date"`
+	Amount         money.Amount  `json:"amount"`
+	UserID         sql.NullInt64 `json:"user_id"`
+	ProjectID      sql.NullInt64 `json:"project_id"`
+	RemoteAccount  string        `json:"remote_account"`
+	Identification string        `json:"identification"`
+	Split          bool          `json:"split"`
+}
+
+// Donations received in the period: whole payments classified as donations (explicitly,
+// or by their project assignment or project VS) and donation parts of split payments
+func (q *Queries) ListDonations(ctx context.Context, arg ListDonationsParams) ([]ListDonationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDonations, arg.DateFrom, arg.DateTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDonationsRow{}
+	for rows.Next() {
+		var i ListDonationsRow
+		if err := rows.Scan(
+			&i.PaymentID,
+			&i.Date,
+			&i.Amount,
+			&i.UserID,
+			&i.ProjectID,
+			&i.RemoteAccount,
+			&i.Identification,
+			&i.Split,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDueLevelChanges = `-- name: ListDueLevelChanges :many
+SELECT id, user_id, level_id, effective_from, request_id, created_by, applied_at, created_at FROM level_history
+WHERE applied_at IS NULL AND effective_from <= ?
+ORDER BY user_id, effective_from, id
+`
+
+func (q *Queries) ListDueLevelChanges(ctx context.Context, effectiveFrom time.Time) ([]LevelHistory, error) {
+	rows, err := q.db.QueryContext(ctx, listDueLevelChanges, effectiveFrom)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LevelHistory{}
+	for rows.Next() {
+		var i LevelHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.LevelID,
+			&i.EffectiveFrom,
+			&i.RequestID,
+			&i.CreatedBy,
+			&i.AppliedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDueLevelPriceChanges = `-- name: ListDueLevelPriceChanges :many
+SELECT c.id, c.level_id, c.new_amount, c.effective_from, c.note, c.created_by, c.notified_at, c.applied_at, c.created_at, l.name AS level_name, l.amount AS level_amount
+FROM level_price_changes c
+JOIN levels l ON c.level_id = l.id
+WHERE c.applied_at IS NULL AND c.effective_from <= ?
+ORDER BY c.effective_from
+`
+
+type ListDueLevelPriceChangesRow struct {
+	ID            int64          `json:"id"`
+	LevelID       int64          `json:"level_id"`
+	NewAmount     money.Amount   `json:"new_amount"`
+	EffectiveFrom time.Time      `json:"effective_from"`
+	Note          sql.NullString `json:"note"`
+	CreatedBy     string         `json:"created_by"`
+	NotifiedAt    sql.NullTime   `json:"notified_at"`
+	AppliedAt     sql.NullTime   `json:"applied_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	LevelName     string         `json:"level_name"`
+	LevelAmount   money.Amount   `json:"level_amount"`
+}
+
+func (q *Queries) ListDueLevelPriceChanges(ctx context.Context, effectiveFrom time.Time) ([]ListDueLevelPriceChangesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDueLevelPriceChanges, effectiveFrom)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDueLevelPriceChangesRow{}
+	for rows.Next() {
+		var i ListDueLevelPriceChangesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.LevelID,
+			&i.NewAmount,
+			&i.EffectiveFrom,
+			&i.Note,
+			&i.CreatedBy,
+			&i.NotifiedAt,
+			&i.AppliedAt,
+			&i.CreatedAt,
+			&i.LevelName,
+			&i.LevelAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEmailCampaignRecipientsToSend = `-- name: ListEmailCampaignRecipientsToSend :many
+SELECT id, campaign_id, user_id, email, status, attempts, last_error, sent_at FROM email_campaign_recipients
+WHERE campaign_id = ?1
+AND (status = 'pending' OR (status = 'failed' AND attempts < ?2))
+ORDER BY id
+`
+
+type ListEmailCampaignRecipientsToSendParams struct {
+	CampaignID  int64 `json:"campaign_id"`
+	MaxAttempts int64 `json:"max_attempts"`
+}
+
+func (q *Queries) ListEmailCampaignRecipientsToSend(ctx context.Context, arg ListEmailCampaignRecipientsToSendParams) ([]EmailCampaignRecipient, error) {
+	rows, err := q.db.QueryContext(ctx, listEmailCampaignRecipientsToSend, arg.CampaignID, arg.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EmailCampaignRecipient{}
+	for rows.Next() {
+		var i EmailCampaignRecipient
+		if err := rows.Scan(
+			&i.ID,
+			&i.CampaignID,
+			&i.UserID,
+			&i.Email,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEmailCampaigns = `-- name: ListEmailCampaigns :many
+SELECT id, name, subject, template_name, body, audience, status, created_by, created_at, completed_at FROM email_campaigns ORDER BY created_at DESC
+`
+
+func (q *Queries) ListEmailCampaigns(ctx context.Context) ([]EmailCampaign, error) {
+	rows, err := q.db.QueryContext(ctx, listEmailCampaigns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EmailCampaign{}
+	for rows.Next() {
+		var i EmailCampaign
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Subject,
+			&i.TemplateName,
+			&i.Body,
+			&i.Audience,
+			&i.Status,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpenseCategories = `-- name: ListExpenseCategories :many
+SELECT DISTINCT category FROM expenses WHERE category != '' ORDER BY category
+`
+
+func (q *Queries) ListExpenseCategories(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listExpenseCategories)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, err
+		}
+		items = append(items, category)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpenses = `-- name: ListExpenses :many
+SELECT e.id, e.kind, e.kind_id, e.date, e.amount, e.remote_account, e.remote_name,
+    e.identification, e.message, e.category, e.project_id, e.staff_comment,
+    COALESCE(p.name, '') AS project_name
+FROM expenses e
+LEFT JOIN projects p ON p.id = e.project_id
+WHERE e.date >= ?1 AND e.date < ?2
+  AND (?3 = '' OR e.category = ?3)
+  AND (?4 = 0 OR e.project_id = ?4)
+ORDER BY e.date DESC, e.id DESC
+`
+
+type ListExpensesParams struct {
+	DateFrom  time.Time `json:"date_from"`
+	DateTo    time.Time `json:"date_to"`
+	Category  string    `json:"category"`
+	ProjectID int64     `json:"project_id"`
+}
+
+type ListExpensesRow struct {
+	ID             int64          `json:"id"`
+	Kind           string         `json:"kind"`
+	KindID         string         `json:"kind_id"`
+	Date           time.Time      `json:"date"`
+	Amount         money.Amount   `json:"amount"`
+	RemoteAccount  string         `json:"remote_account"`
+	RemoteName     string         `json:"remote_name"`
+	Identification string         `json:"identification"`
+	Message        string         `json:"message"`
+	Category       string         `json:"category"`
+	ProjectID      sql.NullInt64  `json:"project_id"`
+	StaffComment   sql.NullString `json:"staff_comment"`
+	ProjectName    string         `json:"project_name"`
+}
+
+func (q *Queries) ListExpenses(ctx context.Context, arg ListExpensesParams) ([]ListExpensesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listExpenses,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Category,
+		arg.ProjectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListExpensesRow{}
+	for rows.Next() {
+		var i ListExpensesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.KindID,
+			&i.Date,
+			&i.Amount,
+			&i.RemoteAccount,
+			&i.RemoteName,
+			&i.Identification,
+			&i.Message,
+			&i.Category,
+			&i.ProjectID,
+			&i.StaffComment,
+			&i.ProjectName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFakturoidInvoicesByUser = `-- name: ListFakturoidInvoicesByUser :many
+SELECT fee_id, user_id, fakturoid_id, number, status, public_url, paid_on, created_at, synced_at FROM fakturoid_invoices WHERE user_id = ? ORDER BY fee_id DESC
+`
+
+func (q *Queries) ListFakturoidInvoicesByUser(ctx context.Context, userID int64) ([]FakturoidInvoice, error) {
+	rows, err := q.db.QueryContext(ctx, listFakturoidInvoicesByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []FakturoidInvoice{}
+	for rows.Next() {
+		var i FakturoidInvoice
+		if err := rows.Scan(
+			&i.FeeID,
+			&i.UserID,
+			&i.FakturoidID,
+			&i.Number,
+			&i.Status,
+			&i.PublicUrl,
+			&i.PaidOn,
+			&i.CreatedAt,
+			&i.SyncedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeesByPeriod = `-- name: ListFeesByPeriod :many
+SELECT id, user_id, level_id, period_start, amount, created_at, months FROM fees WHERE period_start = ? ORDER BY user_id
+`
+
+func (q *Queries) ListFeesByPeriod(ctx context.Context, periodStart time.Time) ([]Fee, error) {
+	rows, err := q.db.QueryContext(ctx, listFeesByPeriod, periodStart)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Fee{}
+	for rows.Next() {
+		var i Fee
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.LevelID,
+			&i.PeriodStart,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Months,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeesByUser = `-- name: ListFeesByUser :many
+SELECT id, user_id, level_id, period_start, amount, created_at, months FROM fees WHERE user_id = ? ORDER BY period_start DESC
+`
+
+func (q *Queries) ListFeesByUser(ctx context.Context, userID int64) ([]Fee, error) {
+	rows, err := q.db.QueryContext(ctx, listFeesByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Fee{}
+	for rows.Next() {
+		var i Fee
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.LevelID,
+			&i.PeriodStart,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Months,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeesByUserPage = `-- name: ListFeesByUserPage :many
+SELECT id, user_id, level_id, period_start, amount, created_at, months FROM fees WHERE user_id = ? ORDER BY period_start DESC, id DESC LIMIT ? OFFSET ?
+`
+
+type ListFeesByUserPageParams struct {
+	UserID int64 `json:"user_id"`
+	Limit  int64 `json:"limit"`
+	Offset int64 `json:"offset"`
+}
+
+func (q *Queries) ListFeesByUserPage(ctx context.Context, arg ListFeesByUserPageParams) ([]Fee, error) {
+	rows, err := q.db.QueryContext(ctx, listFeesByUserPage, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Fee{}
+	for rows.Next() {
+		var i Fee
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.LevelID,
+			&i.PeriodStart,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Months,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeesToInvoiceInFakturoid = `-- name: ListFeesToInvoiceInFakturoid :many
+SELECT f.id AS fee_id, f.user_id, f.period_start, f.amount, f.months,
+    u.email, u.realname, u.payments_id,
+    b.company_name, b.company_id, b.vat_id, b.address, b.fakturoid_subject_id
+FROM fees f
+JOIN billing_details b ON b.user_id = f.user_id
+JOIN users u ON u.id = f.user_id
+WHERE b.fakturoid AND f.created_at >= b.fakturoid_since AND f.amount > 0
+  AND NOT EXISTS (SELECT 1 FROM fakturoid_invoices fi WHERE fi.fee_id = f.id)
+  AND NOT EXISTS (SELECT 1 FROM invoices i WHERE i.fee_id = f.id)
+ORDER BY f.id
+`
+
+type ListFeesToInvoiceInFakturoidRow struct {
+	FeeID              int64          `json:"fee_id"`
+	UserID             int64          `json:"user_id"`
+	PeriodStart        time.Time      `json:"period_start"`
+	Amount             money.Amount   `json:"amount"`
+	Months             int64          `json:"months"`
+	Email              string         `json:"email"`
+	Realname           sql.NullString `json:"realname"`
+	PaymentsID         sql.NullString `json:"payments_id"`
+	CompanyName        string         `json:"company_name"`
+	CompanyID          string         `json:"company_id"`
+	VatID              string         `json:"vat_id"`
+	Address            string         `json:"address"`
+	FakturoidSubjectID sql.NullInt64  `json:"fakturoid_subject_id"`
+}
+
+// Fees of company-billed members created since the flag was set that have neither
+// a Fakturoid invoice nor an invoice issued in the portal
+func (q *Queries) ListFeesToInvoiceInFakturoid(ctx context.Context) ([]ListFeesToInvoiceInFakturoidRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFeesToInvoiceInFakturoid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListFeesToInvoiceInFakturoidRow{}
+	for rows.Next() {
+		var i ListFeesToInvoiceInFakturoidRow
+		if err := rows.Scan(
+			&i.FeeID,
+			&i.UserID,
+			&i.PeriodStart,
+			&i.Amount,
+			&i.Months,
+			&i.Email,
+			&i.Realname,
+			&i.PaymentsID,
+			&i.CompanyName,
+			&i.CompanyID,
+			&i.VatID,
+			&i.Address,
+			&i.FakturoidSubjectID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInvoicesByUser = `-- name: ListInvoicesByUser :many
+SELECT id, user_id, state, number, months, amount, company_name, company_id, vat_id, address, note, admin_comment, decided_by, issued_at, due_at, payment_id, paid_at, created_at, fee_id, charge_id, item FROM invoices WHERE user_id = ? ORDER BY id DESC
+`
+
+func (q *Queries) ListInvoicesByUser(ctx context.Context, userID int64) ([]Invoice, error) {
+	rows, err := q.db.QueryContext(ctx, listInvoicesByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Invoice{}
+	for rows.Next() {
+		var i Invoice
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.State,
+			&i.Number,
+			&i.Months,
+			&i.Amount,
+			&i.CompanyName,
+			&i.CompanyID,
+			&i.VatID,
+			&i.Address,
+			&i.Note,
+			&i.AdminComment,
+			&i.DecidedBy,
+			&i.IssuedAt,
+			&i.DueAt,
+			&i.PaymentID,
+			&i.PaidAt,
+			&i.CreatedAt,
+			&i.FeeID,
+			&i.ChargeID,
+			&i.Item,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInvoicesWithUsers = `-- name: ListInvoicesWithUsers :many
+SELECT i.id, i.user_id, i.state, i.number, i.months, i.amount, i.company_name, i.company_id, i.vat_id, i.address, i.note, i.admin_comment, i.decided_by, i.issued_at, i.due_at, i.payment_id, i.paid_at, i.created_at, i.fee_id, i.charge_id, i.item, u.email, u.realname
+FROM invoices i
+JOIN users u ON i.user_id = u.id
+ORDER BY CASE WHEN i.state = 'requested' THEN 0 ELSE 1 END, i.id DESC
+LIMIT ?
+`
+
+type ListInvoicesWithUsersRow struct {
+	ID           int64          `json:"id"`
+	UserID       int64          `json:"user_id"`
+	State        string         `json:"state"`
+	Number       sql.NullString `json:"number"`
+	Months       int64          `json:"months"`
+	Amount       money.Amount   `json:"amount"`
+	CompanyName  string         `json:"company_name"`
+	CompanyID    string         `json:"company_id"`
+	VatID        string         `json:"vat_id"`
+	Address      string         `json:"address"`
+	Note         sql.NullString `json:"note"`
+	AdminComment sql.NullString `json:"admin_comment"`
+	DecidedBy    sql.NullString `json:"decided_by"`
+	IssuedAt     sql.NullTime   `json:"issued_at"`
+	DueAt        sql.NullTime   `json:"due_at"`
+	PaymentID    sql.NullInt64  `json:"payment_id"`
+	PaidAt       sql.NullTime   `json:"paid_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	FeeID        sql.NullInt64  `json:"fee_id"`
+	ChargeID     sql.NullInt64  `json:"charge_id"`
+	Item         sql.NullString `json:"item"`
+	Email        string         `json:"email"`
+	Realname     sql.NullString `json:"realname"`
+}
+
+// Admin queue: pending requests first, then newest
+func (q *Queries) ListInvoicesWithUsers(ctx context.Context, limit int64) ([]ListInvoicesWithUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listInvoicesWithUsers, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListInvoicesWithUsersRow{}
+	for rows.Next() {
+		var i ListInvoicesWithUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.State,
+			&i.Number,
+			&i.Months,
+			&i.Amount,
+			&i.CompanyName,
+			&i.CompanyID,
+			&i.VatID,
+			&i.Address,
+			&i.Note,
+			&i.AdminComment,
+			&i.DecidedBy,
+			&i.IssuedAt,
+			&i.DueAt,
+			&i.PaymentID,
+			&i.PaidAt,
+			&i.CreatedAt,
+			&i.FeeID,
+			&i.ChargeID,
+			&i.Item,
+			&i.Email,
+			&i.Realname,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLatestBalanceSnapshots = `-- name: ListLatestBalanceSnapshots :many
+SELECT user_id, month, balance, computed_at FROM balance_snapshots s
+WHERE s.month = (SELECT MAX(month) FROM balance_snapshots WHERE user_id = s.user_id)
+`
+
+// Newest snapshot of each user
+func (q *Queries) ListLatestBalanceSnapshots(ctx context.Context) ([]BalanceSnapshot, error) {
+	rows, err := q.db.QueryContext(ctx, listLatestBalanceSnapshots)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []BalanceSnapshot{}
+	for rows.Next() {
+		var i BalanceSnapshot
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Month,
+			&i.Balance,
+			&i.ComputedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLevelChangeRequestsByUser = `-- name: ListLevelChangeRequestsByUser :many
+SELECT r.id, r.user_id, r.level_id, r.effective_from, r.note, r.state, r.admin_comment, r.decided_by, r.decided_at, r.created_at, l.name AS level_name, l.amount AS level_amount
+FROM level_change_requests r
+JOIN levels l ON r.level_id = l.id
+WHERE r.user_id = ?
+ORDER BY r.created_at DESC, r.id DESC
+`
+
+type ListLevelChangeRequestsByUserRow struct {
+	ID            int64          `json:"id"`
+	UserID        int64          `json:"user_id"`
+	LevelID       int64          `json:"level_id"`
+	EffectiveFrom time.Time      `json:"effective_from"`
+	Note          sql.NullString `json:"note"`
+	State         string         `json:"state"`
+	AdminComment  sql.NullString `json:"admin_comment"`
+	DecidedBy     sql.NullString `json:"decided_by"`
+	DecidedAt     sql.NullTime   `json:"decided_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	LevelName     string         `json:"level_name"`
+	LevelAmount   money.Amount   `json:"level_amount"`
+}
+
+func (q *Queries) ListLevelChangeRequestsByUser(ctx context.Context, userID int64) ([]ListLevelChangeRequestsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLevelChangeRequestsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListLevelChangeRequestsByUserRow{}
+	for rows.Next() {
+		var i ListLevelChangeRequestsByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.LevelID,
+			&i.EffectiveFrom,
+			&i.Note,
+			&i.State,
+			&i.AdminComment,
+			&i.DecidedBy,
+			&i.DecidedAt,
+			&i.CreatedAt,
+			&i.LevelName,
+			&i.LevelAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLevelHistoryByUser = `-- name: ListLevelHistoryByUser :many
+SELECT h.id, h.user_id, h.level_id, h.effective_from, h.request_id, h.created_by, h.applied_at, h.created_at, l.name AS level_name, l.amount AS level_amount
+FROM level_history h
+JOIN levels l ON h.level_id = l.id
+WHERE h.user_id = ?
+ORDER BY h.effective_from DESC, h.id DESC
+`
+
+type ListLevelHistoryByUserRow struct {
+	ID            int64         `json:"id"`
+	UserID        int64         `json:"user_id"`
+	LevelID       int64         `json:"level_id"`
+	EffectiveFrom time.Time     `json:"effective_from"`
+	RequestID     sql.NullInt64 `json:"request_id"`
+	CreatedBy     string        `json:"created_by"`
+	AppliedAt     sql.NullTime  `json:"applied_at"`
+	CreatedAt     time.Time     `json:"created_at"`
+	LevelName     string        `json:"level_name"`
+	LevelAmount   money.Amount  `json:"level_amount"`
+}
+
+func (q *Queries) ListLevelHistoryByUser(ctx context.Context, userID int64) ([]ListLevelHistoryByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLevelHistoryByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListLevelHistoryByUserRow{}
+	for rows.Next() {
+		var i ListLevelHistoryByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.LevelID,
+			&i.EffectiveFrom,
+			&i.RequestID,
+			&i.CreatedBy,
+			&i.AppliedAt,
+			&i.CreatedAt,
+			&i.LevelName,
+			&i.LevelAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLevelPriceChangeNotices = `-- name: ListLevelPriceChangeNotices :many
+SELECT user_id FROM level_price_change_notices WHERE change_id = ?
+`
+
+func (q *Queries) ListLevelPriceChangeNotices(ctx context.Context, changeID int64) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listLevelPriceChangeNotices, changeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var user_id int64
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLevelPriceChanges = `-- name: ListLevelPriceChanges :many
+SELECT c.id, c.level_id, c.new_amount, c.effective_from, c.note, c.created_by, c.notified_at, c.applied_at, c.created_at, l.name AS level_name, l.amount AS level_amount
+FROM level_price_changes c
+JOIN levels l ON c.level_id = l.id
+ORDER BY c.applied_at IS NOT NULL, c.effective_from DESC
+LIMIT ?
+`
+
+type ListLevelPriceChangesRow struct {
+	ID            int64          `json:"id"`
+	LevelID       int64          `json:"level_id"`
+	NewAmount     money.Amount   `json:"new_amount"`
+	EffectiveFrom time.Time      `json:"effective_from"`
+	Note          sql.NullString `json:"note"`
+	CreatedBy     string         `json:"created_by"`
+	NotifiedAt    sql.NullTime   `json:"notified_at"`
+	AppliedAt     sql.NullTime   `json:"applied_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	LevelName     string         `json:"level_name"`
+	LevelAmount   money.Amount   `json:"level_amount"`
+}
+
+func (q *Queries) ListLevelPriceChanges(ctx context.Context, limit int64) ([]ListLevelPriceChangesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLevelPriceChanges, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListLevelPriceChangesRow{}
+	for rows.Next() {
+		var i ListLevelPriceChangesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.LevelID,
+			&i.NewAmount,
+			&i.EffectiveFrom,
+			&i.Note,
+			&i.CreatedBy,
+			&i.NotifiedAt,
+			&i.AppliedAt,
+			&i.CreatedAt,
+			&i.LevelName,
+			&i.LevelAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLevelPriceChangesToNotify = `-- name: ListLevelPriceChangesToNotify :many
+SELECT c.id, c.level_id, c.new_amount, c.effective_from, c.note, c.created_by, c.notified_at, c.applied_at, c.created_at, l.name AS level_name, l.amount AS level_amount
+FROM level_price_changes c
+JOIN levels l ON c.level_id = l.id
+WHERE c.applied_at IS NULL AND c.notified_at IS NULL AND c.effective_from <= ?
+ORDER BY c.effective_from
+`
+
+type ListLevelPriceChangesToNotifyRow struct {
+	ID            int64          `json:"id"`
+	LevelID       int64          `json:"level_id"`
+	NewAmount     money.Amount   `json:"new_amount"`
+	EffectiveFrom time.Time      `json:"effective_from"`
+	Note          sql.NullString `json:"note"`
+	CreatedBy     string         `json:"created_by"`
+	NotifiedAt    sql.NullTime   `json:"notified_at"`
+	AppliedAt     sql.NullTime   `json:"applied_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	LevelName     string         `json:"level_name"`
+	LevelAmount   money.Amount   `json:"level_amount"`
+}
+
+// Pending changes whose notice period has started (effective_from <= now + notice)
+func (q *Queries) ListLevelPriceChangesToNotify(ctx context.Context, effectiveFrom time.Time) ([]ListLevelPriceChangesToNotifyRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLevelPriceChangesToNotify, effectiveFrom)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListLevelPriceChangesToNotifyRow{}
+	for rows.Next() {
+		var i ListLevelPriceChangesToNotifyRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.LevelID,
+			&i.NewAmount,
+			&i.EffectiveFrom,
+			&i.Note,
+			&i.CreatedBy,
+			&i.NotifiedAt,
+			&i.AppliedAt,
+			&i.CreatedAt,
+			&i.LevelName,
+			&i.LevelAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLevels = `-- name: ListLevels :many
+SELECT id, name, amount, active, created_at, btcpay FROM levels WHERE active = TRUE ORDER BY amount
+`
+
+func (q *Queries) ListLevels(ctx context.Context) ([]Level, error) {
+	rows, err := q.db.QueryContext(ctx, listLevels)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Level{}
+	for rows.Next() {
+		var i Level
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Amount,
+			&i.Active,
+			&i.CreatedAt,
+			&i.Btcpay,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLinkedKeycloakIDs = `-- name: ListLinkedKeycloakIDs :many
+SELECT keycloak_id FROM users WHERE keycloak_id IS NOT NULL AND keycloak_id != ''
+`
+
+func (q *Queries) ListLinkedKeycloakIDs(ctx context.Context) ([]sql.NullString, error) {
+	rows, err := q.db.QueryContext(ctx, listLinkedKeycloakIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []sql.NullString{}
+	for rows.Next() {
+		var keycloak_id sql.NullString
+		if err := rows.Scan(&keycloak_id); err != nil {
+			return nil, err
+		}
+		items = append(items, keycloak_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLogsBySubsystem = `-- name: ListLogsBySubsystem :many
+SELECT id, subsystem, level, user_id, message, metadata, created_at FROM system_logs WHERE subsystem = ? ORDER BY created_at DESC LIMIT ?
+`
+
+type ListLogsBySubsystemParams struct {
+	Subsystem string `json:"subsystem"`
+	Limit     int64  `json:"limit"`
+}
+
+func (q *Queries) ListLogsBySubsystem(ctx context.Context, arg ListLogsBySubsystemParams) ([]SystemLog, error) {
+	rows, err := q.db.QueryContext(ctx, listLogsBySubsystem, arg.Subsystem, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SystemLog{}
+	for rows.Next() {
+		var i SystemLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Subsystem,
+			&i.Level,
+			&i.UserID,
+			&i.Message,
+			&i.Metadata,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLogsByUser = `-- name: ListLogsByUser :many
+SELECT id, subsystem, level, user_id, message, metadata, created_at FROM system_logs WHERE user_id = ? ORDER BY created_at DESC LIMIT ?
+`
+
+type ListLogsByUserParams struct {
+	UserID sql.NullInt64 `json:"user_id"`
+	Limit  int64         `json:"limit"`
+}
+
+func (q *Queries) ListLogsByUser(ctx context.Context, arg ListLogsByUserParams) ([]SystemLog, error) {
+	rows, err := q.db.QueryContext(ctx, listLogsByUser, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SystemLog{}
+	for rows.Next() {
+		var i SystemLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Subsystem,
+			&i.Level,
+			&i.UserID,
+			&i.Message,
+			&i.Metadata,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLogsFiltered = `-- name: ListLogsFiltered :many
+SELECT id, subsystem, level, user_id, message, metadata, created_at FROM system_logs
+WHERE (? = '' OR subsystem = ?)
+  AND (? = '' OR level = ?)
+  AND (? = 0 OR user_id = ?)
+ORDER BY created_at DESC LIMIT ?
+`
+
+type ListLogsFilteredParams struct {
+	Column1   interface{}   `json:"column_1"`
+	Subsystem string        `json:"subsystem"`
+	Column3   interface{}   `json:"column_3"`
+	Level     string        `json:"level"`
+	Column5   interface{}   `json:"column_5"`
+	UserID    sql.NullInt64 `json:"user_id"`
+	Limit     int64         `json:"limit"`
+}
+
+func (q *Queries) ListLogsFiltered(ctx context.Context, arg ListLogsFilteredParams) ([]SystemLog, error) {
+	rows, err := q.db.QueryContext(ctx, listLogsFiltered,
+		arg.Column1,
+		arg.Subsystem,
+		arg.Column3,
+		arg.Level,
+		arg.Column5,
+		arg.UserID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SystemLog{}
+	for rows.Next() {
+		var i SystemLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Subsystem,
+			&i.Level,
+			&i.UserID,
+			&i.Message,
+			&i.Metadata,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMemberMilestones = `-- name: ListMemberMilestones :many
+SELECT user_id, milestone, emailed, announced, created_at FROM member_milestones
+`
+
+func (q *Queries) ListMemberMilestones(ctx context.Context) ([]MemberMilestone, error) {
+	rows, err := q.db.QueryContext(ctx, listMemberMilestones)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MemberMilestone{}
+	for rows.Next() {
+		var i MemberMilestone
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Milestone,
+			&i.Emailed,
+			&i.Announced,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMemberProjects = `-- name: ListMemberProjects :many
+SELECT p.id, p.name, p.description, p.public,
+    CAST(m.user_id IS NOT NULL AS BOOLEAN) AS joined, COALESCE(m.role, '') AS role
+FROM projects p
+LEFT JOIN project_members m ON m.project_id = p.id AND m.user_id = ?
+ORDER BY joined DESC, p.name
+`
+
+type ListMemberProjectsRow struct {
+	ID          int64          `json:"id"`
+	Name        string         `json:"name"`
+	Description sql.NullString `json:"description"`
+	Public      bool           `json:"public"`
+	Joined      bool           `json:"joined"`
+	Role        string         `json:"role"`
+}
+
+// All projects with the member's involvement, joined ones first
+func (q *Queries) ListMemberProjects(ctx context.Context, userID int64) ([]ListMemberProjectsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listMemberProjects, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMemberProjectsRow{}
+	for rows.Next() {
+		var i ListMemberProjectsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Public,
+			&i.Joined,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMembershipPaymentsByUser = `-- name: ListMembershipPaymentsByUser :many
+SELECT p.id, p.user_id, p.date, p.amount, p.kind, p.kind_id, p.local_account, p.remote_account, p.identification, p.raw_data, p.staff_comment, p.created_at, p.project_id, p.dismissed_at, p.dismissed_by, p.dismissed_reason, p.reversal_of, p.reversal_review, p.content_hash, p.classification
+FROM payments p
+JOIN users u ON p.user_id = u.id
+WHERE p.user_id = ?
+AND p.identification = u.payments_id
+AND p.classification != 'donation'
+AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+ORDER BY p.date DESC
+`
+
+// Only payments that match the user's membership VS (payments_id) and are not
+// donations; split payments count through ListPaymentSplitsByUser instead
+func (q *Queries) ListMembershipPaymentsByUser(ctx context.Context, userID sql.NullInt64) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, listMembershipPaymentsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Payment{}
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Date,
+			&i.Amount,
+			&i.Kind,
+			&i.KindID,
+			&i.LocalAccount,
+			&i.RemoteAccount,
+			&i.Identification,
+			&i.RawData,
+			&i.StaffComment,
+			&i.CreatedAt,
+			&i.ProjectID,
+			&i.DismissedAt,
+			&i.DismissedBy,
+			&i.DismissedReason,
+			&i.ReversalOf,
+			&i.ReversalReview,
+			&i.ContentHash,
+			&i.Classification,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMembershipPaymentsSince = `-- name: ListMembershipPaymentsSince :many
+SELECT p.user_id, p.date, p.amount
+FROM payments p
+JOIN users u ON u.id = p.user_id
+WHERE u.state = 'accepted'
+  AND p.identification = u.payments_id AND p.classification != 'donation'
+  AND p.project_id IS NULL AND p.dismissed_at IS NULL AND p.amount > 0
+  AND p.date >= ?
+ORDER BY p.user_id, p.date
+`
+
+type ListMembershipPaymentsSinceRow struct {
+	UserID sql.NullInt64 `json:"user_id"`
+	Date   time.Time     `json:"date"`
+	Amount money.Amount  `json:"amount"`
+}
+
+// Incoming payments with the member's own VS (the ones counted in the balance)
+// of accepted members, for recognizing standing orders
+func (q *Queries) ListMembershipPaymentsSince(ctx context.Context, date time.Time) ([]ListMembershipPaymentsSinceRow, error) {
+	rows, err := q.db.QueryContext(ctx, listMembershipPaymentsSince, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMembershipPaymentsSinceRow{}
+	for rows.Next() {
+		var i ListMembershipPaymentsSinceRow
+		if err := rows.Scan(&i.UserID, &i.Date, &i.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMergeDuplicateFees = `-- name: ListMergeDuplicateFees :many
+SELECT f.id, f.period_start, f.amount,
+    CAST(EXISTS (SELECT 1 FROM invoices i WHERE i.fee_id = f.id)
+        OR EXISTS (SELECT 1 FROM fakturoid_invoices fi WHERE fi.fee_id = f.id)
+        OR EXISTS (SELECT 1 FROM balance_adjustments a WHERE a.fee_id = f.id) AS BOOLEAN) AS referenced
+FROM fees f
+WHERE f.user_id = ?1
+  AND EXISTS (SELECT 1 FROM fees t WHERE t.user_id = ?2 AND t.period_start = f.period_start)
+ORDER BY f.period_start
+`
+
+type ListMergeDuplicateFeesParams struct {
+	FromUserID int64 `json:"from_user_id"`
+	ToUserID   int64 `json:"to_user_id"`
+}
+
+type ListMergeDuplicateFeesRow struct {
+	ID          int64        `json:"id"`
+	PeriodStart time.Time    `json:"period_start"`
+	Amount      money.Amount `json:"amount"`
+	Referenced  bool         `json:"referenced"`
+}
+
+// Fees of the source user for periods the target user is billed for too; referenced =
+// invoiced or adjusted, such a fee cannot be dropped
+func (q *Queries) ListMergeDuplicateFees(ctx context.Context, arg ListMergeDuplicateFeesParams) ([]ListMergeDuplicateFeesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listMergeDuplicateFees, arg.FromUserID, arg.ToUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMergeDuplicateFeesRow{}
+	for rows.Next() {
+		var i ListMergeDuplicateFeesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PeriodStart,
+			&i.Amount,
+			&i.Referenced,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNotificationOptOuts = `-- name: ListNotificationOptOuts :many
+SELECT user_id FROM user_notification_preferences WHERE notification = ? AND enabled = FALSE
+`
+
+func (q *Queries) ListNotificationOptOuts(ctx context.Context, notification string) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listNotificationOptOuts, notification)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var user_id int64
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOpenFakturoidInvoices = `-- name: ListOpenFakturoidInvoices :many
+SELECT fee_id, user_id, fakturoid_id, number, status, public_url, paid_on, created_at, synced_at FROM fakturoid_invoices
+WHERE status NOT IN ('paid', 'cancelled', 'uncollectible')
+ORDER BY fee_id
+`
+
+// Invoices whose status can still change
+func (q *Queries) ListOpenFakturoidInvoices(ctx context.Context) ([]FakturoidInvoice, error) {
+	rows, err := q.db.QueryContext(ctx, listOpenFakturoidInvoices)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []FakturoidInvoice{}
+	for rows.Next() {
+		var i FakturoidInvoice
+		if err := rows.Scan(
+			&i.FeeID,
+			&i.UserID,
+			&i.FakturoidID,
+			&i.Number,
+			&i.Status,
+			&i.PublicUrl,
+			&i.PaidOn,
+			&i.CreatedAt,
+			&i.SyncedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOpenMembershipPauses = `-- name: ListOpenMembershipPauses :many
+SELECT id, user_id, start_date, end_date, reason, created_by, created_at, ended_at FROM membership_pauses WHERE ended_at IS NULL ORDER BY user_id, id
+`
+
+func (q *Queries) ListOpenMembershipPauses(ctx context.Context) ([]MembershipPause, error) {
+	rows, err := q.db.QueryContext(ctx, listOpenMembershipPauses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MembershipPause{}
+	for rows.Next() {
+		var i MembershipPause
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.StartDate,
+			&i.EndDate,
+			&i.Reason,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.EndedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentMatchRules = `-- name: ListPaymentMatchRules :many
+SELECT r.id, r.name, r.priority, r.remote_account, r.message_pattern, r.specific_symbol,
+    r.amount_min, r.amount_max, r.user_id, r.project_id, r.active, r.match_count,
+    r.last_matched_at, r.created_by, r.created_at,
+    COALESCE(u.email, '') AS user_email, COALESCE(p.name, '') AS project_name
+FROM payment_match_rules r
+LEFT JOIN users u ON u.id = r.user_id
+LEFT JOIN projects p ON p.id = r.project_id
+ORDER BY r.priority, r.id
+`
+
+type ListPaymentMatchRulesRow struct {
+	ID             int64           `json:"id"`
+	Name           string          `json:"name"`
+	Priority       int64           `json:"priority"`
+	RemoteAccount  string          `json:"remote_account"`
+	MessagePattern string          `json:"message_pattern"`
+	SpecificSymbol string          `json:"specific_symbol"`
+	AmountMin      sql.NullFloat64 `json:"amount_min"`
+	AmountMax      sql.NullFloat64 `json:"amount_max"`
+	UserID         sql.NullInt64   `json:"user_id"`
+	ProjectID      sql.NullInt64   `json:"project_id"`
+	Active         bool            `json:"active"`
+	MatchCount     int64           `json:"match_count"`
+	LastMatchedAt  sql.NullTime    `json:"last_matched_at"`
+	CreatedBy      string          `json:"created_by"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UserEmail      string          `json:"user_email"`
+	ProjectName    string          `json:"project_name"`
+}
+
+func (q *Queries) ListPaymentMatchRules(ctx context.Context) ([]ListPaymentMatchRulesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPaymentMatchRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPaymentMatchRulesRow{}
+	for rows.Next() {
+		var i ListPaymentMatchRulesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Priority,
+			&i.RemoteAccount,
+			&i.MessagePattern,
+			&i.SpecificSymbol,
+			&i.AmountMin,
+			&i.AmountMax,
+			&i.UserID,
+			&i.ProjectID,
+			&i.Active,
+			&i.MatchCount,
+			&i.LastMatchedAt,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.UserEmail,
+			&i.ProjectName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentReminders = `-- name: ListPaymentReminders :many
+SELECT user_id, due_date, amount, created_at FROM payment_reminders
+`
+
+func (q *Queries) ListPaymentReminders(ctx context.Context) ([]PaymentReminder, error) {
+	rows, err := q.db.QueryContext(ctx, listPaymentReminders)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PaymentReminder{}
+	for rows.Next() {
+		var i PaymentReminder
+		if err := rows.Scan(
+			&i.UserID,
+			&i.DueDate,
+			&i.Amount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentStatementsByYear = `-- name: ListPaymentStatementsByYear :many
+SELECT user_id, year, sent_at FROM payment_statements WHERE year = ?
+`
+
+// Members whose payment statement for the year was already emailed
+func (q *Queries) ListPaymentStatementsByYear(ctx context.Context, year int64) ([]PaymentStatement, error) {
+	rows, err := q.db.QueryContext(ctx, listPaymentStatementsByYear, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PaymentStatement{}
+	for rows.Next() {
+		var i PaymentStatement
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Year,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentsByUserPage = `-- name: ListPaymentsByUserPage :many
+SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification FROM payments WHERE user_id = ? ORDER BY date DESC, id DESC LIMIT ? OFFSET ?
+`
+
+type ListPaymentsByUserPageParams struct {
+	UserID sql.NullInt64 `json:"user_id"`
+	Limit  int64         `json:"limit"`
+	Offset int64         `json:"offset"`
+}
+
+func (q *Queries) ListPaymentsByUserPage(ctx context.Context, arg ListPaymentsByUserPageParams) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, listPaymentsByUserPage, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Payment{}
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Date,
+			&i.Amount,
+			&i.Kind,
+			&i.KindID,
+			&i.LocalAccount,
+			&i.RemoteAccount,
+			&i.Identification,
+			&i.RawData,
+			&i.StaffComment,
+			&i.CreatedAt,
+			&i.ProjectID,
+			&i.DismissedAt,
+			&i.DismissedBy,
+			&i.DismissedReason,
+			&i.ReversalOf,
+			&i.ReversalReview,
+			&i.ContentHash,
+			&i.Classification,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentSplits = `-- name: ListPaymentSplits :many
+SELECT s.id, s.payment_id, s.user_id, s.project_id, s.amount, s.note, s.created_by, s.created_at,
+    s.classification, COALESCE(u.email, '') AS user_email, COALESCE(pr.name, '') AS project_name
+FROM payment_splits s
+LEFT JOIN users u ON u.id = s.user_id
+LEFT JOIN projects pr ON pr.id = s.project_id
+WHERE s.payment_id = ?
+ORDER BY s.id
+`
+
+type ListPaymentSplitsRow struct {
+	ID             int64         `json:"id"`
+	PaymentID      int64         `json:"payment_id"`
+	UserID         sql.NullInt64 `json:"user_id"`
+	ProjectID      sql.NullInt64 `json:"project_id"`
+	Amount         money.Amount  `json:"amount"`
+	Note           string        `json:"note"`
+	CreatedBy      string        `json:"created_by"`
+	CreatedAt      time.Time     `json:"created_at"`
+	Classification string        `json:"classification"`
+	UserEmail      string        `json:"user_email"`
+	ProjectName    string        `json:"project_name"`
+}
+
+func (q *Queries) ListPaymentSplits(ctx context.Context, paymentID int64) ([]ListPaymentSplitsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPaymentSplits, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPaymentSplitsRow{}
+	for rows.Next() {
+		var i ListPaymentSplitsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PaymentID,
+			&i.UserID,
+			&i.ProjectID,
+			&i.Amount,
+			&i.Note,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.Classification,
+			&i.UserEmail,
+			&i.ProjectName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentSplitsByUser = `-- name: ListPaymentSplitsByUser :many
+SELECT s.id, s.payment_id, s.amount, s.note, p.date
+FROM payment_splits s
+JOIN payments p ON p.id = s.payment_id
+WHERE s.user_id = ? AND s.classification = 'fee'
+ORDER BY p.date DESC
+`
+
+type ListPaymentSplitsByUserRow struct {
+	ID        int64        `json:"id"`
+	PaymentID int64        `json:"payment_id"`
+	Amount    money.Amount `json:"amount"`
+	Note      string       `json:"note"`
+	Date      time.Time    `json:"date"`
+}
+
+// Fee allocations to the member with the date of the split payment
+func (q *Queries) ListPaymentSplitsByUser(ctx context.Context, userID sql.NullInt64) ([]ListPaymentSplitsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPaymentSplitsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPaymentSplitsByUserRow{}
+	for rows.Next() {
+		var i ListPaymentSplitsByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PaymentID,
+			&i.Amount,
+			&i.Note,
+			&i.Date,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentSuggestions = `-- name: ListPaymentSuggestions :many
+SELECT s.payment_id, s.user_id, s.payment_count, u.email
+FROM payment_suggestions s
+JOIN users u ON u.id = s.user_id
+JOIN payments p ON p.id = s.payment_id
+WHERE s.state = 'suggested' AND p.user_id IS NULL AND p.project_id IS NULL AND p.dismissed_at IS NULL
+`
+
+type ListPaymentSuggestionsRow struct {
+	PaymentID    int64  `json:"payment_id"`
+	UserID       int64  `json:"user_id"`
+	PaymentCount int64  `json:"payment_count"`
+	Email        string `json:"email"`
+}
+
+// Open suggestions of payments that are still unassigned
+func (q *Queries) ListPaymentSuggestions(ctx context.Context) ([]ListPaymentSuggestionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPaymentSuggestions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPaymentSuggestionsRow{}
+	for rows.Next() {
+		var i ListPaymentSuggestionsRow
+		if err := rows.Scan(
+			&i.PaymentID,
+			&i.UserID,
+			&i.PaymentCount,
+			&i.Email,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentsByUser = `-- name: ListPaymentsByUser :many
+SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification FROM payments WHERE user_id = ? ORDER BY date DESC
+`
+
+func (q *Queries) ListPaymentsByUser(ctx context.Context, userID sql.NullInt64) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, listPaymentsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Payment{}
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Date,
+			&i.Amount,
+			&i.Kind,
+			&i.KindID,
+			&i.LocalAccount,
+			&i.RemoteAccount,
+			&i.Identification,
+			&i.RawData,
+			&i.StaffComment,
+			&i.CreatedAt,
+			&i.ProjectID,
+			&i.DismissedAt,
+			&i.DismissedBy,
+			&i.DismissedReason,
+			&i.ReversalOf,
+			&i.ReversalReview,
+			&i.ContentHash,
+			&i.Classification,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentsWithoutContentHash = `-- name: ListPaymentsWithoutContentHash :many
+SELECT id, date, amount, remote_account, identification
+FROM payments
+WHERE content_hash = ''
+`
+
+type ListPaymentsWithoutContentHashRow struct {
+	ID             int64        `json:"id"`
+	Date           time.Time    `json:"date"`
+	Amount         money.Amount `json:"amount"`
+	RemoteAccount  string       `json:"remote_account"`
+	Identification string       `json:"identification"`
+}
+
+func (q *Queries) ListPaymentsWithoutContentHash(ctx context.Context) ([]ListPaymentsWithoutContentHashRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPaymentsWithoutContentHash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPaymentsWithoutContentHashRow{}
+	for rows.Next() {
+		var i ListPaymentsWithoutContentHashRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Amount,
+			&i.RemoteAccount,
+			&i.Identification,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingApplicationsForMember = `-- name: ListPendingApplicationsForMember :many
+SELECT a.id, a.realname, a.motivation, a.created_at, l.name AS level_name,
+    (
+        SELECT COUNT(*) FROM application_vouches v JOIN users u ON u.id = v.user_id
+        WHERE v.application_id = a.id AND u.state = 'accepted'
+    ) AS vouch_count,
+    CAST(EXISTS (
+        SELECT 1 FROM application_vouches v WHERE v.application_id = a.id AND v.user_id = ?1
+    ) AS BOOLEAN) AS vouched
+FROM applications a
+JOIN levels l ON a.level_id = l.id
+WHERE a.state = 'pending'
+ORDER BY a.created_at, a.id
+`
+
+type ListPendingApplicationsForMemberRow struct {
+	ID         int64     `json:"id"`
+	Realname   string    `json:"realname"`
+	Motivation string    `json:"motivation"`
+	CreatedAt  time.Time `json:"created_at"`
+	LevelName  string    `json:"level_name"`
+	VouchCount int64     `json:"vouch_count"`
+	Vouched    bool      `json:"vouched"`
+}
+
+// Pending applications with their vouch count and whether the member vouched
+func (q *Queries) ListPendingApplicationsForMember(ctx context.Context, userID int64) ([]ListPendingApplicationsForMemberRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPendingApplicationsForMember, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPendingApplicationsForMemberRow{}
+	for rows.Next() {
+		var i ListPendingApplicationsForMemberRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Realname,
+			&i.Motivation,
+			&i.CreatedAt,
+			&i.LevelName,
+			&i.VouchCount,
+			&i.Vouched,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingLevelChangeRequests = `-- name: ListPendingLevelChangeRequests :many
+SELECT r.id, r.user_id, r.level_id, r.effective_from, r.note, r.state, r.admin_comment, r.decided_by, r.decided_at, r.created_at, l.name AS level_name, l.amount AS level_amount,
+    u.email, u.realname, u.username, cl.name AS current_level_name
+FROM level_change_requests r
+JOIN levels l ON r.level_id = l.id
+JOIN users u ON r.user_id = u.id
+JOIN levels cl ON u.level_id = cl.id
+WHERE r.state = 'requested'
+ORDER BY r.created_at, r.id
+`
+
+type ListPendingLevelChangeRequestsRow struct {
+	ID               int64          `json:"id"`
+	UserID           int64          `json:"user_id"`
+	LevelID          int64          `json:"level_id"`
+	EffectiveFrom    time.Time      `json:"effective_from"`
+	Note             sql.NullString `json:"note"`
+	State            string         `json:"state"`
+	AdminComment     sql.NullString `json:"admin_comment"`
+	DecidedBy        sql.NullString `json:"decided_by"`
+	DecidedAt        sql.NullTime   `json:"decided_at"`
+	CreatedAt        time.Time      `json:"created_at"`
+	LevelName        string         `json:"level_name"`
+	LevelAmount      money.Amount   `json:"level_amount"`
+	Email            string         `json:"email"`
+	Realname         sql.NullString `json:"realname"`
+	Username         sql.NullString `json:"username"`
+	CurrentLevelName string         `json:"current_level_name"`
+}
+
+func (q *Queries) ListPendingLevelChangeRequests(ctx context.Context) ([]ListPendingLevelChangeRequestsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPendingLevelChangeRequests)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPendingLevelChangeRequestsRow{}
+	for rows.Next() {
+		var i ListPendingLevelChangeRequestsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.LevelID,
+			&i.EffectiveFrom,
+			&i.Note,
+			&i.State,
+			&i.AdminComment,
+			&i.DecidedBy,
+			&i.DecidedAt,
+			&i.CreatedAt,
+			&i.LevelName,
+			&i.LevelAmount,
+			&i.Email,
+			&i.Realname,
+			&i.Username,
+			&i.CurrentLevelName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingReversals = `-- name: ListPendingReversals :many
+SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification FROM payments WHERE reversal_review = TRUE AND dismissed_at IS NULL ORDER BY date DESC
+`
+
+func (q *Queries) ListPendingReversals(ctx context.Context) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, listPendingReversals)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Payment{}
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Date,
+			&i.Amount,
+			&i.Kind,
+			&i.KindID,
+			&i.LocalAccount,
+			&i.RemoteAccount,
+			&i.Identification,
+			&i.RawData,
+			&i.StaffComment,
+			&i.CreatedAt,
+			&i.ProjectID,
+			&i.DismissedAt,
+			&i.DismissedBy,
+			&i.DismissedReason,
+			&i.ReversalOf,
+			&i.ReversalReview,
+			&i.ContentHash,
+			&i.Classification,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectLedger = `-- name: ListProjectLedger :many
+SELECT 'payment' AS entry_type, p.id AS entry_id, p.date, p.amount,
+    p.remote_account AS counterparty, COALESCE(p.staff_comment, '') AS note
+FROM payments p
+WHERE (p.project_id = ?1
+   OR p.identification IN (SELECT pv.vs FROM project_vs pv WHERE pv.project_id = ?1))
+  AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+UNION ALL
+SELECT 'split', s.payment_id, p.date, s.amount, p.remote_account, s.note
+FROM payment_splits s
+JOIN payments p ON p.id = s.payment_id
+WHERE s.project_id = ?1
+UNION ALL
+SELECT 'expense', e.id, e.date, -e.amount,
+    COALESCE(NULLIF(e.remote_name, ''), e.remote_account), e.message
+FROM expenses e
+WHERE e.project_id = ?1
+ORDER BY 3, 2
+`
+
+type ListProjectLedgerRow struct {
+	EntryType    string       `json:"entry_type"`
+	EntryID      int64        `json:"entry_id"`
+	Date         time.Time    `json:"date"`
+	Amount       money.Amount `json:"amount"`
+	Counterparty string       `json:"counterparty"`
+	Note         string       `json:"note"`
+}
+
+// Income (as in GetProjectIncome) and expenses of a project, expenses with
+// negative amounts, oldest first
+func (q *Queries) ListProjectLedger(ctx context.Context, projectID sql.NullInt64) ([]ListProjectLedgerRow, error) {
+	rows, err := q.db.QueryContext(ctx, listProjectLedger, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListProjectLedgerRow{}
+	for rows.Next() {
+		var i ListProjectLedgerRow
+		if err := rows.Scan(
+			&i.EntryType,
+			&i.EntryID,
+			&i.Date,
+			&i.Amount,
+			&i.Counterparty,
+			&i.Note,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectMembers = `-- name: ListProjectMembers :many
+SELECT m.user_id, u.email, u.realname, u.username, u.state, m.role, m.created_at
+FROM project_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.project_id = ?
+ORDER BY m.created_at, m.user_id
+`
+
+type ListProjectMembersRow struct {
+	UserID    int64          `json:"user_id"`
+	Email     string         `json:"email"`
+	Realname  sql.NullString `json:"realname"`
+	Username  sql.NullString `json:"username"`
+	State     string         `json:"state"`
+	Role      string         `json:"role"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+func (q *Queries) ListProjectMembers(ctx context.Context, projectID int64) ([]ListProjectMembersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listProjectMembers, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListProjectMembersRow{}
+	for rows.Next() {
+		var i ListProjectMembersRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Email,
+			&i.Realname,
+			&i.Username,
+			&i.State,
+			&i.Role,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectVS = `-- name: ListProjectVS :many
+
+SELECT id, project_id, vs, note, created_at FROM project_vs WHERE project_id = ? ORDER BY created_at
+`
+
+// ============================================================================
+// PROJECT VS (Multiple VS identifiers per project)
+// ============================================================================
+func (q *Queries) ListProjectVS(ctx context.Context, projectID int64) ([]ProjectV, error) {
+	rows, err := q.db.QueryContext(ctx, listProjectVS, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ProjectV{}
+	for rows.Next() {
+		var i ProjectV
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Vs,
+			&i.Note,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectWall = `-- name: ListProjectWall :many
+SELECT w.user_id, w.nickname, w.state, u.email,
+    CAST(COALESCE(SUM(p.amount), 0) AS REAL) / 100 AS total
+FROM project_wall_entries w
+JOIN users u ON u.id = w.user_id
+LEFT JOIN payments p ON (
+        p.project_id = w.project_id
+        OR p.identification IN (SELECT pv.vs FROM project_vs pv WHERE pv.project_id = w.project_id)
+    ) AND (
+        p.user_id = w.user_id
+        OR p.remote_account IN (SELECT m.remote_account FROM payments m WHERE m.user_id = w.user_id AND m.remote_account != '')
+    )
+WHERE w.project_id = ?
+GROUP BY w.user_id, w.nickname, w.state, u.email
+ORDER BY total DESC, w.nickname
+`
+
+type ListProjectWallRow struct {
+	UserID   int64   `json:"user_id"`
+	Nickname string  `json:"nickname"`
+	State    string  `json:"state"`
+	Email    string  `json:"email"`
+	Total    float64 `json:"total"`
+}
+
+// Wall entries with the member's contributions: project payments (by project_id or VS,
+// like GetProjectIncome) assigned to the member or sent from one of their bank accounts
+// known from membership payments (total in CZK)
+func (q *Queries) ListProjectWall(ctx context.Context, projectID int64) ([]ListProjectWallRow, error) {
+	rows, err := q.db.QueryContext(ctx, listProjectWall, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListProjectWallRow{}
+	for rows.Next() {
+		var i ListProjectWallRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Nickname,
+			&i.State,
+			&i.Email,
+			&i.Total,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjects = `-- name: ListProjects :many
+
+SELECT id, name, payments_id, description, public, btcpay, goal, deadline, target_reached_at FROM projects ORDER BY id DESC
+`
+
+// ============================================================================
+// PROJECTS (Fundraising / Special VS)
+// ============================================================================
+func (q *Queries) ListProjects(ctx context.Context) ([]Project, error) {
+	rows, err := q.db.QueryContext(ctx, listProjects)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Project{}
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.PaymentsID,
+			&i.Description,
+			&i.Public,
+			&i.Btcpay,
+			&i.Goal,
+			&i.Deadline,
+			&i.TargetReachedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectsWithOpenTarget = `-- name: ListProjectsWithOpenTarget :many
+SELECT id, name, payments_id, description, public, btcpay, goal, deadline, target_reached_at FROM projects
+WHERE goal > 0 AND target_reached_at IS NULL
+ORDER BY id
+`
+
+// Projects with a goal not reached yet, checked after each bank sync
+func (q *Queries) ListProjectsWithOpenTarget(ctx context.Context) ([]Project, error) {
+	rows, err := q.db.QueryContext(ctx, listProjectsWithOpenTarget)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Project{}
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.PaymentsID,
+			&i.Description,
+			&i.Public,
+			&i.Btcpay,
+			&i.Goal,
+			&i.Deadline,
+			&i.TargetReachedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecentLogs = `-- name: ListRecentLogs :many
+SELECT id, subsystem, level, user_id, message, metadata, created_at FROM system_logs ORDER BY created_at DESC LIMIT ?
+`
+
+func (q *Queries) ListRecentLogs(ctx context.Context, limit int64) ([]SystemLog, error) {
+	rows, err := q.db.QueryContext(ctx, listRecentLogs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SystemLog{}
+	for rows.Next() {
+		var i SystemLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Subsystem,
+			&i.Level,
+			&i.UserID,
+			&i.Message,
+			&i.Metadata,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecentPayments = `-- name: ListRecentPayments :many
+SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification FROM payments ORDER BY date DESC LIMIT ?
+`
+
+func (q *Queries) ListRecentPayments(ctx context.Context, limit int64) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, listRecentPayments, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Payment{}
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Date,
+			&i.Amount,
+			&i.Kind,
+			&i.KindID,
+			&i.LocalAccount,
+			&i.RemoteAccount,
+			&i.Identification,
+			&i.RawData,
+			&i.StaffComment,
+			&i.CreatedAt,
+			&i.ProjectID,
+			&i.DismissedAt,
+			&i.DismissedBy,
+			&i.DismissedReason,
+			&i.ReversalOf,
+			&i.ReversalReview,
+			&i.ContentHash,
+			&i.Classification,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReimbursementBatches = `-- name: ListReimbursementBatches :many
+SELECT id, created_by, total, created_at FROM reimbursement_batches ORDER BY id DESC LIMIT ?
+`
+
+type ListReimbursementBatchesRow struct {
+	ID        int64        `json:"id"`
+	CreatedBy string       `json:"created_by"`
+	Total     money.Amount `json:"total"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func (q *Queries) ListReimbursementBatches(ctx context.Context, limit int64) ([]ListReimbursementBatchesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listReimbursementBatches, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListReimbursementBatchesRow{}
+	for rows.Next() {
+		var i ListReimbursementBatchesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedBy,
+			&i.Total,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReimbursementReceipts = `-- name: ListReimbursementReceipts :many
+SELECT id, reimbursement_id, filename, content_type, created_at
+FROM reimbursement_receipts
+WHERE reimbursement_id = ?
+ORDER BY id
+`
+
+type ListReimbursementReceiptsRow struct {
+	ID              int64     `json:"id"`
+	ReimbursementID int64     `json:"reimbursement_id"`
+	Filename        string    `json:"filename"`
+	ContentType     string    `json:"content_type"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Receipt metadata without the file content
+func (q *Queries) ListReimbursementReceipts(ctx context.Context, reimbursementID int64) ([]ListReimbursementReceiptsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listReimbursementReceipts, reimbursementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListReimbursementReceiptsRow{}
+	for rows.Next() {
+		var i ListReimbursementReceiptsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ReimbursementID,
+			&i.Filename,
+			&i.ContentType,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReimbursementsByUser = `-- name: ListReimbursementsByUser :many
+SELECT id, user_id, state, amount, description, account, admin_comment, decided_by, decided_at, batch_id, payment_id, paid_at, created_at FROM reimbursements WHERE user_id = ? ORDER BY id DESC
+`
+
+func (q *Queries) ListReimbursementsByUser(ctx context.Context, userID int64) ([]Reimbursement, error) {
+	rows, err := q.db.QueryContext(ctx, listReimbursementsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Reimbursement{}
+	for rows.Next() {
+		var i Reimbursement
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.State,
+			&i.Amount,
+			&i.Description,
+			&i.Account,
+			&i.AdminComment,
+			&i.DecidedBy,
+			&i.DecidedAt,
+			&i.BatchID,
+			&i.PaymentID,
+			&i.PaidAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReimbursementsWithUsers = `-- name: ListReimbursementsWithUsers :many
+SELECT r.id, r.user_id, r.state, r.amount, r.description, r.account, r.admin_comment, r.decided_by, r.decided_at, r.batch_id, r.payment_id, r.paid_at, r.created_at, u.email, u.realname
+FROM reimbursements r
+JOIN users u ON r.user_id = u.id
+ORDER BY CASE WHEN r.state = 'submitted' THEN 0 ELSE 1 END, r.id DESC
+LIMIT ?
+`
+
+type ListReimbursementsWithUsersRow struct {
+	ID           int64          `json:"id"`
+	UserID       int64          `json:"user_id"`
+	State        string         `json:"state"`
+	Amount       money.Amount   `json:"amount"`
+	Description  string         `json:"description"`
+	Account      string         `json:"account"`
+	AdminComment sql.NullString `json:"admin_comment"`
+	DecidedBy    sql.NullString `json:"decided_by"`
+	DecidedAt    sql.NullTime   `json:"decided_at"`
+	BatchID      sql.NullInt64  `json:"batch_id"`
+	PaymentID    sql.NullInt64  `json:"payment_id"`
+	PaidAt       sql.NullTime   `json:"paid_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	FeeID        sql.NullInt64  `json:"fee_id"`
+	ChargeID     sql.NullInt64  `json:"charge_id"`
+	Item         sql.NullString `json:"item"`
+	Email        string         `json:"email"`
+	Realname     sql.NullString `json:"realname"`
+}
+
+// Admin queue: pending requests first, then newest
+func (q *Queries) ListReimbursementsWithUsers(ctx context.Context, limit int64) ([]ListReimbursementsWithUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listReimbursementsWithUsers, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListReimbursementsWithUsersRow{}
+	for rows.Next() {
+		var i ListReimbursementsWithUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.State,
+			&i.Amount,
+			&i.Description,
+			&i.Account,
+			&i.AdminComment,
+			&i.DecidedBy,
+			&i.DecidedAt,
+			&i.BatchID,
+			&i.PaymentID,
+			&i.PaidAt,
+			&i.CreatedAt,
+			&i.Email,
+			&i.Realname,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReversalCandidates = `-- name: ListReversalCandidates :many
+SELECT p.id, p.user_id, p.date, p.amount, p.kind, p.kind_id, p.local_account, p.remote_account, p.identification, p.raw_data, p.staff_comment, p.created_at, p.project_id, p.dismissed_at, p.dismissed_by, p.dismissed_reason, p.reversal_of, p.reversal_review, p.content_hash, p.classification
+FROM payments p
+WHERE p.remote_account = ?1
+AND p.amount = ?2
+AND p.date <= ?3
+AND p.reversal_of IS NULL
+AND NOT EXISTS (SELECT 1 FROM payments r WHERE r.reversal_of = p.id)
+ORDER BY p.date DESC
+`
+
+type ListReversalCandidatesParams struct {
+	RemoteAccount string       `json:"remote_account"`
+	Amount        money.Amount `json:"amount"`
+	Date          time.Time    `json:"date"`
+}
+
+// Incoming payments from the same account and amount that have not been reversed yet
+func (q *Queries) ListReversalCandidates(ctx context.Context, arg ListReversalCandidatesParams) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, listReversalCandidates, arg.RemoteAccount, arg.Amount, arg.Date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Payment{}
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Date,
+			&i.Amount,
+			&i.Kind,
+			&i.KindID,
+			&i.LocalAccount,
+			&i.RemoteAccount,
+			&i.Identification,
+			&i.RawData,
+			&i.StaffComment,
+			&i.CreatedAt,
+			&i.ProjectID,
+			&i.DismissedAt,
+			&i.DismissedBy,
+			&i.DismissedReason,
+			&i.ReversalOf,
+			&i.ReversalReview,
+			&i.ContentHash,
+			&i.Classification,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTerminations = `-- name: ListScheduledTerminations :many
+SELECT id, user_id, end_date, reason, state, cancelled_by, created_at, decided_at FROM membership_terminations WHERE state = 'scheduled' ORDER BY end_date, id
+`
+
+func (q *Queries) ListScheduledTerminations(ctx context.Context) ([]MembershipTermination, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTerminations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MembershipTermination{}
+	for rows.Next() {
+		var i MembershipTermination
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.EndDate,
+			&i.Reason,
+			&i.State,
+			&i.CancelledBy,
+			&i.CreatedAt,
+			&i.DecidedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSuspectedPaymentDuplicates = `-- name: ListSuspectedPaymentDuplicates :many
+SELECT payment_id, duplicate_of, state, decided_by, decided_at, created_at FROM payment_duplicates
+WHERE state = 'suspected'
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListSuspectedPaymentDuplicates(ctx context.Context) ([]PaymentDuplicate, error) {
+	rows, err := q.db.QueryContext(ctx, listSuspectedPaymentDuplicates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PaymentDuplicate{}
+	for rows.Next() {
+		var i PaymentDuplicate
+		if err := rows.Scan(
+			&i.PaymentID,
+			&i.DuplicateOf,
+			&i.State,
+			&i.DecidedBy,
+			&i.DecidedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSuspensionExemptions = `-- name: ListSuspensionExemptions :many
+SELECT user_id, reason, created_by, created_at FROM suspension_exemptions ORDER BY user_id
+`
+
+func (q *Queries) ListSuspensionExemptions(ctx context.Context) ([]SuspensionExemption, error) {
+	rows, err := q.db.QueryContext(ctx, listSuspensionExemptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SuspensionExemption{}
+	for rows.Next() {
+		var i SuspensionExemption
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Reason,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTicketMessages = `-- name: ListTicketMessages :many
+SELECT id, ticket_id, direction, author, body, message_id, created_at FROM ticket_messages WHERE ticket_id = ? ORDER BY id
+`
+
+func (q *Queries) ListTicketMessages(ctx context.Context, ticketID int64) ([]TicketMessage, error) {
+	rows, err := q.db.QueryContext(ctx, listTicketMessages, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TicketMessage{}
+	for rows.Next() {
+		var i TicketMessage
+		if err := rows.Scan(
+			&i.ID,
+			&i.TicketID,
+			&i.Direction,
+			&i.Author,
+			&i.Body,
+			&i.MessageID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTickets = `-- name: ListTickets :many
+SELECT t.id, t.user_id, t.email, t.subject, t.state, t.source, t.created_at, t.updated_at, u.realname,
+    (SELECT COUNT(*) FROM ticket_messages m WHERE m.ticket_id = t.id) AS message_count
+FROM tickets t
+LEFT JOIN users u ON t.user_id = u.id
+ORDER BY CASE t.state WHEN 'open' THEN 0 WHEN 'answered' THEN 1 ELSE 2 END, t.updated_at DESC
+LIMIT ?
+`
+
+type ListTicketsRow struct {
+	ID           int64          `json:"id"`
+	UserID       sql.NullInt64  `json:"user_id"`
+	Email        string         `json:"email"`
+	Subject      string         `json:"subject"`
+	State        string         `json:"state"`
+	Source       string         `json:"source"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Realname     sql.NullString `json:"realname"`
+	MessageCount int64          `json:"message_count"`
+}
+
+// Admin inbox: open tickets first, then by last activity
+func (q *Queries) ListTickets(ctx context.Context, limit int64) ([]ListTicketsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTickets, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTicketsRow{}
+	for rows.Next() {
+		var i ListTicketsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Email,
+			&i.Subject,
+			&i.State,
+			&i.Source,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Realname,
+			&i.MessageCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTicketsByUser = `-- name: ListTicketsByUser :many
+SELECT id, user_id, email, subject, state, source, created_at, updated_at FROM tickets WHERE user_id = ? ORDER BY updated_at DESC, id DESC
+`
+
+func (q *Queries) ListTicketsByUser(ctx context.Context, userID sql.NullInt64) ([]Ticket, error) {
+	rows, err := q.db.QueryContext(ctx, listTicketsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Ticket{}
+	for rows.Next() {
+		var i Ticket
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Email,
+			&i.Subject,
+			&i.State,
+			&i.Source,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnassignedPayments = `-- name: ListUnassignedPayments :many
+SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification FROM payments p
+WHERE p.user_id IS NULL AND p.dismissed_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+AND NOT EXISTS (SELECT 1 FROM payment_duplicates d WHERE d.payment_id = p.id AND d.state = 'suspected')
+ORDER BY p.date DESC
+`
+
+// Suspected duplicates wait for review in ListSuspectedPaymentDuplicates
+func (q *Queries) ListUnassignedPayments(ctx context.Context) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, listUnassignedPayments)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 	items := []Payment{}
 	for rows.Next() {
-		var i Payment
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Date,
+			&i.Amount,
+			&i.Kind,
+			&i.KindID,
+			&i.LocalAccount,
+			&i.RemoteAccount,
+			&i.Identification,
+			&i.RawData,
+			&i.StaffComment,
+			&i.CreatedAt,
+			&i.ProjectID,
+			&i.DismissedAt,
+			&i.DismissedBy,
+			&i.DismissedReason,
+			&i.ReversalOf,
+			&i.ReversalReview,
+			&i.ContentHash,
+			&i.Classification,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserBankAccounts = `-- name: ListUserBankAccounts :many
+SELECT remote_account AS account FROM payments WHERE user_id = ?1 AND remote_account != ''
+UNION
+SELECT account FROM reimbursements WHERE user_id = ?1 AND account != ''
+`
+
+// Counter accounts the member paid from or had reimbursements paid to
+func (q *Queries) ListUserBankAccounts(ctx context.Context, userID sql.NullInt64) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listUserBankAccounts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var account string
+		if err := rows.Scan(&account); err != nil {
+			return nil, err
+		}
+		items = append(items, account)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserDashboardWidgets = `-- name: ListUserDashboardWidgets :many
+SELECT user_id, widget, visible, updated_at FROM user_dashboard_widgets WHERE user_id = ?
+`
+
+func (q *Queries) ListUserDashboardWidgets(ctx context.Context, userID int64) ([]UserDashboardWidget, error) {
+	rows, err := q.db.QueryContext(ctx, listUserDashboardWidgets, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []UserDashboardWidget{}
+	for rows.Next() {
+		var i UserDashboardWidget
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Widget,
+			&i.Visible,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserLedger = `-- name: ListUserLedger :many
+SELECT 'payment' AS entry_type, p.id AS entry_id, p.date, p.amount, 0 AS months,
+    p.remote_account AS note
+FROM payments p
+JOIN users u ON p.user_id = u.id
+WHERE p.user_id = ?1
+  AND p.identification = u.payments_id
+  AND p.classification != 'donation'
+  AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+UNION ALL
+SELECT 'split', s.payment_id, p.date, s.amount, 0, s.note
+FROM payment_splits s
+JOIN payments p ON p.id = s.payment_id
+WHERE s.user_id = ?1 AND s.classification = 'fee'
+UNION ALL
+SELECT 'fee', f.id, f.period_start, -f.amount, f.months, ''
+FROM fees f
+WHERE f.user_id = ?1
+UNION ALL
+SELECT 'charge', c.id, c.date, -c.amount, 0, c.description
+FROM charges c
+WHERE c.user_id = ?1
+UNION ALL
+SELECT 'adjustment', a.id, a.created_at, a.amount, 0, a.reason
+FROM balance_adjustments a
+WHERE a.user_id = ?1
+ORDER BY 3, 1, 2
+`
+
+type ListUserLedgerRow struct {
+	EntryType string       `json:"entry_type"`
+	EntryID   int64        `json:"entry_id"`
+	Date      time.Time    `json:"date"`
+	Amount    money.Amount `json:"amount"`
+	Months    int64        `json:"months"`
+	Note      string       `json:"note"`
+}
+
+// Everything GetUserBalance counts, oldest first: payments and fee allocations of
+// split payments, fees and charges (negative), balance adjustments with their sign
+func (q *Queries) ListUserLedger(ctx context.Context, userID sql.NullInt64) ([]ListUserLedgerRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserLedger, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserLedgerRow{}
+	for rows.Next() {
+		var i ListUserLedgerRow
+		if err := rows.Scan(
+			&i.EntryType,
+			&i.EntryID,
+			&i.Date,
+			&i.Amount,
+			&i.Months,
+			&i.Note,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserNotes = `-- name: ListUserNotes :many
+SELECT id, user_id, author, body, pinned, created_at, updated_at FROM user_notes WHERE user_id = ? ORDER BY pinned DESC, created_at DESC, id DESC
+`
+
+func (q *Queries) ListUserNotes(ctx context.Context, userID int64) ([]UserNote, error) {
+	rows, err := q.db.QueryContext(ctx, listUserNotes, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []UserNote{}
+	for rows.Next() {
+		var i UserNote
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Author,
+			&i.Body,
+			&i.Pinned,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserNotificationPreferences = `-- name: ListUserNotificationPreferences :many
+SELECT user_id, notification, enabled, updated_at FROM user_notification_preferences WHERE user_id = ?
+`
+
+func (q *Queries) ListUserNotificationPreferences(ctx context.Context, userID int64) ([]UserNotificationPreference, error) {
+	rows, err := q.db.QueryContext(ctx, listUserNotificationPreferences, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []UserNotificationPreference{}
+	for rows.Next() {
+		var i UserNotificationPreference
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Notification,
+			&i.Enabled,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserPaymentCounts = `-- name: ListUserPaymentCounts :many
+SELECT user_id, COUNT(*) AS total, COUNT(CASE WHEN date >= ?1 THEN 1 END) AS recent
+FROM payments
+WHERE user_id IS NOT NULL AND project_id IS NULL AND dismissed_at IS NULL AND amount > 0
+GROUP BY user_id
+`
+
+type ListUserPaymentCountsRow struct {
+	UserID sql.NullInt64 `json:"user_id"`
+	Total  int64         `json:"total"`
+	Recent int64         `json:"recent"`
+}
+
+// Positive membership-side payments per member, with how many arrived since the given date
+func (q *Queries) ListUserPaymentCounts(ctx context.Context, since time.Time) ([]ListUserPaymentCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserPaymentCounts, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserPaymentCountsRow{}
+	for rows.Next() {
+		var i ListUserPaymentCountsRow
+		if err := rows.Scan(&i.UserID, &i.Total, &i.Recent); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserTasks = `-- name: ListUserTasks :many
+SELECT user_id, task, completed_at, completed_by FROM user_tasks WHERE user_id = ? ORDER BY completed_at
+`
+
+func (q *Queries) ListUserTasks(ctx context.Context, userID int64) ([]UserTask, error) {
+	rows, err := q.db.QueryContext(ctx, listUserTasks, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []UserTask{}
+	for rows.Next() {
+		var i UserTask
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Task,
+			&i.CompletedAt,
+			&i.CompletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users ORDER BY realname, email
+`
+
+func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
 		if err := rows.Scan(
 			&i.ID,
-			&i.UserID,
-			&i.Date,
-			&i.Amount,
-			&i.Kind,
-			&i.KindID,
-			&i.LocalAccount,
-			&i.RemoteAccount,
-			&i.Identification,
-			&i.RawData,
-			&i.StaffComment,
+			&i.KeycloakID,
+			&i.Email,
+			&i.Username,
+			&i.Realname,
+			&i.Phone,
+			&i.AltContact,
+			&i.LevelID,
+			&i.LevelActualAmount,
+			&i.PaymentsID,
+			&i.DateJoined,
+			&i.KeysGranted,
+			&i.KeysReturned,
+			&i.State,
+			&i.IsCouncil,
+			&i.IsStaff,
 			&i.CreatedAt,
-			&i.ProjectID,
-			&i.DismissedAt,
-			&i.DismissedBy,
-			&i.DismissedReason,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersByLevel = `-- name: ListUsersByLevel :many
+SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users WHERE level_id = ? ORDER BY id
+`
+
+func (q *Queries) ListUsersByLevel(ctx context.Context, levelID int64) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersByLevel, levelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.KeycloakID,
+			&i.Email,
+			&i.Username,
+			&i.Realname,
+			&i.Phone,
+			&i.AltContact,
+			&i.LevelID,
+			&i.LevelActualAmount,
+			&i.PaymentsID,
+			&i.DateJoined,
+			&i.KeysGranted,
+			&i.KeysReturned,
+			&i.State,
+			&i.IsCouncil,
+			&i.IsStaff,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersByState = `-- name: ListUsersByState :many
+SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users WHERE state = ? ORDER BY realname, email
+`
+
+func (q *Queries) ListUsersByState(ctx context.Context, state string) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersByState, state)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.KeycloakID,
+			&i.Email,
+			&i.Username,
+			&i.Realname,
+			&i.Phone,
+			&i.AltContact,
+			&i.LevelID,
+			&i.LevelActualAmount,
+			&i.PaymentsID,
+			&i.DateJoined,
+			&i.KeysGranted,
+			&i.KeysReturned,
+			&i.State,
+			&i.IsCouncil,
+			&i.IsStaff,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersPage = `-- name: ListUsersPage :many
+WITH latest AS (
+    -- Newest balance snapshot of each user, entries after its month are added
+    -- (the stored month may carry a time zone suffix date() does not parse)
+    SELECT s.user_id, s.balance, date(substr(s.month, 1, 10), '+1 month') AS since
+    FROM balance_snapshots s
+    WHERE s.month = (SELECT MAX(month) FROM balance_snapshots WHERE user_id = s.user_id)
+),
+listed AS (
+    SELECT u.id, u.keycloak_id, u.email, u.username, u.realname, u.phone, u.alt_contact,
+        u.level_id, u.level_actual_amount, u.payments_id, u.date_joined, u.keys_granted,
+        u.keys_returned, u.state, u.is_council, u.is_staff, u.created_at, u.updated_at,
+        COALESCE(l.balance, 0) +
+        COALESCE((
+            SELECT SUM(p.amount)
+            FROM payments p
+            WHERE p.user_id = u.id
+            AND p.identification = u.payments_id
+            AND p.classification != 'donation'
+            AND NOT EXISTS (SELECT 1 FROM payment_splits s WHERE s.payment_id = p.id)
+            AND (l.since IS NULL OR date(p.date) >= l.since)
+        ), 0) -
+        COALESCE((SELECT SUM(f.amount) FROM fees f WHERE f.user_id = u.id AND (l.since IS NULL OR date(f.period_start) >= l.since)), 0) +
+        COALESCE((
+            SELECT SUM(s.amount)
+            FROM payment_splits s
+            JOIN payments p ON p.id = s.payment_id
+            WHERE s.user_id = u.id AND s.classification = 'fee'
+            AND (l.since IS NULL OR date(p.date) >= l.since)
+        ), 0) -
+        COALESCE((SELECT SUM(c.amount) FROM charges c WHERE c.user_id = u.id AND (l.since IS NULL OR date(c.date) >= l.since)), 0) +
+        COALESCE((SELECT SUM(a.amount) FROM balance_adjustments a WHERE a.user_id = u.id AND (l.since IS NULL OR date(a.created_at) >= l.since)), 0) AS balance
+    FROM users u
+    LEFT JOIN latest l ON l.user_id = u.id
+)
+SELECT id, keycloak_id, email, username, realname, phone, alt_contact,
+    level_id, level_actual_amount, payments_id, date_joined, keys_granted,
+    keys_returned, state, is_council, is_staff, created_at, updated_at,
+    CAST(balance AS INTEGER) AS balance,
+    CAST(COUNT(*) OVER () AS INTEGER) AS total
+FROM listed
+WHERE (?1 = '' OR state = ?1)
+  AND (?2 = ''
+       OR email LIKE '%' || ?2 || '%'
+       OR realname LIKE '%' || ?2 || '%'
+       OR username LIKE '%' || ?2 || '%'
+       OR payments_id = ?2)
+  AND (?3 = ''
+       OR (?3 = 'linked' AND keycloak_id IS NOT NULL AND keycloak_id != '')
+       OR (?3 = 'not_linked' AND (keycloak_id IS NULL OR keycloak_id = '')))
+  AND (?4 IS NULL OR keycloak_id IN (SELECT value FROM json_each(?4)))
+  AND (?5 = ''
+       OR (?5 = 'positive' AND balance >= 0)
+       OR (?5 = 'negative' AND balance < 0))
+ORDER BY
+    CASE WHEN ?6 = 'id_asc' THEN id END ASC,
+    CASE WHEN ?6 = 'balance_asc' THEN balance END ASC,
+    CASE WHEN ?6 = 'balance_desc' THEN balance END DESC,
+    CASE WHEN ?6 = 'name' THEN COALESCE(NULLIF(realname, ''), email) END ASC,
+    id DESC
+LIMIT ?7 OFFSET ?8
+`
+
+type ListUsersPageParams struct {
+	State       string         `json:"state"`
+	Search      string         `json:"search"`
+	Linked      string         `json:"linked"`
+	KeycloakIDs sql.NullString `json:"keycloak_ids"`
+	Balance     string         `json:"balance"`
+	Sort        string         `json:"sort"`
+	Limit       int64          `json:"limit"`
+	Offset      int64          `json:"offset"`
+}
+
+type ListUsersPageRow struct {
+	ID                int64          `json:"id"`
+	KeycloakID        sql.NullString `json:"keycloak_id"`
+	Email             string         `json:"email"`
+	Username          sql.NullString `json:"username"`
+	Realname          sql.NullString `json:"realname"`
+	Phone             sql.NullString `json:"phone"`
+	AltContact        sql.NullString `json:"alt_contact"`
+	LevelID           int64          `json:"level_id"`
+	LevelActualAmount money.Amount   `json:"level_actual_amount"`
+	PaymentsID        sql.NullString `json:"payments_id"`
+	DateJoined        time.Time      `json:"date_joined"`
+	KeysGranted       sql.NullTime   `json:"keys_granted"`
+	KeysReturned      sql.NullTime   `json:"keys_returned"`
+	State             string         `json:"state"`
+	IsCouncil         bool           `json:"is_council"`
+	IsStaff           bool           `json:"is_staff"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	Balance           int64          `json:"balance"`
+	Total             int64          `json:"total"`
+}
+
+// One page of the admin user list, filtered and sorted in SQL. The balance is the
+// latest snapshot plus the entries after its month (as listBalance); total is the
+// number of users matching the filters. keycloak_ids (JSON array, NULL = any)
+// narrows the list to accounts picked by their Keycloak status.
+func (q *Queries) ListUsersPage(ctx context.Context, arg ListUsersPageParams) ([]ListUsersPageRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersPage,
+		arg.State,
+		arg.Search,
+		arg.Linked,
+		arg.KeycloakIDs,
+		arg.Balance,
+		arg.Sort,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUsersPageRow{}
+	for rows.Next() {
+		var i ListUsersPageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.KeycloakID,
+			&i.Email,
+			&i.Username,
+			&i.Realname,
+			&i.Phone,
+			&i.AltContact,
+			&i.LevelID,
+			&i.LevelActualAmount,
+			&i.PaymentsID,
+			&i.DateJoined,
+			&i.KeysGranted,
+			&i.KeysReturned,
+			&i.State,
+			&i.IsCouncil,
+			&i.IsStaff,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Balance,
+			&i.Total,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersWithoutKeycloakID = `-- name: ListUsersWithoutKeycloakID :many
+SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users
+WHERE keycloak_id IS NULL
+  AND id NOT IN (SELECT user_id FROM user_anonymizations)
+ORDER BY id
+`
+
+// Imported members that never logged in (candidates for Keycloak account provisioning),
+// anonymized former members are left out
+func (q *Queries) ListUsersWithoutKeycloakID(ctx context.Context) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersWithoutKeycloakID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.KeycloakID,
+			&i.Email,
+			&i.Username,
+			&i.Realname,
+			&i.Phone,
+			&i.AltContact,
+			&i.LevelID,
+			&i.LevelActualAmount,
+			&i.PaymentsID,
+			&i.DateJoined,
+			&i.KeysGranted,
+			&i.KeysReturned,
+			&i.State,
+			&i.IsCouncil,
+			&i.IsStaff,
+			&i.CreatedAt,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEmailCampaignRecipientFailed = `-- name: MarkEmailCampaignRecipientFailed :exec
+UPDATE email_campaign_recipients SET
+    status = 'failed',
+    last_error = ?
+WHERE id = ?
+`
+
+type MarkEmailCampaignRecipientFailedParams struct {
+	LastError sql.NullString `json:"last_error"`
+	ID        int64          `json:"id"`
+}
+
+func (q *Queries) MarkEmailCampaignRecipientFailed(ctx context.Context, arg MarkEmailCampaignRecipientFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markEmailCampaignRecipientFailed, arg.LastError, arg.ID)
+	return err
+}
+
+const markEmailCampaignRecipientSending = `-- name: MarkEmailCampaignRecipientSending :exec
+UPDATE email_campaign_recipients SET
+    status = 'sending',
+    attempts = attempts + 1
+WHERE id = ?
+`
+
+func (q *Queries) MarkEmailCampaignRecipientSending(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markEmailCampaignRecipientSending, id)
+	return err
+}
+
+const markEmailCampaignRecipientSent = `-- name: MarkEmailCampaignRecipientSent :exec
+UPDATE email_campaign_recipients SET
+    status = 'sent',
+    sent_at = CURRENT_TIMESTAMP,
+    last_error = NULL
+WHERE id = ?
+`
+
+func (q *Queries) MarkEmailCampaignRecipientSent(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markEmailCampaignRecipientSent, id)
+	return err
+}
+
+const markInvoicePaid = `-- name: MarkInvoicePaid :execrows
+UPDATE invoices SET
+    state = 'paid',
+    payment_id = ?,
+    paid_at = ?
+WHERE id = ? AND state = 'approved'
+`
+
+type MarkInvoicePaidParams struct {
+	PaymentID sql.NullInt64 `json:"payment_id"`
+	PaidAt    sql.NullTime  `json:"paid_at"`
+	ID        int64         `json:"id"`
+}
+
+func (q *Queries) MarkInvoicePaid(ctx context.Context, arg MarkInvoicePaidParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markInvoicePaid, arg.PaymentID, arg.PaidAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markLevelHistoryApplied = `-- name: MarkLevelHistoryApplied :exec
+UPDATE level_history SET applied_at = CURRENT_TIMESTAMP WHERE id = ?
+`
+
+func (q *Queries) MarkLevelHistoryApplied(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markLevelHistoryApplied, id)
+	return err
+}
+
+const markLevelPriceChangeApplied = `-- name: MarkLevelPriceChangeApplied :exec
+UPDATE level_price_changes SET applied_at = CURRENT_TIMESTAMP WHERE id = ?
+`
+
+func (q *Queries) MarkLevelPriceChangeApplied(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markLevelPriceChangeApplied, id)
+	return err
+}
+
+const markLevelPriceChangeNotified = `-- name: MarkLevelPriceChangeNotified :exec
+UPDATE level_price_changes SET notified_at = CURRENT_TIMESTAMP WHERE id = ?
+`
+
+func (q *Queries) MarkLevelPriceChangeNotified(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markLevelPriceChangeNotified, id)
+	return err
+}
+
+const markProjectTargetReached = `-- name: MarkProjectTargetReached :execrows
+UPDATE projects SET target_reached_at = CURRENT_TIMESTAMP
+WHERE id = ? AND target_reached_at IS NULL
+`
+
+func (q *Queries) MarkProjectTargetReached(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markProjectTargetReached, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markReimbursementExported = `-- name: MarkReimbursementExported :execrows
+UPDATE reimbursements SET
+    state = 'exported',
+    batch_id = ?
+WHERE id = ? AND state = 'approved'
+`
+
+type MarkReimbursementExportedParams struct {
+	BatchID sql.NullInt64 `json:"batch_id"`
+	ID      int64         `json:"id"`
+}
+
+func (q *Queries) MarkReimbursementExported(ctx context.Context, arg MarkReimbursementExportedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markReimbursementExported, arg.BatchID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markReimbursementPaid = `-- name: MarkReimbursementPaid :execrows
+UPDATE reimbursements SET
+    state = 'paid',
+    payment_id = ?,
+    paid_at = ?
+WHERE id = ? AND state = 'exported'
+`
+
+type MarkReimbursementPaidParams struct {
+	PaymentID sql.NullInt64 `json:"payment_id"`
+	PaidAt    sql.NullTime  `json:"paid_at"`
+	ID        int64         `json:"id"`
+}
+
+func (q *Queries) MarkReimbursementPaid(ctx context.Context, arg MarkReimbursementPaidParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markReimbursementPaid, arg.PaymentID, arg.PaidAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserAPITokens = `-- name: MergeUserAPITokens :execrows
+UPDATE api_tokens SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserAPITokensParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserAPITokens(ctx context.Context, arg MergeUserAPITokensParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserAPITokens, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserApplicationVouches = `-- name: MergeUserApplicationVouches :execrows
+UPDATE OR IGNORE application_vouches SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserApplicationVouchesParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserApplicationVouches(ctx context.Context, arg MergeUserApplicationVouchesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserApplicationVouches, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserApplications = `-- name: MergeUserApplications :execrows
+UPDATE applications SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserApplicationsParams struct {
+	ToUserID   sql.NullInt64 `json:"to_user_id"`
+	FromUserID sql.NullInt64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserApplications(ctx context.Context, arg MergeUserApplicationsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserApplications, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserAuditLog = `-- name: MergeUserAuditLog :execrows
+UPDATE admin_audit_log SET actor_user_id = ?1 WHERE actor_user_id = ?2
+`
+
+type MergeUserAuditLogParams struct {
+	ToUserID   sql.NullInt64 `json:"to_user_id"`
+	FromUserID sql.NullInt64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserAuditLog(ctx context.Context, arg MergeUserAuditLogParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserAuditLog, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserBalanceAdjustments = `-- name: MergeUserBalanceAdjustments :execrows
+UPDATE balance_adjustments SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserBalanceAdjustmentsParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserBalanceAdjustments(ctx context.Context, arg MergeUserBalanceAdjustmentsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserBalanceAdjustments, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserBillingDetails = `-- name: MergeUserBillingDetails :execrows
+UPDATE OR IGNORE billing_details SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserBillingDetailsParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserBillingDetails(ctx context.Context, arg MergeUserBillingDetailsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserBillingDetails, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserCampaignRecipients = `-- name: MergeUserCampaignRecipients :execrows
+UPDATE OR IGNORE email_campaign_recipients SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserCampaignRecipientsParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserCampaignRecipients(ctx context.Context, arg MergeUserCampaignRecipientsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserCampaignRecipients, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserCharges = `-- name: MergeUserCharges :execrows
+UPDATE charges SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserChargesParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserCharges(ctx context.Context, arg MergeUserChargesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserCharges, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserDashboardWidgets = `-- name: MergeUserDashboardWidgets :execrows
+UPDATE OR IGNORE user_dashboard_widgets SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserDashboardWidgetsParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserDashboardWidgets(ctx context.Context, arg MergeUserDashboardWidgetsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserDashboardWidgets, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserDonationReceipts = `-- name: MergeUserDonationReceipts :execrows
+UPDATE OR IGNORE donation_receipts SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserDonationReceiptsParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserDonationReceipts(ctx context.Context, arg MergeUserDonationReceiptsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserDonationReceipts, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserEmailCampaigns = `-- name: MergeUserEmailCampaigns :execrows
+UPDATE email_campaigns SET created_by = ?1 WHERE created_by = ?2
+`
+
+type MergeUserEmailCampaignsParams struct {
+	ToUserID   sql.NullInt64 `json:"to_user_id"`
+	FromUserID sql.NullInt64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserEmailCampaigns(ctx context.Context, arg MergeUserEmailCampaignsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserEmailCampaigns, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserFakturoidInvoices = `-- name: MergeUserFakturoidInvoices :execrows
+UPDATE fakturoid_invoices SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserFakturoidInvoicesParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserFakturoidInvoices(ctx context.Context, arg MergeUserFakturoidInvoicesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserFakturoidInvoices, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserFees = `-- name: MergeUserFees :execrows
+UPDATE OR IGNORE fees SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserFeesParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+// Fees for a period the target already has stay with the source (see ListMergeDuplicateFees)
+func (q *Queries) MergeUserFees(ctx context.Context, arg MergeUserFeesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserFees, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserInvoices = `-- name: MergeUserInvoices :execrows
+UPDATE invoices SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserInvoicesParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserInvoices(ctx context.Context, arg MergeUserInvoicesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserInvoices, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserLevelChangeRequests = `-- name: MergeUserLevelChangeRequests :execrows
+UPDATE level_change_requests SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserLevelChangeRequestsParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserLevelChangeRequests(ctx context.Context, arg MergeUserLevelChangeRequestsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserLevelChangeRequests, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserLevelHistory = `-- name: MergeUserLevelHistory :execrows
+UPDATE level_history SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserLevelHistoryParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserLevelHistory(ctx context.Context, arg MergeUserLevelHistoryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserLevelHistory, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserLogs = `-- name: MergeUserLogs :execrows
+UPDATE system_logs SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserLogsParams struct {
+	ToUserID   sql.NullInt64 `json:"to_user_id"`
+	FromUserID sql.NullInt64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserLogs(ctx context.Context, arg MergeUserLogsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserLogs, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserMilestones = `-- name: MergeUserMilestones :execrows
+UPDATE OR IGNORE member_milestones SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserMilestonesParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserMilestones(ctx context.Context, arg MergeUserMilestonesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserMilestones, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserNotes = `-- name: MergeUserNotes :execrows
+UPDATE user_notes SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserNotesParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserNotes(ctx context.Context, arg MergeUserNotesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserNotes, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserNotificationPreferences = `-- name: MergeUserNotificationPreferences :execrows
+UPDATE OR IGNORE user_notification_preferences SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserNotificationPreferencesParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserNotificationPreferences(ctx context.Context, arg MergeUserNotificationPreferencesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserNotificationPreferences, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserPauses = `-- name: MergeUserPauses :execrows
+UPDATE membership_pauses SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserPausesParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserPauses(ctx context.Context, arg MergeUserPausesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserPauses, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserPaymentDismissals = `-- name: MergeUserPaymentDismissals :execrows
+UPDATE payments SET dismissed_by = ?1 WHERE dismissed_by = ?2
+`
+
+type MergeUserPaymentDismissalsParams struct {
+	ToUserID   sql.NullInt64 `json:"to_user_id"`
+	FromUserID sql.NullInt64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserPaymentDismissals(ctx context.Context, arg MergeUserPaymentDismissalsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserPaymentDismissals, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserPaymentMatchRules = `-- name: MergeUserPaymentMatchRules :execrows
+UPDATE payment_match_rules SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserPaymentMatchRulesParams struct {
+	ToUserID   sql.NullInt64 `json:"to_user_id"`
+	FromUserID sql.NullInt64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserPaymentMatchRules(ctx context.Context, arg MergeUserPaymentMatchRulesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserPaymentMatchRules, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserPaymentPlans = `-- name: MergeUserPaymentPlans :execrows
+UPDATE payment_plans SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserPaymentPlansParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserPaymentPlans(ctx context.Context, arg MergeUserPaymentPlansParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserPaymentPlans, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserPaymentReminders = `-- name: MergeUserPaymentReminders :execrows
+UPDATE OR IGNORE payment_reminders SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserPaymentRemindersParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserPaymentReminders(ctx context.Context, arg MergeUserPaymentRemindersParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserPaymentReminders, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const mergeUserPaymentSettings = `-- name: MergeUserPaymentSettings :execrows
+UPDATE OR IGNORE user_payment_settings SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserPaymentSettingsParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserPaymentSettings(ctx context.Context, arg MergeUserPaymentSettingsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserPaymentSettings, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	return result.RowsAffected()
+}
+
+const mergeUserPaymentSplits = `-- name: MergeUserPaymentSplits :execrows
+UPDATE payment_splits SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserPaymentSplitsParams struct {
+	ToUserID   sql.NullInt64 `json:"to_user_id"`
+	FromUserID sql.NullInt64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserPaymentSplits(ctx context.Context, arg MergeUserPaymentSplitsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserPaymentSplits, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected()
 }
 
-const listFeesByPeriod = `-- name: ListFeesByPeriod :many
-SELECT id, user_id, level_id, period_start, amount, created_at FROM fees WHERE period_start = ? ORDER BY user_id
+const mergeUserPaymentStatements = `-- name: MergeUserPaymentStatements :execrows
+UPDATE OR IGNORE payment_statements SET user_id = ?1 WHERE user_id = ?2
 `
 
-func (q *Queries) ListFeesByPeriod(ctx context.Context, periodStart time.Time) ([]Fee, error) {
-	rows, err := q.db.QueryContext(ctx, listFeesByPeriod, periodStart)
+type MergeUserPaymentStatementsParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserPaymentStatements(ctx context.Context, arg MergeUserPaymentStatementsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserPaymentStatements, arg.ToUserID, arg.FromUserID)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
-	items := []Fee{}
-	for rows.Next() {
-		var i Fee
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.LevelID,
-			&i.PeriodStart,
-			&i.Amount,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
+	return result.RowsAffected()
+}
+
+const mergeUserPaymentSuggestions = `-- name: MergeUserPaymentSuggestions :execrows
+UPDATE payment_suggestions SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserPaymentSuggestionsParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserPaymentSuggestions(ctx context.Context, arg MergeUserPaymentSuggestionsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserPaymentSuggestions, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
+	return result.RowsAffected()
+}
+
+const mergeUserPaymentVS = `-- name: MergeUserPaymentVS :execrows
+UPDATE payments SET identification = ?1
+WHERE user_id = ?2 AND identification = ?3
+`
+
+type MergeUserPaymentVSParams struct {
+	ToPaymentsID   string        `json:"to_payments_id"`
+	FromUserID     sql.NullInt64 `json:"from_user_id"`
+	FromPaymentsID string        `json:"from_payments_id"`
+}
+
+// The duplicate's payments carrying its VS take the VS of the target, balances only
+// count payments matching the member's VS (run before MergeUserPayments)
+func (q *Queries) MergeUserPaymentVS(ctx context.Context, arg MergeUserPaymentVSParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserPaymentVS, arg.ToPaymentsID, arg.FromUserID, arg.FromPaymentsID)
+	if err != nil {
+		return 0, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	return result.RowsAffected()
+}
+
+const mergeUserPayments = `-- name: MergeUserPayments :execrows
+UPDATE payments SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserPaymentsParams struct {
+	ToUserID   sql.NullInt64 `json:"to_user_id"`
+	FromUserID sql.NullInt64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserPayments(ctx context.Context, arg MergeUserPaymentsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserPayments, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected()
 }
 
-const listFeesByUser = `-- name: ListFeesByUser :many
-SELECT id, user_id, level_id, period_start, amount, created_at FROM fees WHERE user_id = ? ORDER BY period_start DESC
+const mergeUserPriceChangeNotices = `-- name: MergeUserPriceChangeNotices :execrows
+UPDATE OR IGNORE level_price_change_notices SET user_id = ?1 WHERE user_id = ?2
 `
 
-func (q *Queries) ListFeesByUser(ctx context.Context, userID int64) ([]Fee, error) {
-	rows, err := q.db.QueryContext(ctx, listFeesByUser, userID)
+type MergeUserPriceChangeNoticesParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserPriceChangeNotices(ctx context.Context, arg MergeUserPriceChangeNoticesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserPriceChangeNotices, arg.ToUserID, arg.FromUserID)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
-	items := []Fee{}
-	for rows.Next() {
-		var i Fee
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.LevelID,
-			&i.PeriodStart,
-			&i.Amount,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
+	return result.RowsAffected()
+}
+
+const mergeUserProjectMemberships = `-- name: MergeUserProjectMemberships :execrows
+UPDATE OR IGNORE project_members SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserProjectMembershipsParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserProjectMemberships(ctx context.Context, arg MergeUserProjectMembershipsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserProjectMemberships, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
+	return result.RowsAffected()
+}
+
+const mergeUserReimbursements = `-- name: MergeUserReimbursements :execrows
+UPDATE reimbursements SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserReimbursementsParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserReimbursements(ctx context.Context, arg MergeUserReimbursementsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserReimbursements, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	return result.RowsAffected()
+}
+
+const mergeUserSuspensionExemption = `-- name: MergeUserSuspensionExemption :execrows
+UPDATE OR IGNORE suspension_exemptions SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserSuspensionExemptionParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserSuspensionExemption(ctx context.Context, arg MergeUserSuspensionExemptionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserSuspensionExemption, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected()
 }
 
-const listLevels = `-- name: ListLevels :many
-SELECT id, name, amount, active, created_at FROM levels WHERE active = TRUE ORDER BY amount
+const mergeUserTasks = `-- name: MergeUserTasks :execrows
+UPDATE OR IGNORE user_tasks SET user_id = ?1 WHERE user_id = ?2
 `
 
-func (q *Queries) ListLevels(ctx context.Context) ([]Level, error) {
-	rows, err := q.db.QueryContext(ctx, listLevels)
+type MergeUserTasksParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserTasks(ctx context.Context, arg MergeUserTasksParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserTasks, arg.ToUserID, arg.FromUserID)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
-	items := []Level{}
-	for rows.Next() {
-		var i Level
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.Amount,
-			&i.Active,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
+	return result.RowsAffected()
+}
+
+const mergeUserTerminations = `-- name: MergeUserTerminations :execrows
+UPDATE OR IGNORE membership_terminations SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserTerminationsParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserTerminations(ctx context.Context, arg MergeUserTerminationsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserTerminations, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
+	return result.RowsAffected()
+}
+
+const mergeUserTickets = `-- name: MergeUserTickets :execrows
+UPDATE tickets SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserTicketsParams struct {
+	ToUserID   sql.NullInt64 `json:"to_user_id"`
+	FromUserID sql.NullInt64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserTickets(ctx context.Context, arg MergeUserTicketsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserTickets, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	return result.RowsAffected()
+}
+
+const mergeUserWallEntries = `-- name: MergeUserWallEntries :execrows
+UPDATE OR IGNORE project_wall_entries SET user_id = ?1 WHERE user_id = ?2
+`
+
+type MergeUserWallEntriesParams struct {
+	ToUserID   int64 `json:"to_user_id"`
+	FromUserID int64 `json:"from_user_id"`
+}
+
+func (q *Queries) MergeUserWallEntries(ctx context.Context, arg MergeUserWallEntriesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, mergeUserWallEntries, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected()
 }
 
-const listLogsBySubsystem = `-- name: ListLogsBySubsystem :many
-SELECT id, subsystem, level, user_id, message, metadata, created_at FROM system_logs WHERE subsystem = ? ORDER BY created_at DESC LIMIT ?
+const moderateProjectWallEntry = `-- name: ModerateProjectWallEntry :execrows
+UPDATE project_wall_entries SET
+    state = ?,
+    nickname = ?,
+    moderated_by = ?,
+    moderated_at = CURRENT_TIMESTAMP
+WHERE project_id = ? AND user_id = ?
 `
 
-type ListLogsBySubsystemParams struct {
-	Subsystem string `json:"subsystem"`
-	Limit     int64  `json:"limit"`
+type ModerateProjectWallEntryParams struct {
+	State       string         `json:"state"`
+	Nickname    string         `json:"nickname"`
+	ModeratedBy sql.NullString `json:"moderated_by"`
+	ProjectID   int64          `json:"project_id"`
+	UserID      int64          `json:"user_id"`
 }
 
-func (q *Queries) ListLogsBySubsystem(ctx context.Context, arg ListLogsBySubsystemParams) ([]SystemLog, error) {
-	rows, err := q.db.QueryContext(ctx, listLogsBySubsystem, arg.Subsystem, arg.Limit)
+func (q *Queries) ModerateProjectWallEntry(ctx context.Context, arg ModerateProjectWallEntryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moderateProjectWallEntry,
+		arg.State,
+		arg.Nickname,
+		arg.ModeratedBy,
+		arg.ProjectID,
+		arg.UserID,
+	)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
-	items := []SystemLog{}
-	for rows.Next() {
-		var i SystemLog
-		if err := rows.Scan(
-			&i.ID,
-			&i.Subsystem,
-			&i.Level,
-			&i.UserID,
-			&i.Message,
-			&i.Metadata,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
+	return result.RowsAffected()
+}
+
+const nextInvoiceNumber = `-- name: NextInvoiceNumber :one
+INSERT INTO invoice_sequences (year, last_number)
+VALUES (?, 1)
+ON CONFLICT(year) DO UPDATE SET last_number = last_number + 1
+RETURNING last_number
+`
+
+func (q *Queries) NextInvoiceNumber(ctx context.Context, year int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, nextInvoiceNumber, year)
+	var last_number int64
+	err := row.Scan(&last_number)
+	return last_number, err
+}
+
+const nextPaymentsID = `-- name: NextPaymentsID :one
+SELECT CAST(MAX(vs) + 1 AS TEXT) AS payments_id FROM (
+    SELECT CAST(payments_id AS INTEGER) AS vs FROM users
+    WHERE payments_id != '' AND payments_id NOT GLOB '*[^0-9]*'
+    UNION ALL
+    SELECT CAST(vs AS INTEGER) FROM project_vs
+    WHERE vs != '' AND vs NOT GLOB '*[^0-9]*'
+    UNION ALL
+    SELECT 1000
+)
+`
+
+// The next free numeric VS for a new member: one above the highest numeric VS of
+// members and projects (at least 1001)
+func (q *Queries) NextPaymentsID(ctx context.Context) (string, error) {
+	row := q.db.QueryRowContext(ctx, nextPaymentsID)
+	var payments_id string
+	err := row.Scan(&payments_id)
+	return payments_id, err
+}
+
+const recordPaymentMatchRuleHit = `-- name: RecordPaymentMatchRuleHit :exec
+UPDATE payment_match_rules SET
+    match_count = match_count + 1,
+    last_matched_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`
+
+func (q *Queries) RecordPaymentMatchRuleHit(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, recordPaymentMatchRuleHit, id)
+	return err
+}
+
+const rejectApplication = `-- name: RejectApplication :execrows
+UPDATE applications SET
+    state = 'rejected',
+    admin_comment = ?,
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'pending'
+`
+
+type RejectApplicationParams struct {
+	AdminComment sql.NullString `json:"admin_comment"`
+	DecidedBy    sql.NullString `json:"decided_by"`
+	ID           int64          `json:"id"`
+}
+
+func (q *Queries) RejectApplication(ctx context.Context, arg RejectApplicationParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rejectApplication, arg.AdminComment, arg.DecidedBy, arg.ID)
+	if err != nil {
+		return 0, err
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
+	return result.RowsAffected()
+}
+
+const rejectInvoice = `-- name: RejectInvoice :execrows
+UPDATE invoices SET
+    state = 'rejected',
+    admin_comment = ?,
+    decided_by = ?
+WHERE id = ? AND state = 'requested'
+`
+
+type RejectInvoiceParams struct {
+	AdminComment sql.NullString `json:"admin_comment"`
+	DecidedBy    sql.NullString `json:"decided_by"`
+	ID           int64          `json:"id"`
+}
+
+func (q *Queries) RejectInvoice(ctx context.Context, arg RejectInvoiceParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rejectInvoice, arg.AdminComment, arg.DecidedBy, arg.ID)
+	if err != nil {
+		return 0, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	return result.RowsAffected()
+}
+
+const rejectLevelChangeRequest = `-- name: RejectLevelChangeRequest :execrows
+UPDATE level_change_requests SET
+    state = 'rejected',
+    admin_comment = ?,
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'requested'
+`
+
+type RejectLevelChangeRequestParams struct {
+	AdminComment sql.NullString `json:"admin_comment"`
+	DecidedBy    sql.NullString `json:"decided_by"`
+	ID           int64          `json:"id"`
+}
+
+func (q *Queries) RejectLevelChangeRequest(ctx context.Context, arg RejectLevelChangeRequestParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rejectLevelChangeRequest, arg.AdminComment, arg.DecidedBy, arg.ID)
+	if err != nil {
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected()
+}
+
+const rejectPaymentSuggestion = `-- name: RejectPaymentSuggestion :execrows
+UPDATE payment_suggestions SET
+    state = 'rejected',
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE payment_id = ? AND state = 'suggested'
+`
+
+type RejectPaymentSuggestionParams struct {
+	DecidedBy sql.NullString `json:"decided_by"`
+	PaymentID int64          `json:"payment_id"`
+}
+
+func (q *Queries) RejectPaymentSuggestion(ctx context.Context, arg RejectPaymentSuggestionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rejectPaymentSuggestion, arg.DecidedBy, arg.PaymentID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rejectReimbursement = `-- name: RejectReimbursement :execrows
+UPDATE reimbursements SET
+    state = 'rejected',
+    admin_comment = ?,
+    decided_by = ?,
+    decided_at = CURRENT_TIMESTAMP
+WHERE id = ? AND state = 'submitted'
+`
+
+type RejectReimbursementParams struct {
+	AdminComment sql.NullString `json:"admin_comment"`
+	DecidedBy    sql.NullString `json:"decided_by"`
+	ID           int64          `json:"id"`
+}
+
+func (q *Queries) RejectReimbursement(ctx context.Context, arg RejectReimbursementParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rejectReimbursement, arg.AdminComment, arg.DecidedBy, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const releaseLock = `-- name: ReleaseLock :exec
+DELETE FROM locks WHERE name = ? AND holder = ?
+`
+
+type ReleaseLockParams struct {
+	Name   string `json:"name"`
+	Holder string `json:"holder"`
 }
 
-const listLogsByUser = `-- name: ListLogsByUser :many
-SELECT id, subsystem, level, user_id, message, metadata, created_at FROM system_logs WHERE user_id = ? ORDER BY created_at DESC LIMIT ?
+func (q *Queries) ReleaseLock(ctx context.Context, arg ReleaseLockParams) error {
+	_, err := q.db.ExecContext(ctx, releaseLock, arg.Name, arg.Holder)
+	return err
+}
+
+const removeProjectVS = `-- name: RemoveProjectVS :exec
+DELETE FROM project_vs WHERE project_id = ? AND vs = ?
 `
 
-type ListLogsByUserParams struct {
-	UserID sql.NullInt64 `json:"user_id"`
-	Limit  int64         `json:"limit"`
+type RemoveProjectVSParams struct {
+	ProjectID int64  `json:"project_id"`
+	Vs        string `json:"vs"`
 }
 
-func (q *Queries) ListLogsByUser(ctx context.Context, arg ListLogsByUserParams) ([]SystemLog, error) {
-	rows, err := q.db.QueryContext(ctx, listLogsByUser, arg.UserID, arg.Limit)
+func (q *Queries) RemoveProjectVS(ctx context.Context, arg RemoveProjectVSParams) error {
+	_, err := q.db.ExecContext(ctx, removeProjectVS, arg.ProjectID, arg.Vs)
+	return err
+}
+
+const reopenUserTask = `-- name: ReopenUserTask :exec
+DELETE FROM user_tasks WHERE user_id = ? AND task = ?
+`
+
+type ReopenUserTaskParams struct {
+	UserID int64  `json:"user_id"`
+	Task   string `json:"task"`
+}
+
+func (q *Queries) ReopenUserTask(ctx context.Context, arg ReopenUserTaskParams) error {
+	_, err := q.db.ExecContext(ctx, reopenUserTask, arg.UserID, arg.Task)
+	return err
+}
+
+const requeueInterruptedEmailCampaignRecipients = `-- name: RequeueInterruptedEmailCampaignRecipients :execrows
+UPDATE email_campaign_recipients SET status = 'pending' WHERE campaign_id = ? AND status = 'sending'
+`
+
+// Explicit admin decision to retry recipients interrupted mid-send (may double-send)
+func (q *Queries) RequeueInterruptedEmailCampaignRecipients(ctx context.Context, campaignID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, requeueInterruptedEmailCampaignRecipients, campaignID)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
-	items := []SystemLog{}
-	for rows.Next() {
-		var i SystemLog
-		if err := rows.Scan(
-			&i.ID,
-			&i.Subsystem,
-			&i.Level,
-			&i.UserID,
-			&i.Message,
-			&i.Metadata,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+	return result.RowsAffected()
 }
 
-const listLogsFiltered = `-- name: ListLogsFiltered :many
-SELECT id, subsystem, level, user_id, message, metadata, created_at FROM system_logs
-WHERE (? = '' OR subsystem = ?)
-  AND (? = '' OR level = ?)
-  AND (? = 0 OR user_id = ?)
-ORDER BY created_at DESC LIMIT ?
+const revokeAPIToken = `-- name: RevokeAPIToken :execrows
+UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ? AND revoked_at IS NULL
 `
 
-type ListLogsFilteredParams struct {
-	Column1   interface{}   `json:"column_1"`
-	Subsystem string        `json:"subsystem"`
-	Column3   interface{}   `json:"column_3"`
-	Level     string        `json:"level"`
-	Column5   interface{}   `json:"column_5"`
-	UserID    sql.NullInt64 `json:"user_id"`
-	Limit     int64         `json:"limit"`
+type RevokeAPITokenParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
 }
 
-func (q *Queries) ListLogsFiltered(ctx context.Context, arg ListLogsFilteredParams) ([]SystemLog, error) {
-	rows, err := q.db.QueryContext(ctx, listLogsFiltered,
-		arg.Column1,
-		arg.Subsystem,
-		arg.Column3,
-		arg.Level,
-		arg.Column5,
-		arg.UserID,
-		arg.Limit,
-	)
+func (q *Queries) RevokeAPIToken(ctx context.Context, arg RevokeAPITokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeAPIToken, arg.ID, arg.UserID)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []SystemLog{}
-	for rows.Next() {
-		var i SystemLog
-		if err := rows.Scan(
-			&i.ID,
-			&i.Subsystem,
-			&i.Level,
-			&i.UserID,
-			&i.Message,
-			&i.Metadata,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected()
 }
 
-const listMembershipPaymentsByUser = `-- name: ListMembershipPaymentsByUser :many
-SELECT p.id, p.user_id, p.date, p.amount, p.kind, p.kind_id, p.local_account, p.remote_account, p.identification, p.raw_data, p.staff_comment, p.created_at, p.project_id, p.dismissed_at, p.dismissed_by, p.dismissed_reason
-FROM payments p
-JOIN users u ON p.user_id = u.id
-WHERE p.user_id = ?
-AND p.identification = u.payments_id
-ORDER BY p.date DESC
+const revokeAuthSession = `-- name: RevokeAuthSession :execrows
+UPDATE auth_sessions SET revoked_at = CURRENT_TIMESTAMP
+WHERE sid = ? AND revoked_at IS NULL
 `
 
-// Only payments that match the user's membership VS (payments_id)
-func (q *Queries) ListMembershipPaymentsByUser(ctx context.Context, userID sql.NullInt64) ([]Payment, error) {
-	rows, err := q.db.QueryContext(ctx, listMembershipPaymentsByUser, userID)
+func (q *Queries) RevokeAuthSession(ctx context.Context, sid string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeAuthSession, sid)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []Payment{}
-	for rows.Next() {
-		var i Payment
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.Date,
-			&i.Amount,
-			&i.Kind,
-			&i.KindID,
-			&i.LocalAccount,
-			&i.RemoteAccount,
-			&i.Identification,
-			&i.RawData,
-			&i.StaffComment,
-			&i.CreatedAt,
-			&i.ProjectID,
-			&i.DismissedAt,
-			&i.DismissedBy,
-			&i.DismissedReason,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected()
 }
 
-const listPaymentsByUser = `-- name: ListPaymentsByUser :many
-SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason FROM payments WHERE user_id = ? ORDER BY date DESC
+const revokeAuthSessionsByKeycloakID = `-- name: RevokeAuthSessionsByKeycloakID :execrows
+UPDATE auth_sessions SET revoked_at = CURRENT_TIMESTAMP
+WHERE keycloak_id = ? AND revoked_at IS NULL
 `
 
-func (q *Queries) ListPaymentsByUser(ctx context.Context, userID sql.NullInt64) ([]Payment, error) {
-	rows, err := q.db.QueryContext(ctx, listPaymentsByUser, userID)
+func (q *Queries) RevokeAuthSessionsByKeycloakID(ctx context.Context, keycloakID string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeAuthSessionsByKeycloakID, keycloakID)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []Payment{}
-	for rows.Next() {
-		var i Payment
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.Date,
-			&i.Amount,
-			&i.Kind,
-			&i.KindID,
-			&i.LocalAccount,
-			&i.RemoteAccount,
-			&i.Identification,
-			&i.RawData,
-			&i.StaffComment,
-			&i.CreatedAt,
-			&i.ProjectID,
-			&i.DismissedAt,
-			&i.DismissedBy,
-			&i.DismissedReason,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
+		return 0, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+	return result.RowsAffected()
 }
 
-const listProjectVS = `-- name: ListProjectVS :many
+const setBillingFakturoidSubject = `-- name: SetBillingFakturoidSubject :exec
+UPDATE billing_details SET fakturoid_subject_id = ? WHERE user_id = ?
+`
 
-SELECT id, project_id, vs, note, created_at FROM project_vs WHERE project_id = ? ORDER BY created_at
+type SetBillingFakturoidSubjectParams struct {
+	FakturoidSubjectID sql.NullInt64 `json:"fakturoid_subject_id"`
+	UserID             int64         `json:"user_id"`
+}
+
+func (q *Queries) SetBillingFakturoidSubject(ctx context.Context, arg SetBillingFakturoidSubjectParams) error {
+	_, err := q.db.ExecContext(ctx, setBillingFakturoidSubject, arg.FakturoidSubjectID, arg.UserID)
+	return err
+}
+
+const setDebtThreshold = `-- name: SetDebtThreshold :exec
+INSERT INTO debt_thresholds (step, months, updated_by)
+VALUES (?, ?, ?)
+ON CONFLICT(step) DO UPDATE SET
+    months = excluded.months,
+    updated_by = excluded.updated_by,
+    updated_at = CURRENT_TIMESTAMP
 `
 
-// ============================================================================
-// PROJECT VS (Multiple VS identifiers per project)
-// ============================================================================
-func (q *Queries) ListProjectVS(ctx context.Context, projectID int64) ([]ProjectV, error) {
-	rows, err := q.db.QueryContext(ctx, listProjectVS, projectID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []ProjectV{}
-	for rows.Next() {
-		var i ProjectV
-		if err := rows.Scan(
-			&i.ID,
-			&i.ProjectID,
-			&i.Vs,
-			&i.Note,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type SetDebtThresholdParams struct {
+	Step      string `json:"step"`
+	Months    int64  `json:"months"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+func (q *Queries) SetDebtThreshold(ctx context.Context, arg SetDebtThresholdParams) error {
+	_, err := q.db.ExecContext(ctx, setDebtThreshold, arg.Step, arg.Months, arg.UpdatedBy)
+	return err
+}
+
+const setLevelBTCPay = `-- name: SetLevelBTCPay :exec
+UPDATE levels SET btcpay = ? WHERE id = ?
+`
+
+type SetLevelBTCPayParams struct {
+	Btcpay bool  `json:"btcpay"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) SetLevelBTCPay(ctx context.Context, arg SetLevelBTCPayParams) error {
+	_, err := q.db.ExecContext(ctx, setLevelBTCPay, arg.Btcpay, arg.ID)
+	return err
+}
+
+const setPaymentClassification = `-- name: SetPaymentClassification :exec
+UPDATE payments SET classification = ? WHERE id = ?
+`
+
+type SetPaymentClassificationParams struct {
+	Classification string `json:"classification"`
+	ID             int64  `json:"id"`
+}
+
+func (q *Queries) SetPaymentClassification(ctx context.Context, arg SetPaymentClassificationParams) error {
+	_, err := q.db.ExecContext(ctx, setPaymentClassification, arg.Classification, arg.ID)
+	return err
+}
+
+const setPaymentContentHash = `-- name: SetPaymentContentHash :exec
+UPDATE payments SET content_hash = ? WHERE id = ?
+`
+
+type SetPaymentContentHashParams struct {
+	ContentHash string `json:"content_hash"`
+	ID          int64  `json:"id"`
+}
+
+func (q *Queries) SetPaymentContentHash(ctx context.Context, arg SetPaymentContentHashParams) error {
+	_, err := q.db.ExecContext(ctx, setPaymentContentHash, arg.ContentHash, arg.ID)
+	return err
+}
+
+const setProjectBTCPay = `-- name: SetProjectBTCPay :exec
+UPDATE projects SET btcpay = ? WHERE id = ?
+`
+
+type SetProjectBTCPayParams struct {
+	Btcpay bool  `json:"btcpay"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) SetProjectBTCPay(ctx context.Context, arg SetProjectBTCPayParams) error {
+	_, err := q.db.ExecContext(ctx, setProjectBTCPay, arg.Btcpay, arg.ID)
+	return err
+}
+
+const setProjectPublic = `-- name: SetProjectPublic :exec
+UPDATE projects SET public = ? WHERE id = ?
+`
+
+type SetProjectPublicParams struct {
+	Public bool  `json:"public"`
+	ID     int64 `json:"id"`
 }
 
-const listProjects = `-- name: ListProjects :many
+func (q *Queries) SetProjectPublic(ctx context.Context, arg SetProjectPublicParams) error {
+	_, err := q.db.ExecContext(ctx, setProjectPublic, arg.Public, arg.ID)
+	return err
+}
 
-SELECT id, name, payments_id, description FROM projects ORDER BY id DESC
+const setProjectTarget = `-- name: SetProjectTarget :exec
+UPDATE projects SET
+    target_reached_at = CASE WHEN goal = ?1 THEN target_reached_at END,
+    goal = ?1,
+    deadline = ?2
+WHERE id = ?3
 `
 
-// ============================================================================
-// PROJECTS (Fundraising / Special VS)
-// ============================================================================
-func (q *Queries) ListProjects(ctx context.Context) ([]Project, error) {
-	rows, err := q.db.QueryContext(ctx, listProjects)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []Project{}
-	for rows.Next() {
-		var i Project
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.PaymentsID,
-			&i.Description,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type SetProjectTargetParams struct {
+	Goal     money.Amount `json:"goal"`
+	Deadline sql.NullTime `json:"deadline"`
+	ID       int64        `json:"id"`
 }
 
-const listRecentLogs = `-- name: ListRecentLogs :many
-SELECT id, subsystem, level, user_id, message, metadata, created_at FROM system_logs ORDER BY created_at DESC LIMIT ?
+// A changed goal is a new target, admins are notified again when it is reached
+func (q *Queries) SetProjectTarget(ctx context.Context, arg SetProjectTargetParams) error {
+	_, err := q.db.ExecContext(ctx, setProjectTarget, arg.Goal, arg.Deadline, arg.ID)
+	return err
+}
+
+const setSuspensionExemption = `-- name: SetSuspensionExemption :exec
+INSERT INTO suspension_exemptions (user_id, reason, created_by)
+VALUES (?, ?, ?)
+ON CONFLICT(user_id) DO UPDATE SET
+    reason = excluded.reason,
+    created_by = excluded.created_by,
+    created_at = CURRENT_TIMESTAMP
 `
 
-func (q *Queries) ListRecentLogs(ctx context.Context, limit int64) ([]SystemLog, error) {
-	rows, err := q.db.QueryContext(ctx, listRecentLogs, limit)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []SystemLog{}
-	for rows.Next() {
-		var i SystemLog
-		if err := rows.Scan(
-			&i.ID,
-			&i.Subsystem,
-			&i.Level,
-			&i.UserID,
-			&i.Message,
-			&i.Metadata,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type SetSuspensionExemptionParams struct {
+	UserID    int64  `json:"user_id"`
+	Reason    string `json:"reason"`
+	CreatedBy string `json:"created_by"`
 }
 
-const listRecentPayments = `-- name: ListRecentPayments :many
-SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason FROM payments ORDER BY date DESC LIMIT ?
+func (q *Queries) SetSuspensionExemption(ctx context.Context, arg SetSuspensionExemptionParams) error {
+	_, err := q.db.ExecContext(ctx, setSuspensionExemption, arg.UserID, arg.Reason, arg.CreatedBy)
+	return err
+}
+
+const setUserBillingCycle = `-- name: SetUserBillingCycle :exec
+INSERT INTO user_payment_settings (user_id, billing_cycle, updated_at)
+VALUES (?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(user_id) DO UPDATE SET
+    billing_cycle = excluded.billing_cycle,
+    updated_at = excluded.updated_at
 `
 
-func (q *Queries) ListRecentPayments(ctx context.Context, limit int64) ([]Payment, error) {
-	rows, err := q.db.QueryContext(ctx, listRecentPayments, limit)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []Payment{}
-	for rows.Next() {
-		var i Payment
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.Date,
-			&i.Amount,
-			&i.Kind,
-			&i.KindID,
-			&i.LocalAccount,
-			&i.RemoteAccount,
-			&i.Identification,
-			&i.RawData,
-			&i.StaffComment,
-			&i.CreatedAt,
-			&i.ProjectID,
-			&i.DismissedAt,
-			&i.DismissedBy,
-			&i.DismissedReason,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type SetUserBillingCycleParams struct {
+	UserID       int64  `json:"user_id"`
+	BillingCycle string `json:"billing_cycle"`
 }
 
-const listUnassignedPayments = `-- name: ListUnassignedPayments :many
-SELECT id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason FROM payments WHERE user_id IS NULL AND dismissed_at IS NULL ORDER BY date DESC
+func (q *Queries) SetUserBillingCycle(ctx context.Context, arg SetUserBillingCycleParams) error {
+	_, err := q.db.ExecContext(ctx, setUserBillingCycle, arg.UserID, arg.BillingCycle)
+	return err
+}
+
+const setUserDashboardWidget = `-- name: SetUserDashboardWidget :exec
+INSERT INTO user_dashboard_widgets (user_id, widget, visible, updated_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(user_id, widget) DO UPDATE SET
+    visible = excluded.visible,
+    updated_at = excluded.updated_at
 `
 
-func (q *Queries) ListUnassignedPayments(ctx context.Context) ([]Payment, error) {
-	rows, err := q.db.QueryContext(ctx, listUnassignedPayments)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []Payment{}
-	for rows.Next() {
-		var i Payment
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.Date,
-			&i.Amount,
-			&i.Kind,
-			&i.KindID,
-			&i.LocalAccount,
-			&i.RemoteAccount,
-			&i.Identification,
-			&i.RawData,
-			&i.StaffComment,
-			&i.CreatedAt,
-			&i.ProjectID,
-			&i.DismissedAt,
-			&i.DismissedBy,
-			&i.DismissedReason,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type SetUserDashboardWidgetParams struct {
+	UserID  int64  `json:"user_id"`
+	Widget  string `json:"widget"`
+	Visible bool   `json:"visible"`
 }
 
-const listUsers = `-- name: ListUsers :many
-SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users ORDER BY realname, email
+func (q *Queries) SetUserDashboardWidget(ctx context.Context, arg SetUserDashboardWidgetParams) error {
+	_, err := q.db.ExecContext(ctx, setUserDashboardWidget, arg.UserID, arg.Widget, arg.Visible)
+	return err
+}
+
+const setUserNotificationPreference = `-- name: SetUserNotificationPreference :exec
+INSERT INTO user_notification_preferences (user_id, notification, enabled, updated_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(user_id, notification) DO UPDATE SET
+    enabled = excluded.enabled,
+    updated_at = excluded.updated_at
 `
 
-func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, listUsers)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []User{}
-	for rows.Next() {
-		var i User
-		if err := rows.Scan(
-			&i.ID,
-			&i.KeycloakID,
-			&i.Email,
-			&i.Username,
-			&i.Realname,
-			&i.Phone,
-			&i.AltContact,
-			&i.LevelID,
-			&i.LevelActualAmount,
-			&i.PaymentsID,
-			&i.DateJoined,
-			&i.KeysGranted,
-			&i.KeysReturned,
-			&i.State,
-			&i.IsCouncil,
-			&i.IsStaff,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type SetUserNotificationPreferenceParams struct {
+	UserID       int64  `json:"user_id"`
+	Notification string `json:"notification"`
+	Enabled      bool   `json:"enabled"`
 }
 
-const listUsersByState = `-- name: ListUsersByState :many
-SELECT id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at FROM users WHERE state = ? ORDER BY realname, email
+func (q *Queries) SetUserNotificationPreference(ctx context.Context, arg SetUserNotificationPreferenceParams) error {
+	_, err := q.db.ExecContext(ctx, setUserNotificationPreference, arg.UserID, arg.Notification, arg.Enabled)
+	return err
+}
+
+const setUserQRFormat = `-- name: SetUserQRFormat :exec
+INSERT INTO user_payment_settings (user_id, qr_format, updated_at)
+VALUES (?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(user_id) DO UPDATE SET
+    qr_format = excluded.qr_format,
+    updated_at = excluded.updated_at
 `
 
-func (q *Queries) ListUsersByState(ctx context.Context, state string) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, listUsersByState, state)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []User{}
-	for rows.Next() {
-		var i User
-		if err := rows.Scan(
-			&i.ID,
-			&i.KeycloakID,
-			&i.Email,
-			&i.Username,
-			&i.Realname,
-			&i.Phone,
-			&i.AltContact,
-			&i.LevelID,
-			&i.LevelActualAmount,
-			&i.PaymentsID,
-			&i.DateJoined,
-			&i.KeysGranted,
-			&i.KeysReturned,
-			&i.State,
-			&i.IsCouncil,
-			&i.IsStaff,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type SetUserQRFormatParams struct {
+	UserID   int64  `json:"user_id"`
+	QrFormat string `json:"qr_format"`
 }
 
-const removeProjectVS = `-- name: RemoveProjectVS :exec
-DELETE FROM project_vs WHERE project_id = ? AND vs = ?
+func (q *Queries) SetUserQRFormat(ctx context.Context, arg SetUserQRFormatParams) error {
+	_, err := q.db.ExecContext(ctx, setUserQRFormat, arg.UserID, arg.QrFormat)
+	return err
+}
+
+const ticketMessageExists = `-- name: TicketMessageExists :one
+SELECT EXISTS(SELECT 1 FROM ticket_messages WHERE message_id = ?)
 `
 
-type RemoveProjectVSParams struct {
-	ProjectID int64  `json:"project_id"`
-	Vs        string `json:"vs"`
+func (q *Queries) TicketMessageExists(ctx context.Context, messageID sql.NullString) (int64, error) {
+	row := q.db.QueryRowContext(ctx, ticketMessageExists, messageID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
 }
 
-func (q *Queries) RemoveProjectVS(ctx context.Context, arg RemoveProjectVSParams) error {
-	_, err := q.db.ExecContext(ctx, removeProjectVS, arg.ProjectID, arg.Vs)
+const touchAPIToken = `-- name: TouchAPIToken :exec
+UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP WHERE id = ?
+`
+
+func (q *Queries) TouchAPIToken(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, touchAPIToken, id)
 	return err
 }
 
@@ -1662,7 +9583,7 @@ UPDATE payments SET
     dismissed_by = NULL,
     dismissed_reason = NULL
 WHERE id = ?
-RETURNING id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason
+RETURNING id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification
 `
 
 func (q *Queries) UndismissPayment(ctx context.Context, id int64) (Payment, error) {
@@ -1685,24 +9606,104 @@ func (q *Queries) UndismissPayment(ctx context.Context, id int64) (Payment, erro
 		&i.DismissedAt,
 		&i.DismissedBy,
 		&i.DismissedReason,
+		&i.ReversalOf,
+		&i.ReversalReview,
+		&i.ContentHash,
+		&i.Classification,
+	)
+	return i, err
+}
+
+const updateEmailCampaignStatus = `-- name: UpdateEmailCampaignStatus :one
+UPDATE email_campaigns SET
+    status = ?,
+    completed_at = ?
+WHERE id = ?
+RETURNING id, name, subject, template_name, body, audience, status, created_by, created_at, completed_at
+`
+
+type UpdateEmailCampaignStatusParams struct {
+	Status      string       `json:"status"`
+	CompletedAt sql.NullTime `json:"completed_at"`
+	ID          int64        `json:"id"`
+}
+
+func (q *Queries) UpdateEmailCampaignStatus(ctx context.Context, arg UpdateEmailCampaignStatusParams) (EmailCampaign, error) {
+	row := q.db.QueryRowContext(ctx, updateEmailCampaignStatus, arg.Status, arg.CompletedAt, arg.ID)
+	var i EmailCampaign
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Subject,
+		&i.TemplateName,
+		&i.Body,
+		&i.Audience,
+		&i.Status,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.CompletedAt,
 	)
 	return i, err
 }
 
+const updateExpenseTags = `-- name: UpdateExpenseTags :execrows
+UPDATE expenses SET
+    category = ?,
+    project_id = ?,
+    staff_comment = ?
+WHERE id = ?
+`
+
+type UpdateExpenseTagsParams struct {
+	Category     string         `json:"category"`
+	ProjectID    sql.NullInt64  `json:"project_id"`
+	StaffComment sql.NullString `json:"staff_comment"`
+	ID           int64          `json:"id"`
+}
+
+func (q *Queries) UpdateExpenseTags(ctx context.Context, arg UpdateExpenseTagsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateExpenseTags,
+		arg.Category,
+		arg.ProjectID,
+		arg.StaffComment,
+		arg.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFakturoidInvoiceStatus = `-- name: UpdateFakturoidInvoiceStatus :exec
+UPDATE fakturoid_invoices SET status = ?, paid_on = ?, synced_at = CURRENT_TIMESTAMP
+WHERE fee_id = ?
+`
+
+type UpdateFakturoidInvoiceStatusParams struct {
+	Status string       `json:"status"`
+	PaidOn sql.NullTime `json:"paid_on"`
+	FeeID  int64        `json:"fee_id"`
+}
+
+func (q *Queries) UpdateFakturoidInvoiceStatus(ctx context.Context, arg UpdateFakturoidInvoiceStatusParams) error {
+	_, err := q.db.ExecContext(ctx, updateFakturoidInvoiceStatus, arg.Status, arg.PaidOn, arg.FeeID)
+	return err
+}
+
 const updateLevel = `-- name: UpdateLevel :one
 UPDATE levels SET
     name = ?,
     amount = ?,
     active = ?
 WHERE id = ?
-RETURNING id, name, amount, active, created_at
+RETURNING id, name, amount, active, created_at, btcpay
 `
 
 type UpdateLevelParams struct {
-	Name   string `json:"name"`
-	Amount string `json:"amount"`
-	Active bool   `json:"active"`
-	ID     int64  `json:"id"`
+	Name   string       `json:"name"`
+	Amount money.Amount `json:"amount"`
+	Active bool         `json:"active"`
+	ID     int64        `json:"id"`
 }
 
 func (q *Queries) UpdateLevel(ctx context.Context, arg UpdateLevelParams) (Level, error) {
@@ -1719,17 +9720,127 @@ func (q *Queries) UpdateLevel(ctx context.Context, arg UpdateLevelParams) (Level
 		&i.Amount,
 		&i.Active,
 		&i.CreatedAt,
+		&i.Btcpay,
 	)
 	return i, err
 }
 
+const updateLevelAmount = `-- name: UpdateLevelAmount :exec
+UPDATE levels SET amount = ? WHERE id = ?
+`
+
+type UpdateLevelAmountParams struct {
+	Amount money.Amount `json:"amount"`
+	ID     int64        `json:"id"`
+}
+
+func (q *Queries) UpdateLevelAmount(ctx context.Context, arg UpdateLevelAmountParams) error {
+	_, err := q.db.ExecContext(ctx, updateLevelAmount, arg.Amount, arg.ID)
+	return err
+}
+
+const updateMergedUser = `-- name: UpdateMergedUser :exec
+UPDATE users SET
+    keycloak_id = ?,
+    username = ?,
+    realname = ?,
+    phone = ?,
+    alt_contact = ?,
+    payments_id = ?,
+    date_joined = ?,
+    keys_granted = ?,
+    keys_returned = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`
+
+type UpdateMergedUserParams struct {
+	KeycloakID   sql.NullString `json:"keycloak_id"`
+	Username     sql.NullString `json:"username"`
+	Realname     sql.NullString `json:"realname"`
+	Phone        sql.NullString `json:"phone"`
+	AltContact   sql.NullString `json:"alt_contact"`
+	PaymentsID   sql.NullString `json:"payments_id"`
+	DateJoined   time.Time      `json:"date_joined"`
+	KeysGranted  sql.NullTime   `json:"keys_granted"`
+	KeysReturned sql.NullTime   `json:"keys_returned"`
+	ID           int64          `json:"id"`
+}
+
+// Identity of the target user after a merge (fields the target was missing come from the source)
+func (q *Queries) UpdateMergedUser(ctx context.Context, arg UpdateMergedUserParams) error {
+	_, err := q.db.ExecContext(ctx, updateMergedUser,
+		arg.KeycloakID,
+		arg.Username,
+		arg.Realname,
+		arg.Phone,
+		arg.AltContact,
+		arg.PaymentsID,
+		arg.DateJoined,
+		arg.KeysGranted,
+		arg.KeysReturned,
+		arg.ID,
+	)
+	return err
+}
+
+const updatePaymentMatchRule = `-- name: UpdatePaymentMatchRule :execrows
+UPDATE payment_match_rules SET
+    name = ?,
+    priority = ?,
+    remote_account = ?,
+    message_pattern = ?,
+    specific_symbol = ?,
+    amount_min = ?,
+    amount_max = ?,
+    user_id = ?,
+    project_id = ?,
+    active = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`
+
+type UpdatePaymentMatchRuleParams struct {
+	Name           string          `json:"name"`
+	Priority       int64           `json:"priority"`
+	RemoteAccount  string          `json:"remote_account"`
+	MessagePattern string          `json:"message_pattern"`
+	SpecificSymbol string          `json:"specific_symbol"`
+	AmountMin      sql.NullFloat64 `json:"amount_min"`
+	AmountMax      sql.NullFloat64 `json:"amount_max"`
+	UserID         sql.NullInt64   `json:"user_id"`
+	ProjectID      sql.NullInt64   `json:"project_id"`
+	Active         bool            `json:"active"`
+	ID             int64           `json:"id"`
+}
+
+func (q *Queries) UpdatePaymentMatchRule(ctx context.Context, arg UpdatePaymentMatchRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updatePaymentMatchRule,
+		arg.Name,
+		arg.Priority,
+		arg.RemoteAccount,
+		arg.MessagePattern,
+		arg.SpecificSymbol,
+		arg.AmountMin,
+		arg.AmountMax,
+		arg.UserID,
+		arg.ProjectID,
+		arg.Active,
+		arg.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateProject = `-- name: UpdateProject :one
 UPDATE projects SET
     name = ?,
     payments_id = ?,
     description = ?
 WHERE id = ?
-RETURNING id, name, payments_id, description
+RETURNING id, name, payments_id, description, public, btcpay, goal, deadline, target_reached_at
 `
 
 type UpdateProjectParams struct {
@@ -1752,10 +9863,32 @@ func (q *Queries) UpdateProject(ctx context.Context, arg UpdateProjectParams) (P
 		&i.Name,
 		&i.PaymentsID,
 		&i.Description,
+		&i.Public,
+		&i.Btcpay,
+		&i.Goal,
+		&i.Deadline,
+		&i.TargetReachedAt,
 	)
 	return i, err
 }
 
+const updateTicketState = `-- name: UpdateTicketState :execrows
+UPDATE tickets SET state = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+`
+
+type UpdateTicketStateParams struct {
+	State string `json:"state"`
+	ID    int64  `json:"id"`
+}
+
+func (q *Queries) UpdateTicketState(ctx context.Context, arg UpdateTicketStateParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateTicketState, arg.State, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users SET
     email = ?,
@@ -1783,7 +9916,7 @@ type UpdateUserParams struct {
 	Phone             sql.NullString `json:"phone"`
 	AltContact        sql.NullString `json:"alt_contact"`
 	LevelID           int64          `json:"level_id"`
-	LevelActualAmount string         `json:"level_actual_amount"`
+	LevelActualAmount money.Amount   `json:"level_actual_amount"`
 	PaymentsID        sql.NullString `json:"payments_id"`
 	State             string         `json:"state"`
 	IsCouncil         bool           `json:"is_council"`
@@ -1843,8 +9976,8 @@ RETURNING id, keycloak_id, email, username, realname, phone, alt_contact, level_
 `
 
 type UpdateUserCustomFeeParams struct {
-	LevelActualAmount string `json:"level_actual_amount"`
-	ID                int64  `json:"id"`
+	LevelActualAmount money.Amount `json:"level_actual_amount"`
+	ID                int64        `json:"id"`
 }
 
 func (q *Queries) UpdateUserCustomFee(ctx context.Context, arg UpdateUserCustomFeeParams) (User, error) {
@@ -1873,6 +10006,55 @@ func (q *Queries) UpdateUserCustomFee(ctx context.Context, arg UpdateUserCustomF
 	return i, err
 }
 
+const updateUserIdentity = `-- name: UpdateUserIdentity :one
+UPDATE users SET
+    email = ?,
+    username = ?,
+    realname = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+RETURNING id, keycloak_id, email, username, realname, phone, alt_contact, level_id, level_actual_amount, payments_id, date_joined, keys_granted, keys_returned, state, is_council, is_staff, created_at, updated_at
+`
+
+type UpdateUserIdentityParams struct {
+	Email    string         `json:"email"`
+	Username sql.NullString `json:"username"`
+	Realname sql.NullString `json:"realname"`
+	ID       int64          `json:"id"`
+}
+
+// Overwrites the fields mirrored from Keycloak (admin "sync from Keycloak")
+func (q *Queries) UpdateUserIdentity(ctx context.Context, arg UpdateUserIdentityParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserIdentity,
+		arg.Email,
+		arg.Username,
+		arg.Realname,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.KeycloakID,
+		&i.Email,
+		&i.Username,
+		&i.Realname,
+		&i.Phone,
+		&i.AltContact,
+		&i.LevelID,
+		&i.LevelActualAmount,
+		&i.PaymentsID,
+		&i.DateJoined,
+		&i.KeysGranted,
+		&i.KeysReturned,
+		&i.State,
+		&i.IsCouncil,
+		&i.IsStaff,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const updateUserKeycloakInfo = `-- name: UpdateUserKeycloakInfo :one
 UPDATE users SET
     username = ?,
@@ -1912,6 +10094,83 @@ func (q *Queries) UpdateUserKeycloakInfo(ctx context.Context, arg UpdateUserKeyc
 	return i, err
 }
 
+const updateUserLevel = `-- name: UpdateUserLevel :exec
+UPDATE users SET
+    level_id = ?,
+    level_actual_amount = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`
+
+type UpdateUserLevelParams struct {
+	LevelID           int64        `json:"level_id"`
+	LevelActualAmount money.Amount `json:"level_actual_amount"`
+	ID                int64        `json:"id"`
+}
+
+// The own amount starts at the new level amount (members paying more set it again)
+func (q *Queries) UpdateUserLevel(ctx context.Context, arg UpdateUserLevelParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserLevel, arg.LevelID, arg.LevelActualAmount, arg.ID)
+	return err
+}
+
+const updateUserNote = `-- name: UpdateUserNote :one
+UPDATE user_notes SET
+    body = ?,
+    pinned = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND user_id = ?
+RETURNING id, user_id, author, body, pinned, created_at, updated_at
+`
+
+type UpdateUserNoteParams struct {
+	Body   string `json:"body"`
+	Pinned bool   `json:"pinned"`
+	ID     int64  `json:"id"`
+	UserID int64  `json:"user_id"`
+}
+
+func (q *Queries) UpdateUserNote(ctx context.Context, arg UpdateUserNoteParams) (UserNote, error) {
+	row := q.db.QueryRowContext(ctx, updateUserNote,
+		arg.Body,
+		arg.Pinned,
+		arg.ID,
+		arg.UserID,
+	)
+	var i UserNote
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Author,
+		&i.Body,
+		&i.Pinned,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateUserPaymentsVS = `-- name: UpdateUserPaymentsVS :execrows
+UPDATE payments SET identification = ?1
+WHERE user_id = ?2 AND identification = ?3
+`
+
+type UpdateUserPaymentsVSParams struct {
+	NewPaymentsID string        `json:"new_payments_id"`
+	UserID        sql.NullInt64 `json:"user_id"`
+	OldPaymentsID string        `json:"old_payments_id"`
+}
+
+// The member's payments carrying the old VS take the new one after a VS change,
+// balances only count payments matching the member's VS
+func (q *Queries) UpdateUserPaymentsVS(ctx context.Context, arg UpdateUserPaymentsVSParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateUserPaymentsVS, arg.NewPaymentsID, arg.UserID, arg.OldPaymentsID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUserProfile = `-- name: UpdateUserProfile :one
 UPDATE users SET
     realname = ?,
@@ -1960,6 +10219,100 @@ func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfilePa
 	return i, err
 }
 
+const updateUserState = `-- name: UpdateUserState :exec
+UPDATE users SET state = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+`
+
+type UpdateUserStateParams struct {
+	State string `json:"state"`
+	ID    int64  `json:"id"`
+}
+
+func (q *Queries) UpdateUserState(ctx context.Context, arg UpdateUserStateParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserState, arg.State, arg.ID)
+	return err
+}
+
+const upsertApplicationVouch = `-- name: UpsertApplicationVouch :exec
+INSERT INTO application_vouches (application_id, user_id, note)
+VALUES (?, ?, ?)
+ON CONFLICT(application_id, user_id) DO UPDATE SET note = excluded.note
+`
+
+type UpsertApplicationVouchParams struct {
+	ApplicationID int64          `json:"application_id"`
+	UserID        int64          `json:"user_id"`
+	Note          sql.NullString `json:"note"`
+}
+
+// Vouching again only updates the note
+func (q *Queries) UpsertApplicationVouch(ctx context.Context, arg UpsertApplicationVouchParams) error {
+	_, err := q.db.ExecContext(ctx, upsertApplicationVouch, arg.ApplicationID, arg.UserID, arg.Note)
+	return err
+}
+
+const upsertAuthSession = `-- name: UpsertAuthSession :exec
+INSERT INTO auth_sessions (sid, keycloak_id, id_token)
+VALUES (?, ?, ?)
+ON CONFLICT(sid) DO UPDATE SET
+    id_token = excluded.id_token,
+    updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertAuthSessionParams struct {
+	Sid        string `json:"sid"`
+	KeycloakID string `json:"keycloak_id"`
+	IDToken    string `json:"id_token"`
+}
+
+func (q *Queries) UpsertAuthSession(ctx context.Context, arg UpsertAuthSessionParams) error {
+	_, err := q.db.ExecContext(ctx, upsertAuthSession, arg.Sid, arg.KeycloakID, arg.IDToken)
+	return err
+}
+
+const upsertBillingDetails = `-- name: UpsertBillingDetails :one
+INSERT INTO billing_details (user_id, company_name, company_id, vat_id, address, updated_at)
+VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(user_id) DO UPDATE SET
+    company_name = excluded.company_name,
+    company_id = excluded.company_id,
+    vat_id = excluded.vat_id,
+    address = excluded.address,
+    updated_at = excluded.updated_at
+RETURNING user_id, company_name, company_id, vat_id, address, updated_at, fakturoid, fakturoid_since, fakturoid_subject_id
+`
+
+type UpsertBillingDetailsParams struct {
+	UserID      int64  `json:"user_id"`
+	CompanyName string `json:"company_name"`
+	CompanyID   string `json:"company_id"`
+	VatID       string `json:"vat_id"`
+	Address     string `json:"address"`
+}
+
+func (q *Queries) UpsertBillingDetails(ctx context.Context, arg UpsertBillingDetailsParams) (BillingDetail, error) {
+	row := q.db.QueryRowContext(ctx, upsertBillingDetails,
+		arg.UserID,
+		arg.CompanyName,
+		arg.CompanyID,
+		arg.VatID,
+		arg.Address,
+	)
+	var i BillingDetail
+	err := row.Scan(
+		&i.UserID,
+		&i.CompanyName,
+		&i.CompanyID,
+		&i.VatID,
+		&i.Address,
+		&i.UpdatedAt,
+		&i.Fakturoid,
+		&i.FakturoidSince,
+		&i.FakturoidSubjectID,
+	)
+	return i, err
+}
+
 const upsertPayment = `-- name: UpsertPayment :one
 INSERT INTO payments (
     user_id, project_id, date, amount, kind, kind_id,
@@ -1975,14 +10328,14 @@ ON CONFLICT(kind, kind_id) DO UPDATE SET
     identification = excluded.identification,
     raw_data = excluded.raw_data,
     staff_comment = excluded.staff_comment
-RETURNING id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason
+RETURNING id, user_id, date, amount, kind, kind_id, local_account, remote_account, identification, raw_data, staff_comment, created_at, project_id, dismissed_at, dismissed_by, dismissed_reason, reversal_of, reversal_review, content_hash, classification
 `
 
 type UpsertPaymentParams struct {
 	UserID         sql.NullInt64  `json:"user_id"`
 	ProjectID      sql.NullInt64  `json:"project_id"`
 	Date           time.Time      `json:"date"`
-	Amount         string         `json:"amount"`
+	Amount         money.Amount   `json:"amount"`
 	Kind           string         `json:"kind"`
 	KindID         string         `json:"kind_id"`
 	LocalAccount   string         `json:"local_account"`
@@ -2024,6 +10377,90 @@ func (q *Queries) UpsertPayment(ctx context.Context, arg UpsertPaymentParams) (P
 		&i.DismissedAt,
 		&i.DismissedBy,
 		&i.DismissedReason,
+		&i.ReversalOf,
+		&i.ReversalReview,
+		&i.ContentHash,
+		&i.Classification,
 	)
 	return i, err
 }
+
+const upsertPaymentSuggestion = `-- name: UpsertPaymentSuggestion :exec
+INSERT INTO payment_suggestions (payment_id, user_id, payment_count)
+VALUES (?, ?, ?)
+ON CONFLICT(payment_id) DO UPDATE SET
+    user_id = excluded.user_id,
+    payment_count = excluded.payment_count
+WHERE payment_suggestions.state = 'suggested'
+`
+
+type UpsertPaymentSuggestionParams struct {
+	PaymentID    int64 `json:"payment_id"`
+	UserID       int64 `json:"user_id"`
+	PaymentCount int64 `json:"payment_count"`
+}
+
+// A rejected suggestion is not proposed again
+func (q *Queries) UpsertPaymentSuggestion(ctx context.Context, arg UpsertPaymentSuggestionParams) error {
+	_, err := q.db.ExecContext(ctx, upsertPaymentSuggestion, arg.PaymentID, arg.UserID, arg.PaymentCount)
+	return err
+}
+
+const upsertProjectMember = `-- name: UpsertProjectMember :exec
+INSERT INTO project_members (project_id, user_id, role)
+VALUES (?, ?, ?)
+ON CONFLICT(project_id, user_id) DO UPDATE SET role = excluded.role
+`
+
+type UpsertProjectMemberParams struct {
+	ProjectID int64  `json:"project_id"`
+	UserID    int64  `json:"user_id"`
+	Role      string `json:"role"`
+}
+
+// Joining again only updates the role
+func (q *Queries) UpsertProjectMember(ctx context.Context, arg UpsertProjectMemberParams) error {
+	_, err := q.db.ExecContext(ctx, upsertProjectMember, arg.ProjectID, arg.UserID, arg.Role)
+	return err
+}
+
+const upsertProjectWallEntry = `-- name: UpsertProjectWallEntry :exec
+INSERT INTO project_wall_entries (project_id, user_id, nickname)
+VALUES (?, ?, ?)
+ON CONFLICT(project_id, user_id) DO UPDATE SET
+    state = CASE WHEN project_wall_entries.nickname = excluded.nickname THEN project_wall_entries.state ELSE 'pending' END,
+    nickname = excluded.nickname,
+    updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertProjectWallEntryParams struct {
+	ProjectID int64  `json:"project_id"`
+	UserID    int64  `json:"user_id"`
+	Nickname  string `json:"nickname"`
+}
+
+// A changed nickname has to be approved again
+func (q *Queries) UpsertProjectWallEntry(ctx context.Context, arg UpsertProjectWallEntryParams) error {
+	_, err := q.db.ExecContext(ctx, upsertProjectWallEntry, arg.ProjectID, arg.UserID, arg.Nickname)
+	return err
+}
+
+const upsertWebSession = `-- name: UpsertWebSession :exec
+INSERT INTO web_sessions (id, data, expires_at, updated_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(id) DO UPDATE SET
+    data = excluded.data,
+    expires_at = excluded.expires_at,
+    updated_at = excluded.updated_at
+`
+
+type UpsertWebSessionParams struct {
+	ID        string    `json:"id"`
+	Data      []byte    `json:"data"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) UpsertWebSession(ctx context.Context, arg UpsertWebSessionParams) error {
+	_, err := q.db.ExecContext(ctx, upsertWebSession, arg.ID, arg.Data, arg.ExpiresAt)
+	return err
+}
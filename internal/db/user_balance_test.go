@@ -0,0 +1,267 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/base48/member-portal/internal/money"
+)
+
+func TestGetUserBalance(t *testing.T) {
+	database, q := openTestDB(t)
+	ctx := context.Background()
+
+	mustExec(t, database, `INSERT INTO levels (id, name, amount) VALUES (100, 'Test', '100000')`)
+	mustExec(t, database, `INSERT INTO users (id, email, level_id, payments_id) VALUES (1, 'a@example.com', 100, '1001'), (2, 'b@example.com', 100, '1002')`)
+
+	payment := `INSERT INTO payments (date, amount, kind, kind_id, local_account, remote_account, identification, user_id, content_hash)
+		VALUES ('2026-01-10', ?, 'fio', ?, 'local', 'remote', '1001', 1, ?)`
+	mustExec(t, database, payment, int64(300000), "p1", "h1")
+	mustExec(t, database, `INSERT INTO fees (user_id, level_id, period_start, amount) VALUES (1, 100, '2026-01-01', '100000'), (1, 100, '2026-02-01', '100000')`)
+
+	charge := `INSERT INTO charges (user_id, description, amount, category, date, created_by) VALUES (?, ?, ?, ?, '2026-02-10', 'admin')`
+	mustExec(t, database, charge, 1, "Skříňka", "50000", "locker")
+	mustExec(t, database, charge, 1, "PLA", int64(12050), "3d_printing")
+	mustExec(t, database, charge, 2, "Jiný člen", int64(70000), "other")
+
+	// Forgiven half of February, a credit note and a correction against the member
+	adjustment := `INSERT INTO balance_adjustments (user_id, fee_id, amount, reason, created_by) VALUES (?, ?, ?, ?, 'admin')`
+	mustExec(t, database, adjustment, 1, 2, int64(50000), "Nemoc")
+	mustExec(t, database, adjustment, 1, nil, "20000", "Dobropis")
+	mustExec(t, database, adjustment, 1, nil, int64(-5000), "Oprava")
+	mustExec(t, database, adjustment, 2, nil, int64(90000), "Jiný člen")
+
+	balance, err := q.GetUserBalance(ctx, GetUserBalanceParams{
+		UserID:   sql.NullInt64{Int64: 1, Valid: true},
+		UserID_2: 1,
+		UserID_3: sql.NullInt64{Int64: 1, Valid: true},
+		UserID_4: 1,
+		UserID_5: 1,
+	})
+	if err != nil {
+		t.Fatalf("GetUserBalance() error: %v", err)
+	}
+	if want := int64(300000 - 200000 - 50000 - 12050 + 50000 + 20000 - 5000); balance != want {
+		t.Errorf("GetUserBalance() = %d, want %d", balance, want)
+	}
+
+	// The ledger lists the same items, so its running total ends at the balance
+	ledger, err := q.ListUserLedger(ctx, sql.NullInt64{Int64: 1, Valid: true})
+	if err != nil {
+		t.Fatalf("ListUserLedger() error: %v", err)
+	}
+	var total int64
+	for _, entry := range ledger {
+		total += int64(entry.Amount)
+	}
+	if len(ledger) != 1+2+2+3 || total != balance {
+		t.Errorf("ListUserLedger() = %d entries totalling %d, want 8 totalling %d", len(ledger), total, balance)
+	}
+	if ledger[0].EntryType != "fee" || ledger[len(ledger)-1].EntryType != "adjustment" {
+		t.Errorf("ListUserLedger() not ordered by date: first %s, last %s", ledger[0].EntryType, ledger[len(ledger)-1].EntryType)
+	}
+
+	credited, err := q.GetFeeAdjustmentTotal(ctx, sql.NullInt64{Int64: 2, Valid: true})
+	if err != nil || credited != 50000 {
+		t.Errorf("GetFeeAdjustmentTotal() = %d, %v; want 50000", credited, err)
+	}
+}
+
+func TestGetUserBalanceSince(t *testing.T) {
+	database, q := openTestDB(t)
+	ctx := context.Background()
+
+	mustExec(t, database, `INSERT INTO levels (id, name, amount) VALUES (100, 'Test', '100000')`)
+	mustExec(t, database, `INSERT INTO users (id, email, level_id, payments_id) VALUES (1, 'a@example.com', 100, '1001')`)
+
+	// Dates as written by the Go driver and on the first day of the next month
+	payment := `INSERT INTO payments (date, amount, kind, kind_id, local_account, remote_account, identification, user_id, content_hash)
+		VALUES (?, ?, 'fio', ?, 'local', 'remote', '1001', 1, ?)`
+	mustExec(t, database, payment, time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC), int64(300000), "p1", "h1")
+	mustExec(t, database, payment, "2026-02-01", int64(100000), "p2", "h2")
+	mustExec(t, database, `INSERT INTO fees (user_id, level_id, period_start, amount) VALUES (1, 100, '2026-01-01', '100000'), (1, 100, '2026-02-01', '100000')`)
+	mustExec(t, database, `INSERT INTO charges (user_id, description, amount, category, date, created_by) VALUES (1, 'Skříňka', '50000', 'locker', '2026-02-10', 'admin')`)
+
+	if err := q.CreateBalanceSnapshot(ctx, CreateBalanceSnapshotParams{
+		UserID:  1,
+		Month:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Balance: money.Amount(200000),
+	}); err != nil {
+		t.Fatalf("CreateBalanceSnapshot() error: %v", err)
+	}
+	snapshots, err := q.ListLatestBalanceSnapshots(ctx)
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("ListLatestBalanceSnapshots() = %d, %v; want 1", len(snapshots), err)
+	}
+
+	delta, err := q.GetUserBalanceSince(ctx, GetUserBalanceSinceParams{
+		UserID: sql.NullInt64{Int64: 1, Valid: true},
+		Since:  "2026-02-01",
+	})
+	if err != nil {
+		t.Fatalf("GetUserBalanceSince() error: %v", err)
+	}
+	if want := int64(100000 - 100000 - 50000); delta != want {
+		t.Errorf("GetUserBalanceSince() = %d, want %d", delta, want)
+	}
+
+	balance, err := q.GetUserBalance(ctx, GetUserBalanceParams{
+		UserID:   sql.NullInt64{Int64: 1, Valid: true},
+		UserID_2: 1,
+		UserID_3: sql.NullInt64{Int64: 1, Valid: true},
+		UserID_4: 1,
+		UserID_5: 1,
+	})
+	if err != nil {
+		t.Fatalf("GetUserBalance() error: %v", err)
+	}
+	if got := int64(snapshots[0].Balance) + delta; got != balance {
+		t.Errorf("snapshot + GetUserBalanceSince() = %d, want GetUserBalance() %d", got, balance)
+	}
+}
+
+func TestListUsersPage(t *testing.T) {
+	database, q := openTestDB(t)
+	ctx := context.Background()
+
+	mustExec(t, database, `INSERT INTO levels (id, name, amount) VALUES (100, 'Test', '100000')`)
+	mustExec(t, database, `INSERT INTO users (id, email, realname, level_id, payments_id, keycloak_id, state) VALUES
+		(1, 'a@example.com', 'Alice', 100, '1001', 'kc-a', 'accepted'),
+		(2, 'b@example.com', 'Bob', 100, '1002', NULL, 'accepted'),
+		(3, 'c@example.com', 'Cyril', 100, '1003', 'kc-c', 'exmember')`)
+
+	// User 1 has a snapshot and entries after it, user 2 only entries (dates as
+	// written by the Go driver)
+	payment := `INSERT INTO payments (date, amount, kind, kind_id, local_account, remote_account, identification, user_id, content_hash)
+		VALUES (?, ?, 'fio', ?, 'local', 'remote', ?, ?, ?)`
+	mustExec(t, database, payment, time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC), int64(300000), "p1", "1001", 1, "h1")
+	mustExec(t, database, payment, "2026-02-01", int64(100000), "p2", "1001", 1, "h2")
+	mustExec(t, database, payment, time.Date(2026, 2, 3, 0, 0, 0, 0, time.UTC), int64(50000), "p3", "1002", 2, "h3")
+	mustExec(t, database, `INSERT INTO fees (user_id, level_id, period_start, amount) VALUES
+		(1, 100, '2026-01-01', '100000'), (1, 100, '2026-02-01', '100000'), (2, 100, '2026-02-01', '100000')`)
+	if err := q.CreateBalanceSnapshot(ctx, CreateBalanceSnapshotParams{
+		UserID:  1,
+		Month:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Balance: money.Amount(200000),
+	}); err != nil {
+		t.Fatalf("CreateBalanceSnapshot() error: %v", err)
+	}
+
+	list := func(arg ListUsersPageParams) []ListUsersPageRow {
+		t.Helper()
+		if arg.Limit == 0 {
+			arg.Limit = 50
+		}
+		rows, err := q.ListUsersPage(ctx, arg)
+		if err != nil {
+			t.Fatalf("ListUsersPage(%+v) error: %v", arg, err)
+		}
+		return rows
+	}
+	ids := func(rows []ListUsersPageRow) []int64 {
+		var ids []int64
+		for _, row := range rows {
+			ids = append(ids, row.ID)
+		}
+		return ids
+	}
+
+	// Balances match GetUserBalance with and without a snapshot
+	for _, row := range list(ListUsersPageParams{}) {
+		want, err := q.GetUserBalance(ctx, GetUserBalanceParams{
+			UserID:   sql.NullInt64{Int64: row.ID, Valid: true},
+			UserID_2: row.ID,
+			UserID_3: sql.NullInt64{Int64: row.ID, Valid: true},
+			UserID_4: row.ID,
+			UserID_5: row.ID,
+		})
+		if err != nil {
+			t.Fatalf("GetUserBalance() error: %v", err)
+		}
+		if row.Balance != want {
+			t.Errorf("user %d balance = %d, want %d", row.ID, row.Balance, want)
+		}
+		if row.Total != 3 {
+			t.Errorf("user %d total = %d, want 3", row.ID, row.Total)
+		}
+	}
+
+	tests := []struct {
+		name string
+		arg  ListUsersPageParams
+		want []int64
+	}{
+		{"default newest first", ListUsersPageParams{}, []int64{3, 2, 1}},
+		{"state", ListUsersPageParams{State: "accepted"}, []int64{2, 1}},
+		{"search name", ListUsersPageParams{Search: "bo"}, []int64{2}},
+		{"search VS", ListUsersPageParams{Search: "1003"}, []int64{3}},
+		{"linked", ListUsersPageParams{Linked: "linked"}, []int64{3, 1}},
+		{"not linked", ListUsersPageParams{Linked: "not_linked"}, []int64{2}},
+		{"keycloak IDs", ListUsersPageParams{KeycloakIDs: sql.NullString{String: `["kc-c"]`, Valid: true}}, []int64{3}},
+		{"negative balance", ListUsersPageParams{Balance: "negative"}, []int64{2}},
+		{"balance ascending", ListUsersPageParams{Sort: "balance_asc"}, []int64{2, 3, 1}},
+		{"second page", ListUsersPageParams{Sort: "id_asc", Limit: 2, Offset: 2}, []int64{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(list(tt.arg))
+			if len(got) != len(tt.want) {
+				t.Fatalf("ids = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ids = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateUserPaymentsVSKeepsBalance(t *testing.T) {
+	database, q := openTestDB(t)
+	ctx := context.Background()
+
+	mustExec(t, database, `INSERT INTO levels (id, name, amount) VALUES (100, 'Test', '100000')`)
+	mustExec(t, database, `INSERT INTO users (id, email, level_id, payments_id) VALUES (1, 'a@example.com', 100, '1001')`)
+
+	payment := `INSERT INTO payments (date, amount, kind, kind_id, local_account, remote_account, identification, user_id, content_hash)
+		VALUES ('2026-01-10', ?, 'fio', ?, 'local', 'remote', '1001', ?, ?)`
+	mustExec(t, database, payment, int64(300000), "p1", 1, "h1")
+	mustExec(t, database, payment, int64(200000), "p2", 1, "h2")
+	// Not matched to the member, stays as it is
+	mustExec(t, database, payment, int64(50000), "p3", nil, "h3")
+	mustExec(t, database, `INSERT INTO fees (user_id, level_id, period_start, amount) VALUES (1, 100, '2026-01-01', '100000')`)
+
+	balance := func() int64 {
+		t.Helper()
+		b, err := q.GetUserBalance(ctx, GetUserBalanceParams{
+			UserID:   sql.NullInt64{Int64: 1, Valid: true},
+			UserID_2: 1,
+			UserID_3: sql.NullInt64{Int64: 1, Valid: true},
+			UserID_4: 1,
+			UserID_5: 1,
+		})
+		if err != nil {
+			t.Fatalf("GetUserBalance() error: %v", err)
+		}
+		return b
+	}
+	want := balance()
+
+	// The VS change of AdminUpdateUserHandler
+	mustExec(t, database, `UPDATE users SET payments_id = '2002' WHERE id = 1`)
+	n, err := q.UpdateUserPaymentsVS(ctx, UpdateUserPaymentsVSParams{
+		NewPaymentsID: "2002",
+		UserID:        sql.NullInt64{Int64: 1, Valid: true},
+		OldPaymentsID: "1001",
+	})
+	if err != nil || n != 2 {
+		t.Fatalf("UpdateUserPaymentsVS() = %d, %v; want 2", n, err)
+	}
+
+	if got := balance(); got != want {
+		t.Errorf("balance after VS change = %d, want %d", got, want)
+	}
+}
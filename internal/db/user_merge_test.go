@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestMergeUserKeepsBalance(t *testing.T) {
+	database, q := openTestDB(t)
+	ctx := context.Background()
+
+	mustExec(t, database, `INSERT INTO levels (id, name, amount) VALUES (100, 'Test', '100000')`)
+	// A legacy import duplicate: both records have their own VS
+	mustExec(t, database, `INSERT INTO users (id, email, level_id, payments_id) VALUES (1, 'a@example.com', 100, '1001'), (2, 'a.old@example.com', 100, '1002')`)
+
+	payment := `INSERT INTO payments (date, amount, kind, kind_id, local_account, remote_account, identification, user_id, content_hash)
+		VALUES ('2026-01-10', ?, 'fio', ?, 'local', 'remote', ?, ?, ?)`
+	mustExec(t, database, payment, int64(300000), "p1", "1001", 1, "h1")
+	mustExec(t, database, payment, int64(150000), "p2", "1002", 2, "h2")
+	mustExec(t, database, `INSERT INTO fees (user_id, level_id, period_start, amount) VALUES (1, 100, '2026-01-01', '100000'), (2, 100, '2025-12-01', '100000')`)
+	mustExec(t, database, `INSERT INTO charges (user_id, description, amount, category, date, created_by) VALUES (2, 'Skříňka', '20000', 'locker', '2026-01-15', 'admin')`)
+	mustExec(t, database, `INSERT INTO balance_adjustments (user_id, amount, reason, created_by) VALUES (2, '10000', 'Dobropis', 'admin')`)
+
+	balance := func(userID int64) int64 {
+		t.Helper()
+		b, err := q.GetUserBalance(ctx, GetUserBalanceParams{
+			UserID:   sql.NullInt64{Int64: userID, Valid: true},
+			UserID_2: userID,
+			UserID_3: sql.NullInt64{Int64: userID, Valid: true},
+			UserID_4: userID,
+			UserID_5: userID,
+		})
+		if err != nil {
+			t.Fatalf("GetUserBalance(%d) error: %v", userID, err)
+		}
+		return b
+	}
+	want := balance(1) + balance(2)
+
+	// The balance-relevant steps of AdminMergeUsersHandler, in its order
+	from := sql.NullInt64{Int64: 2, Valid: true}
+	to := sql.NullInt64{Int64: 1, Valid: true}
+	if n, err := q.MergeUserPaymentVS(ctx, MergeUserPaymentVSParams{ToPaymentsID: "1001", FromUserID: from, FromPaymentsID: "1002"}); err != nil || n != 1 {
+		t.Fatalf("MergeUserPaymentVS() = %d, %v; want 1", n, err)
+	}
+	if _, err := q.MergeUserPayments(ctx, MergeUserPaymentsParams{ToUserID: to, FromUserID: from}); err != nil {
+		t.Fatalf("MergeUserPayments() error: %v", err)
+	}
+	if _, err := q.MergeUserFees(ctx, MergeUserFeesParams{ToUserID: 1, FromUserID: 2}); err != nil {
+		t.Fatalf("MergeUserFees() error: %v", err)
+	}
+	if _, err := q.MergeUserPaymentSplits(ctx, MergeUserPaymentSplitsParams{ToUserID: to, FromUserID: from}); err != nil {
+		t.Fatalf("MergeUserPaymentSplits() error: %v", err)
+	}
+	if _, err := q.MergeUserCharges(ctx, MergeUserChargesParams{ToUserID: 1, FromUserID: 2}); err != nil {
+		t.Fatalf("MergeUserCharges() error: %v", err)
+	}
+	if _, err := q.MergeUserBalanceAdjustments(ctx, MergeUserBalanceAdjustmentsParams{ToUserID: 1, FromUserID: 2}); err != nil {
+		t.Fatalf("MergeUserBalanceAdjustments() error: %v", err)
+	}
+	if err := q.DeleteUser(ctx, 2); err != nil {
+		t.Fatalf("DeleteUser() error: %v", err)
+	}
+
+	if got := balance(1); got != want {
+		t.Errorf("balance after merge = %d, want %d (sum of both before)", got, want)
+	}
+}
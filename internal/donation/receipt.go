@@ -0,0 +1,145 @@
+// Package donation builds the yearly donation receipts ("potvrzení o daru") that
+// donors use to deduct their gifts from the income tax base.
+package donation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/invoice"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/base48/member-portal/internal/pdf"
+)
+
+// Item is one donation on a receipt
+type Item struct {
+	Date    time.Time
+	Amount  money.Amount
+	Purpose string // project name, empty for a general donation
+}
+
+// Receipt confirms all donations of one member in a calendar year
+type Receipt struct {
+	Year  int
+	Donor db.User
+	Items []Item // oldest first
+	Total money.Amount
+}
+
+// Add records a donation on the receipt
+func (r *Receipt) Add(item Item) {
+	r.Items = append(r.Items, item)
+	r.Total += item.Amount
+}
+
+// Number identifies the receipt: year and donor ID (one receipt per donor and year)
+func (r Receipt) Number() string {
+	return fmt.Sprintf("%d/%04d", r.Year, r.Donor.ID)
+}
+
+// Filename is the name of the receipt PDF
+func (r Receipt) Filename() string {
+	return fmt.Sprintf("potvrzeni-o-daru-%d-%d.pdf", r.Year, r.Donor.ID)
+}
+
+// DonorName is the member's full name, the email when the name is missing
+func (r Receipt) DonorName() string {
+	if r.Donor.Realname.Valid && r.Donor.Realname.String != "" {
+		return r.Donor.Realname.String
+	}
+	return r.Donor.Email
+}
+
+// RenderReceipt renders the receipt as a one-page PDF (more pages with many
+// donations) signed by signatory ("Jan Novák, předseda spolku"); issued is the
+// date of issue
+func RenderReceipt(r Receipt, issuer invoice.Issuer, signatory string, issued time.Time) []byte {
+	doc := pdf.New()
+	doc.AddPage()
+
+	const left, right = 50.0, pdf.PageWidth - 50
+	const bottom = pdf.PageHeight - 90
+
+	doc.Text(left, 70, 20, true, "Potvrzení o přijetí daru")
+	doc.TextRight(right, 70, 12, true, "č. "+r.Number())
+	doc.Line(left, 84, right, 84, 1)
+
+	// Recipient and donor side by side
+	column := func(x float64, title string, lines []string) {
+		doc.Text(x, 110, 9, true, title)
+		y := 127.0
+		for _, line := range lines {
+			if line == "" {
+				continue
+			}
+			doc.Text(x, y, 10, false, line)
+			y += 14
+		}
+	}
+	recipientLines := append([]string{issuer.Name}, strings.Split(issuer.Address, "\n")...)
+	if issuer.CompanyID != "" {
+		recipientLines = append(recipientLines, "IČO: "+issuer.CompanyID)
+	}
+	column(left, "Příjemce daru", recipientLines)
+
+	donorLines := []string{r.DonorName(), r.Donor.Email}
+	if r.Donor.PaymentsID.Valid {
+		donorLines = append(donorLines, "VS: "+r.Donor.PaymentsID.String)
+	}
+	column(310, "Dárce", donorLines)
+
+	y := 230.0
+	text := []string{
+		fmt.Sprintf("Potvrzujeme, že jsme v roce %d od dárce přijali bezúplatné plnění (peněžní dar)", r.Year),
+		fmt.Sprintf("v celkové výši %s na podporu činnosti spolku.", invoice.FormatAmount(r.Total.Float64())),
+		"Dárci za dar nebylo poskytnuto žádné protiplnění.",
+	}
+	for _, line := range text {
+		doc.Text(left, y, 10, false, line)
+		y += 15
+	}
+
+	// Donations
+	y += 20
+	doc.Text(left, y, 9, true, "Datum")
+	doc.Text(left+90, y, 9, true, "Účel")
+	doc.TextRight(right, y, 9, true, "Částka")
+	doc.Line(left, y+6, right, y+6, 0.5)
+	y += 24
+	for _, item := range r.Items {
+		if y > bottom {
+			doc.AddPage()
+			y = 70
+		}
+		purpose := item.Purpose
+		if purpose == "" {
+			purpose = "Obecný dar"
+		}
+		doc.Text(left, y, 10, false, item.Date.Format("2. 1. 2006"))
+		doc.Text(left+90, y, 10, false, purpose)
+		doc.TextRight(right, y, 10, false, invoice.FormatAmount(item.Amount.Float64()))
+		y += 15
+	}
+	doc.Line(left, y-8, right, y-8, 0.5)
+	doc.Text(left, y+8, 12, true, "Celkem")
+	doc.TextRight(right, y+8, 12, true, invoice.FormatAmount(r.Total.Float64()))
+
+	// Date of issue and signature
+	y += 60
+	if y > bottom-40 {
+		doc.AddPage()
+		y = 100
+	}
+	doc.Text(left, y, 10, false, "Datum vystavení: "+issued.Format("2. 1. 2006"))
+	doc.Line(right-200, y, right, y, 0.5)
+	if signatory != "" {
+		doc.TextRight(right, y+14, 10, false, signatory)
+	}
+	doc.TextRight(right, y+28, 8, false, "podpis a razítko příjemce")
+
+	doc.Text(left, pdf.PageHeight-50, 8, false, "Dar lze odečíst od základu daně podle § 15 odst. 1 (fyzické osoby) nebo § 20 odst. 8 (právnické osoby) zákona č. 586/1992 Sb.")
+
+	return doc.Bytes()
+}
@@ -0,0 +1,149 @@
+package email
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// CampaignOptions controls how a campaign run sends emails
+type CampaignOptions struct {
+	// Delay is the pause between two emails (keeps the SMTP server happy)
+	Delay time.Duration
+	// MaxAttempts limits how many times a failed recipient is retried
+	MaxAttempts int64
+}
+
+// CampaignResult summarizes a single campaign run
+type CampaignResult struct {
+	Sent        int
+	Failed      int
+	Interrupted int64 // recipients left in 'sending' by an earlier crashed run
+	Completed   bool
+}
+
+// RunCampaign sends a campaign to all recipients that haven't received it yet.
+// Each recipient is marked 'sending' before and 'sent'/'failed' after delivery,
+// so the run can be stopped at any point (ctx cancel, crash) and resumed later
+// without re-sending completed recipients.
+func (c *Client) RunCampaign(ctx context.Context, campaignID int64, opts CampaignOptions) (CampaignResult, error) {
+	var result CampaignResult
+
+	// SendTemplated silently skips when SMTP is missing - that would mark everyone as sent
+	if c.config.SMTPHost == "" {
+		return result, fmt.Errorf("SMTP not configured")
+	}
+
+	campaign, err := c.queries.GetEmailCampaign(ctx, campaignID)
+	if err != nil {
+		return result, fmt.Errorf("failed to load campaign %d: %w", campaignID, err)
+	}
+	if campaign.Status == "completed" {
+		result.Completed = true
+		return result, nil
+	}
+
+	if _, err := c.queries.UpdateEmailCampaignStatus(ctx, db.UpdateEmailCampaignStatusParams{
+		Status: "running",
+		ID:     campaign.ID,
+	}); err != nil {
+		return result, err
+	}
+
+	recipients, err := c.queries.ListEmailCampaignRecipientsToSend(ctx, db.ListEmailCampaignRecipientsToSendParams{
+		CampaignID:  campaign.ID,
+		MaxAttempts: opts.MaxAttempts,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	log.Printf("[Campaign] %s: %d recipients to send", campaign.Name, len(recipients))
+
+	for i, recipient := range recipients {
+		// Stop between recipients, never in the middle of one
+		if ctx.Err() != nil {
+			log.Printf("[Campaign] %s: stopped, %d recipients left", campaign.Name, len(recipients)-i)
+			break
+		}
+
+		if err := c.queries.MarkEmailCampaignRecipientSending(ctx, recipient.ID); err != nil {
+			return result, err
+		}
+
+		data := map[string]interface{}{
+			"Subject":   campaign.Subject,
+			"Body":      campaign.Body.String,
+			"PortalURL": c.config.BaseURL,
+		}
+		if user, err := c.queries.GetUserByID(ctx, recipient.UserID); err == nil {
+			data["Name"] = user.Realname.String
+		}
+
+		// Delivery must not be interrupted by cancellation once started
+		sendErr := c.SendTemplated(context.WithoutCancel(ctx), SendParams{
+			UserID:       sql.NullInt64{Int64: recipient.UserID, Valid: true},
+			Recipient:    recipient.Email,
+			Subject:      campaign.Subject,
+			TemplateName: campaign.TemplateName,
+			Data:         data,
+		})
+
+		if sendErr != nil {
+			result.Failed++
+			err = c.queries.MarkEmailCampaignRecipientFailed(context.WithoutCancel(ctx), db.MarkEmailCampaignRecipientFailedParams{
+				LastError: sql.NullString{String: sendErr.Error(), Valid: true},
+				ID:        recipient.ID,
+			})
+		} else {
+			result.Sent++
+			err = c.queries.MarkEmailCampaignRecipientSent(context.WithoutCancel(ctx), recipient.ID)
+		}
+		if err != nil {
+			return result, err
+		}
+
+		if opts.Delay > 0 && i < len(recipients)-1 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(opts.Delay):
+			}
+		}
+	}
+
+	// Completed once nothing is left to send or retry
+	remaining, err := c.queries.ListEmailCampaignRecipientsToSend(context.WithoutCancel(ctx), db.ListEmailCampaignRecipientsToSendParams{
+		CampaignID:  campaign.ID,
+		MaxAttempts: opts.MaxAttempts,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	stats, err := c.queries.GetEmailCampaignStats(context.WithoutCancel(ctx), campaign.ID)
+	if err != nil {
+		return result, err
+	}
+	for _, s := range stats {
+		if s.Status == "sending" {
+			result.Interrupted = s.Count
+		}
+	}
+
+	if len(remaining) == 0 && result.Interrupted == 0 {
+		result.Completed = true
+		if _, err := c.queries.UpdateEmailCampaignStatus(context.WithoutCancel(ctx), db.UpdateEmailCampaignStatusParams{
+			Status:      "completed",
+			CompletedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			ID:          campaign.ID,
+		}); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
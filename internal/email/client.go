@@ -4,18 +4,40 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
 	"html/template"
 	"log"
 	"math"
+	"mime/multipart"
 	"net/smtp"
-	"path/filepath"
+	"net/textproto"
+	"time"
 
 	"github.com/base48/member-portal/internal/config"
 	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/donation"
+	"github.com/base48/member-portal/internal/milestone"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/base48/member-portal/internal/paymentanalytics"
 	"github.com/base48/member-portal/internal/qrpay"
+	"github.com/base48/member-portal/internal/statement"
+	"github.com/base48/member-portal/internal/templates"
+	"github.com/base48/member-portal/internal/ticket"
 )
 
+// NotificationPaymentReceived is the notification preference of payment
+// confirmations, the member can switch it off in the profile
+const NotificationPaymentReceived = "payment_received"
+
+// NotificationPaymentStatement is the notification preference of the yearly
+// payment statement emailed in January
+const NotificationPaymentStatement = "payment_statement"
+
+// NotificationMissedPayment is the notification preference of reminders of a
+// missed standing-order payment
+const NotificationMissedPayment = "missed_payment"
+
 // Client handles email sending with templates and logging
 type Client struct {
 	config       *config.Config
@@ -28,8 +50,17 @@ type SendParams struct {
 	UserID       sql.NullInt64
 	Recipient    string
 	Subject      string
+	ReplyTo      string // optional, e.g. the support inbox
 	TemplateName string
 	Data         interface{}
+	Attachments  []Attachment // optional, e.g. a PDF statement
+}
+
+// Attachment is a file attached to an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
 }
 
 // New creates a new email client
@@ -50,8 +81,8 @@ func (c *Client) SendTemplated(ctx context.Context, params SendParams) error {
 		return nil
 	}
 
-	// Load and parse template
-	templatePath := filepath.Join(c.config.WebRoot, "templates", "email", params.TemplateName)
+	// Load and parse template (the deployment may override it, TEMPLATE_OVERRIDE_DIR)
+	templatePath := templates.New(c.config.WebRoot, c.config.TemplateOverrideDir).Path("email/" + params.TemplateName)
 	tmpl, err := template.ParseFiles(templatePath)
 	if err != nil {
 		return c.logEmail(ctx, params, fmt.Errorf("template parse error: %w", err))
@@ -64,7 +95,7 @@ func (c *Client) SendTemplated(ctx context.Context, params SendParams) error {
 	}
 
 	// Prepare email message
-	message := c.formatMessage(params.Recipient, params.Subject, body.String())
+	message := c.formatMessage(params.Recipient, params.ReplyTo, params.Subject, body.String(), params.Attachments)
 
 	// Send email
 	auth := smtp.PlainAuth("", c.config.SMTPUsername, c.config.SMTPPassword, c.config.SMTPHost)
@@ -82,14 +113,48 @@ func (c *Client) SendTemplated(ctx context.Context, params SendParams) error {
 	return c.logEmail(ctx, params, err)
 }
 
-// formatMessage creates RFC 2822 compliant email message
-func (c *Client) formatMessage(to, subject, body string) string {
+// formatMessage creates RFC 2822 compliant email message; with attachments a
+// multipart/mixed one with the HTML body as the first part
+func (c *Client) formatMessage(to, replyTo, subject, body string, attachments []Attachment) string {
+	headers := fmt.Sprintf("From: %s\r\nTo: %s\r\n", c.config.SMTPFrom, to)
+	if replyTo != "" {
+		headers += fmt.Sprintf("Reply-To: %s\r\n", replyTo)
+	}
+	if len(attachments) == 0 {
+		return fmt.Sprintf(
+			"%sSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s",
+			headers,
+			subject,
+			body,
+		)
+	}
+
+	var buf bytes.Buffer
+	parts := multipart.NewWriter(&buf)
+	part, _ := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	part.Write([]byte(body))
+	for _, a := range attachments {
+		part, _ := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Disposition":       {fmt.Sprintf(`attachment; filename="%s"`, a.Filename)},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		// Base64 in lines of 76 characters (RFC 2045)
+		encoded := base64.StdEncoding.EncodeToString(a.Content)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	parts.Close()
+
 	return fmt.Sprintf(
-		"From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s",
-		c.config.SMTPFrom,
-		to,
+		"%sSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%s\r\n\r\n%s",
+		headers,
 		subject,
-		body,
+		parts.Boundary(),
+		buf.String(),
 	)
 }
 
@@ -153,16 +218,8 @@ func (c *Client) SendNegativeBalance(ctx context.Context, user *db.User, balance
 	}
 
 	// Generate QR payment code if possible
-	if c.qrpayService != nil && c.qrpayService.IsConfigured() && user.PaymentsID.Valid && user.PaymentsID.String != "" {
-		qrCode, err := c.qrpayService.GeneratePaymentQR(qrpay.GenerateParams{
-			Amount:         math.Abs(balance),
-			VariableSymbol: user.PaymentsID.String,
-			Message:        "CLENSKY PRISPEVEK BASE48",
-			Size:           200,
-		})
-		if err == nil {
-			data["PaymentQRCode"] = template.URL(qrCode)
-		}
+	if qrURL := c.paymentQRURL(ctx, user, math.Abs(balance)); qrURL != "" {
+		data["PaymentQRURL"] = qrURL
 	}
 
 	return c.SendTemplated(ctx, SendParams{
@@ -174,7 +231,7 @@ func (c *Client) SendNegativeBalance(ctx context.Context, user *db.User, balance
 	})
 }
 
-// SendDebtWarning sends warning about significant debt (>2x monthly fee)
+// SendDebtWarning sends warning about significant debt (warning step of the debt ladder)
 func (c *Client) SendDebtWarning(ctx context.Context, user *db.User, balance float64, monthlyFee float64) error {
 	data := map[string]interface{}{
 		"Name":       user.Realname.String,
@@ -185,16 +242,8 @@ func (c *Client) SendDebtWarning(ctx context.Context, user *db.User, balance flo
 	}
 
 	// Generate QR payment code if possible
-	if c.qrpayService != nil && c.qrpayService.IsConfigured() && user.PaymentsID.Valid && user.PaymentsID.String != "" {
-		qrCode, err := c.qrpayService.GeneratePaymentQR(qrpay.GenerateParams{
-			Amount:         math.Abs(balance),
-			VariableSymbol: user.PaymentsID.String,
-			Message:        "CLENSKY PRISPEVEK BASE48",
-			Size:           200,
-		})
-		if err == nil {
-			data["PaymentQRCode"] = template.URL(qrCode)
-		}
+	if qrURL := c.paymentQRURL(ctx, user, math.Abs(balance)); qrURL != "" {
+		data["PaymentQRURL"] = qrURL
 	}
 
 	return c.SendTemplated(ctx, SendParams{
@@ -222,3 +271,305 @@ func (c *Client) SendMembershipSuspended(ctx context.Context, user *db.User, rea
 		Data:         data,
 	})
 }
+
+// SendTicketReply sends an admin reply to a support ticket. The subject carries the
+// ticket tag and Reply-To points to the support inbox, so the member's answer is
+// threaded back to the same ticket.
+func (c *Client) SendTicketReply(ctx context.Context, t *db.Ticket, body string) error {
+	data := map[string]interface{}{
+		"TicketID":  t.ID,
+		"Subject":   t.Subject,
+		"Body":      body,
+		"PortalURL": c.config.BaseURL,
+	}
+
+	return c.SendTemplated(ctx, SendParams{
+		UserID:       t.UserID,
+		Recipient:    t.Email,
+		Subject:      ticket.ReplySubject(t.ID, t.Subject),
+		ReplyTo:      c.config.SupportEmail,
+		TemplateName: "ticket_reply.html",
+		Data:         data,
+	})
+}
+
+// SendFeeChange notifies a member about a planned change of their membership fee
+func (c *Client) SendFeeChange(ctx context.Context, user *db.User, levelName string, oldAmount, newAmount money.Amount, effectiveFrom time.Time, note string) error {
+	data := map[string]interface{}{
+		"Name":          user.Realname.String,
+		"LevelName":     levelName,
+		"OldAmount":     oldAmount,
+		"NewAmount":     newAmount,
+		"EffectiveFrom": effectiveFrom.Format("1. 1. 2006"),
+		"Note":          note,
+		"PaymentsID":    user.PaymentsID.String,
+		"PortalURL":     c.config.BaseURL,
+	}
+
+	return c.SendTemplated(ctx, SendParams{
+		UserID:       sql.NullInt64{Int64: user.ID, Valid: true},
+		Recipient:    user.Email,
+		Subject:      "Změna výše členského příspěvku",
+		TemplateName: "fee_change.html",
+		Data:         data,
+	})
+}
+
+// SendPaymentReceived confirms a payment assigned to the member, with the balance after it
+func (c *Client) SendPaymentReceived(ctx context.Context, user *db.User, amount money.Amount, date time.Time, balance money.Amount) error {
+	data := map[string]interface{}{
+		"Name":       user.Realname.String,
+		"Amount":     amount,
+		"Date":       date.Format("2. 1. 2006"),
+		"Balance":    balance,
+		"Negative":   balance < 0,
+		"PaymentsID": user.PaymentsID.String,
+		"PortalURL":  c.config.BaseURL,
+	}
+
+	return c.SendTemplated(ctx, SendParams{
+		UserID:       sql.NullInt64{Int64: user.ID, Valid: true},
+		Recipient:    user.Email,
+		Subject:      fmt.Sprintf("Přijali jsme tvoji platbu %s Kč", amount),
+		TemplateName: "payment_received.html",
+		Data:         data,
+	})
+}
+
+// SendPaymentStatement sends the member's yearly payment statement, the PDF attached
+func (c *Client) SendPaymentStatement(ctx context.Context, user *db.User, s statement.Statement, pdf []byte) error {
+	data := map[string]interface{}{
+		"Name":      user.Realname.String,
+		"Statement": s,
+		"PortalURL": c.config.BaseURL,
+	}
+
+	return c.SendTemplated(ctx, SendParams{
+		UserID:       sql.NullInt64{Int64: user.ID, Valid: true},
+		Recipient:    user.Email,
+		Subject:      fmt.Sprintf("Přehled tvých plateb za rok %d", s.Year),
+		TemplateName: "payment_statement.html",
+		Data:         data,
+		Attachments: []Attachment{
+			{Filename: s.Filename(), ContentType: "application/pdf", Content: pdf},
+		},
+	})
+}
+
+// SendDonationReceipt sends the donor's yearly donation receipt, the PDF attached
+func (c *Client) SendDonationReceipt(ctx context.Context, user *db.User, r donation.Receipt, pdf []byte) error {
+	data := map[string]interface{}{
+		"Name":      user.Realname.String,
+		"Year":      r.Year,
+		"Total":     r.Total,
+		"Count":     len(r.Items),
+		"PortalURL": c.config.BaseURL,
+	}
+
+	return c.SendTemplated(ctx, SendParams{
+		UserID:       sql.NullInt64{Int64: user.ID, Valid: true},
+		Recipient:    user.Email,
+		Subject:      fmt.Sprintf("Potvrzení o daru za rok %d", r.Year),
+		ReplyTo:      c.config.SupportEmail,
+		TemplateName: "donation_receipt.html",
+		Data:         data,
+		Attachments: []Attachment{
+			{Filename: r.Filename(), ContentType: "application/pdf", Content: pdf},
+		},
+	})
+}
+
+// SendMissedPayment gently reminds a member paying by a standing order that the
+// expected monthly payment has not arrived, with a QR code for the usual amount
+func (c *Client) SendMissedPayment(ctx context.Context, user *db.User, order paymentanalytics.StandingOrder) error {
+	data := map[string]interface{}{
+		"Name":        user.Realname.String,
+		"Amount":      order.Amount,
+		"DueDate":     order.NextDue().Format("2. 1. 2006"),
+		"LastPayment": order.LastPayment.Format("2. 1. 2006"),
+		"PaymentsID":  user.PaymentsID.String,
+		"PortalURL":   c.config.BaseURL,
+	}
+
+	if qrURL := c.paymentQRURL(ctx, user, order.Amount.Float64()); qrURL != "" {
+		data["PaymentQRURL"] = qrURL
+	}
+
+	return c.SendTemplated(ctx, SendParams{
+		UserID:       sql.NullInt64{Int64: user.ID, Valid: true},
+		Recipient:    user.Email,
+		Subject:      "Nedorazila tvoje pravidelná platba",
+		TemplateName: "missed_payment.html",
+		Data:         data,
+	})
+}
+
+// SendPaymentPlanReminder reminds a member with a payment plan of what to pay this
+// month instead of the whole debt: the installment with the fee and any missed
+// installments (amountDue), remaining is the plan's debt left after this month
+func (c *Client) SendPaymentPlanReminder(ctx context.Context, user *db.User, plan db.PaymentPlan, amountDue, remaining money.Amount, onPlan bool) error {
+	data := map[string]interface{}{
+		"Name":        user.Realname.String,
+		"TotalDebt":   plan.TotalDebt,
+		"Installment": plan.Installment,
+		"StartDate":   plan.StartDate.Format("2. 1. 2006"),
+		"AmountDue":   amountDue,
+		"Remaining":   remaining,
+		"OnPlan":      onPlan,
+		"PaymentsID":  user.PaymentsID.String,
+		"PortalURL":   c.config.BaseURL,
+	}
+
+	if qrURL := c.paymentQRURL(ctx, user, amountDue.Float64()); qrURL != "" {
+		data["PaymentQRURL"] = qrURL
+	}
+
+	return c.SendTemplated(ctx, SendParams{
+		UserID:       sql.NullInt64{Int64: user.ID, Valid: true},
+		Recipient:    user.Email,
+		Subject:      "Splátka dluhu za členství",
+		TemplateName: "payment_plan_reminder.html",
+		Data:         data,
+	})
+}
+
+// emailQRValidity is how long QR image links in emails work
+const emailQRValidity = 90 * 24 * time.Hour
+
+// paymentQRURL returns a signed link to the member's payment QR image in the format
+// chosen in the profile; emails link the image because many mail clients strip
+// embedded data URLs. Empty when the member has no VS or the bank is not configured.
+func (c *Client) paymentQRURL(ctx context.Context, user *db.User, amount float64) string {
+	if c.qrpayService == nil || !c.qrpayService.IsConfigured() || !user.PaymentsID.Valid || user.PaymentsID.String == "" {
+		return ""
+	}
+
+	format, err := c.queries.GetUserQRFormat(ctx, user.ID)
+	if err != nil {
+		format = qrpay.FormatSPAYD
+	}
+	if _, err := c.qrpayService.Payload(format, qrpay.GenerateParams{
+		VariableSymbol: user.PaymentsID.String,
+		RecipientName:  c.config.BankName,
+	}); err != nil {
+		format = qrpay.FormatSPAYD
+	}
+
+	return qrpay.SignedImageURL(c.config.BaseURL, c.config.SessionSecret, qrpay.ImageRequest{
+		VariableSymbol: user.PaymentsID.String,
+		Amount:         amount,
+		Size:           200,
+		Format:         format,
+	}, time.Now().Add(emailQRValidity))
+}
+
+// UnmatchedPayment is a row of the unmatched payments digest for admins
+type UnmatchedPayment struct {
+	Date           time.Time
+	Amount         money.Amount
+	VariableSymbol string
+	Sender         string // account name, or the account number
+	Message        string
+}
+
+// SendUnmatchedPayments sends admins the digest of new payments the bank sync could
+// not assign to a member or project
+func (c *Client) SendUnmatchedPayments(ctx context.Context, recipient string, payments []UnmatchedPayment) error {
+	var total money.Amount
+	for _, p := range payments {
+		total += p.Amount
+	}
+
+	data := map[string]interface{}{
+		"Payments":  payments,
+		"Count":     len(payments),
+		"Total":     total,
+		"PortalURL": c.config.BaseURL,
+	}
+
+	return c.SendTemplated(ctx, SendParams{
+		Recipient:    recipient,
+		Subject:      fmt.Sprintf("Nespárované platby: %d nových", len(payments)),
+		TemplateName: "unmatched_payments.html",
+		Data:         data,
+	})
+}
+
+// SendProjectTargetReached tells admins that a project reached its funding goal
+func (c *Client) SendProjectTargetReached(ctx context.Context, recipient string, project db.Project, total money.Amount) error {
+	data := map[string]interface{}{
+		"ProjectName": project.Name,
+		"Total":       total,
+		"Goal":        project.Goal,
+		"PortalURL":   c.config.BaseURL,
+	}
+	if project.Deadline.Valid {
+		data["Deadline"] = project.Deadline.Time.Format("2. 1. 2006")
+	}
+
+	return c.SendTemplated(ctx, SendParams{
+		Recipient:    recipient,
+		Subject:      fmt.Sprintf("Projekt %s dosáhl cíle", project.Name),
+		TemplateName: "project_target_reached.html",
+		Data:         data,
+	})
+}
+
+// SendTerminationRequested tells admins that a member cancelled their membership
+func (c *Client) SendTerminationRequested(ctx context.Context, recipient string, user *db.User, termination db.MembershipTermination) error {
+	name := user.Email
+	if user.Realname.Valid && user.Realname.String != "" {
+		name = user.Realname.String
+	}
+	data := map[string]interface{}{
+		"Name":      name,
+		"Email":     user.Email,
+		"UserID":    user.ID,
+		"EndDate":   termination.EndDate.Format("2. 1. 2006"),
+		"Reason":    termination.Reason,
+		"PortalURL": c.config.BaseURL,
+	}
+
+	return c.SendTemplated(ctx, SendParams{
+		Recipient:    recipient,
+		Subject:      fmt.Sprintf("%s ukončuje členství", name),
+		TemplateName: "termination_requested.html",
+		Data:         data,
+	})
+}
+
+// SendMilestone congratulates a member on a membership anniversary or payment milestone
+func (c *Client) SendMilestone(ctx context.Context, user *db.User, m milestone.Milestone) error {
+	data := map[string]interface{}{
+		"Name":      user.Realname.String,
+		"Kind":      m.Kind,
+		"Count":     m.Count,
+		"Years":     czechYears(m.Count),
+		"PortalURL": c.config.BaseURL,
+	}
+
+	subject := fmt.Sprintf("%s v Base48 - díky!", czechYears(m.Count))
+	if m.Kind == milestone.KindPayments {
+		subject = fmt.Sprintf("Tvoje %d. platba pro Base48 - díky!", m.Count)
+	}
+
+	return c.SendTemplated(ctx, SendParams{
+		UserID:       sql.NullInt64{Int64: user.ID, Valid: true},
+		Recipient:    user.Email,
+		Subject:      subject,
+		TemplateName: "milestone.html",
+		Data:         data,
+	})
+}
+
+// czechYears formats a number of years with the right Czech plural (1 rok, 3 roky, 5 let)
+func czechYears(n int) string {
+	switch {
+	case n == 1:
+		return "1 rok"
+	case n >= 2 && n <= 4:
+		return fmt.Sprintf("%d roky", n)
+	default:
+		return fmt.Sprintf("%d let", n)
+	}
+}
@@ -0,0 +1,286 @@
+// Package fakturoid issues invoices in Fakturoid (API v3) for fees of members
+// whose company pays them, and reads back whether they have been paid.
+package fakturoid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/base48/member-portal/internal/money"
+)
+
+// Invoice statuses (invoice.status)
+const (
+	StatusOpen          = "open"
+	StatusSent          = "sent"
+	StatusOverdue       = "overdue"
+	StatusPaid          = "paid"
+	StatusCancelled     = "cancelled"
+	StatusUncollectible = "uncollectible"
+)
+
+// Closed reports whether the status of an invoice can no longer change by a payment
+func Closed(status string) bool {
+	return status == StatusPaid || status == StatusCancelled || status == StatusUncollectible
+}
+
+const defaultBaseURL = "https://app.fakturoid.cz/api/v3"
+
+// Client calls the API of one Fakturoid account
+type Client struct {
+	baseURL      string
+	slug         string
+	clientID     string
+	clientSecret string
+	userAgent    string
+	httpClient   *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewClient creates a client for the account slug with the client credentials of
+// an API user; contact is the email Fakturoid requires in the User-Agent
+func NewClient(slug, clientID, clientSecret, contact string) *Client {
+	return &Client{
+		baseURL:      defaultBaseURL,
+		slug:         slug,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		userAgent:    fmt.Sprintf("Base48 member portal (%s)", contact),
+		httpClient:   &http.Client{Timeout: 20 * time.Second},
+	}
+}
+
+// Subject is the customer of an invoice (the member's company)
+type Subject struct {
+	ID             int64  `json:"id,omitempty"`
+	Name           string `json:"name"`
+	Street         string `json:"street,omitempty"`
+	City           string `json:"city,omitempty"`
+	Zip            string `json:"zip,omitempty"`
+	RegistrationNo string `json:"registration_no,omitempty"` // IČO
+	VatNo          string `json:"vat_no,omitempty"`          // DIČ
+	Email          string `json:"email,omitempty"`
+}
+
+var zipCity = regexp.MustCompile(`^(\d{3} ?\d{2})\s+(.+)$`)
+
+// SplitAddress splits a free-form address ("Ulice 1\n123 45 Město") into the street,
+// ZIP code and city; lines it cannot parse stay in the street
+func SplitAddress(address string) (street, zip, city string) {
+	var lines []string
+	for _, line := range strings.Split(address, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > 1 {
+		if m := zipCity.FindStringSubmatch(lines[len(lines)-1]); m != nil {
+			return strings.Join(lines[:len(lines)-1], ", "), m[1], m[2]
+		}
+	}
+	return strings.Join(lines, ", "), "", ""
+}
+
+// Line is an item of an invoice
+type Line struct {
+	Name      string
+	Quantity  int
+	UnitPrice money.Amount
+}
+
+// InvoiceParams describe a new invoice
+type InvoiceParams struct {
+	SubjectID      int64
+	CustomID       string // the portal's key of the invoice, e.g. "fee-12"
+	VariableSymbol string // empty = the invoice number
+	Due            int    // days
+	Note           string // printed above the lines
+	Lines          []Line
+}
+
+// Invoice is a Fakturoid invoice (only the fields the portal uses)
+type Invoice struct {
+	ID             int64  `json:"id"`
+	CustomID       string `json:"custom_id"`
+	Number         string `json:"number"`
+	Status         string `json:"status"`
+	VariableSymbol string `json:"variable_symbol"`
+	PublicHTMLURL  string `json:"public_html_url"` // the invoice for the customer, no login needed
+	PaidOn         string `json:"paid_on"`         // YYYY-MM-DD, empty while unpaid
+}
+
+// CreateSubject creates a contact and returns its ID
+func (c *Client) CreateSubject(ctx context.Context, s Subject) (int64, error) {
+	var created Subject
+	if err := c.do(ctx, http.MethodPost, "/subjects.json", s, &created); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// CreateInvoice creates an invoice
+func (c *Client) CreateInvoice(ctx context.Context, p InvoiceParams) (*Invoice, error) {
+	lines := make([]map[string]interface{}, 0, len(p.Lines))
+	for _, line := range p.Lines {
+		lines = append(lines, map[string]interface{}{
+			"name":       line.Name,
+			"quantity":   line.Quantity,
+			"unit_price": line.UnitPrice.String(),
+		})
+	}
+	body := map[string]interface{}{
+		"subject_id": p.SubjectID,
+		"custom_id":  p.CustomID,
+		"due":        p.Due,
+		"currency":   "CZK",
+		"lines":      lines,
+	}
+	if p.VariableSymbol != "" {
+		body["variable_symbol"] = p.VariableSymbol
+	}
+	if p.Note != "" {
+		body["note"] = p.Note
+	}
+
+	var invoice Invoice
+	if err := c.do(ctx, http.MethodPost, "/invoices.json", body, &invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// FindInvoice looks up an invoice by its custom ID (nil if there is none); used to
+// pick up an invoice created by a run that failed to record it
+func (c *Client) FindInvoice(ctx context.Context, customID string) (*Invoice, error) {
+	var invoices []Invoice
+	if err := c.do(ctx, http.MethodGet, "/invoices.json?custom_id="+url.QueryEscape(customID), nil, &invoices); err != nil {
+		return nil, err
+	}
+	for _, invoice := range invoices {
+		if invoice.CustomID == customID {
+			return &invoice, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetInvoice fetches an invoice
+func (c *Client) GetInvoice(ctx context.Context, id int64) (*Invoice, error) {
+	var invoice Invoice
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/invoices/%d.json", id), nil, &invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// accessToken returns a valid token of the client credentials flow, renewing it
+// shortly before it expires
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/oauth/token", strings.NewReader(`{"grant_type":"client_credentials"}`))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(c.clientID, c.clientSecret)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fakturoid token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fakturoid token: HTTP %d", resp.StatusCode)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("invalid fakturoid token response")
+	}
+
+	c.token = token.AccessToken
+	c.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	endpoint := c.baseURL + "/accounts/" + url.PathEscape(c.slug) + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fakturoid request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read fakturoid response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		// Validation errors come as {"errors": {"field": ["message", ...]}}
+		var apiErr struct {
+			Errors map[string][]string `json:"errors"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && len(apiErr.Errors) > 0 {
+			var messages []string
+			for field, errs := range apiErr.Errors {
+				messages = append(messages, field+": "+strings.Join(errs, ", "))
+			}
+			return fmt.Errorf("fakturoid: HTTP %d: %s", resp.StatusCode, strings.Join(messages, "; "))
+		}
+		return fmt.Errorf("fakturoid: HTTP %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid fakturoid response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package fakturoid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSplitAddress(t *testing.T) {
+	tests := []struct {
+		address, street, zip, city string
+	}{
+		{"Ulice 1\n123 45 Město", "Ulice 1", "123 45", "Město"},
+		{"Firma s.r.o.\nUlice 1\n12345 Praha 4", "Firma s.r.o., Ulice 1", "12345", "Praha 4"},
+		{"Ulice 1, Praha", "Ulice 1, Praha", "", ""},
+		{"Ulice 1\nPraha", "Ulice 1, Praha", "", ""},
+	}
+	for _, tt := range tests {
+		street, zip, city := SplitAddress(tt.address)
+		if street != tt.street || zip != tt.zip || city != tt.city {
+			t.Errorf("SplitAddress(%q) = %q, %q, %q, want %q, %q, %q", tt.address, street, zip, city, tt.street, tt.zip, tt.city)
+		}
+	}
+}
+
+func TestCreateInvoice(t *testing.T) {
+	tokens := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token":
+			if id, secret, ok := r.BasicAuth(); !ok || id != "id" || secret != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			tokens++
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "token_type": "Bearer", "expires_in": 7200})
+		case "/accounts/base48/invoices.json":
+			if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("User-Agent") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var body struct {
+				CustomID       string `json:"custom_id"`
+				VariableSymbol string `json:"variable_symbol"`
+				Lines          []struct {
+					UnitPrice string `json:"unit_price"`
+				} `json:"lines"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if len(body.Lines) != 1 || body.Lines[0].UnitPrice != "1500.00" || body.VariableSymbol != "1001" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(map[string]interface{}{"errors": map[string][]string{"lines": {"invalid"}}})
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 7, "custom_id": body.CustomID, "number": "2026-0001", "status": "open"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("base48", "id", "secret", "info@base48.cz")
+	client.baseURL = server.URL
+
+	for i := 0; i < 2; i++ {
+		inv, err := client.CreateInvoice(context.Background(), InvoiceParams{
+			SubjectID:      1,
+			CustomID:       "fee-12",
+			VariableSymbol: "1001",
+			Due:            14,
+			Lines:          []Line{{Name: "Členský příspěvek", Quantity: 1, UnitPrice: 150000}},
+		})
+		if err != nil {
+			t.Fatalf("CreateInvoice() error: %v", err)
+		}
+		if inv.ID != 7 || inv.Number != "2026-0001" || inv.CustomID != "fee-12" || Closed(inv.Status) {
+			t.Errorf("CreateInvoice() = %+v", inv)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("token requested %d times, want once", tokens)
+	}
+
+	if _, err := client.CreateInvoice(context.Background(), InvoiceParams{SubjectID: 1, Lines: []Line{{Name: "x", Quantity: 1, UnitPrice: 100}}}); err == nil {
+		t.Error("CreateInvoice() with a rejected body succeeded")
+	}
+}
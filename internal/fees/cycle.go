@@ -0,0 +1,59 @@
+package fees
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// Billing cycles of a member (user_payment_settings.billing_cycle). Quarters and
+// years are calendar ones.
+const (
+	CycleMonthly   = "monthly"
+	CycleQuarterly = "quarterly"
+	CycleAnnual    = "annual"
+)
+
+// PeriodMonths returns how many months a fee created for month covers: the rest
+// of the member's billing period, i.e. to the end of the calendar quarter or year
+// (a whole period when month is its first month)
+func PeriodMonths(cycle string, month time.Time) int {
+	index := int(month.Month()) - 1
+	switch cycle {
+	case CycleQuarterly:
+		return 3 - index%3
+	case CycleAnnual:
+		return 12 - index
+	}
+	return 1
+}
+
+// CoveredUntil returns the first month the fee does not cover
+func CoveredUntil(fee db.Fee) time.Time {
+	months := int(fee.Months)
+	if months < 1 {
+		months = 1
+	}
+	return fee.PeriodStart.AddDate(0, months, 0)
+}
+
+// NextFeeDate returns the day the next fee of a member is created: fees are created
+// on the first day of a month that no fee covers yet, so the month after the covered
+// months, but next month at the earliest. coveredUntil is zero for a member
+// without fees.
+func NextFeeDate(coveredUntil, now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	if coveredUntil.After(next) {
+		return coveredUntil
+	}
+	return next
+}
+
+// PeriodLabel describes the months a fee covers ("2026-04" or "2026-04 – 2026-06")
+func PeriodLabel(start time.Time, months int) string {
+	if months <= 1 {
+		return start.Format("2006-01")
+	}
+	return fmt.Sprintf("%s – %s", start.Format("2006-01"), start.AddDate(0, months-1, 0).Format("2006-01"))
+}
@@ -0,0 +1,71 @@
+package fees
+
+import (
+	"testing"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+func TestPeriodMonths(t *testing.T) {
+	tests := []struct {
+		cycle string
+		month time.Month
+		want  int
+	}{
+		{CycleMonthly, time.January, 1},
+		{CycleMonthly, time.December, 1},
+		{CycleQuarterly, time.January, 3},
+		{CycleQuarterly, time.February, 2},
+		{CycleQuarterly, time.March, 1},
+		{CycleQuarterly, time.April, 3},
+		{CycleQuarterly, time.December, 1},
+		{CycleAnnual, time.January, 12},
+		{CycleAnnual, time.April, 9},
+		{CycleAnnual, time.December, 1},
+		{"", time.June, 1},
+	}
+
+	for _, tt := range tests {
+		got := PeriodMonths(tt.cycle, time.Date(2026, tt.month, 1, 0, 0, 0, 0, time.UTC))
+		if got != tt.want {
+			t.Errorf("PeriodMonths(%q, %s) = %d, want %d", tt.cycle, tt.month, got, tt.want)
+		}
+	}
+}
+
+func TestNextFeeDate(t *testing.T) {
+	now := time.Date(2026, time.March, 14, 10, 0, 0, 0, time.UTC)
+	april := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	quarter := db.Fee{PeriodStart: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), Months: 3}
+	year := db.Fee{PeriodStart: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), Months: 12}
+	legacy := db.Fee{PeriodStart: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)} // before fees.months
+
+	tests := []struct {
+		name         string
+		coveredUntil time.Time
+		want         time.Time
+	}{
+		{"no fees", time.Time{}, april},
+		{"monthly fee", CoveredUntil(legacy), april},
+		{"quarter ends this month", CoveredUntil(quarter), april},
+		{"year", CoveredUntil(year), time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"covered months in the past", time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC), april},
+	}
+
+	for _, tt := range tests {
+		if got := NextFeeDate(tt.coveredUntil, now); !got.Equal(tt.want) {
+			t.Errorf("%s: NextFeeDate() = %s, want %s", tt.name, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestPeriodLabel(t *testing.T) {
+	start := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+	if got := PeriodLabel(start, 1); got != "2026-11" {
+		t.Errorf("PeriodLabel(1) = %q", got)
+	}
+	if got := PeriodLabel(start, 3); got != "2026-11 – 2027-01" {
+		t.Errorf("PeriodLabel(3) = %q", got)
+	}
+}
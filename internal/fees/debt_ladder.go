@@ -0,0 +1,101 @@
+package fees
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// Steps of the debt reminder ladder, from the mildest
+const (
+	DebtNotice     = "notice"     // create_monthly_fees sends the negative balance email
+	DebtWarning    = "warning"    // create_monthly_fees sends the debt warning email
+	DebtSuspension = "suspension" // suspend_debtors suspends the membership
+)
+
+// DebtLadder holds the debt thresholds of the reminder ladder in monthly fees.
+// Zero disables the notice or warning step.
+type DebtLadder struct {
+	Notice     int `json:"notice"`
+	Warning    int `json:"warning"`
+	Suspension int `json:"suspension"`
+}
+
+// Validate checks that the enabled steps grow with the debt and that suspension
+// has a threshold
+func (l DebtLadder) Validate() error {
+	if l.Notice < 0 || l.Warning < 0 {
+		return fmt.Errorf("thresholds must not be negative")
+	}
+	if l.Suspension < 1 {
+		return fmt.Errorf("suspension threshold must be at least 1 monthly fee")
+	}
+	prev := 0
+	for _, months := range []int{l.Notice, l.Warning, l.Suspension} {
+		if months == 0 {
+			continue
+		}
+		if months <= prev {
+			return fmt.Errorf("thresholds must grow from notice to suspension")
+		}
+		prev = months
+	}
+	return nil
+}
+
+// Step returns the highest step the member's debt exceeds, or "" when none.
+// Thresholds are counted by DebtLimit, so fees billed ahead by a quarterly or
+// annual cycle do not count as debt.
+func (l DebtLadder) Step(debt, monthly money.Amount, last db.Fee) string {
+	steps := []struct {
+		name   string
+		months int
+	}{
+		{DebtSuspension, l.Suspension},
+		{DebtWarning, l.Warning},
+		{DebtNotice, l.Notice},
+	}
+	for _, step := range steps {
+		if step.months > 0 && debt > DebtLimit(monthly, step.months, last) {
+			return step.name
+		}
+	}
+	return ""
+}
+
+// DefaultDebtLadder returns the ladder configured by DEBT_NOTICE_MONTHS,
+// DEBT_WARNING_MONTHS and SUSPENSION_DEBT_MONTHS
+func DefaultDebtLadder(cfg *config.Config) DebtLadder {
+	return DebtLadder{
+		Notice:     cfg.DebtNoticeMonths,
+		Warning:    cfg.DebtWarningMonths,
+		Suspension: cfg.SuspensionDebtMonths,
+	}
+}
+
+// LoadDebtLadder returns the defaults (from config) overridden by the thresholds
+// set in admin settings
+func LoadDebtLadder(ctx context.Context, queries *db.Queries, defaults DebtLadder) (DebtLadder, error) {
+	thresholds, err := queries.ListDebtThresholds(ctx)
+	if err != nil {
+		return DebtLadder{}, err
+	}
+	ladder := defaults
+	for _, t := range thresholds {
+		switch t.Step {
+		case DebtNotice:
+			ladder.Notice = int(t.Months)
+		case DebtWarning:
+			ladder.Warning = int(t.Months)
+		case DebtSuspension:
+			ladder.Suspension = int(t.Months)
+		}
+	}
+	if err := ladder.Validate(); err != nil {
+		return DebtLadder{}, fmt.Errorf("debt thresholds: %w", err)
+	}
+	return ladder, nil
+}
@@ -0,0 +1,61 @@
+package fees
+
+import (
+	"testing"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+func TestDebtLadderValidate(t *testing.T) {
+	tests := []struct {
+		ladder DebtLadder
+		ok     bool
+	}{
+		{DebtLadder{Notice: 1, Warning: 2, Suspension: 3}, true},
+		{DebtLadder{Notice: 0, Warning: 0, Suspension: 3}, true},
+		{DebtLadder{Notice: 2, Warning: 0, Suspension: 3}, true},
+		{DebtLadder{Notice: 1, Warning: 2, Suspension: 0}, false},
+		{DebtLadder{Notice: 2, Warning: 2, Suspension: 3}, false},
+		{DebtLadder{Notice: 1, Warning: 4, Suspension: 3}, false},
+		{DebtLadder{Notice: -1, Warning: 2, Suspension: 3}, false},
+	}
+
+	for _, tt := range tests {
+		err := tt.ladder.Validate()
+		if (err == nil) != tt.ok {
+			t.Errorf("%+v: Validate() = %v, want ok=%v", tt.ladder, err, tt.ok)
+		}
+	}
+}
+
+func TestDebtLadderStep(t *testing.T) {
+	monthly := money.Amount(50000)
+	ladder := DebtLadder{Notice: 1, Warning: 2, Suspension: 3}
+	monthlyFee := db.Fee{Amount: 50000, Months: 1}
+	quarterFee := db.Fee{Amount: 150000, Months: 3}
+
+	tests := []struct {
+		name   string
+		ladder DebtLadder
+		debt   money.Amount
+		last   db.Fee
+		want   string
+	}{
+		{"no debt", ladder, 0, monthlyFee, ""},
+		{"current month unpaid", ladder, 50000, monthlyFee, ""},
+		{"over one month", ladder, 50100, monthlyFee, DebtNotice},
+		{"two months", ladder, 100000, monthlyFee, DebtNotice},
+		{"over two months", ladder, 100100, monthlyFee, DebtWarning},
+		{"over three months", ladder, 150100, monthlyFee, DebtSuspension},
+		{"quarter just billed", ladder, 150000, quarterFee, ""},
+		{"quarter and a month", ladder, 200000, quarterFee, DebtNotice},
+		{"notice disabled", DebtLadder{Warning: 2, Suspension: 3}, 60000, monthlyFee, ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.ladder.Step(tt.debt, monthly, tt.last); got != tt.want {
+			t.Errorf("%s: Step() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
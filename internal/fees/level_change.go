@@ -0,0 +1,83 @@
+package fees
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// AppliedLevelChange summarizes a member's level switch by ApplyDueLevelChanges
+type AppliedLevelChange struct {
+	HistoryID  int64
+	UserID     int64
+	Email      string
+	OldLevelID int64
+	NewLevelID int64
+	LevelName  string
+	Amount     money.Amount
+}
+
+// ApplyDueLevelChanges switches members to the levels approved from periodStart or
+// earlier (level_history entries not applied yet), so fees created afterwards are
+// billed with the level effective for the period. The member's own amount starts
+// at the new level amount. Each change is applied in its own transaction.
+func ApplyDueLevelChanges(ctx context.Context, database *sql.DB, queries *db.Queries, periodStart time.Time) ([]AppliedLevelChange, error) {
+	changes, err := queries.ListDueLevelChanges(ctx, periodStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list level changes: %w", err)
+	}
+
+	var applied []AppliedLevelChange
+	for _, change := range changes {
+		result, err := applyLevelChange(ctx, database, queries, change)
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply level change #%d: %w", change.ID, err)
+		}
+		applied = append(applied, result)
+	}
+	return applied, nil
+}
+
+func applyLevelChange(ctx context.Context, database *sql.DB, queries *db.Queries, change db.LevelHistory) (AppliedLevelChange, error) {
+	result := AppliedLevelChange{
+		HistoryID:  change.ID,
+		UserID:     change.UserID,
+		NewLevelID: change.LevelID,
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	user, err := qtx.GetUserByID(ctx, change.UserID)
+	if err != nil {
+		return result, err
+	}
+	level, err := qtx.GetLevel(ctx, change.LevelID)
+	if err != nil {
+		return result, err
+	}
+	result.Email = user.Email
+	result.OldLevelID = user.LevelID
+	result.LevelName = level.Name
+	result.Amount = level.Amount
+
+	if err := qtx.UpdateUserLevel(ctx, db.UpdateUserLevelParams{
+		LevelID:           level.ID,
+		LevelActualAmount: level.Amount,
+		ID:                user.ID,
+	}); err != nil {
+		return result, err
+	}
+	if err := qtx.MarkLevelHistoryApplied(ctx, change.ID); err != nil {
+		return result, err
+	}
+	return result, tx.Commit()
+}
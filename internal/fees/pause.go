@@ -0,0 +1,36 @@
+package fees
+
+import (
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// PauseCovers reports whether day falls within a membership pause (both dates
+// inclusive, no end date = until cleared). The fee job skips a month when the
+// pause covers its first day.
+func PauseCovers(pause db.MembershipPause, day time.Time) bool {
+	if day.Before(pause.StartDate) {
+		return false
+	}
+	return !pause.EndDate.Valid || !day.After(pause.EndDate.Time)
+}
+
+// PauseExpired reports whether the pause ended before day
+func PauseExpired(pause db.MembershipPause, day time.Time) bool {
+	return pause.EndDate.Valid && pause.EndDate.Time.Before(day)
+}
+
+// SkipPause moves the date of a fee covered by the pause to the first day of the
+// month after the pause ends. ok is false for a pause without an end date: no fee
+// is created until an admin clears it.
+func SkipPause(pause db.MembershipPause, date time.Time) (next time.Time, ok bool) {
+	if !PauseCovers(pause, date) {
+		return date, true
+	}
+	if !pause.EndDate.Valid {
+		return time.Time{}, false
+	}
+	end := pause.EndDate.Time
+	return time.Date(end.Year(), end.Month()+1, 1, 0, 0, 0, 0, time.UTC), true
+}
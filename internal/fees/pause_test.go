@@ -0,0 +1,74 @@
+package fees
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPauseCovers(t *testing.T) {
+	pause := db.MembershipPause{
+		StartDate: date(time.January, 15),
+		EndDate:   sql.NullTime{Time: date(time.March, 1), Valid: true},
+	}
+	open := db.MembershipPause{StartDate: date(time.January, 15)}
+
+	tests := []struct {
+		name  string
+		pause db.MembershipPause
+		day   time.Time
+		want  bool
+	}{
+		{"before start", pause, date(time.January, 1), false},
+		{"start day", pause, date(time.January, 15), true},
+		{"within", pause, date(time.February, 1), true},
+		{"end day", pause, date(time.March, 1), true},
+		{"after end", pause, date(time.April, 1), false},
+		{"no end date", open, date(time.December, 1), true},
+	}
+
+	for _, tt := range tests {
+		if got := PauseCovers(tt.pause, tt.day); got != tt.want {
+			t.Errorf("%s: PauseCovers() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPauseExpired(t *testing.T) {
+	pause := db.MembershipPause{
+		StartDate: date(time.January, 15),
+		EndDate:   sql.NullTime{Time: date(time.March, 20), Valid: true},
+	}
+	if PauseExpired(pause, date(time.March, 20)) {
+		t.Error("PauseExpired() on the end day = true")
+	}
+	if !PauseExpired(pause, date(time.April, 1)) {
+		t.Error("PauseExpired() after the end day = false")
+	}
+	if PauseExpired(db.MembershipPause{StartDate: date(time.January, 15)}, date(time.December, 1)) {
+		t.Error("PauseExpired() without an end date = true")
+	}
+}
+
+func TestSkipPause(t *testing.T) {
+	pause := db.MembershipPause{
+		StartDate: date(time.January, 15),
+		EndDate:   sql.NullTime{Time: date(time.March, 20), Valid: true},
+	}
+
+	if got, ok := SkipPause(pause, date(time.January, 1)); !ok || !got.Equal(date(time.January, 1)) {
+		t.Errorf("SkipPause(before) = %s, %v", got.Format("2006-01-02"), ok)
+	}
+	if got, ok := SkipPause(pause, date(time.February, 1)); !ok || !got.Equal(date(time.April, 1)) {
+		t.Errorf("SkipPause(within) = %s, %v; want 2026-04-01", got.Format("2006-01-02"), ok)
+	}
+	if _, ok := SkipPause(db.MembershipPause{StartDate: date(time.January, 15)}, date(time.February, 1)); ok {
+		t.Error("SkipPause(no end date) ok = true")
+	}
+}
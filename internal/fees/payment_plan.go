@@ -0,0 +1,77 @@
+package fees
+
+import (
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// PlanGraceDays is how long after its due date an installment may arrive before
+// the member counts as not keeping to the plan (bank transfers take a few days)
+const PlanGraceDays = 7
+
+// PlanInstallments returns how many installments pay off the plan's debt
+func PlanInstallments(plan db.PaymentPlan) int {
+	if plan.Installment <= 0 {
+		return 0
+	}
+	return int((plan.TotalDebt + plan.Installment - 1) / plan.Installment)
+}
+
+// PlanDueDate returns the due date of the k-th installment (from 0): the day of
+// the month of start_date, or the last day of shorter months
+func PlanDueDate(plan db.PaymentPlan, k int) time.Time {
+	start := plan.StartDate
+	first := time.Date(start.Year(), start.Month()+time.Month(k), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := start.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// PlanInstallmentsDue returns how many installments are due on day
+func PlanInstallmentsDue(plan db.PaymentPlan, day time.Time) int {
+	total := PlanInstallments(plan)
+	due := 0
+	for due < total && !PlanDueDate(plan, due).After(day) {
+		due++
+	}
+	return due
+}
+
+// PlanRemaining returns the part of the plan's debt left after installments
+func PlanRemaining(plan db.PaymentPlan, installments int) money.Amount {
+	remaining := plan.TotalDebt - plan.Installment*money.Amount(installments)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// PlanFinished reports whether all installments are due on day
+func PlanFinished(plan db.PaymentPlan, day time.Time) bool {
+	return PlanInstallmentsDue(plan, day) >= PlanInstallments(plan)
+}
+
+// OnPlan reports whether a member keeps to the plan on day. Installments are paid
+// on top of the regular fees, so the debt may be at most what the plan has not
+// asked for yet (installments count after PlanGraceDays).
+func OnPlan(plan db.PaymentPlan, debt money.Amount, day time.Time) bool {
+	installments := PlanInstallmentsDue(plan, day.AddDate(0, 0, -PlanGraceDays))
+	return debt <= PlanRemaining(plan, installments)
+}
+
+// PlanAmountDue returns what the member has to pay during the month of day to
+// keep to the plan: that month's installment together with the fees and any
+// missed installments
+func PlanAmountDue(plan db.PaymentPlan, debt money.Amount, day time.Time) money.Amount {
+	monthEnd := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+	due := debt - PlanRemaining(plan, PlanInstallmentsDue(plan, monthEnd))
+	if due < 0 {
+		return 0
+	}
+	return due
+}
@@ -0,0 +1,92 @@
+package fees
+
+import (
+	"testing"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+func TestPlanDueDate(t *testing.T) {
+	plan := db.PaymentPlan{StartDate: date(time.January, 31)}
+
+	tests := []struct {
+		k    int
+		want time.Time
+	}{
+		{0, date(time.January, 31)},
+		{1, date(time.February, 28)},
+		{2, date(time.March, 31)},
+		{3, date(time.April, 30)},
+		{11, date(time.December, 31)},
+		{12, time.Date(2027, time.January, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := PlanDueDate(plan, tt.k); !got.Equal(tt.want) {
+			t.Errorf("PlanDueDate(%d) = %s, want %s", tt.k, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestOnPlan(t *testing.T) {
+	// 3 500 Kč in installments of 1 000 Kč from 15 February: 1 000, 1 000, 1 000, 500
+	plan := db.PaymentPlan{
+		TotalDebt:   350000,
+		Installment: 100000,
+		StartDate:   date(time.February, 15),
+	}
+	if n := PlanInstallments(plan); n != 4 {
+		t.Fatalf("PlanInstallments() = %d, want 4", n)
+	}
+
+	tests := []struct {
+		name string
+		debt money.Amount
+		day  time.Time
+		want bool
+	}{
+		{"before start", 350000, date(time.February, 10), true},
+		{"first installment in grace period", 350000, date(time.February, 20), true},
+		{"first installment missed", 350000, date(time.February, 22), false},
+		{"first installment paid", 250000, date(time.February, 22), true},
+		{"fee unpaid", 300000, date(time.March, 2), false},
+		{"two installments paid", 150000, date(time.March, 25), true},
+		{"last installment is smaller", 0, date(time.May, 25), true},
+		{"debt after the plan", 50000, date(time.June, 1), false},
+	}
+
+	for _, tt := range tests {
+		if got := OnPlan(plan, tt.debt, tt.day); got != tt.want {
+			t.Errorf("%s: OnPlan() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlanAmountDue(t *testing.T) {
+	plan := db.PaymentPlan{
+		TotalDebt:   350000,
+		Installment: 100000,
+		StartDate:   date(time.February, 15),
+	}
+
+	tests := []struct {
+		name string
+		debt money.Amount
+		day  time.Time
+		want money.Amount
+	}{
+		{"installment and fee", 300000, date(time.March, 1), 150000},
+		{"missed installment", 300000, date(time.April, 1), 250000},
+		{"paid ahead", 100000, date(time.March, 1), 0},
+		{"last installment", 100000, date(time.May, 1), 100000},
+		{"after the plan", 50000, date(time.July, 1), 50000},
+	}
+
+	for _, tt := range tests {
+		if got := PlanAmountDue(plan, tt.debt, tt.day); got != tt.want {
+			t.Errorf("%s: PlanAmountDue() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package fees
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// EffectiveAmount returns the monthly fee of a member: level_actual_amount, or the
+// level amount when the member has no own amount
+func EffectiveAmount(actualAmount, levelAmount money.Amount) money.Amount {
+	if actualAmount == 0 {
+		return levelAmount
+	}
+	return actualAmount
+}
+
+// AmountAfterChange returns the member's level_actual_amount after the level amount
+// changes from oldLevel to newLevel, and whether the member's fee changes at all.
+// Members paying the level amount follow it; members who chose to pay more keep
+// their amount unless it falls below the new level amount.
+func AmountAfterChange(actualAmount, oldLevel, newLevel money.Amount) (money.Amount, bool) {
+	if actualAmount == 0 {
+		return actualAmount, oldLevel != newLevel
+	}
+
+	if actualAmount == oldLevel || actualAmount < newLevel {
+		return newLevel, actualAmount != newLevel
+	}
+	return actualAmount, false
+}
+
+// ParseEffectiveMonth parses "YYYY-MM" into the first day of the month (UTC), the
+// period_start of the first fee with the new amount
+func ParseEffectiveMonth(s string) (time.Time, error) {
+	t, err := time.Parse("2006-01", strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid month %q, expected YYYY-MM", s)
+	}
+	return t, nil
+}
+
+// NoticeDeadline returns the latest time a member is notified about a change
+// effective from the given month
+func NoticeDeadline(effectiveFrom time.Time, noticeWeeks int) time.Time {
+	return effectiveFrom.AddDate(0, 0, -7*noticeWeeks)
+}
+
+// AppliedChange summarizes a planned change switched by ApplyDueChanges
+type AppliedChange struct {
+	ChangeID     int64
+	LevelName    string
+	OldAmount    money.Amount
+	NewAmount    money.Amount
+	UsersUpdated int
+}
+
+// ApplyDueChanges switches level amounts (and members' own amounts following them)
+// for all planned changes effective at or before periodStart. Each change is applied
+// in its own transaction, so fees created afterwards use the new amounts.
+func ApplyDueChanges(ctx context.Context, database *sql.DB, queries *db.Queries, periodStart time.Time) ([]AppliedChange, error) {
+	changes, err := queries.ListDueLevelPriceChanges(ctx, periodStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list planned fee changes: %w", err)
+	}
+
+	var applied []AppliedChange
+	for _, change := range changes {
+		result, err := applyChange(ctx, database, queries, change)
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply fee change #%d: %w", change.ID, err)
+		}
+		applied = append(applied, result)
+	}
+	return applied, nil
+}
+
+func applyChange(ctx context.Context, database *sql.DB, queries *db.Queries, change db.ListDueLevelPriceChangesRow) (AppliedChange, error) {
+	result := AppliedChange{
+		ChangeID:  change.ID,
+		LevelName: change.LevelName,
+		OldAmount: change.LevelAmount,
+		NewAmount: change.NewAmount,
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	users, err := qtx.ListUsersByLevel(ctx, change.LevelID)
+	if err != nil {
+		return result, err
+	}
+	for _, user := range users {
+		amount, _ := AmountAfterChange(user.LevelActualAmount, change.LevelAmount, change.NewAmount)
+		if amount == user.LevelActualAmount {
+			continue
+		}
+		if _, err := qtx.UpdateUserCustomFee(ctx, db.UpdateUserCustomFeeParams{
+			LevelActualAmount: amount,
+			ID:                user.ID,
+		}); err != nil {
+			return result, err
+		}
+		result.UsersUpdated++
+	}
+
+	if err := qtx.UpdateLevelAmount(ctx, db.UpdateLevelAmountParams{
+		Amount: change.NewAmount,
+		ID:     change.LevelID,
+	}); err != nil {
+		return result, err
+	}
+	if err := qtx.MarkLevelPriceChangeApplied(ctx, change.ID); err != nil {
+		return result, err
+	}
+	return result, tx.Commit()
+}
@@ -0,0 +1,52 @@
+package fees
+
+import (
+	"time"
+
+	"github.com/base48/member-portal/internal/money"
+)
+
+// Proration modes of the fee for the month a member joins (FEE_PRORATION)
+const (
+	ProrationNone      = "none"       // full fee regardless of the join date
+	ProrationDaily     = "daily"      // share of the days from the join date to the end of the month
+	ProrationHalfMonth = "half-month" // full fee when joined by the 15th, half after it
+)
+
+// ProratedAmount returns the fee for the monthly period starting at periodStart
+// of a member who joined at joined. Only the period the member joined in is
+// prorated; earlier periods (joined in the future) cost nothing and later ones
+// the full amount. Prorated fees are rounded to whole crowns.
+func ProratedAmount(amount money.Amount, periodStart, joined time.Time, mode string) money.Amount {
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	joinDay := time.Date(joined.Year(), joined.Month(), joined.Day(), 0, 0, 0, 0, periodStart.Location())
+	if !joinDay.Before(periodEnd) {
+		return 0 // not a member yet
+	}
+	if !joinDay.After(periodStart) {
+		return amount
+	}
+
+	switch mode {
+	case ProrationDaily:
+		daysInMonth := days(periodEnd.Sub(periodStart))
+		daysLeft := days(periodEnd.Sub(joinDay)) // including the join day
+		return roundCrowns(int64(amount)*daysLeft, daysInMonth)
+	case ProrationHalfMonth:
+		if joinDay.Day() > 15 {
+			return roundCrowns(int64(amount), 2)
+		}
+	}
+	return amount
+}
+
+// days converts a duration between midnights to days (DST days have 23 or 25 hours)
+func days(d time.Duration) int64 {
+	return int64((d + 12*time.Hour) / (24 * time.Hour))
+}
+
+// roundCrowns divides an amount by divisor and rounds it to whole crowns, halves up
+func roundCrowns(amount, divisor int64) money.Amount {
+	unit := divisor * 100
+	return money.Amount((amount + unit/2) / unit * 100)
+}
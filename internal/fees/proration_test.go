@@ -0,0 +1,78 @@
+package fees
+
+import (
+	"testing"
+	"time"
+
+	"github.com/base48/member-portal/internal/money"
+)
+
+func TestProratedAmount(t *testing.T) {
+	date := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	january := date(2026, time.January, 1, 0)
+	february := date(2026, time.February, 1, 0)
+	leapFebruary := date(2028, time.February, 1, 0)
+	const fee = money.Amount(100000) // 1000 Kč
+
+	tests := []struct {
+		name        string
+		periodStart time.Time
+		joined      time.Time
+		mode        string
+		want        money.Amount
+	}{
+		{"joined earlier", february, date(2025, time.March, 20, 0), ProrationDaily, fee},
+		{"joined on the first", february, date(2026, time.February, 1, 18), ProrationDaily, fee},
+		{"joined next month", february, date(2026, time.March, 1, 0), ProrationDaily, 0},
+		{"none mid-month", february, date(2026, time.February, 20, 0), ProrationNone, fee},
+		{"none next month", february, date(2026, time.March, 1, 0), ProrationNone, 0},
+
+		// Daily: days from the join day to the end of the month, whole crowns
+		{"daily second day of 31", january, date(2026, time.January, 2, 9), ProrationDaily, 96800},     // 30/31
+		{"daily last day of 31", january, date(2026, time.January, 31, 23), ProrationDaily, 3200},      // 1/31
+		{"daily 15th of 28", february, date(2026, time.February, 15, 0), ProrationDaily, 50000},        // 14/28
+		{"daily last day of 28", february, date(2026, time.February, 28, 0), ProrationDaily, 3600},     // 1/28
+		{"daily last day of 29", leapFebruary, date(2028, time.February, 29, 0), ProrationDaily, 3400}, // 1/29
+
+		// Half-month: full by the 15th, half after it
+		{"half-month 15th", january, date(2026, time.January, 15, 23), ProrationHalfMonth, fee},
+		{"half-month 16th", january, date(2026, time.January, 16, 0), ProrationHalfMonth, 50000},
+		{"half-month last day", february, date(2026, time.February, 28, 0), ProrationHalfMonth, 50000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ProratedAmount(fee, tt.periodStart, tt.joined, tt.mode)
+			if got != tt.want {
+				t.Errorf("ProratedAmount(%s, %s, %s) = %s, want %s", tt.periodStart.Format("2006-01"), tt.joined.Format("2006-01-02"), tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProratedAmountRounding(t *testing.T) {
+	// 850.50 Kč, half = 425.25 Kč -> 425 Kč; 16 of 31 days = 438.97 Kč -> 439 Kč
+	periodStart := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	joined := time.Date(2026, time.January, 16, 0, 0, 0, 0, time.UTC)
+	if got := ProratedAmount(85050, periodStart, joined, ProrationHalfMonth); got != 42500 {
+		t.Errorf("half-month = %s, want 425.00", got)
+	}
+	if got := ProratedAmount(85050, periodStart, joined, ProrationDaily); got != 43900 {
+		t.Errorf("daily = %s, want 439.00", got)
+	}
+}
+
+func TestProratedAmountPrague(t *testing.T) {
+	prague, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skip("no time zone data")
+	}
+	// March has a 23 hour day (DST), it still has 31 days
+	periodStart := time.Date(2026, time.March, 1, 0, 0, 0, 0, prague)
+	joined := time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)
+	if got := ProratedAmount(100000, periodStart, joined, ProrationDaily); got != 3200 {
+		t.Errorf("daily = %s, want 32.00", got)
+	}
+}
@@ -0,0 +1,18 @@
+package fees
+
+import (
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// DebtLimit returns the debt a member may have before suspend_debtors suspends them:
+// months monthly fees, plus the months the last fee bills ahead with a quarterly or
+// annual billing cycle (right after such a fee is created the member owes the whole
+// period without being late)
+func DebtLimit(monthly money.Amount, months int, last db.Fee) money.Amount {
+	limit := monthly * money.Amount(months)
+	if last.Months > 1 && last.Amount > monthly {
+		limit += last.Amount - monthly
+	}
+	return limit
+}
@@ -0,0 +1,30 @@
+package fees
+
+import (
+	"testing"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+func TestDebtLimit(t *testing.T) {
+	monthly := money.Amount(50000)
+
+	tests := []struct {
+		name string
+		last db.Fee
+		want money.Amount
+	}{
+		{"no fees", db.Fee{}, 150000},
+		{"monthly fee", db.Fee{Amount: 50000, Months: 1}, 150000},
+		{"quarter", db.Fee{Amount: 150000, Months: 3}, 250000},
+		{"rest of the year", db.Fee{Amount: 200000, Months: 4}, 300000},
+		{"old level amount", db.Fee{Amount: 40000, Months: 3}, 150000},
+	}
+
+	for _, tt := range tests {
+		if got := DebtLimit(monthly, 3, tt.last); got != tt.want {
+			t.Errorf("%s: DebtLimit() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package fees
+
+import (
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// TerminationMonths caps the months a fee starting at periodStart covers so that
+// it ends with the month of the termination end date. Zero means the membership
+// ends before the period and no fee is created.
+func TerminationMonths(termination db.MembershipTermination, periodStart time.Time, months int) int {
+	end := termination.EndDate
+	if end.Before(periodStart) {
+		return 0
+	}
+	left := (end.Year()-periodStart.Year())*12 + int(end.Month()-periodStart.Month()) + 1
+	if left < months {
+		return left
+	}
+	return months
+}
+
+// TerminationPassed reports whether the membership ended before day
+func TerminationPassed(termination db.MembershipTermination, day time.Time) bool {
+	return termination.EndDate.Before(day)
+}
@@ -0,0 +1,42 @@
+package fees
+
+import (
+	"testing"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+func TestTerminationMonths(t *testing.T) {
+	termination := db.MembershipTermination{EndDate: date(time.April, 30)}
+
+	tests := []struct {
+		name        string
+		periodStart time.Time
+		months      int
+		want        int
+	}{
+		{"monthly before end", date(time.March, 1), 1, 1},
+		{"monthly in end month", date(time.April, 1), 1, 1},
+		{"monthly after end", date(time.May, 1), 1, 0},
+		{"quarter cut short", date(time.March, 1), 3, 2},
+		{"quarter ending with end month", date(time.February, 1), 3, 3},
+		{"year cut short", date(time.January, 1), 12, 4},
+	}
+
+	for _, tt := range tests {
+		if got := TerminationMonths(termination, tt.periodStart, tt.months); got != tt.want {
+			t.Errorf("%s: TerminationMonths() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTerminationPassed(t *testing.T) {
+	termination := db.MembershipTermination{EndDate: date(time.April, 30)}
+	if TerminationPassed(termination, date(time.April, 30)) {
+		t.Error("TerminationPassed() on the end day = true")
+	}
+	if !TerminationPassed(termination, date(time.May, 1)) {
+		t.Error("TerminationPassed() after the end day = false")
+	}
+}
@@ -3,69 +3,110 @@ package fio
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"strconv"
 	"time"
 )
 
-// Client represents a FIO Bank API client
+// requestInterval is the minimum time between two requests with the same token;
+// FIO rejects faster requests with 409 Conflict
+const requestInterval = 30 * time.Second
+
+// defaultMaxRetries is the number of retries of a request rejected with 409
+const defaultMaxRetries = 3
+
+// ErrRateLimited is returned when FIO keeps rejecting requests with 409 Conflict
+// after all retries (another process uses the same token)
+var ErrRateLimited = errors.New("FIO API rate limit: only one request per token every 30 seconds")
+
+// Options tune the request pacing; zero values use the defaults
+type Options struct {
+	// MinInterval between two requests of this client, at least 30 seconds
+	MinInterval time.Duration
+	// MaxRetries of a request rejected with 409 (another process used the token),
+	// with growing backoff and jitter
+	MaxRetries int
+}
+
+// Client represents a FIO Bank API client; it is not safe for concurrent use
 type Client struct {
-	token      string
-	httpClient *http.Client
-	baseURL    string
+	token       string
+	httpClient  *http.Client
+	baseURL     string
+	minInterval time.Duration
+	maxRetries  int
+	lastRequest time.Time
 }
 
 // NewClient creates a new FIO API client
-func NewClient(token string) *Client {
+func NewClient(token string, opts Options) *Client {
+	if opts.MinInterval < requestInterval {
+		opts.MinInterval = requestInterval
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = defaultMaxRetries
+	}
 	return &Client{
 		token: token,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		baseURL: "https://fioapi.fio.cz/v1/rest",
+		baseURL:     "https://fioapi.fio.cz/v1/rest",
+		minInterval: opts.MinInterval,
+		maxRetries:  opts.MaxRetries,
 	}
 }
 
-// Transaction represents a single FIO bank transaction
+// Transaction represents a single FIO bank transaction; the JSON tags are the API
+// columns (kept in payments.raw_data)
 type Transaction struct {
-	ID                int64       `json:"column22"` // ID transakce
-	Date              string      `json:"column0"`  // Datum (YYYY-MM-DD format)
-	Amount            float64     `json:"column1"`  // Částka
-	Currency          string      `json:"column14"` // Měna
-	AccountNumber     string      `json:"column2"`  // Protiúčet
-	AccountName       string      `json:"column10"` // Název protiúčtu
-	BankCode          string      `json:"column3"`  // Kód banky
-	BankName          string      `json:"column12"` // Název banky
-	VariableSymbol    string      `json:"column5"`  // Variabilní symbol
-	SpecificSymbol    string      `json:"column6"`  // Specifický symbol
-	Message           string      `json:"column16"` // Zpráva pro příjemce
-	Comment           string      `json:"column25"` // Komentář
-	TransactionType   string      `json:"column8"`  // Typ transakce
-	Identification    string      `json:"column7"`  // Identifikace transakce
+	ID              int64   `json:"column22"`           // ID transakce
+	Date            string  `json:"column0"`            // Datum (YYYY-MM-DD format)
+	Amount          float64 `json:"column1"`            // Částka
+	Currency        string  `json:"column14"`           // Měna
+	AccountNumber   string  `json:"column2"`            // Protiúčet
+	AccountName     string  `json:"column10"`           // Název protiúčtu
+	BankCode        string  `json:"column3"`            // Kód banky
+	BankName        string  `json:"column12"`           // Název banky
+	ConstantSymbol  string  `json:"column4,omitempty"`  // Konstantní symbol
+	VariableSymbol  string  `json:"column5"`            // Variabilní symbol
+	SpecificSymbol  string  `json:"column6"`            // Specifický symbol
+	Message         string  `json:"column16"`           // Zpráva pro příjemce
+	Comment         string  `json:"column25"`           // Komentář
+	TransactionType string  `json:"column8"`            // Typ transakce
+	Identification  string  `json:"column7"`            // Identifikace transakce
+	ExecutedBy      string  `json:"column9,omitempty"`  // Provedl
+	Specification   string  `json:"column18,omitempty"` // Upřesnění
+	BIC             string  `json:"column26,omitempty"` // BIC banky protiúčtu
+	InstructionID   int64   `json:"column17,omitempty"` // ID pokynu
+	PayerReference  string  `json:"column27,omitempty"` // Reference plátce
 }
 
 // TransactionList represents the response from FIO API
 type TransactionList struct {
 	AccountStatement struct {
 		Info struct {
-			AccountID   string `json:"accountId"`
-			BankID      string `json:"bankId"`
-			Currency    string `json:"currency"`
-			IBAN        string `json:"iban"`
-			BIC         string `json:"bic"`
+			AccountID      string  `json:"accountId"`
+			BankID         string  `json:"bankId"`
+			Currency       string  `json:"currency"`
+			IBAN           string  `json:"iban"`
+			BIC            string  `json:"bic"`
 			OpeningBalance float64 `json:"openingBalance"`
 			ClosingBalance float64 `json:"closingBalance"`
-			DateStart   string `json:"dateStart"`
-			DateEnd     string `json:"dateEnd"`
-			YearList    int    `json:"yearList"`
-			IDList      int    `json:"idList"`
-			IDFrom      int64  `json:"idFrom"`
-			IDTo        int64  `json:"idTo"`
-			IDLastDownload int64 `json:"idLastDownload"`
+			DateStart      string  `json:"dateStart"`
+			DateEnd        string  `json:"dateEnd"`
+			YearList       int     `json:"yearList"`
+			IDList         int     `json:"idList"`
+			IDFrom         int64   `json:"idFrom"`
+			IDTo           int64   `json:"idTo"`
+			IDLastDownload int64   `json:"idLastDownload"`
 		} `json:"info"`
 		TransactionList struct {
-			Transactions []map[string]interface{} `json:"transaction"`
+			Transactions []apiTransaction `json:"transaction"`
 		} `json:"transactionList"`
 	} `json:"accountStatement"`
 }
@@ -96,47 +137,93 @@ func (c *Client) FetchTransactionsByID(ctx context.Context, year int, idFrom int
 // date should be in format "YYYY-MM-DD"
 func (c *Client) SetLastDownloadDate(ctx context.Context, date string) error {
 	url := fmt.Sprintf("%s/set-last-date/%s/%s/", c.baseURL, c.token, date)
+	_, err := c.get(ctx, url)
+	return err
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+// throttle waits until the next request is allowed (see Options.MinInterval)
+func (c *Client) throttle(ctx context.Context) error {
+	if !c.lastRequest.IsZero() {
+		if err := sleep(ctx, time.Until(c.lastRequest.Add(c.minInterval))); err != nil {
+			return err
+		}
 	}
+	c.lastRequest = time.Now()
+	return nil
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to execute request: %w", err)
-	}
-	defer resp.Body.Close()
+// get performs a throttled GET request and returns the body. A 409 Conflict means
+// the token was used less than 30 seconds ago (e.g. by an overlapping sync); the
+// request is retried after the interval plus a growing backoff with jitter.
+func (c *Client) get(ctx context.Context, url string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.throttle(ctx); err != nil {
+			return nil, err
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
-	}
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
 
-	return nil
-}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute request: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
 
-// fetchTransactions is a helper that performs the actual HTTP request and parsing
-func (c *Client) fetchTransactions(ctx context.Context, url string) ([]Transaction, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		if resp.StatusCode == http.StatusConflict {
+			if attempt >= c.maxRetries {
+				return nil, fmt.Errorf("%w (still rejected after %d retries)", ErrRateLimited, c.maxRetries)
+			}
+			// throttle waits minInterval after this request, the backoff adds to it
+			if err := sleep(ctx, c.backoff(attempt, resp.Header.Get("Retry-After"))); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		return body, nil
 	}
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+// backoff is the extra wait before a retry: Retry-After if FIO sends it, otherwise
+// 0, 30 and 90 s and so on, plus up to 10 s of jitter so overlapping processes
+// do not collide again
+func (c *Client) backoff(attempt int, retryAfter string) time.Duration {
+	jitter := rand.N(10 * time.Second)
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+		return time.Duration(seconds)*time.Second + jitter
 	}
-	defer resp.Body.Close()
+	return requestInterval*time.Duration(1<<attempt-1) + jitter
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+// sleep waits for d or until the context is canceled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
 	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-	body, err := io.ReadAll(resp.Body)
+// fetchTransactions is a helper that performs the actual HTTP request and parsing
+func (c *Client) fetchTransactions(ctx context.Context, url string) ([]Transaction, error) {
+	body, err := c.get(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	var result TransactionList
@@ -144,87 +231,9 @@ func (c *Client) fetchTransactions(ctx context.Context, url string) ([]Transacti
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	// Parse transactions from the raw map structure
 	transactions := make([]Transaction, 0, len(result.AccountStatement.TransactionList.Transactions))
-
-	for _, rawTx := range result.AccountStatement.TransactionList.Transactions {
-		tx := Transaction{}
-
-		// Extract fields from the nested structure
-		if v, ok := rawTx["column22"].(map[string]interface{}); ok {
-			if id, ok := v["value"].(float64); ok {
-				tx.ID = int64(id)
-			}
-		}
-		if v, ok := rawTx["column0"].(map[string]interface{}); ok {
-			if date, ok := v["value"].(string); ok {
-				tx.Date = date
-			}
-		}
-		if v, ok := rawTx["column1"].(map[string]interface{}); ok {
-			if amount, ok := v["value"].(float64); ok {
-				tx.Amount = amount
-			}
-		}
-		if v, ok := rawTx["column14"].(map[string]interface{}); ok {
-			if curr, ok := v["value"].(string); ok {
-				tx.Currency = curr
-			}
-		}
-		if v, ok := rawTx["column2"].(map[string]interface{}); ok {
-			if acc, ok := v["value"].(string); ok {
-				tx.AccountNumber = acc
-			}
-		}
-		if v, ok := rawTx["column10"].(map[string]interface{}); ok {
-			if name, ok := v["value"].(string); ok {
-				tx.AccountName = name
-			}
-		}
-		if v, ok := rawTx["column3"].(map[string]interface{}); ok {
-			if code, ok := v["value"].(string); ok {
-				tx.BankCode = code
-			}
-		}
-		if v, ok := rawTx["column12"].(map[string]interface{}); ok {
-			if bank, ok := v["value"].(string); ok {
-				tx.BankName = bank
-			}
-		}
-		if v, ok := rawTx["column5"].(map[string]interface{}); ok {
-			if vs, ok := v["value"].(string); ok {
-				tx.VariableSymbol = vs
-			} else if vs, ok := v["value"].(float64); ok {
-				tx.VariableSymbol = fmt.Sprintf("%.0f", vs)
-			}
-		}
-		if v, ok := rawTx["column6"].(map[string]interface{}); ok {
-			if ss, ok := v["value"].(string); ok {
-				tx.SpecificSymbol = ss
-			}
-		}
-		if v, ok := rawTx["column16"].(map[string]interface{}); ok {
-			if msg, ok := v["value"].(string); ok {
-				tx.Message = msg
-			}
-		}
-		if v, ok := rawTx["column25"].(map[string]interface{}); ok {
-			if cmt, ok := v["value"].(string); ok {
-				tx.Comment = cmt
-			}
-		}
-		if v, ok := rawTx["column8"].(map[string]interface{}); ok {
-			if typ, ok := v["value"].(string); ok {
-				tx.TransactionType = typ
-			}
-		}
-		if v, ok := rawTx["column7"].(map[string]interface{}); ok {
-			if id, ok := v["value"].(string); ok {
-				tx.Identification = id
-			}
-		}
-
-		transactions = append(transactions, tx)
+	for _, tx := range result.AccountStatement.TransactionList.Transactions {
+		transactions = append(transactions, tx.transaction())
 	}
 
 	return transactions, nil
@@ -0,0 +1,180 @@
+package fio
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// serveFixture returns a client whose API answers every request with the file
+// from testdata
+func serveFixture(t *testing.T, name string) *Client {
+	t.Helper()
+	body, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/transactions.json") {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("token", Options{})
+	client.baseURL = server.URL
+	return client
+}
+
+func TestFetchTransactions(t *testing.T) {
+	client := serveFixture(t, "transactions.json")
+
+	transactions, err := client.FetchTransactionsByPeriod(context.Background(), "2024-03-01", "2024-03-31")
+	if err != nil {
+		t.Fatalf("FetchTransactionsByPeriod() error = %v", err)
+	}
+
+	want := []Transaction{
+		{
+			ID:              26543210001,
+			Date:            "2024-03-05+0100",
+			Amount:          1000,
+			Currency:        "CZK",
+			AccountNumber:   "1234567890",
+			AccountName:     "NOVÁK JAN",
+			BankCode:        "0800",
+			BankName:        "Česká spořitelna, a.s.",
+			ConstantSymbol:  "0308",
+			VariableSymbol:  "1042",
+			Message:         "clensky prispevek",
+			Comment:         "Příspěvek březen",
+			TransactionType: "Bezhotovostní příjem",
+			Identification:  "Příspěvek březen",
+			BIC:             "GIBACZPX",
+			InstructionID:   31234567890,
+		},
+		{
+			ID:              26543210002,
+			Date:            "2024-03-12+0100",
+			Amount:          -100,
+			Currency:        "CZK",
+			AccountNumber:   "2345678901",
+			AccountName:     "Hardware s.r.o.",
+			BankCode:        "2010",
+			BankName:        "Fio banka, a.s.",
+			VariableSymbol:  "2024031", // sent as a number
+			SpecificSymbol:  "77",
+			Message:         "faktura 2024031",
+			TransactionType: "Bezhotovostní platba",
+			ExecutedBy:      "Petr Svoboda",
+			InstructionID:   31234567999,
+			PayerReference:  "FA-2024-031",
+		},
+		{
+			ID:              26543210003,
+			Date:            "2024-03-31+0200",
+			Currency:        "CZK",
+			TransactionType: "Platba kartou",
+			Specification:   "12.50 EUR",
+		},
+	}
+
+	if len(transactions) != len(want) {
+		t.Fatalf("got %d transactions, want %d", len(transactions), len(want))
+	}
+	for i := range want {
+		if transactions[i] != want[i] {
+			t.Errorf("transaction %d:\n got %+v\nwant %+v", i, transactions[i], want[i])
+		}
+	}
+}
+
+func TestFetchTransactionsEmpty(t *testing.T) {
+	client := serveFixture(t, "empty.json")
+
+	transactions, err := client.FetchTransactionsSinceLastDownload(context.Background())
+	if err != nil {
+		t.Fatalf("FetchTransactionsSinceLastDownload() error = %v", err)
+	}
+	if len(transactions) != 0 {
+		t.Errorf("got %d transactions, want none", len(transactions))
+	}
+}
+
+func TestColumnTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    Transaction
+		wantErr bool
+	}{
+		{
+			name: "symbols as numbers",
+			json: `{"column5":{"value":1234567890},"column6":{"value":12.0},"column4":{"value":308}}`,
+			want: Transaction{VariableSymbol: "1234567890", SpecificSymbol: "12", ConstantSymbol: "308"},
+		},
+		{
+			name: "null columns and values",
+			json: `{"column22":null,"column1":{"value":null},"column5":null}`,
+			want: Transaction{},
+		},
+		{
+			name: "ID and amount as text",
+			json: `{"column22":{"value":"26543210001"},"column1":{"value":"-1250,50"}}`,
+			want: Transaction{ID: 26543210001, Amount: -1250.5},
+		},
+		{
+			name: "ID in exponent notation",
+			json: `{"column22":{"value":2.6543210001E10}}`,
+			want: Transaction{ID: 26543210001},
+		},
+		{
+			name:    "amount of an unexpected type",
+			json:    `{"column1":{"value":true}}`,
+			wantErr: true,
+		},
+		{
+			name:    "text of an unexpected type",
+			json:    `{"column5":{"value":{"nested":1}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "fractional ID",
+			json:    `{"column22":{"value":1.5}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tx apiTransaction
+			err := json.Unmarshal([]byte(tt.json), &tx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tx.transaction() != tt.want {
+				t.Errorf("got %+v, want %+v", tx.transaction(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRawDataRoundTrip(t *testing.T) {
+	// payments.raw_data keeps the transaction under the API column names
+	tx := Transaction{ID: 1, VariableSymbol: "1042", InstructionID: 5}
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, column := range []string{`"column22":1`, `"column5":"1042"`, `"column17":5`} {
+		if !strings.Contains(string(data), column) {
+			t.Errorf("raw data %s does not contain %s", data, column)
+		}
+	}
+}
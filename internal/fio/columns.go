@@ -0,0 +1,155 @@
+package fio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// apiTransaction is one transaction of the API response. Every column is an object
+// {"value": ..., "name": "...", "id": N}, or null when the column is empty.
+// The columns follow the FIO API documentation (Specifikace API, "Transakce").
+type apiTransaction struct {
+	ID              intColumn    `json:"column22"` // ID pohybu
+	Date            textColumn   `json:"column0"`  // Datum, "2024-01-15+0100"
+	Amount          amountColumn `json:"column1"`  // Objem
+	AccountNumber   textColumn   `json:"column2"`  // Protiúčet
+	BankCode        textColumn   `json:"column3"`  // Kód banky
+	ConstantSymbol  textColumn   `json:"column4"`  // KS
+	VariableSymbol  textColumn   `json:"column5"`  // VS
+	SpecificSymbol  textColumn   `json:"column6"`  // SS
+	Identification  textColumn   `json:"column7"`  // Uživatelská identifikace
+	TransactionType textColumn   `json:"column8"`  // Typ
+	ExecutedBy      textColumn   `json:"column9"`  // Provedl
+	AccountName     textColumn   `json:"column10"` // Název protiúčtu
+	BankName        textColumn   `json:"column12"` // Název banky
+	Currency        textColumn   `json:"column14"` // Měna
+	Message         textColumn   `json:"column16"` // Zpráva pro příjemce
+	InstructionID   intColumn    `json:"column17"` // ID pokynu
+	Specification   textColumn   `json:"column18"` // Upřesnění
+	Comment         textColumn   `json:"column25"` // Komentář
+	BIC             textColumn   `json:"column26"` // BIC
+	PayerReference  textColumn   `json:"column27"` // Reference plátce
+}
+
+// transaction converts the API columns to a Transaction
+func (t apiTransaction) transaction() Transaction {
+	return Transaction{
+		ID:              int64(t.ID),
+		Date:            string(t.Date),
+		Amount:          float64(t.Amount),
+		Currency:        string(t.Currency),
+		AccountNumber:   string(t.AccountNumber),
+		AccountName:     string(t.AccountName),
+		BankCode:        string(t.BankCode),
+		BankName:        string(t.BankName),
+		ConstantSymbol:  string(t.ConstantSymbol),
+		VariableSymbol:  string(t.VariableSymbol),
+		SpecificSymbol:  string(t.SpecificSymbol),
+		Message:         string(t.Message),
+		Comment:         string(t.Comment),
+		TransactionType: string(t.TransactionType),
+		Identification:  string(t.Identification),
+		ExecutedBy:      string(t.ExecutedBy),
+		Specification:   string(t.Specification),
+		BIC:             string(t.BIC),
+		InstructionID:   int64(t.InstructionID),
+		PayerReference:  string(t.PayerReference),
+	}
+}
+
+// columnValue returns the raw "value" of a column object (nil for a null column
+// or value)
+func columnValue(data []byte) (json.RawMessage, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, nil
+	}
+	var column struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &column); err != nil {
+		return nil, fmt.Errorf("invalid column %s: %w", data, err)
+	}
+	if bytes.Equal(bytes.TrimSpace(column.Value), []byte("null")) {
+		return nil, nil
+	}
+	return column.Value, nil
+}
+
+// textColumn is a text column; FIO sends symbols and account numbers as a string
+// or as a number depending on the account, both are accepted
+type textColumn string
+
+func (c *textColumn) UnmarshalJSON(data []byte) error {
+	value, err := columnValue(data)
+	if err != nil || value == nil {
+		return err
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		*c = textColumn(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(value, &n); err != nil {
+		return fmt.Errorf("column value %s is neither text nor a number", value)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return fmt.Errorf("invalid number %s: %w", n, err)
+	}
+	*c = textColumn(strconv.FormatFloat(f, 'f', -1, 64))
+	return nil
+}
+
+// amountColumn is a decimal column (number, or text with a decimal point or comma)
+type amountColumn float64
+
+func (c *amountColumn) UnmarshalJSON(data []byte) error {
+	value, err := columnValue(data)
+	if err != nil || value == nil {
+		return err
+	}
+	var f float64
+	if err := json.Unmarshal(value, &f); err == nil {
+		*c = amountColumn(f)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return fmt.Errorf("column value %s is not an amount", value)
+	}
+	f, err = strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", "."), 64)
+	if err != nil {
+		return fmt.Errorf("column value %q is not an amount", s)
+	}
+	*c = amountColumn(f)
+	return nil
+}
+
+// intColumn is an ID column (number, or text with digits)
+type intColumn int64
+
+func (c *intColumn) UnmarshalJSON(data []byte) error {
+	value, err := columnValue(data)
+	if err != nil || value == nil {
+		return err
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		s = string(value)
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		// IDs come as JSON numbers, possibly written as 1.2345678e+10
+		f, ferr := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if ferr != nil || f != float64(int64(f)) {
+			return fmt.Errorf("column value %s is not an ID", value)
+		}
+		n = int64(f)
+	}
+	*c = intColumn(n)
+	return nil
+}
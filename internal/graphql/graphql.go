@@ -0,0 +1,484 @@
+// Package graphql implements the read-only part of GraphQL needed by the admin
+// frontend: queries with fields, aliases, arguments, variables and fragments.
+// Types and resolvers are declared in Go, there is no schema language and no
+// introspection apart from __typename. Mutations stay on the REST endpoints.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// ResolveFunc returns the value of a field. source is the value resolved for the
+// parent object (nil on the query root), args holds the arguments with defaults
+// applied. Object fields return a value (or a slice of values for lists) that
+// becomes the source of the nested fields.
+type ResolveFunc func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+
+// Field describes a field of an object type
+type Field struct {
+	// Type is the object type of the field; empty for scalars
+	Type string
+	// List marks fields returning a list. List fields are weighted by their
+	// "limit" argument when computing the query complexity; a negative limit is
+	// rejected.
+	List bool
+	// Args lists the accepted arguments with their defaults (nil = no default)
+	Args map[string]interface{}
+	// Cost is the complexity of resolving the field once, 1 when zero
+	Cost int
+	// Resolve computes the value; when nil the field is read from a
+	// map[string]interface{} source under its name
+	Resolve ResolveFunc
+}
+
+// Object is an object type: field definitions by name
+type Object map[string]*Field
+
+// Schema is a set of object types with a query root and query limits
+type Schema struct {
+	Types map[string]Object
+	Query string // name of the root type
+
+	MaxDepth      int // deepest allowed field nesting, 0 = unlimited
+	MaxComplexity int // highest allowed query complexity, 0 = unlimited
+}
+
+// Request is the body of a GraphQL HTTP request
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// Response is the result of a query. Data is nil when the query was rejected
+// before execution, otherwise failed fields are null and listed in Errors.
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is a query or field error
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Execute parses, validates and runs a query
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{err}}
+	}
+
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{err}}
+	}
+
+	e := &executor{schema: s, doc: doc}
+	if err := e.bindVariables(op, req.Variables); err != nil {
+		return &Response{Errors: []*Error{err}}
+	}
+	complexity, err := e.validate(s.Query, op.selections, 1)
+	if err != nil {
+		return &Response{Errors: []*Error{err}}
+	}
+	if s.MaxComplexity > 0 && complexity > s.MaxComplexity {
+		return &Response{Errors: []*Error{{
+			Message: fmt.Sprintf("query complexity %d exceeds the limit of %d, lower the limits of nested lists", complexity, s.MaxComplexity),
+		}}}
+	}
+
+	data := e.executeSelections(ctx, s.Query, op.selections, nil, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func selectOperation(doc *document, name string) (*operation, *Error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, &Error{Message: "operationName is required for documents with several queries"}
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, &Error{Message: fmt.Sprintf("unknown operation %q", name)}
+}
+
+type executor struct {
+	schema    *Schema
+	doc       *document
+	variables map[string]interface{}
+	declared  map[string]bool
+	errors    []*Error
+}
+
+// bindVariables applies defaults to the request variables
+func (e *executor) bindVariables(op *operation, values map[string]interface{}) *Error {
+	e.variables = make(map[string]interface{}, len(op.variables))
+	e.declared = make(map[string]bool, len(op.variables))
+	for _, def := range op.variables {
+		e.declared[def.name] = true
+		value, ok := values[def.name]
+		if !ok && def.hasDefault {
+			value, ok = def.defaultValue, true
+		}
+		if def.nonNull && value == nil {
+			return &Error{Message: fmt.Sprintf("variable $%s is required", def.name)}
+		}
+		if ok {
+			e.variables[def.name] = value
+		}
+	}
+	return nil
+}
+
+// fieldGroup is a field requested once or several times under the same
+// response key (e.g. directly and through a fragment)
+type fieldGroup struct {
+	key    string
+	fields []*field
+}
+
+// selections returns the merged selection sets of all fields in the group
+func (g *fieldGroup) selections() []*selection {
+	var selections []*selection
+	for _, f := range g.fields {
+		selections = append(selections, f.selections...)
+	}
+	return selections
+}
+
+// collectFields flattens fragments into fields grouped by response key, in the
+// order in which they appear in the query
+func (e *executor) collectFields(typeName string, selections []*selection, visited map[string]bool, groups []*fieldGroup) ([]*fieldGroup, *Error) {
+	var err *Error
+	for _, sel := range selections {
+		switch {
+		case sel.field != nil:
+			key := sel.field.responseKey()
+			var group *fieldGroup
+			for _, g := range groups {
+				if g.key == key {
+					group = g
+					break
+				}
+			}
+			if group == nil {
+				group = &fieldGroup{key: key}
+				groups = append(groups, group)
+			} else if group.fields[0].name != sel.field.name {
+				return nil, &Error{Message: fmt.Sprintf("fields %q and %q conflict under the name %q", group.fields[0].name, sel.field.name, key)}
+			}
+			group.fields = append(group.fields, sel.field)
+
+		case sel.spread != "":
+			if visited[sel.spread] {
+				continue
+			}
+			visited[sel.spread] = true
+			frag, ok := e.doc.fragments[sel.spread]
+			if !ok {
+				return nil, &Error{Message: fmt.Sprintf("unknown fragment %q", sel.spread)}
+			}
+			if err := e.checkTypeCondition(frag.typeCondition, typeName); err != nil {
+				return nil, err
+			}
+			if groups, err = e.collectFields(typeName, frag.selections, visited, groups); err != nil {
+				return nil, err
+			}
+
+		case sel.inline != nil:
+			if sel.inline.typeCondition != "" {
+				if err := e.checkTypeCondition(sel.inline.typeCondition, typeName); err != nil {
+					return nil, err
+				}
+			}
+			if groups, err = e.collectFields(typeName, sel.inline.selections, visited, groups); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return groups, nil
+}
+
+// checkTypeCondition allows fragments only on the type they are spread into;
+// there are no interfaces or unions to match against
+func (e *executor) checkTypeCondition(condition, typeName string) *Error {
+	if _, ok := e.schema.Types[condition]; !ok {
+		return &Error{Message: fmt.Sprintf("unknown type %q", condition)}
+	}
+	if condition != typeName {
+		return &Error{Message: fmt.Sprintf("fragment on %s cannot be spread into %s", condition, typeName)}
+	}
+	return nil
+}
+
+// validate checks the selections against the schema and returns their
+// complexity: every field costs its Cost plus the cost of its selections, which
+// is multiplied by the limit for lists
+func (e *executor) validate(typeName string, selections []*selection, depth int) (int, *Error) {
+	if e.schema.MaxDepth > 0 && depth > e.schema.MaxDepth {
+		return 0, &Error{Message: fmt.Sprintf("query is nested deeper than the limit of %d", e.schema.MaxDepth)}
+	}
+
+	groups, err := e.collectFields(typeName, selections, map[string]bool{}, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	complexity := 0
+	for _, group := range groups {
+		f := group.fields[0]
+		if f.name == "__typename" {
+			if len(f.selections) > 0 {
+				return 0, &Error{Message: "__typename has no fields to select"}
+			}
+			continue
+		}
+
+		def, ok := e.schema.Types[typeName][f.name]
+		if !ok {
+			return 0, &Error{Message: fmt.Sprintf("%s has no field %q", typeName, f.name)}
+		}
+		args, err := e.arguments(def, f)
+		if err != nil {
+			return 0, err
+		}
+
+		cost := def.Cost
+		if cost == 0 {
+			cost = 1
+		}
+
+		nested := group.selections()
+		if def.Type == "" {
+			if len(nested) > 0 {
+				return 0, &Error{Message: fmt.Sprintf("%s.%s is a scalar and has no fields to select", typeName, f.name)}
+			}
+		} else {
+			if len(nested) == 0 {
+				return 0, &Error{Message: fmt.Sprintf("%s.%s needs a selection of fields", typeName, f.name)}
+			}
+			nestedComplexity, err := e.validate(def.Type, nested, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			if def.List {
+				// A negative limit would mean no limit at all to the resolvers
+				if limit, ok := Int(args, "limit"); ok && limit < 0 {
+					return 0, &Error{Message: fmt.Sprintf("%s.%s limit must not be negative", typeName, f.name)}
+				}
+				nestedComplexity *= listLimit(args)
+			}
+			cost += nestedComplexity
+		}
+
+		complexity += cost
+		// Saturate instead of overflowing on absurd limits
+		if complexity > math.MaxInt32 {
+			complexity = math.MaxInt32
+		}
+	}
+	return complexity, nil
+}
+
+// listLimit is the number of items a list field can return
+func listLimit(args map[string]interface{}) int {
+	limit, ok := Int(args, "limit")
+	if !ok || limit < 1 {
+		return 1
+	}
+	if limit > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(limit)
+}
+
+// arguments resolves the arguments of a field: variables are substituted and
+// defaults applied for missing arguments
+func (e *executor) arguments(def *Field, f *field) (map[string]interface{}, *Error) {
+	args := make(map[string]interface{}, len(def.Args))
+	for name, value := range def.Args {
+		if value != nil {
+			args[name] = value
+		}
+	}
+	for _, arg := range f.arguments {
+		if _, ok := def.Args[arg.name]; !ok {
+			return nil, &Error{Message: fmt.Sprintf("field %q has no argument %q", f.name, arg.name)}
+		}
+		value, err := e.substitute(arg.value)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			// An explicit null (or unset variable) falls back to the default
+			continue
+		}
+		args[arg.name] = value
+	}
+	return args, nil
+}
+
+func (e *executor) substitute(value interface{}) (interface{}, *Error) {
+	switch v := value.(type) {
+	case variable:
+		if !e.declared[string(v)] {
+			return nil, &Error{Message: fmt.Sprintf("variable $%s is not declared", v)}
+		}
+		return e.variables[string(v)], nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			var err *Error
+			if list[i], err = e.substitute(item); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			var err *Error
+			if object[key], err = e.substitute(item); err != nil {
+				return nil, err
+			}
+		}
+		return object, nil
+	default:
+		return value, nil
+	}
+}
+
+// executeSelections resolves the fields of one object. The query has been
+// validated, errors here come from resolvers and null out the failing field.
+func (e *executor) executeSelections(ctx context.Context, typeName string, selections []*selection, source interface{}, path []interface{}) *orderedMap {
+	groups, _ := e.collectFields(typeName, selections, map[string]bool{}, nil)
+
+	result := &orderedMap{}
+	for _, group := range groups {
+		f := group.fields[0]
+		fieldPath := append(append([]interface{}{}, path...), group.key)
+
+		if f.name == "__typename" {
+			result.set(group.key, typeName)
+			continue
+		}
+
+		def := e.schema.Types[typeName][f.name]
+		args, _ := e.arguments(def, f)
+
+		var value interface{}
+		var err error
+		if def.Resolve != nil {
+			value, err = def.Resolve(ctx, source, args)
+		} else if object, ok := source.(map[string]interface{}); ok {
+			value = object[f.name]
+		}
+		if err != nil {
+			e.errors = append(e.errors, &Error{Message: err.Error(), Path: fieldPath})
+			result.set(group.key, nil)
+			continue
+		}
+
+		result.set(group.key, e.complete(ctx, def, group.selections(), value, fieldPath))
+	}
+	return result
+}
+
+// complete resolves the nested selections of an object or list value
+func (e *executor) complete(ctx context.Context, def *Field, selections []*selection, value interface{}, path []interface{}) interface{} {
+	if def.Type == "" || value == nil {
+		return value
+	}
+
+	if !def.List {
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil
+		}
+		return e.executeSelections(ctx, def.Type, selections, value, path)
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice {
+		e.errors = append(e.errors, &Error{Message: "resolver returned a non-list value", Path: path})
+		return nil
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = e.executeSelections(ctx, def.Type, selections, rv.Index(i).Interface(), append(append([]interface{}{}, path...), i))
+	}
+	return items
+}
+
+// orderedMap is a JSON object keeping the order of the requested fields
+type orderedMap struct {
+	keys   []string
+	values []interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Int reads an integer argument; JSON variables arrive as float64
+func Int(args map[string]interface{}, name string) (int64, bool) {
+	switch v := args[name].(type) {
+	case int64:
+		return v, true
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+			return 0, false
+		}
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// String reads a string (or enum) argument
+func String(args map[string]interface{}, name string) (string, bool) {
+	v, ok := args[name].(string)
+	return v, ok
+}
+
+// Bool reads a boolean argument
+func Bool(args map[string]interface{}, name string) (bool, bool) {
+	v, ok := args[name].(bool)
+	return v, ok
+}
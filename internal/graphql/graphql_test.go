@@ -0,0 +1,64 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestListLimit(t *testing.T) {
+	schema := &Schema{
+		Types: map[string]Object{
+			"Query": {
+				"items": {
+					Type: "Item",
+					List: true,
+					Args: map[string]interface{}{"limit": int64(10)},
+					Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+						limit, _ := Int(args, "limit")
+						items := []interface{}{}
+						for i := int64(0); i < limit; i++ {
+							items = append(items, map[string]interface{}{"id": i})
+						}
+						return items, nil
+					},
+				},
+			},
+			"Item": {"id": {}},
+		},
+		Query:         "Query",
+		MaxComplexity: 100,
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{"default limit", `{ items { id } }`, ""},
+		{"zero limit", `{ items(limit: 0) { id } }`, ""},
+		{"negative limit", `{ items(limit: -1) { id } }`, "must not be negative"},
+		{"negative limit in a variable", `query($n: Int) { items(limit: $n) { id } }`, "must not be negative"},
+		{"limit over the complexity", `{ items(limit: 1000) { id } }`, "exceeds the limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := schema.Execute(context.Background(), Request{
+				Query:     tt.query,
+				Variables: map[string]interface{}{"n": float64(-5)},
+			})
+			if tt.wantErr == "" {
+				if len(resp.Errors) > 0 {
+					t.Fatalf("Execute() errors = %v", resp.Errors[0])
+				}
+				return
+			}
+			if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tt.wantErr) {
+				t.Fatalf("Execute() errors = %v, want %q", resp.Errors, tt.wantErr)
+			}
+			if resp.Data != nil {
+				t.Errorf("Execute() ran a rejected query")
+			}
+		})
+	}
+}
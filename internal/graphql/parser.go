@@ -0,0 +1,460 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// document is a parsed query document
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	name       string
+	variables  []*variableDefinition
+	selections []*selection
+}
+
+type variableDefinition struct {
+	name         string
+	nonNull      bool
+	defaultValue interface{}
+	hasDefault   bool
+}
+
+// selection is a field, a fragment spread or an inline fragment
+type selection struct {
+	field  *field
+	spread string
+	inline *fragment
+}
+
+type field struct {
+	alias      string
+	name       string
+	arguments  []*argument
+	selections []*selection
+}
+
+// responseKey is the key of the field in the result
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type argument struct {
+	name  string
+	value interface{}
+}
+
+type fragment struct {
+	name          string
+	typeCondition string // empty for inline fragments without a type condition
+	selections    []*selection
+}
+
+// variable is a $name reference inside an argument value
+type variable string
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+// parse parses a query document; only the executable subset without directives
+// and block strings is accepted
+func parse(src string) (doc *document, err *Error) {
+	p := &parser{src: src}
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxErr, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, syntaxErr
+		}
+	}()
+
+	p.next()
+	doc = &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunct, "{"):
+			doc.operations = append(doc.operations, &operation{selections: p.parseSelectionSet()})
+		case p.peek(tokenName, "query"):
+			doc.operations = append(doc.operations, p.parseOperation())
+		case p.peek(tokenName, "mutation"), p.peek(tokenName, "subscription"):
+			p.fail("only queries are supported")
+		case p.peek(tokenName, "fragment"):
+			frag := p.parseFragmentDefinition()
+			if _, exists := doc.fragments[frag.name]; exists {
+				p.fail(fmt.Sprintf("fragment %q defined more than once", frag.name))
+			}
+			doc.fragments[frag.name] = frag
+		default:
+			p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, &Error{Message: "document contains no query"}
+	}
+	return doc, nil
+}
+
+func (p *parser) parseOperation() *operation {
+	p.expect(tokenName, "query")
+	op := &operation{}
+	if p.tok.kind == tokenName {
+		op.name = p.tok.value
+		p.next()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			op.variables = append(op.variables, p.parseVariableDefinition())
+		}
+	}
+	p.rejectDirectives()
+	op.selections = p.parseSelectionSet()
+	return op
+}
+
+func (p *parser) parseVariableDefinition() *variableDefinition {
+	p.expect(tokenPunct, "$")
+	def := &variableDefinition{name: p.parseName()}
+	p.expect(tokenPunct, ":")
+	def.nonNull = p.parseType()
+	if p.skip("=") {
+		def.defaultValue = p.parseValue(true)
+		def.hasDefault = true
+	}
+	return def
+}
+
+// parseType skips a type reference; the types are checked by the resolvers
+// when reading arguments. Reports whether the outer type is non-null.
+func (p *parser) parseType() bool {
+	if p.skip("[") {
+		p.parseType()
+		p.expect(tokenPunct, "]")
+	} else {
+		p.parseName()
+	}
+	return p.skip("!")
+}
+
+func (p *parser) parseFragmentDefinition() *fragment {
+	p.expect(tokenName, "fragment")
+	frag := &fragment{name: p.parseName()}
+	if frag.name == "on" {
+		p.fail("fragment cannot be named \"on\"")
+	}
+	p.expect(tokenName, "on")
+	frag.typeCondition = p.parseName()
+	p.rejectDirectives()
+	frag.selections = p.parseSelectionSet()
+	return frag
+}
+
+func (p *parser) parseSelectionSet() []*selection {
+	p.expect(tokenPunct, "{")
+	var selections []*selection
+	for !p.skip("}") {
+		selections = append(selections, p.parseSelection())
+	}
+	if len(selections) == 0 {
+		p.fail("empty selection set")
+	}
+	return selections
+}
+
+func (p *parser) parseSelection() *selection {
+	if p.skip("...") {
+		if p.tok.kind == tokenName && p.tok.value != "on" {
+			name := p.parseName()
+			p.rejectDirectives()
+			return &selection{spread: name}
+		}
+		inline := &fragment{}
+		if p.skip("on") {
+			inline.typeCondition = p.parseName()
+		}
+		p.rejectDirectives()
+		inline.selections = p.parseSelectionSet()
+		return &selection{inline: inline}
+	}
+
+	f := &field{name: p.parseName()}
+	if p.skip(":") {
+		f.alias = f.name
+		f.name = p.parseName()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			arg := &argument{name: p.parseName()}
+			p.expect(tokenPunct, ":")
+			arg.value = p.parseValue(false)
+			f.arguments = append(f.arguments, arg)
+		}
+	}
+	p.rejectDirectives()
+	if p.peek(tokenPunct, "{") {
+		f.selections = p.parseSelectionSet()
+	}
+	return &selection{field: f}
+}
+
+// parseValue parses an argument value; constant values (variable defaults)
+// cannot reference variables
+func (p *parser) parseValue(constant bool) interface{} {
+	tok := p.tok
+	switch tok.kind {
+	case tokenPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				p.fail("variable not allowed in a default value")
+			}
+			p.next()
+			return variable(p.parseName())
+		case "[":
+			p.next()
+			list := []interface{}{}
+			for !p.skip("]") {
+				list = append(list, p.parseValue(constant))
+			}
+			return list
+		case "{":
+			p.next()
+			object := map[string]interface{}{}
+			for !p.skip("}") {
+				name := p.parseName()
+				p.expect(tokenPunct, ":")
+				object[name] = p.parseValue(constant)
+			}
+			return object
+		}
+	case tokenInt:
+		p.next()
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			p.failAt(tok.pos, fmt.Sprintf("integer %s out of range", tok.value))
+		}
+		return n
+	case tokenFloat:
+		p.next()
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			p.failAt(tok.pos, fmt.Sprintf("invalid number %s", tok.value))
+		}
+		return f
+	case tokenString:
+		p.next()
+		return tok.value
+	case tokenName:
+		p.next()
+		switch tok.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		default:
+			// Enum values are passed to resolvers as strings
+			return tok.value
+		}
+	}
+	p.unexpected()
+	return nil
+}
+
+func (p *parser) parseName() string {
+	if p.tok.kind != tokenName {
+		p.unexpected()
+	}
+	name := p.tok.value
+	p.next()
+	return name
+}
+
+func (p *parser) rejectDirectives() {
+	if p.peek(tokenPunct, "@") {
+		p.fail("directives are not supported")
+	}
+}
+
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+// skip consumes the punctuator or keyword if it is next
+func (p *parser) skip(value string) bool {
+	if (p.tok.kind == tokenPunct || p.tok.kind == tokenName) && p.tok.value == value {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, value string) {
+	if !p.peek(kind, value) {
+		p.fail(fmt.Sprintf("expected %q, found %s", value, p.describe()))
+	}
+	p.next()
+}
+
+func (p *parser) unexpected() {
+	p.fail("unexpected " + p.describe())
+}
+
+func (p *parser) describe() string {
+	if p.tok.kind == tokenEOF {
+		return "end of query"
+	}
+	return strconv.Quote(p.tok.value)
+}
+
+func (p *parser) fail(msg string) {
+	p.failAt(p.tok.pos, msg)
+}
+
+func (p *parser) failAt(pos int, msg string) {
+	line := 1 + strings.Count(p.src[:pos], "\n")
+	column := pos - strings.LastIndex(p.src[:pos], "\n")
+	panic(&Error{Message: fmt.Sprintf("syntax error at line %d, column %d: %s", line, column, msg)})
+}
+
+// next reads the following token, skipping whitespace, commas and comments
+func (p *parser) next() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+		} else if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		} else if strings.HasPrefix(p.src[p.pos:], "\uFEFF") {
+			p.pos += len("\uFEFF")
+		} else {
+			break
+		}
+	}
+
+	start := p.pos
+	if start >= len(p.src) {
+		p.tok = token{kind: tokenEOF, pos: start}
+		return
+	}
+
+	c := p.src[start]
+	switch {
+	case strings.ContainsRune("!$()[]{}:=@|&", rune(c)):
+		p.pos++
+		p.tok = token{kind: tokenPunct, value: string(c), pos: start}
+	case strings.HasPrefix(p.src[start:], "..."):
+		p.pos += 3
+		p.tok = token{kind: tokenPunct, value: "...", pos: start}
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for p.pos < len(p.src) && isNameChar(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok = token{kind: tokenName, value: p.src[start:p.pos], pos: start}
+	case c == '-' || c >= '0' && c <= '9':
+		p.tok = p.lexNumber()
+	case c == '"':
+		p.tok = p.lexString()
+	default:
+		p.failAt(start, fmt.Sprintf("unexpected character %q", c))
+	}
+}
+
+func (p *parser) lexNumber() token {
+	start := p.pos
+	kind := tokenInt
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() {
+		from := p.pos
+		for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			p.pos++
+		}
+		if p.pos == from {
+			p.failAt(start, "invalid number")
+		}
+	}
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		kind = tokenFloat
+		p.pos++
+		digits()
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		kind = tokenFloat
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+	}
+	if p.pos < len(p.src) && isNameChar(p.src[p.pos]) {
+		p.failAt(start, "invalid number")
+	}
+	return token{kind: kind, value: p.src[start:p.pos], pos: start}
+}
+
+// lexString reads a quoted string; the escapes are the same as in JSON
+func (p *parser) lexString() token {
+	start := p.pos
+	if strings.HasPrefix(p.src[start:], `"""`) {
+		p.failAt(start, "block strings are not supported")
+	}
+	p.pos++
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			p.failAt(start, "unterminated string")
+		}
+		c := p.src[p.pos]
+		p.pos++
+		if c == '\\' {
+			p.pos++
+		} else if c == '"' {
+			break
+		}
+	}
+
+	var value string
+	if err := json.Unmarshal([]byte(p.src[start:p.pos]), &value); err != nil {
+		p.failAt(start, "invalid string")
+	}
+	return token{kind: tokenString, value: value, pos: start}
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/base48/member-portal/internal/accounting"
+)
+
+// AdminAccountingExportHandler exports payments received, fees and one-off charges of
+// a month or a whole year for the accounting system, as CSV or an XML import of
+// Pohoda or Money S3
+// GET /admin/accounting/export?year=2026&month=3&format=csv
+func (h *Handler) AdminAccountingExportHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	period := accounting.Period{Year: time.Now().Year()}
+	if parsed, err := strconv.Atoi(query.Get("year")); err == nil && parsed > 2000 && parsed < 3000 {
+		period.Year = parsed
+	}
+	if month := query.Get("month"); month != "" {
+		parsed, err := strconv.Atoi(month)
+		if err != nil || parsed < 0 || parsed > 12 {
+			http.Error(w, "Invalid month", http.StatusBadRequest)
+			return
+		}
+		period.Month = parsed
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = accounting.FormatCSV
+	}
+	if format == accounting.FormatPohoda && h.config.InvoiceIssuerCompanyID == "" {
+		// Pohoda imports a data pack only into the accounting unit with its IČO
+		http.Error(w, "INVOICE_ISSUER_COMPANY_ID not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	entries, err := accounting.Load(r.Context(), h.queries, period)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var data []byte
+	var contentType, filename string
+	switch format {
+	case accounting.FormatCSV:
+		data, err = accounting.EncodeCSV(entries)
+		contentType = "text/csv; charset=utf-8"
+		filename = fmt.Sprintf("base48-ucetnictvi-%s.csv", period)
+	case accounting.FormatPohoda:
+		data, err = accounting.EncodePohoda(entries, period, h.config.InvoiceIssuerCompanyID)
+		contentType = "application/xml; charset=utf-8"
+		filename = fmt.Sprintf("base48-pohoda-%s.xml", period)
+	case accounting.FormatMoney:
+		data, err = accounting.EncodeMoney(entries, period)
+		contentType = "application/xml; charset=utf-8"
+		filename = fmt.Sprintf("base48-money-%s.xml", period)
+	default:
+		http.Error(w, "Invalid format (csv, pohoda or money)", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Write(data)
+}
@@ -27,24 +27,6 @@ type AdminRoleResponse struct {
 	Error   string `json:"error,omitempty"`
 }
 
-// RequireAdmin middleware ensures user has memberportal_admin role
-func (h *Handler) RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		user := h.auth.GetUser(r)
-		if user == nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		if !user.IsAdmin() {
-			http.Error(w, "Forbidden - admin access required", http.StatusForbidden)
-			return
-		}
-
-		next(w, r)
-	}
-}
-
 // AdminAssignRoleHandler assigns a role to a user (admin only)
 // POST /api/admin/roles/assign
 // Body: {"user_id": "keycloak-user-id", "role_name": "active_member"}
@@ -72,14 +54,8 @@ func (h *Handler) AdminAssignRoleHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	accessToken, err := h.serviceAccount.GetAccessToken(r.Context())
-	if err != nil {
-		h.jsonError(w, fmt.Sprintf("Failed to get service account token: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	// Create Keycloak client with service account token
-	kcClient := keycloak.NewClient(h.config, accessToken)
+	// Keycloak client with the cached service account token
+	kcClient := keycloak.NewClientWithTokenProvider(h.config, h.serviceAccount)
 
 	// Assign the role
 	if err := kcClient.AssignRoleToUser(r.Context(), req.UserID, req.RoleName); err != nil {
@@ -87,6 +63,9 @@ func (h *Handler) AdminAssignRoleHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Cached role lists are stale now
+	h.roleCache.Invalidate()
+
 	h.jsonSuccess(w, fmt.Sprintf("Role %s assigned to user %s", req.RoleName, req.UserID))
 }
 
@@ -117,14 +96,8 @@ func (h *Handler) AdminRemoveRoleHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	accessToken, err := h.serviceAccount.GetAccessToken(r.Context())
-	if err != nil {
-		h.jsonError(w, fmt.Sprintf("Failed to get service account token: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	// Create Keycloak client with service account token
-	kcClient := keycloak.NewClient(h.config, accessToken)
+	// Keycloak client with the cached service account token
+	kcClient := keycloak.NewClientWithTokenProvider(h.config, h.serviceAccount)
 
 	// Remove the role
 	if err := kcClient.RemoveRoleFromUser(r.Context(), req.UserID, req.RoleName); err != nil {
@@ -132,6 +105,9 @@ func (h *Handler) AdminRemoveRoleHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Cached role lists are stale now
+	h.roleCache.Invalidate()
+
 	h.jsonSuccess(w, fmt.Sprintf("Role %s removed from user %s", req.RoleName, req.UserID))
 }
 
@@ -150,14 +126,8 @@ func (h *Handler) AdminGetUserRolesHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	accessToken, err := h.serviceAccount.GetAccessToken(r.Context())
-	if err != nil {
-		h.jsonError(w, fmt.Sprintf("Failed to get service account token: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	// Create Keycloak client with service account token
-	kcClient := keycloak.NewClient(h.config, accessToken)
+	// Keycloak client with the cached service account token
+	kcClient := keycloak.NewClientWithTokenProvider(h.config, h.serviceAccount)
 
 	// Get user roles
 	roles, err := kcClient.GetUserRoles(r.Context(), userID)
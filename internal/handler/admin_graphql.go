@@ -0,0 +1,395 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/graphql"
+	"github.com/base48/member-portal/internal/money"
+)
+
+const (
+	// graphQLMaxDepth allows e.g. users → payments → user → fees → level → name
+	graphQLMaxDepth = 6
+
+	// graphQLMaxComplexity is roughly the number of resolved fields; all members
+	// with their balance and last dozen payments fit comfortably
+	graphQLMaxComplexity = 50000
+
+	// maxGraphQLBody limits the size of the request body
+	maxGraphQLBody = 64 << 10
+)
+
+// AdminGraphQLHandler runs read-only GraphQL queries over members, payments,
+// fees and levels, so admin views can fetch exactly the data they show
+// POST /api/admin/graphql
+func (h *Handler) AdminGraphQLHandler(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLBody)).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp := h.adminGraphQLSchema().Execute(r.Context(), req)
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Data == nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// adminGraphQLSchema builds the schema for one request; levels are loaded once
+// and shared by all users and fees in the response
+func (h *Handler) adminGraphQLSchema() *graphql.Schema {
+	var levels map[int64]db.Level
+	levelByID := func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+		if levels == nil {
+			all, err := h.queries.ListAllLevels(ctx)
+			if err != nil {
+				return nil, err
+			}
+			levels = make(map[int64]db.Level, len(all))
+			for _, level := range all {
+				levels[level.ID] = level
+			}
+		}
+		level, ok := levels[source.(map[string]interface{})["levelId"].(int64)]
+		if !ok {
+			return nil, nil
+		}
+		return levelObject(level), nil
+	}
+
+	userByID := func(ctx context.Context, id int64) (interface{}, error) {
+		user, err := h.queries.GetUserByID(ctx, id)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return userObject(user), nil
+	}
+
+	query := graphql.Object{
+		"users": {
+			Type: "User",
+			List: true,
+			Args: map[string]interface{}{"state": nil, "limit": int64(500)},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				var users []db.User
+				var err error
+				if state, ok := graphql.String(args, "state"); ok {
+					users, err = h.queries.ListUsersByState(ctx, state)
+				} else {
+					users, err = h.queries.ListUsers(ctx)
+				}
+				if err != nil {
+					return nil, err
+				}
+				objects := make([]map[string]interface{}, 0, len(users))
+				for _, user := range limitRows(users, args) {
+					objects = append(objects, userObject(user))
+				}
+				return objects, nil
+			},
+		},
+		"user": {
+			Type: "User",
+			Args: map[string]interface{}{"id": nil, "email": nil},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				if id, ok := graphql.Int(args, "id"); ok {
+					return userByID(ctx, id)
+				}
+				email, ok := graphql.String(args, "email")
+				if !ok {
+					return nil, fmt.Errorf("id or email is required")
+				}
+				user, err := h.queries.GetUserByEmail(ctx, email)
+				if err == sql.ErrNoRows {
+					return nil, nil
+				} else if err != nil {
+					return nil, err
+				}
+				return userObject(user), nil
+			},
+		},
+		"payments": {
+			Type: "Payment",
+			List: true,
+			Args: map[string]interface{}{"unassigned": false, "limit": int64(100)},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				limit, _ := graphql.Int(args, "limit")
+				var payments []db.Payment
+				var err error
+				if unassigned, _ := graphql.Bool(args, "unassigned"); unassigned {
+					payments, err = h.queries.ListUnassignedPayments(ctx)
+				} else {
+					payments, err = h.queries.ListRecentPayments(ctx, limit)
+				}
+				if err != nil {
+					return nil, err
+				}
+				return paymentObjects(limitRows(payments, args)), nil
+			},
+		},
+		"levels": {
+			Type: "Level",
+			List: true,
+			Args: map[string]interface{}{"all": false, "limit": int64(100)},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				var rows []db.Level
+				var err error
+				if all, _ := graphql.Bool(args, "all"); all {
+					rows, err = h.queries.ListAllLevels(ctx)
+				} else {
+					rows, err = h.queries.ListLevels(ctx)
+				}
+				if err != nil {
+					return nil, err
+				}
+				objects := make([]map[string]interface{}, 0, len(rows))
+				for _, level := range limitRows(rows, args) {
+					objects = append(objects, levelObject(level))
+				}
+				return objects, nil
+			},
+		},
+	}
+
+	user := graphql.Object{
+		"id":                {},
+		"email":             {},
+		"username":          {},
+		"realname":          {},
+		"phone":             {},
+		"altContact":        {},
+		"state":             {},
+		"levelId":           {},
+		"levelActualAmount": {},
+		"paymentsId":        {},
+		"dateJoined":        {},
+		"keysGranted":       {},
+		"keysReturned":      {},
+		"isCouncil":         {},
+		"isStaff":           {},
+		"keycloakId":        {},
+		"level":             {Type: "Level", Resolve: levelByID},
+		"balance": {
+			Cost: 2,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				id := source.(map[string]interface{})["id"].(int64)
+				balance, err := h.queries.GetUserBalance(ctx, db.GetUserBalanceParams{
+					UserID:   sql.NullInt64{Int64: id, Valid: true},
+					UserID_2: id,
+					UserID_3: sql.NullInt64{Int64: id, Valid: true},
+					UserID_4: id,
+					UserID_5: id,
+				})
+				return money.Amount(balance).Float64(), err
+			},
+		},
+		"roles": {
+			Cost: 2,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				keycloakID, ok := source.(map[string]interface{})["keycloakId"].(string)
+				if !ok {
+					return []string{}, nil
+				}
+				kcClient, err := h.keycloakClient()
+				if err != nil {
+					return nil, err
+				}
+				userRoles, err := h.roleCache.Get(ctx, kcClient)
+				if err != nil {
+					return nil, err
+				}
+				if roles := userRoles[keycloakID]; roles != nil {
+					return roles, nil
+				}
+				return []string{}, nil
+			},
+		},
+		"payments": {
+			Type: "Payment",
+			List: true,
+			Args: map[string]interface{}{"limit": int64(100)},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				id := source.(map[string]interface{})["id"].(int64)
+				payments, err := h.queries.ListPaymentsByUser(ctx, sql.NullInt64{Int64: id, Valid: true})
+				if err != nil {
+					return nil, err
+				}
+				return paymentObjects(limitRows(payments, args)), nil
+			},
+		},
+		"fees": {
+			Type: "Fee",
+			List: true,
+			Args: map[string]interface{}{"limit": int64(100)},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				id := source.(map[string]interface{})["id"].(int64)
+				rows, err := h.queries.ListFeesByUser(ctx, id)
+				if err != nil {
+					return nil, err
+				}
+				objects := make([]map[string]interface{}, 0, len(rows))
+				for _, fee := range limitRows(rows, args) {
+					objects = append(objects, map[string]interface{}{
+						"id":          fee.ID,
+						"userId":      fee.UserID,
+						"levelId":     fee.LevelID,
+						"periodStart": fee.PeriodStart.Format("2006-01"),
+						"amount":      fee.Amount,
+					})
+				}
+				return objects, nil
+			},
+		},
+	}
+
+	payment := graphql.Object{
+		"id":             {},
+		"userId":         {},
+		"date":           {},
+		"amount":         {},
+		"kind":           {},
+		"kindId":         {},
+		"localAccount":   {},
+		"remoteAccount":  {},
+		"identification": {},
+		"staffComment":   {},
+		"projectId":      {},
+		"reversalOf":     {},
+		"user": {
+			Type: "User",
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				id, ok := source.(map[string]interface{})["userId"].(int64)
+				if !ok {
+					return nil, nil
+				}
+				return userByID(ctx, id)
+			},
+		},
+	}
+
+	fee := graphql.Object{
+		"id":          {},
+		"userId":      {},
+		"levelId":     {},
+		"periodStart": {},
+		"amount":      {},
+		"level":       {Type: "Level", Resolve: levelByID},
+		"user": {
+			Type: "User",
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return userByID(ctx, source.(map[string]interface{})["userId"].(int64))
+			},
+		},
+	}
+
+	level := graphql.Object{
+		"id":     {},
+		"name":   {},
+		"amount": {},
+		"active": {},
+	}
+
+	return &graphql.Schema{
+		Types: map[string]graphql.Object{
+			"Query":   query,
+			"User":    user,
+			"Payment": payment,
+			"Fee":     fee,
+			"Level":   level,
+		},
+		Query:         "Query",
+		MaxDepth:      graphQLMaxDepth,
+		MaxComplexity: graphQLMaxComplexity,
+	}
+}
+
+// limitRows applies the limit argument of a list field
+func limitRows[T any](rows []T, args map[string]interface{}) []T {
+	if limit, ok := graphql.Int(args, "limit"); ok && limit >= 0 && int64(len(rows)) > limit {
+		return rows[:limit]
+	}
+	return rows
+}
+
+func userObject(u db.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                u.ID,
+		"email":             u.Email,
+		"username":          nullStringValue(u.Username),
+		"realname":          nullStringValue(u.Realname),
+		"phone":             nullStringValue(u.Phone),
+		"altContact":        nullStringValue(u.AltContact),
+		"state":             u.State,
+		"levelId":           u.LevelID,
+		"levelActualAmount": u.LevelActualAmount,
+		"paymentsId":        nullStringValue(u.PaymentsID),
+		"dateJoined":        u.DateJoined.Format("2006-01-02"),
+		"keysGranted":       nullDateValue(u.KeysGranted),
+		"keysReturned":      nullDateValue(u.KeysReturned),
+		"isCouncil":         u.IsCouncil,
+		"isStaff":           u.IsStaff,
+		"keycloakId":        nullStringValue(u.KeycloakID),
+	}
+}
+
+func paymentObjects(payments []db.Payment) []map[string]interface{} {
+	objects := make([]map[string]interface{}, 0, len(payments))
+	for _, p := range payments {
+		objects = append(objects, map[string]interface{}{
+			"id":             p.ID,
+			"userId":         nullInt64Value(p.UserID),
+			"date":           p.Date.Format("2006-01-02"),
+			"amount":         p.Amount,
+			"kind":           p.Kind,
+			"kindId":         p.KindID,
+			"localAccount":   p.LocalAccount,
+			"remoteAccount":  p.RemoteAccount,
+			"identification": p.Identification,
+			"staffComment":   nullStringValue(p.StaffComment),
+			"projectId":      nullInt64Value(p.ProjectID),
+			"reversalOf":     nullInt64Value(p.ReversalOf),
+		})
+	}
+	return objects
+}
+
+func levelObject(l db.Level) map[string]interface{} {
+	return map[string]interface{}{
+		"id":     l.ID,
+		"name":   l.Name,
+		"amount": l.Amount,
+		"active": l.Active,
+	}
+}
+
+// nullStringValue, nullInt64Value and nullDateValue turn NULL into a GraphQL null
+func nullStringValue(s sql.NullString) interface{} {
+	if !s.Valid {
+		return nil
+	}
+	return s.String
+}
+
+func nullInt64Value(n sql.NullInt64) interface{} {
+	if !n.Valid {
+		return nil
+	}
+	return n.Int64
+}
+
+func nullDateValue(t sql.NullTime) interface{} {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.Format("2006-01-02")
+}
@@ -0,0 +1,380 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/keycloak"
+	"github.com/go-chi/chi/v5"
+)
+
+// Directions of a consistency panel resolution
+const (
+	syncFromKeycloak = "from_keycloak" // overwrite portal data with Keycloak
+	syncToKeycloak   = "to_keycloak"   // overwrite Keycloak with portal data
+)
+
+// keycloakDisabledStates are membership states whose Keycloak account is expected
+// to be disabled; everyone else should be able to log in (e.g. to pay off debt)
+var keycloakDisabledStates = map[string]bool{
+	"rejected": true,
+	"exmember": true,
+}
+
+// KeycloakDiffField is one row of the admin consistency panel
+type KeycloakDiffField struct {
+	Field    string `json:"field"` // email, name, username, enabled, roles
+	Label    string `json:"label"`
+	Portal   string `json:"portal"`         // portal value, or the value expected from membership state
+	Keycloak string `json:"keycloak"`       // actual value in Keycloak
+	Note     string `json:"note,omitempty"` // why the portal side has this value
+	Mismatch bool   `json:"mismatch"`
+	CanPull  bool   `json:"can_pull"` // "sync from Keycloak" makes sense for this field
+	CanPush  bool   `json:"can_push"` // "push to Keycloak" makes sense for this field
+}
+
+// KeycloakDiff compares a member's portal record with their Keycloak account
+type KeycloakDiff struct {
+	Linked     bool                `json:"linked"`
+	Error      string              `json:"error,omitempty"`
+	Fields     []KeycloakDiffField `json:"fields"`
+	Mismatches int                 `json:"mismatches"`
+}
+
+// keycloakFullName joins first and last name the way realname is stored in the portal
+func keycloakFullName(kcUser *keycloak.User) string {
+	return strings.TrimSpace(kcUser.FirstName + " " + kcUser.LastName)
+}
+
+// expectedStateRoles returns the roles managed by MEMBERSHIP_STATE_ROLES and the
+// one the member should have in their current state ("" if none)
+func (h *Handler) expectedStateRoles(state string) (managed []string, wanted string) {
+	seen := make(map[string]bool)
+	for _, role := range h.config.MembershipStateRoles {
+		if !seen[role] {
+			seen[role] = true
+			managed = append(managed, role)
+		}
+	}
+	sort.Strings(managed)
+	return managed, h.config.MembershipStateRoles[state]
+}
+
+// buildKeycloakDiff loads the member's Keycloak account and compares it field by field.
+// Keycloak errors are reported in the result, not returned, so the profile page still renders.
+func (h *Handler) buildKeycloakDiff(ctx context.Context, dbUser *db.User) KeycloakDiff {
+	diff := KeycloakDiff{Fields: []KeycloakDiffField{}}
+
+	if !dbUser.KeycloakID.Valid || dbUser.KeycloakID.String == "" {
+		return diff
+	}
+	diff.Linked = true
+
+	kcClient, err := h.keycloakClient()
+	if err != nil {
+		diff.Error = err.Error()
+		return diff
+	}
+
+	kcUser, err := kcClient.GetUser(ctx, dbUser.KeycloakID.String)
+	if err != nil {
+		diff.Error = err.Error()
+		return diff
+	}
+
+	roles, err := kcClient.GetUserRoles(ctx, dbUser.KeycloakID.String)
+	if err != nil {
+		diff.Error = err.Error()
+		return diff
+	}
+
+	diff.Fields = append(diff.Fields,
+		KeycloakDiffField{
+			Field:    "email",
+			Label:    "Email",
+			Portal:   dbUser.Email,
+			Keycloak: kcUser.Email,
+			Mismatch: !strings.EqualFold(dbUser.Email, kcUser.Email),
+			CanPull:  kcUser.Email != "",
+			CanPush:  true,
+		},
+		KeycloakDiffField{
+			Field:    "name",
+			Label:    "Jméno",
+			Portal:   dbUser.Realname.String,
+			Keycloak: keycloakFullName(kcUser),
+			Mismatch: strings.TrimSpace(dbUser.Realname.String) != keycloakFullName(kcUser),
+			CanPull:  true,
+			CanPush:  true,
+		},
+		// Keycloak usernames are usually not editable, so the portal only follows them
+		KeycloakDiffField{
+			Field:    "username",
+			Label:    "Přezdívka",
+			Portal:   dbUser.Username.String,
+			Keycloak: kcUser.Username,
+			Mismatch: dbUser.Username.String != kcUser.Username,
+			CanPull:  kcUser.Username != "",
+		},
+	)
+
+	expectEnabled := !keycloakDisabledStates[dbUser.State]
+	diff.Fields = append(diff.Fields, KeycloakDiffField{
+		Field:    "enabled",
+		Label:    "Účet povolen",
+		Portal:   yesNo(expectEnabled),
+		Keycloak: yesNo(kcUser.Enabled),
+		Note:     "podle stavu " + dbUser.State,
+		Mismatch: expectEnabled != kcUser.Enabled,
+		CanPush:  true,
+	})
+
+	// Only roles managed by MEMBERSHIP_STATE_ROLES are compared, others are set by hand
+	managed, wanted := h.expectedStateRoles(dbUser.State)
+	if len(managed) > 0 {
+		isManaged := make(map[string]bool, len(managed))
+		for _, role := range managed {
+			isManaged[role] = true
+		}
+		var actual []string
+		for _, role := range roles {
+			if isManaged[role.Name] {
+				actual = append(actual, role.Name)
+			}
+		}
+		sort.Strings(actual)
+
+		var expected []string
+		if wanted != "" {
+			expected = []string{wanted}
+		}
+
+		diff.Fields = append(diff.Fields, KeycloakDiffField{
+			Field:    "roles",
+			Label:    "Role",
+			Portal:   strings.Join(expected, ", "),
+			Keycloak: strings.Join(actual, ", "),
+			Note:     "podle stavu " + dbUser.State,
+			Mismatch: strings.Join(expected, ",") != strings.Join(actual, ","),
+			CanPush:  true,
+		})
+	}
+
+	for _, field := range diff.Fields {
+		if field.Mismatch {
+			diff.Mismatches++
+		}
+	}
+	return diff
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "ano"
+	}
+	return "ne"
+}
+
+// KeycloakResolveRequest is the request body for resolving a consistency panel mismatch
+type KeycloakResolveRequest struct {
+	Field     string `json:"field"`
+	Direction string `json:"direction"` // from_keycloak or to_keycloak
+}
+
+// AdminResolveKeycloakDiffHandler resolves one mismatch between the portal and Keycloak,
+// either by copying the Keycloak value to the portal or by pushing the portal value
+// (or the value expected from membership state) to Keycloak
+// POST /api/admin/users/{id}/keycloak/resolve
+// Body: {"field": "email", "direction": "from_keycloak"}
+func (h *Handler) AdminResolveKeycloakDiffHandler(w http.ResponseWriter, r *http.Request) {
+	user := h.auth.GetUser(r)
+
+	ctx := r.Context()
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req KeycloakResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Direction != syncFromKeycloak && req.Direction != syncToKeycloak {
+		h.jsonError(w, "direction must be from_keycloak or to_keycloak", http.StatusBadRequest)
+		return
+	}
+
+	targetDBUser, err := h.queries.GetUserByID(ctx, userID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if !targetDBUser.KeycloakID.Valid || targetDBUser.KeycloakID.String == "" {
+		h.jsonError(w, "User is not linked to a Keycloak account", http.StatusBadRequest)
+		return
+	}
+	keycloakID := targetDBUser.KeycloakID.String
+
+	adminDBUser := DBUserFrom(ctx)
+
+	kcClient, err := h.keycloakClient()
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Service account error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	kcUser, err := kcClient.GetUser(ctx, keycloakID)
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Keycloak error: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	var change string
+	if req.Direction == syncFromKeycloak {
+		params := db.UpdateUserIdentityParams{
+			Email:    targetDBUser.Email,
+			Username: targetDBUser.Username,
+			Realname: targetDBUser.Realname,
+			ID:       targetDBUser.ID,
+		}
+
+		switch req.Field {
+		case "email":
+			if kcUser.Email == "" {
+				h.jsonError(w, "Keycloak account has no email", http.StatusBadRequest)
+				return
+			}
+			params.Email = kcUser.Email
+			change = fmt.Sprintf("email %s -> %s", targetDBUser.Email, kcUser.Email)
+		case "name":
+			name := keycloakFullName(kcUser)
+			params.Realname = sql.NullString{String: name, Valid: name != ""}
+			change = fmt.Sprintf("name %q -> %q", targetDBUser.Realname.String, name)
+		case "username":
+			if kcUser.Username == "" {
+				h.jsonError(w, "Keycloak account has no username", http.StatusBadRequest)
+				return
+			}
+			params.Username = sql.NullString{String: kcUser.Username, Valid: true}
+			change = fmt.Sprintf("username %q -> %q", targetDBUser.Username.String, kcUser.Username)
+		default:
+			h.jsonError(w, fmt.Sprintf("Field %q cannot be synced from Keycloak", req.Field), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.UpdateUserIdentity(ctx, params); err != nil {
+			// Most likely the email is already used by another member
+			h.jsonError(w, fmt.Sprintf("Failed to update user: %v", err), http.StatusConflict)
+			return
+		}
+	} else {
+		switch req.Field {
+		case "email":
+			err = kcClient.UpdateUserProfile(ctx, keycloakID, targetDBUser.Email, kcUser.FirstName, kcUser.LastName)
+			change = fmt.Sprintf("email %s -> %s", kcUser.Email, targetDBUser.Email)
+		case "name":
+			firstName, lastName, _ := strings.Cut(strings.TrimSpace(targetDBUser.Realname.String), " ")
+			err = kcClient.UpdateUserProfile(ctx, keycloakID, kcUser.Email, firstName, strings.TrimSpace(lastName))
+			change = fmt.Sprintf("name %q -> %q", keycloakFullName(kcUser), targetDBUser.Realname.String)
+		case "enabled":
+			// Locking yourself out has to be done in the Keycloak console
+			if keycloakDisabledStates[targetDBUser.State] {
+				if keycloakID == user.ID {
+					h.jsonError(w, "You cannot disable your own account", http.StatusBadRequest)
+					return
+				}
+				err = kcClient.DisableUser(ctx, keycloakID)
+				change = "account disabled"
+			} else {
+				err = kcClient.EnableUser(ctx, keycloakID)
+				change = "account enabled"
+			}
+		case "roles":
+			change, err = h.pushStateRoles(ctx, kcClient, keycloakID, targetDBUser.State)
+			h.roleCache.Invalidate()
+		default:
+			h.jsonError(w, fmt.Sprintf("Field %q cannot be pushed to Keycloak", req.Field), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			h.jsonError(w, fmt.Sprintf("Keycloak error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		// Update the cached user so the users page shows the new state right away
+		if kcUser, err := kcClient.GetUser(ctx, keycloakID); err == nil {
+			h.userCache.Update(*kcUser)
+		} else {
+			h.userCache.Invalidate()
+		}
+	}
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) resolved Keycloak mismatch of %s (%s): %s",
+			adminUsername, adminDBUser.Email, targetDBUser.Email, req.Direction, change),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"keycloak_id":%q,"field":%q,"direction":%q}`,
+				adminDBUser.ID, targetDBUser.ID, keycloakID, req.Field, req.Direction),
+			Valid: true,
+		},
+	})
+
+	h.jsonSuccess(w, fmt.Sprintf("Resolved %s of %s: %s", req.Field, targetDBUser.Email, change))
+}
+
+// pushStateRoles assigns the role expected for the membership state and removes
+// the other managed roles. It returns a description of the changes made.
+func (h *Handler) pushStateRoles(ctx context.Context, kcClient *keycloak.Client, keycloakID, state string) (string, error) {
+	managed, wanted := h.expectedStateRoles(state)
+	if len(managed) == 0 {
+		return "", fmt.Errorf("MEMBERSHIP_STATE_ROLES is empty")
+	}
+
+	var changes []string
+	for _, role := range managed {
+		has, err := kcClient.UserHasRole(ctx, keycloakID, role)
+		if err != nil {
+			return strings.Join(changes, ", "), err
+		}
+
+		switch {
+		case role == wanted && !has:
+			if err := kcClient.AssignRoleToUser(ctx, keycloakID, role); err != nil {
+				return strings.Join(changes, ", "), err
+			}
+			changes = append(changes, "+"+role)
+		case role != wanted && has:
+			if err := kcClient.RemoveRoleFromUser(ctx, keycloakID, role); err != nil {
+				return strings.Join(changes, ", "), err
+			}
+			changes = append(changes, "-"+role)
+		}
+	}
+
+	if len(changes) == 0 {
+		return "roles already match", nil
+	}
+	return "roles " + strings.Join(changes, ", "), nil
+}
@@ -13,15 +13,6 @@ import (
 // GET /admin/logs
 func (h *Handler) AdminLogsHandler(w http.ResponseWriter, r *http.Request) {
 	user := h.auth.GetUser(r)
-	if user == nil {
-		http.Redirect(w, r, "/auth/login", http.StatusTemporaryRedirect)
-		return
-	}
-
-	if !user.IsAdmin() {
-		http.Error(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
 
 	ctx := r.Context()
 
@@ -79,10 +70,7 @@ func (h *Handler) AdminLogsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get DBUser for layout
-	dbUser, _ := h.queries.GetUserByKeycloakID(ctx, sql.NullString{
-		String: user.ID,
-		Valid:  true,
-	})
+	dbUser := DBUserFrom(ctx)
 
 	data := map[string]interface{}{
 		"Title":       "Systémové logy",
@@ -1,13 +1,21 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
 
 	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/base48/member-portal/internal/paymentrule"
 )
 
 // UnmatchedPaymentInfo contains payment with analysis
@@ -17,34 +25,38 @@ type UnmatchedPaymentInfo struct {
 	Category    string // "empty_vs", "user_not_found", "sync_bug"
 	Reason      string
 	IsIncoming  bool
-	AmountFloat float64
+	Suggestion  *db.ListPaymentSuggestionsRow // member suggested by the sender account history
+}
+
+// PendingReversalInfo is a returned payment with the credits it could reverse
+type PendingReversalInfo struct {
+	Payment     db.Payment
+	Candidates  []db.Payment
+}
+
+// DuplicatePaymentInfo is a payment imported again from another source with the
+// payment it repeats
+type DuplicatePaymentInfo struct {
+	Payment       db.Payment
+	Original      db.Payment
+	OriginalOwner string // member email or project name of the original
+}
+
+// PaymentMatchRuleInfo is a payment match rule with a readable summary of its conditions
+type PaymentMatchRuleInfo struct {
+	Rule       db.ListPaymentMatchRulesRow
+	Conditions string
 }
 
 // AdminUnmatchedPaymentsHandler shows all payments that couldn't be automatically matched to users
 // GET /admin/payments/unmatched
 func (h *Handler) AdminUnmatchedPaymentsHandler(w http.ResponseWriter, r *http.Request) {
 	user := h.auth.GetUser(r)
-	if user == nil {
-		http.Redirect(w, r, "/auth/login", http.StatusTemporaryRedirect)
-		return
-	}
-
-	if !user.IsAdmin() {
-		http.Error(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
 
 	ctx := r.Context()
 
 	// Get database user
-	dbUser, err := h.queries.GetUserByKeycloakID(ctx, sql.NullString{
-		String: user.ID,
-		Valid:  true,
-	})
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
-	}
+	dbUser := DBUserFrom(ctx)
 
 	// Get all unassigned payments
 	unassignedPayments, err := h.queries.ListUnassignedPayments(ctx)
@@ -53,9 +65,20 @@ func (h *Handler) AdminUnmatchedPaymentsHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	// Members suggested by sync_fio_payments for payments without VS
+	suggestionRows, err := h.queries.ListPaymentSuggestions(ctx)
+	if err != nil {
+		http.Error(w, "Failed to fetch payment suggestions", http.StatusInternalServerError)
+		return
+	}
+	suggestions := make(map[int64]*db.ListPaymentSuggestionsRow, len(suggestionRows))
+	for i := range suggestionRows {
+		suggestions[suggestionRows[i].PaymentID] = &suggestionRows[i]
+	}
+
 	// Analyze each payment - ONLY INCOMING PAYMENTS
 	var unmatchedList []UnmatchedPaymentInfo
-	totalAmount := 0.0
+	var totalAmount money.Amount
 	countPayments := 0
 
 	// Count by category
@@ -64,31 +87,25 @@ func (h *Handler) AdminUnmatchedPaymentsHandler(w http.ResponseWriter, r *http.R
 	countSyncBug := 0
 
 	for _, payment := range unassignedPayments {
-		// Parse amount for totals and direction
-		amountFloat := 0.0
-		if amount, err := strconv.ParseFloat(payment.Amount, 64); err == nil {
-			amountFloat = amount
-		}
-
 		// SKIP: outgoing payments (negative/zero) and small incoming (< 5 Kč, usually bank interest)
-		if amountFloat < 5 {
+		if payment.Amount < minUnmatchedAmount {
 			continue
 		}
 
 		// Only process incoming payments from here
-		totalAmount += amountFloat
+		totalAmount += payment.Amount
 		countPayments++
 
 		info := UnmatchedPaymentInfo{
-			Payment:     payment,
-			AmountFloat: amountFloat,
-			IsIncoming:  true, // Always true now
+			Payment:    payment,
+			IsIncoming: true, // Always true now
 		}
 
 		// Skip if no identification (VS)
 		if payment.Identification == "" {
 			info.Category = "empty_vs"
 			info.Reason = "Empty variable symbol"
+			info.Suggestion = suggestions[payment.ID]
 			unmatchedList = append(unmatchedList, info)
 			countEmptyVS++
 			continue
@@ -136,14 +153,10 @@ func (h *Handler) AdminUnmatchedPaymentsHandler(w http.ResponseWriter, r *http.R
 
 	// Filter dismissed payments - exclude those with project VS (they belong to projects, not archive)
 	var dismissedPayments []db.Payment
-	dismissedTotal := 0.0
+	var dismissedTotal money.Amount
 	for _, p := range allDismissedPayments {
 		// Skip small amounts
-		amountFloat := 0.0
-		if amount, err := strconv.ParseFloat(p.Amount, 64); err == nil {
-			amountFloat = amount
-		}
-		if amountFloat < 5 {
+		if p.Amount < minUnmatchedAmount {
 			continue
 		}
 
@@ -157,7 +170,56 @@ func (h *Handler) AdminUnmatchedPaymentsHandler(w http.ResponseWriter, r *http.R
 		}
 
 		dismissedPayments = append(dismissedPayments, p)
-		dismissedTotal += amountFloat
+		dismissedTotal += p.Amount
+	}
+
+	// Reversals with multiple matching credits wait for manual linking
+	pendingPayments, err := h.queries.ListPendingReversals(ctx)
+	if err != nil {
+		http.Error(w, "Failed to fetch pending reversals", http.StatusInternalServerError)
+		return
+	}
+
+	var pendingReversals []PendingReversalInfo
+	for _, p := range pendingPayments {
+		candidates, err := h.queries.ListReversalCandidates(ctx, db.ListReversalCandidatesParams{
+			RemoteAccount: p.RemoteAccount,
+			Amount:        -p.Amount,
+			Date:          p.Date,
+		})
+		if err != nil {
+			http.Error(w, "Failed to fetch reversal candidates", http.StatusInternalServerError)
+			return
+		}
+		pendingReversals = append(pendingReversals, PendingReversalInfo{
+			Payment:    p,
+			Candidates: candidates,
+		})
+	}
+
+	suspectedDuplicates, err := h.suspectedDuplicates(ctx)
+	if err != nil {
+		http.Error(w, "Failed to fetch suspected duplicates", http.StatusInternalServerError)
+		return
+	}
+
+	ruleRows, err := h.queries.ListPaymentMatchRules(ctx)
+	if err != nil {
+		http.Error(w, "Failed to fetch match rules", http.StatusInternalServerError)
+		return
+	}
+	matchRules := make([]PaymentMatchRuleInfo, 0, len(ruleRows))
+	for _, row := range ruleRows {
+		matchRules = append(matchRules, PaymentMatchRuleInfo{
+			Rule: row,
+			Conditions: paymentrule.Describe(db.PaymentMatchRule{
+				RemoteAccount:  row.RemoteAccount,
+				MessagePattern: row.MessagePattern,
+				SpecificSymbol: row.SpecificSymbol,
+				AmountMin:      row.AmountMin,
+				AmountMax:      row.AmountMax,
+			}),
+		})
 	}
 
 	// Prepare template data
@@ -173,7 +235,10 @@ func (h *Handler) AdminUnmatchedPaymentsHandler(w http.ResponseWriter, r *http.R
 		"CountSyncBug":      countSyncBug,
 		"DismissedPayments": dismissedPayments,
 		"DismissedCount":    len(dismissedPayments),
-		"DismissedTotal":    dismissedTotal,
+		"DismissedTotal":    dismissedTotal.Float64(),
+		"PendingReversals":  pendingReversals,
+		"SuspectedDuplicates": suspectedDuplicates,
+		"MatchRules":        matchRules,
 	}
 
 	h.render(w, "admin_payments_unmatched.html", data)
@@ -191,17 +256,6 @@ type AssignPaymentRequest struct {
 // IMPORTANT: This also sets the payment's identification to the user's payments_id
 // so that it will be counted in the user's balance calculation
 func (h *Handler) AdminAssignPaymentHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil {
-		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !user.IsAdmin() {
-		h.jsonError(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
-
 	var req AssignPaymentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
@@ -231,31 +285,17 @@ func (h *Handler) AdminAssignPaymentHandler(w http.ResponseWriter, r *http.Reque
 		staffComment = sql.NullString{String: req.StaffComment, Valid: true}
 	}
 
-	// Use UpsertPayment to update all fields including identification
-	_, err = h.queries.UpsertPayment(ctx, db.UpsertPaymentParams{
-		UserID:         sql.NullInt64{Int64: req.UserID, Valid: true},
-		ProjectID:      sql.NullInt64{}, // Clear project assignment when assigning to user
-		Date:           payment.Date,
-		Amount:         payment.Amount,
-		Kind:           payment.Kind,
-		KindID:         payment.KindID,
-		LocalAccount:   payment.LocalAccount,
-		RemoteAccount:  payment.RemoteAccount,
-		Identification: targetUser.PaymentsID.String, // SET VS to user's payments_id!
-		RawData:        payment.RawData,
-		StaffComment:   staffComment,
-	})
-
+	// Project assignment is cleared, VS set to the user's payments_id
+	err = h.reassignPayment(ctx, payment.ID,
+		sql.NullInt64{Int64: req.UserID, Valid: true}, sql.NullInt64{},
+		targetUser.PaymentsID.String, staffComment)
 	if err != nil {
 		h.jsonError(w, "Failed to assign payment: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Log the assignment
-	adminDBUser, _ := h.queries.GetUserByKeycloakID(ctx, sql.NullString{
-		String: user.ID,
-		Valid:  true,
-	})
+	adminDBUser := DBUserFrom(ctx)
 
 	adminUsername := "unknown"
 	if adminDBUser.Username.Valid {
@@ -272,7 +312,7 @@ func (h *Handler) AdminAssignPaymentHandler(w http.ResponseWriter, r *http.Reque
 		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
 		Message: fmt.Sprintf("Admin %s (%s) manually assigned payment #%d (%.2f Kč) to user %s (%s), VS set to '%s'",
 			adminUsername, adminDBUser.Email,
-			payment.ID, parseFloat(payment.Amount),
+			payment.ID, payment.Amount.Float64(),
 			targetUsername, targetUser.Email,
 			targetUser.PaymentsID.String),
 		Metadata: sql.NullString{
@@ -289,13 +329,36 @@ func (h *Handler) AdminAssignPaymentHandler(w http.ResponseWriter, r *http.Reque
 	})
 }
 
-// Helper function to parse float from string
-func parseFloat(s string) float64 {
-	var f float64
-	fmt.Sscanf(s, "%f", &f)
-	return f
+// reassignPayment sets the user, project, VS and staff comment of a payment. The VS must
+// match the user's payments_id for the payment to count in the balance. Serialized with
+// other balance updates; the payment is re-read to not overwrite a concurrent sync.
+func (h *Handler) reassignPayment(ctx context.Context, paymentID int64, userID, projectID sql.NullInt64, identification string, staffComment sql.NullString) error {
+	return h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		current, err := queries.GetPayment(ctx, paymentID)
+		if err != nil {
+			return err
+		}
+		_, err = queries.UpsertPayment(ctx, db.UpsertPaymentParams{
+			UserID:         userID,
+			ProjectID:      projectID,
+			Date:           current.Date,
+			Amount:         current.Amount,
+			Kind:           current.Kind,
+			KindID:         current.KindID,
+			LocalAccount:   current.LocalAccount,
+			RemoteAccount:  current.RemoteAccount,
+			Identification: identification,
+			RawData:        current.RawData,
+			StaffComment:   staffComment,
+		})
+		return err
+	})
 }
 
+// minUnmatchedAmount hides small incoming payments (usually bank interest) from
+// the unmatched list and the archive
+const minUnmatchedAmount money.Amount = 500 // 5 Kč
+
 // DismissPaymentRequest is the request body for dismissing a payment
 type DismissPaymentRequest struct {
 	PaymentID int64  `json:"payment_id"`
@@ -305,17 +368,6 @@ type DismissPaymentRequest struct {
 // AdminDismissPaymentHandler marks a payment as dismissed (seen/ignored)
 // POST /api/admin/payments/dismiss
 func (h *Handler) AdminDismissPaymentHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil {
-		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !user.IsAdmin() {
-		h.jsonError(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
-
 	var req DismissPaymentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
@@ -324,16 +376,6 @@ func (h *Handler) AdminDismissPaymentHandler(w http.ResponseWriter, r *http.Requ
 
 	ctx := r.Context()
 
-	// Get admin user from DB
-	adminDBUser, err := h.queries.GetUserByKeycloakID(ctx, sql.NullString{
-		String: user.ID,
-		Valid:  true,
-	})
-	if err != nil {
-		h.jsonError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-
 	// Verify payment exists
 	payment, err := h.queries.GetPayment(ctx, req.PaymentID)
 	if err != nil {
@@ -341,27 +383,37 @@ func (h *Handler) AdminDismissPaymentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Dismiss the payment
-	staffComment := sql.NullString{}
-	if req.Reason != "" {
-		staffComment = sql.NullString{String: "[DISMISSED] " + req.Reason, Valid: true}
-	} else {
-		staffComment = sql.NullString{String: "[DISMISSED]", Valid: true}
+	if err := h.dismissPayment(ctx, payment, req.Reason); err != nil {
+		h.jsonError(w, "Failed to dismiss payment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Payment dismissed successfully",
+	})
+}
+
+// dismissPayment moves a payment to the archive of handled payments and logs it
+func (h *Handler) dismissPayment(ctx context.Context, payment db.Payment, reason string) error {
+	adminDBUser := DBUserFrom(ctx)
+
+	staffComment := sql.NullString{String: "[DISMISSED]", Valid: true}
+	if reason != "" {
+		staffComment = sql.NullString{String: "[DISMISSED] " + reason, Valid: true}
 	}
 
-	_, err = h.queries.DismissPayment(ctx, db.DismissPaymentParams{
+	_, err := h.queries.DismissPayment(ctx, db.DismissPaymentParams{
 		DismissedBy:     adminDBUser.ID,
-		DismissedReason: req.Reason,
+		DismissedReason: reason,
 		StaffComment:    staffComment,
-		ID:              req.PaymentID,
+		ID:              payment.ID,
 	})
-
 	if err != nil {
-		h.jsonError(w, "Failed to dismiss payment: "+err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 
-	// Log the action
 	adminUsername := "unknown"
 	if adminDBUser.Username.Valid {
 		adminUsername = adminDBUser.Username.String
@@ -373,20 +425,15 @@ func (h *Handler) AdminDismissPaymentHandler(w http.ResponseWriter, r *http.Requ
 		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
 		Message: fmt.Sprintf("Admin %s (%s) dismissed payment #%d (%.2f Kč) - reason: %s",
 			adminUsername, adminDBUser.Email,
-			payment.ID, parseFloat(payment.Amount),
-			req.Reason),
+			payment.ID, payment.Amount.Float64(),
+			reason),
 		Metadata: sql.NullString{
 			String: fmt.Sprintf(`{"admin_user_id":%d,"payment_id":%d,"amount":"%s","reason":"%s"}`,
-				adminDBUser.ID, payment.ID, payment.Amount, req.Reason),
+				adminDBUser.ID, payment.ID, payment.Amount, reason),
 			Valid: true,
 		},
 	})
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "Payment dismissed successfully",
-	})
+	return nil
 }
 
 // UndismissPaymentRequest is the request body for undismissing a payment
@@ -397,17 +444,6 @@ type UndismissPaymentRequest struct {
 // AdminUndismissPaymentHandler restores a dismissed payment back to unmatched
 // POST /api/admin/payments/undismiss
 func (h *Handler) AdminUndismissPaymentHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil {
-		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !user.IsAdmin() {
-		h.jsonError(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
-
 	var req UndismissPaymentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
@@ -417,14 +453,7 @@ func (h *Handler) AdminUndismissPaymentHandler(w http.ResponseWriter, r *http.Re
 	ctx := r.Context()
 
 	// Get admin user from DB
-	adminDBUser, err := h.queries.GetUserByKeycloakID(ctx, sql.NullString{
-		String: user.ID,
-		Valid:  true,
-	})
-	if err != nil {
-		h.jsonError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
+	adminDBUser := DBUserFrom(ctx)
 
 	// Verify payment exists
 	payment, err := h.queries.GetPayment(ctx, req.PaymentID)
@@ -452,7 +481,7 @@ func (h *Handler) AdminUndismissPaymentHandler(w http.ResponseWriter, r *http.Re
 		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
 		Message: fmt.Sprintf("Admin %s (%s) restored payment #%d (%.2f Kč) from archive",
 			adminUsername, adminDBUser.Email,
-			payment.ID, parseFloat(payment.Amount)),
+			payment.ID, payment.Amount.Float64()),
 		Metadata: sql.NullString{
 			String: fmt.Sprintf(`{"admin_user_id":%d,"payment_id":%d,"amount":"%s"}`,
 				adminDBUser.ID, payment.ID, payment.Amount),
@@ -482,17 +511,6 @@ type UpdatePaymentRequest struct {
 // AdminUpdatePaymentHandler updates payment data and optionally assigns it
 // POST /api/admin/payments/update
 func (h *Handler) AdminUpdatePaymentHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil {
-		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !user.IsAdmin() {
-		h.jsonError(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
-
 	var req UpdatePaymentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
@@ -550,30 +568,14 @@ func (h *Handler) AdminUpdatePaymentHandler(w http.ResponseWriter, r *http.Reque
 		staffComment = sql.NullString{String: req.StaffComment, Valid: true}
 	}
 
-	_, err = h.queries.UpsertPayment(ctx, db.UpsertPaymentParams{
-		UserID:         userID,
-		ProjectID:      projectID,
-		Date:           payment.Date,
-		Amount:         payment.Amount,
-		Kind:           payment.Kind,
-		KindID:         payment.KindID,
-		LocalAccount:   payment.LocalAccount,
-		RemoteAccount:  payment.RemoteAccount,
-		Identification: identification,
-		RawData:        payment.RawData,
-		StaffComment:   staffComment,
-	})
-
+	err = h.reassignPayment(ctx, payment.ID, userID, projectID, identification, staffComment)
 	if err != nil {
 		h.jsonError(w, "Failed to update: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Log the update action
-	adminDBUser, _ := h.queries.GetUserByKeycloakID(ctx, sql.NullString{
-		String: user.ID,
-		Valid:  true,
-	})
+	adminDBUser := DBUserFrom(ctx)
 
 	adminUsername := "unknown"
 	if adminDBUser.Username.Valid {
@@ -592,7 +594,7 @@ func (h *Handler) AdminUpdatePaymentHandler(w http.ResponseWriter, r *http.Reque
 		}
 		logMessage = fmt.Sprintf("Admin %s (%s) updated payment #%d (%.2f Kč) and assigned to user %s (%s), VS set to '%s'",
 			adminUsername, adminDBUser.Email,
-			payment.ID, parseFloat(payment.Amount),
+			payment.ID, payment.Amount.Float64(),
 			targetUsername, targetUser.Email,
 			identification)
 		metadata = fmt.Sprintf(`{"admin_user_id":%d,"action":"assign_user","payment_id":%d,"target_user_id":%d,"amount":"%s","vs":"%s","staff_comment":"%s"}`,
@@ -601,7 +603,7 @@ func (h *Handler) AdminUpdatePaymentHandler(w http.ResponseWriter, r *http.Reque
 	case "project":
 		logMessage = fmt.Sprintf("Admin %s (%s) updated payment #%d (%.2f Kč) and assigned to project '%s', VS set to '%s'",
 			adminUsername, adminDBUser.Email,
-			payment.ID, parseFloat(payment.Amount),
+			payment.ID, payment.Amount.Float64(),
 			targetProject.Name,
 			identification)
 		metadata = fmt.Sprintf(`{"admin_user_id":%d,"action":"assign_project","payment_id":%d,"target_project_id":%d,"project_name":"%s","amount":"%s","vs":"%s","staff_comment":"%s"}`,
@@ -610,7 +612,7 @@ func (h *Handler) AdminUpdatePaymentHandler(w http.ResponseWriter, r *http.Reque
 	default: // "unmatched" or no assignment
 		logMessage = fmt.Sprintf("Admin %s (%s) updated payment #%d (%.2f Kč) data without assignment, VS set to '%s'",
 			adminUsername, adminDBUser.Email,
-			payment.ID, parseFloat(payment.Amount),
+			payment.ID, payment.Amount.Float64(),
 			identification)
 		metadata = fmt.Sprintf(`{"admin_user_id":%d,"action":"update_unmatched","payment_id":%d,"amount":"%s","vs":"%s","message":"%s","staff_comment":"%s"}`,
 			adminDBUser.ID, payment.ID, payment.Amount, identification, req.Message, req.StaffComment)
@@ -633,3 +635,470 @@ func (h *Handler) AdminUpdatePaymentHandler(w http.ResponseWriter, r *http.Reque
 		"message": "Payment updated successfully",
 	})
 }
+
+// errReversalConflict is returned from the link transaction when either payment is
+// already part of a reversal
+var errReversalConflict = errors.New("reversal conflict")
+
+// LinkReversalRequest is the request body for linking a reversal to its original payment
+type LinkReversalRequest struct {
+	PaymentID         int64 `json:"payment_id"`
+	OriginalPaymentID int64 `json:"original_payment_id"`
+}
+
+// AdminLinkReversalHandler links a returned (negative) payment to the credit it reverses
+// POST /api/admin/payments/reversal/link
+// The reversal takes over user, project and VS of the original so the balance nets out.
+// A payment is linked to one original only and an original has one reversal (409).
+func (h *Handler) AdminLinkReversalHandler(w http.ResponseWriter, r *http.Request) {
+	var req LinkReversalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+
+	adminDBUser := DBUserFrom(ctx)
+
+	payment, err := h.queries.GetPayment(ctx, req.PaymentID)
+	if err != nil {
+		h.jsonError(w, "Payment not found", http.StatusNotFound)
+		return
+	}
+
+	original, err := h.queries.GetPayment(ctx, req.OriginalPaymentID)
+	if err != nil {
+		h.jsonError(w, "Original payment not found", http.StatusNotFound)
+		return
+	}
+
+	amount := payment.Amount
+	if amount >= 0 {
+		h.jsonError(w, "Only outgoing payments can be linked as reversals", http.StatusBadRequest)
+		return
+	}
+	if original.ReversalOf.Valid || original.Amount != -amount {
+		h.jsonError(w, "Original payment amount does not match the reversal", http.StatusBadRequest)
+		return
+	}
+
+	var conflict string
+	err = h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		// Checked in the transaction, two links of the same payments would debit twice
+		current, err := queries.GetPayment(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+		if current.ReversalOf.Valid {
+			conflict = fmt.Sprintf("Payment #%d is already linked as the reversal of payment #%d", payment.ID, current.ReversalOf.Int64)
+			return errReversalConflict
+		}
+		if other, err := queries.GetPaymentReversal(ctx, sql.NullInt64{Int64: original.ID, Valid: true}); err == nil {
+			conflict = fmt.Sprintf("Payment #%d is already reversed by payment #%d", original.ID, other.ID)
+			return errReversalConflict
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+
+		_, err = queries.LinkPaymentReversal(ctx, db.LinkPaymentReversalParams{
+			ReversalOf:     sql.NullInt64{Int64: original.ID, Valid: true},
+			UserID:         original.UserID,
+			ProjectID:      original.ProjectID,
+			Identification: original.Identification,
+			ID:             payment.ID,
+		})
+		return err
+	})
+	if errors.Is(err, errReversalConflict) {
+		h.jsonError(w, conflict, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.jsonError(w, "Failed to link reversal: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) linked reversal #%d (%.2f Kč) to payment #%d",
+			adminUsername, adminDBUser.Email,
+			payment.ID, amount.Float64(), original.ID),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"payment_id":%d,"original_payment_id":%d,"amount":"%s"}`,
+				adminDBUser.ID, payment.ID, original.ID, payment.Amount),
+			Valid: true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Reversal linked successfully",
+	})
+}
+
+// AssignPaymentByIDRequest is the request body for POST /api/admin/payments/{id}/assign
+type AssignPaymentByIDRequest struct {
+	UserID          *int64 `json:"user_id"`
+	ProjectID       *int64 `json:"project_id"`
+	StaffComment    string `json:"staff_comment"`
+	RememberAccount bool   `json:"remember_account"` // match rule: FIO sync assigns future payments from the sender's account to the user
+}
+
+// AdminAssignPaymentByIDHandler assigns an unmatched payment to a user or a project
+// POST /api/admin/payments/{id}/assign
+// VS is set to the user's (project's) payments_id so the payment counts in the balance.
+// With remember_account the sender's account is remembered for the user.
+func (h *Handler) AdminAssignPaymentByIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req AssignPaymentByIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if (req.UserID == nil) == (req.ProjectID == nil) {
+		h.jsonError(w, "Exactly one of user_id and project_id is required", http.StatusBadRequest)
+		return
+	}
+	if req.RememberAccount && req.UserID == nil {
+		h.jsonError(w, "remember_account works only with user_id", http.StatusBadRequest)
+		return
+	}
+
+	payment, err := h.queries.GetPayment(ctx, id)
+	if err != nil {
+		h.jsonError(w, "Payment not found", http.StatusNotFound)
+		return
+	}
+	if req.RememberAccount && payment.RemoteAccount == "" {
+		h.jsonError(w, "Payment has no sender account to remember", http.StatusBadRequest)
+		return
+	}
+
+	staffComment := sql.NullString{String: req.StaffComment, Valid: req.StaffComment != ""}
+
+	adminDBUser := DBUserFrom(ctx)
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	var logMessage, metadata string
+	if req.UserID != nil {
+		targetUser, err := h.queries.GetUserByID(ctx, *req.UserID)
+		if err != nil {
+			h.jsonError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if !targetUser.PaymentsID.Valid {
+			h.jsonError(w, "User has no payments_id, the payment would not count in their balance", http.StatusBadRequest)
+			return
+		}
+
+		err = h.reassignPayment(ctx, payment.ID,
+			sql.NullInt64{Int64: targetUser.ID, Valid: true}, sql.NullInt64{},
+			targetUser.PaymentsID.String, staffComment)
+		if err != nil {
+			h.jsonError(w, "Failed to assign payment: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if req.RememberAccount {
+			err = h.rememberPaymentAccount(ctx, payment.RemoteAccount, targetUser.ID, adminUsername)
+			if err != nil {
+				h.jsonError(w, "Payment assigned, but failed to remember the account: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		logMessage = fmt.Sprintf("Admin %s (%s) assigned payment #%d (%.2f Kč) to user %s, VS set to '%s'",
+			adminUsername, adminDBUser.Email,
+			payment.ID, payment.Amount.Float64(),
+			targetUser.Email, targetUser.PaymentsID.String)
+		if req.RememberAccount {
+			logMessage += fmt.Sprintf(", account %s remembered", payment.RemoteAccount)
+		}
+		metadata = fmt.Sprintf(`{"admin_user_id":%d,"action":"assign_user","payment_id":%d,"target_user_id":%d,"amount":%q,"vs":%q,"remember_account":%t}`,
+			adminDBUser.ID, payment.ID, targetUser.ID, payment.Amount, targetUser.PaymentsID.String, req.RememberAccount)
+	} else {
+		targetProject, err := h.queries.GetProject(ctx, *req.ProjectID)
+		if err != nil {
+			h.jsonError(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		identification := payment.Identification
+		if targetProject.PaymentsID.Valid {
+			identification = targetProject.PaymentsID.String
+		}
+
+		err = h.reassignPayment(ctx, payment.ID,
+			sql.NullInt64{}, sql.NullInt64{Int64: targetProject.ID, Valid: true},
+			identification, staffComment)
+		if err != nil {
+			h.jsonError(w, "Failed to assign payment: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		logMessage = fmt.Sprintf("Admin %s (%s) assigned payment #%d (%.2f Kč) to project '%s', VS set to '%s'",
+			adminUsername, adminDBUser.Email,
+			payment.ID, payment.Amount.Float64(),
+			targetProject.Name, identification)
+		metadata = fmt.Sprintf(`{"admin_user_id":%d,"action":"assign_project","payment_id":%d,"target_project_id":%d,"amount":%q,"vs":%q}`,
+			adminDBUser.ID, payment.ID, targetProject.ID, payment.Amount, identification)
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   logMessage,
+		Metadata:  sql.NullString{String: metadata, Valid: true},
+	})
+
+	h.jsonSuccess(w, "Payment assigned")
+}
+
+// IgnorePaymentRequest is the request body for POST /api/admin/payments/{id}/ignore
+type IgnorePaymentRequest struct {
+	Reason string `json:"reason"`
+}
+
+// AdminIgnorePaymentHandler moves a payment that belongs to nobody (donation, duplicate)
+// to the archive of handled payments
+// POST /api/admin/payments/{id}/ignore
+func (h *Handler) AdminIgnorePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	// Reason is optional, an empty body is fine
+	var req IgnorePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payment, err := h.queries.GetPayment(ctx, id)
+	if err != nil {
+		h.jsonError(w, "Payment not found", http.StatusNotFound)
+		return
+	}
+	if payment.UserID.Valid || payment.ProjectID.Valid {
+		h.jsonError(w, "Payment is already assigned", http.StatusConflict)
+		return
+	}
+	if payment.DismissedAt != nil {
+		h.jsonError(w, "Payment is already ignored", http.StatusConflict)
+		return
+	}
+
+	if err := h.dismissPayment(ctx, payment, strings.TrimSpace(req.Reason)); err != nil {
+		h.jsonError(w, "Failed to ignore payment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Payment ignored")
+}
+
+// AdminRejectPaymentSuggestionHandler rejects the member suggested for a payment by
+// the sender account history; sync_fio_payments does not suggest it again
+// POST /api/admin/payments/{id}/suggestion/reject
+func (h *Handler) AdminRejectPaymentSuggestionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	adminUsername := "unknown"
+	if adminDBUser := DBUserFrom(ctx); adminDBUser != nil && adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	rows, err := h.queries.RejectPaymentSuggestion(ctx, db.RejectPaymentSuggestionParams{
+		DecidedBy: sql.NullString{String: adminUsername, Valid: true},
+		PaymentID: id,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to reject suggestion: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "No open suggestion for this payment", http.StatusNotFound)
+		return
+	}
+
+	_, _ = h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		Message:   fmt.Sprintf("Payment suggestion rejected by %s", adminUsername),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"payment_id":%d}`, id), Valid: true},
+	})
+
+	h.jsonSuccess(w, "Suggestion rejected")
+}
+
+// suspectedDuplicates lists payments waiting for a merge / keep decision
+func (h *Handler) suspectedDuplicates(ctx context.Context) ([]DuplicatePaymentInfo, error) {
+	rows, err := h.queries.ListSuspectedPaymentDuplicates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := make([]DuplicatePaymentInfo, 0, len(rows))
+	for _, row := range rows {
+		payment, err := h.queries.GetPayment(ctx, row.PaymentID)
+		if err != nil {
+			return nil, err
+		}
+		original, err := h.queries.GetPayment(ctx, row.DuplicateOf)
+		if err != nil {
+			return nil, err
+		}
+
+		owner := "nespárovaná"
+		if original.UserID.Valid {
+			if user, err := h.queries.GetUserByID(ctx, original.UserID.Int64); err == nil {
+				owner = user.Email
+			}
+		} else if original.ProjectID.Valid {
+			if project, err := h.queries.GetProject(ctx, original.ProjectID.Int64); err == nil {
+				owner = "projekt " + project.Name
+			}
+		} else if original.DismissedAt != nil {
+			owner = "v archivu"
+		}
+
+		duplicates = append(duplicates, DuplicatePaymentInfo{
+			Payment:       payment,
+			Original:      original,
+			OriginalOwner: owner,
+		})
+	}
+	return duplicates, nil
+}
+
+// AdminMergeDuplicatePaymentHandler merges a suspected duplicate into the payment
+// it repeats: the duplicate is moved to the archive of handled payments
+// POST /api/admin/payments/{id}/duplicate/merge
+func (h *Handler) AdminMergeDuplicatePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	duplicate, payment, ok := h.suspectedDuplicate(w, r)
+	if !ok {
+		return
+	}
+
+	adminUsername := "unknown"
+	if adminDBUser := DBUserFrom(ctx); adminDBUser != nil && adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+	if _, err := h.queries.DecidePaymentDuplicate(ctx, db.DecidePaymentDuplicateParams{
+		State:     "merged",
+		DecidedBy: sql.NullString{String: adminUsername, Valid: true},
+		PaymentID: payment.ID,
+	}); err != nil {
+		h.jsonError(w, "Failed to merge payment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.dismissPayment(ctx, payment, fmt.Sprintf("Duplicita platby #%d", duplicate.DuplicateOf)); err != nil {
+		h.jsonError(w, "Failed to merge payment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Payment merged")
+}
+
+// AdminIgnoreDuplicatePaymentHandler keeps a suspected duplicate as a payment of its
+// own (two real payments with the same content); it is assigned by VS like the
+// import would, otherwise it stays among unmatched payments
+// POST /api/admin/payments/{id}/duplicate/ignore
+func (h *Handler) AdminIgnoreDuplicatePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	duplicate, payment, ok := h.suspectedDuplicate(w, r)
+	if !ok {
+		return
+	}
+
+	adminUsername := "unknown"
+	if adminDBUser := DBUserFrom(ctx); adminDBUser != nil && adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+	if _, err := h.queries.DecidePaymentDuplicate(ctx, db.DecidePaymentDuplicateParams{
+		State:     "ignored",
+		DecidedBy: sql.NullString{String: adminUsername, Valid: true},
+		PaymentID: payment.ID,
+	}); err != nil {
+		h.jsonError(w, "Failed to keep payment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if payment.Identification != "" {
+		user, err := h.queries.GetUserByPaymentsID(ctx, sql.NullString{String: payment.Identification, Valid: true})
+		if err == nil {
+			if err := h.reassignPayment(ctx, payment.ID, sql.NullInt64{Int64: user.ID, Valid: true}, sql.NullInt64{},
+				payment.Identification, sql.NullString{String: fmt.Sprintf("Není duplicita platby #%d", duplicate.DuplicateOf), Valid: true}); err != nil {
+				h.jsonError(w, "Failed to assign payment: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		} else if err != sql.ErrNoRows {
+			h.jsonError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	_, _ = h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		Message:   fmt.Sprintf("Payment #%d kept as not a duplicate of #%d by %s", payment.ID, duplicate.DuplicateOf, adminUsername),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"payment_id":%d,"duplicate_of":%d}`, payment.ID, duplicate.DuplicateOf), Valid: true},
+	})
+
+	h.jsonSuccess(w, "Payment kept")
+}
+
+// suspectedDuplicate loads the open duplicate record and the payment of the {id} URL
+// parameter, writing the error response if there is none
+func (h *Handler) suspectedDuplicate(w http.ResponseWriter, r *http.Request) (db.PaymentDuplicate, db.Payment, bool) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid payment ID", http.StatusBadRequest)
+		return db.PaymentDuplicate{}, db.Payment{}, false
+	}
+
+	duplicate, err := h.queries.GetPaymentDuplicate(ctx, id)
+	if err != nil || duplicate.State != "suspected" {
+		h.jsonError(w, "No suspected duplicate for this payment", http.StatusNotFound)
+		return db.PaymentDuplicate{}, db.Payment{}, false
+	}
+	payment, err := h.queries.GetPayment(ctx, id)
+	if err != nil {
+		h.jsonError(w, "Payment not found", http.StatusNotFound)
+		return db.PaymentDuplicate{}, db.Payment{}, false
+	}
+	return duplicate, payment, true
+}
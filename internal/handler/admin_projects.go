@@ -7,29 +7,18 @@ import (
 	"strconv"
 
 	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
 )
 
 // AdminProjectsHandler shows the projects management page
 // GET /admin/projects
 func (h *Handler) AdminProjectsHandler(w http.ResponseWriter, r *http.Request) {
 	user := h.auth.GetUser(r)
-	if user == nil {
-		http.Redirect(w, r, "/auth/login", http.StatusTemporaryRedirect)
-		return
-	}
-
-	if !user.IsAdmin() {
-		http.Error(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
 
 	ctx := r.Context()
 
 	// Get DBUser for layout
-	dbUser, _ := h.queries.GetUserByKeycloakID(ctx, sql.NullString{
-		String: user.ID,
-		Valid:  true,
-	})
+	dbUser := DBUserFrom(ctx)
 
 	data := map[string]interface{}{
 		"Title":  "Správa projektů",
@@ -48,28 +37,25 @@ type VSInfo struct {
 
 // ProjectResponse is the JSON response for a project
 type ProjectResponse struct {
-	ID          int64    `json:"id"`
-	Name        string   `json:"name"`
-	PaymentsID  string   `json:"payments_id"`  // Primary VS (deprecated, use VSList)
-	VSList      []VSInfo `json:"vs_list"`      // All VS identifiers
-	Description string   `json:"description"`
-	TotalAmount float64  `json:"total_amount"`
+	ID              int64    `json:"id"`
+	Name            string   `json:"name"`
+	PaymentsID      string   `json:"payments_id"` // Primary VS (deprecated, use VSList)
+	VSList          []VSInfo `json:"vs_list"`     // All VS identifiers
+	Description     string   `json:"description"`
+	TotalAmount     float64  `json:"total_amount"` // net balance: income minus expenses
+	Income          float64  `json:"income"`
+	Spent           float64  `json:"spent"`
+	Public          bool     `json:"public"`            // public page /projects/{id} with the contributor wall
+	Btcpay          bool     `json:"btcpay"`            // accepts crypto donations via BTCPay
+	Goal            float64  `json:"goal"`              // funding goal on the public page, 0 = none
+	Deadline        string   `json:"deadline"`          // YYYY-MM-DD, empty = open-ended
+	Progress        int      `json:"progress"`          // raised share of the goal in percent, capped at 100
+	TargetReachedAt string   `json:"target_reached_at"` // when the goal was reached, empty = not yet
 }
 
 // AdminProjectsAPIHandler returns list of projects (JSON)
 // GET /api/admin/projects
 func (h *Handler) AdminProjectsAPIHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil {
-		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !user.IsAdmin() {
-		h.jsonError(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
-
 	ctx := r.Context()
 
 	// Get all active projects
@@ -82,14 +68,12 @@ func (h *Handler) AdminProjectsAPIHandler(w http.ResponseWriter, r *http.Request
 	// Convert to response format with proper string handling and calculate totals
 	projectResponses := make([]ProjectResponse, len(projects))
 	for i, p := range projects {
-		// Get total amount for this project (by project_id or any VS in project_vs)
-		balanceInterface, err := h.queries.GetProjectBalance(ctx, sql.NullInt64{Int64: p.ID, Valid: true})
-		totalAmount := 0.0
-		if err == nil {
-			// The query returns interface{}, need to convert to float64
-			if f, ok := balanceInterface.(float64); ok {
-				totalAmount = f
-			}
+		// Get income (by project_id or any VS in project_vs) and net balance after expenses
+		projectID := sql.NullInt64{Int64: p.ID, Valid: true}
+		income, _ := h.queries.GetProjectIncome(ctx, projectID)
+		balance, err := h.queries.GetProjectBalance(ctx, projectID)
+		if err != nil {
+			balance = income
 		}
 
 		// Get all VS identifiers for this project
@@ -104,13 +88,29 @@ func (h *Handler) AdminProjectsAPIHandler(w http.ResponseWriter, r *http.Request
 			}
 		}
 
+		var deadline, reachedAt string
+		if p.Deadline.Valid {
+			deadline = p.Deadline.Time.Format("2006-01-02")
+		}
+		if p.TargetReachedAt.Valid {
+			reachedAt = p.TargetReachedAt.Time.Format("2006-01-02")
+		}
+
 		projectResponses[i] = ProjectResponse{
-			ID:          p.ID,
-			Name:        p.Name,
-			PaymentsID:  p.PaymentsID.String,
-			VSList:      vsInfoList,
-			Description: p.Description.String,
-			TotalAmount: totalAmount,
+			ID:              p.ID,
+			Name:            p.Name,
+			PaymentsID:      p.PaymentsID.String,
+			VSList:          vsInfoList,
+			Description:     p.Description.String,
+			TotalAmount:     money.Amount(balance).Float64(),
+			Income:          money.Amount(income).Float64(),
+			Spent:           money.Amount(income - balance).Float64(),
+			Public:          p.Public,
+			Btcpay:          p.Btcpay,
+			Goal:            p.Goal.Float64(),
+			Deadline:        deadline,
+			Progress:        fundingProgress(money.Amount(income).Float64(), p.Goal),
+			TargetReachedAt: reachedAt,
 		}
 	}
 
@@ -131,17 +131,6 @@ type CreateProjectRequest struct {
 // AdminCreateProjectHandler creates a new project
 // POST /api/admin/projects
 func (h *Handler) AdminCreateProjectHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil {
-		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !user.IsAdmin() {
-		h.jsonError(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
-
 	var req CreateProjectRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
@@ -191,17 +180,6 @@ func (h *Handler) AdminCreateProjectHandler(w http.ResponseWriter, r *http.Reque
 // AdminDeleteProjectHandler deletes a project
 // DELETE /api/admin/projects/{id}
 func (h *Handler) AdminDeleteProjectHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil {
-		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !user.IsAdmin() {
-		h.jsonError(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
-
 	// Parse project ID from URL
 	var req struct {
 		ProjectID int64 `json:"project_id"`
@@ -231,7 +209,7 @@ func (h *Handler) AdminDeleteProjectHandler(w http.ResponseWriter, r *http.Reque
 type PaymentResponse struct {
 	ID            int64  `json:"id"`
 	Date          string `json:"date"`
-	Amount        string `json:"amount"`
+	Amount        money.Amount `json:"amount"`
 	RemoteAccount string `json:"remote_account"`
 	Identification string `json:"identification"`
 	Message       string `json:"message"`
@@ -241,17 +219,6 @@ type PaymentResponse struct {
 // AdminProjectPaymentsHandler returns payments for a project
 // GET /api/admin/projects/{id}/payments
 func (h *Handler) AdminProjectPaymentsHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil {
-		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !user.IsAdmin() {
-		h.jsonError(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
-
 	// Parse project ID from query
 	projectIDStr := r.URL.Query().Get("project_id")
 	if projectIDStr == "" {
@@ -295,6 +262,62 @@ func (h *Handler) AdminProjectPaymentsHandler(w http.ResponseWriter, r *http.Req
 	})
 }
 
+// LedgerEntry is one line of a project ledger
+type LedgerEntry struct {
+	Type         string       `json:"type"` // payment, split (allocation of a split payment) or expense
+	ID           int64        `json:"id"`   // payment or expense ID
+	Date         string       `json:"date"`
+	Amount       money.Amount `json:"amount"` // negative for expenses
+	Counterparty string       `json:"counterparty"`
+	Note         string       `json:"note"`
+	Balance      money.Amount `json:"balance"` // running balance after this entry
+}
+
+// AdminProjectLedgerHandler returns income and expenses of a project with a running balance
+// GET /api/admin/projects/ledger?project_id=
+func (h *Handler) AdminProjectLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(r.URL.Query().Get("project_id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid project_id", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.queries.ListProjectLedger(r.Context(), sql.NullInt64{Int64: projectID, Valid: true})
+	if err != nil {
+		h.jsonError(w, "Failed to fetch ledger: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]LedgerEntry, len(rows))
+	var balance, income, spent money.Amount
+	for i, row := range rows {
+		balance += row.Amount
+		if row.EntryType == "expense" {
+			spent -= row.Amount
+		} else {
+			income += row.Amount
+		}
+		entries[i] = LedgerEntry{
+			Type:         row.EntryType,
+			ID:           row.EntryID,
+			Date:         row.Date.Format("02.01.2006"),
+			Amount:       row.Amount,
+			Counterparty: row.Counterparty,
+			Note:         row.Note,
+			Balance:      balance,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"entries": entries,
+		"income":  income,
+		"spent":   spent,
+		"balance": balance,
+	})
+}
+
 // AddProjectVSRequest is the request body for adding a VS to a project
 type AddProjectVSRequest struct {
 	ProjectID int64  `json:"project_id"`
@@ -305,17 +328,6 @@ type AddProjectVSRequest struct {
 // AdminAddProjectVSHandler adds a VS identifier to a project
 // POST /api/admin/projects/vs
 func (h *Handler) AdminAddProjectVSHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil {
-		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !user.IsAdmin() {
-		h.jsonError(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
-
 	var req AddProjectVSRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
@@ -364,17 +376,6 @@ type RemoveProjectVSRequest struct {
 // AdminRemoveProjectVSHandler removes a VS identifier from a project
 // DELETE /api/admin/projects/vs
 func (h *Handler) AdminRemoveProjectVSHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil {
-		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !user.IsAdmin() {
-		h.jsonError(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
-
 	var req RemoveProjectVSRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
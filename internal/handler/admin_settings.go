@@ -5,28 +5,17 @@ import (
 	"net/http"
 
 	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fees"
 )
 
 // AdminSettingsHandler shows admin settings page
 func (h *Handler) AdminSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	user := h.auth.GetUser(r)
-	if user == nil {
-		http.Redirect(w, r, "/auth/login", http.StatusTemporaryRedirect)
-		return
-	}
-
-	if !user.IsAdmin() {
-		http.Error(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
 
 	ctx := r.Context()
 
 	// Get DBUser for layout
-	dbUser, _ := h.queries.GetUserByKeycloakID(ctx, sql.NullString{
-		String: user.ID,
-		Valid:  true,
-	})
+	dbUser := DBUserFrom(ctx)
 
 	// Get SMTP configuration status
 	smtpConfigured := h.config.SMTPHost != "" && h.config.SMTPPort != 0
@@ -38,22 +27,31 @@ func (h *Handler) AdminSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		"SMTPConfigured": smtpConfigured,
 	}
 
+	if levels, err := h.queries.ListAllLevels(ctx); err == nil {
+		data["Levels"] = levels
+	}
+	if changes, err := h.queries.ListLevelPriceChanges(ctx, 50); err == nil {
+		data["FeeChanges"] = changes
+	}
+	data["FeeChangeNoticeWeeks"] = h.config.FeeChangeNoticeWeeks
+	data["DebtLadderDefaults"] = fees.DefaultDebtLadder(h.config)
+	if ladder, err := fees.LoadDebtLadder(ctx, h.queries, fees.DefaultDebtLadder(h.config)); err == nil {
+		data["DebtLadder"] = ladder
+	}
+	data["BTCPayEnabled"] = h.btcpay != nil
+
+	data["TemplateOverrideDir"] = h.config.TemplateOverrideDir
+	if overrides, err := h.templates.Overrides(); err != nil {
+		data["TemplateOverridesError"] = err.Error()
+	} else {
+		data["TemplateOverrides"] = overrides
+	}
+
 	h.render(w, "admin_settings.html", data)
 }
 
 // AdminTestEmailHandler sends test email
 func (h *Handler) AdminTestEmailHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if !user.IsAdmin() {
-		http.Error(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
-
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -79,15 +77,7 @@ func (h *Handler) AdminTestEmailHandler(w http.ResponseWriter, r *http.Request)
 	testUser, err := h.queries.GetUserByEmail(ctx, recipient)
 	if err != nil {
 		// Use admin user as fallback
-		dbUser, err := h.queries.GetUserByKeycloakID(ctx, sql.NullString{
-			String: user.ID,
-			Valid:  true,
-		})
-		if err != nil {
-			http.Error(w, "Failed to get user data", http.StatusInternalServerError)
-			return
-		}
-		testUser = dbUser
+		testUser = *DBUserFrom(ctx)
 	}
 
 	// Send appropriate test email
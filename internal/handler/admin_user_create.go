@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// AdminCreateUserRequest is the body of POST /api/admin/users
+type AdminCreateUserRequest struct {
+	Email             string       `json:"email"`
+	Realname          string       `json:"realname"`
+	Phone             string       `json:"phone"`
+	AltContact        string       `json:"alt_contact"`
+	State             string       `json:"state"` // empty = accepted
+	LevelID           int64        `json:"level_id"`
+	LevelActualAmount money.Amount `json:"level_actual_amount"` // zero = the level amount
+	PaymentsID        string       `json:"payments_id"`         // empty = the next free VS
+	ProvisionKeycloak bool         `json:"provision_keycloak"`  // create the Keycloak account right away
+}
+
+// AdminCreateUserHandler creates a member record before the member's first login
+// (legacy members, members paying in cash). The member gets the given VS or the
+// next free one; the Keycloak account is created on request, otherwise the member
+// is linked by email on the first login (or provisioned from the profile later).
+// A Keycloak failure is reported but does not undo the member.
+// POST /api/admin/users
+// Body: {"email": "...", "realname": "...", "level_id": 2, "payments_id": "", "provision_keycloak": true}
+func (h *Handler) AdminCreateUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req AdminCreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	req.Realname = strings.TrimSpace(req.Realname)
+	req.Phone = strings.TrimSpace(req.Phone)
+	req.AltContact = strings.TrimSpace(req.AltContact)
+	req.PaymentsID = strings.TrimSpace(req.PaymentsID)
+	if req.State == "" {
+		req.State = "accepted"
+	}
+
+	if !strings.Contains(req.Email, "@") {
+		h.jsonError(w, "Invalid email", http.StatusBadRequest)
+		return
+	}
+	if !memberStates[req.State] {
+		h.jsonError(w, fmt.Sprintf("Unknown state %q", req.State), http.StatusBadRequest)
+		return
+	}
+	if !validPaymentsID(req.PaymentsID) {
+		h.jsonError(w, "Variable symbol must be up to 10 digits", http.StatusBadRequest)
+		return
+	}
+
+	level, err := h.queries.GetLevel(ctx, req.LevelID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Level not found", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if req.LevelActualAmount == 0 {
+		req.LevelActualAmount = level.Amount
+	}
+	if req.LevelActualAmount < level.Amount {
+		h.jsonError(w, fmt.Sprintf("Amount must be at least %s (level %s)", level.Amount, level.Name), http.StatusBadRequest)
+		return
+	}
+
+	if other, err := h.queries.GetUserByEmail(ctx, req.Email); err == nil {
+		h.jsonError(w, fmt.Sprintf("Email is already used by user #%d", other.ID), http.StatusConflict)
+		return
+	} else if err != sql.ErrNoRows {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if req.PaymentsID != "" {
+		if taken, err := h.paymentsIDTaken(ctx, req.PaymentsID); err != nil {
+			h.jsonError(w, "Database error", http.StatusInternalServerError)
+			return
+		} else if taken != "" {
+			h.jsonError(w, taken, http.StatusConflict)
+			return
+		}
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	// The next free VS is taken inside the transaction creating the member
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	paymentsID := req.PaymentsID
+	if paymentsID == "" {
+		if paymentsID, err = qtx.NextPaymentsID(ctx); err != nil {
+			h.jsonError(w, "Failed to assign payments VS", http.StatusInternalServerError)
+			return
+		}
+	}
+	member, err := qtx.CreateUser(ctx, db.CreateUserParams{
+		Email:             req.Email,
+		Realname:          sql.NullString{String: req.Realname, Valid: req.Realname != ""},
+		Phone:             sql.NullString{String: req.Phone, Valid: req.Phone != ""},
+		AltContact:        sql.NullString{String: req.AltContact, Valid: req.AltContact != ""},
+		LevelID:           level.ID,
+		LevelActualAmount: req.LevelActualAmount,
+		PaymentsID:        sql.NullString{String: paymentsID, Valid: true},
+		State:             req.State,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			h.jsonError(w, "Email or variable symbol is already used", http.StatusConflict)
+			return
+		}
+		h.jsonError(w, fmt.Sprintf("Failed to create member: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		h.jsonError(w, "Failed to create member", http.StatusInternalServerError)
+		return
+	}
+
+	keycloakError := ""
+	if req.ProvisionKeycloak {
+		if kcClient, err := h.keycloakClient(); err != nil {
+			keycloakError = err.Error()
+		} else if keycloakID, _, err := kcClient.ProvisionUser(ctx, member.Email, "", req.Realname); keycloakID == "" {
+			keycloakError = err.Error()
+		} else {
+			// A failed password email is reported, but the account exists and must be linked anyway
+			if err != nil {
+				keycloakError = err.Error()
+			}
+			if linked, err := h.queries.LinkKeycloakID(ctx, db.LinkKeycloakIDParams{
+				KeycloakID: sql.NullString{String: keycloakID, Valid: true},
+				Email:      member.Email,
+			}); err != nil {
+				keycloakError = fmt.Sprintf("failed to link Keycloak account: %v", err)
+			} else {
+				member = linked
+				if len(h.config.MembershipStateRoles) > 0 {
+					if _, err := h.pushStateRoles(ctx, kcClient, keycloakID, member.State); err != nil {
+						keycloakError = fmt.Sprintf("failed to assign roles: %v", err)
+					}
+				}
+			}
+			h.userCache.Invalidate()
+			h.roleCache.Invalidate()
+		}
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) created member %s (VS %s, %s)",
+			adminUsername, adminDBUser.Email, member.Email, paymentsID, member.State),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"payments_id":%q,"level_id":%d,"provision_keycloak":%t,"keycloak_id":%q,"keycloak_error":%q}`,
+				adminDBUser.ID, member.ID, paymentsID, level.ID, req.ProvisionKeycloak, member.KeycloakID.String, keycloakError),
+			Valid: true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"user_id":        member.ID,
+		"payments_id":    paymentsID,
+		"keycloak_id":    member.KeycloakID.String,
+		"keycloak_error": keycloakError,
+	})
+}
@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// AdminUpdateUserRequest is the body of PUT /api/admin/users/{id}; all fields are
+// replaced, the admin form sends the current values of those it does not change
+type AdminUpdateUserRequest struct {
+	Email             string       `json:"email"`
+	State             string       `json:"state"`
+	LevelID           int64        `json:"level_id"`
+	LevelActualAmount money.Amount `json:"level_actual_amount"` // own amount, at least the level amount
+	PaymentsID        string       `json:"payments_id"`         // empty = no VS
+}
+
+// memberStates are the values allowed in users.state
+var memberStates = map[string]bool{
+	"awaiting":  true,
+	"accepted":  true,
+	"rejected":  true,
+	"exmember":  true,
+	"suspended": true,
+}
+
+// AdminUpdateUserHandler changes a member's email, state, level, own amount and VS.
+// A level change is effective immediately and goes to the level history; on a VS
+// change the member's payments carrying the old VS move to the new one; a state
+// change is pushed to the Keycloak roles (MEMBERSHIP_STATE_ROLES) when the member
+// has an account. The email in Keycloak is not changed (the Keycloak diff shows it).
+// PUT /api/admin/users/{id}
+// Body: {"email": "...", "state": "accepted", "level_id": 2, "level_actual_amount": "1000.00", "payments_id": "1042"}
+func (h *Handler) AdminUpdateUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req AdminUpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	req.PaymentsID = strings.TrimSpace(req.PaymentsID)
+
+	if !strings.Contains(req.Email, "@") {
+		h.jsonError(w, "Invalid email", http.StatusBadRequest)
+		return
+	}
+	if !memberStates[req.State] {
+		h.jsonError(w, fmt.Sprintf("Unknown state %q", req.State), http.StatusBadRequest)
+		return
+	}
+	if !validPaymentsID(req.PaymentsID) {
+		h.jsonError(w, "Variable symbol must be up to 10 digits", http.StatusBadRequest)
+		return
+	}
+
+	level, err := h.queries.GetLevel(ctx, req.LevelID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Level not found", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if req.LevelActualAmount < level.Amount {
+		h.jsonError(w, fmt.Sprintf("Amount must be at least %s (level %s)", level.Amount, level.Name), http.StatusBadRequest)
+		return
+	}
+
+	if !strings.EqualFold(req.Email, targetDBUser.Email) {
+		if other, err := h.queries.GetUserByEmail(ctx, req.Email); err == nil && other.ID != targetDBUser.ID {
+			h.jsonError(w, fmt.Sprintf("Email is already used by user #%d", other.ID), http.StatusConflict)
+			return
+		} else if err != nil && err != sql.ErrNoRows {
+			h.jsonError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	paymentsID := sql.NullString{String: req.PaymentsID, Valid: req.PaymentsID != ""}
+	if paymentsID.Valid && paymentsID.String != targetDBUser.PaymentsID.String {
+		if taken, err := h.paymentsIDTaken(ctx, paymentsID.String); err != nil {
+			h.jsonError(w, "Database error", http.StatusInternalServerError)
+			return
+		} else if taken != "" {
+			h.jsonError(w, taken, http.StatusConflict)
+			return
+		}
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	// The update, the payments re-tagged to a new VS and the level history entry in
+	// one balance update, so no payment import sees the member half-changed
+	var (
+		updated    db.User
+		retaggedVS int64
+	)
+	err = h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		var err error
+		updated, err = queries.UpdateUser(ctx, db.UpdateUserParams{
+			Email:             req.Email,
+			Username:          targetDBUser.Username,
+			Realname:          targetDBUser.Realname,
+			Phone:             targetDBUser.Phone,
+			AltContact:        targetDBUser.AltContact,
+			LevelID:           level.ID,
+			LevelActualAmount: req.LevelActualAmount,
+			PaymentsID:        paymentsID,
+			State:             req.State,
+			IsCouncil:         targetDBUser.IsCouncil,
+			IsStaff:           targetDBUser.IsStaff,
+			KeysGranted:       targetDBUser.KeysGranted,
+			KeysReturned:      targetDBUser.KeysReturned,
+			ID:                targetDBUser.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Balances only count payments carrying the member's current VS
+		oldVS := targetDBUser.PaymentsID.String
+		if oldVS != "" && paymentsID.Valid && paymentsID.String != oldVS {
+			retaggedVS, err = queries.UpdateUserPaymentsVS(ctx, db.UpdateUserPaymentsVSParams{
+				NewPaymentsID: paymentsID.String,
+				UserID:        sql.NullInt64{Int64: targetDBUser.ID, Valid: true},
+				OldPaymentsID: oldVS,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to re-tag payments: %w", err)
+			}
+		}
+
+		if level.ID != targetDBUser.LevelID {
+			now := time.Now()
+			history, err := queries.CreateLevelHistory(ctx, db.CreateLevelHistoryParams{
+				UserID:        targetDBUser.ID,
+				LevelID:       level.ID,
+				EffectiveFrom: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+				CreatedBy:     adminUsername,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to record level history: %w", err)
+			}
+			if err := queries.MarkLevelHistoryApplied(ctx, history.ID); err != nil {
+				return fmt.Errorf("failed to record level history: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Lost a race with another member taking the email or VS
+		if strings.Contains(err.Error(), "UNIQUE") {
+			h.jsonError(w, "Email or variable symbol is already used", http.StatusConflict)
+			return
+		}
+		h.jsonError(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	var changes []string
+	if updated.Email != targetDBUser.Email {
+		changes = append(changes, fmt.Sprintf("email %s -> %s", targetDBUser.Email, updated.Email))
+	}
+	if updated.State != targetDBUser.State {
+		changes = append(changes, fmt.Sprintf("state %s -> %s", targetDBUser.State, updated.State))
+	}
+	if updated.LevelID != targetDBUser.LevelID {
+		changes = append(changes, fmt.Sprintf("level %d -> %d", targetDBUser.LevelID, updated.LevelID))
+	}
+	if updated.LevelActualAmount != targetDBUser.LevelActualAmount {
+		changes = append(changes, fmt.Sprintf("amount %s -> %s", targetDBUser.LevelActualAmount, updated.LevelActualAmount))
+	}
+	if updated.PaymentsID.String != targetDBUser.PaymentsID.String {
+		change := fmt.Sprintf("VS %q -> %q", targetDBUser.PaymentsID.String, updated.PaymentsID.String)
+		if retaggedVS > 0 {
+			change += fmt.Sprintf(" (%d payments re-tagged)", retaggedVS)
+		}
+		changes = append(changes, change)
+	}
+	if len(changes) == 0 {
+		h.jsonSuccess(w, "Nothing changed")
+		return
+	}
+
+	keycloakError := ""
+	if updated.State != targetDBUser.State && updated.KeycloakID.Valid && len(h.config.MembershipStateRoles) > 0 {
+		if kcClient, err := h.keycloakClient(); err != nil {
+			keycloakError = err.Error()
+		} else if _, err := h.pushStateRoles(ctx, kcClient, updated.KeycloakID.String, updated.State); err != nil {
+			keycloakError = fmt.Sprintf("failed to update roles: %v", err)
+		}
+		h.roleCache.Invalidate()
+	}
+
+	changesJSON, _ := json.Marshal(changes)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) updated user %s: %s",
+			adminUsername, adminDBUser.Email, targetDBUser.Email, strings.Join(changes, ", ")),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"changes":%s}`,
+				adminDBUser.ID, targetDBUser.ID, changesJSON),
+			Valid: true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"message":        fmt.Sprintf("User %s updated", updated.Email),
+		"user":           updated,
+		"changes":        changes,
+		"keycloak_error": keycloakError,
+	})
+}
+
+// validPaymentsID reports whether vs is empty or a Czech variable symbol (up to 10 digits)
+func validPaymentsID(vs string) bool {
+	return len(vs) <= 10 && strings.Trim(vs, "0123456789") == ""
+}
+
+// paymentsIDTaken returns who already uses the VS, or "" when it is free. Payments
+// are matched to members and projects by VS, it must be unique across both.
+func (h *Handler) paymentsIDTaken(ctx context.Context, vs string) (string, error) {
+	if other, err := h.queries.GetUserByPaymentsID(ctx, sql.NullString{String: vs, Valid: true}); err == nil {
+		return fmt.Sprintf("Variable symbol is already used by user #%d", other.ID), nil
+	} else if err != sql.ErrNoRows {
+		return "", err
+	}
+	if project, err := h.queries.GetProjectByPaymentsID(ctx, vs); err == nil {
+		return fmt.Sprintf("Variable symbol is already used by project %s", project.Name), nil
+	} else if err != sql.ErrNoRows {
+		return "", err
+	}
+	return "", nil
+}
@@ -3,15 +3,15 @@ package handler
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"html/template"
-	"math"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/base48/member-portal/internal/auth"
 	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
 	"github.com/base48/member-portal/internal/qrpay"
 	"github.com/go-chi/chi/v5"
 )
@@ -20,15 +20,6 @@ import (
 // GET /admin/users/:id
 func (h *Handler) AdminUserProfileHandler(w http.ResponseWriter, r *http.Request) {
 	currentUser := h.auth.GetUser(r)
-	if currentUser == nil {
-		http.Redirect(w, r, "/auth/login", http.StatusTemporaryRedirect)
-		return
-	}
-
-	if !currentUser.IsAdmin() {
-		http.Error(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
-	}
 
 	ctx := r.Context()
 
@@ -48,20 +39,12 @@ func (h *Handler) AdminUserProfileHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	// Get current admin's DB user for layout
-	adminDBUser, _ := h.queries.GetUserByKeycloakID(ctx, sql.NullString{
-		String: currentUser.ID,
-		Valid:  true,
-	})
+	adminDBUser := DBUserFrom(ctx)
 
 	// Fetch Keycloak info for target user (if linked)
 	var targetKeycloakUser *auth.User
 	if targetDBUser.KeycloakID.Valid && targetDBUser.KeycloakID.String != "" {
-		// Get service account token
-		accessToken, err := h.getServiceAccountToken(ctx)
-		if err == nil {
-			// Fetch user from Keycloak
-			targetKeycloakUser, _ = h.fetchKeycloakUserByID(ctx, accessToken, targetDBUser.KeycloakID.String)
-		}
+		targetKeycloakUser, _ = h.fetchKeycloakUserByID(ctx, targetDBUser.KeycloakID.String)
 	}
 
 	// If no Keycloak data, create minimal User object from DB
@@ -87,6 +70,27 @@ func (h *Handler) AdminUserProfileHandler(w http.ResponseWriter, r *http.Request
 	data["DBUser"] = adminDBUser              // For layout navbar (logged-in admin)
 	data["TargetUser"] = data["ViewedUser"]   // The user being viewed (rename for template)
 	data["Title"] = fmt.Sprintf("Profil uživatele: %s", targetDBUser.Email)
+	data["KeycloakDiff"] = h.buildKeycloakDiff(ctx, &targetDBUser)
+	if tickets, err := h.userTicketThreads(ctx, targetDBUser.ID); err == nil {
+		data["Tickets"] = tickets
+	}
+	if exemption, err := h.queries.GetSuspensionExemption(ctx, targetDBUser.ID); err == nil {
+		data["SuspensionExemption"] = exemption
+	}
+	if billing, err := h.queries.GetBillingDetails(ctx, targetDBUser.ID); err == nil {
+		data["Billing"] = billing
+	}
+	if anonymization, err := h.queries.GetUserAnonymization(ctx, targetDBUser.ID); err == nil {
+		data["Anonymization"] = anonymization
+	}
+	if notes, err := h.queries.ListUserNotes(ctx, targetDBUser.ID); err == nil {
+		data["Notes"] = notes
+	}
+	// The edit form offers inactive levels too, the member may still be on one
+	if levels, err := h.queries.ListAllLevels(ctx); err == nil {
+		data["AllLevels"] = levels
+	}
+	data["FakturoidEnabled"] = h.config.FakturoidEnabled()
 
 	// Log admin action (track who viewed whose profile)
 	adminUsername := "unknown"
@@ -142,20 +146,22 @@ func (h *Handler) buildProfileData(ctx context.Context, targetDBUser *db.User, t
 	balance, err := h.queries.GetUserBalance(ctx, db.GetUserBalanceParams{
 		UserID:   sql.NullInt64{Int64: targetDBUser.ID, Valid: true},
 		UserID_2: targetDBUser.ID,
+		UserID_3: sql.NullInt64{Int64: targetDBUser.ID, Valid: true},
+		UserID_4: targetDBUser.ID,
+		UserID_5: targetDBUser.ID,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate balance: %w", err)
 	}
 
 	// Calculate total paid (sum of all payments) and filter small payments for display
-	var totalPaid float64
+	var totalPaid money.Amount
 	var displayPayments []db.Payment
 	for _, payment := range payments {
-		var amount float64
-		fmt.Sscanf(payment.Amount, "%f", &amount)
-		totalPaid += amount
+		totalPaid += payment.Amount
 		// Only show payments >= 5 Kč in the table (small amounts like interest clutter the view)
-		if amount >= 5 {
+		// Reversals are negative but always shown so the member sees why the balance dropped
+		if payment.Amount >= 500 || payment.ReversalOf.Valid {
 			displayPayments = append(displayPayments, payment)
 		}
 	}
@@ -163,130 +169,123 @@ func (h *Handler) buildProfileData(ctx context.Context, targetDBUser *db.User, t
 	// Build Keycloak account URL
 	keycloakAccountURL := fmt.Sprintf("%s/realms/%s/account", h.config.KeycloakURL, h.config.KeycloakRealm)
 
-	// Generate QR payment code if user has PaymentsID (variable symbol) and has debt
-	var paymentQRCode string
+	// Next fee (for the rest of the quarter / year with a longer billing cycle)
+	nextFee := h.upcomingFee(ctx, targetDBUser, level, time.Now())
+	pause := h.membershipPause(ctx, targetDBUser.ID, time.Now())
+	termination := h.membershipTermination(ctx, targetDBUser.ID)
+	plan := h.paymentPlan(ctx, targetDBUser.ID, money.Amount(balance), time.Now())
+
+	// QR payment code if user has PaymentsID (variable symbol): for the debt, otherwise
+	// the next fee. The image is served by /qr/payment.png, not embedded.
+	var paymentQRURL string
 	var qrAmount float64
+	qrFormat := h.userQRFormat(ctx, targetDBUser.ID)
 	if h.qrpayService.IsConfigured() && targetDBUser.PaymentsID.Valid && targetDBUser.PaymentsID.String != "" {
-		// Generate QR for debt repayment or monthly fee
-		var qrMessage string
-
-		if balance < 0 {
-			// User has debt - generate QR for full debt amount
-			qrAmount = math.Abs(float64(balance))
-			qrMessage = "CLENSKY PRISPEVEK BASE48"
-		} else {
-			// No debt - generate QR for monthly fee
-			var levelAmount float64
-			fmt.Sscanf(level.Amount, "%f", &levelAmount)
-			// Use custom amount if set and higher than level minimum
-			var customAmount float64
-			fmt.Sscanf(targetDBUser.LevelActualAmount, "%f", &customAmount)
-			if customAmount > levelAmount {
-				qrAmount = customAmount
-			} else {
-				qrAmount = levelAmount
-			}
-			qrMessage = "CLENSKY PRISPEVEK BASE48"
+		var dueDate time.Time
+		if plan != nil && plan.AmountDue > 0 {
+			qrAmount = plan.AmountDue.Float64() // keeps the member on the plan
+		} else if balance < 0 {
+			qrAmount = money.Amount(-balance).Float64()
+		} else if nextFee != nil {
+			qrAmount = nextFee.Amount
+			dueDate = nextFee.dueDate
+		} else if pause == nil || !pause.Active { // nothing to pay while paused until cleared
+			qrAmount = monthlyFeeAmount(level, targetDBUser).Float64()
 		}
 
 		if qrAmount > 0 {
-			qrCode, err := h.qrpayService.GeneratePaymentQR(qrpay.GenerateParams{
-				Amount:         qrAmount,
+			// In the format of the member's banking app (SPAYD, SEPA or PAY by square)
+			if _, err := h.qrpayService.Payload(qrFormat, qrpay.GenerateParams{
 				VariableSymbol: targetDBUser.PaymentsID.String,
-				Message:        qrMessage,
-				Size:           200,
-			})
-			if err == nil {
-				paymentQRCode = qrCode
+				RecipientName:  h.config.BankName,
+			}); err != nil {
+				qrFormat = qrpay.FormatSPAYD // e.g. BANK_NAME missing for SEPA codes
+			}
+			req := qrpay.ImageRequest{Amount: qrAmount, DueDate: dueDate, Size: 200, Format: qrFormat}
+
+			if viewer := DBUserFrom(ctx); viewer != nil && viewer.ID == targetDBUser.ID {
+				paymentQRURL = "/qr/payment.png?" + req.Query().Encode()
+			} else {
+				// Admin view of another member's profile
+				req.VariableSymbol = targetDBUser.PaymentsID.String
+				paymentQRURL = h.signedPaymentQRURL(req, time.Hour)
 			}
 		}
 	}
 
+	// Level change requests and history (optional, the profile works without them)
+	levels, _ := h.queries.ListLevels(ctx)
+	levelChanges, _ := h.queries.ListLevelChangeRequestsByUser(ctx, targetDBUser.ID)
+	levelHistory, _ := h.queries.ListLevelHistoryByUser(ctx, targetDBUser.ID)
+	adjustments, _ := h.balanceAdjustments(ctx, targetDBUser.ID)
+
+	// Everything counted in the balance, newest first for the profile
+	ledger, _ := h.userLedger(ctx, targetDBUser.ID)
+	slices.Reverse(ledger)
+
+	// Invoices issued for fees and charges, linked from their rows
+	itemInvoices, _ := h.itemInvoices(ctx, targetDBUser.ID)
+
+	// Onboarding checklist of new members
+	onboarding, _ := h.userOnboarding(ctx, targetDBUser.ID)
+
 	return map[string]interface{}{
 		"ViewedUser":         targetUser,    // The user being viewed (renamed for clarity)
 		"TargetDBUser":       targetDBUser,  // The user being viewed (DB record)
 		"Level":              level,
 		"Payments":           displayPayments, // Filtered: only payments >= 5 Kč
 		"Fees":               fees,
-		"Balance":            float64(balance),
-		"TotalPaid":          int64(totalPaid),
+		"Balance":            money.Amount(balance).Float64(),
+		"TotalPaid":          int64(totalPaid.Float64()),
 		"KeycloakAccountURL": keycloakAccountURL,
 		"IsAdminView":        false, // Default, will be overridden if admin view
-		"PaymentQRCode":      template.URL(paymentQRCode), // Mark as safe URL for template
+		"PaymentQRURL":       paymentQRURL,
 		"QRAmount":           qrAmount,
+		"QRFormat":           qrFormat,
+		"QRFormats":          qrFormats,
+		"NextFee":            nextFee,
+		"BillingCycle":       h.userBillingCycle(ctx, targetDBUser.ID),
+		"BillingCycles":      billingCycles,
+		"Pause":              pause,
+		"Termination":        termination,
+		"PaymentPlan":        plan,
+		"Charges":            h.userCharges(ctx, targetDBUser.ID),
+		"ChargeCategories":   chargeCategories,
+		"ItemInvoices":       itemInvoices,
+		"FakturoidInvoices":  h.feeFakturoidInvoices(ctx, targetDBUser.ID),
+		"Adjustments":        adjustments,
+		"Ledger":             ledger,
+		"Levels":             levels,
+		"LevelChanges":       levelChanges,
+		"LevelHistory":       levelHistory,
+		"Onboarding":         onboarding,
 	}, nil
 }
 
-// fetchKeycloakUserByID fetches a user from Keycloak by their ID
-func (h *Handler) fetchKeycloakUserByID(ctx context.Context, accessToken, keycloakID string) (*auth.User, error) {
-	url := fmt.Sprintf("%s/admin/realms/%s/users/%s", h.config.KeycloakURL, h.config.KeycloakRealm, keycloakID)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// fetchKeycloakUserByID fetches a user and their realm roles from Keycloak
+func (h *Handler) fetchKeycloakUserByID(ctx context.Context, keycloakID string) (*auth.User, error) {
+	kcClient, err := h.keycloakClient()
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	resp, err := http.DefaultClient.Do(req)
+	kcUser, err := kcClient.GetUser(ctx, keycloakID)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("keycloak returned status %d", resp.StatusCode)
-	}
-
-	var kcUser KeycloakUserInfo
-	if err := json.NewDecoder(resp.Body).Decode(&kcUser); err != nil {
-		return nil, err
+	// Roles are optional for the profile view - show the user even if this fails
+	roleNames := []string{}
+	if roles, err := kcClient.GetUserRoles(ctx, keycloakID); err == nil {
+		for _, role := range roles {
+			roleNames = append(roleNames, role.Name)
+		}
 	}
 
-	// Fetch roles for this user
-	roles, _ := h.fetchUserRolesFromKeycloak(ctx, accessToken, keycloakID)
-
 	return &auth.User{
 		ID:            kcUser.ID,
 		Email:         kcUser.Email,
 		PreferredName: kcUser.Username,
-		Roles:         roles,
+		Roles:         roleNames,
 	}, nil
 }
-
-// fetchUserRolesFromKeycloak fetches roles for a specific user
-func (h *Handler) fetchUserRolesFromKeycloak(ctx context.Context, accessToken, keycloakID string) ([]string, error) {
-	url := fmt.Sprintf("%s/admin/realms/%s/users/%s/role-mappings/realm",
-		h.config.KeycloakURL, h.config.KeycloakRealm, keycloakID)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return []string{}, nil
-	}
-
-	var kcRoles []struct {
-		Name string `json:"name"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&kcRoles); err != nil {
-		return nil, err
-	}
-
-	roles := make([]string, len(kcRoles))
-	for i, r := range kcRoles {
-		roles[i] = r.Name
-	}
-
-	return roles, nil
-}
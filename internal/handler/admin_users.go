@@ -5,13 +5,14 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/base48/member-portal/internal/db"
 	"github.com/base48/member-portal/internal/keycloak"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/go-chi/chi/v5"
 )
 
 // KeycloakUserInfo contains info from Keycloak API
@@ -20,342 +21,682 @@ type KeycloakUserInfo struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Enabled  bool   `json:"enabled"`
+
+	EmailVerified   bool     `json:"emailVerified"`
+	OTPConfigured   bool     `json:"totp"`
+	RequiredActions []string `json:"requiredActions"`
 }
 
 // AdminUserListItem combines database and Keycloak info
 type AdminUserListItem struct {
 	DBUser           db.User
-	KeycloakEnabled  *bool  // nil if not found in Keycloak
+	KeycloakEnabled  *bool // nil if not found in Keycloak
 	KeycloakUsername string
+	EmailVerified    bool     // Keycloak email verified (only valid when found in Keycloak)
+	OTPConfigured    bool     // Keycloak OTP set up (only valid when found in Keycloak)
+	RequiredActions  []string // Pending Keycloak required actions
 	Roles            []string
-	Balance          int64
+	Balance          money.Amount
 }
 
-// AdminUsersHandler shows admin overview of all users with Keycloak status and roles
-// GET /admin/users
-func (h *Handler) AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil {
-		http.Redirect(w, r, "/auth/login", http.StatusTemporaryRedirect)
-		return
+// IsKeycloakEnabled reports whether the linked Keycloak account is enabled
+// (templates treat a non-nil *bool as true, so they need this helper)
+func (i AdminUserListItem) IsKeycloakEnabled() bool {
+	return i.KeycloakEnabled != nil && *i.KeycloakEnabled
+}
+
+// Page sizes of the admin user list
+const (
+	defaultAdminUsersPerPage = 50
+	maxAdminUsersPerPage     = 500
+)
+
+// AdminUserListQuery holds the filters, sort and page of the admin user list
+type AdminUserListQuery struct {
+	State    string // users.state, empty = all
+	Keycloak string // linked, not_linked, enabled, disabled, no_otp, unverified_email
+	Balance  string // positive, negative
+	Search   string // email, name or nickname substring, or exact VS
+	Sort     string // id_asc, id_desc, balance_asc, balance_desc, name; empty = newest first
+	Page     int    // 1-based
+	PerPage  int
+}
+
+// parseAdminUserListQuery reads the user list parameters from the query string;
+// a missing or invalid page or page size falls back to the default
+func parseAdminUserListQuery(r *http.Request) AdminUserListQuery {
+	query := r.URL.Query()
+	q := AdminUserListQuery{
+		State:    query.Get("state"),
+		Keycloak: query.Get("keycloak"),
+		Balance:  query.Get("balance"),
+		Search:   strings.TrimSpace(query.Get("search")),
+		Sort:     query.Get("sort"),
+		Page:     1,
+		PerPage:  defaultAdminUsersPerPage,
+	}
+	if page, err := strconv.Atoi(query.Get("page")); err == nil && page > 0 {
+		q.Page = page
+	}
+	if perPage, err := strconv.Atoi(query.Get("per_page")); err == nil && perPage > 0 {
+		q.PerPage = min(perPage, maxAdminUsersPerPage)
 	}
+	return q
+}
 
-	if !user.IsAdmin() {
-		http.Error(w, "Forbidden - admin access required", http.StatusForbidden)
-		return
+// listAdminUsers returns one page of the admin user list and the number of users
+// matching the filters. Filtering, sorting and paging is done in SQL; filters on
+// the Keycloak account status are turned into the list of matching Keycloak IDs,
+// and Keycloak info and roles are added to the rows of the page only.
+func (h *Handler) listAdminUsers(ctx context.Context, q AdminUserListQuery, keycloakUsers map[string]KeycloakUserInfo, userRoles map[string][]string) ([]AdminUserListItem, int64, error) {
+	params := db.ListUsersPageParams{
+		State:   q.State,
+		Search:  q.Search,
+		Balance: q.Balance,
+		Sort:    q.Sort,
+		Limit:   int64(q.PerPage),
+		Offset:  int64(q.Page-1) * int64(q.PerPage),
+	}
+	switch q.Keycloak {
+	case "linked", "not_linked":
+		params.Linked = q.Keycloak
+	case "enabled", "disabled", "no_otp", "unverified_email":
+		keycloakIDs := []string{}
+		for id, kcUser := range keycloakUsers {
+			if matchesKeycloakStatus(kcUser, q.Keycloak) {
+				keycloakIDs = append(keycloakIDs, id)
+			}
+		}
+		idsJSON, err := json.Marshal(keycloakIDs)
+		if err != nil {
+			return nil, 0, err
+		}
+		params.KeycloakIDs = sql.NullString{String: string(idsJSON), Valid: true}
 	}
 
-	ctx := r.Context()
+	rows, err := h.queries.ListUsersPage(ctx, params)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	// Get filter and sort parameters from query string
-	filterState := r.URL.Query().Get("state")
-	filterKeycloak := r.URL.Query().Get("keycloak")
-	filterBalance := r.URL.Query().Get("balance")
-	filterSearch := strings.ToLower(r.URL.Query().Get("search"))
-	sortBy := r.URL.Query().Get("sort")
+	var total int64
+	userList := make([]AdminUserListItem, 0, len(rows))
+	for _, row := range rows {
+		total = row.Total
+		item := AdminUserListItem{
+			DBUser: db.User{
+				ID:                row.ID,
+				KeycloakID:        row.KeycloakID,
+				Email:             row.Email,
+				Username:          row.Username,
+				Realname:          row.Realname,
+				Phone:             row.Phone,
+				AltContact:        row.AltContact,
+				LevelID:           row.LevelID,
+				LevelActualAmount: row.LevelActualAmount,
+				PaymentsID:        row.PaymentsID,
+				DateJoined:        row.DateJoined,
+				KeysGranted:       row.KeysGranted,
+				KeysReturned:      row.KeysReturned,
+				State:             row.State,
+				IsCouncil:         row.IsCouncil,
+				IsStaff:           row.IsStaff,
+				CreatedAt:         row.CreatedAt,
+				UpdatedAt:         row.UpdatedAt,
+			},
+			Balance: money.Amount(row.Balance),
+		}
 
-	// Get all users from database
-	dbUsers, err := h.queries.ListUsers(ctx)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
-		return
+		// Match with Keycloak user
+		if row.KeycloakID.Valid && row.KeycloakID.String != "" {
+			if kcUser, found := keycloakUsers[row.KeycloakID.String]; found {
+				item.KeycloakEnabled = &kcUser.Enabled
+				item.KeycloakUsername = kcUser.Username
+				item.EmailVerified = kcUser.EmailVerified
+				item.OTPConfigured = kcUser.OTPConfigured
+				item.RequiredActions = kcUser.RequiredActions
+
+				// System roles are already filtered out by the loader
+				item.Roles = userRoles[row.KeycloakID.String]
+			}
+		}
+
+		userList = append(userList, item)
+	}
+
+	// A page past the end has no rows to read the total from
+	if len(rows) == 0 && q.Page > 1 {
+		first := q
+		first.Page = 1
+		_, total, err = h.listAdminUsers(ctx, first, keycloakUsers, userRoles)
+	}
+
+	return userList, total, err
+}
+
+// matchesKeycloakStatus checks a Keycloak account against the Keycloak status filter
+func matchesKeycloakStatus(kcUser KeycloakUserInfo, status string) bool {
+	switch status {
+	case "enabled":
+		return kcUser.Enabled
+	case "disabled":
+		return !kcUser.Enabled
+	case "no_otp":
+		return !kcUser.OTPConfigured
+	case "unverified_email":
+		return !kcUser.EmailVerified
 	}
+	return true
+}
+
+// AdminUsersHandler shows admin overview of users with Keycloak status and roles,
+// one page of the filtered list at a time. The page is rendered on the server
+// through listAdminUsers, the same code as AdminUsersAPIHandler. Keycloak users
+// and roles come from the caches refreshed in the background; Keycloak is only
+// called when a cache is empty (after start or an invalidation).
+// GET /admin/users?page=&per_page=&sort=&state=&keycloak=&balance=&search=
+func (h *Handler) AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	user := h.auth.GetUser(r)
+
+	ctx := r.Context()
+
+	q := parseAdminUserListQuery(r)
 
 	// Get service account token for Keycloak API
-	accessToken, err := h.getServiceAccountToken(ctx)
+	kcClient, err := h.keycloakClient()
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Service account error: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	kcClient := keycloak.NewClient(h.config, accessToken)
-
 	// Fetch all Keycloak users once (more efficient than per-user requests)
-	keycloakUsers, err := h.fetchAllKeycloakUsers(ctx, accessToken)
+	keycloakUsers, err := h.fetchAllKeycloakUsers(ctx)
 	if err != nil {
 		// Log error but continue - we can still show DB data
 		fmt.Printf("[AdminUsers] Warning: Failed to fetch Keycloak users: %v\n", err)
 		keycloakUsers = make(map[string]KeycloakUserInfo)
 	}
 
-	// Build combined user list with filtering
-	userList := make([]AdminUserListItem, 0, len(dbUsers))
-
-	for _, dbUser := range dbUsers {
-		item := AdminUserListItem{
-			DBUser: dbUser,
-		}
-
-		// Get balance
-		if balance, err := h.queries.GetUserBalance(ctx, db.GetUserBalanceParams{
-			UserID:   sql.NullInt64{Int64: dbUser.ID, Valid: true},
-			UserID_2: dbUser.ID,
-		}); err == nil {
-			item.Balance = balance
-		}
+	// Fetch roles of all users at once (cached, one request per realm role)
+	userRoles, err := h.roleCache.Get(ctx, kcClient)
+	if err != nil {
+		fmt.Printf("[AdminUsers] Warning: Failed to fetch Keycloak roles: %v\n", err)
+		userRoles = make(map[string][]string)
+	}
 
-		// Match with Keycloak user
-		if dbUser.KeycloakID.Valid && dbUser.KeycloakID.String != "" {
-			if kcUser, found := keycloakUsers[dbUser.KeycloakID.String]; found {
-				item.KeycloakEnabled = &kcUser.Enabled
-				item.KeycloakUsername = kcUser.Username
+	userList, total, err := h.listAdminUsers(ctx, q, keycloakUsers, userRoles)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
 
-				// Get user's roles from Keycloak
-				if roles, err := kcClient.GetUserRoles(ctx, dbUser.KeycloakID.String); err == nil {
-					roleNames := make([]string, 0, len(roles))
-					for _, role := range roles {
-						// Filter out default/system roles
-						if !strings.HasPrefix(role.Name, "default-") &&
-							!strings.HasPrefix(role.Name, "uma_") &&
-							role.Name != "offline_access" {
-							roleNames = append(roleNames, role.Name)
-						}
-					}
-					item.Roles = roleNames
-				}
+	// Required-action report: enabled member accounts missing OTP or email
+	// verification (all members, not just the page)
+	noOTPKeycloakIDs := []string{}
+	countUnverifiedEmail := 0
+	if linkedIDs, err := h.queries.ListLinkedKeycloakIDs(ctx); err == nil {
+		for _, id := range linkedIDs {
+			kcUser, found := keycloakUsers[id.String]
+			if !found || !kcUser.Enabled {
+				continue
+			}
+			if !kcUser.OTPConfigured {
+				noOTPKeycloakIDs = append(noOTPKeycloakIDs, id.String)
+			}
+			if !kcUser.EmailVerified {
+				countUnverifiedEmail++
 			}
 		}
-
-		// Apply filters
-		if !matchesFilters(item, filterState, filterKeycloak, filterBalance, filterSearch) {
-			continue
-		}
-
-		userList = append(userList, item)
 	}
 
-	// Apply sorting
-	sortUserList(userList, sortBy)
+	// Links to the neighbouring pages keep the filters
+	pageURL := func(page int) string {
+		query := r.URL.Query()
+		query.Set("page", strconv.Itoa(page))
+		return "/admin/users?" + query.Encode()
+	}
+	offset := int64(q.Page-1) * int64(q.PerPage)
+	prevURL, nextURL := "", ""
+	if q.Page > 1 {
+		prevURL = pageURL(q.Page - 1)
+	}
+	if offset+int64(len(userList)) < total {
+		nextURL = pageURL(q.Page + 1)
+	}
 
 	// Render template
 	data := map[string]interface{}{
 		"Title":          "Admin - Users",
 		"User":           user,
 		"UserList":       userList,
-		"FilterState":    filterState,
-		"FilterKeycloak": filterKeycloak,
-		"FilterBalance":  filterBalance,
-		"FilterSearch":   r.URL.Query().Get("search"), // Original case
-		"SortBy":         sortBy,
+		"FilterState":    q.State,
+		"FilterKeycloak": q.Keycloak,
+		"FilterBalance":  q.Balance,
+		"FilterSearch":   q.Search,
+		"SortBy":         q.Sort,
+		"PerPage":        q.PerPage,
+
+		"Total":     total,
+		"ShownFrom": offset + 1,
+		"ShownTo":   offset + int64(len(userList)),
+		"PrevURL":   prevURL,
+		"NextURL":   nextURL,
+
+		"NoOTPKeycloakIDs":     noOTPKeycloakIDs,
+		"CountUnverifiedEmail": countUnverifiedEmail,
+	}
+	if levels, err := h.queries.ListLevels(ctx); err == nil {
+		data["Levels"] = levels
 	}
 
 	h.render(w, "admin_users.html", data)
 }
 
-// matchesFilters checks if a user item matches the given filter criteria
-func matchesFilters(item AdminUserListItem, state, keycloak, balance, search string) bool {
-	// Filter by state
-	if state != "" && item.DBUser.State != state {
-		return false
+// AdminUsersAPIHandler returns one page of the filtered user list with Keycloak info;
+// the parameters are those of the admin users page, total counts all matching users
+// GET /api/admin/users?page=&per_page=&sort=&state=&keycloak=&balance=&search=
+func (h *Handler) AdminUsersAPIHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	q := parseAdminUserListQuery(r)
+
+	// Get service account token for Keycloak API
+	kcClient, err := h.keycloakClient()
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Service account error: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	// Filter by Keycloak status
-	if keycloak != "" {
-		switch keycloak {
-		case "linked":
-			if !item.DBUser.KeycloakID.Valid || item.DBUser.KeycloakID.String == "" {
-				return false
-			}
-		case "not_linked":
-			if item.DBUser.KeycloakID.Valid && item.DBUser.KeycloakID.String != "" {
-				return false
-			}
-		case "enabled":
-			if item.KeycloakEnabled == nil || !*item.KeycloakEnabled {
-				return false
-			}
-		case "disabled":
-			if item.KeycloakEnabled == nil || *item.KeycloakEnabled {
-				return false
-			}
-		}
+	// Fetch all Keycloak users
+	keycloakUsers, err := h.fetchAllKeycloakUsers(ctx)
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Keycloak error: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	// Filter by balance
-	if balance != "" {
-		switch balance {
-		case "positive":
-			if item.Balance < 0 {
-				return false
-			}
-		case "negative":
-			if item.Balance >= 0 {
-				return false
-			}
-		}
+	// Fetch roles of all users at once (cached, one request per realm role)
+	userRoles, err := h.roleCache.Get(ctx, kcClient)
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Keycloak error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	userList, total, err := h.listAdminUsers(ctx, q, keycloakUsers, userRoles)
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Build response
+	type UserResponse struct {
+		ID               int64    `json:"id"`
+		Email            string   `json:"email"`
+		Username         string   `json:"username"`
+		Realname         string   `json:"realname"`
+		PaymentsID       string   `json:"payments_id"`
+		State            string   `json:"state"`
+		Balance          float64  `json:"balance"`
+		KeycloakID       string   `json:"keycloak_id"`
+		KeycloakEnabled  *bool    `json:"keycloak_enabled"`
+		KeycloakUsername string   `json:"keycloak_username"`
+		EmailVerified    bool     `json:"email_verified"`
+		OTPConfigured    bool     `json:"otp_configured"`
+		RequiredActions  []string `json:"required_actions"`
+		Roles            []string `json:"roles"`
 	}
 
-	// Filter by search (email or realname)
-	if search != "" {
-		emailMatch := strings.Contains(strings.ToLower(item.DBUser.Email), search)
-		nameMatch := item.DBUser.Realname.Valid && strings.Contains(strings.ToLower(item.DBUser.Realname.String), search)
-		if !emailMatch && !nameMatch {
-			return false
+	response := make([]UserResponse, 0, len(userList))
+	for _, item := range userList {
+		userResp := UserResponse{
+			ID:               item.DBUser.ID,
+			Email:            item.DBUser.Email,
+			Username:         item.DBUser.Username.String,
+			Realname:         item.DBUser.Realname.String,
+			PaymentsID:       item.DBUser.PaymentsID.String,
+			State:            item.DBUser.State,
+			Balance:          item.Balance.Float64(),
+			KeycloakID:       item.DBUser.KeycloakID.String,
+			KeycloakEnabled:  item.KeycloakEnabled,
+			KeycloakUsername: item.KeycloakUsername,
+			EmailVerified:    item.EmailVerified,
+			OTPConfigured:    item.OTPConfigured,
+			RequiredActions:  item.RequiredActions,
+			Roles:            item.Roles,
+		}
+		if item.KeycloakEnabled != nil && userResp.Roles == nil {
+			userResp.Roles = []string{}
 		}
+
+		response = append(response, userResp)
 	}
 
-	return true
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"users":    response,
+		"total":    total,
+		"page":     q.Page,
+		"per_page": q.PerPage,
+	})
 }
 
-// sortUserList sorts the user list based on the sort parameter
-func sortUserList(userList []AdminUserListItem, sortBy string) {
-	switch sortBy {
-	case "id_asc":
-		sort.Slice(userList, func(i, j int) bool {
-			return userList[i].DBUser.ID < userList[j].DBUser.ID
-		})
-	case "id_desc":
-		sort.Slice(userList, func(i, j int) bool {
-			return userList[i].DBUser.ID > userList[j].DBUser.ID
-		})
-	case "balance_asc":
-		sort.Slice(userList, func(i, j int) bool {
-			return userList[i].Balance < userList[j].Balance
-		})
-	case "balance_desc":
-		sort.Slice(userList, func(i, j int) bool {
-			return userList[i].Balance > userList[j].Balance
-		})
-	default:
-		// Default: sort by ID descending (newest members first)
-		sort.Slice(userList, func(i, j int) bool {
-			return userList[i].DBUser.ID > userList[j].DBUser.ID
-		})
+// fetchAllKeycloakUsers returns all Keycloak users as a map keyed by ID (served from cache)
+func (h *Handler) fetchAllKeycloakUsers(ctx context.Context) (map[string]KeycloakUserInfo, error) {
+	users, err := h.userCache.Get(ctx)
+	if err != nil {
+		return nil, err
 	}
+
+	userMap := make(map[string]KeycloakUserInfo, len(users))
+	for id, user := range users {
+		userMap[id] = KeycloakUserInfo{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Enabled:  user.Enabled,
+
+			EmailVerified:   user.EmailVerified,
+			OTPConfigured:   user.Totp,
+			RequiredActions: user.RequiredActions,
+		}
+	}
+
+	return userMap, nil
 }
 
-// AdminUsersAPIHandler returns JSON list of users with Keycloak info
-// GET /api/admin/users
-func (h *Handler) AdminUsersAPIHandler(w http.ResponseWriter, r *http.Request) {
-	user := h.auth.GetUser(r)
-	if user == nil || !user.IsAdmin() {
-		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
+// AdminKeycloakRefreshHandler reloads cached Keycloak users and roles, so the next
+// user list does not wait for Keycloak
+// POST /api/admin/keycloak/refresh
+func (h *Handler) AdminKeycloakRefreshHandler(w http.ResponseWriter, r *http.Request) {
+	kcClient, err := h.keycloakClient()
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Service account error: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	ctx := r.Context()
+	if err := h.roleCache.Refresh(r.Context(), kcClient); err != nil {
+		h.roleCache.Invalidate()
+		h.jsonError(w, fmt.Sprintf("Keycloak error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := h.userCache.Refresh(r.Context()); err != nil {
+		h.userCache.Invalidate()
+		h.jsonError(w, fmt.Sprintf("Keycloak error: %v", err), http.StatusInternalServerError)
+		return
+	}
 
-	// Get all users from database
-	dbUsers, err := h.queries.ListUsers(ctx)
-	if err != nil {
-		h.jsonError(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+	h.jsonSuccess(w, "Keycloak cache refreshed")
+}
+
+// OTPReminderRequest is the request body for sending OTP setup reminders
+type OTPReminderRequest struct {
+	// KeycloakIDs limits reminders to these users; empty means all enabled users without OTP
+	KeycloakIDs []string `json:"keycloak_ids"`
+}
+
+// AdminOTPReminderHandler asks Keycloak to email an OTP setup link to members without OTP
+// POST /api/admin/keycloak/otp-reminder
+func (h *Handler) AdminOTPReminderHandler(w http.ResponseWriter, r *http.Request) {
+	var req OTPReminderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Get service account token for Keycloak API
-	accessToken, err := h.getServiceAccountToken(ctx)
+	ctx := r.Context()
+
+	adminDBUser := DBUserFrom(ctx)
+
+	kcClient, err := h.keycloakClient()
 	if err != nil {
 		h.jsonError(w, fmt.Sprintf("Service account error: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	kcClient := keycloak.NewClient(h.config, accessToken)
-
-	// Fetch all Keycloak users
-	keycloakUsers, err := h.fetchAllKeycloakUsers(ctx, accessToken)
+	keycloakUsers, err := h.fetchAllKeycloakUsers(ctx)
 	if err != nil {
 		h.jsonError(w, fmt.Sprintf("Keycloak error: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Build response
-	type UserResponse struct {
-		ID               int64    `json:"id"`
-		Email            string   `json:"email"`
-		Realname         string   `json:"realname"`
-		State            string   `json:"state"`
-		Balance          int64    `json:"balance"`
-		KeycloakID       string   `json:"keycloak_id"`
-		KeycloakEnabled  *bool    `json:"keycloak_enabled"`
-		KeycloakUsername string   `json:"keycloak_username"`
-		Roles            []string `json:"roles"`
+	// Only portal members are reminded, not every account in the realm
+	dbUsers, err := h.queries.ListUsers(ctx)
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	response := make([]UserResponse, 0, len(dbUsers))
+	selected := make(map[string]bool, len(req.KeycloakIDs))
+	for _, id := range req.KeycloakIDs {
+		selected[id] = true
+	}
 
+	sent := 0
+	var failed []string
 	for _, dbUser := range dbUsers {
-		userResp := UserResponse{
-			ID:       dbUser.ID,
-			Email:    dbUser.Email,
-			Realname: dbUser.Realname.String,
-			State:    dbUser.State,
+		if !dbUser.KeycloakID.Valid || dbUser.KeycloakID.String == "" {
+			continue
+		}
+		if len(selected) > 0 && !selected[dbUser.KeycloakID.String] {
+			continue
 		}
 
-		// Get balance
-		if balance, err := h.queries.GetUserBalance(ctx, db.GetUserBalanceParams{
-			UserID:   sql.NullInt64{Int64: dbUser.ID, Valid: true},
-			UserID_2: dbUser.ID,
-		}); err == nil {
-			userResp.Balance = balance
+		kcUser, found := keycloakUsers[dbUser.KeycloakID.String]
+		if !found || !kcUser.Enabled || kcUser.OTPConfigured {
+			continue
 		}
 
-		// Keycloak info
-		if dbUser.KeycloakID.Valid && dbUser.KeycloakID.String != "" {
-			userResp.KeycloakID = dbUser.KeycloakID.String
-
-			if kcUser, found := keycloakUsers[dbUser.KeycloakID.String]; found {
-				userResp.KeycloakEnabled = &kcUser.Enabled
-				userResp.KeycloakUsername = kcUser.Username
-
-				// Get roles
-				if roles, err := kcClient.GetUserRoles(ctx, dbUser.KeycloakID.String); err == nil {
-					roleNames := make([]string, 0)
-					for _, role := range roles {
-						if !strings.HasPrefix(role.Name, "default-") &&
-							!strings.HasPrefix(role.Name, "uma_") &&
-							role.Name != "offline_access" {
-							roleNames = append(roleNames, role.Name)
-						}
-					}
-					userResp.Roles = roleNames
-				}
-			}
+		if err := kcClient.ExecuteActionsEmail(ctx, kcUser.ID, []string{keycloak.ActionConfigureTOTP}); err != nil {
+			fmt.Printf("[OTPReminder] Failed to send reminder to %s: %v\n", dbUser.Email, err)
+			failed = append(failed, dbUser.Email)
+			continue
 		}
+		sent++
+	}
 
-		response = append(response, userResp)
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
 	}
 
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) sent OTP setup reminder to %d members (%d failed)",
+			adminUsername, adminDBUser.Email, sent, len(failed)),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"sent":%d,"failed":%d}`, adminDBUser.ID, sent, len(failed)),
+			Valid:  true,
+		},
+	})
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"users":   response,
+		"sent":    sent,
+		"failed":  failed,
 	})
 }
 
+// AdminEnableKeycloakUserHandler enables the member's Keycloak account
+// POST /api/admin/users/{id}/keycloak/enable
+func (h *Handler) AdminEnableKeycloakUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.setKeycloakUserEnabled(w, r, true)
+}
+
+// AdminDisableKeycloakUserHandler disables the member's Keycloak account so they cannot log in
+// POST /api/admin/users/{id}/keycloak/disable
+func (h *Handler) AdminDisableKeycloakUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.setKeycloakUserEnabled(w, r, false)
+}
+
+func (h *Handler) setKeycloakUserEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	user := h.auth.GetUser(r)
 
-// fetchAllKeycloakUsers fetches all users from Keycloak API and returns them as a map
-func (h *Handler) fetchAllKeycloakUsers(ctx context.Context, accessToken string) (map[string]KeycloakUserInfo, error) {
-	url := fmt.Sprintf("%s/admin/realms/%s/users", h.config.KeycloakURL, h.config.KeycloakRealm)
+	ctx := r.Context()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		return nil, err
+		h.jsonError(w, "Invalid user ID", http.StatusBadRequest)
+		return
 	}
 
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
+	targetDBUser, err := h.queries.GetUserByID(ctx, userID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if !targetDBUser.KeycloakID.Valid || targetDBUser.KeycloakID.String == "" {
+		h.jsonError(w, "User is not linked to a Keycloak account", http.StatusBadRequest)
+		return
+	}
+
+	// Locking yourself out has to be done in the Keycloak console
+	if !enabled && targetDBUser.KeycloakID.String == user.ID {
+		h.jsonError(w, "You cannot disable your own account", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
 
-	resp, err := http.DefaultClient.Do(req)
+	kcClient, err := h.keycloakClient()
 	if err != nil {
-		return nil, err
+		h.jsonError(w, fmt.Sprintf("Service account error: %v", err), http.StatusInternalServerError)
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("keycloak API error: %s - %s", resp.Status, string(body))
+	action := "disabled"
+	if enabled {
+		action = "enabled"
+		err = kcClient.EnableUser(ctx, targetDBUser.KeycloakID.String)
+	} else {
+		err = kcClient.DisableUser(ctx, targetDBUser.KeycloakID.String)
+	}
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Keycloak error: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	var users []KeycloakUserInfo
-	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
-		return nil, err
+	// Update the cached user so the users page shows the new state right away
+	if kcUser, err := kcClient.GetUser(ctx, targetDBUser.KeycloakID.String); err == nil {
+		h.userCache.Update(*kcUser)
+	} else {
+		h.userCache.Invalidate()
 	}
 
-	// Convert to map for fast lookups
-	userMap := make(map[string]KeycloakUserInfo, len(users))
-	for _, user := range users {
-		userMap[user.ID] = user
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
 	}
 
-	return userMap, nil
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) %s Keycloak account of %s",
+			adminUsername, adminDBUser.Email, action, targetDBUser.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"keycloak_id":%q,"enabled":%t}`,
+				adminDBUser.ID, targetDBUser.ID, targetDBUser.KeycloakID.String, enabled),
+			Valid: true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Keycloak account of %s %s", targetDBUser.Email, action),
+		"enabled": enabled,
+	})
+}
+
+// AdminProvisionKeycloakUserHandler creates a Keycloak account for a member without one,
+// emails them a link to set their password and links the account to the member
+// POST /api/admin/users/{id}/keycloak/provision
+func (h *Handler) AdminProvisionKeycloakUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	targetDBUser, err := h.queries.GetUserByID(ctx, userID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if targetDBUser.KeycloakID.Valid {
+		h.jsonError(w, "User is already linked to a Keycloak account", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	kcClient, err := h.keycloakClient()
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Service account error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	keycloakID, created, err := kcClient.ProvisionUser(ctx, targetDBUser.Email, targetDBUser.Username.String, targetDBUser.Realname.String)
+	if keycloakID == "" {
+		h.jsonError(w, fmt.Sprintf("Keycloak error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	// A failed password email is reported, but the account exists and must be linked anyway
+	emailError := ""
+	if err != nil {
+		emailError = err.Error()
+	}
+
+	if _, err := h.queries.LinkKeycloakID(ctx, db.LinkKeycloakIDParams{
+		KeycloakID: sql.NullString{String: keycloakID, Valid: true},
+		Email:      targetDBUser.Email,
+	}); err != nil {
+		h.jsonError(w, fmt.Sprintf("Failed to link Keycloak account: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.userCache.Invalidate()
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	action := "linked existing"
+	if created {
+		action = "created"
+	}
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) %s Keycloak account for %s",
+			adminUsername, adminDBUser.Email, action, targetDBUser.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"keycloak_id":%q,"created":%t,"email_error":%q}`,
+				adminDBUser.ID, targetDBUser.ID, keycloakID, created, emailError),
+			Valid: true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"keycloak_id": keycloakID,
+		"created":     created,
+		"email_error": emailError,
+	})
 }
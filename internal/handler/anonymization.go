@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/base48/member-portal/internal/anonymize"
+	"github.com/base48/member-portal/internal/db"
+)
+
+// AnonymizeRequest is the body of POST /api/admin/users/{id}/anonymize
+type AnonymizeRequest struct {
+	ConfirmEmail string `json:"confirm_email"` // the member's current email, typed by the admin
+}
+
+// AdminAnonymizePreviewHandler shows what anonymizing a former member would scrub
+// (the anonymization runs in a transaction that is rolled back)
+// GET /api/admin/users/{id}/anonymize
+func (h *Handler) AdminAnonymizePreviewHandler(w http.ResponseWriter, r *http.Request) {
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	summary, err := anonymize.Member(r.Context(), h.database, h.queries, targetDBUser.ID, DBUserFrom(r.Context()).Email, true)
+	if !h.anonymizeOK(w, err) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"summary": summary,
+	})
+}
+
+// AdminAnonymizeHandler scrubs the personal data of a former member. Payments,
+// fees and invoices stay with their amounts. Cannot be undone, so the admin has
+// to confirm it by typing the member's email.
+// POST /api/admin/users/{id}/anonymize
+// Body: {"confirm_email": "jan@example.com"}
+func (h *Handler) AdminAnonymizeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req AnonymizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !strings.EqualFold(strings.TrimSpace(req.ConfirmEmail), targetDBUser.Email) {
+		h.jsonError(w, "Confirmation does not match the member's email", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	summary, err := anonymize.Member(ctx, h.database, h.queries, targetDBUser.ID, adminDBUser.Email, false)
+	if !h.anonymizeOK(w, err) {
+		return
+	}
+
+	// The message must not name the member, it stays in the log after the anonymization
+	encoded, _ := json.Marshal(summary)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "warning",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s anonymized former member #%d", adminDBUser.Email, targetDBUser.ID),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"summary":%s}`, adminDBUser.ID, targetDBUser.ID, encoded),
+			Valid:  true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"summary": summary,
+	})
+}
+
+// anonymizeOK writes the error response of a failed anonymization
+func (h *Handler) anonymizeOK(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, anonymize.ErrNotFormerMember), errors.Is(err, anonymize.ErrAlreadyAnonymized):
+		h.jsonError(w, err.Error(), http.StatusConflict)
+	default:
+		h.jsonError(w, "Failed to anonymize member", http.StatusInternalServerError)
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/bankimport"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// maxIngestBatch limits how many payments a single ingest request may contain
+const maxIngestBatch = 500
+
+// IngestPayment is a normalized payment record sent by an external collector
+// (bar/credit system, GitHub Sponsors, ...)
+type IngestPayment struct {
+	ID             string          `json:"id"`             // unique within the source, used for deduplication
+	Date           string          `json:"date"`           // RFC 3339 or YYYY-MM-DD
+	Amount         float64         `json:"amount"`         // CZK, must be positive
+	Identification string          `json:"identification"` // payments_id of the member or project VS
+	RemoteAccount  string          `json:"remote_account"` // payer (account, username, ...)
+	Comment        string          `json:"comment"`
+	Raw            json.RawMessage `json:"raw,omitempty"` // original record, stored for reference
+}
+
+// IngestPaymentsRequest is the body of POST /api/ingest/payments
+type IngestPaymentsRequest struct {
+	Payments []IngestPayment `json:"payments"`
+}
+
+// IngestPaymentResult reports what happened to a single ingested record
+type IngestPaymentResult struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"` // inserted, updated, unchanged, error
+	PaymentID   int64  `json:"payment_id,omitempty"`
+	Matched     bool   `json:"matched"`                // assigned to a member
+	DuplicateOf int64  `json:"duplicate_of,omitempty"` // same content as a payment from another source, waits for review
+	Error       string `json:"error,omitempty"`
+}
+
+// IngestPaymentsHandler stores payments collected outside the bank account.
+// Authenticated by a per-source token (INGEST_TOKENS); the source becomes payments.kind,
+// so a token can only create and update payments of its own source.
+// POST /api/ingest/payments
+func (h *Handler) IngestPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	source, ok := h.ingestSource(r)
+	if !ok {
+		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req IngestPaymentsRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 5<<20)).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Payments) == 0 {
+		h.jsonError(w, "No payments provided", http.StatusBadRequest)
+		return
+	}
+	if len(req.Payments) > maxIngestBatch {
+		h.jsonError(w, fmt.Sprintf("Too many payments (max %d per request)", maxIngestBatch), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	results := make([]IngestPaymentResult, 0, len(req.Payments))
+	counts := map[string]int{}
+	unmatched := 0
+
+	// The whole batch is one balance update; per-record errors do not roll back the rest
+	err := h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		for _, p := range req.Payments {
+			result := ingestPayment(ctx, queries, source, p)
+			results = append(results, result)
+			counts[result.Status]++
+			if result.Status != "error" && !result.Matched {
+				unmatched++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to save payments", http.StatusServiceUnavailable)
+		return
+	}
+
+	level := "success"
+	if counts["error"] > 0 {
+		level = "warning"
+	} else if unmatched > 0 {
+		level = "info"
+	}
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "ingest",
+		Level:     level,
+		UserID:    sql.NullInt64{},
+		Message: fmt.Sprintf("Ingest from %s: %d new, %d updated, %d unmatched, %d errors",
+			source, counts["inserted"], counts["updated"], unmatched, counts["error"]),
+		Metadata: sql.NullString{String: fmt.Sprintf(`{"source":%q,"inserted":%d,"updated":%d,"unchanged":%d,"unmatched":%d,"errors":%d}`,
+			source, counts["inserted"], counts["updated"], counts["unchanged"], unmatched, counts["error"]), Valid: true},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   counts["error"] == 0,
+		"source":    source,
+		"inserted":  counts["inserted"],
+		"updated":   counts["updated"],
+		"unchanged": counts["unchanged"],
+		"unmatched": unmatched,
+		"errors":    counts["error"],
+		"results":   results,
+	})
+}
+
+// ingestSource returns the source name for the request's bearer token
+func (h *Handler) ingestSource(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+
+	// Compare against every token in constant time
+	source, found := "", false
+	for configured, name := range h.config.IngestTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(configured)) == 1 {
+			source, found = name, true
+		}
+	}
+	return source, found
+}
+
+// ingestPayment validates and upserts a single record. Matching works like the FIO
+// sync: identification is looked up as the member's payments_id (VS), project VS
+// are resolved by identification, everything else shows up as unmatched.
+func ingestPayment(ctx context.Context, queries *db.Queries, source string, p IngestPayment) IngestPaymentResult {
+	result := IngestPaymentResult{ID: p.ID, Status: "error"}
+
+	p.ID = strings.TrimSpace(p.ID)
+	p.Identification = strings.TrimSpace(p.Identification)
+	if p.ID == "" {
+		result.Error = "id is required"
+		return result
+	}
+	if p.Amount <= 0 {
+		result.Error = "amount must be positive"
+		return result
+	}
+
+	date, err := time.Parse(time.RFC3339, p.Date)
+	if err != nil {
+		if date, err = time.Parse("2006-01-02", p.Date); err != nil {
+			result.Error = "invalid date (expected RFC 3339 or YYYY-MM-DD)"
+			return result
+		}
+	}
+
+	var userID sql.NullInt64
+	if p.Identification != "" {
+		user, err := queries.GetUserByPaymentsID(ctx, sql.NullString{String: p.Identification, Valid: true})
+		if err == nil {
+			userID = sql.NullInt64{Int64: user.ID, Valid: true}
+		} else if err != sql.ErrNoRows {
+			result.Error = "database error"
+			return result
+		}
+	}
+
+	rawData := p.Raw
+	if len(rawData) == 0 {
+		rawData, _ = json.Marshal(p)
+	}
+
+	params := db.UpsertPaymentParams{
+		UserID:         userID,
+		ProjectID:      sql.NullInt64{},
+		Date:           date,
+		Amount:         money.FromFloat(p.Amount),
+		Kind:           source,
+		KindID:         p.ID,
+		LocalAccount:   strings.ToUpper(source),
+		RemoteAccount:  p.RemoteAccount,
+		Identification: p.Identification,
+		RawData:        sql.NullString{String: string(rawData), Valid: true},
+		StaffComment:   sql.NullString{String: p.Comment, Valid: p.Comment != ""},
+	}
+
+	existing, err := queries.GetPaymentByKindAndID(ctx, db.GetPaymentByKindAndIDParams{
+		Kind:   source,
+		KindID: p.ID,
+	})
+	var hash string
+	var original *db.Payment
+	switch {
+	case err == sql.ErrNoRows:
+		result.Status = "inserted"
+
+		// The same payment may already be imported from another source
+		hash = bankimport.ContentHash(date, params.Amount, p.RemoteAccount, p.Identification)
+		if original, err = bankimport.FindDuplicate(ctx, queries, hash, source); err != nil {
+			result.Status = "error"
+			result.Error = "database error"
+			return result
+		}
+		if original != nil {
+			params.UserID = sql.NullInt64{}
+			params.StaffComment = sql.NullString{String: fmt.Sprintf("Možná duplicita platby #%d (%s %s)", original.ID, original.Kind, original.KindID), Valid: true}
+			result.DuplicateOf = original.ID
+		}
+	case err != nil:
+		result.Error = "database error"
+		return result
+	default:
+		// Keep manual assignments done by admins in the portal
+		if !userID.Valid {
+			params.UserID = existing.UserID
+		}
+		params.ProjectID = existing.ProjectID
+		if existing.StaffComment.Valid {
+			params.StaffComment = existing.StaffComment
+		}
+
+		if params.UserID == existing.UserID && params.Amount == existing.Amount &&
+			params.Date.Equal(existing.Date) && params.Identification == existing.Identification &&
+			params.RemoteAccount == existing.RemoteAccount {
+			result.Status = "unchanged"
+			result.PaymentID = existing.ID
+			result.Matched = existing.UserID.Valid
+			return result
+		}
+		result.Status = "updated"
+	}
+
+	payment, err := queries.UpsertPayment(ctx, params)
+	if err != nil {
+		result.Status = "error"
+		result.Error = "failed to save payment"
+		return result
+	}
+
+	if hash != "" {
+		if err := bankimport.RecordDuplicate(ctx, queries, payment.ID, hash, original); err != nil {
+			log.Printf("⚠ Failed to record content hash of payment #%d: %v", payment.ID, err)
+		}
+	}
+
+	result.PaymentID = payment.ID
+	result.Matched = payment.UserID.Valid
+	return result
+}
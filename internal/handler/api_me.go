@@ -0,0 +1,372 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/base48/member-portal/internal/qrpay"
+)
+
+// UpcomingFee describes the next membership fee that will be charged
+type UpcomingFee struct {
+	Date         string  `json:"date"`
+	Amount       float64 `json:"amount"`
+	Period       string  `json:"period"` // months the fee covers, "2026-04" or "2026-04 – 2026-06"
+	Months       int     `json:"months"`
+	BillingCycle string  `json:"billing_cycle"` // monthly, quarterly or annual
+
+	amount  money.Amount
+	dueDate time.Time
+}
+
+// UpcomingPayment contains bank details for paying the suggested amount
+type UpcomingPayment struct {
+	IBAN               string `json:"iban"`
+	VariableSymbol     string `json:"variable_symbol"`
+	Message            string `json:"message"`
+	QRPayload          string `json:"qr_payload"`          // SPAYD string, empty if bank is not configured
+	EPCPayload         string `json:"epc_payload"`         // SEPA (EPC069-12) QR payload for foreign banks, without amount
+	PayBySquarePayload string `json:"paybysquare_payload"` // PAY by square payload for Slovak banks, without amount
+	QRFormat           string `json:"qr_format"`           // format the member chose in the profile
+}
+
+// MeUpcomingHandler returns the member's upcoming obligations (next fee, debt, what to pay)
+// GET /api/me/upcoming
+func (h *Handler) MeUpcomingHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	level, err := h.queries.GetLevel(ctx, dbUser.LevelID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch level", http.StatusInternalServerError)
+		return
+	}
+
+	balance, err := h.queries.GetUserBalance(ctx, db.GetUserBalanceParams{
+		UserID:   sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		UserID_2: dbUser.ID,
+		UserID_3: sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		UserID_4: dbUser.ID,
+		UserID_5: dbUser.ID,
+	})
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Failed to calculate balance: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	monthlyFee := monthlyFeeAmount(level, dbUser)
+
+	// Fees are created on the first day of a month for accepted members only
+	nextFee := h.upcomingFee(ctx, dbUser, level, time.Now())
+	pause := h.membershipPause(ctx, dbUser.ID, time.Now())
+	plan := h.paymentPlan(ctx, dbUser.ID, money.Amount(balance), time.Now())
+
+	// Same logic as the profile QR code: pay off the debt (with a payment plan what
+	// keeps the member on it), otherwise the next fee
+	var debt money.Amount
+	suggested := monthlyFee
+	var dueDate time.Time // the debt is due now, the fee on the day it is created
+	if plan != nil && plan.AmountDue > 0 {
+		debt = -money.Amount(balance)
+		suggested = plan.AmountDue
+	} else if balance := money.Amount(balance); balance < 0 {
+		debt = -balance
+		suggested = debt
+	} else if nextFee != nil {
+		suggested = nextFee.amount
+		dueDate = nextFee.dueDate
+	} else if pause != nil && pause.Active {
+		suggested = 0 // paused until cleared, nothing to pay
+	}
+	portalURL := h.config.BaseURL + "/profile"
+
+	var payment *UpcomingPayment
+	if dbUser.PaymentsID.Valid && dbUser.PaymentsID.String != "" {
+		payment = &UpcomingPayment{
+			IBAN:           h.qrpayService.BankIBAN(),
+			VariableSymbol: dbUser.PaymentsID.String,
+			Message:        "CLENSKY PRISPEVEK BASE48",
+		}
+		if h.qrpayService.IsConfigured() && suggested > 0 {
+			payment.QRPayload = h.qrpayService.GenerateSPAYDString(qrpay.GenerateParams{
+				Amount:         suggested.Float64(),
+				VariableSymbol: payment.VariableSymbol,
+				Message:        payment.Message,
+				DueDate:        dueDate,
+				URL:            portalURL,
+			})
+			payment.EPCPayload = h.qrpayService.GenerateEPCString(qrpay.GenerateParams{
+				Amount:         suggested.Float64(),
+				VariableSymbol: payment.VariableSymbol,
+				Message:        payment.Message,
+				RecipientName:  h.config.BankName,
+			})
+			payment.PayBySquarePayload = h.qrpayService.GeneratePayBySquareString(qrpay.GenerateParams{
+				Amount:         suggested.Float64(),
+				VariableSymbol: payment.VariableSymbol,
+				Message:        payment.Message,
+				RecipientName:  h.config.BankName,
+				DueDate:        dueDate,
+				URL:            portalURL,
+			})
+		}
+		payment.QRFormat = h.userQRFormat(r.Context(), dbUser.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":           true,
+		"balance":           money.Amount(balance).Float64(),
+		"debt":              debt.Float64(),
+		"monthly_fee":       monthlyFee.Float64(),
+		"next_fee":          nextFee,
+		"pause":             pause,
+		"payment_plan":      plan,
+		"suggested_payment": suggested.Float64(),
+		"payment":           payment,
+	})
+}
+
+// monthlyFeeAmount returns the member's monthly fee - custom amount if set and
+// higher than the level minimum, otherwise the level amount
+func monthlyFeeAmount(level db.Level, user *db.User) money.Amount {
+	if user.LevelActualAmount > level.Amount {
+		return user.LevelActualAmount
+	}
+	return level.Amount
+}
+
+// Page size of the member API lists
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// pageParams reads ?limit= and ?offset= of a paginated list
+func pageParams(r *http.Request) (limit, offset int64, err error) {
+	limit = defaultPageLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		offset, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative number")
+		}
+	}
+	return limit, offset, nil
+}
+
+// MePayment is a payment in the member API
+type MePayment struct {
+	ID              int64   `json:"id"`
+	Date            string  `json:"date"`
+	Amount          float64 `json:"amount"`
+	Kind            string  `json:"kind"` // fio, manual, source of the ingest API, ...
+	RemoteAccount   string  `json:"remote_account"`
+	Identification  string  `json:"identification"`    // VS
+	CountsInBalance bool    `json:"counts_in_balance"` // VS is the member's payments_id
+}
+
+// MeFee is a monthly membership fee in the member API
+type MeFee struct {
+	ID     int64   `json:"id"`
+	Period string  `json:"period"` // YYYY-MM, or a range for quarterly / annual fees
+	Amount float64 `json:"amount"`
+}
+
+// MeCharge is a one-off charge (locker, 3D printing, material) in the member API
+type MeCharge struct {
+	ID          int64   `json:"id"`
+	Date        string  `json:"date"` // YYYY-MM-DD
+	Category    string  `json:"category"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
+
+// MeAdjustment is a balance adjustment (forgiven fee, credit note) in the member API
+type MeAdjustment struct {
+	ID     int64   `json:"id"`
+	Date   string  `json:"date"` // YYYY-MM-DD
+	FeeID  *int64  `json:"fee_id,omitempty"`
+	Period string  `json:"period,omitempty"` // of the adjusted fee
+	Reason string  `json:"reason"`
+	Amount float64 `json:"amount"` // positive = in the member's favour
+}
+
+// MePaymentsHandler returns the member's payments, newest first
+// GET /api/me/payments?limit=50&offset=0
+func (h *Handler) MePaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	userID := sql.NullInt64{Int64: dbUser.ID, Valid: true}
+
+	total, err := h.queries.CountPaymentsByUser(ctx, userID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch payments", http.StatusInternalServerError)
+		return
+	}
+	rows, err := h.queries.ListPaymentsByUserPage(ctx, db.ListPaymentsByUserPageParams{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to fetch payments", http.StatusInternalServerError)
+		return
+	}
+
+	payments := make([]MePayment, 0, len(rows))
+	for _, p := range rows {
+		payments = append(payments, MePayment{
+			ID:              p.ID,
+			Date:            p.Date.Format("2006-01-02"),
+			Amount:          p.Amount.Float64(),
+			Kind:            p.Kind,
+			RemoteAccount:   p.RemoteAccount,
+			Identification:  p.Identification,
+			CountsInBalance: dbUser.PaymentsID.Valid && p.Identification == dbUser.PaymentsID.String,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"payments": payments,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// MeBalanceHandler returns the member's balance and fees, newest first, with all
+// one-off charges and balance adjustments
+// GET /api/me/balance?limit=50&offset=0
+func (h *Handler) MeBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+
+	balance, err := h.queries.GetUserBalance(ctx, db.GetUserBalanceParams{
+		UserID:   sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		UserID_2: dbUser.ID,
+		UserID_3: sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		UserID_4: dbUser.ID,
+		UserID_5: dbUser.ID,
+	})
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Failed to calculate balance: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	level, err := h.queries.GetLevel(ctx, dbUser.LevelID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch level", http.StatusInternalServerError)
+		return
+	}
+
+	total, err := h.queries.CountFeesByUser(ctx, dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch fees", http.StatusInternalServerError)
+		return
+	}
+	rows, err := h.queries.ListFeesByUserPage(ctx, db.ListFeesByUserPageParams{
+		UserID: dbUser.ID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to fetch fees", http.StatusInternalServerError)
+		return
+	}
+
+	fees := make([]MeFee, 0, len(rows))
+	for _, fee := range rows {
+		fees = append(fees, MeFee{
+			ID:     fee.ID,
+			Period: feePeriod(fee),
+			Amount: fee.Amount.Float64(),
+		})
+	}
+
+	chargeRows, err := h.queries.ListChargesByUser(ctx, dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch charges", http.StatusInternalServerError)
+		return
+	}
+	charges := make([]MeCharge, 0, len(chargeRows))
+	for _, charge := range chargeRows {
+		charges = append(charges, MeCharge{
+			ID:          charge.ID,
+			Date:        charge.Date.Format("2006-01-02"),
+			Category:    charge.Category,
+			Description: charge.Description,
+			Amount:      charge.Amount.Float64(),
+		})
+	}
+
+	adjustmentRows, err := h.balanceAdjustments(ctx, dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch adjustments", http.StatusInternalServerError)
+		return
+	}
+	adjustments := make([]MeAdjustment, 0, len(adjustmentRows))
+	for _, adjustment := range adjustmentRows {
+		item := MeAdjustment{
+			ID:     adjustment.ID,
+			Date:   adjustment.CreatedAt.Format("2006-01-02"),
+			Period: adjustment.Period,
+			Reason: adjustment.Reason,
+			Amount: adjustment.Amount.Float64(),
+		}
+		if adjustment.FeeID.Valid {
+			item.FeeID = &adjustment.FeeID.Int64
+		}
+		adjustments = append(adjustments, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"balance":     money.Amount(balance).Float64(),
+		"monthly_fee": monthlyFeeAmount(level, dbUser).Float64(),
+		"fees":        fees,
+		"charges":     charges,
+		"adjustments": adjustments,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
@@ -0,0 +1,374 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/auth"
+	"github.com/base48/member-portal/internal/db"
+)
+
+// Personal API token scopes; a write scope includes the matching read scope
+const (
+	ScopeMeRead     = "me:read"     // GET /api/me/*
+	ScopeMeWrite    = "me:write"    // any /api/me/* request
+	ScopeAdminRead  = "admin:read"  // GET /api/admin/* (and the read-only GraphQL endpoint)
+	ScopeAdminWrite = "admin:write" // any /api/admin/* request
+)
+
+// apiTokenPrefix marks portal tokens so they are easy to spot in scripts and secret scanners
+const apiTokenPrefix = "b48_"
+
+// maxAPITokensPerUser limits active tokens of a single user
+const maxAPITokensPerUser = 20
+
+// APITokenScope describes a scope offered in the profile
+type APITokenScope struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	AdminOnly bool   `json:"admin_only"`
+}
+
+// apiTokenScopes lists scopes in display order
+var apiTokenScopes = []APITokenScope{
+	{ID: ScopeMeRead, Title: "Čtení mých údajů (platby, widgety, faktury…)"},
+	{ID: ScopeMeWrite, Title: "Změny mých údajů"},
+	{ID: ScopeAdminRead, Title: "Čtení admin API", AdminOnly: true},
+	{ID: ScopeAdminWrite, Title: "Změny přes admin API", AdminOnly: true},
+}
+
+// readOnlyAPIPosts are POST endpoints that do not change anything
+var readOnlyAPIPosts = map[string]bool{
+	"/api/admin/graphql": true,
+}
+
+// sessionOnlyAPIPaths cannot be used with an API token: a token must not mint
+// new tokens, and impersonation lives in the browser session
+var sessionOnlyAPIPaths = []string{
+	"/api/me/tokens",
+	"/api/admin/impersonate",
+}
+
+// APIToken is a token as listed in the profile (never includes the secret)
+type APIToken struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPITokenRequest is the body of POST /api/me/tokens
+type CreateAPITokenRequest struct {
+	Name        string   `json:"name"`
+	Scopes      []string `json:"scopes"`
+	ExpiresDays int      `json:"expires_days"` // 0 = no expiry
+}
+
+// APITokenAuth is a middleware for the JSON API that authenticates requests with
+// "Authorization: Bearer <token>" instead of the session. It must run before
+// RequireAuth and LoadDBUser. Requests without a bearer token pass through; an
+// invalid token is rejected rather than falling back to the session.
+func (h *Handler) APITokenAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+
+		token, err := h.queries.GetAPITokenByHash(ctx, hashAPIToken(raw))
+		if err != nil || (token.ExpiresAt.Valid && time.Now().After(token.ExpiresAt.Time)) {
+			h.jsonError(w, "Invalid or expired API token", http.StatusUnauthorized)
+			return
+		}
+
+		for _, path := range sessionOnlyAPIPaths {
+			if strings.HasPrefix(r.URL.Path, path) {
+				h.jsonError(w, "Not available with an API token", http.StatusForbidden)
+				return
+			}
+		}
+
+		scope := requiredScope(r)
+		if !hasScope(token.Scopes, scope) {
+			h.jsonError(w, fmt.Sprintf("API token lacks the %s scope", scope), http.StatusForbidden)
+			return
+		}
+
+		dbUser, err := h.queries.GetUserByID(ctx, token.UserID)
+		if err != nil {
+			h.jsonError(w, "Invalid or expired API token", http.StatusUnauthorized)
+			return
+		}
+
+		// Roles are looked up on every request, so admin tokens stop working on the
+		// admin API as soon as the admin role is removed in Keycloak
+		user := &auth.User{
+			ID:            dbUser.KeycloakID.String,
+			Email:         dbUser.Email,
+			EmailVerified: true,
+			Name:          dbUser.Realname.String,
+			PreferredName: dbUser.Username.String,
+			Roles:         h.memberRoles(ctx, &dbUser),
+			APITokenID:    token.ID,
+		}
+
+		// Scripts polling every few seconds would otherwise write on each request
+		if !token.LastUsedAt.Valid || time.Since(token.LastUsedAt.Time) > time.Minute {
+			h.queries.TouchAPIToken(ctx, token.ID)
+		}
+
+		ctx = context.WithValue(ctx, dbUserContextKey, &dbUser)
+		ctx = auth.WithUser(ctx, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requiredScope returns the scope needed for the request
+func requiredScope(r *http.Request) string {
+	write := r.Method != http.MethodGet && r.Method != http.MethodHead && !readOnlyAPIPosts[r.URL.Path]
+	admin := strings.HasPrefix(r.URL.Path, "/api/admin/")
+	switch {
+	case admin && write:
+		return ScopeAdminWrite
+	case admin:
+		return ScopeAdminRead
+	case write:
+		return ScopeMeWrite
+	default:
+		return ScopeMeRead
+	}
+}
+
+// hasScope reports whether the space separated scopes grant scope
+func hasScope(scopes, scope string) bool {
+	for _, s := range strings.Fields(scopes) {
+		if s == scope || s == strings.TrimSuffix(scope, ":read")+":write" {
+			return true
+		}
+	}
+	return false
+}
+
+// hashAPIToken returns the stored form of a token
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateAPIToken creates a new random token
+func generateAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// availableAPITokenScopes returns the scopes the user may grant
+func availableAPITokenScopes(user *auth.User) []APITokenScope {
+	scopes := make([]APITokenScope, 0, len(apiTokenScopes))
+	for _, scope := range apiTokenScopes {
+		if scope.AdminOnly && !user.IsAdmin() {
+			continue
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes
+}
+
+// userAPITokens returns the active tokens of a user
+func (h *Handler) userAPITokens(ctx context.Context, userID int64) ([]APIToken, error) {
+	rows, err := h.queries.ListAPITokensByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]APIToken, 0, len(rows))
+	for _, row := range rows {
+		tokens = append(tokens, apiTokenFromRow(row))
+	}
+	return tokens, nil
+}
+
+func apiTokenFromRow(row db.ApiToken) APIToken {
+	token := APIToken{
+		ID:        row.ID,
+		Name:      row.Name,
+		Prefix:    row.TokenPrefix,
+		Scopes:    strings.Fields(row.Scopes),
+		CreatedAt: row.CreatedAt,
+	}
+	if row.ExpiresAt.Valid {
+		token.ExpiresAt = &row.ExpiresAt.Time
+	}
+	if row.LastUsedAt.Valid {
+		token.LastUsedAt = &row.LastUsedAt.Time
+	}
+	return token
+}
+
+// MeAPITokensHandler lists the member's active API tokens and the scopes they may grant
+// GET /api/me/tokens
+func (h *Handler) MeAPITokensHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.userAPITokens(r.Context(), dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"tokens":  tokens,
+		"scopes":  availableAPITokenScopes(h.auth.GetUser(r)),
+	})
+}
+
+// MeCreateAPITokenHandler creates a personal API token; the token is returned only once
+// POST /api/me/tokens
+// Body: {"name": "dveře", "scopes": ["me:read"], "expires_days": 365}
+func (h *Handler) MeCreateAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	var req CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		h.jsonError(w, "Name is required (max 100 characters)", http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresDays < 0 || req.ExpiresDays > 3650 {
+		h.jsonError(w, "Invalid expiry", http.StatusBadRequest)
+		return
+	}
+	if len(req.Scopes) == 0 {
+		h.jsonError(w, "At least one scope is required", http.StatusBadRequest)
+		return
+	}
+
+	allowed := make(map[string]bool)
+	for _, scope := range availableAPITokenScopes(h.auth.GetUser(r)) {
+		allowed[scope.ID] = true
+	}
+	for _, scope := range req.Scopes {
+		if !allowed[scope] {
+			h.jsonError(w, fmt.Sprintf("Scope not allowed: %s", scope), http.StatusBadRequest)
+			return
+		}
+	}
+
+	existing, err := h.queries.ListAPITokensByUser(ctx, dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if len(existing) >= maxAPITokensPerUser {
+		h.jsonError(w, fmt.Sprintf("Too many active tokens (max %d), revoke some first", maxAPITokensPerUser), http.StatusBadRequest)
+		return
+	}
+
+	raw, err := generateAPIToken()
+	if err != nil {
+		h.jsonError(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	var expiresAt sql.NullTime
+	if req.ExpiresDays > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, req.ExpiresDays), Valid: true}
+	}
+
+	created, err := h.queries.CreateAPIToken(ctx, db.CreateAPITokenParams{
+		UserID:      dbUser.ID,
+		Name:        req.Name,
+		TokenHash:   hashAPIToken(raw),
+		TokenPrefix: raw[:len(apiTokenPrefix)+6],
+		Scopes:      strings.Join(req.Scopes, " "),
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to save token", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "auth",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		Message:   fmt.Sprintf("User %s created API token %q (%s)", dbUser.Email, created.Name, created.Scopes),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"token_id":%d,"scopes":%q}`, created.ID, created.Scopes), Valid: true},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"token":     raw,
+		"api_token": apiTokenFromRow(created),
+	})
+}
+
+// MeRevokeAPITokenHandler revokes one of the member's API tokens
+// DELETE /api/me/tokens/{id}
+func (h *Handler) MeRevokeAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	tokenID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := h.queries.RevokeAPIToken(ctx, db.RevokeAPITokenParams{ID: tokenID, UserID: dbUser.ID})
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if revoked == 0 {
+		h.jsonError(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "auth",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		Message:   fmt.Sprintf("User %s revoked API token #%d", dbUser.Email, tokenID),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"token_id":%d}`, tokenID), Valid: true},
+	})
+
+	h.jsonSuccess(w, "Token revoked")
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// maxVouchNote limits the note a member adds to their vouch
+const maxVouchNote = 500
+
+// VouchRequest is the body of POST /api/me/applications/{id}/vouch
+type VouchRequest struct {
+	Note string `json:"note"`
+}
+
+// MeApplicationsHandler lists pending membership applications with the member's vouches
+// GET /api/me/applications
+func (h *Handler) MeApplicationsHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+	if dbUser.State != "accepted" {
+		h.jsonError(w, "Vouching is available to active members only", http.StatusForbidden)
+		return
+	}
+
+	applications, err := h.queries.ListPendingApplicationsForMember(r.Context(), dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch applications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":          true,
+		"applications":     applications,
+		"vouches_required": h.config.ApplicationVouches,
+	})
+}
+
+// MeVouchApplicationHandler records that the member vouches for an applicant
+// POST /api/me/applications/{id}/vouch
+// Body: {"note": "známe se z workshopu"}
+func (h *Handler) MeVouchApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	if dbUser.State != "accepted" {
+		h.jsonError(w, "Vouching is available to active members only", http.StatusForbidden)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid application ID", http.StatusBadRequest)
+		return
+	}
+
+	var req VouchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	note := strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(note) > maxVouchNote {
+		h.jsonError(w, fmt.Sprintf("Note is too long (max %d characters)", maxVouchNote), http.StatusBadRequest)
+		return
+	}
+
+	application, err := h.queries.GetApplication(ctx, id)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Application not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if application.State != "pending" {
+		h.jsonError(w, "Application is not waiting for approval", http.StatusConflict)
+		return
+	}
+
+	if err := h.queries.UpsertApplicationVouch(ctx, db.UpsertApplicationVouchParams{
+		ApplicationID: id,
+		UserID:        dbUser.ID,
+		Note:          sql.NullString{String: note, Valid: note != ""},
+	}); err != nil {
+		h.jsonError(w, "Failed to vouch for application", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "membership",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		Message:   fmt.Sprintf("%s vouched for membership application #%d of %s", dbUser.Email, id, application.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"application_id":%d}`, id),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Vouched for application")
+}
+
+// MeUnvouchApplicationHandler withdraws the member's vouch while the application is pending
+// DELETE /api/me/applications/{id}/vouch
+func (h *Handler) MeUnvouchApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid application ID", http.StatusBadRequest)
+		return
+	}
+
+	application, err := h.queries.GetApplication(ctx, id)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Application not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if application.State != "pending" {
+		h.jsonError(w, "Application is already decided", http.StatusConflict)
+		return
+	}
+
+	rows, err := h.queries.DeleteApplicationVouch(ctx, db.DeleteApplicationVouchParams{
+		ApplicationID: id,
+		UserID:        dbUser.ID,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to withdraw vouch", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "You have not vouched for this application", http.StatusNotFound)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "membership",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		Message:   fmt.Sprintf("%s withdrew their vouch for membership application #%d", dbUser.Email, id),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"application_id":%d}`, id),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Vouch withdrawn")
+}
@@ -0,0 +1,389 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// Limits of the application form fields
+const (
+	maxApplicationName       = 200
+	maxApplicationMotivation = 2000
+)
+
+// ApplicationRequest is the body of POST /api/applications
+type ApplicationRequest struct {
+	Realname   string `json:"realname"`
+	Email      string `json:"email"`
+	Motivation string `json:"motivation"`
+	LevelID    int64  `json:"level_id"`
+	Website    string `json:"website"` // hidden field, filled in only by spam bots
+}
+
+// RejectApplicationRequest is the body of POST /api/admin/applications/{id}/reject
+type RejectApplicationRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ApplyHandler shows the public membership application form
+// GET /apply
+func (h *Handler) ApplyHandler(w http.ResponseWriter, r *http.Request) {
+	levels, err := h.queries.ListLevels(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":           "Přihláška do Base48",
+		"User":            h.auth.GetUser(r),
+		"Levels":          levels,
+		"VouchesRequired": h.config.ApplicationVouches,
+	}
+
+	h.render(w, "apply.html", data)
+}
+
+// SubmitApplicationHandler stores an application from the public form for approval
+// POST /api/applications
+// Body: {"realname": "...", "email": "...", "motivation": "...", "level_id": 2}
+func (h *Handler) SubmitApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req ApplicationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Website != "" {
+		// Pretend success, the bot does not learn it was caught
+		h.jsonSuccess(w, "Application received")
+		return
+	}
+
+	realname := strings.TrimSpace(req.Realname)
+	motivation := strings.TrimSpace(req.Motivation)
+	if realname == "" || utf8.RuneCountInString(realname) > maxApplicationName {
+		h.jsonError(w, fmt.Sprintf("Name is required (max %d characters)", maxApplicationName), http.StatusBadRequest)
+		return
+	}
+	if motivation == "" || utf8.RuneCountInString(motivation) > maxApplicationMotivation {
+		h.jsonError(w, fmt.Sprintf("Motivation is required (max %d characters)", maxApplicationMotivation), http.StatusBadRequest)
+		return
+	}
+	address, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	if err != nil || address.Name != "" {
+		h.jsonError(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+	email := strings.ToLower(address.Address)
+
+	level, err := h.queries.GetLevel(ctx, req.LevelID)
+	if err != nil || !level.Active {
+		h.jsonError(w, "Level not found", http.StatusNotFound)
+		return
+	}
+
+	// The form answers the same for every address, so it cannot be used to find out
+	// who is a member; an application with a member's email is refused at approval
+	if _, err := h.queries.GetPendingApplicationByEmail(ctx, email); err == nil {
+		// The earlier application is still waiting, there is nothing to add
+		h.jsonSuccess(w, "Application received")
+		return
+	} else if err != sql.ErrNoRows {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	application, err := h.queries.CreateApplication(ctx, db.CreateApplicationParams{
+		Realname:   realname,
+		Email:      email,
+		Motivation: motivation,
+		LevelID:    level.ID,
+	})
+	if err != nil {
+		// Lost a race with the same application submitted twice
+		if strings.Contains(err.Error(), "UNIQUE") {
+			h.jsonSuccess(w, "Application received")
+			return
+		}
+		h.jsonError(w, "Failed to save application", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "membership",
+		Level:     "info",
+		UserID:    sql.NullInt64{},
+		Message:   fmt.Sprintf("Membership application #%d from %s (%s)", application.ID, email, level.Name),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"application_id":%d,"email":%q,"level_id":%d}`, application.ID, email, level.ID),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Application received")
+}
+
+// AdminApplicationsHandler shows the membership applications, pending first
+// GET /admin/applications
+func (h *Handler) AdminApplicationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	applications, err := h.queries.ListApplications(ctx, 200)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	pending := 0
+	for _, a := range applications {
+		if a.State == "pending" {
+			pending++
+		}
+	}
+
+	vouchRows, err := h.queries.ListApplicationVouches(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	vouches := make(map[int64][]db.ListApplicationVouchesRow)
+	for _, v := range vouchRows {
+		vouches[v.ApplicationID] = append(vouches[v.ApplicationID], v)
+	}
+
+	data := map[string]interface{}{
+		"Title":           "Přihlášky",
+		"User":            h.auth.GetUser(r),
+		"DBUser":          DBUserFrom(ctx),
+		"Applications":    applications,
+		"Pending":         pending,
+		"Vouches":         vouches,
+		"VouchesRequired": int64(h.config.ApplicationVouches),
+	}
+
+	h.render(w, "admin_applications.html", data)
+}
+
+// AdminApproveApplicationHandler approves an application once enough accepted members
+// vouched for it (APPLICATION_VOUCHES): creates the member as accepted with the
+// requested level and the next free VS, creates their Keycloak account with the role
+// of accepted members and sends the welcome email. Keycloak and email failures are
+// reported but do not undo the approval - the account can be provisioned from the
+// member's profile.
+// POST /api/admin/applications/{id}/approve
+func (h *Handler) AdminApproveApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid application ID", http.StatusBadRequest)
+		return
+	}
+
+	application, err := h.queries.GetApplication(ctx, id)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Application not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if application.State != "pending" {
+		h.jsonError(w, "Application is not waiting for approval", http.StatusConflict)
+		return
+	}
+
+	if _, err := h.queries.GetUserByEmail(ctx, application.Email); err == nil {
+		h.jsonError(w, "A member with this email already exists", http.StatusConflict)
+		return
+	} else if err != sql.ErrNoRows {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	level, err := h.queries.GetLevel(ctx, application.LevelID)
+	if err != nil {
+		h.jsonError(w, "Level not found", http.StatusNotFound)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	// Member and approval in one transaction, the VS is taken inside it
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	vouchCount, err := qtx.CountApplicationVouches(ctx, id)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if vouchCount < int64(h.config.ApplicationVouches) {
+		h.jsonError(w, fmt.Sprintf("Application needs %d vouches from accepted members, it has %d", h.config.ApplicationVouches, vouchCount), http.StatusConflict)
+		return
+	}
+
+	paymentsID, err := qtx.NextPaymentsID(ctx)
+	if err != nil {
+		h.jsonError(w, "Failed to assign payments VS", http.StatusInternalServerError)
+		return
+	}
+	member, err := qtx.CreateUser(ctx, db.CreateUserParams{
+		Email:             application.Email,
+		Realname:          sql.NullString{String: application.Realname, Valid: true},
+		LevelID:           level.ID,
+		LevelActualAmount: level.Amount,
+		PaymentsID:        sql.NullString{String: paymentsID, Valid: true},
+		State:             "accepted",
+	})
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Failed to create member: %v", err), http.StatusInternalServerError)
+		return
+	}
+	rows, err := qtx.ApproveApplication(ctx, db.ApproveApplicationParams{
+		UserID:    sql.NullInt64{Int64: member.ID, Valid: true},
+		DecidedBy: sql.NullString{String: adminUsername, Valid: true},
+		ID:        id,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to approve application", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "Application is not waiting for approval", http.StatusConflict)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		h.jsonError(w, "Failed to approve application", http.StatusInternalServerError)
+		return
+	}
+
+	keycloakError := ""
+	if kcClient, err := h.keycloakClient(); err != nil {
+		keycloakError = err.Error()
+	} else if keycloakID, _, err := kcClient.ProvisionUser(ctx, member.Email, "", application.Realname); keycloakID == "" {
+		keycloakError = err.Error()
+	} else {
+		// A failed password email is reported, but the account exists and must be linked anyway
+		if err != nil {
+			keycloakError = err.Error()
+		}
+		if linked, err := h.queries.LinkKeycloakID(ctx, db.LinkKeycloakIDParams{
+			KeycloakID: sql.NullString{String: keycloakID, Valid: true},
+			Email:      member.Email,
+		}); err != nil {
+			keycloakError = fmt.Sprintf("failed to link Keycloak account: %v", err)
+		} else {
+			member = linked
+			if _, err := h.pushStateRoles(ctx, kcClient, keycloakID, member.State); err != nil {
+				keycloakError = fmt.Sprintf("failed to assign roles: %v", err)
+			}
+		}
+		h.userCache.Invalidate()
+		h.roleCache.Invalidate()
+	}
+
+	emailError := ""
+	if err := h.emailClient.SendWelcome(ctx, &member); err != nil {
+		emailError = err.Error()
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) approved membership application #%d of %s (VS %s)",
+			adminUsername, adminDBUser.Email, id, member.Email, paymentsID),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"application_id":%d,"target_user_id":%d,"payments_id":%q,"vouches":%d,"keycloak_error":%q,"email_error":%q}`,
+				adminDBUser.ID, id, member.ID, paymentsID, vouchCount, keycloakError, emailError),
+			Valid: true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"user_id":        member.ID,
+		"payments_id":    paymentsID,
+		"keycloak_error": keycloakError,
+		"email_error":    emailError,
+	})
+}
+
+// AdminRejectApplicationHandler rejects an application
+// POST /api/admin/applications/{id}/reject
+// Body: {"reason": "..."}
+func (h *Handler) AdminRejectApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid application ID", http.StatusBadRequest)
+		return
+	}
+
+	var req RejectApplicationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	rows, err := h.queries.RejectApplication(ctx, db.RejectApplicationParams{
+		AdminComment: sql.NullString{String: req.Reason, Valid: req.Reason != ""},
+		DecidedBy:    sql.NullString{String: adminUsername, Valid: true},
+		ID:           id,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to reject application", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "Application is not waiting for approval", http.StatusConflict)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s (%s) rejected membership application #%d", adminUsername, adminDBUser.Email, id),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"application_id":%d,"reason":%q}`, adminDBUser.ID, id, req.Reason),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Application rejected")
+}
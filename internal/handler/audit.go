@@ -0,0 +1,310 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/base48/member-portal/internal/auth"
+	"github.com/base48/member-portal/internal/db"
+)
+
+// auditMethods are the admin API methods recorded in the audit log
+var auditMethods = map[string]bool{
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+const (
+	maxAuditBody    = 64 << 10 // larger request bodies are not summarized
+	maxAuditPayload = 2000     // stored request summary
+	maxAuditValue   = 200      // single value in the summary
+	maxAuditError   = 500      // stored error message
+)
+
+// auditTargetFields identify the object of an admin action in the query or the body
+var auditTargetFields = []string{"id", "user_id", "project_id", "payment_id", "invoice_id", "ticket_id", "level_id", "from_user_id", "to_user_id"}
+
+// auditSecretWords mark request fields whose values are never stored (the email
+// typed to confirm an anonymization would survive it in the audit log)
+var auditSecretWords = []string{"password", "secret", "token", "confirm"}
+
+// AdminAudit records every mutating admin API call in admin_audit_log: the admin,
+// target IDs, a summary of the request and the response status (GraphQL queries
+// are POST requests too, so they are recorded as well). It must run after LoadDBUser.
+func (h *Handler) AdminAudit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auditMethods[r.Method] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		fields, payload := readAuditPayload(r)
+
+		response := &cappedBuffer{limit: maxAuditError}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(response)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		var errMessage string
+		if status >= http.StatusBadRequest {
+			errMessage = auditErrorMessage(response.Bytes())
+		}
+
+		h.recordAudit(r, status, auditTarget(r, fields), payload, errMessage)
+	})
+}
+
+// recordAudit stores an audit log entry; a failure is only logged, the call itself
+// has already been handled
+func (h *Handler) recordAudit(r *http.Request, status int, target, payload, errMessage string) {
+	ctx := r.Context()
+
+	params := db.CreateAdminAuditLogParams{
+		ActorEmail: "unknown",
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Target:     target,
+		Payload:    payload,
+		Status:     int64(status),
+		Error:      errMessage,
+	}
+	if dbUser := DBUserFrom(ctx); dbUser != nil {
+		params.ActorUserID = sql.NullInt64{Int64: dbUser.ID, Valid: true}
+		params.ActorEmail = dbUser.Email
+	}
+	if user := auth.UserFromContext(ctx); user != nil && user.APITokenID != 0 {
+		params.ApiTokenID = sql.NullInt64{Int64: user.APITokenID, Valid: true}
+	}
+
+	if err := h.queries.CreateAdminAuditLog(ctx, params); err != nil {
+		log.Printf("Failed to write admin audit log (%s %s): %v", r.Method, r.URL.Path, err)
+	}
+}
+
+// readAuditPayload summarizes the request body and puts it back for the handler.
+// It returns the top-level scalar fields (for the target) and the stored summary.
+func readAuditPayload(r *http.Request) (map[string]string, string) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		return nil, fmt.Sprintf("(multipart/form-data, %d B)", r.ContentLength)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAuditBody+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil || len(body) == 0 {
+		return nil, ""
+	}
+	if len(body) > maxAuditBody {
+		return nil, fmt.Sprintf("(%s, over %d B)", mediaType, maxAuditBody)
+	}
+
+	summary := make(map[string]interface{})
+	fields := make(map[string]string)
+
+	if mediaType == "application/x-www-form-urlencoded" {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, "(invalid form data)"
+		}
+		for key := range values {
+			value := values.Get(key)
+			fields[key] = value
+			summary[key] = auditValue(key, value)
+		}
+	} else {
+		// The admin API takes JSON even without a Content-Type header
+		var object map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&object); err != nil {
+			return nil, shorten(string(body), maxAuditValue)
+		}
+		for key, value := range object {
+			switch v := value.(type) {
+			case string:
+				fields[key] = v
+				summary[key] = auditValue(key, v)
+			case json.Number:
+				fields[key] = v.String()
+				summary[key] = auditValue(key, v)
+			case nil, bool:
+				summary[key] = auditValue(key, v)
+			default:
+				encoded, _ := json.Marshal(v)
+				summary[key] = auditValue(key, string(encoded))
+			}
+		}
+	}
+
+	encoded, err := json.Marshal(summary)
+	if err != nil {
+		return fields, ""
+	}
+	return fields, shorten(string(encoded), maxAuditPayload)
+}
+
+// auditValue returns the value to store for a request field: secrets are redacted,
+// long strings cut
+func auditValue(key string, value interface{}) interface{} {
+	lower := strings.ToLower(key)
+	for _, word := range auditSecretWords {
+		if strings.Contains(lower, word) && !strings.HasSuffix(lower, "_id") {
+			return "***"
+		}
+	}
+	if s, ok := value.(string); ok {
+		return shorten(s, maxAuditValue)
+	}
+	return value
+}
+
+// auditTarget lists the IDs of the objects the call works with: URL parameters,
+// then known ID fields from the query and the body
+func auditTarget(r *http.Request, fields map[string]string) string {
+	var parts []string
+	seen := make(map[string]bool)
+	add := func(key, value string) {
+		if value == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		parts = append(parts, key+"="+shorten(value, 40))
+	}
+
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		for i, key := range rctx.URLParams.Keys {
+			if key != "*" && i < len(rctx.URLParams.Values) {
+				add(key, rctx.URLParams.Values[i])
+			}
+		}
+	}
+	query := r.URL.Query()
+	for _, key := range auditTargetFields {
+		add(key, query.Get(key))
+		add(key, fields[key])
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// auditErrorMessage extracts the error of a failed call from the JSON response
+// ({"error": "..."}) or takes the plain text response
+func auditErrorMessage(body []byte) string {
+	var response struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &response); err == nil {
+		if response.Error != "" {
+			return shorten(response.Error, maxAuditError)
+		}
+		return shorten(response.Message, maxAuditError)
+	}
+	return shorten(strings.TrimSpace(string(body)), maxAuditError)
+}
+
+// shorten cuts s to at most n characters
+func shorten(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
+
+// cappedBuffer keeps the first limit bytes written to it (the start of a response)
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// AdminAuditHandler shows the admin audit log with filters
+// GET /admin/audit?path=&actor=&failed=1&limit=200
+func (h *Handler) AdminAuditHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	path := strings.TrimSpace(query.Get("path"))
+	actorStr := query.Get("actor")
+	failed := query.Get("failed") == "1"
+
+	var actorID int64
+	if actorStr != "" {
+		if parsed, err := strconv.ParseInt(actorStr, 10, 64); err == nil {
+			actorID = parsed
+		}
+	}
+
+	limit := int64(200)
+	if parsed, err := strconv.ParseInt(query.Get("limit"), 10, 64); err == nil && parsed > 0 {
+		limit = parsed
+	}
+
+	failedFilter := 0
+	if failed {
+		failedFilter = 1
+	}
+
+	entries, err := h.queries.ListAdminAuditLog(ctx, db.ListAdminAuditLogParams{
+		Column1:     path,
+		Path:        "%" + path + "%",
+		Column3:     actorID,
+		ActorUserID: sql.NullInt64{Int64: actorID, Valid: actorID > 0},
+		Column5:     failedFilter,
+		Limit:       limit,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":   "Audit adminů",
+		"User":    h.auth.GetUser(r),
+		"DBUser":  DBUserFrom(ctx),
+		"Entries": entries,
+		"Path":    path,
+		"Actor":   actorStr,
+		"Failed":  failed,
+		"Limit":   limit,
+	}
+
+	h.render(w, "admin_audit.html", data)
+}
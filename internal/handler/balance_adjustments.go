@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// maxAdjustmentReason limits the reason of a balance adjustment
+const maxAdjustmentReason = 500
+
+// BalanceAdjustment is a balance adjustment with the period of the adjusted fee
+type BalanceAdjustment struct {
+	db.BalanceAdjustment
+	Period string // empty for a credit not tied to a fee
+}
+
+// balanceAdjustments returns the member's balance adjustments, newest first
+func (h *Handler) balanceAdjustments(ctx context.Context, userID int64) ([]BalanceAdjustment, error) {
+	rows, err := h.queries.ListBalanceAdjustmentsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	adjustments := make([]BalanceAdjustment, 0, len(rows))
+	for _, row := range rows {
+		adjustment := BalanceAdjustment{BalanceAdjustment: row}
+		if row.FeeID.Valid {
+			fee, err := h.queries.GetFee(ctx, row.FeeID.Int64)
+			if err != nil {
+				return nil, err
+			}
+			adjustment.Period = feePeriod(fee)
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+	return adjustments, nil
+}
+
+// AdjustmentRequest is the body of the fee adjustment and credit endpoints
+type AdjustmentRequest struct {
+	Amount *money.Amount `json:"amount"` // positive = in the member's favour
+	Reason string        `json:"reason"`
+}
+
+// decodeAdjustmentRequest parses and validates the request body; writes the error
+// response and returns false when it is invalid
+func (h *Handler) decodeAdjustmentRequest(w http.ResponseWriter, r *http.Request) (AdjustmentRequest, bool) {
+	var req AdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		h.jsonError(w, "Reason is required", http.StatusBadRequest)
+		return req, false
+	}
+	if utf8.RuneCountInString(req.Reason) > maxAdjustmentReason {
+		h.jsonError(w, fmt.Sprintf("Reason is too long (max %d characters)", maxAdjustmentReason), http.StatusBadRequest)
+		return req, false
+	}
+	if req.Amount != nil && *req.Amount == 0 {
+		h.jsonError(w, "Amount must not be zero", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// AdminAdjustFeeHandler corrects a fee by a signed adjustment record; the fee
+// itself is never changed. Without an amount the rest of the fee is forgiven.
+// POST /api/admin/fees/{id}/adjust
+// Body: {"amount": 500, "reason": "Nemoc, odpuštěna polovina"}
+func (h *Handler) AdminAdjustFeeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	feeID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid fee ID", http.StatusBadRequest)
+		return
+	}
+
+	req, ok := h.decodeAdjustmentRequest(w, r)
+	if !ok {
+		return
+	}
+
+	fee, err := h.queries.GetFee(ctx, feeID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Fee not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	targetDBUser, err := h.queries.GetUserByID(ctx, fee.UserID)
+	if err != nil {
+		h.jsonError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	// Adjustments change the balance - serialized with payments and fees. The
+	// credited total is checked inside so a fee cannot be forgiven twice.
+	var adjustment db.BalanceAdjustment
+	var badRequest string
+	err = h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		credited, err := queries.GetFeeAdjustmentTotal(ctx, sql.NullInt64{Int64: fee.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+		remaining := fee.Amount - money.Amount(credited)
+		amount := remaining
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+		if amount == 0 {
+			badRequest = "Fee is already fully forgiven"
+			return nil
+		}
+		if amount > remaining {
+			badRequest = fmt.Sprintf("Amount exceeds the rest of the fee (%s Kč)", remaining)
+			return nil
+		}
+
+		adjustment, err = queries.CreateBalanceAdjustment(ctx, db.CreateBalanceAdjustmentParams{
+			UserID:    fee.UserID,
+			FeeID:     sql.NullInt64{Int64: fee.ID, Valid: true},
+			Amount:    amount,
+			Reason:    req.Reason,
+			CreatedBy: adminDBUser.Email,
+		})
+		return err
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to save adjustment", http.StatusInternalServerError)
+		return
+	}
+	if badRequest != "" {
+		h.jsonError(w, badRequest, http.StatusBadRequest)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s adjusted fee %s of %s by %s Kč: %s",
+			adminDBUser.Email, feePeriod(fee), targetDBUser.Email, adjustment.Amount, req.Reason),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"fee_id":%d,"adjustment_id":%d,"amount":%q}`,
+				adminDBUser.ID, targetDBUser.ID, fee.ID, adjustment.ID, adjustment.Amount),
+			Valid: true,
+		},
+	})
+
+	h.jsonSuccess(w, "Fee adjusted")
+}
+
+// AdminCreditUserHandler adds a signed adjustment of the member's balance not tied
+// to a fee (credit note, or a correction against the member when negative)
+// POST /api/admin/users/{id}/credit
+// Body: {"amount": 300, "reason": "Vrácení za nákup materiálu"}
+func (h *Handler) AdminCreditUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := h.decodeAdjustmentRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Amount == nil {
+		h.jsonError(w, "Amount is required", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	var adjustment db.BalanceAdjustment
+	err := h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		var err error
+		adjustment, err = queries.CreateBalanceAdjustment(ctx, db.CreateBalanceAdjustmentParams{
+			UserID:    targetDBUser.ID,
+			Amount:    *req.Amount,
+			Reason:    req.Reason,
+			CreatedBy: adminDBUser.Email,
+		})
+		return err
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to save adjustment", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s credited %s %s Kč: %s", adminDBUser.Email, targetDBUser.Email, adjustment.Amount, req.Reason),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"adjustment_id":%d,"amount":%q}`,
+				adminDBUser.ID, targetDBUser.ID, adjustment.ID, adjustment.Amount),
+			Valid: true,
+		},
+	})
+
+	h.jsonSuccess(w, "Credit added")
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/base48/member-portal/internal/money"
+)
+
+// BalanceReportMonth is one month of the balance report
+type BalanceReportMonth struct {
+	Month   string  `json:"month"` // YYYY-MM
+	Debtors int64   `json:"debtors"`
+	Debt    float64 `json:"debt"`
+	Credit  float64 `json:"credit"` // prepaid by members with a positive balance
+}
+
+// AdminBalanceReportHandler returns month-end totals of member balances from the
+// balance snapshots, oldest first (for charts)
+// GET /api/admin/reports/balances
+func (h *Handler) AdminBalanceReportHandler(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.queries.ListBalanceSnapshotTotals(r.Context())
+	if err != nil {
+		h.jsonError(w, "Failed to fetch balance snapshots", http.StatusInternalServerError)
+		return
+	}
+
+	months := make([]BalanceReportMonth, 0, len(rows))
+	for _, row := range rows {
+		months = append(months, BalanceReportMonth{
+			Month:   row.Month.Format("2006-01"),
+			Debtors: row.Debtors,
+			Debt:    money.Amount(row.Debt).Float64(),
+			Credit:  money.Amount(row.Credit).Float64(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"months":  months,
+	})
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"github.com/base48/member-portal/internal/bankimport"
+	"github.com/base48/member-portal/internal/db"
+)
+
+// AdminImportBankStatementHandler imports payments from a statement downloaded from
+// the bank (FIO CSV, GPC/ABO or camt.053), through the same matching as the FIO sync.
+// Already imported transactions (same FIO movement ID or bank reference) are not
+// duplicated.
+// POST /api/admin/payments/import
+// Body: multipart form with statement (file) and optional format (fio-csv, gpc, camt053)
+func (h *Handler) AdminImportBankStatementHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	r.Body = http.MaxBytesReader(w, r.Body, bankimport.MaxStatementSize+1<<20)
+	if err := r.ParseMultipartForm(bankimport.MaxStatementSize); err != nil {
+		h.jsonError(w, "Invalid form data or statement too large", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("statement")
+	if err != nil {
+		h.jsonError(w, "Statement file is required", http.StatusBadRequest)
+		return
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		h.jsonError(w, "Failed to read statement", http.StatusBadRequest)
+		return
+	}
+
+	filename := filepath.Base(header.Filename)
+	format := r.FormValue("format")
+	if format == "" {
+		format = bankimport.DetectFormat(filename, data)
+		if format == "" {
+			h.jsonError(w, "Unknown statement format, choose FIO CSV, GPC or camt.053", http.StatusBadRequest)
+			return
+		}
+	}
+
+	transactions, err := bankimport.Parse(format, data)
+	if err != nil {
+		h.jsonError(w, "Invalid statement: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// One balance update; failed transactions do not roll back the rest
+	var summary bankimport.Summary
+	err = h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		var err error
+		summary, err = bankimport.Import(ctx, queries, transactions, bankimport.Options{
+			AutoLinkByAccount: h.config.FIOAutoLinkByAccount,
+		})
+		return err
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to import statement: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     summary.Level(),
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s imported bank statement %s (%s): %s", adminDBUser.Email, filename, format, summary.Message()),
+		Metadata:  sql.NullString{String: summary.Metadata(), Valid: true},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":          summary.Errors == 0,
+		"format":           format,
+		"summary":          summary,
+		"unmatched":        summary.Unmatched(),
+		"reversals_review": len(summary.ReversalsReview),
+	})
+}
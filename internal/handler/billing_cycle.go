@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fees"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// BillingCycle is how often the member pays the fee
+type BillingCycle struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// billingCycles lists the cycles in the profile select, monthly is the default
+var billingCycles = []BillingCycle{
+	{ID: fees.CycleMonthly, Title: "měsíčně"},
+	{ID: fees.CycleQuarterly, Title: "čtvrtletně"},
+	{ID: fees.CycleAnnual, Title: "ročně"},
+}
+
+// userBillingCycle returns the member's billing cycle, monthly when not set
+func (h *Handler) userBillingCycle(ctx context.Context, userID int64) string {
+	cycle, err := h.queries.GetUserBillingCycle(ctx, userID)
+	if err != nil {
+		return fees.CycleMonthly
+	}
+	return cycle
+}
+
+// upcomingFee returns the next fee of an accepted member (nil for others): created
+// on the first day of the month after the months the last fee covers and after the
+// member's pause, for the rest of the member's billing period. nil also during
+// a pause without an end date and when the membership ends before the fee date.
+func (h *Handler) upcomingFee(ctx context.Context, user *db.User, level db.Level, now time.Time) *UpcomingFee {
+	if user.State != "accepted" {
+		return nil
+	}
+
+	var coveredUntil time.Time
+	if last, err := h.queries.GetLastFeeByUser(ctx, user.ID); err == nil {
+		coveredUntil = fees.CoveredUntil(last)
+	}
+	date := fees.NextFeeDate(coveredUntil, now)
+	if pause, err := h.queries.GetOpenMembershipPause(ctx, user.ID); err == nil {
+		var ok bool
+		if date, ok = fees.SkipPause(pause, date); !ok {
+			return nil
+		}
+	}
+	cycle := h.userBillingCycle(ctx, user.ID)
+	months := fees.PeriodMonths(cycle, date)
+	if termination, err := h.queries.GetScheduledTermination(ctx, user.ID); err == nil {
+		if months = fees.TerminationMonths(termination, date, months); months == 0 {
+			return nil
+		}
+	}
+	monthly := monthlyFeeAmount(level, user)
+	// An approved level change effective by then is applied before the fee is created
+	if change, err := h.queries.GetScheduledLevelChange(ctx, db.GetScheduledLevelChangeParams{
+		UserID:        user.ID,
+		EffectiveFrom: date,
+	}); err == nil {
+		if next, err := h.queries.GetLevel(ctx, change.LevelID); err == nil {
+			monthly = next.Amount
+		}
+	}
+	amount := monthly * money.Amount(months)
+
+	return &UpcomingFee{
+		Date:         date.Format("2006-01-02"),
+		Amount:       amount.Float64(),
+		Period:       fees.PeriodLabel(date, months),
+		Months:       months,
+		BillingCycle: cycle,
+		amount:       amount,
+		dueDate:      date,
+	}
+}
+
+// feePeriod describes the months a fee covers ("2026-04" or "2026-04 – 2026-06")
+func feePeriod(fee db.Fee) string {
+	return fees.PeriodLabel(fee.PeriodStart, int(fee.Months))
+}
+
+// MeBillingCycleHandler sets how often the member pays; takes effect with the next fee
+// POST /api/me/billing-cycle
+// Body: {"billing_cycle": "annual"}
+func (h *Handler) MeBillingCycleHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		BillingCycle string `json:"billing_cycle"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	known := false
+	for _, cycle := range billingCycles {
+		if cycle.ID == req.BillingCycle {
+			known = true
+			break
+		}
+	}
+	if !known {
+		h.jsonError(w, fmt.Sprintf("Unknown billing cycle: %s", req.BillingCycle), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.queries.SetUserBillingCycle(r.Context(), db.SetUserBillingCycleParams{
+		UserID:       dbUser.ID,
+		BillingCycle: req.BillingCycle,
+	}); err != nil {
+		h.jsonError(w, "Failed to save billing cycle", http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Billing cycle saved")
+}
@@ -0,0 +1,313 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/base48/member-portal/internal/btcpay"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// btcpayPaymentKind is payments.kind of crypto payments, kind_id is the BTCPay invoice ID
+const btcpayPaymentKind = "btcpay"
+
+// BTCPayInvoiceRequest is the request body for POST /api/me/btcpay/invoice
+type BTCPayInvoiceRequest struct {
+	ProjectID int64   `json:"project_id"` // donation to a project; 0 = membership fee
+	Amount    float64 `json:"amount"`     // CZK; for fees defaults to the debt or the monthly fee
+}
+
+// MeBTCPayInvoiceHandler creates a BTCPay invoice for the member's fee or for a
+// donation to a project and returns the checkout link. Only levels and projects
+// with btcpay enabled accept crypto payments.
+// POST /api/me/btcpay/invoice
+func (h *Handler) MeBTCPayInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	if h.btcpay == nil {
+		h.jsonError(w, "Crypto payments are not configured", http.StatusNotFound)
+		return
+	}
+
+	var req BTCPayInvoiceRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	amount := money.FromFloat(req.Amount)
+	if amount < 0 {
+		h.jsonError(w, "Amount must be positive", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	userID := strconv.FormatInt(dbUser.ID, 10)
+	params := btcpay.InvoiceParams{
+		BuyerEmail: dbUser.Email,
+		Metadata:   map[string]string{"user_id": userID},
+	}
+
+	if req.ProjectID != 0 {
+		project, err := h.queries.GetProject(ctx, req.ProjectID)
+		if err != nil {
+			h.jsonError(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		if !project.Btcpay {
+			h.jsonError(w, "The project does not accept crypto payments", http.StatusForbidden)
+			return
+		}
+		if amount == 0 {
+			h.jsonError(w, "Amount is required for a donation", http.StatusBadRequest)
+			return
+		}
+
+		projectID := strconv.FormatInt(project.ID, 10)
+		params.OrderID = "project-" + projectID
+		params.ItemDesc = "Příspěvek na projekt " + project.Name
+		params.Metadata["project_id"] = projectID
+		params.RedirectURL = h.config.BaseURL + "/profile?btcpay=success"
+		if project.Public {
+			params.RedirectURL = h.config.BaseURL + "/projects/" + projectID + "?btcpay=success"
+		}
+	} else {
+		level, err := h.queries.GetLevel(ctx, dbUser.LevelID)
+		if err != nil {
+			h.jsonError(w, "Failed to fetch level", http.StatusInternalServerError)
+			return
+		}
+		if !level.Btcpay {
+			h.jsonError(w, "Your membership level does not accept crypto payments", http.StatusForbidden)
+			return
+		}
+		if !dbUser.PaymentsID.Valid || dbUser.PaymentsID.String == "" {
+			h.jsonError(w, "You have no variable symbol yet, the payment could not be assigned", http.StatusBadRequest)
+			return
+		}
+
+		if amount == 0 {
+			balance, err := h.queries.GetUserBalance(ctx, db.GetUserBalanceParams{
+				UserID:   sql.NullInt64{Int64: dbUser.ID, Valid: true},
+				UserID_2: dbUser.ID,
+				UserID_3: sql.NullInt64{Int64: dbUser.ID, Valid: true},
+				UserID_4: dbUser.ID,
+				UserID_5: dbUser.ID,
+			})
+			if err != nil {
+				h.jsonError(w, fmt.Sprintf("Failed to calculate balance: %v", err), http.StatusInternalServerError)
+				return
+			}
+			amount = -money.Amount(balance)
+			if amount <= 0 {
+				amount = monthlyFeeAmount(level, dbUser)
+			}
+		}
+
+		params.OrderID = "fee-" + userID
+		params.ItemDesc = "Členský příspěvek Base48 (VS " + dbUser.PaymentsID.String + ")"
+		params.Metadata["payments_id"] = dbUser.PaymentsID.String
+		params.RedirectURL = h.config.BaseURL + "/profile?btcpay=success"
+	}
+	params.Amount = amount
+
+	invoice, err := h.btcpay.CreateInvoice(ctx, params)
+	if err != nil {
+		log.Printf("[BTCPay] Failed to create invoice for %s: %v", dbUser.Email, err)
+		h.jsonError(w, "Failed to start crypto payment", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"url":     invoice.CheckoutLink,
+		"amount":  amount.Float64(),
+	})
+}
+
+// BTCPayWebhookHandler records settled invoices as payments of the member or the
+// project. Authenticated by the signature of BTCPAY_WEBHOOK_SECRET; the invoice is
+// fetched from the API and its ID keeps a redelivered event from being stored twice.
+// POST /api/btcpay/webhook
+func (h *Handler) BTCPayWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if h.btcpay == nil {
+		h.jsonError(w, "Crypto payments are not configured", http.StatusNotFound)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	event, err := btcpay.ParseWebhook(payload, r.Header.Get("BTCPay-Sig"), h.config.BTCPayWebhookSecret)
+	if errors.Is(err, btcpay.ErrInvalidSignature) {
+		h.jsonError(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if event.Type != btcpay.EventInvoiceSettled || event.StoreID != h.config.BTCPayStoreID {
+		h.jsonSuccess(w, "Event ignored")
+		return
+	}
+
+	if err := h.recordBTCPayPayment(r.Context(), event.InvoiceID); err != nil {
+		log.Printf("[BTCPay] Failed to record invoice %s: %v", event.InvoiceID, err)
+		h.queries.CreateLog(r.Context(), db.CreateLogParams{
+			Subsystem: "btcpay",
+			Level:     "error",
+			Message:   fmt.Sprintf("Failed to record crypto payment %s: %v", event.InvoiceID, err),
+			Metadata:  sql.NullString{String: fmt.Sprintf(`{"invoice_id":%q,"delivery_id":%q}`, event.InvoiceID, event.DeliveryID), Valid: true},
+		})
+		// BTCPay retries failed deliveries
+		h.jsonError(w, "Failed to record payment", http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Payment recorded")
+}
+
+// recordBTCPayPayment stores a settled invoice as a payment from its metadata:
+// fees with the member's VS so they count in the balance, donations with the
+// project so they count in its total (and on the wall)
+func (h *Handler) recordBTCPayPayment(ctx context.Context, invoiceID string) error {
+	invoice, err := h.btcpay.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if invoice.Status != btcpay.StatusSettled {
+		return fmt.Errorf("invoice is %s, not settled", invoice.Status)
+	}
+	if invoice.Currency != "CZK" {
+		return fmt.Errorf("unexpected currency %q", invoice.Currency)
+	}
+
+	userID, err := strconv.ParseInt(invoice.MetadataString("user_id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invoice without user_id metadata")
+	}
+	user, err := h.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("user %d: %w", userID, err)
+	}
+
+	var projectID sql.NullInt64
+	identification := invoice.MetadataString("payments_id")
+	if value := invoice.MetadataString("project_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid project_id metadata %q", value)
+		}
+		project, err := h.queries.GetProject(ctx, id)
+		if err != nil {
+			return fmt.Errorf("project %d: %w", id, err)
+		}
+		projectID = sql.NullInt64{Int64: project.ID, Valid: true}
+		identification = project.PaymentsID.String
+	} else if identification == "" && user.PaymentsID.Valid {
+		identification = user.PaymentsID.String
+	}
+
+	rawData, _ := json.Marshal(invoice)
+
+	var payment db.Payment
+	err = h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		var err error
+		payment, err = queries.UpsertPayment(ctx, db.UpsertPaymentParams{
+			UserID:         sql.NullInt64{Int64: user.ID, Valid: true},
+			ProjectID:      projectID,
+			Date:           time.Now(),
+			Amount:         invoice.Amount,
+			Kind:           btcpayPaymentKind,
+			KindID:         invoice.ID,
+			LocalAccount:   "BTCPAY",
+			RemoteAccount:  user.Email,
+			Identification: identification,
+			RawData:        sql.NullString{String: string(rawData), Valid: true},
+			StaffComment:   sql.NullString{String: "Platba v kryptu (BTCPay)", Valid: true},
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "btcpay",
+		Level:     "success",
+		UserID:    sql.NullInt64{Int64: user.ID, Valid: true},
+		Message:   fmt.Sprintf("Crypto payment #%d (%s Kč) from %s recorded", payment.ID, invoice.Amount, user.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"payment_id":%d,"invoice_id":%q,"amount":"%s","project_id":%d}`,
+				payment.ID, invoice.ID, invoice.Amount, projectID.Int64),
+			Valid: true,
+		},
+	})
+	return nil
+}
+
+// AdminSetLevelBTCPayHandler allows or disallows crypto payments of fees for a level
+// POST /api/admin/levels/btcpay
+// Body: {"level_id": 1, "btcpay": true}
+func (h *Handler) AdminSetLevelBTCPayHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		LevelID int64 `json:"level_id"`
+		Btcpay  bool  `json:"btcpay"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.queries.SetLevelBTCPay(r.Context(), db.SetLevelBTCPayParams{
+		Btcpay: req.Btcpay,
+		ID:     req.LevelID,
+	}); err != nil {
+		h.jsonError(w, "Failed to update level: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Level updated")
+}
+
+// AdminSetProjectBTCPayHandler allows or disallows crypto donations to a project
+// POST /api/admin/projects/btcpay
+// Body: {"project_id": 1, "btcpay": true}
+func (h *Handler) AdminSetProjectBTCPayHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ProjectID int64 `json:"project_id"`
+		Btcpay    bool  `json:"btcpay"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.queries.SetProjectBTCPay(r.Context(), db.SetProjectBTCPayParams{
+		Btcpay: req.Btcpay,
+		ID:     req.ProjectID,
+	}); err != nil {
+		h.jsonError(w, "Failed to update project: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Project updated")
+}
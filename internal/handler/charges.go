@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// maxChargeDescription limits the description of a one-off charge
+const maxChargeDescription = 200
+
+// ChargeCategory is a category of one-off charges (charges.category)
+type ChargeCategory struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// chargeCategories lists the categories allowed by the charges table
+var chargeCategories = []ChargeCategory{
+	{ID: "locker", Title: "skříňka"},
+	{ID: "3d_printing", Title: "3D tisk"},
+	{ID: "materials", Title: "materiál z dílny"},
+	{ID: "other", Title: "jiné"},
+}
+
+// Charge is a one-off charge with its category title, for the profile
+type Charge struct {
+	db.Charge
+	CategoryTitle string
+}
+
+// userCharges returns the member's charges, newest first (nil on error, the
+// profile works without them)
+func (h *Handler) userCharges(ctx context.Context, userID int64) []Charge {
+	rows, err := h.queries.ListChargesByUser(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	charges := make([]Charge, 0, len(rows))
+	for _, row := range rows {
+		charge := Charge{Charge: row, CategoryTitle: row.Category}
+		for _, category := range chargeCategories {
+			if category.ID == row.Category {
+				charge.CategoryTitle = category.Title
+				break
+			}
+		}
+		charges = append(charges, charge)
+	}
+	return charges
+}
+
+// ChargeRequest is the body of POST /api/admin/users/{id}/charges
+type ChargeRequest struct {
+	Description string       `json:"description"`
+	Amount      money.Amount `json:"amount"`
+	Category    string       `json:"category"`
+	Date        string       `json:"date"` // YYYY-MM-DD, today by default
+}
+
+// AdminCreateChargeHandler adds a one-off charge (locker, 3D printing, material)
+// to a member; it counts in the membership balance like a fee
+// POST /api/admin/users/{id}/charges
+// Body: {"description": "PLA 250 g", "amount": 150, "category": "3d_printing", "date": "2026-10-14"}
+func (h *Handler) AdminCreateChargeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChargeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	description := strings.TrimSpace(req.Description)
+	if description == "" {
+		h.jsonError(w, "Description is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(description) > maxChargeDescription {
+		h.jsonError(w, fmt.Sprintf("Description is too long (max %d characters)", maxChargeDescription), http.StatusBadRequest)
+		return
+	}
+	if req.Amount <= 0 {
+		h.jsonError(w, "Amount must be positive", http.StatusBadRequest)
+		return
+	}
+	known := false
+	for _, category := range chargeCategories {
+		if category.ID == req.Category {
+			known = true
+			break
+		}
+	}
+	if !known {
+		h.jsonError(w, fmt.Sprintf("Unknown category: %s", req.Category), http.StatusBadRequest)
+		return
+	}
+	date := time.Now().UTC().Truncate(24 * time.Hour)
+	if req.Date != "" {
+		parsed, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			h.jsonError(w, "Invalid date (expected YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		date = parsed
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	// Charges change the balance - serialized with payments and fees
+	var charge db.Charge
+	err := h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		var err error
+		charge, err = queries.CreateCharge(ctx, db.CreateChargeParams{
+			UserID:      targetDBUser.ID,
+			Description: description,
+			Amount:      req.Amount,
+			Category:    req.Category,
+			Date:        date,
+			CreatedBy:   adminDBUser.Email,
+		})
+		return err
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to save charge", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s charged %s %s Kč: %s", adminDBUser.Email, targetDBUser.Email, req.Amount, description),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"charge_id":%d,"amount":%q,"category":%q}`,
+				adminDBUser.ID, targetDBUser.ID, charge.ID, req.Amount, req.Category),
+			Valid: true,
+		},
+	})
+
+	h.jsonSuccess(w, "Charge added")
+}
+
+// AdminDeleteChargeHandler removes a charge entered by mistake
+// DELETE /api/admin/charges/{id}
+func (h *Handler) AdminDeleteChargeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	chargeID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid charge ID", http.StatusBadRequest)
+		return
+	}
+
+	charge, err := h.queries.GetCharge(ctx, chargeID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Charge not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		return queries.DeleteCharge(ctx, charge.ID)
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to delete charge", http.StatusInternalServerError)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "warning",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s deleted charge #%d (%s Kč: %s) of user %d", adminDBUser.Email, charge.ID, charge.Amount, charge.Description, charge.UserID),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"charge_id":%d,"amount":%q}`,
+				adminDBUser.ID, charge.UserID, charge.ID, charge.Amount),
+			Valid: true,
+		},
+	})
+
+	h.jsonSuccess(w, "Charge deleted")
+}
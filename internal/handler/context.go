@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+type contextKey int
+
+const dbUserContextKey contextKey = iota
+
+// LoadDBUser is a middleware that resolves the database user of the logged in
+// member once per request (linking or creating the record on first login) and
+// stores it in the request context. Anonymous requests pass through unchanged,
+// so it can also guard routes that answer 401 themselves.
+func (h *Handler) LoadDBUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := h.auth.GetUser(r)
+		// Anonymous, or already resolved by APITokenAuth
+		if user == nil || DBUserFrom(r.Context()) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		dbUser, err := h.getOrCreateUser(r, user)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), dbUserContextKey, dbUser)))
+	})
+}
+
+// DBUserFrom returns the database user stored by LoadDBUser, or nil
+func DBUserFrom(ctx context.Context) *db.User {
+	dbUser, _ := ctx.Value(dbUserContextKey).(*db.User)
+	return dbUser
+}
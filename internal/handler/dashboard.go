@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// DashboardWidget describes a widget on the member dashboard (profile page)
+type DashboardWidget struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Endpoint string `json:"endpoint"` // JSON data source
+	Visible  bool   `json:"visible"`
+}
+
+// dashboardWidgets lists available widgets in display order
+var dashboardWidgets = []DashboardWidget{
+	{ID: "payments_year", Title: "Moje platby letos", Endpoint: "/api/me/widgets/payments-year"},
+	{ID: "balance_trend", Title: "Vývoj bilance", Endpoint: "/api/me/widgets/balance-trend"},
+	{ID: "occupancy", Title: "Obsazenost prostoru", Endpoint: "/api/me/widgets/occupancy"},
+}
+
+// WidgetSettingRequest is the body of POST /api/me/widgets
+type WidgetSettingRequest struct {
+	Widget  string `json:"widget"`
+	Visible bool   `json:"visible"`
+}
+
+// MonthAmount is a single data point of the monthly widgets
+type MonthAmount struct {
+	Month  string  `json:"month"` // YYYY-MM
+	Amount float64 `json:"amount"`
+}
+
+// spaceAPIClient fetches the SpaceAPI status for the occupancy widget
+var spaceAPIClient = &http.Client{Timeout: 5 * time.Second}
+
+// meDBUser returns the database user of the logged in member, writing a JSON 401 if there is none
+func (h *Handler) meDBUser(w http.ResponseWriter, r *http.Request) (*db.User, bool) {
+	dbUser := DBUserFrom(r.Context())
+	if dbUser == nil {
+		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	return dbUser, true
+}
+
+// userDashboardWidgets returns all widgets with the user's visibility settings applied
+func (h *Handler) userDashboardWidgets(ctx context.Context, userID int64) ([]DashboardWidget, error) {
+	settings, err := h.queries.ListUserDashboardWidgets(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden := make(map[string]bool)
+	for _, s := range settings {
+		hidden[s.Widget] = !s.Visible
+	}
+
+	widgets := make([]DashboardWidget, 0, len(dashboardWidgets))
+	for _, widget := range dashboardWidgets {
+		widget.Visible = !hidden[widget.ID]
+		widgets = append(widgets, widget)
+	}
+	return widgets, nil
+}
+
+// MeWidgetsHandler lists dashboard widgets with the member's show/hide settings
+// GET /api/me/widgets
+func (h *Handler) MeWidgetsHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	widgets, err := h.userDashboardWidgets(r.Context(), dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"widgets": widgets,
+	})
+}
+
+// MeWidgetSettingsHandler shows or hides a dashboard widget for the member
+// POST /api/me/widgets
+// Body: {"widget": "occupancy", "visible": false}
+func (h *Handler) MeWidgetSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req WidgetSettingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	known := false
+	for _, widget := range dashboardWidgets {
+		if widget.ID == req.Widget {
+			known = true
+			break
+		}
+	}
+	if !known {
+		h.jsonError(w, fmt.Sprintf("Unknown widget: %s", req.Widget), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.queries.SetUserDashboardWidget(r.Context(), db.SetUserDashboardWidgetParams{
+		UserID:  dbUser.ID,
+		Widget:  req.Widget,
+		Visible: req.Visible,
+	}); err != nil {
+		h.jsonError(w, "Failed to save widget settings", http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Widget settings saved")
+}
+
+// MePaymentsYearWidgetHandler returns the member's payments per month of the current year
+// GET /api/me/widgets/payments-year
+func (h *Handler) MePaymentsYearWidgetHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	payments, err := h.queries.ListPaymentsByUser(r.Context(), sql.NullInt64{Int64: dbUser.ID, Valid: true})
+	if err != nil {
+		h.jsonError(w, "Failed to fetch payments", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	months := make([]MonthAmount, int(now.Month()))
+	for i := range months {
+		months[i].Month = fmt.Sprintf("%d-%02d", now.Year(), i+1)
+	}
+
+	var total money.Amount
+	for _, payment := range payments {
+		if payment.Date.Year() != now.Year() || payment.Date.After(now) {
+			continue
+		}
+		months[payment.Date.Month()-1].Amount += payment.Amount.Float64()
+		total += payment.Amount
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"year":    now.Year(),
+		"total":   total.Float64(),
+		"months":  months,
+	})
+}
+
+// MeBalanceTrendWidgetHandler returns the membership balance at the end of each of the last 12 months
+// GET /api/me/widgets/balance-trend
+func (h *Handler) MeBalanceTrendWidgetHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	// Same inputs as GetUserBalance (payments, fees, charges, adjustments), oldest first
+	changes, err := h.queries.ListUserLedger(ctx, sql.NullInt64{Int64: dbUser.ID, Valid: true})
+	if err != nil {
+		h.jsonError(w, "Failed to fetch ledger", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	firstMonth := time.Date(now.Year(), now.Month()-11, 1, 0, 0, 0, 0, time.UTC)
+
+	points := make([]MonthAmount, 0, 12)
+	var balance money.Amount
+	next := 0
+	for month := firstMonth; len(points) < 12; month = month.AddDate(0, 1, 0) {
+		monthEnd := month.AddDate(0, 1, 0)
+		for next < len(changes) && changes[next].Date.Before(monthEnd) {
+			balance += changes[next].Amount
+			next++
+		}
+		points = append(points, MonthAmount{Month: month.Format("2006-01"), Amount: balance.Float64()})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"points":  points,
+	})
+}
+
+// MeOccupancyWidgetHandler returns whether the space is open and how many people are there,
+// taken from the hackerspace SpaceAPI endpoint (SPACE_API_URL)
+// GET /api/me/widgets/occupancy
+func (h *Handler) MeOccupancyWidgetHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.meDBUser(w, r); !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.config.SpaceAPIURL == "" {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":    true,
+			"configured": false,
+		})
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), "GET", h.config.SpaceAPIURL, nil)
+	if err != nil {
+		h.jsonError(w, "Invalid SPACE_API_URL", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := spaceAPIClient.Do(req)
+	if err != nil {
+		h.jsonError(w, "SpaceAPI unavailable", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		h.jsonError(w, fmt.Sprintf("SpaceAPI returned %s", resp.Status), http.StatusBadGateway)
+		return
+	}
+
+	var status struct {
+		State struct {
+			Open       *bool `json:"open"`
+			LastChange int64 `json:"lastchange"`
+		} `json:"state"`
+		Sensors struct {
+			PeopleNowPresent []struct {
+				Value int `json:"value"`
+			} `json:"people_now_present"`
+		} `json:"sensors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		h.jsonError(w, "Invalid SpaceAPI response", http.StatusBadGateway)
+		return
+	}
+
+	result := map[string]interface{}{
+		"success":    true,
+		"configured": true,
+		"open":       status.State.Open, // null if unknown
+	}
+	if status.State.LastChange > 0 {
+		result["last_change"] = time.Unix(status.State.LastChange, 0).Format(time.RFC3339)
+	}
+	if len(status.Sensors.PeopleNowPresent) > 0 {
+		people := 0
+		for _, sensor := range status.Sensors.PeopleNowPresent {
+			people += sensor.Value
+		}
+		result["people_present"] = people
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
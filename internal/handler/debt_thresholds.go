@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fees"
+)
+
+// AdminDebtThresholdsHandler returns the debt reminder ladder in effect and the
+// defaults from configuration
+// GET /api/admin/debt-thresholds
+func (h *Handler) AdminDebtThresholdsHandler(w http.ResponseWriter, r *http.Request) {
+	defaults := fees.DefaultDebtLadder(h.config)
+	ladder, err := fees.LoadDebtLadder(r.Context(), h.queries, defaults)
+	if err != nil {
+		h.jsonError(w, "Failed to load debt thresholds", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"thresholds": ladder,
+		"defaults":   defaults,
+	})
+}
+
+// AdminSetDebtThresholdsHandler stores the whole debt reminder ladder; the next
+// create_monthly_fees and suspend_debtors runs use it
+// POST /api/admin/debt-thresholds
+// Body: {"notice": 1, "warning": 2, "suspension": 3}
+func (h *Handler) AdminSetDebtThresholdsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var ladder fees.DebtLadder
+	if err := json.NewDecoder(r.Body).Decode(&ladder); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := ladder.Validate(); err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	steps := []struct {
+		name   string
+		months int
+	}{
+		{fees.DebtNotice, ladder.Notice},
+		{fees.DebtWarning, ladder.Warning},
+		{fees.DebtSuspension, ladder.Suspension},
+	}
+	for _, step := range steps {
+		if err := qtx.SetDebtThreshold(ctx, db.SetDebtThresholdParams{
+			Step:      step.name,
+			Months:    int64(step.months),
+			UpdatedBy: adminDBUser.Email,
+		}); err != nil {
+			h.jsonError(w, "Failed to save debt thresholds", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		h.jsonError(w, "Failed to save debt thresholds", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s set debt thresholds: notice %d, warning %d, suspension %d monthly fees",
+			adminDBUser.Email, ladder.Notice, ladder.Warning, ladder.Suspension),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"notice":%d,"warning":%d,"suspension":%d}`,
+				adminDBUser.ID, ladder.Notice, ladder.Warning, ladder.Suspension),
+			Valid: true,
+		},
+	})
+
+	h.jsonSuccess(w, "Debt thresholds saved")
+}
+
+// AdminResetDebtThresholdsHandler drops the thresholds set in admin settings, the
+// configuration defaults apply again
+// DELETE /api/admin/debt-thresholds
+func (h *Handler) AdminResetDebtThresholdsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := h.queries.DeleteDebtThresholds(ctx); err != nil {
+		h.jsonError(w, "Failed to reset debt thresholds", http.StatusInternalServerError)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s reset debt thresholds to configuration defaults", adminDBUser.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d}`, adminDBUser.ID),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Debt thresholds reset to defaults")
+}
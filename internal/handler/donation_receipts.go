@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/donation"
+	"github.com/base48/member-portal/internal/invoice"
+)
+
+// DonationReceiptRow is a donor of the year with the state of their receipt
+type DonationReceiptRow struct {
+	donation.Receipt
+	Sent bool // emailed by the bulk send
+}
+
+// donationReceipts groups donations of the year (donationsOfYear) into receipts of
+// donors (members), largest total first; donations from unknown accounts have no
+// donor to confirm them to
+func (h *Handler) donationReceipts(ctx context.Context, year int, donations []Donation) ([]donation.Receipt, error) {
+	users, err := h.queries.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usersByID := make(map[int64]db.User, len(users))
+	for _, u := range users {
+		usersByID[u.ID] = u
+	}
+
+	byDonor := make(map[int64]*donation.Receipt)
+	// donationsOfYear is newest first, receipts list donations oldest first
+	for i := len(donations) - 1; i >= 0; i-- {
+		d := donations[i]
+		user, ok := usersByID[d.UserID.Int64]
+		if !d.UserID.Valid || !ok {
+			continue
+		}
+		receipt, ok := byDonor[user.ID]
+		if !ok {
+			receipt = &donation.Receipt{Year: year, Donor: user}
+			byDonor[user.ID] = receipt
+		}
+		receipt.Add(donation.Item{Date: d.Date, Amount: d.Amount, Purpose: d.ProjectName})
+	}
+
+	receipts := make([]donation.Receipt, 0, len(byDonor))
+	for _, r := range byDonor {
+		receipts = append(receipts, *r)
+	}
+	sort.Slice(receipts, func(i, j int) bool {
+		if receipts[i].Total != receipts[j].Total {
+			return receipts[i].Total > receipts[j].Total
+		}
+		return receipts[i].Donor.ID < receipts[j].Donor.ID
+	})
+	return receipts, nil
+}
+
+// donationReceiptRows returns the receipts of the year with the sent state
+func (h *Handler) donationReceiptRows(ctx context.Context, year int, donations []Donation) ([]DonationReceiptRow, error) {
+	receipts, err := h.donationReceipts(ctx, year, donations)
+	if err != nil {
+		return nil, err
+	}
+	sent, err := h.queries.ListDonationReceiptsByYear(ctx, int64(year))
+	if err != nil {
+		return nil, err
+	}
+	sentTo := make(map[int64]bool, len(sent))
+	for _, s := range sent {
+		sentTo[s.UserID] = true
+	}
+
+	rows := make([]DonationReceiptRow, 0, len(receipts))
+	for _, r := range receipts {
+		rows = append(rows, DonationReceiptRow{Receipt: r, Sent: sentTo[r.Donor.ID]})
+	}
+	return rows, nil
+}
+
+// renderDonationReceipt renders the receipt PDF dated today
+func (h *Handler) renderDonationReceipt(r donation.Receipt) []byte {
+	return donation.RenderReceipt(r, invoice.IssuerFromConfig(h.config), h.config.DonationReceiptSignatory, time.Now())
+}
+
+// AdminDonationReceiptHandler downloads the donation receipt of a donor for a year
+// GET /admin/donations/receipts/{userID}?year=2026
+func (h *Handler) AdminDonationReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil || year <= 2000 || year >= 3000 {
+		http.Error(w, "Invalid year", http.StatusBadRequest)
+		return
+	}
+
+	donations, err := h.donationsOfYear(r.Context(), year)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	receipts, err := h.donationReceipts(r.Context(), year, donations)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	for _, receipt := range receipts {
+		if receipt.Donor.ID == userID {
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, receipt.Filename()))
+			w.Write(h.renderDonationReceipt(receipt))
+			return
+		}
+	}
+	http.Error(w, "No donations of this member in the year", http.StatusNotFound)
+}
+
+// AdminSendDonationReceiptsHandler emails the receipts of a year to all donors who
+// have not got theirs yet
+// POST /api/admin/donations/receipts/send
+// Body: {"year": 2025}
+func (h *Handler) AdminSendDonationReceiptsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req struct {
+		Year int `json:"year"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Year <= 2000 || req.Year >= time.Now().Year() {
+		h.jsonError(w, "Receipts can be sent only for a past year", http.StatusBadRequest)
+		return
+	}
+	if h.config.SMTPHost == "" {
+		// SendTemplated would skip silently and the receipts would be recorded as sent
+		h.jsonError(w, "SMTP not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	donations, err := h.donationsOfYear(ctx, req.Year)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	rows, err := h.donationReceiptRows(ctx, req.Year, donations)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	sent := 0
+	failed := 0
+	for _, row := range rows {
+		if row.Sent {
+			continue
+		}
+		donor := row.Donor
+		if err := h.emailClient.SendDonationReceipt(ctx, &donor, row.Receipt, h.renderDonationReceipt(row.Receipt)); err != nil {
+			failed++
+			continue
+		}
+		if err := h.queries.CreateDonationReceipt(ctx, db.CreateDonationReceiptParams{
+			UserID: donor.ID,
+			Year:   int64(req.Year),
+		}); err != nil {
+			failed++
+			continue
+		}
+		sent++
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s sent donation receipts for %d: %d sent, %d failed", adminDBUser.Email, req.Year, sent, failed),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"year":%d,"sent":%d,"failed":%d}`, adminDBUser.ID, req.Year, sent, failed),
+			Valid:  true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"sent":    sent,
+		"failed":  failed,
+	})
+}
@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// Payment classifications (payments.classification, payment_splits.classification)
+const (
+	classificationFee      = "fee"
+	classificationDonation = "donation"
+)
+
+// Donation is one donation of the report with the donor and project resolved
+type Donation struct {
+	db.ListDonationsRow
+	Donor       string // member name or email, sender account for unknown donors
+	ProjectName string
+}
+
+// DonationTotal is the sum of donations of one donor or to one project
+type DonationTotal struct {
+	Name  string
+	Total money.Amount
+	Count int
+}
+
+// DonationMonth is the sum of donations received in one month
+type DonationMonth struct {
+	Month string
+	Total money.Amount
+	Count int
+}
+
+// donationsOfYear lists donations received in the year with donors and projects resolved
+func (h *Handler) donationsOfYear(ctx context.Context, year int) ([]Donation, error) {
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := h.queries.ListDonations(ctx, db.ListDonationsParams{
+		DateFrom: from,
+		DateTo:   from.AddDate(1, 0, 0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := h.queries.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	donors := make(map[int64]string, len(users))
+	for _, u := range users {
+		donors[u.ID] = u.Email
+		if u.Realname.Valid && u.Realname.String != "" {
+			donors[u.ID] = u.Realname.String
+		}
+	}
+	projects, err := h.queries.ListProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	projectNames := make(map[int64]string, len(projects))
+	for _, p := range projects {
+		projectNames[p.ID] = p.Name
+	}
+
+	donations := make([]Donation, 0, len(rows))
+	for _, row := range rows {
+		d := Donation{ListDonationsRow: row, Donor: row.RemoteAccount}
+		if row.UserID.Valid {
+			d.Donor = donors[row.UserID.Int64]
+		}
+		if row.ProjectID.Valid {
+			d.ProjectName = projectNames[row.ProjectID.Int64]
+		}
+		donations = append(donations, d)
+	}
+	return donations, nil
+}
+
+// AdminDonationsHandler shows donations of a year with monthly, donor and project
+// totals - the summary for the annual accounting
+// GET /admin/donations?year=2026
+func (h *Handler) AdminDonationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	year := time.Now().Year()
+	if parsed, err := strconv.Atoi(r.URL.Query().Get("year")); err == nil && parsed > 2000 && parsed < 3000 {
+		year = parsed
+	}
+
+	donations, err := h.donationsOfYear(ctx, year)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	months := make([]DonationMonth, 12)
+	for i := range months {
+		months[i].Month = fmt.Sprintf("%d-%02d", year, i+1)
+	}
+	byDonor := make(map[string]*DonationTotal)
+	byProject := make(map[string]*DonationTotal)
+	var total money.Amount
+	for _, d := range donations {
+		months[d.Date.Month()-1].Total += d.Amount
+		months[d.Date.Month()-1].Count++
+		total += d.Amount
+
+		addDonationTotal(byDonor, d.Donor, d.Amount)
+		addDonationTotal(byProject, d.ProjectName, d.Amount)
+	}
+
+	receipts, err := h.donationReceiptRows(ctx, year, donations)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":            "Dary",
+		"User":             h.auth.GetUser(r),
+		"DBUser":           DBUserFrom(ctx),
+		"Year":             year,
+		"PrevYear":         year - 1,
+		"NextYear":         year + 1,
+		"Donations":        donations,
+		"Months":           months,
+		"DonorTotals":      sortedDonationTotals(byDonor),
+		"ProjectTotals":    sortedDonationTotals(byProject),
+		"Total":            total,
+		"Receipts":         receipts,
+		"PastYear":         year < time.Now().Year(),
+		"ReceiptSignatory": h.config.DonationReceiptSignatory,
+	}
+
+	h.render(w, "admin_donations.html", data)
+}
+
+// AdminDonationsCSVHandler exports donations of a year for the accounting, in the
+// same format as the member payment export (UTF-8 with BOM, semicolons, decimal comma)
+// GET /admin/donations.csv?year=2026
+func (h *Handler) AdminDonationsCSVHandler(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().Year()
+	if parsed, err := strconv.Atoi(r.URL.Query().Get("year")); err == nil && parsed > 2000 && parsed < 3000 {
+		year = parsed
+	}
+
+	donations, err := h.donationsOfYear(r.Context(), year)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="base48-dary-%d.csv"`, year))
+	// Excel detects UTF-8 only with the byte order mark
+	w.Write([]byte("\ufeff"))
+
+	out := csv.NewWriter(w)
+	out.Comma = ';'
+	out.Write([]string{"Datum", "Částka (Kč)", "Dárce", "Projekt", "VS", "Protiúčet", "Platba", "Část platby"})
+	for _, d := range donations {
+		split := ""
+		if d.Split {
+			split = "ano"
+		}
+		out.Write([]string{
+			d.Date.Format("2006-01-02"),
+			strings.Replace(d.Amount.String(), ".", ",", 1),
+			d.Donor,
+			d.ProjectName,
+			d.Identification,
+			d.RemoteAccount,
+			strconv.FormatInt(d.PaymentID, 10),
+			split,
+		})
+	}
+	out.Flush()
+}
+
+func addDonationTotal(totals map[string]*DonationTotal, name string, amount money.Amount) {
+	t, ok := totals[name]
+	if !ok {
+		t = &DonationTotal{Name: name}
+		totals[name] = t
+	}
+	t.Total += amount
+	t.Count++
+}
+
+func sortedDonationTotals(totals map[string]*DonationTotal) []DonationTotal {
+	sorted := make([]DonationTotal, 0, len(totals))
+	for _, t := range totals {
+		sorted = append(sorted, *t)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Total > sorted[j].Total })
+	return sorted
+}
+
+// AdminSetPaymentClassificationHandler marks a payment as a membership fee or a
+// donation. A donation does not count in the member's balance even with their VS;
+// "" returns to the automatic classification (project VS or project = donation).
+// POST /api/admin/payments/{id}/classification
+// Body: {"classification": "donation"}
+func (h *Handler) AdminSetPaymentClassificationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Classification string `json:"classification"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch req.Classification {
+	case "", classificationFee, classificationDonation:
+	default:
+		h.jsonError(w, "Classification must be fee, donation or empty", http.StatusBadRequest)
+		return
+	}
+
+	payment, err := h.queries.GetPayment(ctx, id)
+	if err != nil {
+		h.jsonError(w, "Payment not found", http.StatusNotFound)
+		return
+	}
+	if payment.Amount <= 0 {
+		h.jsonError(w, "Only incoming payments can be classified", http.StatusBadRequest)
+		return
+	}
+
+	err = h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		return queries.SetPaymentClassification(ctx, db.SetPaymentClassificationParams{
+			Classification: req.Classification,
+			ID:             id,
+		})
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to classify payment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s classified payment #%d (%s CZK) as %q", adminDBUser.Email, id, payment.Amount, req.Classification),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"payment_id":%d,"from":%q,"to":%q}`, adminDBUser.ID, id, payment.Classification, req.Classification),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Payment classified")
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// ExpenseMonth is the sum of expenses in one month
+type ExpenseMonth struct {
+	Month string
+	Total float64
+	Count int
+}
+
+// ExpenseCategoryTotal is the sum of expenses with one tag ("" = untagged)
+type ExpenseCategoryTotal struct {
+	Category string
+	Total    float64
+	Count    int
+}
+
+// AdminExpensesHandler shows outgoing payments of a year with monthly and category totals
+// GET /admin/expenses?year=2026&category=&project=
+func (h *Handler) AdminExpensesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	year := time.Now().Year()
+	if parsed, err := strconv.Atoi(query.Get("year")); err == nil && parsed > 2000 && parsed < 3000 {
+		year = parsed
+	}
+	category := strings.TrimSpace(query.Get("category"))
+	var projectID int64
+	if parsed, err := strconv.ParseInt(query.Get("project"), 10, 64); err == nil {
+		projectID = parsed
+	}
+
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	expenses, err := h.queries.ListExpenses(ctx, db.ListExpensesParams{
+		DateFrom:  from,
+		DateTo:    from.AddDate(1, 0, 0),
+		Category:  category,
+		ProjectID: projectID,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	months := make([]ExpenseMonth, 12)
+	for i := range months {
+		months[i].Month = fmt.Sprintf("%d-%02d", year, i+1)
+	}
+	byCategory := make(map[string]*ExpenseCategoryTotal)
+	total := 0.0
+	for _, e := range expenses {
+		amount := e.Amount.Float64()
+		months[e.Date.Month()-1].Total += amount
+		months[e.Date.Month()-1].Count++
+		total += amount
+
+		c, ok := byCategory[e.Category]
+		if !ok {
+			c = &ExpenseCategoryTotal{Category: e.Category}
+			byCategory[e.Category] = c
+		}
+		c.Total += amount
+		c.Count++
+	}
+	categoryTotals := make([]ExpenseCategoryTotal, 0, len(byCategory))
+	for _, c := range byCategory {
+		categoryTotals = append(categoryTotals, *c)
+	}
+	sort.Slice(categoryTotals, func(i, j int) bool { return categoryTotals[i].Total > categoryTotals[j].Total })
+
+	categories, err := h.queries.ListExpenseCategories(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	projects, err := h.queries.ListProjects(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":          "Výdaje",
+		"User":           h.auth.GetUser(r),
+		"DBUser":         DBUserFrom(ctx),
+		"Year":           year,
+		"PrevYear":       year - 1,
+		"NextYear":       year + 1,
+		"Category":       category,
+		"ProjectID":      projectID,
+		"Expenses":       expenses,
+		"Months":         months,
+		"CategoryTotals": categoryTotals,
+		"Total":          total,
+		"Categories":     categories,
+		"Projects":       projects,
+	}
+
+	h.render(w, "admin_expenses.html", data)
+}
+
+// UpdateExpenseRequest is the request body for tagging an expense
+type UpdateExpenseRequest struct {
+	Category     string `json:"category"`
+	ProjectID    *int64 `json:"project_id"` // null = no project
+	StaffComment string `json:"staff_comment"`
+}
+
+// AdminUpdateExpenseHandler sets the category, project and comment of an expense
+// POST /api/admin/expenses/{id}
+func (h *Handler) AdminUpdateExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateExpenseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	category := strings.TrimSpace(req.Category)
+	if len(category) > 100 {
+		h.jsonError(w, "Category is too long", http.StatusBadRequest)
+		return
+	}
+
+	projectID := sql.NullInt64{}
+	if req.ProjectID != nil {
+		if _, err := h.queries.GetProject(ctx, *req.ProjectID); err != nil {
+			h.jsonError(w, "Project not found", http.StatusBadRequest)
+			return
+		}
+		projectID = sql.NullInt64{Int64: *req.ProjectID, Valid: true}
+	}
+
+	comment := strings.TrimSpace(req.StaffComment)
+	rows, err := h.queries.UpdateExpenseTags(ctx, db.UpdateExpenseTagsParams{
+		Category:     category,
+		ProjectID:    projectID,
+		StaffComment: sql.NullString{String: comment, Valid: comment != ""},
+		ID:           id,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to update expense: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+
+	h.jsonSuccess(w, "Expense updated")
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// FakturoidRequest is the body of POST /api/admin/users/{id}/fakturoid
+type FakturoidRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// feeFakturoidInvoices returns the member's Fakturoid invoices by fee ID (nil on
+// error, the profile works without them)
+func (h *Handler) feeFakturoidInvoices(ctx context.Context, userID int64) map[int64]*db.FakturoidInvoice {
+	invoices, err := h.queries.ListFakturoidInvoicesByUser(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	byFee := make(map[int64]*db.FakturoidInvoice, len(invoices))
+	for i := range invoices {
+		byFee[invoices[i].FeeID] = &invoices[i]
+	}
+	return byFee
+}
+
+// AdminSetFakturoidHandler turns invoicing of the member's fees in Fakturoid on or
+// off. Only fees created after turning it on are invoiced (by sync_fakturoid).
+// POST /api/admin/users/{id}/fakturoid
+// Body: {"enabled": true}
+func (h *Handler) AdminSetFakturoidHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req FakturoidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Enabled && !h.config.FakturoidEnabled() {
+		h.jsonError(w, "Fakturoid not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	if _, err := h.queries.GetBillingDetails(ctx, targetDBUser.ID); err == sql.ErrNoRows {
+		h.jsonError(w, "Member has no billing details", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var err error
+	if req.Enabled {
+		_, err = h.queries.EnableBillingFakturoid(ctx, targetDBUser.ID)
+	} else {
+		_, err = h.queries.DisableBillingFakturoid(ctx, targetDBUser.ID)
+	}
+	if err != nil {
+		h.jsonError(w, "Failed to update billing details", http.StatusInternalServerError)
+		return
+	}
+
+	state := "off"
+	if req.Enabled {
+		state = "on"
+	}
+	adminDBUser := DBUserFrom(ctx)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s turned Fakturoid invoicing %s for %s", adminDBUser.Email, state, targetDBUser.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"enabled":%t}`, adminDBUser.ID, targetDBUser.ID, req.Enabled),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Fakturoid invoicing updated")
+}
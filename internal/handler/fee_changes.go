@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fees"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// CreateFeeChangeRequest is the body of POST /api/admin/fee-changes
+type CreateFeeChangeRequest struct {
+	LevelID       int64   `json:"level_id"`
+	Amount        float64 `json:"amount"`         // new monthly amount, whole CZK
+	EffectiveFrom string  `json:"effective_from"` // YYYY-MM, first month billed with the new amount
+	Note          string  `json:"note"`           // shown in the notification email
+}
+
+// AdminCreateFeeChangeHandler plans a new level amount from a future month. Members
+// are notified by notify_fee_changes, create_monthly_fees switches the amount.
+// POST /api/admin/fee-changes
+func (h *Handler) AdminCreateFeeChangeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateFeeChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	level, err := h.queries.GetLevel(ctx, req.LevelID)
+	if err != nil {
+		h.jsonError(w, "Level not found", http.StatusNotFound)
+		return
+	}
+
+	if req.Amount < 0 {
+		h.jsonError(w, "Amount must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	effectiveFrom, err := fees.ParseEffectiveMonth(req.EffectiveFrom)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Fees of the current month may already exist, the change can start next month at the earliest
+	now := time.Now()
+	if !effectiveFrom.After(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)) {
+		h.jsonError(w, "Effective month must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	note := strings.TrimSpace(req.Note)
+	change, err := h.queries.CreateLevelPriceChange(ctx, db.CreateLevelPriceChangeParams{
+		LevelID:       level.ID,
+		NewAmount:     money.FromFloat(req.Amount),
+		EffectiveFrom: effectiveFrom,
+		Note:          sql.NullString{String: note, Valid: note != ""},
+		CreatedBy:     adminUsername,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to plan fee change (is another change planned for the same month?)", http.StatusConflict)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) planned fee change for %s: %s → %s Kč from %s",
+			adminUsername, adminDBUser.Email, level.Name, level.Amount, change.NewAmount, effectiveFrom.Format("2006-01")),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"change_id":%d,"level_id":%d,"old_amount":%q,"new_amount":%q,"effective_from":%q}`,
+				adminDBUser.ID, change.ID, level.ID, level.Amount, change.NewAmount, effectiveFrom.Format("2006-01")),
+			Valid: true,
+		},
+	})
+
+	notifyBy := fees.NoticeDeadline(effectiveFrom, h.config.FeeChangeNoticeWeeks)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"change":      change,
+		"notify_by":   notifyBy.Format("2006-01-02"),
+		"late_notice": notifyBy.Before(now),
+	})
+}
+
+// AdminDeleteFeeChangeHandler cancels a planned fee change that has not been applied yet
+// DELETE /api/admin/fee-changes/{id}
+func (h *Handler) AdminDeleteFeeChangeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid fee change ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.queries.DeleteLevelPriceChange(ctx, id)
+	if err != nil {
+		h.jsonError(w, "Failed to cancel fee change", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "Fee change not found or already applied", http.StatusConflict)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s (%s) cancelled planned fee change #%d", adminUsername, adminDBUser.Email, id),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"change_id":%d}`, adminDBUser.ID, id),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Fee change cancelled")
+}
@@ -1,30 +1,46 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
 	"html/template"
 	"net/http"
-	"path/filepath"
+	"time"
 
 	"github.com/base48/member-portal/internal/auth"
+	"github.com/base48/member-portal/internal/balance"
+	"github.com/base48/member-portal/internal/btcpay"
 	"github.com/base48/member-portal/internal/config"
 	"github.com/base48/member-portal/internal/db"
 	"github.com/base48/member-portal/internal/email"
+	"github.com/base48/member-portal/internal/keycloak"
+	"github.com/base48/member-portal/internal/money"
 	"github.com/base48/member-portal/internal/qrpay"
+	"github.com/base48/member-portal/internal/ratelimit"
+	"github.com/base48/member-portal/internal/stripe"
+	"github.com/base48/member-portal/internal/templates"
 )
 
 // Handler holds dependencies for HTTP handlers
 type Handler struct {
 	auth           *auth.Authenticator
 	queries        *db.Queries
-	templates      *template.Template
+	database       *sql.DB // for handlers that need their own transaction
+	templates      *templates.Resolver
 	config         *config.Config
 	serviceAccount *auth.ServiceAccountClient
 	emailClient    *email.Client
 	qrpayService   *qrpay.Service
-	webRoot        string
+	roleCache      *keycloak.RoleCache
+	userCache      *keycloak.UserCache
+	balanceQueue   *balance.Queue
+	maintenance    *maintenanceMode
+	authLimiter    *ratelimit.Limiter // nil = no limit
+	apiLimiter     *ratelimit.Limiter // nil = no limit
+	stripe         *stripe.Client     // nil = card payments off
+	btcpay         *btcpay.Client     // nil = crypto payments off
 }
 
 // New creates a new Handler instance
@@ -57,26 +73,86 @@ func New(authenticator *auth.Authenticator, database *sql.DB, cfg *config.Config
 	// Initialize email client (with QR service for payment codes in emails)
 	emailClient := email.New(cfg, queries, qrService)
 
-	// Note: templates is set to nil, we'll parse on each request
-	// This is simpler than managing template name conflicts
+	// Keycloak user list and user roles are cached and refreshed in the background,
+	// so admin user lists only wait for Keycloak right after start or a refresh
+	userCache := keycloak.NewUserCache(
+		time.Duration(cfg.KeycloakUserCacheTTL)*time.Second,
+		func(ctx context.Context) ([]keycloak.User, error) {
+			if serviceAccount == nil {
+				return nil, fmt.Errorf("service account not configured")
+			}
+			return keycloak.NewClientWithTokenProvider(cfg, serviceAccount).ListUsers(ctx)
+		},
+	)
+	roleCache := keycloak.NewRoleCache(time.Duration(cfg.KeycloakRoleCacheTTL) * time.Second)
+	if serviceAccount != nil {
+		userCache.Start(context.Background())
+		roleCache.Start(context.Background(), keycloak.NewClientWithTokenProvider(cfg, serviceAccount))
+	}
+
+	// All balance-changing writes of the server go through a single writer
+	balanceQueue := balance.NewQueue(database)
+	balanceQueue.Start(context.Background())
+
+	// Rate limiters are per process, a restart resets them
+	var authLimiter, apiLimiter *ratelimit.Limiter
+	if cfg.RateLimitAuth > 0 {
+		authLimiter = ratelimit.New(cfg.RateLimitAuth, 0)
+	}
+	if cfg.RateLimitAPI > 0 {
+		apiLimiter = ratelimit.New(cfg.RateLimitAPI, 0)
+	}
+
+	// Card payments need both the API key and the webhook secret, otherwise
+	// paid sessions would never be recorded
+	var stripeClient *stripe.Client
+	if cfg.StripeSecretKey != "" && cfg.StripeWebhookSecret != "" {
+		stripeClient = stripe.NewClient(cfg.StripeSecretKey)
+	}
+
+	var btcpayClient *btcpay.Client
+	if cfg.BTCPayURL != "" && cfg.BTCPayAPIKey != "" && cfg.BTCPayStoreID != "" && cfg.BTCPayWebhookSecret != "" {
+		btcpayClient = btcpay.NewClient(cfg.BTCPayURL, cfg.BTCPayAPIKey, cfg.BTCPayStoreID)
+	}
+
+	// Templates are parsed on each request (simpler than managing template name
+	// conflicts); broken deployment overrides stop the server here
+	templateResolver := templates.New(cfg.WebRoot, cfg.TemplateOverrideDir)
+	if err := templateResolver.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid template overrides: %w", err)
+	}
+
 	return &Handler{
 		auth:           authenticator,
 		queries:        queries,
-		templates:      nil, // Will be loaded per-request
+		database:       database,
+		templates:      templateResolver,
 		config:         cfg,
 		serviceAccount: serviceAccount,
 		emailClient:    emailClient,
 		qrpayService:   qrService,
-		webRoot:        cfg.WebRoot,
+		roleCache:      roleCache,
+		userCache:      userCache,
+		balanceQueue:   balanceQueue,
+		maintenance: &maintenanceMode{
+			enabled: cfg.MaintenanceMode,
+			until:   cfg.MaintenanceUntil,
+			message: cfg.MaintenanceMessage,
+		},
+		authLimiter: authLimiter,
+		apiLimiter:  apiLimiter,
+		stripe:      stripeClient,
+		btcpay:      btcpayClient,
 	}, nil
 }
 
-// getServiceAccountToken is a helper to get service account token with error handling
-func (h *Handler) getServiceAccountToken(ctx context.Context) (string, error) {
+// keycloakClient returns a Keycloak admin client backed by the service account.
+// The token is cached by the service account and renewed only when needed.
+func (h *Handler) keycloakClient() (*keycloak.Client, error) {
 	if h.serviceAccount == nil {
-		return "", fmt.Errorf("service account not configured")
+		return nil, fmt.Errorf("service account not configured")
 	}
-	return h.serviceAccount.GetAccessToken(ctx)
+	return keycloak.NewClientWithTokenProvider(h.config, h.serviceAccount), nil
 }
 
 // HomeHandler displays the home page
@@ -161,7 +237,7 @@ func (h *Handler) getOrCreateUser(r *http.Request, kcUser *auth.User) (*db.User,
 		Phone:             sql.NullString{},
 		AltContact:        sql.NullString{},
 		LevelID:           1, // Awaiting level
-		LevelActualAmount: "0",
+		LevelActualAmount: 0,
 		PaymentsID:        sql.NullString{},
 		State:             "awaiting",
 		IsCouncil:         false,
@@ -186,16 +262,7 @@ func (h *Handler) getOrCreateUser(r *http.Request, kcUser *auth.User) (*db.User,
 // ProfileHandler displays and updates user profile
 func (h *Handler) ProfileHandler(w http.ResponseWriter, r *http.Request) {
 	user := h.auth.GetUser(r)
-	if user == nil {
-		http.Redirect(w, r, "/auth/login", http.StatusTemporaryRedirect)
-		return
-	}
-
-	dbUser, err := h.getOrCreateUser(r, user)
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
-	}
+	dbUser := DBUserFrom(r.Context())
 
 	if r.Method == http.MethodPost {
 		// Check which form was submitted
@@ -232,6 +299,49 @@ func (h *Handler) ProfileHandler(w http.ResponseWriter, r *http.Request) {
 	data["User"] = data["ViewedUser"]  // For own profile, ViewedUser = current user
 	data["DBUser"] = dbUser             // For layout compatibility (current user)
 	data["Success"] = r.URL.Query().Get("success") == "1"
+	if ledger, ok := data["Ledger"].([]UserLedgerEntry); ok {
+		data["StatementYears"] = statementYears(ledger)
+	}
+
+	// Widgets are optional - the profile still works without them
+	if widgets, err := h.userDashboardWidgets(r.Context(), dbUser.ID); err == nil {
+		data["DashboardWidgets"] = widgets
+	}
+	data["StripeEnabled"] = h.stripe != nil
+	data["StripePaid"] = r.URL.Query().Get("stripe") == "success"
+	data["BTCPayEnabled"] = h.btcpay != nil
+	data["BTCPayPaid"] = r.URL.Query().Get("btcpay") == "success"
+	if prefs, err := h.userNotificationPreferences(r.Context(), dbUser.ID); err == nil {
+		data["NotificationPreferences"] = prefs
+	}
+	if tokens, err := h.userAPITokens(r.Context(), dbUser.ID); err == nil {
+		data["APITokens"] = tokens
+		data["APITokenScopes"] = availableAPITokenScopes(user)
+	}
+
+	// Company billing and invoices (optional as well)
+	if billing, err := h.queries.GetBillingDetails(r.Context(), dbUser.ID); err == nil {
+		data["Billing"] = billing
+	}
+	if invoices, err := h.queries.ListInvoicesByUser(r.Context(), dbUser.ID); err == nil {
+		data["Invoices"] = invoices
+	}
+	if reimbursements, err := h.queries.ListReimbursementsByUser(r.Context(), dbUser.ID); err == nil {
+		data["Reimbursements"] = reimbursements
+	}
+	if tickets, err := h.userTicketThreads(r.Context(), dbUser.ID); err == nil {
+		data["Tickets"] = tickets
+	}
+	if projects, err := h.queries.ListMemberProjects(r.Context(), dbUser.ID); err == nil {
+		data["MemberProjects"] = projects
+	}
+	if dbUser.State == "accepted" {
+		if applications, err := h.queries.ListPendingApplicationsForMember(r.Context(), dbUser.ID); err == nil {
+			data["PendingApplications"] = applications
+			data["VouchesRequired"] = int64(h.config.ApplicationVouches)
+		}
+	}
+	data["SupportEmail"] = h.config.SupportEmail
 
 	h.render(w, "profile.html", data)
 }
@@ -241,8 +351,8 @@ func (h *Handler) handleCustomFeeUpdate(w http.ResponseWriter, r *http.Request,
 	customFeeStr := r.FormValue("custom_fee_amount")
 
 	// Parse the custom fee amount
-	var customFee float64
-	if _, err := fmt.Sscanf(customFeeStr, "%f", &customFee); err != nil {
+	customFee, err := money.Parse(customFeeStr)
+	if err != nil {
 		http.Error(w, "Neplatná částka", http.StatusBadRequest)
 		return
 	}
@@ -254,22 +364,15 @@ func (h *Handler) handleCustomFeeUpdate(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	// Parse level minimum amount
-	var levelMinimum float64
-	if _, err := fmt.Sscanf(level.Amount, "%f", &levelMinimum); err != nil {
-		http.Error(w, "Chyba konfigurace úrovně členství", http.StatusInternalServerError)
-		return
-	}
-
 	// Validate: custom fee must be >= level minimum
-	if customFee < levelMinimum {
-		http.Error(w, fmt.Sprintf("Částka musí být minimálně %s Kč (minimum pro %s)", level.Amount, level.Name), http.StatusBadRequest)
+	if customFee < level.Amount {
+		http.Error(w, fmt.Sprintf("Částka musí být minimálně %.0f Kč (minimum pro %s)", level.Amount.Float64(), level.Name), http.StatusBadRequest)
 		return
 	}
 
 	// Update the custom fee amount
 	_, err = h.queries.UpdateUserCustomFee(r.Context(), db.UpdateUserCustomFeeParams{
-		LevelActualAmount: fmt.Sprintf("%.0f", customFee),
+		LevelActualAmount: customFee,
 		ID:                dbUser.ID,
 	})
 	if err != nil {
@@ -282,8 +385,8 @@ func (h *Handler) handleCustomFeeUpdate(w http.ResponseWriter, r *http.Request,
 		Subsystem: "membership",
 		Level:     "info",
 		UserID:    sql.NullInt64{Int64: dbUser.ID, Valid: true},
-		Message:   fmt.Sprintf("Custom fee amount updated: %.0f Kč (minimum: %s Kč)", customFee, level.Amount),
-		Metadata:  sql.NullString{String: fmt.Sprintf(`{"old_amount":"%s","new_amount":"%.0f","level_minimum":"%s"}`, dbUser.LevelActualAmount, customFee, level.Amount), Valid: true},
+		Message:   fmt.Sprintf("Custom fee amount updated: %s Kč (minimum: %s Kč)", customFee, level.Amount),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"old_amount":"%s","new_amount":"%s","level_minimum":"%s"}`, dbUser.LevelActualAmount, customFee, level.Amount), Valid: true},
 	})
 
 	http.Redirect(w, r, "/profile?success=1", http.StatusSeeOther)
@@ -291,25 +394,35 @@ func (h *Handler) handleCustomFeeUpdate(w http.ResponseWriter, r *http.Request,
 
 // render is a helper to render templates
 func (h *Handler) render(w http.ResponseWriter, name string, data interface{}) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	h.renderStatus(w, http.StatusOK, name, data)
+}
 
-	// Add BaseURL to template data for OG tags
+// renderStatus is render with a custom status code (e.g. 503 for the maintenance page)
+func (h *Handler) renderStatus(w http.ResponseWriter, status int, name string, data interface{}) {
+	// Add BaseURL to template data for OG tags, maintenance state for the admin banner
 	if dataMap, ok := data.(map[string]interface{}); ok {
 		dataMap["BaseURL"] = h.config.BaseURL
+		if _, set := dataMap["Maintenance"]; !set {
+			dataMap["Maintenance"] = h.maintenance.status()
+		}
 	}
 
 	// Parse templates fresh each time to avoid name conflicts
-	tmpl, err := template.ParseFiles(
-		filepath.Join(h.webRoot, "templates", "layout.html"),
-		filepath.Join(h.webRoot, "templates", name),
-	)
+	tmpl, err := template.ParseFiles(h.templates.PageFiles(name)...)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Template parse error: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Execute the layout template (which includes the specific page)
-	if err := tmpl.ExecuteTemplate(w, "layout.html", data); err != nil {
+	// Execute the layout template (which includes the specific page); buffered so
+	// that a template error can still be reported with a proper status code
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "layout.html", data); err != nil {
 		http.Error(w, fmt.Sprintf("Template execution error: %v", err), http.StatusInternalServerError)
+		return
 	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
 }
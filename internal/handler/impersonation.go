@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/auth"
+	"github.com/base48/member-portal/internal/db"
+)
+
+// Impersonation is a middleware for member routes that serves the request as the
+// member an admin is impersonating. It must run after LoadDBUser. The view is
+// read-only: an admin must not submit forms on behalf of the member.
+func (h *Handler) Impersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		admin := h.auth.GetUser(r)
+		targetID := h.auth.ImpersonatedUserID(r)
+		// A session left over after the admin role was removed is ignored
+		if targetID == 0 || !admin.IsAdmin() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			msg := "Read-only while viewing the portal as a member"
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				h.jsonError(w, msg, http.StatusForbidden)
+			} else {
+				http.Error(w, msg, http.StatusForbidden)
+			}
+			return
+		}
+
+		target, err := h.queries.GetUserByID(ctx, targetID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Impersonated member not available: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		impersonator := admin.PreferredName
+		if impersonator == "" {
+			impersonator = admin.Email
+		}
+		member := &auth.User{
+			ID:            target.KeycloakID.String,
+			Email:         target.Email,
+			EmailVerified: true,
+			Name:          target.Realname.String,
+			PreferredName: target.Username.String,
+			Roles:         h.memberRoles(ctx, &target),
+			Impersonator:  impersonator,
+		}
+
+		ctx = context.WithValue(ctx, dbUserContextKey, &target)
+		ctx = auth.WithUser(ctx, member)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// memberRoles returns the Keycloak realm roles of a member from the role cache;
+// without a Keycloak account (or with Keycloak unavailable) the member has none
+func (h *Handler) memberRoles(ctx context.Context, user *db.User) []string {
+	if !user.KeycloakID.Valid {
+		return nil
+	}
+	kcClient, err := h.keycloakClient()
+	if err != nil {
+		return nil
+	}
+	userRoles, err := h.roleCache.Get(ctx, kcClient)
+	if err != nil {
+		log.Printf("Failed to load roles of user %d: %v", user.ID, err)
+		return nil
+	}
+	return userRoles[user.KeycloakID.String]
+}
+
+// AdminImpersonateHandler starts viewing the dashboard and profile as a member
+// POST /api/admin/impersonate/{userID}
+func (h *Handler) AdminImpersonateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	target, err := h.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		h.jsonError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	if target.ID == adminDBUser.ID {
+		h.jsonError(w, "Cannot impersonate yourself", http.StatusBadRequest)
+		return
+	}
+
+	previousID := h.auth.ImpersonatedUserID(r)
+	if err := h.auth.SetImpersonation(w, r, target.ID); err != nil {
+		h.jsonError(w, "Failed to save session", http.StatusInternalServerError)
+		return
+	}
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s (%s) started viewing the portal as %s", adminUsername, adminDBUser.Email, target.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"previous_target_user_id":%d}`, adminDBUser.ID, target.ID, previousID),
+			Valid:  true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"redirect": "/profile",
+	})
+}
+
+// AdminStopImpersonationHandler returns the admin to their own view
+// DELETE /api/admin/impersonate
+func (h *Handler) AdminStopImpersonationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetID := h.auth.ImpersonatedUserID(r)
+	if targetID == 0 {
+		h.jsonError(w, "Not impersonating anyone", http.StatusConflict)
+		return
+	}
+
+	if err := h.auth.SetImpersonation(w, r, 0); err != nil {
+		h.jsonError(w, "Failed to save session", http.StatusInternalServerError)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	targetEmail := fmt.Sprintf("user #%d", targetID)
+	if target, err := h.queries.GetUserByID(ctx, targetID); err == nil {
+		targetEmail = target.Email
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s (%s) stopped viewing the portal as %s", adminUsername, adminDBUser.Email, targetEmail),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d}`, adminDBUser.ID, targetID),
+			Valid:  true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"redirect": fmt.Sprintf("/admin/users/%d", targetID),
+	})
+}
@@ -0,0 +1,637 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/invoice"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// BillingDetailsRequest is the body of POST /api/me/billing
+type BillingDetailsRequest struct {
+	CompanyName string `json:"company_name"`
+	CompanyID   string `json:"company_id"` // IČO
+	VatID       string `json:"vat_id"`     // DIČ
+	Address     string `json:"address"`
+}
+
+// InvoiceRequest is the body of POST /api/me/invoices
+type InvoiceRequest struct {
+	Months int64  `json:"months"`
+	Note   string `json:"note"`
+}
+
+// ItemInvoiceRequest is the body of POST /api/me/invoices/items and
+// POST /api/admin/users/{id}/invoices; exactly one of the IDs is set
+type ItemInvoiceRequest struct {
+	FeeID    int64  `json:"fee_id"`
+	ChargeID int64  `json:"charge_id"`
+	Note     string `json:"note"`
+}
+
+// ItemInvoices are the member's invoices by the fee or charge they were issued for
+type ItemInvoices struct {
+	Fees    map[int64]*db.Invoice
+	Charges map[int64]*db.Invoice
+}
+
+// RejectInvoiceRequest is the body of POST /api/admin/invoices/{id}/reject
+type RejectInvoiceRequest struct {
+	Reason string `json:"reason"`
+}
+
+// MeBillingHandler returns the member's company billing details (null if not set)
+// GET /api/me/billing
+func (h *Handler) MeBillingHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	var billing *db.BillingDetail
+	details, err := h.queries.GetBillingDetails(r.Context(), dbUser.ID)
+	if err == nil {
+		billing = &details
+	} else if err != sql.ErrNoRows {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"billing": billing,
+	})
+}
+
+// MeUpdateBillingHandler saves the member's company billing details
+// POST /api/me/billing
+// Body: {"company_name": "ACME s.r.o.", "company_id": "12345678", "vat_id": "CZ12345678", "address": "..."}
+func (h *Handler) MeUpdateBillingHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req BillingDetailsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.CompanyName = strings.TrimSpace(req.CompanyName)
+	req.Address = strings.TrimSpace(req.Address)
+	if req.CompanyName == "" || req.Address == "" {
+		h.jsonError(w, "Company name and address are required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.queries.UpsertBillingDetails(r.Context(), db.UpsertBillingDetailsParams{
+		UserID:      dbUser.ID,
+		CompanyName: req.CompanyName,
+		CompanyID:   strings.TrimSpace(req.CompanyID),
+		VatID:       strings.TrimSpace(req.VatID),
+		Address:     req.Address,
+	}); err != nil {
+		h.jsonError(w, "Failed to save billing details", http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Billing details saved")
+}
+
+// MeInvoicesHandler lists the member's invoices and invoice requests
+// GET /api/me/invoices
+func (h *Handler) MeInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	invoices, err := h.queries.ListInvoicesByUser(r.Context(), dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"invoices": invoices,
+	})
+}
+
+// MeRequestInvoiceHandler creates an invoice request for N monthly fees. The
+// company details are copied from the member's billing details.
+// POST /api/me/invoices
+// Body: {"months": 12, "note": "PO 2026/123"}
+func (h *Handler) MeRequestInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	var req InvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Months < 1 || req.Months > invoice.MaxMonths {
+		h.jsonError(w, fmt.Sprintf("Months must be between 1 and %d", invoice.MaxMonths), http.StatusBadRequest)
+		return
+	}
+
+	if dbUser.State != "accepted" {
+		h.jsonError(w, "Invoices are available to active members only", http.StatusForbidden)
+		return
+	}
+
+	billing, err := h.queries.GetBillingDetails(ctx, dbUser.ID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Fill in billing details first", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// One pending request at a time keeps the admin queue readable
+	existing, err := h.queries.ListInvoicesByUser(ctx, dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	for _, inv := range existing {
+		if inv.State == invoice.StateRequested {
+			h.jsonError(w, "You already have a pending invoice request", http.StatusConflict)
+			return
+		}
+	}
+
+	level, err := h.queries.GetLevel(ctx, dbUser.LevelID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	amount := monthlyFeeAmount(level, dbUser) * money.Amount(req.Months)
+
+	inv, err := h.queries.CreateInvoiceRequest(ctx, db.CreateInvoiceRequestParams{
+		UserID:      dbUser.ID,
+		Months:      req.Months,
+		Amount:      amount,
+		CompanyName: billing.CompanyName,
+		CompanyID:   billing.CompanyID,
+		VatID:       billing.VatID,
+		Address:     billing.Address,
+		Note:        sql.NullString{String: strings.TrimSpace(req.Note), Valid: strings.TrimSpace(req.Note) != ""},
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to create invoice request", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "invoice",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Invoice requested by %s: %d months, %s Kč (%s)", dbUser.Email, req.Months, amount, billing.CompanyName),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"invoice_id":%d,"months":%d}`, inv.ID, req.Months), Valid: true},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"invoice": inv,
+	})
+}
+
+// MeItemInvoiceHandler issues an invoice for one of the member's fees or charges
+// POST /api/me/invoices/items
+// Body: {"fee_id": 123} or {"charge_id": 45, "note": "PO 2026/123"}
+func (h *Handler) MeItemInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req ItemInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	inv, ok := h.issueItemInvoice(w, r, dbUser, req, dbUser.Email)
+	if !ok {
+		return
+	}
+
+	h.queries.CreateLog(r.Context(), db.CreateLogParams{
+		Subsystem: "invoice",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Invoice %s issued to %s: %s, %s Kč", inv.Number.String, dbUser.Email, inv.Item.String, inv.Amount),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"invoice_id":%d,"number":%q}`, inv.ID, inv.Number.String), Valid: true},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"invoice": inv,
+	})
+}
+
+// AdminItemInvoiceHandler issues an invoice for a fee or a charge of a member
+// POST /api/admin/users/{id}/invoices
+// Body: {"fee_id": 123} or {"charge_id": 45}
+func (h *Handler) AdminItemInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req ItemInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	inv, ok := h.issueItemInvoice(w, r, targetDBUser, req, adminDBUser.Email)
+	if !ok {
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s issued invoice %s to %s: %s, %s Kč", adminDBUser.Email, inv.Number.String, targetDBUser.Email, inv.Item.String, inv.Amount),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"invoice_id":%d,"number":%q}`, adminDBUser.ID, targetDBUser.ID, inv.ID, inv.Number.String),
+			Valid:  true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"invoice": inv,
+	})
+}
+
+// issueItemInvoice issues an invoice for a fee or a charge of member, addressed to
+// the member's billing details; it writes the error response and returns false
+// when the invoice cannot be issued
+func (h *Handler) issueItemInvoice(w http.ResponseWriter, r *http.Request, member *db.User, req ItemInvoiceRequest, issuedBy string) (db.Invoice, bool) {
+	ctx := r.Context()
+
+	if (req.FeeID > 0) == (req.ChargeID > 0) {
+		h.jsonError(w, "Set either fee_id or charge_id", http.StatusBadRequest)
+		return db.Invoice{}, false
+	}
+
+	billing, err := h.queries.GetBillingDetails(ctx, member.ID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Fill in billing details first", http.StatusBadRequest)
+		return db.Invoice{}, false
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return db.Invoice{}, false
+	}
+
+	params := db.CreateItemInvoiceParams{
+		UserID:      member.ID,
+		CompanyName: billing.CompanyName,
+		CompanyID:   billing.CompanyID,
+		VatID:       billing.VatID,
+		Address:     billing.Address,
+		Note:        sql.NullString{String: strings.TrimSpace(req.Note), Valid: strings.TrimSpace(req.Note) != ""},
+		DecidedBy:   sql.NullString{String: issuedBy, Valid: true},
+	}
+	if req.FeeID > 0 {
+		fee, err := h.queries.GetFee(ctx, req.FeeID)
+		if err == sql.ErrNoRows || (err == nil && fee.UserID != member.ID) {
+			h.jsonError(w, "Fee not found", http.StatusNotFound)
+			return db.Invoice{}, false
+		} else if err != nil {
+			h.jsonError(w, "Database error", http.StatusInternalServerError)
+			return db.Invoice{}, false
+		}
+		if _, err := h.queries.GetFakturoidInvoice(ctx, fee.ID); err == nil {
+			h.jsonError(w, "Fee is invoiced in Fakturoid", http.StatusConflict)
+			return db.Invoice{}, false
+		} else if err != sql.ErrNoRows {
+			h.jsonError(w, "Database error", http.StatusInternalServerError)
+			return db.Invoice{}, false
+		}
+		params.FeeID = sql.NullInt64{Int64: fee.ID, Valid: true}
+		params.Item = sql.NullString{String: invoice.FeeItem(fee), Valid: true}
+		params.Months = fee.Months
+		params.Amount = fee.Amount
+	} else {
+		charge, err := h.queries.GetCharge(ctx, req.ChargeID)
+		if err == sql.ErrNoRows || (err == nil && charge.UserID != member.ID) {
+			h.jsonError(w, "Charge not found", http.StatusNotFound)
+			return db.Invoice{}, false
+		} else if err != nil {
+			h.jsonError(w, "Database error", http.StatusInternalServerError)
+			return db.Invoice{}, false
+		}
+		params.ChargeID = sql.NullInt64{Int64: charge.ID, Valid: true}
+		params.Item = sql.NullString{String: charge.Description, Valid: true}
+		params.Amount = charge.Amount
+	}
+	if params.Amount <= 0 {
+		// Waived fees have nothing to invoice
+		h.jsonError(w, "Nothing to invoice", http.StatusBadRequest)
+		return db.Invoice{}, false
+	}
+
+	existing, err := h.itemInvoices(ctx, member.ID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return db.Invoice{}, false
+	}
+	if existing.Fees[req.FeeID] != nil || existing.Charges[req.ChargeID] != nil {
+		h.jsonError(w, "Invoice has already been issued", http.StatusConflict)
+		return db.Invoice{}, false
+	}
+
+	// Number and invoice in one transaction, so the series has no gaps
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return db.Invoice{}, false
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	now := time.Now()
+	sequence, err := qtx.NextInvoiceNumber(ctx, int64(now.Year()))
+	if err != nil {
+		h.jsonError(w, "Failed to allocate invoice number", http.StatusInternalServerError)
+		return db.Invoice{}, false
+	}
+	params.Number = sql.NullString{String: invoice.FormatNumber(now.Year(), sequence), Valid: true}
+	params.IssuedAt = sql.NullTime{Time: now, Valid: true}
+	params.DueAt = sql.NullTime{Time: now.AddDate(0, 0, h.config.InvoiceDueDays), Valid: true}
+
+	inv, err := qtx.CreateItemInvoice(ctx, params)
+	if err != nil {
+		h.jsonError(w, "Failed to issue invoice", http.StatusInternalServerError)
+		return db.Invoice{}, false
+	}
+	if err := tx.Commit(); err != nil {
+		h.jsonError(w, "Failed to issue invoice", http.StatusInternalServerError)
+		return db.Invoice{}, false
+	}
+	return inv, true
+}
+
+// itemInvoices returns the member's invoices issued for fees and charges
+func (h *Handler) itemInvoices(ctx context.Context, userID int64) (ItemInvoices, error) {
+	invoices, err := h.queries.ListInvoicesByUser(ctx, userID)
+	if err != nil {
+		return ItemInvoices{}, err
+	}
+	items := ItemInvoices{Fees: map[int64]*db.Invoice{}, Charges: map[int64]*db.Invoice{}}
+	for i := range invoices {
+		inv := &invoices[i]
+		if inv.FeeID.Valid {
+			items.Fees[inv.FeeID.Int64] = inv
+		}
+		if inv.ChargeID.Valid {
+			items.Charges[inv.ChargeID.Int64] = inv
+		}
+	}
+	return items, nil
+}
+
+// InvoicePDFHandler downloads an issued invoice as PDF (own invoices, admins any);
+// a paid invoice shows the date of payment and serves as the receipt
+// GET /invoices/{id}/pdf
+func (h *Handler) InvoicePDFHandler(w http.ResponseWriter, r *http.Request) {
+	user := h.auth.GetUser(r)
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.queries.GetInvoice(ctx, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Invoice not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	member, err := h.queries.GetUserByID(ctx, inv.UserID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Members see only their own invoices; answer 404 so IDs cannot be probed
+	if !user.IsAdmin() && (!member.KeycloakID.Valid || member.KeycloakID.String != user.ID) {
+		http.Error(w, "Invoice not found", http.StatusNotFound)
+		return
+	}
+
+	if inv.State != invoice.StateApproved && inv.State != invoice.StatePaid {
+		http.Error(w, "Invoice has not been issued yet", http.StatusConflict)
+		return
+	}
+
+	content, err := invoice.Render(inv, invoice.IssuerFromConfig(h.config), member)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to render invoice: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="faktura-%s.pdf"`, inv.Number.String))
+	w.Write(content)
+}
+
+// AdminInvoicesHandler shows the invoice queue (pending requests first)
+// GET /admin/invoices
+func (h *Handler) AdminInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	user := h.auth.GetUser(r)
+
+	ctx := r.Context()
+
+	invoices, err := h.queries.ListInvoicesWithUsers(ctx, 200)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	pending := 0
+	for _, inv := range invoices {
+		if inv.State == invoice.StateRequested {
+			pending++
+		}
+	}
+
+	// Get DBUser for layout
+	dbUser := DBUserFrom(ctx)
+
+	data := map[string]interface{}{
+		"Title":    "Faktury",
+		"User":     user,
+		"DBUser":   dbUser,
+		"Invoices": invoices,
+		"Pending":  pending,
+	}
+
+	h.render(w, "admin_invoices.html", data)
+}
+
+// AdminApproveInvoiceHandler assigns the next number of the year's series and
+// issues the invoice; the number is the VS the payment is matched by
+// POST /api/admin/invoices/{id}/approve
+func (h *Handler) AdminApproveInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	// Number and approval in one transaction, so the series has no gaps
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	now := time.Now()
+	sequence, err := qtx.NextInvoiceNumber(ctx, int64(now.Year()))
+	if err != nil {
+		h.jsonError(w, "Failed to allocate invoice number", http.StatusInternalServerError)
+		return
+	}
+	number := invoice.FormatNumber(now.Year(), sequence)
+
+	rows, err := qtx.ApproveInvoice(ctx, db.ApproveInvoiceParams{
+		Number:    sql.NullString{String: number, Valid: true},
+		DecidedBy: sql.NullString{String: adminUsername, Valid: true},
+		IssuedAt:  sql.NullTime{Time: now, Valid: true},
+		DueAt:     sql.NullTime{Time: now.AddDate(0, 0, h.config.InvoiceDueDays), Valid: true},
+		ID:        id,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to approve invoice", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "Invoice is not waiting for approval", http.StatusConflict)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		h.jsonError(w, "Failed to approve invoice", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s (%s) approved invoice %s", adminUsername, adminDBUser.Email, number),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"invoice_id":%d,"number":%q}`, adminDBUser.ID, id, number),
+			Valid:  true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Invoice approved",
+		"number":  number,
+	})
+}
+
+// AdminRejectInvoiceHandler rejects an invoice request
+// POST /api/admin/invoices/{id}/reject
+// Body: {"reason": "..."}
+func (h *Handler) AdminRejectInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	var req RejectInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	rows, err := h.queries.RejectInvoice(ctx, db.RejectInvoiceParams{
+		AdminComment: sql.NullString{String: req.Reason, Valid: req.Reason != ""},
+		DecidedBy:    sql.NullString{String: adminUsername, Valid: true},
+		ID:           id,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to reject invoice", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "Invoice is not waiting for approval", http.StatusConflict)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s (%s) rejected invoice request #%d", adminUsername, adminDBUser.Email, id),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"invoice_id":%d,"reason":%q}`, adminDBUser.ID, id, req.Reason),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Invoice request rejected")
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/base48/member-portal/internal/fees"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// UserLedgerEntry is one line of a member's balance ledger
+type UserLedgerEntry struct {
+	Type    string       `json:"type"` // payment, split (fee allocation of a split payment), fee, charge or adjustment
+	ID      int64        `json:"id"`   // payment, fee, charge or adjustment ID
+	Date    string       `json:"date"` // YYYY-MM-DD
+	Day     time.Time    `json:"-"`    // Date for templates
+	Title   string       `json:"title"`
+	Note    string       `json:"note"`
+	Amount  money.Amount `json:"amount"`  // negative for fees and charges
+	Balance money.Amount `json:"balance"` // running balance after this entry
+}
+
+// userLedger returns everything counted in the member's balance, oldest first,
+// with a running balance (the last one equals GetUserBalance)
+func (h *Handler) userLedger(ctx context.Context, userID int64) ([]UserLedgerEntry, error) {
+	rows, err := h.queries.ListUserLedger(ctx, sql.NullInt64{Int64: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]UserLedgerEntry, len(rows))
+	var balance money.Amount
+	for i, row := range rows {
+		balance += row.Amount
+		entry := UserLedgerEntry{
+			Type:    row.EntryType,
+			ID:      row.EntryID,
+			Date:    row.Date.Format("2006-01-02"),
+			Day:     row.Date,
+			Note:    row.Note,
+			Amount:  row.Amount,
+			Balance: balance,
+		}
+		switch row.EntryType {
+		case "payment":
+			entry.Title = "Platba"
+		case "split":
+			entry.Title = "Část platby"
+		case "fee":
+			entry.Title = "Členský příspěvek " + fees.PeriodLabel(row.Date, int(row.Months))
+		case "charge":
+			entry.Title = "Poplatek"
+		case "adjustment":
+			entry.Title = "Úprava bilance"
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+// MeLedgerHandler returns the member's payments, fees, charges and balance
+// adjustments in one list, oldest first, with a running balance
+// GET /api/me/ledger
+func (h *Handler) MeLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.userLedger(r.Context(), dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch ledger", http.StatusInternalServerError)
+		return
+	}
+
+	var balance money.Amount
+	if len(entries) > 0 {
+		balance = entries[len(entries)-1].Balance
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"entries": entries,
+		"balance": balance,
+	})
+}
@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fees"
+)
+
+// maxLevelChangeNote limits the member's note to a level change request
+const maxLevelChangeNote = 500
+
+// LevelChangeRequestBody is the body of POST /api/me/level-change
+type LevelChangeRequestBody struct {
+	LevelID       int64  `json:"level_id"`
+	EffectiveFrom string `json:"effective_from"` // YYYY-MM, first month with the new level
+	Note          string `json:"note"`
+}
+
+// ApproveLevelChangeRequestBody is the body of POST /api/admin/level-changes/{id}/approve
+type ApproveLevelChangeRequestBody struct {
+	EffectiveFrom string `json:"effective_from"` // YYYY-MM, optional: the month the member asked for
+}
+
+// RejectLevelChangeRequestBody is the body of POST /api/admin/level-changes/{id}/reject
+type RejectLevelChangeRequestBody struct {
+	Reason string `json:"reason"`
+}
+
+// firstFutureMonth returns the first month a level change can take effect: fees of
+// the current month may already exist
+func firstFutureMonth(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// MeLevelChangesHandler returns the member's level change requests and level history
+// GET /api/me/level-change
+func (h *Handler) MeLevelChangesHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	requests, err := h.queries.ListLevelChangeRequestsByUser(ctx, dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch level change requests", http.StatusInternalServerError)
+		return
+	}
+	history, err := h.queries.ListLevelHistoryByUser(ctx, dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch level history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"requests": requests,
+		"history":  history,
+	})
+}
+
+// MeRequestLevelChangeHandler asks for another membership level from a future month;
+// an admin approves the request and create_monthly_fees switches the level
+// POST /api/me/level-change
+// Body: {"level_id": 3, "effective_from": "2026-05", "note": "..."}
+func (h *Handler) MeRequestLevelChangeHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	var req LevelChangeRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if dbUser.State != "accepted" {
+		h.jsonError(w, "Level changes are available to active members only", http.StatusForbidden)
+		return
+	}
+
+	level, err := h.queries.GetLevel(ctx, req.LevelID)
+	if err != nil || !level.Active {
+		h.jsonError(w, "Level not found", http.StatusNotFound)
+		return
+	}
+	if level.ID == dbUser.LevelID {
+		h.jsonError(w, "You already have this level", http.StatusBadRequest)
+		return
+	}
+
+	effectiveFrom, err := fees.ParseEffectiveMonth(req.EffectiveFrom)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if effectiveFrom.Before(firstFutureMonth(time.Now())) {
+		h.jsonError(w, "Effective month must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	note := strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(note) > maxLevelChangeNote {
+		h.jsonError(w, fmt.Sprintf("Note is too long (max %d characters)", maxLevelChangeNote), http.StatusBadRequest)
+		return
+	}
+
+	// One pending request at a time keeps the admin queue readable
+	if _, err := h.queries.GetPendingLevelChangeRequest(ctx, dbUser.ID); err == nil {
+		h.jsonError(w, "You already have a pending level change request", http.StatusConflict)
+		return
+	} else if err != sql.ErrNoRows {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	request, err := h.queries.CreateLevelChangeRequest(ctx, db.CreateLevelChangeRequestParams{
+		UserID:        dbUser.ID,
+		LevelID:       level.ID,
+		EffectiveFrom: effectiveFrom,
+		Note:          sql.NullString{String: note, Valid: note != ""},
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to create level change request", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "membership",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Level change requested by %s: %s from %s", dbUser.Email, level.Name, effectiveFrom.Format("2006-01")),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"request_id":%d,"old_level_id":%d,"new_level_id":%d,"effective_from":%q}`,
+				request.ID, dbUser.LevelID, level.ID, effectiveFrom.Format("2006-01")),
+			Valid: true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"request": request,
+	})
+}
+
+// AdminLevelChangesHandler lists level change requests waiting for approval
+// GET /api/admin/level-changes
+func (h *Handler) AdminLevelChangesHandler(w http.ResponseWriter, r *http.Request) {
+	requests, err := h.queries.ListPendingLevelChangeRequests(r.Context())
+	if err != nil {
+		h.jsonError(w, "Failed to fetch level change requests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"requests": requests,
+	})
+}
+
+// AdminApproveLevelChangeHandler approves a level change request and records it in
+// the member's level history; create_monthly_fees switches the level in the
+// effective month
+// POST /api/admin/level-changes/{id}/approve
+// Body: {"effective_from": "2026-06"} (optional)
+func (h *Handler) AdminApproveLevelChangeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ApproveLevelChangeRequestBody
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	request, err := h.queries.GetLevelChangeRequest(ctx, id)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Level change request not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	effectiveFrom := request.EffectiveFrom
+	if req.EffectiveFrom != "" {
+		if effectiveFrom, err = fees.ParseEffectiveMonth(req.EffectiveFrom); err != nil {
+			h.jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if effectiveFrom.Before(firstFutureMonth(time.Now())) {
+		h.jsonError(w, "Effective month must be in the future (set effective_from)", http.StatusBadRequest)
+		return
+	}
+
+	// Approval and history entry in one transaction
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	approved, err := qtx.ApproveLevelChangeRequest(ctx, db.ApproveLevelChangeRequestParams{
+		EffectiveFrom: effectiveFrom,
+		DecidedBy:     sql.NullString{String: adminUsername, Valid: true},
+		ID:            id,
+	})
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Request is not waiting for approval", http.StatusConflict)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Failed to approve level change", http.StatusInternalServerError)
+		return
+	}
+	if _, err := qtx.CreateLevelHistory(ctx, db.CreateLevelHistoryParams{
+		UserID:        approved.UserID,
+		LevelID:       approved.LevelID,
+		EffectiveFrom: approved.EffectiveFrom,
+		RequestID:     sql.NullInt64{Int64: approved.ID, Valid: true},
+		CreatedBy:     adminUsername,
+	}); err != nil {
+		h.jsonError(w, "Failed to record level history", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		h.jsonError(w, "Failed to approve level change", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) approved level change request #%d of user %d from %s",
+			adminUsername, adminDBUser.Email, id, approved.UserID, effectiveFrom.Format("2006-01")),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"request_id":%d,"target_user_id":%d,"level_id":%d,"effective_from":%q}`,
+				adminDBUser.ID, id, approved.UserID, approved.LevelID, effectiveFrom.Format("2006-01")),
+			Valid: true,
+		},
+	})
+
+	h.jsonSuccess(w, "Level change approved")
+}
+
+// AdminRejectLevelChangeHandler rejects a level change request
+// POST /api/admin/level-changes/{id}/reject
+// Body: {"reason": "..."}
+func (h *Handler) AdminRejectLevelChangeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	var req RejectLevelChangeRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	rows, err := h.queries.RejectLevelChangeRequest(ctx, db.RejectLevelChangeRequestParams{
+		AdminComment: sql.NullString{String: req.Reason, Valid: req.Reason != ""},
+		DecidedBy:    sql.NullString{String: adminUsername, Valid: true},
+		ID:           id,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to reject level change", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "Request is not waiting for approval", http.StatusConflict)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s (%s) rejected level change request #%d", adminUsername, adminDBUser.Email, id),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"request_id":%d,"reason":%q}`, adminDBUser.ID, id, req.Reason),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Level change request rejected")
+}
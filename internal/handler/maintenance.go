@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// maxMaintenanceMinutes limits how long maintenance can be switched on from the admin API
+const maxMaintenanceMinutes = 24 * 60
+
+// maintenanceMode is the runtime maintenance switch. It starts from the MAINTENANCE_*
+// config; changes made by admins are kept in memory only and reset on restart.
+type maintenanceMode struct {
+	mu      sync.RWMutex
+	enabled bool
+	until   time.Time // zero = until turned off
+	message string
+}
+
+// MaintenanceStatus describes the current maintenance window
+type MaintenanceStatus struct {
+	Active  bool       `json:"active"`
+	Until   *time.Time `json:"until,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+// status returns the current state; a window whose end has passed is no longer active
+func (m *maintenanceMode) status() MaintenanceStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.enabled || (!m.until.IsZero() && time.Now().After(m.until)) {
+		return MaintenanceStatus{}
+	}
+
+	status := MaintenanceStatus{Active: true, Message: m.message}
+	if !m.until.IsZero() {
+		until := m.until
+		status.Until = &until
+	}
+	return status
+}
+
+func (m *maintenanceMode) set(enabled bool, until time.Time, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.enabled = enabled
+	m.until = until
+	m.message = message
+}
+
+// maintenanceAllowedPrefixes stay reachable during maintenance so admins can log in
+var maintenanceAllowedPrefixes = []string{"/static/", "/auth/"}
+
+// MaintenanceMiddleware serves a 503 page (JSON for /api/) to everyone except admins
+// while maintenance mode is active
+func (h *Handler) MaintenanceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := h.maintenance.status()
+		if !status.Active {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for _, prefix := range maintenanceAllowedPrefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		user := h.auth.GetUser(r)
+		if user != nil && user.IsAdmin() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if status.Until != nil {
+			retryAfter := int(time.Until(*status.Until).Seconds()) + 1
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		}
+
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			h.jsonError(w, "Service is under maintenance", http.StatusServiceUnavailable)
+			return
+		}
+
+		h.renderStatus(w, http.StatusServiceUnavailable, "maintenance.html", map[string]interface{}{
+			"Title":       "Údržba",
+			"User":        user,
+			"Maintenance": status,
+		})
+	})
+}
+
+// AdminMaintenanceHandler returns the maintenance mode state
+// GET /api/admin/maintenance
+func (h *Handler) AdminMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"maintenance": h.maintenance.status(),
+	})
+}
+
+// MaintenanceRequest is the request body for switching maintenance mode
+type MaintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Minutes int    `json:"minutes"` // length of the window, required when enabling
+	Message string `json:"message"` // shown to members on the maintenance page
+}
+
+// AdminSetMaintenanceHandler switches maintenance mode on for a limited time, or off
+// POST /api/admin/maintenance
+// Body: {"enabled": true, "minutes": 60, "message": "..."}
+func (h *Handler) AdminSetMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req MaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var until time.Time
+	if req.Enabled {
+		if req.Minutes < 1 || req.Minutes > maxMaintenanceMinutes {
+			h.jsonError(w, fmt.Sprintf("minutes must be between 1 and %d", maxMaintenanceMinutes), http.StatusBadRequest)
+			return
+		}
+		until = time.Now().Add(time.Duration(req.Minutes) * time.Minute)
+	}
+	message := strings.TrimSpace(req.Message)
+
+	h.maintenance.set(req.Enabled, until, message)
+
+	adminDBUser := DBUserFrom(ctx)
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	logMessage := fmt.Sprintf("Admin %s (%s) turned maintenance mode off", adminUsername, adminDBUser.Email)
+	if req.Enabled {
+		logMessage = fmt.Sprintf("Admin %s (%s) turned maintenance mode on until %s",
+			adminUsername, adminDBUser.Email, until.Format("2006-01-02 15:04"))
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "warning",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   logMessage,
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"enabled":%t,"minutes":%d,"message":%q}`,
+				adminDBUser.ID, req.Enabled, req.Minutes, message),
+			Valid: true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"maintenance": h.maintenance.status(),
+	})
+}
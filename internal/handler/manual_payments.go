@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// manualPaymentKind is payments.kind of payments entered by admins (cash at the space, ...)
+const manualPaymentKind = "manual"
+
+// ManualPaymentRequest is the request body for POST /api/admin/payments
+type ManualPaymentRequest struct {
+	UserID       int64   `json:"user_id"`
+	Amount       float64 `json:"amount"`        // CZK, must be positive
+	Date         string  `json:"date"`          // YYYY-MM-DD, today if empty
+	StaffComment string  `json:"staff_comment"` // e.g. "hotově na členské schůzi"
+}
+
+// AdminCreateManualPaymentHandler records a payment received outside the bank
+// account. The VS is set to the member's payments_id, so it counts in the balance
+// and shows in the payment history like bank payments.
+// POST /api/admin/payments
+func (h *Handler) AdminCreateManualPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req ManualPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	amount := money.FromFloat(req.Amount)
+	if amount <= 0 {
+		h.jsonError(w, "Amount must be positive", http.StatusBadRequest)
+		return
+	}
+
+	date := time.Now()
+	if req.Date != "" {
+		parsed, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			h.jsonError(w, "Invalid date (expected YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		if parsed.After(time.Now()) {
+			h.jsonError(w, "Date is in the future", http.StatusBadRequest)
+			return
+		}
+		date = parsed
+	}
+
+	user, err := h.queries.GetUserByID(ctx, req.UserID)
+	if err != nil {
+		h.jsonError(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.PaymentsID.Valid || user.PaymentsID.String == "" {
+		h.jsonError(w, "User has no payments_id, the payment would not count in their balance", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	comment := strings.TrimSpace(req.StaffComment)
+	rawData, _ := json.Marshal(map[string]interface{}{
+		"entered_by": adminUsername,
+		"entered_at": time.Now().UTC().Format(time.RFC3339),
+		"comment":    comment,
+	})
+
+	var payment db.Payment
+	err = h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		var err error
+		payment, err = queries.CreatePayment(ctx, db.CreatePaymentParams{
+			UserID:         sql.NullInt64{Int64: user.ID, Valid: true},
+			Date:           date,
+			Amount:         amount,
+			Kind:           manualPaymentKind,
+			KindID:         strconv.FormatInt(time.Now().UnixNano(), 10),
+			LocalAccount:   "MANUAL",
+			RemoteAccount:  "",
+			Identification: user.PaymentsID.String,
+			RawData:        sql.NullString{String: string(rawData), Valid: true},
+			StaffComment:   sql.NullString{String: comment, Valid: comment != ""},
+		})
+		return err
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to create payment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) recorded manual payment #%d (%s Kč) for %s",
+			adminUsername, adminDBUser.Email, payment.ID, amount, user.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"payment_id":%d,"user_id":%d,"amount":"%s"}`,
+				adminDBUser.ID, payment.ID, user.ID, amount),
+			Valid: true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"payment": payment,
+	})
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fees"
+)
+
+// maxPauseReason limits the reason of a membership pause
+const maxPauseReason = 500
+
+// MembershipPause describes a member's pause (fee holiday) for the profile and the API
+type MembershipPause struct {
+	StartDate string `json:"start_date"`         // YYYY-MM-DD
+	EndDate   string `json:"end_date,omitempty"` // YYYY-MM-DD, empty = until cleared by an admin
+	Reason    string `json:"reason"`
+	Active    bool   `json:"active"` // false for a pause starting in the future
+}
+
+// MembershipPauseRequest is the body of POST /api/admin/users/{id}/pause
+type MembershipPauseRequest struct {
+	StartDate string `json:"start_date"` // YYYY-MM-DD
+	EndDate   string `json:"end_date"`   // YYYY-MM-DD, optional
+	Reason    string `json:"reason"`
+}
+
+// membershipPause returns the member's current or planned pause, nil if there is
+// none or it is over (create_monthly_fees ends expired pauses only once a month)
+func (h *Handler) membershipPause(ctx context.Context, userID int64, now time.Time) *MembershipPause {
+	pause, err := h.queries.GetOpenMembershipPause(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if fees.PauseExpired(pause, today) {
+		return nil
+	}
+
+	info := &MembershipPause{
+		StartDate: pause.StartDate.Format("2006-01-02"),
+		Reason:    pause.Reason,
+		Active:    fees.PauseCovers(pause, today),
+	}
+	if pause.EndDate.Valid {
+		info.EndDate = pause.EndDate.Time.Format("2006-01-02")
+	}
+	return info
+}
+
+// AdminSetMembershipPauseHandler pauses a membership: no fees are created for
+// the months starting within the pause. Replaces the member's previous pause.
+// POST /api/admin/users/{id}/pause
+// Body: {"start_date": "2026-02-01", "end_date": "2026-07-31", "reason": "rodičovská"}
+func (h *Handler) AdminSetMembershipPauseHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req MembershipPauseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		h.jsonError(w, "Invalid start_date (expected YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	var endDate sql.NullTime
+	if req.EndDate != "" {
+		end, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			h.jsonError(w, "Invalid end_date (expected YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		if end.Before(startDate) {
+			h.jsonError(w, "end_date must not be before start_date", http.StatusBadRequest)
+			return
+		}
+		endDate = sql.NullTime{Time: end, Valid: true}
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if utf8.RuneCountInString(reason) > maxPauseReason {
+		h.jsonError(w, fmt.Sprintf("Reason is too long (max %d characters)", maxPauseReason), http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	if err := h.queries.EndMembershipPauses(ctx, targetDBUser.ID); err != nil {
+		h.jsonError(w, "Failed to replace previous pause", http.StatusInternalServerError)
+		return
+	}
+	pause, err := h.queries.CreateMembershipPause(ctx, db.CreateMembershipPauseParams{
+		UserID:    targetDBUser.ID,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Reason:    reason,
+		CreatedBy: adminDBUser.Email,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to save pause", http.StatusInternalServerError)
+		return
+	}
+
+	until := "until cleared"
+	if endDate.Valid {
+		until = "until " + req.EndDate
+	}
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s paused membership of %s from %s %s", adminDBUser.Email, targetDBUser.Email, req.StartDate, until),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"pause_id":%d,"start_date":%q,"end_date":%q,"reason":%q}`,
+				adminDBUser.ID, targetDBUser.ID, pause.ID, req.StartDate, req.EndDate, reason),
+			Valid: true,
+		},
+	})
+
+	h.jsonSuccess(w, "Membership paused")
+}
+
+// AdminClearMembershipPauseHandler ends the member's pause, fees are created again
+// from the next month
+// DELETE /api/admin/users/{id}/pause
+func (h *Handler) AdminClearMembershipPauseHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := h.queries.GetOpenMembershipPause(ctx, targetDBUser.ID); err == sql.ErrNoRows {
+		h.jsonError(w, "Membership is not paused", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.queries.EndMembershipPauses(ctx, targetDBUser.ID); err != nil {
+		h.jsonError(w, "Failed to clear pause", http.StatusInternalServerError)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s cleared membership pause of %s", adminDBUser.Email, targetDBUser.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d}`, adminDBUser.ID, targetDBUser.ID),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Membership pause cleared")
+}
+
+// targetUserFromURL loads the member from the {id} URL parameter
+func (h *Handler) targetUserFromURL(w http.ResponseWriter, r *http.Request) (*db.User, bool) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid user ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	user, err := h.queries.GetUserByID(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "User not found", http.StatusNotFound)
+		return nil, false
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return &user, true
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/matrix"
+)
+
+// maxTerminationReason limits the reason a member gives for leaving
+const maxTerminationReason = 1000
+
+// MembershipTermination describes a member's scheduled termination for the profile and the API
+type MembershipTermination struct {
+	EndDate     string    `json:"end_date"` // YYYY-MM-DD, last day of membership
+	Reason      string    `json:"reason"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// MembershipTerminationRequest is the body of POST /api/me/termination
+type MembershipTerminationRequest struct {
+	EndMonth string `json:"end_month"` // YYYY-MM, membership ends on its last day
+	Reason   string `json:"reason"`
+}
+
+// membershipTermination returns the member's scheduled termination, nil if there is none
+func (h *Handler) membershipTermination(ctx context.Context, userID int64) *MembershipTermination {
+	termination, err := h.queries.GetScheduledTermination(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	return &MembershipTermination{
+		EndDate:     termination.EndDate.Format("2006-01-02"),
+		Reason:      termination.Reason,
+		RequestedAt: termination.CreatedAt,
+	}
+}
+
+// MeTerminationHandler returns the member's scheduled termination
+// GET /api/me/termination
+func (h *Handler) MeTerminationHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"termination": h.membershipTermination(r.Context(), dbUser.ID),
+	})
+}
+
+// MeRequestTerminationHandler cancels the member's membership as of the last day
+// of the given month: no fees are created for the months after it and
+// complete_terminations makes the member an exmember once the day has passed.
+// POST /api/me/termination
+// Body: {"end_month": "2026-06", "reason": "stěhuju se"}
+func (h *Handler) MeRequestTerminationHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	if dbUser.State != "accepted" && dbUser.State != "suspended" {
+		h.jsonError(w, "Only members can cancel their membership", http.StatusForbidden)
+		return
+	}
+
+	var req MembershipTerminationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	month, err := time.Parse("2006-01", req.EndMonth)
+	if err != nil {
+		h.jsonError(w, "Invalid end_month (expected YYYY-MM)", http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	if month.Before(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)) {
+		h.jsonError(w, "end_month must not be in the past", http.StatusBadRequest)
+		return
+	}
+	endDate := month.AddDate(0, 1, -1)
+
+	reason := strings.TrimSpace(req.Reason)
+	if utf8.RuneCountInString(reason) > maxTerminationReason {
+		h.jsonError(w, fmt.Sprintf("Reason is too long (max %d characters)", maxTerminationReason), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.queries.GetScheduledTermination(ctx, dbUser.ID); err == nil {
+		h.jsonError(w, "Membership termination is already scheduled", http.StatusConflict)
+		return
+	} else if err != sql.ErrNoRows {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	termination, err := h.queries.CreateMembershipTermination(ctx, db.CreateMembershipTerminationParams{
+		UserID:  dbUser.ID,
+		EndDate: endDate,
+		Reason:  reason,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to save membership termination", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "membership",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		Message:   fmt.Sprintf("%s cancelled their membership as of %s", dbUser.Email, endDate.Format("2006-01-02")),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"termination_id":%d,"end_date":%q,"reason":%q}`, termination.ID, endDate.Format("2006-01-02"), reason),
+			Valid:  true,
+		},
+	})
+
+	h.notifyTerminationRequested(ctx, dbUser, termination)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"termination": h.membershipTermination(ctx, dbUser.ID),
+	})
+}
+
+// notifyTerminationRequested tells admins about the termination by email to
+// ADMIN_NOTIFY_EMAIL and to the admin Matrix room, if set. Failures are only
+// logged, the termination is already saved.
+func (h *Handler) notifyTerminationRequested(ctx context.Context, user *db.User, termination db.MembershipTermination) {
+	if h.config.AdminNotifyEmail != "" {
+		if err := h.emailClient.SendTerminationRequested(ctx, h.config.AdminNotifyEmail, user, termination); err != nil {
+			log.Printf("Failed to email admins about membership termination of %s: %v", user.Email, err)
+		}
+	}
+
+	if h.config.MatrixHomeserverURL != "" && h.config.MatrixAccessToken != "" && h.config.MatrixAdminRoomID != "" {
+		text := fmt.Sprintf("%s ukončuje členství k %s", user.Email, termination.EndDate.Format("2. 1. 2006"))
+		if termination.Reason != "" {
+			text += ": " + termination.Reason
+		}
+		text += fmt.Sprintf("\n%s/admin/users/%d", h.config.BaseURL, user.ID)
+		room := matrix.NewClient(h.config.MatrixHomeserverURL, h.config.MatrixAccessToken, h.config.MatrixAdminRoomID)
+		if err := room.SendText(ctx, text); err != nil {
+			log.Printf("Failed to post membership termination of %s to Matrix: %v", user.Email, err)
+		}
+	}
+}
+
+// MeCancelTerminationHandler withdraws the member's scheduled termination
+// DELETE /api/me/termination
+func (h *Handler) MeCancelTerminationHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	rows, err := h.queries.CancelMembershipTermination(ctx, db.CancelMembershipTerminationParams{
+		CancelledBy: sql.NullString{String: dbUser.Email, Valid: true},
+		UserID:      dbUser.ID,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to cancel membership termination", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "No membership termination is scheduled", http.StatusNotFound)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "membership",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		Message:   fmt.Sprintf("%s withdrew their membership termination", dbUser.Email),
+	})
+
+	h.jsonSuccess(w, "Membership termination withdrawn")
+}
+
+// AdminCancelTerminationHandler withdraws a member's scheduled termination, e.g.
+// when the member changed their mind by email
+// DELETE /api/admin/users/{id}/termination
+func (h *Handler) AdminCancelTerminationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	rows, err := h.queries.CancelMembershipTermination(ctx, db.CancelMembershipTerminationParams{
+		CancelledBy: sql.NullString{String: adminDBUser.Email, Valid: true},
+		UserID:      targetDBUser.ID,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to cancel membership termination", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "No membership termination is scheduled", http.StatusNotFound)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s cancelled membership termination of %s", adminDBUser.Email, targetDBUser.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d}`, adminDBUser.ID, targetDBUser.ID),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Membership termination cancelled")
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/email"
+	"github.com/base48/member-portal/internal/milestone"
+)
+
+// NotificationPreference is an optional notification the member can switch off
+type NotificationPreference struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Enabled bool   `json:"enabled"`
+}
+
+// notificationPreferences lists optional notifications, all enabled by default
+var notificationPreferences = []NotificationPreference{
+	{ID: milestone.NotificationEmail, Title: "Gratulační email k výročí členství a 100. platbě"},
+	{ID: milestone.NotificationAnnounce, Title: "Zmínka o výročí a 100. platbě v komunitní Matrix místnosti"},
+	{ID: email.NotificationPaymentReceived, Title: "Potvrzení přijaté platby emailem"},
+	{ID: email.NotificationMissedPayment, Title: "Připomínka, když nedorazí pravidelná platba (trvalý příkaz)"},
+	{ID: email.NotificationPaymentStatement, Title: "Roční přehled plateb emailem (v lednu)"},
+}
+
+// NotificationPreferenceRequest is the body of POST /api/me/notifications
+type NotificationPreferenceRequest struct {
+	Notification string `json:"notification"`
+	Enabled      bool   `json:"enabled"`
+}
+
+// userNotificationPreferences returns all optional notifications with the user's settings applied
+func (h *Handler) userNotificationPreferences(ctx context.Context, userID int64) ([]NotificationPreference, error) {
+	settings, err := h.queries.ListUserNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	disabled := make(map[string]bool)
+	for _, s := range settings {
+		disabled[s.Notification] = !s.Enabled
+	}
+
+	prefs := make([]NotificationPreference, 0, len(notificationPreferences))
+	for _, pref := range notificationPreferences {
+		pref.Enabled = !disabled[pref.ID]
+		prefs = append(prefs, pref)
+	}
+	return prefs, nil
+}
+
+// MeNotificationsHandler lists optional notifications with the member's settings
+// GET /api/me/notifications
+func (h *Handler) MeNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.userNotificationPreferences(r.Context(), dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"notifications": prefs,
+	})
+}
+
+// MeNotificationSettingsHandler switches an optional notification on or off for the member
+// POST /api/me/notifications
+// Body: {"notification": "milestone_announce", "enabled": false}
+func (h *Handler) MeNotificationSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req NotificationPreferenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	known := false
+	for _, pref := range notificationPreferences {
+		if pref.ID == req.Notification {
+			known = true
+			break
+		}
+	}
+	if !known {
+		h.jsonError(w, fmt.Sprintf("Unknown notification: %s", req.Notification), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.queries.SetUserNotificationPreference(r.Context(), db.SetUserNotificationPreferenceParams{
+		UserID:       dbUser.ID,
+		Notification: req.Notification,
+		Enabled:      req.Enabled,
+	}); err != nil {
+		h.jsonError(w, "Failed to save notification settings", http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Notification settings saved")
+}
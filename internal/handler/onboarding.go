@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// OnboardingTask is a step of the new member checklist
+type OnboardingTask struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Done        bool       `json:"done"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CompletedBy string     `json:"completed_by,omitempty"`
+}
+
+// Onboarding is the member's checklist with the overall state
+type Onboarding struct {
+	Tasks       []OnboardingTask `json:"tasks"`
+	Done        int              `json:"done"`
+	Onboarded   bool             `json:"onboarded"`
+	OnboardedAt *time.Time       `json:"onboarded_at,omitempty"` // when the last task was ticked off
+}
+
+// onboardingTasks lists the checklist in display order; the IDs are stored in user_tasks
+var onboardingTasks = []OnboardingTask{
+	{ID: "agreement", Title: "Podepsaná členská smlouva"},
+	{ID: "safety_briefing", Title: "Školení bezpečnosti"},
+	{ID: "chip", Title: "Vydaný čip ke dveřím"},
+	{ID: "mailing_list", Title: "Přidání do e-mailové konference"},
+}
+
+// OnboardingTaskRequest is the body of POST /api/admin/users/{id}/onboarding
+type OnboardingTaskRequest struct {
+	Task string `json:"task"`
+	Done bool   `json:"done"`
+}
+
+// userOnboarding returns the checklist with the user's completed tasks applied
+func (h *Handler) userOnboarding(ctx context.Context, userID int64) (*Onboarding, error) {
+	rows, err := h.queries.ListUserTasks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	completed := make(map[string]db.UserTask)
+	for _, row := range rows {
+		completed[row.Task] = row
+	}
+
+	onboarding := &Onboarding{Tasks: make([]OnboardingTask, 0, len(onboardingTasks))}
+	for _, task := range onboardingTasks {
+		if row, ok := completed[task.ID]; ok {
+			completedAt := row.CompletedAt
+			task.Done = true
+			task.CompletedAt = &completedAt
+			task.CompletedBy = row.CompletedBy.String
+			onboarding.Done++
+			if onboarding.OnboardedAt == nil || completedAt.After(*onboarding.OnboardedAt) {
+				onboarding.OnboardedAt = &completedAt
+			}
+		}
+		onboarding.Tasks = append(onboarding.Tasks, task)
+	}
+	onboarding.Onboarded = onboarding.Done == len(onboardingTasks)
+	if !onboarding.Onboarded {
+		onboarding.OnboardedAt = nil
+	}
+	return onboarding, nil
+}
+
+// MeOnboardingHandler returns the member's onboarding checklist
+// GET /api/me/onboarding
+func (h *Handler) MeOnboardingHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	onboarding, err := h.userOnboarding(r.Context(), dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"onboarding": onboarding,
+	})
+}
+
+// AdminSetOnboardingTaskHandler ticks an onboarding task of a member off or reopens it
+// POST /api/admin/users/{id}/onboarding
+// Body: {"task": "chip", "done": true}
+func (h *Handler) AdminSetOnboardingTaskHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req OnboardingTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	known := false
+	for _, task := range onboardingTasks {
+		if task.ID == req.Task {
+			known = true
+			break
+		}
+	}
+	if !known {
+		h.jsonError(w, fmt.Sprintf("Unknown task: %s", req.Task), http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	var err error
+	if req.Done {
+		err = h.queries.CompleteUserTask(ctx, db.CompleteUserTaskParams{
+			UserID:      targetDBUser.ID,
+			Task:        req.Task,
+			CompletedBy: sql.NullString{String: adminDBUser.Email, Valid: true},
+		})
+	} else {
+		err = h.queries.ReopenUserTask(ctx, db.ReopenUserTaskParams{
+			UserID: targetDBUser.ID,
+			Task:   req.Task,
+		})
+	}
+	if err != nil {
+		h.jsonError(w, "Failed to update onboarding task", http.StatusInternalServerError)
+		return
+	}
+
+	onboarding, err := h.userOnboarding(ctx, targetDBUser.ID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	action := "reopened"
+	if req.Done {
+		action = "completed"
+	}
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s %s onboarding task %s of %s", adminDBUser.Email, action, req.Task, targetDBUser.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"task":%q,"done":%t,"onboarded":%t}`,
+				adminDBUser.ID, targetDBUser.ID, req.Task, req.Done, onboarding.Onboarded),
+			Valid: true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"onboarding": onboarding,
+	})
+}
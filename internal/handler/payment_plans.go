@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fees"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// maxPlanNote limits the note of a payment plan
+const maxPlanNote = 500
+
+// PaymentPlan describes a member's payment plan and how they keep to it, for the
+// profile and the API
+type PaymentPlan struct {
+	ID           int64        `json:"id"`
+	TotalDebt    money.Amount `json:"total_debt"`
+	Installment  money.Amount `json:"installment"`
+	StartDate    string       `json:"start_date"` // YYYY-MM-DD, first installment due
+	Note         string       `json:"note"`
+	Installments int          `json:"installments"`     // number of installments
+	Due          int          `json:"installments_due"` // installments due by now
+	NextDueDate  string       `json:"next_due_date"`    // YYYY-MM-DD, empty after the last one
+	Remaining    money.Amount `json:"remaining"`        // plan debt not asked for yet
+	AmountDue    money.Amount `json:"amount_due"`       // to pay this month: installment, fees, arrears
+	OnPlan       bool         `json:"on_plan"`          // false when an installment is missing
+}
+
+// PaymentPlanRequest is the body of POST /api/admin/users/{id}/payment-plan
+type PaymentPlanRequest struct {
+	TotalDebt   money.Amount `json:"total_debt"` // optional, the current debt by default
+	Installment money.Amount `json:"installment"`
+	StartDate   string       `json:"start_date"` // YYYY-MM-DD
+	Note        string       `json:"note"`
+}
+
+// paymentPlan returns the member's active payment plan, nil if there is none.
+// balance is the member's current balance.
+func (h *Handler) paymentPlan(ctx context.Context, userID int64, balance money.Amount, now time.Time) *PaymentPlan {
+	plan, err := h.queries.GetActivePaymentPlan(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	debt := -balance
+
+	info := &PaymentPlan{
+		ID:           plan.ID,
+		TotalDebt:    plan.TotalDebt,
+		Installment:  plan.Installment,
+		StartDate:    plan.StartDate.Format("2006-01-02"),
+		Note:         plan.Note,
+		Installments: fees.PlanInstallments(plan),
+		Due:          fees.PlanInstallmentsDue(plan, today),
+		AmountDue:    fees.PlanAmountDue(plan, debt, today),
+		OnPlan:       fees.OnPlan(plan, debt, today),
+	}
+	info.Remaining = fees.PlanRemaining(plan, info.Due)
+	if info.Due < info.Installments {
+		info.NextDueDate = fees.PlanDueDate(plan, info.Due).Format("2006-01-02")
+	}
+	return info
+}
+
+// AdminSetPaymentPlanHandler agrees a payment plan with a member in debt, replacing
+// the previous one. Reminders then ask for the installment instead of the whole
+// debt and suspend_debtors skips the member while they keep to the plan.
+// POST /api/admin/users/{id}/payment-plan
+// Body: {"total_debt": 3500, "installment": 1000, "start_date": "2026-11-15", "note": "..."}
+func (h *Handler) AdminSetPaymentPlanHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req PaymentPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		h.jsonError(w, "Invalid start_date (expected YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	note := strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(note) > maxPlanNote {
+		h.jsonError(w, fmt.Sprintf("Note is too long (max %d characters)", maxPlanNote), http.StatusBadRequest)
+		return
+	}
+
+	totalDebt := req.TotalDebt
+	if totalDebt == 0 {
+		balance, err := h.queries.GetUserBalance(ctx, db.GetUserBalanceParams{
+			UserID:   sql.NullInt64{Int64: targetDBUser.ID, Valid: true},
+			UserID_2: targetDBUser.ID,
+			UserID_3: sql.NullInt64{Int64: targetDBUser.ID, Valid: true},
+			UserID_4: targetDBUser.ID,
+			UserID_5: targetDBUser.ID,
+		})
+		if err != nil {
+			h.jsonError(w, "Failed to calculate balance", http.StatusInternalServerError)
+			return
+		}
+		totalDebt = -money.Amount(balance)
+	}
+	if totalDebt <= 0 {
+		h.jsonError(w, "Member is not in debt", http.StatusBadRequest)
+		return
+	}
+	if req.Installment <= 0 || req.Installment > totalDebt {
+		h.jsonError(w, "installment must be positive and at most total_debt", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	if err := qtx.EndPaymentPlans(ctx, targetDBUser.ID); err != nil {
+		h.jsonError(w, "Failed to replace previous payment plan", http.StatusInternalServerError)
+		return
+	}
+	plan, err := qtx.CreatePaymentPlan(ctx, db.CreatePaymentPlanParams{
+		UserID:      targetDBUser.ID,
+		TotalDebt:   totalDebt,
+		Installment: req.Installment,
+		StartDate:   startDate,
+		Note:        note,
+		CreatedBy:   adminDBUser.Email,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to save payment plan", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		h.jsonError(w, "Failed to save payment plan", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s agreed payment plan with %s: %s Kč in installments of %s Kč from %s",
+			adminDBUser.Email, targetDBUser.Email, totalDebt, req.Installment, req.StartDate),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"plan_id":%d,"total_debt":%q,"installment":%q,"start_date":%q}`,
+				adminDBUser.ID, targetDBUser.ID, plan.ID, totalDebt, req.Installment, req.StartDate),
+			Valid: true,
+		},
+	})
+
+	h.jsonSuccess(w, "Payment plan saved")
+}
+
+// AdminEndPaymentPlanHandler cancels the member's payment plan, the full debt
+// counts again for reminders and suspension
+// DELETE /api/admin/users/{id}/payment-plan
+func (h *Handler) AdminEndPaymentPlanHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	plan, err := h.queries.GetActivePaymentPlan(ctx, targetDBUser.ID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Member has no payment plan", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.queries.EndPaymentPlans(ctx, targetDBUser.ID); err != nil {
+		h.jsonError(w, "Failed to end payment plan", http.StatusInternalServerError)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s cancelled payment plan of %s", adminDBUser.Email, targetDBUser.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"plan_id":%d}`, adminDBUser.ID, targetDBUser.ID, plan.ID),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Payment plan cancelled")
+}
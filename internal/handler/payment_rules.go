@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/paymentrule"
+)
+
+// PaymentMatchRuleRequest is the request body for creating and updating a payment match rule.
+// Empty conditions match anything; at least one condition is required.
+type PaymentMatchRuleRequest struct {
+	Name           string   `json:"name"`
+	Priority       *int64   `json:"priority"` // lower is evaluated first, default 100
+	RemoteAccount  string   `json:"remote_account"`
+	MessagePattern string   `json:"message_pattern"` // regexp on the message for the recipient
+	SpecificSymbol string   `json:"specific_symbol"`
+	AmountMin      *float64 `json:"amount_min"`
+	AmountMax      *float64 `json:"amount_max"`
+	UserID         *int64   `json:"user_id"`
+	ProjectID      *int64   `json:"project_id"`
+	Active         *bool    `json:"active"` // default true
+}
+
+// toPaymentMatchRule validates the request and converts it to a rule row
+func (h *Handler) toPaymentMatchRule(ctx context.Context, req PaymentMatchRuleRequest) (db.PaymentMatchRule, error) {
+	rule := db.PaymentMatchRule{
+		Name:           strings.TrimSpace(req.Name),
+		Priority:       paymentrule.DefaultPriority,
+		RemoteAccount:  strings.TrimSpace(req.RemoteAccount),
+		MessagePattern: strings.TrimSpace(req.MessagePattern),
+		SpecificSymbol: strings.TrimSpace(req.SpecificSymbol),
+		Active:         true,
+	}
+	if req.Priority != nil {
+		rule.Priority = *req.Priority
+	}
+	if req.AmountMin != nil {
+		rule.AmountMin = sql.NullFloat64{Float64: *req.AmountMin, Valid: true}
+	}
+	if req.AmountMax != nil {
+		rule.AmountMax = sql.NullFloat64{Float64: *req.AmountMax, Valid: true}
+	}
+	if req.UserID != nil {
+		rule.UserID = sql.NullInt64{Int64: *req.UserID, Valid: true}
+	}
+	if req.ProjectID != nil {
+		rule.ProjectID = sql.NullInt64{Int64: *req.ProjectID, Valid: true}
+	}
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+
+	if err := paymentrule.Validate(rule); err != nil {
+		return rule, err
+	}
+
+	// A payment counts in the member's balance only with their payments_id as VS
+	if rule.UserID.Valid {
+		user, err := h.queries.GetUserByID(ctx, rule.UserID.Int64)
+		if err != nil {
+			return rule, fmt.Errorf("user not found")
+		}
+		if !user.PaymentsID.Valid {
+			return rule, fmt.Errorf("user has no payments_id")
+		}
+	}
+	if rule.ProjectID.Valid {
+		if _, err := h.queries.GetProject(ctx, rule.ProjectID.Int64); err != nil {
+			return rule, fmt.Errorf("project not found")
+		}
+	}
+	return rule, nil
+}
+
+// rememberPaymentAccount creates (or retargets) the rule matching only the sender account
+func (h *Handler) rememberPaymentAccount(ctx context.Context, remoteAccount string, userID int64, createdBy string) error {
+	existing, err := h.queries.GetAccountPaymentMatchRule(ctx, remoteAccount)
+	if err == sql.ErrNoRows {
+		_, err = h.queries.CreatePaymentMatchRule(ctx, db.CreatePaymentMatchRuleParams{
+			Name:          "Zapamatovaný účet",
+			Priority:      paymentrule.DefaultPriority,
+			RemoteAccount: remoteAccount,
+			UserID:        sql.NullInt64{Int64: userID, Valid: true},
+			Active:        true,
+			CreatedBy:     createdBy,
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = h.queries.UpdatePaymentMatchRule(ctx, db.UpdatePaymentMatchRuleParams{
+		Name:          existing.Name,
+		Priority:      existing.Priority,
+		RemoteAccount: existing.RemoteAccount,
+		UserID:        sql.NullInt64{Int64: userID, Valid: true},
+		Active:        true,
+		ID:            existing.ID,
+	})
+	return err
+}
+
+// logPaymentMatchRule writes a rule change to the system log
+func (h *Handler) logPaymentMatchRule(ctx context.Context, action string, id int64, rule db.PaymentMatchRule) {
+	adminDBUser := DBUserFrom(ctx)
+
+	target := fmt.Sprintf("user %d", rule.UserID.Int64)
+	if rule.ProjectID.Valid {
+		target = fmt.Sprintf("project %d", rule.ProjectID.Int64)
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s %s payment match rule #%d (%s → %s)", adminDBUser.Email, action, id, paymentrule.Describe(rule), target),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"admin_user_id":%d,"rule_id":%d,"action":%q}`, adminDBUser.ID, id, action), Valid: true},
+	})
+}
+
+// AdminPaymentMatchRulesHandler lists payment match rules in evaluation order
+// GET /api/admin/payments/rules
+func (h *Handler) AdminPaymentMatchRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.queries.ListPaymentMatchRules(r.Context())
+	if err != nil {
+		h.jsonError(w, "Failed to fetch match rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"rules":   rules,
+	})
+}
+
+// AdminCreatePaymentMatchRuleHandler creates a payment match rule
+// POST /api/admin/payments/rules
+func (h *Handler) AdminCreatePaymentMatchRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req PaymentMatchRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.toPaymentMatchRule(ctx, req)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	createdBy := adminDBUser.Email
+	if adminDBUser.Username.Valid {
+		createdBy = adminDBUser.Username.String
+	}
+
+	created, err := h.queries.CreatePaymentMatchRule(ctx, db.CreatePaymentMatchRuleParams{
+		Name:           rule.Name,
+		Priority:       rule.Priority,
+		RemoteAccount:  rule.RemoteAccount,
+		MessagePattern: rule.MessagePattern,
+		SpecificSymbol: rule.SpecificSymbol,
+		AmountMin:      rule.AmountMin,
+		AmountMax:      rule.AmountMax,
+		UserID:         rule.UserID,
+		ProjectID:      rule.ProjectID,
+		Active:         rule.Active,
+		CreatedBy:      createdBy,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to create match rule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.logPaymentMatchRule(ctx, "created", created.ID, created)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"rule":    created,
+	})
+}
+
+// AdminUpdatePaymentMatchRuleHandler replaces a payment match rule (the whole rule is sent)
+// POST /api/admin/payments/rules/{id}
+func (h *Handler) AdminUpdatePaymentMatchRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	var req PaymentMatchRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.toPaymentMatchRule(ctx, req)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.queries.UpdatePaymentMatchRule(ctx, db.UpdatePaymentMatchRuleParams{
+		Name:           rule.Name,
+		Priority:       rule.Priority,
+		RemoteAccount:  rule.RemoteAccount,
+		MessagePattern: rule.MessagePattern,
+		SpecificSymbol: rule.SpecificSymbol,
+		AmountMin:      rule.AmountMin,
+		AmountMax:      rule.AmountMax,
+		UserID:         rule.UserID,
+		ProjectID:      rule.ProjectID,
+		Active:         rule.Active,
+		ID:             id,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to update match rule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if updated == 0 {
+		h.jsonError(w, "Match rule not found", http.StatusNotFound)
+		return
+	}
+
+	h.logPaymentMatchRule(ctx, "updated", id, rule)
+	h.jsonSuccess(w, "Match rule updated")
+}
+
+// AdminDeletePaymentMatchRuleHandler deletes a payment match rule; payments assigned
+// by it stay assigned
+// DELETE /api/admin/payments/rules/{id}
+func (h *Handler) AdminDeletePaymentMatchRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.queries.GetPaymentMatchRule(ctx, id)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Match rule not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.queries.DeletePaymentMatchRule(ctx, id); err != nil {
+		h.jsonError(w, "Failed to delete match rule", http.StatusInternalServerError)
+		return
+	}
+
+	h.logPaymentMatchRule(ctx, "deleted", id, rule)
+	h.jsonSuccess(w, "Match rule deleted")
+}
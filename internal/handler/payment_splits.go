@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+)
+
+// PaymentSplitAllocation is one part of a split payment, for a user or a project
+type PaymentSplitAllocation struct {
+	UserID    *int64  `json:"user_id"`
+	ProjectID *int64  `json:"project_id"`
+	Amount    float64 `json:"amount"`
+	Note      string  `json:"note"`
+	// Classification is "fee" or "donation"; defaults to fee for users and
+	// donation for projects, a member's part can also be a donation
+	Classification string `json:"classification"`
+}
+
+// classification returns the allocation's classification with the default filled in
+func (a PaymentSplitAllocation) classification() string {
+	if a.Classification != "" {
+		return a.Classification
+	}
+	if a.ProjectID != nil {
+		return classificationDonation
+	}
+	return classificationFee
+}
+
+// SplitPaymentRequest is the request body for POST /api/admin/payments/{id}/splits
+type SplitPaymentRequest struct {
+	Allocations []PaymentSplitAllocation `json:"allocations"`
+}
+
+// validatePaymentSplit checks that the allocations cover the whole payment and point
+// to existing users and projects
+func (h *Handler) validatePaymentSplit(ctx context.Context, payment db.Payment, allocations []PaymentSplitAllocation) error {
+	if len(allocations) < 2 {
+		return fmt.Errorf("at least two allocations are required (use assign for a single target)")
+	}
+
+	var total money.Amount
+	for i, a := range allocations {
+		if (a.UserID == nil) == (a.ProjectID == nil) {
+			return fmt.Errorf("allocation %d: exactly one of user_id or project_id is required", i+1)
+		}
+		if money.FromFloat(a.Amount) <= 0 {
+			return fmt.Errorf("allocation %d: amount must be positive", i+1)
+		}
+		total += money.FromFloat(a.Amount)
+
+		switch a.classification() {
+		case classificationFee:
+			if a.ProjectID != nil {
+				return fmt.Errorf("allocation %d: a project allocation cannot be a membership fee", i+1)
+			}
+		case classificationDonation:
+		default:
+			return fmt.Errorf("allocation %d: classification must be fee or donation", i+1)
+		}
+
+		if a.UserID != nil {
+			if _, err := h.queries.GetUserByID(ctx, *a.UserID); err != nil {
+				return fmt.Errorf("allocation %d: user not found", i+1)
+			}
+		} else if _, err := h.queries.GetProject(ctx, *a.ProjectID); err != nil {
+			return fmt.Errorf("allocation %d: project not found", i+1)
+		}
+	}
+
+	if total != payment.Amount {
+		return fmt.Errorf("allocations sum to %s, payment amount is %s", total, payment.Amount)
+	}
+	return nil
+}
+
+// AdminPaymentSplitsHandler lists the allocations of a payment (empty if not split)
+// GET /api/admin/payments/{id}/splits
+func (h *Handler) AdminPaymentSplitsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	payment, err := h.queries.GetPayment(ctx, id)
+	if err != nil {
+		h.jsonError(w, "Payment not found", http.StatusNotFound)
+		return
+	}
+
+	splits, err := h.queries.ListPaymentSplits(ctx, id)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch payment splits", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"payment": payment,
+		"splits":  splits,
+	})
+}
+
+// AdminSplitPaymentHandler divides a payment into allocations (replacing earlier ones).
+// The allocations must add up to the payment amount; balances then count the
+// allocations instead of the payment itself.
+// POST /api/admin/payments/{id}/splits
+func (h *Handler) AdminSplitPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SplitPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payment, err := h.queries.GetPayment(ctx, id)
+	if err != nil {
+		h.jsonError(w, "Payment not found", http.StatusNotFound)
+		return
+	}
+	if payment.DismissedAt != nil {
+		h.jsonError(w, "Payment is ignored, restore it first", http.StatusConflict)
+		return
+	}
+	if payment.ReversalOf.Valid {
+		h.jsonError(w, "A reversal cannot be split", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validatePaymentSplit(ctx, payment, req.Allocations); err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	targets := make([]string, 0, len(req.Allocations))
+	err = h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		if _, err := queries.DeletePaymentSplits(ctx, id); err != nil {
+			return err
+		}
+		for _, a := range req.Allocations {
+			params := db.CreatePaymentSplitParams{
+				PaymentID:      id,
+				Amount:         money.FromFloat(a.Amount),
+				Note:           strings.TrimSpace(a.Note),
+				CreatedBy:      adminUsername,
+				Classification: a.classification(),
+			}
+			var target string
+			if a.UserID != nil {
+				params.UserID = sql.NullInt64{Int64: *a.UserID, Valid: true}
+				target = fmt.Sprintf("user %d", *a.UserID)
+			} else {
+				params.ProjectID = sql.NullInt64{Int64: *a.ProjectID, Valid: true}
+				target = fmt.Sprintf("project %d", *a.ProjectID)
+			}
+			if _, err := queries.CreatePaymentSplit(ctx, params); err != nil {
+				return err
+			}
+			targets = append(targets, fmt.Sprintf("%s → %s", params.Amount, target))
+		}
+		return nil
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to split payment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s split payment #%d (%s CZK): %s", adminDBUser.Email, id, payment.Amount, strings.Join(targets, ", ")),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"admin_user_id":%d,"payment_id":%d,"allocations":%d}`, adminDBUser.ID, id, len(req.Allocations)), Valid: true},
+	})
+
+	h.jsonSuccess(w, "Payment split")
+}
+
+// AdminDeletePaymentSplitsHandler removes the allocations, the payment counts as a
+// whole again
+// DELETE /api/admin/payments/{id}/splits
+func (h *Handler) AdminDeletePaymentSplitsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	var deleted int64
+	err = h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		var err error
+		deleted, err = queries.DeletePaymentSplits(ctx, id)
+		return err
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to remove payment split: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		h.jsonError(w, "Payment is not split", http.StatusNotFound)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s removed split of payment #%d", adminDBUser.Email, id),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"admin_user_id":%d,"payment_id":%d}`, adminDBUser.ID, id), Valid: true},
+	})
+
+	h.jsonSuccess(w, "Payment split removed")
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/money"
+)
+
+// paymentHistoryRow is a line of the payment history export
+type paymentHistoryRow struct {
+	date          time.Time
+	kind          string // platba, vrácení, příspěvek
+	amount        money.Amount
+	vs            string
+	source        string // payments.kind, empty for fees
+	remoteAccount string
+}
+
+// ProfilePaymentsCSVHandler exports the member's payments and fees as CSV for personal
+// bookkeeping. The file is for Czech Excel: UTF-8 with BOM, semicolon separated,
+// decimal comma; fees are negative.
+// GET /profile/payments.csv
+func (h *Handler) ProfilePaymentsCSVHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser := DBUserFrom(r.Context())
+	if dbUser == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+
+	payments, err := h.queries.ListPaymentsByUser(ctx, sql.NullInt64{Int64: dbUser.ID, Valid: true})
+	if err != nil {
+		http.Error(w, "Failed to fetch payments", http.StatusInternalServerError)
+		return
+	}
+	fees, err := h.queries.ListFeesByUser(ctx, dbUser.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch fees", http.StatusInternalServerError)
+		return
+	}
+
+	rows := make([]paymentHistoryRow, 0, len(payments)+len(fees))
+	for _, p := range payments {
+		kind := "platba"
+		if p.ReversalOf.Valid {
+			kind = "vrácení"
+		}
+		rows = append(rows, paymentHistoryRow{
+			date:          p.Date,
+			kind:          kind,
+			amount:        p.Amount,
+			vs:            p.Identification,
+			source:        p.Kind,
+			remoteAccount: p.RemoteAccount,
+		})
+	}
+	for _, fee := range fees {
+		rows = append(rows, paymentHistoryRow{
+			date:   fee.PeriodStart,
+			kind:   "příspěvek",
+			amount: -fee.Amount,
+		})
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].date.After(rows[j].date) })
+
+	filename := fmt.Sprintf("base48-platby-%s.csv", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	// Excel detects UTF-8 only with the byte order mark
+	w.Write([]byte("\ufeff"))
+
+	out := csv.NewWriter(w)
+	out.Comma = ';'
+	out.Write([]string{"Datum", "Druh", "Částka (Kč)", "VS", "Zdroj", "Protiúčet"})
+	for _, row := range rows {
+		out.Write([]string{
+			row.date.Format("2006-01-02"),
+			row.kind,
+			strings.Replace(row.amount.String(), ".", ",", 1),
+			row.vs,
+			row.source,
+			row.remoteAccount,
+		})
+	}
+	out.Flush()
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// maxProjectRole limits the free-text role of a project member
+const maxProjectRole = 100
+
+// ProjectMembershipRequest is the body of POST /api/me/projects/{id}/members
+type ProjectMembershipRequest struct {
+	Role string `json:"role"`
+}
+
+// ProjectMemberResponse is a member involved in a project (admin view)
+type ProjectMemberResponse struct {
+	UserID   int64  `json:"user_id"`
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	Role     string `json:"role"`
+	JoinedAt string `json:"joined_at"` // YYYY-MM-DD
+}
+
+// MeProjectsHandler lists all projects with the member's involvement
+// GET /api/me/projects
+func (h *Handler) MeProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	projects, err := h.queries.ListMemberProjects(r.Context(), dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch projects", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"projects": projects,
+	})
+}
+
+// MeJoinProjectHandler adds the member to a project or changes their role in it
+// POST /api/me/projects/{id}/members
+// Body: {"role": "elektronika"}
+func (h *Handler) MeJoinProjectHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.queries.GetProject(ctx, projectID); err != nil {
+		h.jsonError(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	var req ProjectMembershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	role := strings.TrimSpace(req.Role)
+	if utf8.RuneCountInString(role) > maxProjectRole {
+		h.jsonError(w, fmt.Sprintf("Role is too long (max %d characters)", maxProjectRole), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.queries.UpsertProjectMember(ctx, db.UpsertProjectMemberParams{
+		ProjectID: projectID,
+		UserID:    dbUser.ID,
+		Role:      role,
+	}); err != nil {
+		h.jsonError(w, "Failed to join project", http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Joined project")
+}
+
+// MeLeaveProjectHandler removes the member from a project
+// DELETE /api/me/projects/{id}/members
+func (h *Handler) MeLeaveProjectHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.queries.DeleteProjectMember(r.Context(), db.DeleteProjectMemberParams{
+		ProjectID: projectID,
+		UserID:    dbUser.ID,
+	}); err != nil {
+		h.jsonError(w, "Failed to leave project", http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Left project")
+}
+
+// AdminProjectMembersHandler lists members involved in a project
+// GET /api/admin/projects/members?project_id=
+func (h *Handler) AdminProjectMembersHandler(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(r.URL.Query().Get("project_id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid project_id", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.queries.ListProjectMembers(r.Context(), projectID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch project members", http.StatusInternalServerError)
+		return
+	}
+
+	members := make([]ProjectMemberResponse, len(rows))
+	for i, row := range rows {
+		name := row.Realname.String
+		if name == "" {
+			name = row.Username.String
+		}
+		members[i] = ProjectMemberResponse{
+			UserID:   row.UserID,
+			Email:    row.Email,
+			Name:     name,
+			State:    row.State,
+			Role:     row.Role,
+			JoinedAt: row.CreatedAt.Format("2006-01-02"),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"members": members,
+	})
+}
@@ -0,0 +1,463 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/base48/member-portal/internal/qrpay"
+)
+
+// maxWallNickname limits the displayed name on the contributor wall
+const maxWallNickname = 40
+
+// Moderation states of a wall entry
+const (
+	WallStatePending  = "pending"
+	WallStateApproved = "approved"
+	WallStateHidden   = "hidden"
+)
+
+// WallContributor is a contributor shown on the public wall; the exact amount is not public
+type WallContributor struct {
+	Nickname string `json:"nickname"`
+	Bracket  string `json:"bracket"`
+}
+
+// WallEntryRequest is the body of POST /api/me/projects/{id}/wall
+type WallEntryRequest struct {
+	Nickname string `json:"nickname"`
+}
+
+// ModerateWallRequest is the body of POST /api/admin/projects/wall
+type ModerateWallRequest struct {
+	ProjectID int64  `json:"project_id"`
+	UserID    int64  `json:"user_id"`
+	State     string `json:"state"`    // approved or hidden
+	Nickname  string `json:"nickname"` // optional correction of the displayed name
+}
+
+// contributionBracket hides the exact contribution on the public wall
+func contributionBracket(total float64) string {
+	switch {
+	case total >= 5000:
+		return "5 000 Kč a více"
+	case total >= 2000:
+		return "2 000 – 4 999 Kč"
+	case total >= 500:
+		return "500 – 1 999 Kč"
+	default:
+		return "do 500 Kč"
+	}
+}
+
+// publicProject returns the project if it has a public page
+func (h *Handler) publicProject(ctx context.Context, idParam string) (db.Project, bool) {
+	projectID, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		return db.Project{}, false
+	}
+	project, err := h.queries.GetProject(ctx, projectID)
+	if err != nil || !project.Public {
+		return db.Project{}, false
+	}
+	return project, true
+}
+
+// projectTotal returns the amount raised by the project
+func (h *Handler) projectTotal(ctx context.Context, projectID int64) float64 {
+	income, err := h.queries.GetProjectIncome(ctx, sql.NullInt64{Int64: projectID, Valid: true})
+	if err != nil {
+		return 0
+	}
+	return money.Amount(income).Float64()
+}
+
+// publicWall returns approved contributors whose contribution was found, largest first
+func publicWall(rows []db.ListProjectWallRow) []WallContributor {
+	wall := []WallContributor{}
+	for _, row := range rows {
+		if row.State != WallStateApproved || row.Total <= 0 {
+			continue
+		}
+		wall = append(wall, WallContributor{Nickname: row.Nickname, Bracket: contributionBracket(row.Total)})
+	}
+	return wall
+}
+
+// validNickname trims the nickname and checks its length
+func validNickname(nickname string) (string, bool) {
+	nickname = strings.TrimSpace(nickname)
+	n := utf8.RuneCountInString(nickname)
+	return nickname, n > 0 && n <= maxWallNickname
+}
+
+// ProjectPageHandler shows the public page of a fundraising project with the contributor wall;
+// logged in members can join the wall there
+// GET /projects/{id}
+func (h *Handler) ProjectPageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	project, ok := h.publicProject(ctx, chi.URLParam(r, "id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	rows, err := h.queries.ListProjectWall(ctx, project.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	dbUser := DBUserFrom(ctx)
+	total := h.projectTotal(ctx, project.ID)
+	data := map[string]interface{}{
+		"Title":    project.Name,
+		"User":     h.auth.GetUser(r),
+		"DBUser":   dbUser,
+		"Project":  project,
+		"Total":    total,
+		"Goal":     project.Goal.Float64(),
+		"Progress": fundingProgress(total, project.Goal),
+		"DaysLeft": daysLeft(project.Deadline, time.Now()),
+		"Wall":     publicWall(rows),
+		// Crypto donations need a member account to pair the payment with
+		"BTCPayDonations": h.btcpay != nil && project.Btcpay && dbUser != nil,
+		"BTCPayPaid":      r.URL.Query().Get("btcpay") == "success",
+	}
+
+	if dbUser != nil {
+		for _, row := range rows {
+			if row.UserID == dbUser.ID {
+				data["MyEntry"] = row
+				data["MyBracket"] = contributionBracket(row.Total)
+			}
+		}
+	}
+
+	// Donation QR code on the primary VS; supporters banking abroad switch the format
+	if h.qrpayService.IsConfigured() && project.PaymentsID.Valid && project.PaymentsID.String != "" {
+		format := qrpay.FormatSPAYD
+		for _, f := range qrFormats {
+			if f.ID == r.URL.Query().Get("qr") {
+				format = f.ID
+			}
+		}
+		req := qrpay.ImageRequest{Size: 200, Format: format}
+		data["DonationQRURL"] = fmt.Sprintf("/projects/%d/qr.png?%s", project.ID, req.Query().Encode())
+		data["QRFormat"] = format
+		data["QRFormats"] = qrFormats
+	}
+
+	h.render(w, "project_public.html", data)
+}
+
+// ProjectQRImageHandler renders the donation QR code of a public project on its
+// primary VS; the amount is optional, supporters usually enter their own
+// GET /projects/{id}/qr.png?amount=&size=&format=
+func (h *Handler) ProjectQRImageHandler(w http.ResponseWriter, r *http.Request) {
+	project, ok := h.publicProject(r.Context(), chi.URLParam(r, "id"))
+	if !ok || !project.PaymentsID.Valid || project.PaymentsID.String == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	req, err := qrpay.ParseImageQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.VariableSymbol != "" && req.VariableSymbol != project.PaymentsID.String {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	req.VariableSymbol = project.PaymentsID.String
+
+	h.writePaymentQR(w, req, qrpay.GenerateParams{
+		Message: "DAR BASE48 " + project.Name,
+		URL:     fmt.Sprintf("%s/projects/%d", h.config.BaseURL, project.ID),
+	}, "public, max-age=3600")
+}
+
+// daysLeft returns the whole days until the deadline, 0 when it passed or is not set
+func daysLeft(deadline sql.NullTime, now time.Time) int {
+	if !deadline.Valid {
+		return 0
+	}
+	days := int(deadline.Time.Sub(now).Hours()/24) + 1 // the deadline day counts
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// fundingProgress returns the raised share of the goal in percent, capped at 100;
+// 0 without a goal
+func fundingProgress(total float64, goal money.Amount) int {
+	if goal <= 0 || total <= 0 {
+		return 0
+	}
+	progress := int(total / goal.Float64() * 100)
+	if progress > 100 {
+		return 100
+	}
+	return progress
+}
+
+// ProjectWallAPIHandler returns the project total and the contributor wall for the space display
+// GET /api/projects/{id}/wall
+func (h *Handler) ProjectWallAPIHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	project, ok := h.publicProject(ctx, chi.URLParam(r, "id"))
+	if !ok {
+		h.jsonError(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := h.queries.ListProjectWall(ctx, project.ID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"project": map[string]interface{}{
+			"id":          project.ID,
+			"name":        project.Name,
+			"description": project.Description.String,
+			"total":       h.projectTotal(ctx, project.ID),
+			"goal":        project.Goal.Float64(),
+		},
+		"contributors": publicWall(rows),
+	})
+}
+
+// MeJoinProjectWallHandler adds the member to the wall of a public project or changes
+// the nickname (shown after admin approval)
+// POST /api/me/projects/{id}/wall
+// Body: {"nickname": "Franta"}
+func (h *Handler) MeJoinProjectWallHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	project, ok := h.publicProject(ctx, chi.URLParam(r, "id"))
+	if !ok {
+		h.jsonError(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	var req WallEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	nickname, valid := validNickname(req.Nickname)
+	if !valid {
+		h.jsonError(w, fmt.Sprintf("Nickname is required (max %d characters)", maxWallNickname), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.queries.UpsertProjectWallEntry(ctx, db.UpsertProjectWallEntryParams{
+		ProjectID: project.ID,
+		UserID:    dbUser.ID,
+		Nickname:  nickname,
+	}); err != nil {
+		h.jsonError(w, "Failed to save wall entry", http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Wall entry saved")
+}
+
+// MeLeaveProjectWallHandler removes the member from the wall of a project
+// DELETE /api/me/projects/{id}/wall
+func (h *Handler) MeLeaveProjectWallHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.queries.DeleteProjectWallEntry(r.Context(), db.DeleteProjectWallEntryParams{
+		ProjectID: projectID,
+		UserID:    dbUser.ID,
+	}); err != nil {
+		h.jsonError(w, "Failed to remove wall entry", http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Wall entry removed")
+}
+
+// AdminProjectWallHandler lists all wall entries of a project including pending and hidden ones
+// GET /api/admin/projects/wall?project_id=1
+func (h *Handler) AdminProjectWallHandler(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(r.URL.Query().Get("project_id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid project_id", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.queries.ListProjectWall(r.Context(), projectID)
+	if err != nil {
+		h.jsonError(w, "Failed to fetch wall: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"entries": rows,
+	})
+}
+
+// AdminModerateProjectWallHandler approves or hides a wall entry, optionally correcting the nickname
+// POST /api/admin/projects/wall
+// Body: {"project_id": 1, "user_id": 42, "state": "approved", "nickname": ""}
+func (h *Handler) AdminModerateProjectWallHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req ModerateWallRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.State != WallStateApproved && req.State != WallStateHidden {
+		h.jsonError(w, "State must be approved or hidden", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.queries.GetProjectWallEntry(ctx, db.GetProjectWallEntryParams{
+		ProjectID: req.ProjectID,
+		UserID:    req.UserID,
+	})
+	if err != nil {
+		h.jsonError(w, "Wall entry not found", http.StatusNotFound)
+		return
+	}
+
+	nickname := entry.Nickname
+	if req.Nickname != "" {
+		var valid bool
+		if nickname, valid = validNickname(req.Nickname); !valid {
+			h.jsonError(w, fmt.Sprintf("Nickname too long (max %d characters)", maxWallNickname), http.StatusBadRequest)
+			return
+		}
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	if _, err := h.queries.ModerateProjectWallEntry(ctx, db.ModerateProjectWallEntryParams{
+		State:       req.State,
+		Nickname:    nickname,
+		ModeratedBy: sql.NullString{String: adminUsername, Valid: true},
+		ProjectID:   req.ProjectID,
+		UserID:      req.UserID,
+	}); err != nil {
+		h.jsonError(w, "Failed to moderate wall entry: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: req.UserID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) set wall entry %q of project #%d to %s",
+			adminUsername, adminDBUser.Email, nickname, req.ProjectID, req.State),
+		Metadata: sql.NullString{String: fmt.Sprintf(`{"project_id":%d,"user_id":%d,"state":%q,"nickname":%q,"previous_nickname":%q}`,
+			req.ProjectID, req.UserID, req.State, nickname, entry.Nickname), Valid: true},
+	})
+
+	h.jsonSuccess(w, "Wall entry updated")
+}
+
+// AdminSetProjectTargetHandler sets the funding target: the goal shown on the
+// public page and an optional deadline
+// POST /api/admin/projects/target
+// Body: {"project_id": 1, "goal": 50000, "deadline": "2026-12-31"} (Kč, 0 = no goal; deadline optional)
+func (h *Handler) AdminSetProjectTargetHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ProjectID int64        `json:"project_id"`
+		Goal      money.Amount `json:"goal"`
+		Deadline  string       `json:"deadline"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Goal < 0 {
+		h.jsonError(w, "Goal must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	var deadline sql.NullTime
+	if req.Deadline != "" {
+		date, err := time.Parse("2006-01-02", req.Deadline)
+		if err != nil {
+			h.jsonError(w, "Invalid deadline, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		deadline = sql.NullTime{Time: date, Valid: true}
+	}
+
+	if err := h.queries.SetProjectTarget(r.Context(), db.SetProjectTargetParams{
+		Goal:     req.Goal,
+		Deadline: deadline,
+		ID:       req.ProjectID,
+	}); err != nil {
+		h.jsonError(w, "Failed to update project: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Project updated")
+}
+
+// AdminSetProjectPublicHandler publishes or hides the public page of a project
+// POST /api/admin/projects/public
+// Body: {"project_id": 1, "public": true}
+func (h *Handler) AdminSetProjectPublicHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ProjectID int64 `json:"project_id"`
+		Public    bool  `json:"public"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.queries.SetProjectPublic(r.Context(), db.SetProjectPublicParams{
+		Public: req.Public,
+		ID:     req.ProjectID,
+	}); err != nil {
+		h.jsonError(w, "Failed to update project: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Project updated")
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/qrpay"
+)
+
+// QRFormat is a QR payment format the member can choose for their banking app
+type QRFormat struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// qrFormats lists the formats in the profile select, SPAYD is the default
+var qrFormats = []QRFormat{
+	{ID: qrpay.FormatSPAYD, Title: "Česká banka (QR Platba)"},
+	{ID: qrpay.FormatEPC, Title: "Zahraniční banka - SEPA (GiroCode)"},
+	{ID: qrpay.FormatPayBySquare, Title: "Slovenská banka (PAY by square)"},
+}
+
+// userQRFormat returns the member's QR format, SPAYD when not set
+func (h *Handler) userQRFormat(ctx context.Context, userID int64) string {
+	format, err := h.queries.GetUserQRFormat(ctx, userID)
+	if err != nil {
+		return qrpay.FormatSPAYD
+	}
+	return format
+}
+
+// MeQRFormatHandler sets the QR payment format shown in the member's profile
+// POST /api/me/qr-format
+// Body: {"qr_format": "paybysquare"}
+func (h *Handler) MeQRFormatHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		QRFormat string `json:"qr_format"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	known := false
+	for _, format := range qrFormats {
+		if format.ID == req.QRFormat {
+			known = true
+			break
+		}
+	}
+	if !known {
+		h.jsonError(w, fmt.Sprintf("Unknown QR format: %s", req.QRFormat), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.queries.SetUserQRFormat(r.Context(), db.SetUserQRFormatParams{
+		UserID:   dbUser.ID,
+		QrFormat: req.QRFormat,
+	}); err != nil {
+		h.jsonError(w, "Failed to save QR format", http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "QR format saved")
+}
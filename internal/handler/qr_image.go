@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/base48/member-portal/internal/qrpay"
+)
+
+// qrPaymentMessage is the message of membership fee payments in QR codes
+const qrPaymentMessage = "CLENSKY PRISPEVEK BASE48"
+
+// PaymentQRImageHandler renders a payment QR code of the signed-in member as PNG,
+// so pages link the image instead of embedding it as base64. Only the member's own
+// VS can be used; the format defaults to the one chosen in the profile.
+// GET /qr/payment.png?amount=450.00&due=2026-04-01&size=200&format=&vs=
+func (h *Handler) PaymentQRImageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	dbUser := DBUserFrom(ctx)
+	if dbUser == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	req, err := qrpay.ParseImageQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !dbUser.PaymentsID.Valid || dbUser.PaymentsID.String == "" {
+		http.Error(w, "No variable symbol assigned", http.StatusNotFound)
+		return
+	}
+	if req.VariableSymbol == "" {
+		req.VariableSymbol = dbUser.PaymentsID.String
+	} else if req.VariableSymbol != dbUser.PaymentsID.String {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if req.Format == "" {
+		req.Format = h.userQRFormat(ctx, dbUser.ID)
+	}
+
+	h.writePaymentQR(w, req, h.feeQRParams(), "private, max-age=300")
+}
+
+// SignedPaymentQRImageHandler renders the QR code of a signed URL (emails, admin
+// view of a member's profile); no session needed, the signature covers all parameters.
+// GET /qr/signed/payment.png?vs=&amount=&due=&size=&format=&exp=&sig=
+func (h *Handler) SignedPaymentQRImageHandler(w http.ResponseWriter, r *http.Request) {
+	req, err := qrpay.VerifySignedImageQuery(h.config.SessionSecret, r.URL.Query(), time.Now())
+	if errors.Is(err, qrpay.ErrExpired) {
+		http.Error(w, "Link expired", http.StatusGone)
+		return
+	} else if err != nil {
+		http.Error(w, "Invalid link", http.StatusForbidden)
+		return
+	}
+
+	h.writePaymentQR(w, req, h.feeQRParams(), "private, max-age=86400")
+}
+
+// signedPaymentQRURL returns a signed image URL valid for the given time
+func (h *Handler) signedPaymentQRURL(req qrpay.ImageRequest, validity time.Duration) string {
+	return qrpay.SignedImageURL(h.config.BaseURL, h.config.SessionSecret, req, time.Now().Add(validity))
+}
+
+// feeQRParams are the message and back-link of membership fee QR codes
+func (h *Handler) feeQRParams() qrpay.GenerateParams {
+	return qrpay.GenerateParams{
+		Message: qrPaymentMessage,
+		URL:     h.config.BaseURL + "/profile",
+	}
+}
+
+// writePaymentQR writes the QR image of the request; params carry the message
+// and back-link of the payment
+func (h *Handler) writePaymentQR(w http.ResponseWriter, req qrpay.ImageRequest, params qrpay.GenerateParams, cacheControl string) {
+	if !h.qrpayService.IsConfigured() {
+		http.Error(w, "QR payments are not configured", http.StatusNotFound)
+		return
+	}
+
+	params.Amount = req.Amount
+	params.VariableSymbol = req.VariableSymbol
+	params.DueDate = req.DueDate
+	params.RecipientName = h.config.BankName
+	params.Size = req.Size
+	png, err := h.qrpayService.GeneratePNG(req.Format, params)
+	if err != nil {
+		http.Error(w, "Failed to generate QR code", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Write(png)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/base48/member-portal/internal/ratelimit"
+)
+
+// rateLimitExempt paths are called by Keycloak, not by clients
+var rateLimitExempt = map[string]bool{
+	"/auth/backchannel-logout": true,
+}
+
+// RateLimitMiddleware limits /auth/* per client IP and /api/* per user or client IP
+// (RATE_LIMIT_AUTH, RATE_LIMIT_API). It must run after RealIPMiddleware.
+func (h *Handler) RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var limiter *ratelimit.Limiter
+		var key string
+		switch {
+		case rateLimitExempt[r.URL.Path]:
+		case strings.HasPrefix(r.URL.Path, "/auth/"):
+			limiter, key = h.authLimiter, "ip:"+clientIP(r)
+		case strings.HasPrefix(r.URL.Path, "/api/"):
+			limiter, key = h.apiLimiter, h.apiClientKey(r)
+		}
+		if limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ok, retryAfter := limiter.Allow(key)
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			h.jsonError(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, "Too many requests, try again later", http.StatusTooManyRequests)
+	})
+}
+
+// apiClientKey identifies the API client: the logged in user, otherwise the
+// client IP. Bearer tokens are not used as keys - made-up tokens would get a
+// fresh bucket with every request.
+func (h *Handler) apiClientKey(r *http.Request) string {
+	if r.Header.Get("Authorization") == "" {
+		if user := h.auth.GetUser(r); user != nil {
+			return "user:" + user.Email
+		}
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP returns the client address without the port
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIPMiddleware sets r.RemoteAddr to the client IP from X-Forwarded-For or
+// X-Real-IP, but only for requests coming from TRUSTED_PROXIES. Anyone else could
+// put any address there and get a fresh rate limit bucket with every request.
+func (h *Handler) RealIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if peer, ok := parseIP(clientIP(r)); ok && h.trustedProxy(peer) {
+			if ip := h.forwardedIP(r); ip != "" {
+				r.RemoteAddr = ip
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedIP returns the client IP as reported by the trusted proxies, or "".
+// X-Forwarded-For is read from the right: entries added by trusted proxies are
+// skipped, the first other one was added by the proxy for its client; anything
+// further left came from the client and cannot be trusted.
+func (h *Handler) forwardedIP(r *http.Request) string {
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		hops := strings.Split(strings.Join(values, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip, ok := parseIP(strings.TrimSpace(hops[i]))
+			if !ok {
+				return ""
+			}
+			if i == 0 || !h.trustedProxy(ip) {
+				return ip.String()
+			}
+		}
+	}
+	if ip, ok := parseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ok {
+		return ip.String()
+	}
+	return ""
+}
+
+// trustedProxy reports whether ip belongs to TRUSTED_PROXIES
+func (h *Handler) trustedProxy(ip netip.Addr) bool {
+	for _, prefix := range h.config.TrustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIP parses an IP address, with or without a port; IPv4-mapped IPv6
+// addresses are returned as IPv4
+func parseIP(value string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	ip, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
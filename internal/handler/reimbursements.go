@@ -0,0 +1,533 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fio"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/base48/member-portal/internal/reimbursement"
+)
+
+// RejectReimbursementRequest is the body of POST /api/admin/reimbursements/{id}/reject
+type RejectReimbursementRequest struct {
+	Reason string `json:"reason"`
+}
+
+// adminReimbursementRow is a reimbursement in the admin queue with its receipts
+type adminReimbursementRow struct {
+	db.ListReimbursementsWithUsersRow
+	Receipts []db.ListReimbursementReceiptsRow
+}
+
+// receiptUpload is a validated receipt file waiting to be stored
+type receiptUpload struct {
+	filename    string
+	contentType string
+	data        []byte
+}
+
+// MeReimbursementsHandler lists the member's reimbursement requests
+// GET /api/me/reimbursements
+func (h *Handler) MeReimbursementsHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	reimbursements, err := h.queries.ListReimbursementsByUser(r.Context(), dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"reimbursements": reimbursements,
+	})
+}
+
+// MeSubmitReimbursementHandler creates a reimbursement request with receipts
+// POST /api/me/reimbursements
+// Body: multipart form with amount, description, account ("[prefix-]number/bank")
+// and one or more receipts (PDF, JPEG or PNG)
+func (h *Handler) MeSubmitReimbursementHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	r.Body = http.MaxBytesReader(w, r.Body, reimbursement.MaxReceipts*reimbursement.MaxReceiptSize+1<<20)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		h.jsonError(w, "Invalid form data or receipts too large", http.StatusBadRequest)
+		return
+	}
+
+	amount, err := money.Parse(r.FormValue("amount"))
+	if err != nil || amount <= 0 || amount > reimbursement.MaxAmount {
+		h.jsonError(w, fmt.Sprintf("Amount must be between 0 and %.0f CZK", reimbursement.MaxAmount.Float64()), http.StatusBadRequest)
+		return
+	}
+
+	description := strings.TrimSpace(r.FormValue("description"))
+	if description == "" {
+		h.jsonError(w, "Description is required", http.StatusBadRequest)
+		return
+	}
+
+	account := strings.ReplaceAll(strings.TrimSpace(r.FormValue("account")), " ", "")
+	if _, _, err := fio.ParseAccount(account); err != nil {
+		h.jsonError(w, "Invalid account number, use [prefix-]number/bank code", http.StatusBadRequest)
+		return
+	}
+
+	files := r.MultipartForm.File["receipts"]
+	if len(files) == 0 {
+		h.jsonError(w, "At least one receipt is required", http.StatusBadRequest)
+		return
+	}
+	if len(files) > reimbursement.MaxReceipts {
+		h.jsonError(w, fmt.Sprintf("At most %d receipts can be attached", reimbursement.MaxReceipts), http.StatusBadRequest)
+		return
+	}
+
+	uploads := make([]receiptUpload, 0, len(files))
+	for _, fh := range files {
+		if fh.Size > reimbursement.MaxReceiptSize {
+			h.jsonError(w, fmt.Sprintf("Receipt %s is larger than %d MB", fh.Filename, reimbursement.MaxReceiptSize>>20), http.StatusBadRequest)
+			return
+		}
+
+		f, err := fh.Open()
+		if err != nil {
+			h.jsonError(w, "Failed to read receipt", http.StatusBadRequest)
+			return
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			h.jsonError(w, "Failed to read receipt", http.StatusBadRequest)
+			return
+		}
+
+		// Trust the content, not the browser supplied type
+		contentType := http.DetectContentType(data)
+		if !reimbursement.AllowedReceiptTypes[contentType] {
+			h.jsonError(w, fmt.Sprintf("Receipt %s must be a PDF, JPEG or PNG", fh.Filename), http.StatusBadRequest)
+			return
+		}
+
+		uploads = append(uploads, receiptUpload{
+			filename:    filepath.Base(fh.Filename),
+			contentType: contentType,
+			data:        data,
+		})
+	}
+
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	created, err := qtx.CreateReimbursement(ctx, db.CreateReimbursementParams{
+		UserID:      dbUser.ID,
+		Amount:      amount,
+		Description: description,
+		Account:     account,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to create reimbursement request", http.StatusInternalServerError)
+		return
+	}
+
+	for _, upload := range uploads {
+		if err := qtx.CreateReimbursementReceipt(ctx, db.CreateReimbursementReceiptParams{
+			ReimbursementID: created.ID,
+			Filename:        upload.filename,
+			ContentType:     upload.contentType,
+			Data:            upload.data,
+		}); err != nil {
+			h.jsonError(w, "Failed to store receipt", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		h.jsonError(w, "Failed to create reimbursement request", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "reimbursement",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Reimbursement requested by %s: %s Kč (%s)", dbUser.Email, amount, description),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"reimbursement_id":%d,"receipts":%d}`, created.ID, len(uploads)), Valid: true},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"reimbursement": created,
+	})
+}
+
+// ReimbursementReceiptHandler downloads a receipt (own requests, admins any)
+// GET /reimbursements/{id}/receipts/{receiptID}
+func (h *Handler) ReimbursementReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	user := h.auth.GetUser(r)
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid reimbursement ID", http.StatusBadRequest)
+		return
+	}
+	receiptID, err := strconv.ParseInt(chi.URLParam(r, "receiptID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.queries.GetReimbursement(ctx, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Receipt not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Members see only their own receipts; answer 404 so IDs cannot be probed
+	if !user.IsAdmin() && item.UserID != DBUserFrom(ctx).ID {
+		http.Error(w, "Receipt not found", http.StatusNotFound)
+		return
+	}
+
+	receipt, err := h.queries.GetReimbursementReceipt(ctx, db.GetReimbursementReceiptParams{
+		ID:              receiptID,
+		ReimbursementID: id,
+	})
+	if err == sql.ErrNoRows {
+		http.Error(w, "Receipt not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", receipt.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": receipt.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(receipt.Data)
+}
+
+// AdminReimbursementsHandler shows the reimbursement queue, the approved items
+// waiting for export and the exported payment order batches
+// GET /admin/reimbursements
+func (h *Handler) AdminReimbursementsHandler(w http.ResponseWriter, r *http.Request) {
+	user := h.auth.GetUser(r)
+
+	ctx := r.Context()
+
+	items, err := h.queries.ListReimbursementsWithUsers(ctx, 200)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	rows := make([]adminReimbursementRow, 0, len(items))
+	pending := 0
+	approved := 0
+	var approvedTotal money.Amount
+	for _, item := range items {
+		receipts, err := h.queries.ListReimbursementReceipts(ctx, item.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		rows = append(rows, adminReimbursementRow{ListReimbursementsWithUsersRow: item, Receipts: receipts})
+
+		switch item.State {
+		case reimbursement.StateSubmitted:
+			pending++
+		case reimbursement.StateApproved:
+			approved++
+			approvedTotal += item.Amount
+		}
+	}
+
+	batches, err := h.queries.ListReimbursementBatches(ctx, 20)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Get DBUser for layout
+	dbUser := DBUserFrom(ctx)
+
+	data := map[string]interface{}{
+		"Title":          "Proplácení výdajů",
+		"User":           user,
+		"DBUser":         dbUser,
+		"Reimbursements": rows,
+		"Pending":        pending,
+		"Approved":       approved,
+		"ApprovedTotal":  approvedTotal.String(),
+		"Batches":        batches,
+	}
+
+	h.render(w, "admin_reimbursements.html", data)
+}
+
+// AdminApproveReimbursementHandler approves a reimbursement for the next payment batch
+// POST /api/admin/reimbursements/{id}/approve
+func (h *Handler) AdminApproveReimbursementHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid reimbursement ID", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	rows, err := h.queries.ApproveReimbursement(ctx, db.ApproveReimbursementParams{
+		DecidedBy: sql.NullString{String: adminUsername, Valid: true},
+		ID:        id,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to approve reimbursement", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "Reimbursement is not waiting for approval", http.StatusConflict)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s (%s) approved reimbursement #%d", adminUsername, adminDBUser.Email, id),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"reimbursement_id":%d}`, adminDBUser.ID, id),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Reimbursement approved")
+}
+
+// AdminRejectReimbursementHandler rejects a reimbursement request
+// POST /api/admin/reimbursements/{id}/reject
+// Body: {"reason": "..."}
+func (h *Handler) AdminRejectReimbursementHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid reimbursement ID", http.StatusBadRequest)
+		return
+	}
+
+	var req RejectReimbursementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	rows, err := h.queries.RejectReimbursement(ctx, db.RejectReimbursementParams{
+		AdminComment: sql.NullString{String: req.Reason, Valid: req.Reason != ""},
+		DecidedBy:    sql.NullString{String: adminUsername, Valid: true},
+		ID:           id,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to reject reimbursement", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "Reimbursement is not waiting for approval", http.StatusConflict)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s (%s) rejected reimbursement #%d", adminUsername, adminDBUser.Email, id),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"reimbursement_id":%d,"reason":%q}`, adminDBUser.ID, id, req.Reason),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Reimbursement rejected")
+}
+
+// AdminExportReimbursementsHandler puts all approved reimbursements into a FIO payment
+// order batch. The treasurer downloads the file and uploads it to internet banking;
+// each order carries the reimbursement VS, so FIO sync matches the debit back.
+// POST /api/admin/reimbursements/export
+func (h *Handler) AdminExportReimbursementsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accountFrom, err := fio.AccountFromIBAN(h.config.BankIBAN)
+	if err != nil {
+		h.jsonError(w, "Bank account (BANK_IBAN) is not configured", http.StatusInternalServerError)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	approved, err := qtx.ListApprovedReimbursements(ctx)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if len(approved) == 0 {
+		h.jsonError(w, "No approved reimbursements to export", http.StatusConflict)
+		return
+	}
+
+	dueDate := time.Now().Format("2006-01-02")
+	orders := make([]fio.DomesticOrder, 0, len(approved))
+	var total money.Amount
+	for _, item := range approved {
+		number, bankCode, err := fio.ParseAccount(item.Account)
+		if err != nil {
+			h.jsonError(w, fmt.Sprintf("Reimbursement #%d: %v", item.ID, err), http.StatusConflict)
+			return
+		}
+		orders = append(orders, fio.DomesticOrder{
+			AccountFrom: accountFrom,
+			AccountTo:   number,
+			BankCode:    bankCode,
+			Amount:      item.Amount.Float64(),
+			VS:          reimbursement.VariableSymbol(item.ID),
+			Date:        dueDate,
+			Message:     fmt.Sprintf("Base48 proplaceni vydaju #%d", item.ID),
+			Comment:     fmt.Sprintf("Proplacení #%d: %s", item.ID, item.Description),
+		})
+		total += item.Amount
+	}
+
+	content, err := fio.EncodeOrdersXML(orders)
+	if err != nil {
+		h.jsonError(w, "Failed to build payment orders", http.StatusInternalServerError)
+		return
+	}
+
+	batch, err := qtx.CreateReimbursementBatch(ctx, db.CreateReimbursementBatchParams{
+		CreatedBy: adminUsername,
+		Total:     total,
+		Xml:       string(content),
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to create payment batch", http.StatusInternalServerError)
+		return
+	}
+
+	for _, item := range approved {
+		if _, err := qtx.MarkReimbursementExported(ctx, db.MarkReimbursementExportedParams{
+			BatchID: sql.NullInt64{Int64: batch.ID, Valid: true},
+			ID:      item.ID,
+		}); err != nil {
+			h.jsonError(w, "Failed to create payment batch", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		h.jsonError(w, "Failed to create payment batch", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s (%s) exported reimbursement batch #%d: %d payments, %s Kč",
+			adminUsername, adminDBUser.Email, batch.ID, len(orders), total),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"batch_id":%d,"count":%d,"total":"%s"}`, adminDBUser.ID, batch.ID, len(orders), total),
+			Valid:  true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"batch_id":     batch.ID,
+		"count":        len(orders),
+		"total":        batch.Total,
+		"download_url": fmt.Sprintf("/admin/reimbursements/batches/%d", batch.ID),
+	})
+}
+
+// AdminReimbursementBatchHandler downloads an exported payment order file
+// GET /admin/reimbursements/batches/{id}
+func (h *Handler) AdminReimbursementBatchHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := h.queries.GetReimbursementBatch(r.Context(), id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Batch not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="proplaceni-%d.xml"`, batch.ID))
+	w.Write([]byte(batch.Xml))
+}
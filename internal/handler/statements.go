@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/invoice"
+	"github.com/base48/member-portal/internal/statement"
+)
+
+// statementYears returns the years with entries in the member's ledger (newest
+// first, as in the profile) - the years a payment statement can be shown for
+func statementYears(ledger []UserLedgerEntry) []int {
+	var years []int
+	for _, entry := range ledger {
+		year := entry.Day.UTC().Year()
+		if len(years) == 0 || years[len(years)-1] != year {
+			years = append(years, year)
+		}
+	}
+	return years
+}
+
+// memberStatement loads the member's statement for the year from the URL; writes
+// the error response and returns false when there is none
+func (h *Handler) memberStatement(w http.ResponseWriter, r *http.Request) (statement.Statement, bool) {
+	dbUser := DBUserFrom(r.Context())
+	if dbUser == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return statement.Statement{}, false
+	}
+
+	year, err := strconv.Atoi(chi.URLParam(r, "year"))
+	if err != nil || year < 2000 || year > time.Now().Year() {
+		http.Error(w, "Invalid year", http.StatusBadRequest)
+		return statement.Statement{}, false
+	}
+
+	s, err := statement.Load(r.Context(), h.queries, *dbUser, year)
+	if err != nil {
+		http.Error(w, "Failed to fetch payments", http.StatusInternalServerError)
+		return s, false
+	}
+	if s.Empty() {
+		http.Error(w, "No payments or fees in this year", http.StatusNotFound)
+		return s, false
+	}
+	return s, true
+}
+
+// ProfileStatementHandler shows the member's payments and fees of a calendar year
+// GET /profile/statements/{year}
+func (h *Handler) ProfileStatementHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.memberStatement(w, r)
+	if !ok {
+		return
+	}
+
+	h.render(w, "statement.html", map[string]interface{}{
+		"Title":     fmt.Sprintf("Přehled plateb %d", s.Year),
+		"User":      h.auth.GetUser(r),
+		"DBUser":    DBUserFrom(r.Context()),
+		"Statement": s,
+	})
+}
+
+// ProfileStatementPDFHandler downloads the member's statement of a calendar year as PDF
+// GET /profile/statements/{year}/pdf
+func (h *Handler) ProfileStatementPDFHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.memberStatement(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, s.Filename()))
+	w.Write(statement.RenderPDF(s, invoice.IssuerFromConfig(h.config)))
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/base48/member-portal/internal/stripe"
+)
+
+// stripePaymentKind is payments.kind of card payments, kind_id is the Checkout session ID
+const stripePaymentKind = "stripe"
+
+// MeStripeCheckoutHandler creates a Stripe Checkout session for the member's debt and
+// returns the URL of the payment page
+// POST /api/me/stripe/checkout
+func (h *Handler) MeStripeCheckoutHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	if h.stripe == nil {
+		h.jsonError(w, "Card payments are not configured", http.StatusNotFound)
+		return
+	}
+	if !dbUser.PaymentsID.Valid || dbUser.PaymentsID.String == "" {
+		h.jsonError(w, "You have no variable symbol yet, the payment could not be assigned", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+
+	balance, err := h.queries.GetUserBalance(ctx, db.GetUserBalanceParams{
+		UserID:   sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		UserID_2: dbUser.ID,
+		UserID_3: sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		UserID_4: dbUser.ID,
+		UserID_5: dbUser.ID,
+	})
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Failed to calculate balance: %v", err), http.StatusInternalServerError)
+		return
+	}
+	debt := -money.Amount(balance)
+	if debt <= 0 {
+		h.jsonError(w, "Nothing to pay, your balance is not negative", http.StatusBadRequest)
+		return
+	}
+
+	userID := strconv.FormatInt(dbUser.ID, 10)
+	session, err := h.stripe.CreateCheckoutSession(ctx, stripe.CheckoutParams{
+		Amount:            debt,
+		Description:       "Členský příspěvek Base48 (VS " + dbUser.PaymentsID.String + ")",
+		CustomerEmail:     dbUser.Email,
+		ClientReferenceID: userID,
+		SuccessURL:        h.config.BaseURL + "/profile?stripe=success",
+		CancelURL:         h.config.BaseURL + "/profile",
+		Metadata: map[string]string{
+			"user_id":     userID,
+			"payments_id": dbUser.PaymentsID.String,
+		},
+	})
+	if err != nil {
+		log.Printf("[Stripe] Failed to create checkout session for %s: %v", dbUser.Email, err)
+		h.jsonError(w, "Failed to start card payment", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"url":     session.URL,
+		"amount":  debt.Float64(),
+	})
+}
+
+// StripeWebhookHandler records paid Checkout sessions as payments of the member.
+// Authenticated by the signature of STRIPE_WEBHOOK_SECRET; Stripe retries events
+// until it gets 2xx, the session ID keeps a retried event from being stored twice.
+// POST /api/stripe/webhook
+func (h *Handler) StripeWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if h.stripe == nil {
+		h.jsonError(w, "Card payments are not configured", http.StatusNotFound)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	event, err := stripe.ParseWebhook(payload, r.Header.Get("Stripe-Signature"), h.config.StripeWebhookSecret, time.Now())
+	if errors.Is(err, stripe.ErrInvalidSignature) {
+		h.jsonError(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Other events the endpoint may be subscribed to are acknowledged and ignored
+	if event.Type != stripe.EventCheckoutCompleted && event.Type != stripe.EventCheckoutAsyncSucceeded {
+		h.jsonSuccess(w, "Event ignored")
+		return
+	}
+
+	session, err := event.Session()
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Delayed payment methods complete the session unpaid and succeed later
+	if session.PaymentStatus != stripe.PaymentStatusPaid {
+		h.jsonSuccess(w, "Payment not completed yet")
+		return
+	}
+
+	if err := h.recordStripePayment(r.Context(), session); err != nil {
+		log.Printf("[Stripe] Failed to record session %s: %v", session.ID, err)
+		h.queries.CreateLog(r.Context(), db.CreateLogParams{
+			Subsystem: "stripe",
+			Level:     "error",
+			Message:   fmt.Sprintf("Failed to record card payment %s: %v", session.ID, err),
+			Metadata:  sql.NullString{String: fmt.Sprintf(`{"session_id":%q,"event_id":%q}`, session.ID, event.ID), Valid: true},
+		})
+		// Stripe retries the event later
+		h.jsonError(w, "Failed to record payment", http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Payment recorded")
+}
+
+// recordStripePayment stores a paid session as a payment of the member from its
+// metadata, with the member's VS so it counts in the balance
+func (h *Handler) recordStripePayment(ctx context.Context, session *stripe.Session) error {
+	if session.Currency != "czk" {
+		return fmt.Errorf("unexpected currency %q", session.Currency)
+	}
+
+	userID, err := strconv.ParseInt(session.Metadata["user_id"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("session without user_id metadata")
+	}
+	user, err := h.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("user %d: %w", userID, err)
+	}
+
+	// The VS at checkout time, the member's payments_id could change in between
+	identification := session.Metadata["payments_id"]
+	if identification == "" && user.PaymentsID.Valid {
+		identification = user.PaymentsID.String
+	}
+
+	rawData, _ := json.Marshal(session)
+
+	var payment db.Payment
+	err = h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		var err error
+		payment, err = queries.UpsertPayment(ctx, db.UpsertPaymentParams{
+			UserID:         sql.NullInt64{Int64: user.ID, Valid: true},
+			ProjectID:      sql.NullInt64{},
+			Date:           time.Now(),
+			Amount:         session.Amount(),
+			Kind:           stripePaymentKind,
+			KindID:         session.ID,
+			LocalAccount:   "STRIPE",
+			RemoteAccount:  session.CustomerEmail,
+			Identification: identification,
+			RawData:        sql.NullString{String: string(rawData), Valid: true},
+			StaffComment:   sql.NullString{String: "Platba kartou (" + session.PaymentIntent + ")", Valid: session.PaymentIntent != ""},
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "stripe",
+		Level:     "success",
+		UserID:    sql.NullInt64{Int64: user.ID, Valid: true},
+		Message:   fmt.Sprintf("Card payment #%d (%s Kč) from %s recorded", payment.ID, session.Amount(), user.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"payment_id":%d,"session_id":%q,"amount":"%s"}`, payment.ID, session.ID, session.Amount()),
+			Valid:  true,
+		},
+	})
+	return nil
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// maxExemptionReason limits the reason of a suspension exemption
+const maxExemptionReason = 500
+
+// SuspensionExemptionRequest is the body of POST /api/admin/users/{id}/suspension-exemption
+type SuspensionExemptionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// AdminSetSuspensionExemptionHandler exempts a member from automatic suspension for
+// debt (suspend_debtors), e.g. when the board agreed on instalments
+// POST /api/admin/users/{id}/suspension-exemption
+// Body: {"reason": "splátkový kalendář do 06/2026"}
+func (h *Handler) AdminSetSuspensionExemptionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req SuspensionExemptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if utf8.RuneCountInString(reason) > maxExemptionReason {
+		h.jsonError(w, fmt.Sprintf("Reason is too long (max %d characters)", maxExemptionReason), http.StatusBadRequest)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	if err := h.queries.SetSuspensionExemption(ctx, db.SetSuspensionExemptionParams{
+		UserID:    targetDBUser.ID,
+		Reason:    reason,
+		CreatedBy: adminDBUser.Email,
+	}); err != nil {
+		h.jsonError(w, "Failed to save exemption", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s exempted %s from suspension for debt", adminDBUser.Email, targetDBUser.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"reason":%q}`, adminDBUser.ID, targetDBUser.ID, reason),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Member exempted from automatic suspension")
+}
+
+// AdminDeleteSuspensionExemptionHandler removes the member's exemption
+// DELETE /api/admin/users/{id}/suspension-exemption
+func (h *Handler) AdminDeleteSuspensionExemptionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := h.queries.DeleteSuspensionExemption(ctx, targetDBUser.ID)
+	if err != nil {
+		h.jsonError(w, "Failed to remove exemption", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "Member is not exempt", http.StatusNotFound)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s removed suspension exemption of %s", adminDBUser.Email, targetDBUser.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d}`, adminDBUser.ID, targetDBUser.ID),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Suspension exemption removed")
+}
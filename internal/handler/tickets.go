@@ -0,0 +1,557 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/ticket"
+)
+
+// InboundEmail is the body of POST /api/ingest/email, posted by the mail provider's
+// inbound webhook (or a small MTA pipe script) for every email sent to the support inbox
+type InboundEmail struct {
+	From      string `json:"from"` // "Jan Novák <jan@example.com>" or a bare address
+	Subject   string `json:"subject"`
+	Text      string `json:"text"`       // plain text body
+	MessageID string `json:"message_id"` // optional, repeated deliveries of the same email are ignored
+}
+
+// CreateTicketRequest is the body of POST /api/me/tickets
+type CreateTicketRequest struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// TicketReplyRequest is the body of POST /api/me/tickets/{id}/reply and
+// POST /api/admin/tickets/{id}/reply
+type TicketReplyRequest struct {
+	Body  string `json:"body"`
+	Close bool   `json:"close"` // admin only: close the ticket with the reply
+}
+
+// TicketStateRequest is the body of POST /api/admin/tickets/{id}/state
+type TicketStateRequest struct {
+	State string `json:"state"` // open or closed
+}
+
+// ticketThread is a ticket with its messages, oldest first
+type ticketThread struct {
+	db.Ticket
+	Messages []db.TicketMessage `json:"messages"`
+}
+
+// userTicketThreads returns the member's tickets with their messages
+func (h *Handler) userTicketThreads(ctx context.Context, userID int64) ([]ticketThread, error) {
+	tickets, err := h.queries.ListTicketsByUser(ctx, sql.NullInt64{Int64: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	threads := make([]ticketThread, 0, len(tickets))
+	for _, t := range tickets {
+		messages, err := h.queries.ListTicketMessages(ctx, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, ticketThread{Ticket: t, Messages: messages})
+	}
+	return threads, nil
+}
+
+// addTicketMessage appends a message to a ticket and moves it to the given state
+func (h *Handler) addTicketMessage(ctx context.Context, params db.CreateTicketMessageParams, state string) error {
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	if _, err := qtx.CreateTicketMessage(ctx, params); err != nil {
+		return err
+	}
+	if _, err := qtx.UpdateTicketState(ctx, db.UpdateTicketStateParams{
+		State: state,
+		ID:    params.TicketID,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// openTicket creates a ticket together with its first message
+func (h *Handler) openTicket(ctx context.Context, params db.CreateTicketParams, message db.CreateTicketMessageParams) (db.Ticket, error) {
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		return db.Ticket{}, err
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	t, err := qtx.CreateTicket(ctx, params)
+	if err != nil {
+		return db.Ticket{}, err
+	}
+	message.TicketID = t.ID
+	if _, err := qtx.CreateTicketMessage(ctx, message); err != nil {
+		return db.Ticket{}, err
+	}
+	return t, tx.Commit()
+}
+
+// MeTicketsHandler lists the member's support tickets with their messages
+// GET /api/me/tickets
+func (h *Handler) MeTicketsHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	threads, err := h.userTicketThreads(r.Context(), dbUser.ID)
+	if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"tickets": threads,
+	})
+}
+
+// MeCreateTicketHandler opens a support ticket from the portal form
+// POST /api/me/tickets
+func (h *Handler) MeCreateTicketHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	var req CreateTicketRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	subject := ticket.CleanSubject(req.Subject)
+	body := ticket.CleanBody(req.Body)
+	if subject == "" || body == "" {
+		h.jsonError(w, "Subject and message are required", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.openTicket(ctx, db.CreateTicketParams{
+		UserID:  sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		Email:   dbUser.Email,
+		Subject: subject,
+		Source:  ticket.SourcePortal,
+	}, db.CreateTicketMessageParams{
+		Direction: ticket.DirectionIn,
+		Author:    dbUser.Email,
+		Body:      body,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to create ticket", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "support",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: dbUser.ID, Valid: true},
+		Message:   fmt.Sprintf("User %s opened ticket #%d from the portal", dbUser.Email, created.ID),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"ticket_id":%d,"source":"portal"}`, created.ID), Valid: true},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"ticket_id": created.ID,
+	})
+}
+
+// MeReplyTicketHandler adds the member's message to their ticket and reopens it
+// POST /api/me/tickets/{id}/reply
+func (h *Handler) MeReplyTicketHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, ok := h.meDBUser(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	var req TicketReplyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	body := ticket.CleanBody(req.Body)
+	if body == "" {
+		h.jsonError(w, "Message is required", http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.queries.GetTicket(ctx, id)
+	if err != nil || !t.UserID.Valid || t.UserID.Int64 != dbUser.ID {
+		h.jsonError(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.addTicketMessage(ctx, db.CreateTicketMessageParams{
+		TicketID:  t.ID,
+		Direction: ticket.DirectionIn,
+		Author:    dbUser.Email,
+		Body:      body,
+	}, ticket.StateOpen); err != nil {
+		h.jsonError(w, "Failed to save message", http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, "Message sent")
+}
+
+// InboundEmailHandler turns emails sent to the support inbox into tickets. A subject
+// tagged "[#123]" from the ticket's address is appended to that ticket, anything else
+// opens a new one; the sender is linked to a member by email address.
+// Authenticated by SUPPORT_INBOUND_TOKEN (Bearer), disabled when unset.
+// POST /api/ingest/email
+func (h *Handler) InboundEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.supportInboundAuthorized(r) {
+		h.jsonError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+
+	var req InboundEmail
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 5<<20)).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	from, err := mail.ParseAddress(req.From)
+	if err != nil {
+		h.jsonError(w, "Invalid sender address", http.StatusBadRequest)
+		return
+	}
+	body := ticket.CleanBody(req.Text)
+	if body == "" {
+		h.jsonError(w, "Empty message", http.StatusBadRequest)
+		return
+	}
+
+	messageID := sql.NullString{String: strings.TrimSpace(req.MessageID), Valid: strings.TrimSpace(req.MessageID) != ""}
+	if messageID.Valid {
+		exists, err := h.queries.TicketMessageExists(ctx, messageID)
+		if err != nil {
+			h.jsonError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if exists == 1 {
+			h.jsonSuccess(w, "Duplicate message ignored")
+			return
+		}
+	}
+
+	// Replies are threaded only when they come from the ticket's address, so guessing
+	// a ticket number does not let anyone write into someone else's conversation
+	var t db.Ticket
+	threaded := false
+	if id, ok := ticket.ParseSubjectTag(req.Subject); ok {
+		if existing, err := h.queries.GetTicket(ctx, id); err == nil && strings.EqualFold(existing.Email, from.Address) {
+			t, threaded = existing, true
+		}
+	}
+
+	message := db.CreateTicketMessageParams{
+		TicketID:  t.ID,
+		Direction: ticket.DirectionIn,
+		Author:    from.Address,
+		Body:      body,
+		MessageID: messageID,
+	}
+	if threaded {
+		err = h.addTicketMessage(ctx, message, ticket.StateOpen)
+	} else {
+		var userID sql.NullInt64
+		if user, err := h.queries.GetUserByEmail(ctx, from.Address); err == nil {
+			userID = sql.NullInt64{Int64: user.ID, Valid: true}
+		}
+
+		subject := ticket.CleanSubject(req.Subject)
+		if subject == "" {
+			subject = "(bez předmětu)"
+		}
+
+		t, err = h.openTicket(ctx, db.CreateTicketParams{
+			UserID:  userID,
+			Email:   from.Address,
+			Subject: subject,
+			Source:  ticket.SourceEmail,
+		}, message)
+	}
+	if err != nil {
+		h.jsonError(w, "Failed to save message", http.StatusInternalServerError)
+		return
+	}
+
+	action := "opened"
+	if threaded {
+		action = "replied to"
+	}
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "support",
+		Level:     "info",
+		UserID:    t.UserID,
+		Message:   fmt.Sprintf("Email from %s %s ticket #%d", from.Address, action, t.ID),
+		Metadata:  sql.NullString{String: fmt.Sprintf(`{"ticket_id":%d,"source":"email","threaded":%t}`, t.ID, threaded), Valid: true},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"ticket_id": t.ID,
+		"created":   !threaded,
+		"matched":   t.UserID.Valid,
+	})
+}
+
+// supportInboundAuthorized checks the webhook's bearer token in constant time
+func (h *Handler) supportInboundAuthorized(r *http.Request) bool {
+	if h.config.SupportInboundToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.config.SupportInboundToken)) == 1
+}
+
+// AdminTicketsHandler shows the support inbox
+// GET /admin/tickets
+func (h *Handler) AdminTicketsHandler(w http.ResponseWriter, r *http.Request) {
+	user := h.auth.GetUser(r)
+
+	ctx := r.Context()
+
+	tickets, err := h.queries.ListTickets(ctx, 200)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	open := 0
+	for _, t := range tickets {
+		if t.State == ticket.StateOpen {
+			open++
+		}
+	}
+
+	// Get DBUser for layout
+	dbUser := DBUserFrom(ctx)
+
+	data := map[string]interface{}{
+		"Title":        "Podpora",
+		"User":         user,
+		"DBUser":       dbUser,
+		"Tickets":      tickets,
+		"Open":         open,
+		"SupportEmail": h.config.SupportEmail,
+	}
+
+	h.render(w, "admin_tickets.html", data)
+}
+
+// AdminTicketHandler shows a ticket thread with the reply form
+// GET /admin/tickets/{id}
+func (h *Handler) AdminTicketHandler(w http.ResponseWriter, r *http.Request) {
+	user := h.auth.GetUser(r)
+
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.queries.GetTicket(ctx, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Ticket not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	messages, err := h.queries.ListTicketMessages(ctx, t.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var member *db.User
+	if t.UserID.Valid {
+		if u, err := h.queries.GetUserByID(ctx, t.UserID.Int64); err == nil {
+			member = &u
+		}
+	}
+
+	// Get DBUser for layout
+	dbUser := DBUserFrom(ctx)
+
+	data := map[string]interface{}{
+		"Title":    fmt.Sprintf("Požadavek #%d", t.ID),
+		"User":     user,
+		"DBUser":   dbUser,
+		"Ticket":   t,
+		"Messages": messages,
+		"Member":   member,
+	}
+
+	h.render(w, "admin_ticket.html", data)
+}
+
+// AdminReplyTicketHandler emails an admin reply to the requester and stores it in the thread
+// POST /api/admin/tickets/{id}/reply
+func (h *Handler) AdminReplyTicketHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	var req TicketReplyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	body := ticket.CleanBody(req.Body)
+	if body == "" {
+		h.jsonError(w, "Message is required", http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.queries.GetTicket(ctx, id)
+	if err != nil {
+		h.jsonError(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	// Send first, so the thread never shows a reply the member did not get
+	if err := h.emailClient.SendTicketReply(ctx, &t, body); err != nil {
+		h.jsonError(w, "Failed to send email", http.StatusBadGateway)
+		return
+	}
+
+	state := ticket.StateAnswered
+	if req.Close {
+		state = ticket.StateClosed
+	}
+	if err := h.addTicketMessage(ctx, db.CreateTicketMessageParams{
+		TicketID:  t.ID,
+		Direction: ticket.DirectionOut,
+		Author:    adminUsername,
+		Body:      body,
+	}, state); err != nil {
+		h.jsonError(w, "Email sent, but failed to save the reply", http.StatusInternalServerError)
+		return
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s (%s) replied to ticket #%d", adminUsername, adminDBUser.Email, t.ID),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"ticket_id":%d,"state":%q}`, adminDBUser.ID, t.ID, state),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Reply sent")
+}
+
+// AdminTicketStateHandler closes or reopens a ticket
+// POST /api/admin/tickets/{id}/state
+func (h *Handler) AdminTicketStateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	var req TicketStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.State != ticket.StateOpen && req.State != ticket.StateClosed {
+		h.jsonError(w, "State must be open or closed", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.queries.UpdateTicketState(ctx, db.UpdateTicketStateParams{
+		State: req.State,
+		ID:    id,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to update ticket", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	adminUsername := "unknown"
+	if adminDBUser.Username.Valid {
+		adminUsername = adminDBUser.Username.String
+	}
+
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s (%s) set ticket #%d to %s", adminUsername, adminDBUser.Email, id, req.State),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"ticket_id":%d,"state":%q}`, adminDBUser.ID, id, req.State),
+			Valid:  true,
+		},
+	})
+
+	h.jsonSuccess(w, "Ticket updated")
+}
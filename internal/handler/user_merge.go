@@ -0,0 +1,382 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// MergeUsersRequest is the body of POST /api/admin/users/merge
+type MergeUsersRequest struct {
+	FromUserID        int64 `json:"from_user_id"`        // the duplicate, deleted by the merge
+	ToUserID          int64 `json:"to_user_id"`          // the record that stays
+	DropDuplicateFees bool  `json:"drop_duplicate_fees"` // drop the duplicate's fees for months the target is billed for
+}
+
+// errMergeConflict is returned from the merge transaction when the records cannot be merged
+var errMergeConflict = errors.New("merge conflict")
+
+// AdminMergeUsersHandler merges a duplicate user record into another one: payments,
+// fees, logs, project memberships and everything else owned by the duplicate move
+// to the target, the target keeps its own values and only fills in missing ones
+// (Keycloak ID, VS, contacts), and the duplicate is deleted. The duplicate's payments
+// carrying its own VS are moved to the target's VS so the balances add up.
+// POST /api/admin/users/merge
+// Body: {"from_user_id": 12, "to_user_id": 7, "drop_duplicate_fees": false}
+func (h *Handler) AdminMergeUsersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req MergeUsersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.FromUserID == 0 || req.ToUserID == 0 {
+		h.jsonError(w, "from_user_id and to_user_id are required", http.StatusBadRequest)
+		return
+	}
+	if req.FromUserID == req.ToUserID {
+		h.jsonError(w, "Cannot merge a user into itself", http.StatusBadRequest)
+		return
+	}
+
+	from, err := h.queries.GetUserByID(ctx, req.FromUserID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Source user not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	to, err := h.queries.GetUserByID(ctx, req.ToUserID)
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Target user not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// An anonymized record has nothing left worth merging and must not be revived
+	for _, u := range []db.User{from, to} {
+		if _, err := h.queries.GetUserAnonymization(ctx, u.ID); err == nil {
+			h.jsonError(w, fmt.Sprintf("User #%d is anonymized", u.ID), http.StatusConflict)
+			return
+		} else if err != sql.ErrNoRows {
+			h.jsonError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	var (
+		conflict     string
+		droppedFees  []string
+		merged       = map[string]int64{}
+		mergedUser   db.User
+		droppedVS    string
+		keycloakGone string
+	)
+
+	err = h.balanceQueue.Do(ctx, func(ctx context.Context, queries *db.Queries) error {
+		// Both records billed for the same month would break the one-fee-per-month
+		// rule; the admin decides whether the duplicate's fees can go
+		duplicates, err := queries.ListMergeDuplicateFees(ctx, db.ListMergeDuplicateFeesParams{
+			FromUserID: from.ID,
+			ToUserID:   to.ID,
+		})
+		if err != nil {
+			return err
+		}
+		for _, fee := range duplicates {
+			period := fee.PeriodStart.Format("2006-01")
+			if fee.Referenced {
+				conflict = fmt.Sprintf("Fee for %s of user #%d is invoiced or adjusted and cannot be dropped", period, from.ID)
+				return errMergeConflict
+			}
+			droppedFees = append(droppedFees, period)
+		}
+		if len(droppedFees) > 0 && !req.DropDuplicateFees {
+			conflict = fmt.Sprintf("Both users have fees for %s; set drop_duplicate_fees to drop those of user #%d",
+				strings.Join(droppedFees, ", "), from.ID)
+			return errMergeConflict
+		}
+		for _, fee := range duplicates {
+			if err := queries.DeleteFee(ctx, fee.ID); err != nil {
+				return err
+			}
+		}
+
+		fromNull := sql.NullInt64{Int64: from.ID, Valid: true}
+		toNull := sql.NullInt64{Int64: to.ID, Valid: true}
+
+		// The target keeps its VS; the duplicate's own one is dropped, so its payments
+		// are moved to the target's VS to keep counting in the balance
+		targetVS := coalesceNullString(to.PaymentsID, from.PaymentsID)
+		if from.PaymentsID.Valid && from.PaymentsID.String != "" && from.PaymentsID.String != targetVS.String {
+			droppedVS = from.PaymentsID.String
+		}
+
+		steps := []struct {
+			name string
+			run  func() (int64, error)
+		}{
+			{"payment_vs", func() (int64, error) {
+				if droppedVS == "" {
+					return 0, nil
+				}
+				return queries.MergeUserPaymentVS(ctx, db.MergeUserPaymentVSParams{
+					ToPaymentsID:   targetVS.String,
+					FromUserID:     fromNull,
+					FromPaymentsID: droppedVS,
+				})
+			}},
+			{"payments", func() (int64, error) {
+				return queries.MergeUserPayments(ctx, db.MergeUserPaymentsParams{ToUserID: toNull, FromUserID: fromNull})
+			}},
+			{"payment_dismissals", func() (int64, error) {
+				return queries.MergeUserPaymentDismissals(ctx, db.MergeUserPaymentDismissalsParams{ToUserID: toNull, FromUserID: fromNull})
+			}},
+			{"fees", func() (int64, error) {
+				return queries.MergeUserFees(ctx, db.MergeUserFeesParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"charges", func() (int64, error) {
+				return queries.MergeUserCharges(ctx, db.MergeUserChargesParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"balance_adjustments", func() (int64, error) {
+				return queries.MergeUserBalanceAdjustments(ctx, db.MergeUserBalanceAdjustmentsParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"payment_plans", func() (int64, error) {
+				return queries.MergeUserPaymentPlans(ctx, db.MergeUserPaymentPlansParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"payment_splits", func() (int64, error) {
+				return queries.MergeUserPaymentSplits(ctx, db.MergeUserPaymentSplitsParams{ToUserID: toNull, FromUserID: fromNull})
+			}},
+			{"payment_suggestions", func() (int64, error) {
+				return queries.MergeUserPaymentSuggestions(ctx, db.MergeUserPaymentSuggestionsParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"payment_match_rules", func() (int64, error) {
+				return queries.MergeUserPaymentMatchRules(ctx, db.MergeUserPaymentMatchRulesParams{ToUserID: toNull, FromUserID: fromNull})
+			}},
+			{"payment_reminders", func() (int64, error) {
+				return queries.MergeUserPaymentReminders(ctx, db.MergeUserPaymentRemindersParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"payment_settings", func() (int64, error) {
+				return queries.MergeUserPaymentSettings(ctx, db.MergeUserPaymentSettingsParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"payment_statements", func() (int64, error) {
+				return queries.MergeUserPaymentStatements(ctx, db.MergeUserPaymentStatementsParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"invoices", func() (int64, error) {
+				return queries.MergeUserInvoices(ctx, db.MergeUserInvoicesParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"fakturoid_invoices", func() (int64, error) {
+				return queries.MergeUserFakturoidInvoices(ctx, db.MergeUserFakturoidInvoicesParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"billing_details", func() (int64, error) {
+				return queries.MergeUserBillingDetails(ctx, db.MergeUserBillingDetailsParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"donation_receipts", func() (int64, error) {
+				return queries.MergeUserDonationReceipts(ctx, db.MergeUserDonationReceiptsParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"reimbursements", func() (int64, error) {
+				return queries.MergeUserReimbursements(ctx, db.MergeUserReimbursementsParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"logs", func() (int64, error) {
+				return queries.MergeUserLogs(ctx, db.MergeUserLogsParams{ToUserID: toNull, FromUserID: fromNull})
+			}},
+			{"audit_log", func() (int64, error) {
+				return queries.MergeUserAuditLog(ctx, db.MergeUserAuditLogParams{ToUserID: toNull, FromUserID: fromNull})
+			}},
+			{"project_memberships", func() (int64, error) {
+				return queries.MergeUserProjectMemberships(ctx, db.MergeUserProjectMembershipsParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"project_wall_entries", func() (int64, error) {
+				return queries.MergeUserWallEntries(ctx, db.MergeUserWallEntriesParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"tickets", func() (int64, error) {
+				return queries.MergeUserTickets(ctx, db.MergeUserTicketsParams{ToUserID: toNull, FromUserID: fromNull})
+			}},
+			{"email_campaigns", func() (int64, error) {
+				return queries.MergeUserEmailCampaigns(ctx, db.MergeUserEmailCampaignsParams{ToUserID: toNull, FromUserID: fromNull})
+			}},
+			{"campaign_recipients", func() (int64, error) {
+				return queries.MergeUserCampaignRecipients(ctx, db.MergeUserCampaignRecipientsParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"notification_preferences", func() (int64, error) {
+				return queries.MergeUserNotificationPreferences(ctx, db.MergeUserNotificationPreferencesParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"dashboard_widgets", func() (int64, error) {
+				return queries.MergeUserDashboardWidgets(ctx, db.MergeUserDashboardWidgetsParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"api_tokens", func() (int64, error) {
+				return queries.MergeUserAPITokens(ctx, db.MergeUserAPITokensParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"milestones", func() (int64, error) {
+				return queries.MergeUserMilestones(ctx, db.MergeUserMilestonesParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"pauses", func() (int64, error) {
+				return queries.MergeUserPauses(ctx, db.MergeUserPausesParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"level_change_requests", func() (int64, error) {
+				return queries.MergeUserLevelChangeRequests(ctx, db.MergeUserLevelChangeRequestsParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"level_history", func() (int64, error) {
+				return queries.MergeUserLevelHistory(ctx, db.MergeUserLevelHistoryParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"price_change_notices", func() (int64, error) {
+				return queries.MergeUserPriceChangeNotices(ctx, db.MergeUserPriceChangeNoticesParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"suspension_exemption", func() (int64, error) {
+				return queries.MergeUserSuspensionExemption(ctx, db.MergeUserSuspensionExemptionParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"applications", func() (int64, error) {
+				return queries.MergeUserApplications(ctx, db.MergeUserApplicationsParams{ToUserID: toNull, FromUserID: fromNull})
+			}},
+			{"application_vouches", func() (int64, error) {
+				return queries.MergeUserApplicationVouches(ctx, db.MergeUserApplicationVouchesParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"tasks", func() (int64, error) {
+				return queries.MergeUserTasks(ctx, db.MergeUserTasksParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"terminations", func() (int64, error) {
+				return queries.MergeUserTerminations(ctx, db.MergeUserTerminationsParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+			{"notes", func() (int64, error) {
+				return queries.MergeUserNotes(ctx, db.MergeUserNotesParams{ToUserID: to.ID, FromUserID: from.ID})
+			}},
+		}
+		for _, step := range steps {
+			n, err := step.run()
+			if err != nil {
+				return fmt.Errorf("%s: %w", step.name, err)
+			}
+			if n > 0 {
+				merged[step.name] = n
+			}
+		}
+
+		// Rows the target already had a counterpart for (UPDATE OR IGNORE) stay with
+		// the duplicate; most go with it by ON DELETE CASCADE, these two tables don't cascade
+		if err := queries.DeleteUserCampaignRecipients(ctx, from.ID); err != nil {
+			return err
+		}
+		if err := queries.DeleteUserPriceChangeNotices(ctx, from.ID); err != nil {
+			return err
+		}
+
+		// The target's history changed, snapshot_balances recomputes it
+		if err := queries.DeleteBalanceSnapshotsByUser(ctx, from.ID); err != nil {
+			return err
+		}
+		if err := queries.DeleteBalanceSnapshotsByUser(ctx, to.ID); err != nil {
+			return err
+		}
+
+		// Deleting first frees the unique Keycloak ID, username and VS for the target
+		if err := queries.DeleteUser(ctx, from.ID); err != nil {
+			return fmt.Errorf("delete user: %w", err)
+		}
+
+		mergedUser = to
+		mergedUser.KeycloakID = coalesceNullString(to.KeycloakID, from.KeycloakID)
+		mergedUser.Username = coalesceNullString(to.Username, from.Username)
+		mergedUser.Realname = coalesceNullString(to.Realname, from.Realname)
+		mergedUser.Phone = coalesceNullString(to.Phone, from.Phone)
+		mergedUser.AltContact = coalesceNullString(to.AltContact, from.AltContact)
+		mergedUser.PaymentsID = targetVS
+		if from.DateJoined.Before(to.DateJoined) {
+			mergedUser.DateJoined = from.DateJoined
+		}
+		if !to.KeysGranted.Valid {
+			mergedUser.KeysGranted = from.KeysGranted
+		}
+		if !to.KeysReturned.Valid {
+			mergedUser.KeysReturned = from.KeysReturned
+		}
+
+		if from.KeycloakID.Valid && from.KeycloakID.String != "" && from.KeycloakID.String != mergedUser.KeycloakID.String {
+			keycloakGone = from.KeycloakID.String
+		}
+
+		return queries.UpdateMergedUser(ctx, db.UpdateMergedUserParams{
+			KeycloakID:   mergedUser.KeycloakID,
+			Username:     mergedUser.Username,
+			Realname:     mergedUser.Realname,
+			Phone:        mergedUser.Phone,
+			AltContact:   mergedUser.AltContact,
+			PaymentsID:   mergedUser.PaymentsID,
+			DateJoined:   mergedUser.DateJoined,
+			KeysGranted:  mergedUser.KeysGranted,
+			KeysReturned: mergedUser.KeysReturned,
+			ID:           to.ID,
+		})
+	})
+	if errors.Is(err, errMergeConflict) {
+		h.jsonError(w, conflict, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.jsonError(w, fmt.Sprintf("Failed to merge users: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Two Keycloak accounts for one member: the duplicate's login must not keep
+	// working with a portal record that no longer exists
+	var warnings []string
+	if keycloakGone != "" {
+		if _, err := h.queries.RevokeAuthSessionsByKeycloakID(ctx, keycloakGone); err != nil {
+			warnings = append(warnings, fmt.Sprintf("revoking sessions: %v", err))
+		}
+		if kcClient, err := h.keycloakClient(); err != nil {
+			warnings = append(warnings, fmt.Sprintf("Keycloak account %s was not disabled: %v", keycloakGone, err))
+		} else if err := kcClient.DisableUser(ctx, keycloakGone); err != nil {
+			warnings = append(warnings, fmt.Sprintf("Keycloak account %s was not disabled: %v", keycloakGone, err))
+		}
+		h.userCache.Invalidate()
+	}
+	if droppedVS != "" {
+		warnings = append(warnings, fmt.Sprintf("Variable symbol %s of user #%d was dropped, %d of its payments now carry %s; new payments with %s will not be matched",
+			droppedVS, from.ID, merged["payment_vs"], mergedUser.PaymentsID.String, droppedVS))
+	}
+
+	mergedJSON, _ := json.Marshal(merged)
+	droppedJSON, _ := json.Marshal(droppedFees)
+	h.queries.CreateLog(ctx, db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "warning",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message: fmt.Sprintf("Admin %s merged user #%d (%s) into #%d (%s)",
+			adminDBUser.Email, from.ID, from.Email, to.ID, to.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"from_user_id":%d,"to_user_id":%d,"from_keycloak_id":%q,"merged":%s,"dropped_fees":%s}`,
+				adminDBUser.ID, from.ID, to.ID, from.KeycloakID.String, mergedJSON, droppedJSON),
+			Valid: true,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"message":      fmt.Sprintf("User #%d merged into #%d", from.ID, to.ID),
+		"user":         mergedUser,
+		"merged":       merged,
+		"dropped_fees": droppedFees,
+		"warnings":     warnings,
+	})
+}
+
+// coalesceNullString returns a, or b when a is empty
+func coalesceNullString(a, b sql.NullString) sql.NullString {
+	if a.Valid && a.String != "" {
+		return a
+	}
+	return b
+}
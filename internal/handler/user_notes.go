@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// maxUserNoteLength limits a single admin note (in characters)
+const maxUserNoteLength = 5000
+
+// UserNoteRequest is the body of the admin note endpoints
+type UserNoteRequest struct {
+	Body   string `json:"body"`
+	Pinned bool   `json:"pinned"`
+}
+
+// AdminCreateUserNoteHandler adds an admin note to a member record
+// POST /api/admin/users/{id}/notes
+// Body: {"body": "Agreed to pay double in December", "pinned": true}
+func (h *Handler) AdminCreateUserNoteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := h.decodeUserNote(w, r)
+	if !ok {
+		return
+	}
+
+	adminDBUser := DBUserFrom(ctx)
+
+	note, err := h.queries.CreateUserNote(ctx, db.CreateUserNoteParams{
+		UserID: targetDBUser.ID,
+		Author: adminDBUser.Email,
+		Body:   req.Body,
+		Pinned: req.Pinned,
+	})
+	if err != nil {
+		h.jsonError(w, "Failed to save note", http.StatusInternalServerError)
+		return
+	}
+
+	h.logUserNote(r, "added", targetDBUser, note)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"note":    note,
+	})
+}
+
+// AdminUpdateUserNoteHandler edits or (un)pins an admin note
+// PUT /api/admin/users/{id}/notes/{noteID}
+// Body: {"body": "...", "pinned": false}
+func (h *Handler) AdminUpdateUserNoteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+	noteID, err := strconv.ParseInt(chi.URLParam(r, "noteID"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid note ID", http.StatusBadRequest)
+		return
+	}
+
+	req, ok := h.decodeUserNote(w, r)
+	if !ok {
+		return
+	}
+
+	note, err := h.queries.UpdateUserNote(ctx, db.UpdateUserNoteParams{
+		Body:   req.Body,
+		Pinned: req.Pinned,
+		ID:     noteID,
+		UserID: targetDBUser.ID,
+	})
+	if err == sql.ErrNoRows {
+		h.jsonError(w, "Note not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.jsonError(w, "Failed to save note", http.StatusInternalServerError)
+		return
+	}
+
+	h.logUserNote(r, "edited", targetDBUser, note)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"note":    note,
+	})
+}
+
+// AdminDeleteUserNoteHandler deletes an admin note
+// DELETE /api/admin/users/{id}/notes/{noteID}
+func (h *Handler) AdminDeleteUserNoteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	targetDBUser, ok := h.targetUserFromURL(w, r)
+	if !ok {
+		return
+	}
+	noteID, err := strconv.ParseInt(chi.URLParam(r, "noteID"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "Invalid note ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.queries.DeleteUserNote(ctx, db.DeleteUserNoteParams{ID: noteID, UserID: targetDBUser.ID})
+	if err != nil {
+		h.jsonError(w, "Failed to delete note", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		h.jsonError(w, "Note not found", http.StatusNotFound)
+		return
+	}
+
+	h.logUserNote(r, "deleted", targetDBUser, db.UserNote{ID: noteID})
+
+	h.jsonSuccess(w, "Note deleted")
+}
+
+// decodeUserNote reads and validates the note body
+func (h *Handler) decodeUserNote(w http.ResponseWriter, r *http.Request) (UserNoteRequest, bool) {
+	var req UserNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" {
+		h.jsonError(w, "Note is empty", http.StatusBadRequest)
+		return req, false
+	}
+	if utf8.RuneCountInString(req.Body) > maxUserNoteLength {
+		h.jsonError(w, fmt.Sprintf("Note is longer than %d characters", maxUserNoteLength), http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// logUserNote records a note change in the system log; the text itself stays
+// in the note (and in the admin audit log)
+func (h *Handler) logUserNote(r *http.Request, action string, target *db.User, note db.UserNote) {
+	adminDBUser := DBUserFrom(r.Context())
+	h.queries.CreateLog(r.Context(), db.CreateLogParams{
+		Subsystem: "admin",
+		Level:     "info",
+		UserID:    sql.NullInt64{Int64: adminDBUser.ID, Valid: true},
+		Message:   fmt.Sprintf("Admin %s %s note #%d of %s", adminDBUser.Email, action, note.ID, target.Email),
+		Metadata: sql.NullString{
+			String: fmt.Sprintf(`{"admin_user_id":%d,"target_user_id":%d,"note_id":%d,"pinned":%t}`,
+				adminDBUser.ID, target.ID, note.ID, note.Pinned),
+			Valid: true,
+		},
+	})
+}
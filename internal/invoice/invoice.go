@@ -0,0 +1,218 @@
+package invoice
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"image/png"
+	"strings"
+
+	"github.com/base48/member-portal/internal/config"
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fees"
+	"github.com/base48/member-portal/internal/pdf"
+	"github.com/base48/member-portal/internal/qrpay"
+)
+
+// Invoice states (invoices.state)
+const (
+	StateRequested = "requested" // waiting for admin approval
+	StateApproved  = "approved"  // number assigned, waiting for payment
+	StateRejected  = "rejected"
+	StatePaid      = "paid" // matched to an incoming payment by VS
+)
+
+// MaxMonths limits how many monthly fees a single invoice can cover
+const MaxMonths = 12
+
+// Issuer is the organization issuing the invoices
+type Issuer struct {
+	Name      string
+	Address   string
+	CompanyID string // IČO
+	IBAN      string
+	BIC       string
+}
+
+// IssuerFromConfig returns the issuer details configured via INVOICE_ISSUER_* and BANK_*
+func IssuerFromConfig(cfg *config.Config) Issuer {
+	return Issuer{
+		Name:      cfg.InvoiceIssuerName,
+		Address:   cfg.InvoiceIssuerAddress,
+		CompanyID: cfg.InvoiceIssuerCompanyID,
+		IBAN:      cfg.BankIBAN,
+		BIC:       cfg.BankBIC,
+	}
+}
+
+// FormatNumber builds the invoice number from the year and its sequence number
+// (e.g. 20260001). The number is also the variable symbol of the payment.
+func FormatNumber(year int, sequence int64) string {
+	return fmt.Sprintf("%d%04d", year, sequence)
+}
+
+// FeeItem describes an invoiced fee ("Členský příspěvek Base48 2026-04 – 2026-06")
+func FeeItem(fee db.Fee) string {
+	return "Členský příspěvek Base48 " + fees.PeriodLabel(fee.PeriodStart, int(fee.Months))
+}
+
+// Render renders an issued invoice as a one-page PDF: a proforma invoice for N
+// months, or an invoice for a fee or a charge (inv.Item). Unpaid invoices carry a
+// QR payment code, paid ones the date of payment and serve as the receipt.
+func Render(inv db.Invoice, issuer Issuer, member db.User) ([]byte, error) {
+	if !inv.Number.Valid {
+		return nil, fmt.Errorf("invoice %d has no number (not approved)", inv.ID)
+	}
+
+	amount := inv.Amount.Float64()
+
+	doc := pdf.New()
+	doc.AddPage()
+
+	const left, right = 50.0, pdf.PageWidth - 50
+
+	if inv.Item.Valid {
+		doc.Text(left, 70, 20, true, "Faktura")
+	} else {
+		doc.Text(left, 70, 20, true, "Zálohová faktura")
+		doc.Text(left, 88, 9, false, "Není daňový doklad")
+	}
+	doc.TextRight(right, 70, 14, true, "č. "+inv.Number.String)
+	doc.Line(left, 100, right, 100, 1)
+
+	// Issuer and customer side by side
+	column := func(x float64, title string, lines []string) {
+		doc.Text(x, 125, 9, true, title)
+		y := 142.0
+		for _, line := range lines {
+			if line == "" {
+				continue
+			}
+			doc.Text(x, y, 10, false, line)
+			y += 14
+		}
+	}
+
+	issuerLines := append([]string{issuer.Name}, strings.Split(issuer.Address, "\n")...)
+	if issuer.CompanyID != "" {
+		issuerLines = append(issuerLines, "IČO: "+issuer.CompanyID)
+	}
+	column(left, "Dodavatel", issuerLines)
+
+	customerLines := append([]string{inv.CompanyName}, strings.Split(inv.Address, "\n")...)
+	if inv.CompanyID != "" {
+		customerLines = append(customerLines, "IČO: "+inv.CompanyID)
+	}
+	if inv.VatID != "" {
+		customerLines = append(customerLines, "DIČ: "+inv.VatID)
+	}
+	column(310, "Odběratel", customerLines)
+
+	// Dates and payment details
+	y := 260.0
+	doc.Rect(left, y, right-left, 88, true, 0.95)
+	details := [][2]string{
+		{"Datum vystavení:", formatDate(inv.IssuedAt)},
+		{"Datum splatnosti:", formatDate(inv.DueAt)},
+		{"Bankovní účet (IBAN):", issuer.IBAN},
+		{"Variabilní symbol:", inv.Number.String},
+	}
+	if inv.State == StatePaid {
+		details[1] = [2]string{"Uhrazeno dne:", formatDate(inv.PaidAt)}
+	}
+	for i, d := range details {
+		doc.Text(left+10, y+20+float64(i)*18, 10, false, d[0])
+		doc.Text(left+150, y+20+float64(i)*18, 10, true, d[1])
+	}
+
+	// Items
+	y = 380
+	doc.Text(left, y, 9, true, "Položka")
+	doc.TextRight(right, y, 9, true, "Částka")
+	doc.Line(left, y+6, right, y+6, 0.5)
+
+	memberName := member.Email
+	if member.Realname.Valid && member.Realname.String != "" {
+		memberName = member.Realname.String
+	}
+	description := fmt.Sprintf("Členský příspěvek Base48 - %s, %d %s", memberName, inv.Months, monthsWord(inv.Months))
+	if inv.Item.Valid {
+		description = inv.Item.String + " - " + memberName
+	}
+	doc.Text(left, y+24, 10, false, description)
+	doc.TextRight(right, y+24, 10, false, FormatAmount(amount))
+	doc.Line(left, y+36, right, y+36, 0.5)
+
+	if inv.State == StatePaid {
+		doc.Text(left, y+60, 12, true, "Celkem uhrazeno")
+	} else {
+		doc.Text(left, y+60, 12, true, "Celkem k úhradě")
+	}
+	doc.TextRight(right, y+60, 12, true, FormatAmount(amount))
+
+	if inv.Note.Valid && inv.Note.String != "" {
+		doc.Text(left, y+90, 9, false, "Poznámka: "+inv.Note.String)
+	}
+
+	// QR payment code, skipped when the bank account is not configured or already paid
+	if issuer.IBAN != "" && inv.State != StatePaid {
+		spayd := qrpay.GenerateSPAYD(qrpay.PaymentParams{
+			IBAN:           issuer.IBAN,
+			BIC:            issuer.BIC,
+			Amount:         amount,
+			Currency:       "CZK",
+			VariableSymbol: inv.Number.String,
+			Message:        "Faktura " + inv.Number.String,
+		})
+		qrPNG, err := qrpay.GenerateQRPNG(spayd, 300)
+		if err != nil {
+			return nil, err
+		}
+		qrImage, err := png.Decode(bytes.NewReader(qrPNG))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode QR code: %w", err)
+		}
+		if err := doc.Image(right-130, y+110, 130, 130, qrImage); err != nil {
+			return nil, err
+		}
+		doc.TextRight(right, y+255, 8, false, "QR Platba")
+	}
+
+	doc.Text(left, pdf.PageHeight-50, 8, false, issuer.Name+" - vystaveno členským portálem")
+
+	return doc.Bytes(), nil
+}
+
+func formatDate(t sql.NullTime) string {
+	if !t.Valid {
+		return "-"
+	}
+	return t.Time.Format("2. 1. 2006")
+}
+
+// FormatAmount formats CZK with a space as thousands separator (1 500,00 Kč)
+func FormatAmount(amount float64) string {
+	whole := fmt.Sprintf("%.2f", amount)
+	intPart, frac, _ := strings.Cut(whole, ".")
+
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(c)
+	}
+	return b.String() + "," + frac + " Kč"
+}
+
+// monthsWord returns the Czech plural of "month"
+func monthsWord(n int64) string {
+	switch {
+	case n == 1:
+		return "měsíc"
+	case n >= 2 && n <= 4:
+		return "měsíce"
+	default:
+		return "měsíců"
+	}
+}
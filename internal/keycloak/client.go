@@ -1,11 +1,14 @@
 package keycloak
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 
 	"github.com/base48/member-portal/internal/config"
@@ -13,11 +16,27 @@ import (
 
 // Client wraps Keycloak Admin API calls
 type Client struct {
-	config      *config.Config
-	adminToken  string
-	httpClient  *http.Client
+	config     *config.Config
+	tokens     TokenProvider
+	httpClient *http.Client
 }
 
+// TokenProvider supplies admin access tokens. Implementations are expected to
+// cache the token; InvalidateToken is called when Keycloak rejects it with 401.
+type TokenProvider interface {
+	GetAccessToken(ctx context.Context) (string, error)
+	InvalidateToken()
+}
+
+// staticToken is a TokenProvider for a fixed token that cannot be renewed
+type staticToken string
+
+func (t staticToken) GetAccessToken(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+func (t staticToken) InvalidateToken() {}
+
 // Role represents a Keycloak role
 type Role struct {
 	ID          string `json:"id"`
@@ -28,184 +47,394 @@ type Role struct {
 	ContainerID string `json:"containerId"`
 }
 
-// NewClient creates a new Keycloak admin client
+// User represents a Keycloak user as returned by the admin API
+type User struct {
+	ID              string   `json:"id"`
+	Username        string   `json:"username"`
+	Email           string   `json:"email"`
+	FirstName       string   `json:"firstName"`
+	LastName        string   `json:"lastName"`
+	Enabled         bool     `json:"enabled"`
+	EmailVerified   bool     `json:"emailVerified"`
+	Totp            bool     `json:"totp"` // OTP authenticator configured
+	RequiredActions []string `json:"requiredActions"`
+}
+
+// Required actions that can be requested via ExecuteActionsEmail
+const (
+	ActionConfigureTOTP  = "CONFIGURE_TOTP"
+	ActionVerifyEmail    = "VERIFY_EMAIL"
+	ActionUpdatePassword = "UPDATE_PASSWORD"
+)
+
+// APIError is returned when Keycloak responds with an unexpected status code
+type APIError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s - %s", e.Status, e.Body)
+}
+
+// NewClient creates a new Keycloak admin client using a fixed admin token
 func NewClient(cfg *config.Config, adminToken string) *Client {
+	return NewClientWithTokenProvider(cfg, staticToken(adminToken))
+}
+
+// NewClientWithTokenProvider creates a Keycloak admin client that asks the
+// provider for a token on every request and retries once after a 401
+func NewClientWithTokenProvider(cfg *config.Config, tokens TokenProvider) *Client {
 	return &Client{
 		config:     cfg,
-		adminToken: adminToken,
+		tokens:     tokens,
 		httpClient: &http.Client{},
 	}
 }
 
-// GetRealmRoles returns all realm roles
-func (c *Client) GetRealmRoles(ctx context.Context) ([]Role, error) {
-	url := fmt.Sprintf("%s/admin/realms/%s/roles", c.config.KeycloakURL, c.config.KeycloakRealm)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// do sends an authenticated request to the realm admin API. path is relative to
+// /admin/realms/{realm}; payload (if not nil) is sent as JSON and a successful
+// response body is decoded into out (if not nil).
+func (c *Client) do(ctx context.Context, method, path string, payload, out interface{}) error {
+	resp, err := c.request(ctx, method, path, payload)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer resp.Body.Close()
 
-	req.Header.Set("Authorization", "Bearer "+c.adminToken)
-	req.Header.Set("Content-Type", "application/json")
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// request is like do but returns the successful response; the caller must close its body
+func (c *Client) request(ctx context.Context, method, path string, payload interface{}) (*http.Response, error) {
+	endpoint := fmt.Sprintf("%s/admin/realms/%s%s", c.config.KeycloakURL, c.config.KeycloakRealm, path)
+
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.send(ctx, method, endpoint, data)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get roles: %s - %s", resp.Status, string(body))
+	// Token may have been revoked or expired early - get a fresh one and retry once
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		c.tokens.InvalidateToken()
+
+		if resp, err = c.send(ctx, method, endpoint, data); err != nil {
+			return nil, err
+		}
 	}
 
-	var roles []Role
-	if err := json.NewDecoder(resp.Body).Decode(&roles); err != nil {
-		return nil, err
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(respBody)}
 	}
 
-	return roles, nil
+	return resp, nil
 }
 
-// GetRoleByName gets a specific realm role by name
-func (c *Client) GetRoleByName(ctx context.Context, roleName string) (*Role, error) {
-	url := fmt.Sprintf("%s/admin/realms/%s/roles/%s", c.config.KeycloakURL, c.config.KeycloakRealm, roleName)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// send performs a single request with the current admin token
+func (c *Client) send(ctx context.Context, method, endpoint string, data []byte) (*http.Response, error) {
+	token, err := c.tokens.GetAccessToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.adminToken)
-	req.Header.Set("Content-Type", "application/json")
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
 
-	resp, err := c.httpClient.Do(req)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get role: %s - %s", resp.Status, string(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.httpClient.Do(req)
+}
+
+// GetUser returns a single user by Keycloak ID
+func (c *Client) GetUser(ctx context.Context, userID string) (*User, error) {
+	var user User
+	if err := c.do(ctx, "GET", "/users/"+userID, nil, &user); err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	return &user, nil
+}
 
-	var role Role
-	if err := json.NewDecoder(resp.Body).Decode(&role); err != nil {
-		return nil, err
+// ListUsers returns all users of the realm
+func (c *Client) ListUsers(ctx context.Context) ([]User, error) {
+	const pageSize = 100
+	var users []User
+
+	// Keycloak pages the users endpoint (default max is 100)
+	for first := 0; ; first += pageSize {
+		var page []User
+		path := fmt.Sprintf("/users?first=%d&max=%d", first, pageSize)
+		if err := c.do(ctx, "GET", path, nil, &page); err != nil {
+			return nil, fmt.Errorf("failed to list users: %w", err)
+		}
+
+		users = append(users, page...)
+
+		if len(page) < pageSize {
+			return users, nil
+		}
+	}
+}
+
+// FindUserByEmail returns the user with exactly this email, or nil if there is none
+func (c *Client) FindUserByEmail(ctx context.Context, email string) (*User, error) {
+	var users []User
+	path := "/users?exact=true&email=" + url.QueryEscape(email)
+	if err := c.do(ctx, "GET", path, nil, &users); err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
 
-	return &role, nil
+	for _, user := range users {
+		if strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+	return nil, nil
 }
 
-// GetUserRoles returns all realm roles assigned to a user
-func (c *Client) GetUserRoles(ctx context.Context, userID string) ([]Role, error) {
-	url := fmt.Sprintf("%s/admin/realms/%s/users/%s/role-mappings/realm",
-		c.config.KeycloakURL, c.config.KeycloakRealm, userID)
+// CreateUser creates a user and returns the new Keycloak user ID (user.ID and user.Totp are ignored)
+func (c *Client) CreateUser(ctx context.Context, user User) (string, error) {
+	payload := map[string]interface{}{
+		"username":        user.Username,
+		"email":           user.Email,
+		"firstName":       user.FirstName,
+		"lastName":        user.LastName,
+		"enabled":         user.Enabled,
+		"emailVerified":   user.EmailVerified,
+		"requiredActions": user.RequiredActions,
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	resp, err := c.request(ctx, "POST", "/users", payload)
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("failed to create user: %w", err)
 	}
+	resp.Body.Close()
 
-	req.Header.Set("Authorization", "Bearer "+c.adminToken)
-	req.Header.Set("Content-Type", "application/json")
+	// Keycloak returns the new user URL in the Location header (.../users/{id})
+	location := resp.Header.Get("Location")
+	id := location[strings.LastIndex(location, "/")+1:]
+	if id == "" {
+		return "", fmt.Errorf("failed to create user: missing Location header")
+	}
+	return id, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// ProvisionUser creates an enabled account for a portal member and emails them a link
+// to set their password. If an account with the email already exists, its ID is
+// returned with created=false and no email is sent.
+func (c *Client) ProvisionUser(ctx context.Context, email, username, realname string) (id string, created bool, err error) {
+	existing, err := c.FindUserByEmail(ctx, email)
 	if err != nil {
-		return nil, err
+		return "", false, err
+	}
+	if existing != nil {
+		return existing.ID, false, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get user roles: %s - %s", resp.Status, string(body))
+	if username == "" {
+		username = email
+	}
+	firstName, lastName, _ := strings.Cut(strings.TrimSpace(realname), " ")
+
+	id, err = c.CreateUser(ctx, User{
+		Username:        username,
+		Email:           email,
+		FirstName:       firstName,
+		LastName:        strings.TrimSpace(lastName),
+		Enabled:         true,
+		EmailVerified:   true, // address comes from the member records
+		RequiredActions: []string{ActionUpdatePassword},
+	})
+	if err != nil {
+		return "", false, err
 	}
 
-	var roles []Role
-	if err := json.NewDecoder(resp.Body).Decode(&roles); err != nil {
-		return nil, err
+	if err := c.ExecuteActionsEmail(ctx, id, []string{ActionUpdatePassword}); err != nil {
+		return id, true, fmt.Errorf("account created but %w", err)
+	}
+	return id, true, nil
+}
+
+// EnableUser enables a user account so they can log in
+func (c *Client) EnableUser(ctx context.Context, userID string) error {
+	return c.setUserEnabled(ctx, userID, true)
+}
+
+// DisableUser disables a user account; existing sessions stop working on refresh
+func (c *Client) DisableUser(ctx context.Context, userID string) error {
+	return c.setUserEnabled(ctx, userID, false)
+}
+
+func (c *Client) setUserEnabled(ctx context.Context, userID string, enabled bool) error {
+	// Partial representation - Keycloak only updates the fields that are present
+	payload := map[string]interface{}{"enabled": enabled}
+	if err := c.do(ctx, "PUT", "/users/"+userID, payload, nil); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// UpdateUserProfile overwrites the user's email and name
+func (c *Client) UpdateUserProfile(ctx context.Context, userID, email, firstName, lastName string) error {
+	payload := map[string]interface{}{
+		"email":     email,
+		"firstName": firstName,
+		"lastName":  lastName,
 	}
+	if err := c.do(ctx, "PUT", "/users/"+userID, payload, nil); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
 
+// GetRealmRoles returns all realm roles
+func (c *Client) GetRealmRoles(ctx context.Context) ([]Role, error) {
+	var roles []Role
+	if err := c.do(ctx, "GET", "/roles", nil, &roles); err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
 	return roles, nil
 }
 
-// AssignRoleToUser assigns a realm role to a user
-func (c *Client) AssignRoleToUser(ctx context.Context, userID, roleName string) error {
-	// First get the role details
-	role, err := c.GetRoleByName(ctx, roleName)
-	if err != nil {
-		return fmt.Errorf("failed to get role %s: %w", roleName, err)
+// GetRoleByName gets a specific realm role by name
+func (c *Client) GetRoleByName(ctx context.Context, roleName string) (*Role, error) {
+	var role Role
+	if err := c.do(ctx, "GET", "/roles/"+url.PathEscape(roleName), nil, &role); err != nil {
+		return nil, fmt.Errorf("failed to get role: %w", err)
 	}
+	return &role, nil
+}
 
-	url := fmt.Sprintf("%s/admin/realms/%s/users/%s/role-mappings/realm",
-		c.config.KeycloakURL, c.config.KeycloakRealm, userID)
+// GetUserRoles returns all realm roles assigned to a user
+func (c *Client) GetUserRoles(ctx context.Context, userID string) ([]Role, error) {
+	var roles []Role
+	if err := c.do(ctx, "GET", "/users/"+userID+"/role-mappings/realm", nil, &roles); err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	return roles, nil
+}
 
-	// Keycloak expects an array of role objects
-	rolePayload := []Role{*role}
-	body, err := json.Marshal(rolePayload)
-	if err != nil {
-		return err
+// GetRoleUserIDs returns IDs of all users that have the realm role assigned directly
+func (c *Client) GetRoleUserIDs(ctx context.Context, roleName string) ([]string, error) {
+	const pageSize = 100
+	var userIDs []string
+
+	// Keycloak pages the role members endpoint (default max is 100)
+	for first := 0; ; first += pageSize {
+		var users []struct {
+			ID string `json:"id"`
+		}
+		path := fmt.Sprintf("/roles/%s/users?first=%d&max=%d", url.PathEscape(roleName), first, pageSize)
+		if err := c.do(ctx, "GET", path, nil, &users); err != nil {
+			return nil, fmt.Errorf("failed to get role users: %w", err)
+		}
+
+		for _, u := range users {
+			userIDs = append(userIDs, u.ID)
+		}
+
+		if len(users) < pageSize {
+			return userIDs, nil
+		}
 	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(body)))
+// GetAllUserRoles returns realm role names of all users keyed by Keycloak user ID.
+// It makes one request per realm role instead of one per user; system roles are skipped.
+func (c *Client) GetAllUserRoles(ctx context.Context) (map[string][]string, error) {
+	roles, err := c.GetRealmRoles(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.adminToken)
-	req.Header.Set("Content-Type", "application/json")
+	userRoles := make(map[string][]string)
+	for _, role := range roles {
+		if IsSystemRole(role.Name) {
+			continue
+		}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
+		userIDs, err := c.GetRoleUserIDs(ctx, role.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get users of role %s: %w", role.Name, err)
+		}
+
+		for _, userID := range userIDs {
+			userRoles[userID] = append(userRoles[userID], role.Name)
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to assign role: %s - %s", resp.Status, string(respBody))
+	for _, names := range userRoles {
+		sort.Strings(names)
 	}
 
+	return userRoles, nil
+}
+
+// IsSystemRole reports whether a role is a Keycloak built-in (default roles, UMA, offline access)
+func IsSystemRole(name string) bool {
+	return strings.HasPrefix(name, "default-") ||
+		strings.HasPrefix(name, "uma_") ||
+		name == "offline_access"
+}
+
+// ExecuteActionsEmail makes Keycloak email the user a link to perform the given
+// required actions (e.g. ActionConfigureTOTP)
+func (c *Client) ExecuteActionsEmail(ctx context.Context, userID string, actions []string) error {
+	if err := c.do(ctx, "PUT", "/users/"+userID+"/execute-actions-email", actions, nil); err != nil {
+		return fmt.Errorf("failed to send actions email: %w", err)
+	}
 	return nil
 }
 
-// RemoveRoleFromUser removes a realm role from a user
-func (c *Client) RemoveRoleFromUser(ctx context.Context, userID, roleName string) error {
+// AssignRoleToUser assigns a realm role to a user
+func (c *Client) AssignRoleToUser(ctx context.Context, userID, roleName string) error {
 	// First get the role details
 	role, err := c.GetRoleByName(ctx, roleName)
 	if err != nil {
 		return fmt.Errorf("failed to get role %s: %w", roleName, err)
 	}
 
-	url := fmt.Sprintf("%s/admin/realms/%s/users/%s/role-mappings/realm",
-		c.config.KeycloakURL, c.config.KeycloakRealm, userID)
-
 	// Keycloak expects an array of role objects
-	rolePayload := []Role{*role}
-	body, err := json.Marshal(rolePayload)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "DELETE", url, strings.NewReader(string(body)))
-	if err != nil {
-		return err
+	if err := c.do(ctx, "POST", "/users/"+userID+"/role-mappings/realm", []Role{*role}, nil); err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.adminToken)
-	req.Header.Set("Content-Type", "application/json")
+	return nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// RemoveRoleFromUser removes a realm role from a user
+func (c *Client) RemoveRoleFromUser(ctx context.Context, userID, roleName string) error {
+	// First get the role details
+	role, err := c.GetRoleByName(ctx, roleName)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get role %s: %w", roleName, err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to remove role: %s - %s", resp.Status, string(respBody))
+	// Keycloak expects an array of role objects
+	if err := c.do(ctx, "DELETE", "/users/"+userID+"/role-mappings/realm", []Role{*role}, nil); err != nil {
+		return fmt.Errorf("failed to remove role: %w", err)
 	}
 
 	return nil
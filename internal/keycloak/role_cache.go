@@ -0,0 +1,122 @@
+package keycloak
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// RoleCache keeps the user→roles mapping of the whole realm in memory so that
+// user lists don't hit Keycloak once per user. Entries are reloaded on access
+// after the TTL expires, or periodically by the background refresher started
+// with Start. It is safe for concurrent use.
+type RoleCache struct {
+	ttl time.Duration
+
+	// loadMu serializes Keycloak fetches; mu guards the fields below and is not
+	// held during a fetch, so requests served from the cache never wait for Keycloak
+	loadMu sync.Mutex
+
+	mu        sync.Mutex
+	roles     map[string][]string
+	fetchedAt time.Time
+	version   int // bumped by Invalidate
+}
+
+// NewRoleCache creates a role cache; entries older than ttl are reloaded on access
+func NewRoleCache(ttl time.Duration) *RoleCache {
+	return &RoleCache{ttl: ttl}
+}
+
+// Get returns role names keyed by Keycloak user ID, loading them through the
+// client when the cache is empty or expired. The returned map must not be modified.
+func (rc *RoleCache) Get(ctx context.Context, c *Client) (map[string][]string, error) {
+	if roles, ok := rc.cached(); ok {
+		return roles, nil
+	}
+
+	rc.loadMu.Lock()
+	defer rc.loadMu.Unlock()
+
+	// Another request may have loaded the roles while this one waited
+	if roles, ok := rc.cached(); ok {
+		return roles, nil
+	}
+	return rc.fetch(ctx, c)
+}
+
+// Refresh reloads roles from Keycloak immediately
+func (rc *RoleCache) Refresh(ctx context.Context, c *Client) error {
+	rc.loadMu.Lock()
+	defer rc.loadMu.Unlock()
+
+	_, err := rc.fetch(ctx, c)
+	return err
+}
+
+// Invalidate drops cached roles, e.g. after a role was assigned or removed
+func (rc *RoleCache) Invalidate() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.roles = nil
+	rc.version++
+}
+
+// Start refreshes the cache every TTL in the background until ctx is cancelled.
+// Failed refreshes are logged and the previous data is kept. Without a positive
+// TTL there is no background refresh.
+func (rc *RoleCache) Start(ctx context.Context, c *Client) {
+	if rc.ttl <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(rc.ttl)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := rc.Refresh(ctx, c); err != nil {
+					log.Printf("[keycloak] Background role cache refresh failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// cached returns the cached roles and whether they are still fresh
+func (rc *RoleCache) cached() (map[string][]string, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return rc.roles, rc.roles != nil && time.Since(rc.fetchedAt) < rc.ttl
+}
+
+// fetch loads roles from Keycloak and swaps them in; the caller holds loadMu.
+// When Invalidate ran during the fetch, the result may miss that change and is
+// kept as already expired, so the next Get loads the roles again.
+func (rc *RoleCache) fetch(ctx context.Context, c *Client) (map[string][]string, error) {
+	rc.mu.Lock()
+	version := rc.version
+	rc.mu.Unlock()
+
+	roles, err := c.GetAllUserRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.roles = roles
+	rc.fetchedAt = time.Now()
+	if rc.version != version {
+		rc.fetchedAt = time.Time{}
+	}
+	return roles, nil
+}
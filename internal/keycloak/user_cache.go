@@ -0,0 +1,148 @@
+package keycloak
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// UserCache keeps the realm user list in memory, keyed by Keycloak user ID.
+// Entries are reloaded on access after the TTL expires, or periodically by
+// the background refresher started with Start. It is safe for concurrent use.
+type UserCache struct {
+	ttl  time.Duration
+	load func(ctx context.Context) ([]User, error)
+
+	// loadMu serializes Keycloak fetches; mu guards the fields below and is not
+	// held during a fetch, so requests served from the cache never wait for Keycloak
+	loadMu sync.Mutex
+
+	mu        sync.Mutex
+	users     map[string]User
+	fetchedAt time.Time
+	version   int // bumped by Invalidate and Update
+}
+
+// NewUserCache creates a user cache; load is called to fetch users from Keycloak
+// (typically a service-account client's ListUsers)
+func NewUserCache(ttl time.Duration, load func(ctx context.Context) ([]User, error)) *UserCache {
+	return &UserCache{ttl: ttl, load: load}
+}
+
+// Get returns all users keyed by ID, loading them when the cache is empty or
+// expired. The returned map must not be modified.
+func (uc *UserCache) Get(ctx context.Context) (map[string]User, error) {
+	if users, ok := uc.cached(); ok {
+		return users, nil
+	}
+
+	uc.loadMu.Lock()
+	defer uc.loadMu.Unlock()
+
+	// Another request may have loaded the users while this one waited
+	if users, ok := uc.cached(); ok {
+		return users, nil
+	}
+	return uc.fetch(ctx)
+}
+
+// Refresh reloads users from Keycloak immediately
+func (uc *UserCache) Refresh(ctx context.Context) error {
+	uc.loadMu.Lock()
+	defer uc.loadMu.Unlock()
+
+	_, err := uc.fetch(ctx)
+	return err
+}
+
+// Invalidate drops cached users so the next Get reloads them
+func (uc *UserCache) Invalidate() {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.users = nil
+	uc.version++
+}
+
+// Update replaces a single cached user after a change made through the admin API,
+// so the change is visible without reloading the whole realm
+func (uc *UserCache) Update(user User) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if uc.users == nil {
+		return
+	}
+
+	// Maps returned by Get may still be in use - copy instead of modifying in place
+	userMap := make(map[string]User, len(uc.users))
+	for id, u := range uc.users {
+		userMap[id] = u
+	}
+	userMap[user.ID] = user
+	uc.users = userMap
+	uc.version++
+}
+
+// Start refreshes the cache every TTL in the background until ctx is cancelled.
+// Failed refreshes are logged and the previous data is kept. Without a positive
+// TTL there is no background refresh.
+func (uc *UserCache) Start(ctx context.Context) {
+	if uc.ttl <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(uc.ttl)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := uc.Refresh(ctx); err != nil {
+					log.Printf("[keycloak] Background user cache refresh failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// cached returns the cached users and whether they are still fresh
+func (uc *UserCache) cached() (map[string]User, bool) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	return uc.users, uc.users != nil && time.Since(uc.fetchedAt) < uc.ttl
+}
+
+// fetch loads users from Keycloak and swaps them in; the caller holds loadMu.
+// When Invalidate or Update ran during the fetch, the result may miss that change
+// and is kept as already expired, so the next Get loads the users again.
+func (uc *UserCache) fetch(ctx context.Context) (map[string]User, error) {
+	uc.mu.Lock()
+	version := uc.version
+	uc.mu.Unlock()
+
+	users, err := uc.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userMap := make(map[string]User, len(users))
+	for _, user := range users {
+		userMap[user.ID] = user
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.users = userMap
+	uc.fetchedAt = time.Now()
+	if uc.version != version {
+		uc.fetchedAt = time.Time{}
+	}
+	return userMap, nil
+}
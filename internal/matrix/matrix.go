@@ -0,0 +1,81 @@
+// Package matrix posts messages to a Matrix room through the client-server API.
+// Only sending plain text messages is needed, so no SDK is used.
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client sends messages to one room as the bot user of the access token
+type Client struct {
+	homeserver  string
+	accessToken string
+	roomID      string
+	httpClient  *http.Client
+}
+
+// NewClient creates a client; the bot user must already be a member of the room
+func NewClient(homeserver, accessToken, roomID string) *Client {
+	return &Client{
+		homeserver:  strings.TrimRight(homeserver, "/"),
+		accessToken: accessToken,
+		roomID:      roomID,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendText posts a plain text message (m.text) to the room
+func (c *Client) SendText(ctx context.Context, text string) error {
+	// The transaction ID makes retries of the same request idempotent
+	txn := make([]byte, 16)
+	if _, err := rand.Read(txn); err != nil {
+		return fmt.Errorf("failed to generate transaction ID: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		c.homeserver, url.PathEscape(c.roomID), hex.EncodeToString(txn))
+
+	body, err := json.Marshal(map[string]string{
+		"msgtype": "m.text",
+		"body":    text,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("matrix request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var matrixErr struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &matrixErr) == nil && matrixErr.ErrCode != "" {
+			return fmt.Errorf("matrix: %s: %s", matrixErr.ErrCode, matrixErr.Error)
+		}
+		return fmt.Errorf("matrix: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package milestone detects membership milestones worth a congratulation:
+// anniversaries of joining and the 100th payment.
+package milestone
+
+import (
+	"fmt"
+	"time"
+)
+
+// Notification preferences a member can switch off in the profile
+const (
+	NotificationEmail    = "milestone_email"    // congratulation email
+	NotificationAnnounce = "milestone_announce" // mention in the community Matrix room
+)
+
+// PaymentCount is the payment milestone
+const PaymentCount = 100
+
+// Kind of milestone
+const (
+	KindAnniversary = "anniversary"
+	KindPayments    = "payments"
+)
+
+// Milestone is a milestone reached by a member
+type Milestone struct {
+	Kind  string
+	Count int // years of membership or number of payments
+}
+
+// ID identifies the milestone in member_milestones (anniversary_3, payments_100)
+func (m Milestone) ID() string {
+	return fmt.Sprintf("%s_%d", m.Kind, m.Count)
+}
+
+// Anniversary returns the anniversary of joining that fell within the last
+// window days up to today. Only the latest anniversary is considered, so a
+// missed run catches up but the first run does not congratulate on old years.
+// Members who joined on 29 February celebrate on 28 February in common years.
+func Anniversary(joined, today time.Time, window int) (Milestone, bool) {
+	joined = dateOf(joined)
+	today = dateOf(today)
+
+	years := today.Year() - joined.Year()
+	if anniversaryDate(joined, today.Year()).After(today) {
+		years--
+	}
+	if years < 1 {
+		return Milestone{}, false
+	}
+
+	date := anniversaryDate(joined, joined.Year()+years)
+	if today.Sub(date) >= time.Duration(window)*24*time.Hour {
+		return Milestone{}, false
+	}
+	return Milestone{Kind: KindAnniversary, Count: years}, true
+}
+
+// Payments reports the payment milestone when it was reached by payments
+// received recently: total payments in all, recent of them since the window start
+func Payments(total, recent int64) (Milestone, bool) {
+	if total >= PaymentCount && total-recent < PaymentCount {
+		return Milestone{Kind: KindPayments, Count: PaymentCount}, true
+	}
+	return Milestone{}, false
+}
+
+// anniversaryDate is the anniversary of joined in the given year
+func anniversaryDate(joined time.Time, year int) time.Time {
+	day := joined.Day()
+	if joined.Month() == time.February && day == 29 && !isLeap(year) {
+		day = 28
+	}
+	return time.Date(year, joined.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
@@ -0,0 +1,140 @@
+// Package money holds amounts in integer minor units (haléře), so sums and
+// comparisons of payments, fees and balances are exact. Amounts are stored in
+// INTEGER columns and shown with two decimals ("450.00").
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount is an amount of money in haléře (1/100 CZK)
+type Amount int64
+
+// FromFloat converts an amount in crowns, rounded to haléře
+func FromFloat(crowns float64) Amount {
+	return Amount(math.Round(crowns * 100))
+}
+
+// Parse reads an amount in crowns: "450", "450.5", "-1 234,50". At most two
+// decimals are allowed.
+func Parse(s string) (Amount, error) {
+	clean := strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(strings.TrimSpace(s))
+	negative := strings.HasPrefix(clean, "-")
+	clean = strings.TrimPrefix(strings.TrimPrefix(clean, "-"), "+")
+
+	whole, fraction, hasFraction := strings.Cut(clean, ".")
+	if whole == "" && (!hasFraction || fraction == "") || len(fraction) > 2 || !digits(whole) || !digits(fraction) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	var amount int64
+	if whole != "" {
+		crowns, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil || crowns > math.MaxInt64/100 {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+		amount = crowns * 100
+	}
+	if fraction != "" {
+		hellers, _ := strconv.ParseInt((fraction + "0")[:2], 10, 64)
+		amount += hellers
+	}
+	if negative {
+		amount = -amount
+	}
+	return Amount(amount), nil
+}
+
+// digits reports whether s has only ASCII digits
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Float64 returns the amount in crowns
+func (a Amount) Float64() float64 {
+	return float64(a) / 100
+}
+
+// Abs returns the absolute value
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// String formats the amount in crowns with two decimals, "-1234.50"
+func (a Amount) String() string {
+	sign := ""
+	if a < 0 {
+		sign = "-"
+	}
+	abs := uint64(a)
+	if a < 0 {
+		abs = uint64(-a)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, abs/100, abs%100)
+}
+
+// MarshalJSON writes the amount as a decimal string ("450.00"), the format the
+// API used when amounts were stored as text
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON accepts a decimal string or a number of crowns
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		amount, err := Parse(s)
+		if err != nil {
+			return err
+		}
+		*a = amount
+		return nil
+	}
+	var crowns float64
+	if err := json.Unmarshal(data, &crowns); err != nil {
+		return fmt.Errorf("invalid amount %s", data)
+	}
+	*a = FromFloat(crowns)
+	return nil
+}
+
+// Scan implements sql.Scanner for INTEGER columns with haléře
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+	case int64:
+		*a = Amount(v)
+	case float64:
+		*a = Amount(math.Round(v)) // SUM over REAL expressions
+	case []byte:
+		return a.Scan(string(v))
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("money: cannot scan %q into Amount", v)
+		}
+		*a = Amount(n)
+	default:
+		return fmt.Errorf("money: cannot scan %T into Amount", src)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (a Amount) Value() (driver.Value, error) {
+	return int64(a), nil
+}
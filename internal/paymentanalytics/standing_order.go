@@ -0,0 +1,142 @@
+// Package paymentanalytics analyzes members' payment history. It recognizes
+// standing orders (regular monthly payments of the same amount) so a member whose
+// order stops arriving can be reminded before the debt grows.
+package paymentanalytics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/base48/member-portal/internal/money"
+)
+
+// Detection parameters
+const (
+	// MinPayments is how many regular payments in a row make a standing order
+	MinPayments = 3
+	// MinInterval and MaxInterval bound the days between two monthly payments;
+	// banks shift orders falling on weekends and holidays by a few days
+	MinInterval = 25
+	MaxInterval = 35
+	// AmountTolerance is the relative difference of amounts still counted as the
+	// same order (percent), e.g. a member rounding a changed fee up
+	AmountTolerance = 5
+)
+
+// Payment is an incoming membership payment
+type Payment struct {
+	Date   time.Time
+	Amount money.Amount
+}
+
+// StandingOrder is a recurring monthly payment recognized in the history
+type StandingOrder struct {
+	Amount      money.Amount // median amount of the order's payments
+	DayOfMonth  int          // median day of month the payments arrive
+	Payments    int          // payments in the recognized run
+	LastPayment time.Time
+}
+
+// DetectStandingOrder looks for a standing order in the history: a run of payments
+// of about the same amount (amount clustering) that arrived in monthly intervals
+// (interval clustering). Other payments in between or after, like debt repayments
+// or donations, do not break the run. The most recent run is returned.
+func DetectStandingOrder(payments []Payment) (StandingOrder, bool) {
+	sorted := make([]Payment, 0, len(payments))
+	for _, p := range payments {
+		if p.Amount > 0 {
+			sorted = append(sorted, p)
+		}
+	}
+	if len(sorted) < MinPayments {
+		return StandingOrder{}, false
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	for last := len(sorted) - 1; last >= MinPayments-1; last-- {
+		if run := monthlyRun(sorted[:last+1]); len(run) >= MinPayments {
+			return standingOrder(run), true
+		}
+	}
+	return StandingOrder{}, false
+}
+
+// monthlyRun walks back from the last payment over payments of a similar amount
+// while they arrived in monthly intervals; the run is newest first
+func monthlyRun(sorted []Payment) []Payment {
+	run := []Payment{sorted[len(sorted)-1]}
+	for i := len(sorted) - 2; i >= 0; i-- {
+		if !similarAmount(sorted[i].Amount, run[0].Amount) {
+			continue
+		}
+		days := daysBetween(sorted[i].Date, run[len(run)-1].Date)
+		if days < MinInterval {
+			continue // e.g. a second payment in the same month
+		}
+		if days > MaxInterval {
+			break
+		}
+		run = append(run, sorted[i])
+	}
+	return run
+}
+
+func standingOrder(run []Payment) StandingOrder {
+	amounts := make([]int64, len(run))
+	days := make([]int64, len(run))
+	for i, p := range run {
+		amounts[i] = int64(p.Amount)
+		days[i] = int64(p.Date.Day())
+	}
+	return StandingOrder{
+		Amount:      money.Amount(median(amounts)),
+		DayOfMonth:  int(median(days)),
+		Payments:    len(run),
+		LastPayment: run[0].Date,
+	}
+}
+
+// NextDue is the day the next payment of the order is expected: the order's day
+// of month in the month after the last payment (last day for shorter months)
+func (o StandingOrder) NextDue() time.Time {
+	last := dateOf(o.LastPayment)
+	year, month := last.Year(), last.Month()+1
+	if month > time.December {
+		year, month = year+1, time.January
+	}
+	day := o.DayOfMonth
+	if lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day(); day > lastDay {
+		day = lastDay
+	}
+	due := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	// A payment that arrived late (e.g. on the 30th for an order due on the 2nd)
+	// must not make the next one due within days
+	if due.Before(last) || daysBetween(last, due) < MinInterval {
+		due = last.AddDate(0, 0, MinInterval)
+	}
+	return due
+}
+
+// Missed reports whether the next payment is more than graceDays late on today
+func (o StandingOrder) Missed(today time.Time, graceDays int) bool {
+	return daysBetween(o.NextDue(), dateOf(today)) > graceDays
+}
+
+func similarAmount(a, b money.Amount) bool {
+	diff := (a - b).Abs()
+	return diff*100 <= b.Abs()*AmountTolerance
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(dateOf(to).Sub(dateOf(from)).Hours() / 24)
+}
+
+func median(values []int64) int64 {
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
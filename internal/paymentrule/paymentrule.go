@@ -0,0 +1,127 @@
+// Package paymentrule matches incoming payments with a wrong or missing VS to a
+// member or a project by admin-defined rules (payment_match_rules)
+package paymentrule
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/base48/member-portal/internal/db"
+)
+
+// DefaultPriority is used for rules created without a priority (remembered accounts)
+const DefaultPriority = 100
+
+// MaxPatternLength limits the message regexp entered by an admin
+const MaxPatternLength = 200
+
+// Payment holds the fields of an incoming payment the rules look at
+type Payment struct {
+	RemoteAccount  string // "account/bank code"
+	Message        string // message for the recipient
+	SpecificSymbol string
+	Amount         float64
+}
+
+// Rule is an active payment_match_rules row with the compiled message pattern
+type Rule struct {
+	db.PaymentMatchRule
+	message *regexp.Regexp
+}
+
+// Validate checks a rule before it is saved: exactly one target, at least one
+// condition (a rule without conditions would take every unmatched payment),
+// a valid message regexp and a sane amount range
+func Validate(rule db.PaymentMatchRule) error {
+	if rule.UserID.Valid == rule.ProjectID.Valid {
+		return errors.New("exactly one of user_id and project_id is required")
+	}
+	if rule.RemoteAccount == "" && rule.MessagePattern == "" && rule.SpecificSymbol == "" &&
+		!rule.AmountMin.Valid && !rule.AmountMax.Valid {
+		return errors.New("rule needs at least one condition")
+	}
+	if len(rule.MessagePattern) > MaxPatternLength {
+		return fmt.Errorf("message_pattern is longer than %d characters", MaxPatternLength)
+	}
+	if _, err := regexp.Compile(rule.MessagePattern); err != nil {
+		return fmt.Errorf("invalid message_pattern: %w", err)
+	}
+	if rule.AmountMin.Valid && rule.AmountMax.Valid && rule.AmountMin.Float64 > rule.AmountMax.Float64 {
+		return errors.New("amount_min is greater than amount_max")
+	}
+	return nil
+}
+
+// Compile prepares rules for matching, keeping their order (priority). Invalid rules
+// are skipped and returned as an error, so one broken rule does not stop the others.
+func Compile(rows []db.PaymentMatchRule) ([]Rule, error) {
+	rules := make([]Rule, 0, len(rows))
+	var errs []error
+	for _, row := range rows {
+		if err := Validate(row); err != nil {
+			errs = append(errs, fmt.Errorf("rule #%d: %w", row.ID, err))
+			continue
+		}
+		rule := Rule{PaymentMatchRule: row}
+		if row.MessagePattern != "" {
+			rule.message = regexp.MustCompile(row.MessagePattern)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, errors.Join(errs...)
+}
+
+// Matches reports whether the payment meets all conditions of the rule
+func (r Rule) Matches(p Payment) bool {
+	if r.RemoteAccount != "" && !strings.EqualFold(strings.TrimSpace(p.RemoteAccount), r.RemoteAccount) {
+		return false
+	}
+	if r.SpecificSymbol != "" && strings.TrimSpace(p.SpecificSymbol) != r.SpecificSymbol {
+		return false
+	}
+	if r.AmountMin.Valid && p.Amount < r.AmountMin.Float64 {
+		return false
+	}
+	if r.AmountMax.Valid && p.Amount > r.AmountMax.Float64 {
+		return false
+	}
+	if r.message != nil && !r.message.MatchString(p.Message) {
+		return false
+	}
+	return true
+}
+
+// First returns the first rule (by priority) matching the payment
+func First(rules []Rule, p Payment) (Rule, bool) {
+	for _, rule := range rules {
+		if rule.Matches(p) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Describe summarizes the conditions of a rule for logs and the admin UI
+func Describe(rule db.PaymentMatchRule) string {
+	var parts []string
+	if rule.RemoteAccount != "" {
+		parts = append(parts, "účet "+rule.RemoteAccount)
+	}
+	if rule.MessagePattern != "" {
+		parts = append(parts, "zpráva ~ /"+rule.MessagePattern+"/")
+	}
+	if rule.SpecificSymbol != "" {
+		parts = append(parts, "SS "+rule.SpecificSymbol)
+	}
+	switch {
+	case rule.AmountMin.Valid && rule.AmountMax.Valid:
+		parts = append(parts, fmt.Sprintf("%.0f-%.0f Kč", rule.AmountMin.Float64, rule.AmountMax.Float64))
+	case rule.AmountMin.Valid:
+		parts = append(parts, fmt.Sprintf("≥ %.0f Kč", rule.AmountMin.Float64))
+	case rule.AmountMax.Valid:
+		parts = append(parts, fmt.Sprintf("≤ %.0f Kč", rule.AmountMax.Float64))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,175 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Document is a minimal PDF writer for simple generated documents (invoices,
+// receipts): text in Helvetica, lines, rectangles and grayscale images.
+// Coordinates are in points measured from the top left corner of the page.
+//
+// Text uses the standard WinAnsi encoding, so no fonts need to be embedded.
+// Characters outside it (e.g. č, ř, ě) are written without diacritics.
+type Document struct {
+	pages  []*bytes.Buffer
+	images []pdfImage
+}
+
+type pdfImage struct {
+	width, height int
+	data          []byte // zlib-compressed 8-bit grayscale
+}
+
+// New creates an empty document; call AddPage before drawing
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage starts a new A4 page; drawing calls go to the last page
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *Document) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// Text draws s with its baseline at (x, y)
+func (d *Document) Text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page(), "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, PageHeight-y, encodeText(s))
+}
+
+// TextRight draws s so that it ends at x (for amounts in table columns)
+func (d *Document) TextRight(x, y, size float64, bold bool, s string) {
+	d.Text(x-TextWidth(s, size, bold), y, size, bold, s)
+}
+
+// Line draws a line of the given width
+func (d *Document) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.page(), "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// Rect draws a rectangle outline, or fills it with a gray level (0 = black, 1 = white)
+func (d *Document) Rect(x, y, w, h float64, fill bool, gray float64) {
+	if fill {
+		fmt.Fprintf(d.page(), "%.2f g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x, PageHeight-y-h, w, h)
+		return
+	}
+	fmt.Fprintf(d.page(), "%.2f G 0.5 w %.2f %.2f %.2f %.2f re S 0 G\n", gray, x, PageHeight-y-h, w, h)
+}
+
+// Image draws img converted to grayscale into the box at (x, y) with size w x h
+func (d *Document) Image(x, y, w, h float64, img image.Image) error {
+	bounds := img.Bounds()
+	gray := make([]byte, 0, bounds.Dx()*bounds.Dy())
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			gray = append(gray, color.GrayModel.Convert(img.At(px, py)).(color.Gray).Y)
+		}
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(gray); err != nil {
+		return fmt.Errorf("failed to compress image: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress image: %w", err)
+	}
+
+	d.images = append(d.images, pdfImage{width: bounds.Dx(), height: bounds.Dy(), data: compressed.Bytes()})
+	fmt.Fprintf(d.page(), "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, PageHeight-y-h, len(d.images))
+	return nil
+}
+
+// WriteTo writes the finished PDF
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+
+	// Objects are numbered: 1 catalog, 2 pages, 3-4 fonts, then images,
+	// then a page + content stream pair for every page
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			out.WriteString("stream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream\n")
+		}
+		out.WriteString("endobj\n")
+	}
+
+	firstImage := 5
+	firstPage := firstImage + len(d.images)
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)), nil)
+
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>", nil)
+
+	xobjects := make([]string, len(d.images))
+	for i, img := range d.images {
+		object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>",
+			img.width, img.height, len(img.data)), img.data)
+		xobjects[i] = fmt.Sprintf("/Im%d %d 0 R", i+1, firstImage+i)
+	}
+
+	resources := "/Font << /F1 3 0 R /F2 4 0 R >>"
+	if len(xobjects) > 0 {
+		resources += " /XObject << " + strings.Join(xobjects, " ") + " >>"
+	}
+
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << %s >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, resources, firstPage+2*i+1), nil)
+		object(fmt.Sprintf("<< /Length %d >>", content.Len()), content.Bytes())
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.WriteTo(w)
+}
+
+// Bytes returns the finished PDF
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	return buf.Bytes()
+}
@@ -0,0 +1,90 @@
+package pdf
+
+import "strings"
+
+// winAnsiSpecial maps characters outside Latin-1 that WinAnsiEncoding (CP1252) has
+var winAnsiSpecial = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, 'Š': 0x8A, 'š': 0x9A, 'Ž': 0x8E,
+	'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// foldDiacritics maps accented letters to their base letter. It is used for
+// characters WinAnsi cannot encode and for width lookups.
+var foldDiacritics = map[rune]rune{
+	'á': 'a', 'ä': 'a', 'č': 'c', 'ď': 'd', 'é': 'e', 'ě': 'e', 'í': 'i', 'ĺ': 'l',
+	'ľ': 'l', 'ň': 'n', 'ó': 'o', 'ô': 'o', 'ö': 'o', 'ŕ': 'r', 'ř': 'r', 'š': 's',
+	'ť': 't', 'ú': 'u', 'ů': 'u', 'ü': 'u', 'ý': 'y', 'ž': 'z',
+	'Á': 'A', 'Ä': 'A', 'Č': 'C', 'Ď': 'D', 'É': 'E', 'Ě': 'E', 'Í': 'I', 'Ĺ': 'L',
+	'Ľ': 'L', 'Ň': 'N', 'Ó': 'O', 'Ô': 'O', 'Ö': 'O', 'Ŕ': 'R', 'Ř': 'R', 'Š': 'S',
+	'Ť': 'T', 'Ú': 'U', 'Ů': 'U', 'Ü': 'U', 'Ý': 'Y', 'Ž': 'Z',
+}
+
+// encodeText converts s to WinAnsi and escapes it for a PDF string literal
+func encodeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		var c byte
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			c = byte(r)
+		case r < 0x20:
+			c = ' '
+		case r < 0x7F || (r >= 0xA0 && r <= 0xFF):
+			c = byte(r)
+		default:
+			if special, ok := winAnsiSpecial[r]; ok {
+				c = special
+			} else if base, ok := foldDiacritics[r]; ok {
+				c = byte(base)
+			} else {
+				c = '?'
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// Helvetica and Helvetica-Bold advance widths (1/1000 em) of ASCII 32-126
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// TextWidth returns the width of s in points. Accented letters count as their
+// base letter, other characters outside ASCII as an average glyph.
+func TextWidth(s string, size float64, bold bool) float64 {
+	widths := &helveticaWidths
+	if bold {
+		widths = &helveticaBoldWidths
+	}
+
+	total := 0
+	for _, r := range s {
+		if base, ok := foldDiacritics[r]; ok {
+			r = base
+		}
+		if r >= 32 && r <= 126 {
+			total += widths[r-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
@@ -0,0 +1,129 @@
+package qrpay
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EPC069-12 ("Girocode", SEPA credit transfer QR) support for members banking
+// outside Czechia, whose apps do not read SPAYD.
+// See: https://www.europeanpaymentscouncil.eu/document-library/guidance-documents/quick-response-code-guidelines-enable-data-capture-initiation
+
+// EPCParams holds the parameters for generating an EPC QR payload.
+type EPCParams struct {
+	// BIC is the beneficiary bank's BIC (optional in version 002).
+	BIC string
+	// Name is the beneficiary name (required), max 70 chars.
+	Name string
+	// IBAN is the beneficiary account (required).
+	IBAN string
+	// Amount is the amount in EUR, the only currency of SEPA transfers.
+	// Zero means no amount specified (the payer fills it in).
+	Amount float64
+	// Purpose is the optional 4-letter purpose code (e.g. "GDDS").
+	Purpose string
+	// Reference is the structured creditor reference (RF...); excludes Text.
+	Reference string
+	// Text is the unstructured remittance information, max 140 chars.
+	Text string
+}
+
+// GenerateEPC creates an EPC QR payload (version 002, UTF-8, SEPA credit transfer).
+// Fields are separated by newlines; trailing empty fields are omitted.
+//
+// Example output:
+//
+//	BCD
+//	002
+//	1
+//	SCT
+//	FIOBCZPP
+//	Base48, z.s.
+//	CZ6520100000002900000001
+//
+//
+//
+//	/VS1234567890/ CLENSKY PRISPEVEK BASE48
+func GenerateEPC(p EPCParams) string {
+	amount := ""
+	if p.Amount > 0 {
+		amount = fmt.Sprintf("EUR%.2f", p.Amount)
+	}
+
+	text := ""
+	if p.Reference == "" {
+		text = truncateRunes(epcValue(p.Text), 140)
+	}
+
+	lines := []string{
+		"BCD",
+		"002",
+		"1", // UTF-8
+		"SCT",
+		strings.ToUpper(strings.TrimSpace(p.BIC)),
+		truncateRunes(epcValue(p.Name), 70),
+		strings.ToUpper(strings.ReplaceAll(p.IBAN, " ", "")),
+		amount,
+		strings.ToUpper(strings.TrimSpace(p.Purpose)),
+		strings.TrimSpace(p.Reference),
+		text,
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// epcValue removes newlines, which would shift the following fields
+func epcValue(s string) string {
+	s = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+	return strings.TrimSpace(s)
+}
+
+// truncateRunes cuts s to maxLen characters without splitting a UTF-8 sequence.
+func truncateRunes(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) > maxLen {
+		return string(runes[:maxLen])
+	}
+	return s
+}
+
+// ParseEPC parses an EPC QR payload back into EPCParams.
+// This is useful for validation and testing.
+func ParseEPC(payload string) (*EPCParams, error) {
+	lines := strings.Split(payload, "\n")
+	if len(lines) < 7 || lines[0] != "BCD" || lines[3] != "SCT" {
+		return nil, fmt.Errorf("invalid EPC header")
+	}
+	if lines[1] != "001" && lines[1] != "002" {
+		return nil, fmt.Errorf("unsupported EPC version %q", lines[1])
+	}
+
+	field := func(i int) string {
+		if i < len(lines) {
+			return lines[i]
+		}
+		return ""
+	}
+
+	params := &EPCParams{
+		BIC:       field(4),
+		Name:      field(5),
+		IBAN:      field(6),
+		Purpose:   field(8),
+		Reference: field(9),
+		Text:      field(10),
+	}
+	if amount := field(7); amount != "" {
+		if !strings.HasPrefix(amount, "EUR") {
+			return nil, fmt.Errorf("unsupported EPC currency in %q", amount)
+		}
+		if _, err := fmt.Sscanf(amount[3:], "%f", &params.Amount); err != nil {
+			return nil, fmt.Errorf("invalid EPC amount %q", amount)
+		}
+	}
+
+	return params, nil
+}
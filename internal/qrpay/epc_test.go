@@ -0,0 +1,129 @@
+package qrpay
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateEPC(t *testing.T) {
+	tests := []struct {
+		name   string
+		params EPCParams
+		want   string
+	}{
+		{
+			name: "full payment",
+			params: EPCParams{
+				BIC:    "FIOBCZPP",
+				Name:   "Base48, z.s.",
+				IBAN:   "CZ42 2010 0000 0029 0008 6515",
+				Amount: 18.50,
+				Text:   "/VS1234/ Clensky prispevek",
+			},
+			want: "BCD\n002\n1\nSCT\nFIOBCZPP\nBase48, z.s.\nCZ4220100000002900086515\nEUR18.50\n\n\n/VS1234/ Clensky prispevek",
+		},
+		{
+			name: "no amount, no text",
+			params: EPCParams{
+				Name: "Base48, z.s.",
+				IBAN: "CZ4220100000002900086515",
+			},
+			want: "BCD\n002\n1\nSCT\n\nBase48, z.s.\nCZ4220100000002900086515",
+		},
+		{
+			name: "reference excludes text",
+			params: EPCParams{
+				Name:      "Base48",
+				IBAN:      "CZ4220100000002900086515",
+				Reference: "RF18539007547034",
+				Text:      "ignored",
+			},
+			want: "BCD\n002\n1\nSCT\n\nBase48\nCZ4220100000002900086515\n\n\nRF18539007547034",
+		},
+		{
+			name: "newlines in text",
+			params: EPCParams{
+				Name: "Base48",
+				IBAN: "CZ4220100000002900086515",
+				Text: "line one\nline two",
+			},
+			want: "BCD\n002\n1\nSCT\n\nBase48\nCZ4220100000002900086515\n\n\n\nline one line two",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GenerateEPC(tt.params); got != tt.want {
+				t.Errorf("GenerateEPC() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateEPCLimits(t *testing.T) {
+	result := GenerateEPC(EPCParams{
+		Name: strings.Repeat("Ž", 100),
+		IBAN: "CZ4220100000002900086515",
+		Text: strings.Repeat("č", 200),
+	})
+
+	params, err := ParseEPC(result)
+	if err != nil {
+		t.Fatalf("ParseEPC() error = %v", err)
+	}
+	if n := len([]rune(params.Name)); n != 70 {
+		t.Errorf("Name has %d chars, want 70", n)
+	}
+	if n := len([]rune(params.Text)); n != 140 {
+		t.Errorf("Text has %d chars, want 140", n)
+	}
+}
+
+func TestParseEPC(t *testing.T) {
+	// Test roundtrip: generate then parse
+	original := EPCParams{
+		BIC:    "FIOBCZPP",
+		Name:   "Base48, z.s.",
+		IBAN:   "CZ4220100000002900086515",
+		Amount: 20.00,
+		Text:   "/VS1234567890/ TEST",
+	}
+
+	params, err := ParseEPC(GenerateEPC(original))
+	if err != nil {
+		t.Fatalf("ParseEPC() error = %v", err)
+	}
+	if *params != original {
+		t.Errorf("ParseEPC() = %+v, want %+v", *params, original)
+	}
+
+	if _, err := ParseEPC("SPD*1.0*ACC:CZ4220100000002900086515*"); err == nil {
+		t.Error("ParseEPC() should reject a SPAYD string")
+	}
+}
+
+func TestServiceGenerateEPCString(t *testing.T) {
+	s := NewService("CZ4220100000002900086515", "FIOBCZPP")
+
+	result := s.GenerateEPCString(GenerateParams{
+		Amount:         450,
+		VariableSymbol: "1234567890",
+		Message:        "CLENSKY PRISPEVEK BASE48",
+		RecipientName:  "Base48, z.s.",
+	})
+
+	params, err := ParseEPC(result)
+	if err != nil {
+		t.Fatalf("ParseEPC() error = %v", err)
+	}
+	if params.Amount != 0 {
+		t.Errorf("Amount = %f, EPC codes are EUR only and must not carry the CZK amount", params.Amount)
+	}
+	if want := "/VS1234567890/ CLENSKY PRISPEVEK BASE48 450.00 CZK"; params.Text != want {
+		t.Errorf("Text = %q, want %q", params.Text, want)
+	}
+
+	if _, err := s.GenerateEPCQR(GenerateParams{VariableSymbol: "1"}); err == nil {
+		t.Error("GenerateEPCQR() without recipient name should fail")
+	}
+}
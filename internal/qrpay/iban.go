@@ -0,0 +1,91 @@
+package qrpay
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// czechAccountPattern matches a Czech national account number "[prefix-]number/bank"
+var czechAccountPattern = regexp.MustCompile(`^(?:(\d{1,6})-)?(\d{2,10})/(\d{4})$`)
+
+// ibanPattern matches the structure of a normalized IBAN: country code, check
+// digits and up to 30 alphanumeric characters of the national account (BBAN)
+var ibanPattern = regexp.MustCompile(`^[A-Z]{2}\d{2}[A-Z0-9]{11,30}$`)
+
+// ibanLengths are the IBAN lengths of countries members pay from; other countries
+// are checked by structure and checksum only
+var ibanLengths = map[string]int{
+	"AT": 20, "BE": 16, "CZ": 24, "DE": 22, "FR": 27, "GB": 22,
+	"HU": 28, "IT": 27, "NL": 18, "PL": 28, "SK": 24,
+}
+
+// NormalizeIBAN removes whitespace and upper-cases an IBAN
+// ("cz65 0800 0000 1920 0014 5399" -> "CZ6508000000192000145399").
+func NormalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+// ValidateIBAN checks the structure, country length and mod-97 checksum
+// (ISO 13616) of an IBAN. Whitespace and case are ignored.
+func ValidateIBAN(iban string) error {
+	iban = NormalizeIBAN(iban)
+	if !ibanPattern.MatchString(iban) {
+		return fmt.Errorf("invalid IBAN %q", iban)
+	}
+	if length, ok := ibanLengths[iban[:2]]; ok && len(iban) != length {
+		return fmt.Errorf("invalid IBAN %q: %s IBAN has %d characters", iban, iban[:2], length)
+	}
+	if ibanMod97(iban[4:]+iban[:4]) != 1 {
+		return fmt.Errorf("invalid IBAN %q: wrong check digits", iban)
+	}
+	return nil
+}
+
+// CzechAccountToIBAN converts a Czech national account number
+// ("000000-2900086515/2010" or "2900086515/2010") to an IBAN.
+func CzechAccountToIBAN(account string) (string, error) {
+	m := czechAccountPattern.FindStringSubmatch(strings.Join(strings.Fields(account), ""))
+	if m == nil {
+		return "", fmt.Errorf("invalid account number %q (expected [prefix-]number/bank)", account)
+	}
+
+	// BBAN: bank code, 6 digit prefix and 10 digit number, zero padded
+	bban := m[3] + fmt.Sprintf("%06s", m[1]) + fmt.Sprintf("%010s", m[2])
+	check := 98 - ibanMod97(bban+"CZ00")
+	return fmt.Sprintf("CZ%02d%s", check, bban), nil
+}
+
+// ParseIBAN accepts an IBAN or a Czech national account number and returns the
+// normalized, validated IBAN.
+func ParseIBAN(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		iban, err := CzechAccountToIBAN(value)
+		if err != nil {
+			return "", err
+		}
+		value = iban
+	}
+
+	iban := NormalizeIBAN(value)
+	if err := ValidateIBAN(iban); err != nil {
+		return "", err
+	}
+	return iban, nil
+}
+
+// ibanMod97 computes the remainder of the number formed by replacing letters
+// with 10..35, digit by digit so it fits any length
+func ibanMod97(s string) int {
+	remainder := 0
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		}
+	}
+	return remainder
+}
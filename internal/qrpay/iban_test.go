@@ -0,0 +1,85 @@
+package qrpay
+
+import "testing"
+
+func TestValidateIBAN(t *testing.T) {
+	tests := []struct {
+		iban    string
+		wantErr bool
+	}{
+		{"CZ6508000000192000145399", false},
+		{"cz65 0800 0000 1920 0014 5399", false},
+		{"SK3112000000198742637541", false},
+		{"DE89370400440532013000", false},
+		{"GB29NWBK60161331926819", false},
+		{"CZ6608000000192000145399", true},  // wrong check digits
+		{"CZ650800000019200014539", true},   // too short for CZ
+		{"CZ65080000001920001453991", true}, // too long for CZ
+		{"2900086515/2010", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateIBAN(tt.iban)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateIBAN(%q) error = %v, wantErr %v", tt.iban, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCzechAccountToIBAN(t *testing.T) {
+	tests := []struct {
+		account string
+		want    string
+		wantErr bool
+	}{
+		{account: "19-2000145399/0800", want: "CZ6508000000192000145399"},
+		{account: "000000-2900086515/2010", want: "CZ4220100000002900086515"},
+		{account: "2900086515/2010", want: "CZ4220100000002900086515"},
+		{account: " 2900086515 / 2010 ", want: "CZ4220100000002900086515"},
+		{account: "2900086515", wantErr: true},
+		{account: "2900086515/20", wantErr: true},
+		{account: "1234567-2900086515/2010", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := CzechAccountToIBAN(tt.account)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("CzechAccountToIBAN(%q) = %q, expected error", tt.account, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("CzechAccountToIBAN(%q) error: %v", tt.account, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CzechAccountToIBAN(%q) = %q, want %q", tt.account, got, tt.want)
+		}
+	}
+}
+
+func TestParseIBAN(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "CZ65 0800 0000 1920 0014 5399", want: "CZ6508000000192000145399"},
+		{value: "19-2000145399/0800", want: "CZ6508000000192000145399"},
+		{value: "CZ65 0800 0000 1920 0014 5398", wantErr: true},
+		{value: "not an account", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseIBAN(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseIBAN(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseIBAN(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
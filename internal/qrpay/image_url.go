@@ -0,0 +1,144 @@
+package qrpay
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Limits of QR images requested by URL
+const (
+	MinImageSize = 100
+	MaxImageSize = 1000
+)
+
+// SignedImagePath is the portal route serving images of signed URLs
+const SignedImagePath = "/qr/signed/payment.png"
+
+// Errors of signed image URLs
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpired          = errors.New("link expired")
+)
+
+// ImageRequest describes a payment QR image requested by URL, e.g.
+// /qr/payment.png?vs=1234&amount=450.00&due=2026-04-01&size=200&format=spayd
+type ImageRequest struct {
+	VariableSymbol string
+	Amount         float64   // CZK, zero = no amount
+	DueDate        time.Time // zero = no due date
+	Size           int       // pixels, zero = DefaultQRSize
+	Format         string    // "" = SPAYD
+}
+
+// Query returns the URL query of the request (without signature).
+func (r ImageRequest) Query() url.Values {
+	q := url.Values{}
+	if r.VariableSymbol != "" {
+		q.Set("vs", r.VariableSymbol)
+	}
+	if r.Amount > 0 {
+		q.Set("amount", strconv.FormatFloat(r.Amount, 'f', 2, 64))
+	}
+	if !r.DueDate.IsZero() {
+		q.Set("due", r.DueDate.Format("2006-01-02"))
+	}
+	if r.Size > 0 {
+		q.Set("size", strconv.Itoa(r.Size))
+	}
+	if r.Format != "" {
+		q.Set("format", r.Format)
+	}
+	return q
+}
+
+// ParseImageQuery reads and validates an image request from the URL query.
+func ParseImageQuery(q url.Values) (ImageRequest, error) {
+	req := ImageRequest{
+		VariableSymbol: q.Get("vs"),
+		Format:         q.Get("format"),
+	}
+
+	if req.VariableSymbol != "" && sanitizeSymbol(req.VariableSymbol, 10) != req.VariableSymbol {
+		return req, fmt.Errorf("invalid variable symbol")
+	}
+	if value := q.Get("amount"); value != "" {
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil || amount < 0 || amount > 1000000 {
+			return req, fmt.Errorf("invalid amount")
+		}
+		req.Amount = amount
+	}
+	if value := q.Get("due"); value != "" {
+		due, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return req, fmt.Errorf("invalid due date")
+		}
+		req.DueDate = due
+	}
+	if value := q.Get("size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < MinImageSize || size > MaxImageSize {
+			return req, fmt.Errorf("size must be between %d and %d", MinImageSize, MaxImageSize)
+		}
+		req.Size = size
+	}
+	switch req.Format {
+	case "", FormatSPAYD, FormatEPC, FormatPayBySquare:
+	default:
+		return req, fmt.Errorf("unknown format %q", req.Format)
+	}
+
+	return req, nil
+}
+
+// SignImageQuery returns the query of a signed image URL valid until expires,
+// for places without a session like emails.
+func SignImageQuery(secret string, req ImageRequest, expires time.Time) url.Values {
+	q := req.Query()
+	q.Set("exp", strconv.FormatInt(expires.Unix(), 10))
+	q.Set("sig", imageSignature(secret, q))
+	return q
+}
+
+// SignedImageURL returns an absolute signed image URL valid until expires.
+func SignedImageURL(baseURL, secret string, req ImageRequest, expires time.Time) string {
+	return baseURL + SignedImagePath + "?" + SignImageQuery(secret, req, expires).Encode()
+}
+
+// VerifySignedImageQuery checks the signature and expiry of a signed image URL
+// and returns the request.
+func VerifySignedImageQuery(secret string, q url.Values, now time.Time) (ImageRequest, error) {
+	signed := url.Values{}
+	for key, values := range q {
+		if key != "sig" {
+			signed[key] = values
+		}
+	}
+	expected := imageSignature(secret, signed)
+	if !hmac.Equal([]byte(q.Get("sig")), []byte(expected)) {
+		return ImageRequest{}, ErrInvalidSignature
+	}
+
+	exp, err := strconv.ParseInt(q.Get("exp"), 10, 64)
+	if err != nil {
+		return ImageRequest{}, ErrInvalidSignature
+	}
+	if now.After(time.Unix(exp, 0)) {
+		return ImageRequest{}, ErrExpired
+	}
+
+	return ParseImageQuery(q)
+}
+
+// imageSignature is HMAC-SHA256 of the canonical (sorted) query
+func imageSignature(secret string, q url.Values) string {
+	mac := hmac.New(sha256.New, []byte("qrpay-image:"+secret))
+	mac.Write([]byte(q.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,127 @@
+package qrpay
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseImageQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    ImageRequest
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			query: "",
+			want:  ImageRequest{},
+		},
+		{
+			name:  "all parameters",
+			query: "vs=1234&amount=450.00&due=2026-04-01&size=300&format=paybysquare",
+			want:  ImageRequest{VariableSymbol: "1234", Amount: 450, DueDate: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), Size: 300, Format: FormatPayBySquare},
+		},
+		{name: "invalid due date", query: "due=1.4.2026", wantErr: true},
+		{name: "non-numeric VS", query: "vs=12a4", wantErr: true},
+		{name: "VS too long", query: "vs=12345678901", wantErr: true},
+		{name: "negative amount", query: "amount=-1", wantErr: true},
+		{name: "size too small", query: "size=50", wantErr: true},
+		{name: "size too large", query: "size=5000", wantErr: true},
+		{name: "unknown format", query: "format=bitcoin", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseImageQuery(q)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseImageQuery(%q) expected error, got %+v", tt.query, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseImageQuery(%q) error: %v", tt.query, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseImageQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignedImageQuery(t *testing.T) {
+	const secret = "test-secret"
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	req := ImageRequest{VariableSymbol: "1234", Amount: 450, DueDate: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), Size: 200, Format: FormatEPC}
+	q := SignImageQuery(secret, req, now.Add(time.Hour))
+
+	t.Run("valid", func(t *testing.T) {
+		got, err := VerifySignedImageQuery(secret, q, now)
+		if err != nil {
+			t.Fatalf("VerifySignedImageQuery error: %v", err)
+		}
+		if got != req {
+			t.Errorf("VerifySignedImageQuery = %+v, want %+v", got, req)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := VerifySignedImageQuery(secret, q, now.Add(2*time.Hour))
+		if !errors.Is(err, ErrExpired) {
+			t.Errorf("expected ErrExpired, got %v", err)
+		}
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		_, err := VerifySignedImageQuery("other-secret", q, now)
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	for _, key := range []string{"vs", "amount", "exp"} {
+		t.Run("tampered "+key, func(t *testing.T) {
+			tampered := url.Values{}
+			for k, v := range q {
+				tampered[k] = v
+			}
+			tampered.Set(key, "9999999999")
+			_, err := VerifySignedImageQuery(secret, tampered, now)
+			if !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+
+	t.Run("added parameter", func(t *testing.T) {
+		extended := url.Values{}
+		for k, v := range q {
+			extended[k] = v
+		}
+		extended.Set("size", "1000")
+		_, err := VerifySignedImageQuery(secret, extended, now)
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+}
+
+func TestSignedImageURL(t *testing.T) {
+	got := SignedImageURL("https://portal.example", "secret", ImageRequest{VariableSymbol: "1234"}, time.Unix(1700000000, 0))
+	if !strings.HasPrefix(got, "https://portal.example"+SignedImagePath+"?") {
+		t.Errorf("unexpected URL %s", got)
+	}
+	for _, param := range []string{"vs=1234", "exp=1700000000", "sig="} {
+		if !strings.Contains(got, param) {
+			t.Errorf("URL %s missing %s", got, param)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package qrpay
+
+// Minimal raw LZMA1 encoder for PAY by square payloads. Payment data is a few
+// hundred bytes at most, so the encoder only emits literals: the stream is a valid
+// LZMA stream (lc=3, lp=0, pb=2, any dictionary size) that every decoder reads,
+// without implementing match finding. No header and no end marker - PAY by square
+// carries the uncompressed length itself.
+
+const (
+	lzmaLC = 3
+	lzmaLP = 0
+	lzmaPB = 2
+
+	lzmaProbBits  = 11
+	lzmaProbInit  = 1 << (lzmaProbBits - 1)
+	lzmaMoveBits  = 5
+	lzmaTopValue  = 1 << 24
+	lzmaNumStates = 12
+)
+
+// lzmaRangeEncoder is the LZMA binary range coder
+type lzmaRangeEncoder struct {
+	low       uint64
+	rng       uint32
+	cache     byte
+	cacheSize int
+	out       []byte
+}
+
+func newLZMARangeEncoder() *lzmaRangeEncoder {
+	return &lzmaRangeEncoder{rng: 0xFFFFFFFF, cacheSize: 1}
+}
+
+func (e *lzmaRangeEncoder) encodeBit(prob *uint16, bit uint32) {
+	bound := (e.rng >> lzmaProbBits) * uint32(*prob)
+	if bit == 0 {
+		e.rng = bound
+		*prob += (1<<lzmaProbBits - *prob) >> lzmaMoveBits
+	} else {
+		e.low += uint64(bound)
+		e.rng -= bound
+		*prob -= *prob >> lzmaMoveBits
+	}
+	for e.rng < lzmaTopValue {
+		e.rng <<= 8
+		e.shiftLow()
+	}
+}
+
+func (e *lzmaRangeEncoder) shiftLow() {
+	if uint32(e.low) < 0xFF000000 || e.low>>32 != 0 {
+		carry := byte(e.low >> 32)
+		temp := e.cache
+		for {
+			e.out = append(e.out, temp+carry)
+			temp = 0xFF
+			e.cacheSize--
+			if e.cacheSize == 0 {
+				break
+			}
+		}
+		e.cache = byte(e.low >> 24)
+	}
+	e.cacheSize++
+	e.low = (e.low & 0x00FFFFFF) << 8
+}
+
+func (e *lzmaRangeEncoder) flush() []byte {
+	for i := 0; i < 5; i++ {
+		e.shiftLow()
+	}
+	return e.out
+}
+
+// lzmaCompress encodes data as a raw LZMA1 stream of literals
+func lzmaCompress(data []byte) []byte {
+	enc := newLZMARangeEncoder()
+	isMatch := make([]uint16, lzmaNumStates<<lzmaPB)
+	literal := make([]uint16, 0x300<<(lzmaLC+lzmaLP))
+	for i := range isMatch {
+		isMatch[i] = lzmaProbInit
+	}
+	for i := range literal {
+		literal[i] = lzmaProbInit
+	}
+
+	// Only literals follow, so the state machine stays in state 0
+	const state = 0
+	var prev byte
+	for pos, b := range data {
+		posState := pos & (1<<lzmaPB - 1)
+		enc.encodeBit(&isMatch[state<<lzmaPB+posState], 0)
+
+		litState := (pos&(1<<lzmaLP-1))<<lzmaLC + int(prev>>(8-lzmaLC))
+		probs := literal[0x300*litState : 0x300*(litState+1)]
+		symbol := uint32(1)
+		for i := 7; i >= 0; i-- {
+			bit := uint32(b>>uint(i)) & 1
+			enc.encodeBit(&probs[symbol], bit)
+			symbol = symbol<<1 | bit
+		}
+		prev = b
+	}
+
+	return enc.flush()
+}
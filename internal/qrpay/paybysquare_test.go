@@ -0,0 +1,62 @@
+package qrpay
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPayBySquareData(t *testing.T) {
+	got := payBySquareData(PayBySquareParams{
+		IBAN:            "CZ42 2010 0000 0029 0008 6515",
+		BIC:             "fiobczpp",
+		Amount:          18.5,
+		VariableSymbol:  "VS 1234",
+		Note:            "Členský\tpříspěvek",
+		BeneficiaryName: "Base48, z.s.",
+	})
+
+	want := strings.Join([]string{
+		"", "1", "1", "18.50", "EUR", "", "1234", "", "", "", "Clensky prispevek",
+		"1", "CZ4220100000002900086515", "FIOBCZPP", "0", "0", "Base48, z.s.", "", "",
+	}, "\t")
+	if got != want {
+		t.Errorf("payBySquareData() = %q, want %q", got, want)
+	}
+}
+
+func TestGeneratePayBySquare(t *testing.T) {
+	// Reference output, decoded with liblzma (raw LZMA1, lc=3 lp=0 pb=2) and the
+	// CRC32 checked
+	got := GeneratePayBySquare(PayBySquareParams{
+		IBAN:            "CZ4220100000002900086515",
+		BIC:             "FIOBCZPP",
+		VariableSymbol:  "1234567890",
+		Note:            "Členský příspěvek BASE48 450.00 CZK",
+		BeneficiaryName: "Base48, z.s.",
+	})
+	want := "0407E0000ILG3G2CP301FA4GJV0B78SP0JTLJBF9N0SMH9JF0PQK8EC3L91HMLAUV5I4A1M34ITN7RIG1BB3TF1RHT964R8R4D8938SP4E3FNA6MSJKV0FT9KDCR9JD0C8D89U23J7HEUQ3NORRT95EIHM7LMAEPF0EDFPVJ2PMP8D1H7I5NELC7B06601OT"
+	if got != want {
+		t.Errorf("GeneratePayBySquare() = %q, want %q", got, want)
+	}
+}
+
+func TestServiceGeneratePayBySquareString(t *testing.T) {
+	s := NewService("CZ4220100000002900086515", "FIOBCZPP")
+
+	got := s.GeneratePayBySquareString(GenerateParams{
+		Amount:         450,
+		VariableSymbol: "1234567890",
+		Message:        "Členský příspěvek BASE48",
+		RecipientName:  "Base48, z.s.",
+	})
+	want := GeneratePayBySquare(PayBySquareParams{
+		IBAN:            "CZ4220100000002900086515",
+		BIC:             "FIOBCZPP",
+		VariableSymbol:  "1234567890",
+		Note:            "Členský příspěvek BASE48 450.00 CZK",
+		BeneficiaryName: "Base48, z.s.",
+	})
+	if got != want {
+		t.Errorf("GeneratePayBySquareString() = %q, want %q", got, want)
+	}
+}
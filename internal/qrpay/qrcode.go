@@ -3,6 +3,7 @@ package qrpay
 import (
 	"encoding/base64"
 	"fmt"
+	"strings"
 
 	"github.com/skip2/go-qrcode"
 )
@@ -40,3 +41,46 @@ func GenerateQRBase64(content string, size int) (string, error) {
 	encoded := base64.StdEncoding.EncodeToString(png)
 	return "data:image/png;base64," + encoded, nil
 }
+
+// GenerateQRSVG generates a QR code as an SVG document. Unlike PNG it stays crisp
+// at any scale, so templates and PDFs can embed it inline; emails keep using PNG
+// because many mail clients do not render SVG.
+// The size sets the width and height attributes in pixels, the viewBox is in
+// modules including the quiet zone.
+func GenerateQRSVG(content string, size int) (string, error) {
+	if size <= 0 {
+		size = DefaultQRSize
+	}
+
+	qr, err := qrcode.New(content, QRRecoveryLevel)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate QR code: %w", err)
+	}
+	bitmap := qr.Bitmap()
+	modules := len(bitmap)
+
+	// One path for all dark modules, each horizontal run as a single rectangle
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			run := 1
+			for x+run < len(row) && row[x+run] {
+				run++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", x, y, run, run)
+			x += run
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, modules, modules)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/>`, modules, modules)
+	fmt.Fprintf(&b, `<path fill="#000" d="%s"/>`, path.String())
+	b.WriteString("</svg>\n")
+
+	return b.String(), nil
+}
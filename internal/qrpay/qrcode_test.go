@@ -0,0 +1,97 @@
+package qrpay
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func TestGenerateQRSVGGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		size    int
+	}{
+		{
+			name:    "spayd",
+			content: "SPD*1.0*ACC:CZ4220100000002900086515+FIOBCZPP*AM:450.00*CC:CZK*MSG:CLENSKY PRISPEVEK BASE48*X-VS:1234",
+			size:    200,
+		},
+		{
+			name:    "short",
+			content: "base48",
+			size:    0, // DefaultQRSize
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateQRSVG(tt.content, tt.size)
+			if err != nil {
+				t.Fatalf("GenerateQRSVG() error: %v", err)
+			}
+
+			golden := "testdata/" + tt.name + ".svg"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("GenerateQRSVG() differs from %s (run with -update after intended changes)\ngot:  %s\nwant: %s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestGenerateQRSVGModules(t *testing.T) {
+	const content = "base48"
+	svg, err := GenerateQRSVG(content, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300" `) {
+		t.Errorf("unexpected SVG header: %.100s", svg)
+	}
+
+	qr, err := qrcode.New(content, QRRecoveryLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := qr.Bitmap()
+
+	// Paint the runs of the path back and compare with the symbol
+	_, d, _ := strings.Cut(svg, ` d="`)
+	d, _, _ = strings.Cut(d, `"`)
+	got := make([][]bool, len(want))
+	for i := range got {
+		got[i] = make([]bool, len(want))
+	}
+	for _, rect := range strings.Split(strings.TrimSuffix(d, "z"), "z") {
+		var x, y, run, back int
+		if _, err := fmt.Sscanf(rect, "M%d %dh%dv1h-%d", &x, &y, &run, &back); err != nil || run != back {
+			t.Fatalf("unexpected path segment %q", rect)
+		}
+		for i := 0; i < run; i++ {
+			got[y][x+i] = true
+		}
+	}
+
+	for y := range want {
+		for x := range want[y] {
+			if got[y][x] != want[y][x] {
+				t.Fatalf("module %d,%d: got %v, want %v", x, y, got[y][x], want[y][x])
+			}
+		}
+	}
+}
@@ -2,6 +2,15 @@ package qrpay
 
 import (
 	"fmt"
+	"strings"
+	"time"
+)
+
+// Payment QR code formats
+const (
+	FormatSPAYD       = "spayd"       // Czech QR Platba
+	FormatEPC         = "epc"         // SEPA "GiroCode"
+	FormatPayBySquare = "paybysquare" // Slovak PAY by square
 )
 
 // Service provides high-level methods for generating payment QR codes.
@@ -26,10 +35,28 @@ type GenerateParams struct {
 	VariableSymbol string
 	// Message is an optional message for the payment.
 	Message string
+	// RecipientName is the account holder's name, required by EPC QR codes.
+	RecipientName string
+	// DueDate is the payment due date, zero means none. Not part of EPC QR codes.
+	DueDate time.Time
+	// ConstantSymbol and SpecificSymbol are the other Czech payment symbols
+	// (optional). Not part of EPC QR codes.
+	ConstantSymbol string
+	SpecificSymbol string
+	// URL links back to the payment page in the portal (SPAYD X-URL, optional).
+	URL string
 	// Size is the QR code size in pixels. Defaults to 200.
 	Size int
 }
 
+// dueDate formats the due date as YYYYMMDD, empty when not set
+func (p GenerateParams) dueDate() string {
+	if p.DueDate.IsZero() {
+		return ""
+	}
+	return p.DueDate.Format("20060102")
+}
+
 // GeneratePaymentQR generates a QR code for a payment to the organization's account.
 // Returns a Base64 data URL ready to use in an HTML img tag.
 func (s *Service) GeneratePaymentQR(params GenerateParams) (string, error) {
@@ -37,14 +64,7 @@ func (s *Service) GeneratePaymentQR(params GenerateParams) (string, error) {
 		return "", fmt.Errorf("bank IBAN not configured")
 	}
 
-	spayd := GenerateSPAYD(PaymentParams{
-		IBAN:           s.bankIBAN,
-		BIC:            s.bankBIC,
-		Amount:         params.Amount,
-		Currency:       "CZK",
-		VariableSymbol: params.VariableSymbol,
-		Message:        params.Message,
-	})
+	spayd := s.GenerateSPAYDString(params)
 
 	size := params.Size
 	if size <= 0 {
@@ -54,6 +74,49 @@ func (s *Service) GeneratePaymentQR(params GenerateParams) (string, error) {
 	return GenerateQRBase64(spayd, size)
 }
 
+// GenerateEPCQR generates a SEPA (EPC069-12 "Girocode") QR code for a payment to
+// the organization's account, for members whose banking apps do not read SPAYD.
+// SEPA transfers are in EUR, so the QR carries no amount: the CZK amount goes to
+// the message with the variable symbol and the bank converts the transfer.
+// Returns a Base64 data URL ready to use in an HTML img tag.
+func (s *Service) GenerateEPCQR(params GenerateParams) (string, error) {
+	if s.bankIBAN == "" {
+		return "", fmt.Errorf("bank IBAN not configured")
+	}
+	if params.RecipientName == "" {
+		return "", fmt.Errorf("recipient name is required for EPC QR codes")
+	}
+
+	size := params.Size
+	if size <= 0 {
+		size = DefaultQRSize
+	}
+
+	return GenerateQRBase64(s.GenerateEPCString(params), size)
+}
+
+// GenerateEPCString generates just the EPC payload without QR code.
+func (s *Service) GenerateEPCString(params GenerateParams) string {
+	var text []string
+	if vs := sanitizeSymbol(params.VariableSymbol, 10); vs != "" {
+		// Czech banks read the variable symbol of foreign payments from the message
+		text = append(text, "/VS"+vs+"/")
+	}
+	if params.Message != "" {
+		text = append(text, params.Message)
+	}
+	if params.Amount > 0 {
+		text = append(text, fmt.Sprintf("%.2f CZK", params.Amount))
+	}
+
+	return GenerateEPC(EPCParams{
+		BIC:  s.bankBIC,
+		Name: params.RecipientName,
+		IBAN: s.bankIBAN,
+		Text: strings.Join(text, " "),
+	})
+}
+
 // GenerateSPAYDString generates just the SPAYD string without QR code.
 // Useful for debugging or alternative display methods.
 func (s *Service) GenerateSPAYDString(params GenerateParams) string {
@@ -63,10 +126,95 @@ func (s *Service) GenerateSPAYDString(params GenerateParams) string {
 		Amount:         params.Amount,
 		Currency:       "CZK",
 		VariableSymbol: params.VariableSymbol,
+		ConstantSymbol: params.ConstantSymbol,
+		SpecificSymbol: params.SpecificSymbol,
 		Message:        params.Message,
+		RecipientName:  params.RecipientName,
+		DueDate:        params.dueDate(),
+		URL:            params.URL,
 	})
 }
 
+// GeneratePayBySquareQR generates a PAY by square QR code for a payment to the
+// organization's account, for members whose Slovak banking apps only read this
+// format. Like the EPC code it is in EUR without an amount; the variable symbol
+// has its own field and the CZK amount goes to the note.
+// Returns a Base64 data URL ready to use in an HTML img tag.
+func (s *Service) GeneratePayBySquareQR(params GenerateParams) (string, error) {
+	if s.bankIBAN == "" {
+		return "", fmt.Errorf("bank IBAN not configured")
+	}
+
+	size := params.Size
+	if size <= 0 {
+		size = DefaultQRSize
+	}
+
+	return GenerateQRBase64(s.GeneratePayBySquareString(params), size)
+}
+
+// GeneratePayBySquareString generates just the PAY by square payload without QR code.
+func (s *Service) GeneratePayBySquareString(params GenerateParams) string {
+	note := params.Message
+	if params.Amount > 0 {
+		note = strings.TrimSpace(fmt.Sprintf("%s %.2f CZK", note, params.Amount))
+	}
+
+	return GeneratePayBySquare(PayBySquareParams{
+		IBAN:            s.bankIBAN,
+		BIC:             s.bankBIC,
+		DueDate:         params.dueDate(),
+		VariableSymbol:  params.VariableSymbol,
+		ConstantSymbol:  params.ConstantSymbol,
+		SpecificSymbol:  params.SpecificSymbol,
+		Note:            note,
+		BeneficiaryName: params.RecipientName,
+	})
+}
+
+// Payload generates the payload of a payment QR code in the given format
+// ("" is SPAYD).
+func (s *Service) Payload(format string, params GenerateParams) (string, error) {
+	if s.bankIBAN == "" {
+		return "", fmt.Errorf("bank IBAN not configured")
+	}
+
+	switch format {
+	case FormatSPAYD, "":
+		return s.GenerateSPAYDString(params), nil
+	case FormatEPC:
+		if params.RecipientName == "" {
+			return "", fmt.Errorf("recipient name is required for EPC QR codes")
+		}
+		return s.GenerateEPCString(params), nil
+	case FormatPayBySquare:
+		return s.GeneratePayBySquareString(params), nil
+	}
+	return "", fmt.Errorf("unknown QR format %q", format)
+}
+
+// GeneratePNG generates a payment QR code in the given format as PNG bytes,
+// for serving the image by URL instead of embedding it.
+func (s *Service) GeneratePNG(format string, params GenerateParams) ([]byte, error) {
+	payload, err := s.Payload(format, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return GenerateQRPNG(payload, params.Size)
+}
+
+// GenerateSVG generates a payment QR code in the given format as an SVG document,
+// for embedding in pages and PDFs.
+func (s *Service) GenerateSVG(format string, params GenerateParams) (string, error) {
+	payload, err := s.Payload(format, params)
+	if err != nil {
+		return "", err
+	}
+
+	return GenerateQRSVG(payload, params.Size)
+}
+
 // BankIBAN returns the configured bank IBAN.
 func (s *Service) BankIBAN() string {
 	return s.bankIBAN
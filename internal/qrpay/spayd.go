@@ -1,4 +1,5 @@
-// Package qrpay implements Czech QR payment code generation (SPAYD format).
+// Package qrpay implements Czech QR payment code generation (SPAYD format)
+// and SEPA (EPC069-12) and Slovak PAY by square QR codes for foreign banking apps.
 // See: https://qr-platba.cz/pro-vyvojare/specifikace-formatu/
 package qrpay
 
@@ -29,6 +30,8 @@ type PaymentParams struct {
 	RecipientName string
 	// DueDate is the payment due date in YYYYMMDD format (DT).
 	DueDate string
+	// URL is a link back to the payment in the recipient's system (X-URL), max 140 chars.
+	URL string
 }
 
 // GenerateSPAYD creates a SPAYD (Short Payment Descriptor) string from payment parameters.
@@ -44,7 +47,7 @@ func GenerateSPAYD(p PaymentParams) string {
 	parts = append(parts, "SPD*1.0")
 
 	// Account (required) - IBAN with optional BIC
-	acc := p.IBAN
+	acc := NormalizeIBAN(p.IBAN)
 	if p.BIC != "" {
 		acc += "+" + p.BIC
 	}
@@ -90,6 +93,11 @@ func GenerateSPAYD(p PaymentParams) string {
 		parts = append(parts, "X-KS:"+sanitizeSymbol(p.ConstantSymbol, 10))
 	}
 
+	// Back-link to the recipient's system (optional, max 140 chars)
+	if u := sanitizeURL(p.URL, 140); u != "" {
+		parts = append(parts, "X-URL:"+u)
+	}
+
 	// Join with asterisk separator and add trailing asterisk
 	return strings.Join(parts, "*") + "*"
 }
@@ -116,6 +124,16 @@ func sanitizeMessage(s string, maxLen int) string {
 	return s
 }
 
+// sanitizeURL encodes asterisks of a URL and drops it when it is too long,
+// since a truncated link would be broken.
+func sanitizeURL(s string, maxLen int) string {
+	s = strings.ReplaceAll(s, "*", "%2A")
+	if len(s) > maxLen {
+		return ""
+	}
+	return s
+}
+
 // sanitizeSymbol ensures a symbol contains only digits and is within length limit.
 func sanitizeSymbol(s string, maxLen int) string {
 	// Keep only digits
@@ -208,6 +226,8 @@ func ParseSPAYD(spayd string) (*PaymentParams, error) {
 			params.SpecificSymbol = decoded
 		case "X-KS":
 			params.ConstantSymbol = decoded
+		case "X-URL":
+			params.URL = decoded
 		}
 	}
 
@@ -3,6 +3,7 @@ package qrpay
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestGenerateSPAYD(t *testing.T) {
@@ -65,6 +66,27 @@ func TestGenerateSPAYD(t *testing.T) {
 				"X-KS:0308",
 			},
 		},
+		{
+			name: "due date and back-link",
+			params: PaymentParams{
+				IBAN:    "CZ6508000000192000145399",
+				DueDate: "20260401",
+				URL:     "https://portal.base48.cz/profile",
+			},
+			contains: []string{
+				"DT:20260401",
+				"X-URL:https://portal.base48.cz/profile",
+			},
+		},
+		{
+			name: "normalized IBAN",
+			params: PaymentParams{
+				IBAN: "cz65 0800 0000 1920 0014 5399",
+			},
+			contains: []string{
+				"ACC:CZ6508000000192000145399*",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -169,3 +191,34 @@ func TestGenerateQRBase64(t *testing.T) {
 		t.Errorf("GenerateQRBase64() should return data URL, got %q", result[:50])
 	}
 }
+
+func TestServiceSPAYDParams(t *testing.T) {
+	s := NewService("CZ4220100000002900086515", "FIOBCZPP")
+	spayd := s.GenerateSPAYDString(GenerateParams{
+		Amount:         450,
+		VariableSymbol: "1234",
+		ConstantSymbol: "0308",
+		SpecificSymbol: "42",
+		DueDate:        time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		URL:            "https://portal.base48.cz/profile",
+	})
+
+	params, err := ParseSPAYD(spayd)
+	if err != nil {
+		t.Fatalf("ParseSPAYD() error = %v", err)
+	}
+	if params.DueDate != "20260401" {
+		t.Errorf("DueDate = %q, want 20260401", params.DueDate)
+	}
+	if params.ConstantSymbol != "0308" || params.SpecificSymbol != "42" {
+		t.Errorf("symbols KS=%q SS=%q, want 0308 and 42", params.ConstantSymbol, params.SpecificSymbol)
+	}
+	if params.URL != "https://portal.base48.cz/profile" {
+		t.Errorf("URL = %q", params.URL)
+	}
+
+	// No due date when not set
+	if spayd := s.GenerateSPAYDString(GenerateParams{VariableSymbol: "1234"}); strings.Contains(spayd, "DT:") {
+		t.Errorf("GenerateSPAYDString() = %q, want no due date", spayd)
+	}
+}
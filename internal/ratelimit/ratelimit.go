@@ -0,0 +1,80 @@
+// Package ratelimit implements an in-memory token bucket rate limiter keyed by
+// client (IP address, user, API token). Limits are per server process.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets of idle clients are dropped
+const sweepInterval = time.Minute
+
+// Limiter allows perMinute requests per key on average with bursts of up to
+// burst requests. It is safe for concurrent use.
+type Limiter struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New creates a limiter; burst below 1 defaults to perMinute
+func New(perMinute, burst int) *Limiter {
+	if burst < 1 {
+		burst = perMinute
+	}
+	return &Limiter{
+		rate:      float64(perMinute) / 60,
+		burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token for key. If none is left, it returns false and how long
+// the client should wait before retrying.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets that have refilled completely - a new bucket is the same
+func (l *Limiter) sweep(now time.Time) {
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
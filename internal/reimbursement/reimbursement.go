@@ -0,0 +1,55 @@
+package reimbursement
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/base48/member-portal/internal/money"
+)
+
+// Reimbursement states (reimbursements.state)
+const (
+	StateSubmitted = "submitted" // waiting for council approval
+	StateApproved  = "approved"  // waiting for export to a payment order batch
+	StateRejected  = "rejected"
+	StateExported  = "exported" // in a payment order batch, waiting for the bank debit
+	StatePaid      = "paid"     // matched to the outgoing payment by VS
+)
+
+// Receipt upload limits
+const (
+	MaxReceipts    = 5
+	MaxReceiptSize = 5 << 20 // bytes per file
+)
+
+// MaxAmount is a sanity limit for a single request (100 000 CZK), larger purchases go through the council directly
+const MaxAmount money.Amount = 100000_00
+
+// AllowedReceiptTypes are the content types accepted for receipts (detected from content)
+var AllowedReceiptTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+// vsPrefix marks variable symbols of reimbursement payments, so the bank debit can be
+// told apart from other outgoing payments
+const vsPrefix = "99"
+
+// VariableSymbol returns the VS used in the payment order for a reimbursement (e.g. 99000042)
+func VariableSymbol(id int64) string {
+	return fmt.Sprintf("%s%06d", vsPrefix, id)
+}
+
+// ParseVariableSymbol returns the reimbursement ID encoded in a variable symbol
+func ParseVariableSymbol(vs string) (int64, bool) {
+	if len(vs) != len(vsPrefix)+6 || !strings.HasPrefix(vs, vsPrefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(vs[len(vsPrefix):], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
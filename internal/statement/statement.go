@@ -0,0 +1,182 @@
+// Package statement builds the yearly payment statement of a member: everything
+// paid towards membership in a calendar year with the fees it covered, for the
+// member's own records (HTML in the profile and the January email, PDF download).
+package statement
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/fees"
+	"github.com/base48/member-portal/internal/invoice"
+	"github.com/base48/member-portal/internal/money"
+	"github.com/base48/member-portal/internal/pdf"
+)
+
+// Line is one item of the statement
+type Line struct {
+	Date   time.Time
+	Title  string
+	Amount money.Amount
+}
+
+// Statement summarizes a member's payments and fees in a calendar year
+type Statement struct {
+	Year        int
+	Member      db.User
+	Payments    []Line       // payments and fee parts of split payments
+	Charges     []Line       // fees and one-off charges (positive amounts)
+	Adjustments []Line       // forgiven fees and credit notes (positive = in the member's favour)
+	Paid        money.Amount // sum of Payments
+	Charged     money.Amount // sum of Charges
+	Adjusted    money.Amount // sum of Adjustments
+	Opening     money.Amount // balance at the start of the year
+	Closing     money.Amount // balance at the end of the year (so far for the current year)
+	InProgress  bool         // the year has not ended yet
+}
+
+// Empty reports whether nothing was paid or charged in the year
+func (s Statement) Empty() bool {
+	return len(s.Payments) == 0 && len(s.Charges) == 0 && len(s.Adjustments) == 0
+}
+
+// Build sorts the member's ledger entries (ListUserLedger, oldest first) of the year
+// into a statement; earlier entries only make up the opening balance
+func Build(member db.User, ledger []db.ListUserLedgerRow, year int) Statement {
+	s := Statement{Year: year, Member: member}
+	for _, entry := range ledger {
+		entryYear := entry.Date.UTC().Year()
+		if entryYear < year {
+			s.Opening += entry.Amount
+			continue
+		}
+		if entryYear > year {
+			continue
+		}
+
+		line := Line{Date: entry.Date, Amount: entry.Amount}
+		switch entry.EntryType {
+		case "payment", "split":
+			line.Title = "Platba"
+			if entry.EntryType == "split" && entry.Note != "" {
+				line.Title = "Část platby: " + entry.Note
+			}
+			s.Payments = append(s.Payments, line)
+			s.Paid += line.Amount
+		case "fee":
+			line.Title = "Členský příspěvek " + fees.PeriodLabel(entry.Date, int(entry.Months))
+			line.Amount = -entry.Amount
+			s.Charges = append(s.Charges, line)
+			s.Charged += line.Amount
+		case "charge":
+			line.Title = entry.Note
+			line.Amount = -entry.Amount
+			s.Charges = append(s.Charges, line)
+			s.Charged += line.Amount
+		case "adjustment":
+			line.Title = entry.Note
+			s.Adjustments = append(s.Adjustments, line)
+			s.Adjusted += line.Amount
+		}
+	}
+	s.Closing = s.Opening + s.Paid - s.Charged + s.Adjusted
+	return s
+}
+
+// Load builds the member's statement for the year from the database
+func Load(ctx context.Context, queries *db.Queries, member db.User, year int) (Statement, error) {
+	ledger, err := queries.ListUserLedger(ctx, sql.NullInt64{Int64: member.ID, Valid: true})
+	if err != nil {
+		return Statement{}, err
+	}
+	s := Build(member, ledger, year)
+	s.InProgress = year >= time.Now().Year()
+	return s, nil
+}
+
+// Filename is the name of the statement PDF
+func (s Statement) Filename() string {
+	return fmt.Sprintf("base48-platby-%d.pdf", s.Year)
+}
+
+// RenderPDF renders the statement as a PDF, on more pages when the year is long
+func RenderPDF(s Statement, issuer invoice.Issuer) []byte {
+	doc := pdf.New()
+	doc.AddPage()
+
+	const left, right = 50.0, pdf.PageWidth - 50
+	const bottom = pdf.PageHeight - 70
+
+	title := fmt.Sprintf("Přehled plateb za rok %d", s.Year)
+	if s.InProgress {
+		title += " (průběžný)"
+	}
+	doc.Text(left, 70, 18, true, title)
+	doc.Line(left, 84, right, 84, 1)
+
+	memberName := s.Member.Email
+	if s.Member.Realname.Valid && s.Member.Realname.String != "" {
+		memberName = s.Member.Realname.String
+	}
+	doc.Text(left, 108, 10, false, "Člen: "+memberName)
+	if s.Member.PaymentsID.Valid {
+		doc.Text(left, 122, 10, false, "Variabilní symbol: "+s.Member.PaymentsID.String)
+	}
+	doc.Text(310, 108, 10, false, issuer.Name)
+	if issuer.CompanyID != "" {
+		doc.Text(310, 122, 10, false, "IČO: "+issuer.CompanyID)
+	}
+
+	y := 160.0
+	section := func(heading string, lines []Line, total money.Amount) {
+		if len(lines) == 0 {
+			return
+		}
+		if y > bottom-60 {
+			doc.AddPage()
+			y = 70
+		}
+		doc.Text(left, y, 11, true, heading)
+		y += 8
+		doc.Line(left, y, right, y, 0.5)
+		y += 16
+		for _, line := range lines {
+			if y > bottom {
+				doc.AddPage()
+				y = 70
+			}
+			doc.Text(left, y, 10, false, line.Date.Format("2. 1. 2006"))
+			doc.Text(left+80, y, 10, false, line.Title)
+			doc.TextRight(right, y, 10, false, invoice.FormatAmount(line.Amount.Float64()))
+			y += 15
+		}
+		doc.Line(left, y-8, right, y-8, 0.5)
+		doc.Text(left, y+6, 10, true, "Celkem")
+		doc.TextRight(right, y+6, 10, true, invoice.FormatAmount(total.Float64()))
+		y += 36
+	}
+	section("Platby", s.Payments, s.Paid)
+	section("Příspěvky a poplatky", s.Charges, s.Charged)
+	section("Úpravy a dobropisy", s.Adjustments, s.Adjusted)
+
+	if y > bottom-50 {
+		doc.AddPage()
+		y = 70
+	}
+	closing := "Zůstatek na konci roku"
+	if s.InProgress {
+		closing = "Aktuální zůstatek"
+	}
+	doc.Rect(left, y, right-left, 46, true, 0.95)
+	doc.Text(left+10, y+18, 10, false, "Zůstatek na začátku roku")
+	doc.TextRight(right-10, y+18, 10, false, invoice.FormatAmount(s.Opening.Float64()))
+	doc.Text(left+10, y+36, 10, true, closing)
+	doc.TextRight(right-10, y+36, 10, true, invoice.FormatAmount(s.Closing.Float64()))
+
+	doc.Text(left, pdf.PageHeight-50, 8, false, issuer.Name+" - vystaveno členským portálem, slouží jako přehled pro člena")
+
+	return doc.Bytes()
+}
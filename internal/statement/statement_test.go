@@ -0,0 +1,74 @@
+package statement
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/base48/member-portal/internal/db"
+	"github.com/base48/member-portal/internal/invoice"
+	"github.com/base48/member-portal/internal/money"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestBuild(t *testing.T) {
+	ledger := []db.ListUserLedgerRow{
+		{EntryType: "fee", Date: date(2024, 12, 1), Amount: -100000, Months: 1},
+		{EntryType: "payment", Date: date(2024, 12, 5), Amount: 50000},
+		{EntryType: "fee", Date: date(2025, 1, 1), Amount: -300000, Months: 3},
+		{EntryType: "payment", Date: date(2025, 1, 10), Amount: 350000},
+		{EntryType: "charge", Date: date(2025, 2, 3), Amount: -50000, Note: "Skříňka"},
+		{EntryType: "split", Date: date(2025, 3, 1), Amount: 20000, Note: "Za Petra"},
+		{EntryType: "adjustment", Date: date(2025, 3, 2), Amount: 10000, Note: "Dobropis"},
+		{EntryType: "payment", Date: date(2026, 1, 4), Amount: 100000},
+	}
+
+	s := Build(db.User{ID: 1}, ledger, 2025)
+
+	if s.Opening != -50000 {
+		t.Errorf("Opening = %s, want -500.00", s.Opening)
+	}
+	if len(s.Payments) != 2 || s.Paid != 370000 {
+		t.Errorf("Payments = %d totalling %s, want 2 totalling 3700.00", len(s.Payments), s.Paid)
+	}
+	if len(s.Charges) != 2 || s.Charged != 350000 {
+		t.Errorf("Charges = %d totalling %s, want 2 totalling 3500.00", len(s.Charges), s.Charged)
+	}
+	if s.Charges[0].Title != "Členský příspěvek 2025-01 – 2025-03" || s.Charges[1].Title != "Skříňka" {
+		t.Errorf("Charge titles = %q, %q", s.Charges[0].Title, s.Charges[1].Title)
+	}
+	if s.Payments[1].Title != "Část platby: Za Petra" {
+		t.Errorf("Split title = %q", s.Payments[1].Title)
+	}
+	if s.Adjusted != 10000 {
+		t.Errorf("Adjusted = %s, want 100.00", s.Adjusted)
+	}
+	if want := money.Amount(-50000 + 370000 - 350000 + 10000); s.Closing != want {
+		t.Errorf("Closing = %s, want %s", s.Closing, want)
+	}
+	if s.Empty() {
+		t.Error("Empty() = true for a year with entries")
+	}
+
+	if empty := Build(db.User{ID: 1}, ledger, 2023); !empty.Empty() || empty.Opening != 0 {
+		t.Errorf("Build(2023) = %+v, want an empty statement", empty)
+	}
+}
+
+func TestRenderPDFPages(t *testing.T) {
+	s := Statement{Year: 2025}
+	for day := 0; day < 120; day++ {
+		s.Payments = append(s.Payments, Line{Date: date(2025, 1, 1).AddDate(0, 0, day*3), Title: "Platba", Amount: 10000})
+	}
+
+	out := RenderPDF(s, invoice.Issuer{Name: "Base48"})
+	if !bytes.HasPrefix(out, []byte("%PDF-")) {
+		t.Fatalf("RenderPDF() does not start with a PDF header")
+	}
+	if pages := bytes.Count(out, []byte("/Type /Page ")); pages < 2 {
+		t.Errorf("RenderPDF() of 120 payments has %d pages, want more than one", pages)
+	}
+}
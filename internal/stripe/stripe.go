@@ -0,0 +1,210 @@
+// Package stripe creates Stripe Checkout sessions for card payments of membership
+// fees and verifies the webhook events Stripe sends back. Only these two calls of
+// the API are needed, so no SDK is used.
+package stripe
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/base48/member-portal/internal/money"
+)
+
+// Event types of a finished Checkout session
+const (
+	EventCheckoutCompleted      = "checkout.session.completed"
+	EventCheckoutAsyncSucceeded = "checkout.session.async_payment_succeeded"
+)
+
+// PaymentStatusPaid is the payment status of a paid Checkout session
+const PaymentStatusPaid = "paid"
+
+// webhookTolerance is how old a signed webhook may be (replay protection)
+const webhookTolerance = 5 * time.Minute
+
+// Client calls the Stripe API with a secret key
+type Client struct {
+	secretKey  string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the secret key (sk_live_..., sk_test_...)
+func NewClient(secretKey string) *Client {
+	return &Client{
+		secretKey:  secretKey,
+		baseURL:    "https://api.stripe.com",
+		httpClient: &http.Client{Timeout: 20 * time.Second},
+	}
+}
+
+// CheckoutParams describe a one-off card payment
+type CheckoutParams struct {
+	Amount            money.Amount
+	Description       string // line item shown on the Stripe payment page
+	CustomerEmail     string
+	ClientReferenceID string // our user ID, returned in the webhook
+	SuccessURL        string
+	CancelURL         string
+	Metadata          map[string]string
+}
+
+// Session is a Checkout session (as created and as sent in webhook events)
+type Session struct {
+	ID                string            `json:"id"`
+	URL               string            `json:"url"` // payment page the member is redirected to
+	AmountTotal       int64             `json:"amount_total"`
+	Currency          string            `json:"currency"`
+	PaymentStatus     string            `json:"payment_status"`
+	ClientReferenceID string            `json:"client_reference_id"`
+	CustomerEmail     string            `json:"customer_email"`
+	PaymentIntent     string            `json:"payment_intent"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+// Amount is the paid amount; CZK has haléře as the minor unit, same as money.Amount
+func (s *Session) Amount() money.Amount {
+	return money.Amount(s.AmountTotal)
+}
+
+// CreateCheckoutSession creates a payment page for the amount in CZK
+func (c *Client) CreateCheckoutSession(ctx context.Context, p CheckoutParams) (*Session, error) {
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", "czk")
+	form.Set("line_items[0][price_data][unit_amount]", strconv.FormatInt(int64(p.Amount), 10))
+	form.Set("line_items[0][price_data][product_data][name]", p.Description)
+	form.Set("success_url", p.SuccessURL)
+	form.Set("cancel_url", p.CancelURL)
+	if p.CustomerEmail != "" {
+		form.Set("customer_email", p.CustomerEmail)
+	}
+	if p.ClientReferenceID != "" {
+		form.Set("client_reference_id", p.ClientReferenceID)
+	}
+	for key, value := range p.Metadata {
+		form.Set("metadata["+key+"]", value)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/checkout/sessions", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.secretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("stripe request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stripe response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var stripeErr struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &stripeErr) == nil && stripeErr.Error.Message != "" {
+			return nil, fmt.Errorf("stripe: %s: %s", stripeErr.Error.Type, stripeErr.Error.Message)
+		}
+		return nil, fmt.Errorf("stripe: HTTP %d", resp.StatusCode)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("invalid stripe response: %w", err)
+	}
+	return &session, nil
+}
+
+// Event is a webhook event
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// Session decodes the Checkout session of a checkout.session.* event
+func (e *Event) Session() (*Session, error) {
+	var session Session
+	if err := json.Unmarshal(e.Data.Object, &session); err != nil {
+		return nil, fmt.Errorf("invalid checkout session in event %s: %w", e.ID, err)
+	}
+	return &session, nil
+}
+
+// ErrInvalidSignature is returned for a webhook not signed with the endpoint secret
+var ErrInvalidSignature = errors.New("invalid stripe signature")
+
+// ParseWebhook verifies the Stripe-Signature header of a webhook request
+// ("t=<unix time>,v1=<hex HMAC-SHA256 of "t.payload">") and decodes the event
+func ParseWebhook(payload []byte, signatureHeader, secret string, now time.Time) (*Event, error) {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(signatureHeader, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return nil, ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > webhookTolerance || age < -webhookTolerance {
+		return nil, fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	valid := false
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid stripe event: %w", err)
+	}
+	return &event, nil
+}
@@ -0,0 +1,198 @@
+// Package templates resolves the HTML templates of the portal. A deployment can
+// shadow selected templates with files of the same relative path in
+// TEMPLATE_OVERRIDE_DIR (e.g. "profile.html", "email/welcome.html") and add
+// partials ("partials/*.html") that redefine the blocks of layout.html, so other
+// spaces can rebrand the portal without forking it.
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Template kinds by location in the templates directory
+const (
+	KindLayout  = "layout"
+	KindPage    = "page"
+	KindPartial = "partial"
+	KindEmail   = "email"
+)
+
+const (
+	layoutName = "layout.html"
+	partialDir = "partials"
+	emailDir   = "email"
+)
+
+// Override is a template provided by the deployment
+type Override struct {
+	Name    string    // relative path, e.g. "email/welcome.html"
+	Kind    string    // layout, page, partial or email
+	Shadows bool      // replaces a default template (false = new partial or page)
+	ModTime time.Time // last change of the override file
+}
+
+// Resolver finds templates in the override directory first, then among the defaults
+type Resolver struct {
+	dir         string // default templates (WEB_ROOT/templates)
+	overrideDir string // empty = no overrides
+}
+
+// New creates a resolver for webRoot/templates with an optional override directory
+func New(webRoot, overrideDir string) *Resolver {
+	return &Resolver{
+		dir:         filepath.Join(webRoot, "templates"),
+		overrideDir: overrideDir,
+	}
+}
+
+// Path returns the file of the template with the given relative name: the
+// override if the deployment has one, otherwise the default
+func (r *Resolver) Path(name string) string {
+	if r.overrideDir != "" {
+		path := filepath.Join(r.overrideDir, filepath.FromSlash(name))
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return filepath.Join(r.dir, filepath.FromSlash(name))
+}
+
+// PageFiles returns the files to parse for a page: layout, partials (default and
+// override ones, an override shadowing a default of the same name) and the page.
+// Partials come after the layout so their definitions replace its blocks.
+func (r *Resolver) PageFiles(page string) []string {
+	files := []string{r.Path(layoutName)}
+	for _, name := range r.partials() {
+		files = append(files, r.Path(partialDir+"/"+name))
+	}
+	return append(files, r.Path(page))
+}
+
+// partials lists partial names from both directories
+func (r *Resolver) partials() []string {
+	seen := make(map[string]bool)
+	for _, dir := range []string{r.dir, r.overrideDir} {
+		if dir == "" {
+			continue
+		}
+		matches, _ := filepath.Glob(filepath.Join(dir, partialDir, "*.html"))
+		for _, match := range matches {
+			seen[filepath.Base(match)] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Overrides lists the templates provided by the deployment
+func (r *Resolver) Overrides() ([]Override, error) {
+	overrides := []Override{}
+	if r.overrideDir == "" {
+		return overrides, nil
+	}
+
+	err := filepath.WalkDir(r.overrideDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".html") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(r.overrideDir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		_, statErr := os.Stat(filepath.Join(r.dir, rel))
+
+		overrides = append(overrides, Override{
+			Name:    name,
+			Kind:    kindOf(name),
+			Shadows: statErr == nil,
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("template overrides in %s: %w", r.overrideDir, err)
+	}
+	return overrides, nil
+}
+
+// kindOf returns the kind of a template by its relative name, "" for unknown locations
+func kindOf(name string) string {
+	switch {
+	case name == layoutName:
+		return KindLayout
+	case !strings.Contains(name, "/"):
+		return KindPage
+	case strings.HasPrefix(name, partialDir+"/") && strings.Count(name, "/") == 1:
+		return KindPartial
+	case strings.HasPrefix(name, emailDir+"/") && strings.Count(name, "/") == 1:
+		return KindEmail
+	}
+	return ""
+}
+
+// Validate parses every template affected by the overrides, so a broken override
+// stops the server at startup instead of breaking pages for members. A changed
+// layout or partial is checked with all pages.
+func (r *Resolver) Validate() error {
+	overrides, err := r.Overrides()
+	if err != nil || len(overrides) == 0 {
+		return err
+	}
+
+	pages := make(map[string]bool)
+	var errs []error
+	for _, o := range overrides {
+		switch o.Kind {
+		case KindPage:
+			pages[o.Name] = true
+		case KindLayout, KindPartial:
+			defaults, _ := filepath.Glob(filepath.Join(r.dir, "*.html"))
+			for _, path := range defaults {
+				if name := filepath.Base(path); name != layoutName {
+					pages[name] = true
+				}
+			}
+		case KindEmail:
+			if _, err := template.ParseFiles(r.Path(o.Name)); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			errs = append(errs, fmt.Errorf("template override %s: unknown location (expected page, layout.html, partials/ or email/)", o.Name))
+		}
+	}
+
+	names := make([]string, 0, len(pages))
+	for name := range pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := template.ParseFiles(r.PageFiles(name)...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
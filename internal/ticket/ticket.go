@@ -0,0 +1,90 @@
+package ticket
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Ticket states (tickets.state)
+const (
+	StateOpen     = "open"     // waiting for an admin reply
+	StateAnswered = "answered" // replied, waiting for the member
+	StateClosed   = "closed"
+)
+
+// Ticket sources (tickets.source)
+const (
+	SourceEmail  = "email"
+	SourcePortal = "portal"
+)
+
+// Message directions (ticket_messages.direction)
+const (
+	DirectionIn  = "in"  // from the member
+	DirectionOut = "out" // admin reply
+)
+
+// Size limits, longer inbound emails are truncated rather than rejected
+const (
+	MaxSubjectLength = 200
+	MaxBodyLength    = 64 << 10
+)
+
+// tagPattern matches the "[#123]" tag that threads replies back to a ticket
+var tagPattern = regexp.MustCompile(`\[#(\d+)\]`)
+
+// ReplySubject returns the subject of an email about a ticket ("Re: [#12] Klíče od dílny")
+func ReplySubject(id int64, subject string) string {
+	return fmt.Sprintf("Re: [#%d] %s", id, subject)
+}
+
+// ParseSubjectTag returns the ticket ID from the "[#123]" tag in an email subject
+func ParseSubjectTag(subject string) (int64, bool) {
+	m := tagPattern.FindStringSubmatch(subject)
+	if m == nil {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// CleanSubject strips reply prefixes and the ticket tag, collapses whitespace
+// (no header injection through line breaks) and limits the length
+func CleanSubject(subject string) string {
+	subject = tagPattern.ReplaceAllString(subject, "")
+	subject = strings.Join(strings.Fields(subject), " ")
+	for {
+		lower := strings.ToLower(subject)
+		trimmed := false
+		for _, prefix := range []string{"re:", "fwd:", "fw:", "odp:"} {
+			if strings.HasPrefix(lower, prefix) {
+				subject = strings.TrimSpace(subject[len(prefix):])
+				trimmed = true
+				break
+			}
+		}
+		if !trimmed {
+			break
+		}
+	}
+	return truncate(subject, MaxSubjectLength)
+}
+
+// CleanBody normalizes line endings and limits the length of a message body
+func CleanBody(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	return truncate(strings.TrimSpace(body), MaxBodyLength)
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) > max {
+		return string(runes[:max])
+	}
+	return s
+}
@@ -0,0 +1,8 @@
+-- Migration 008: Link bank reversals (returned payments, chargebacks) to the original credit
+-- A reversal is an outgoing payment that cancels an earlier incoming one
+
+ALTER TABLE payments ADD COLUMN reversal_of INTEGER NULL REFERENCES payments(id);
+ALTER TABLE payments ADD COLUMN reversal_review BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Index for looking up whether a payment has already been reversed
+CREATE INDEX IF NOT EXISTS idx_payments_reversal_of ON payments(reversal_of);
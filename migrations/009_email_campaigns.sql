@@ -0,0 +1,31 @@
+-- Migration 009: Mass email campaigns with per-recipient delivery tracking
+-- Interrupted campaigns resume from the recipient list and never re-send delivered emails
+
+CREATE TABLE IF NOT EXISTS email_campaigns (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    template_name TEXT NOT NULL,       -- file in web/templates/email/
+    body TEXT,                         -- optional text passed to the template as .Body
+    audience TEXT NOT NULL,            -- user state the recipients were selected by
+    status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'running', 'completed')),
+    created_by INTEGER REFERENCES users(id),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS email_campaign_recipients (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    campaign_id INTEGER NOT NULL REFERENCES email_campaigns(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    email TEXT NOT NULL,
+    -- 'sending' is set right before SMTP delivery; a recipient left in this state
+    -- was interrupted mid-send and is not retried automatically (may have been delivered)
+    status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'sending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    sent_at DATETIME,
+    UNIQUE(campaign_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_campaign_recipients_status ON email_campaign_recipients(campaign_id, status);
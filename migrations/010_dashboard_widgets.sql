@@ -0,0 +1,10 @@
+-- Migration 010: Per-user dashboard widget settings
+-- Widgets without a row are visible (default), so only changed settings are stored
+
+CREATE TABLE IF NOT EXISTS user_dashboard_widgets (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    widget TEXT NOT NULL,              -- widget ID (payments_year, balance_trend, occupancy)
+    visible BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, widget)
+);
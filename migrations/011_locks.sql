@@ -0,0 +1,9 @@
+-- Migration 011: Cross-process advisory locks
+-- Serializes writes that change member balances (payments, fees) between the server and cron jobs
+
+CREATE TABLE IF NOT EXISTS locks (
+    name TEXT PRIMARY KEY,
+    holder TEXT NOT NULL,              -- job@host:pid of the current owner
+    acquired_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL       -- stale locks (crashed holder) can be taken over after this
+);
@@ -0,0 +1,44 @@
+-- Migration 012: Proforma invoices for company-paid memberships
+-- Members request an invoice for N months of fees, an admin approves it (assigns the
+-- number, which is also the VS) and the payment is matched by that VS in FIO sync
+
+-- Company billing details entered by the member
+CREATE TABLE IF NOT EXISTS billing_details (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    company_name TEXT NOT NULL,
+    company_id TEXT NOT NULL DEFAULT '',   -- IČO
+    vat_id TEXT NOT NULL DEFAULT '',       -- DIČ
+    address TEXT NOT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Number series per year (invoice number = year + 4-digit sequence, e.g. 20260001)
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    year INTEGER PRIMARY KEY,
+    last_number INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    state TEXT NOT NULL DEFAULT 'requested' CHECK (state IN ('requested', 'approved', 'rejected', 'paid')),
+    number TEXT UNIQUE,                    -- assigned on approval, used as VS
+    months INTEGER NOT NULL,               -- number of monthly fees covered
+    amount TEXT NOT NULL,                  -- Decimal as TEXT
+    -- Billing details snapshot (later changes do not alter issued invoices)
+    company_name TEXT NOT NULL,
+    company_id TEXT NOT NULL DEFAULT '',
+    vat_id TEXT NOT NULL DEFAULT '',
+    address TEXT NOT NULL,
+    note TEXT,                             -- member's note, e.g. purchase order number
+    admin_comment TEXT,                    -- rejection reason
+    decided_by TEXT,                       -- admin who approved/rejected
+    issued_at DATETIME,
+    due_at DATETIME,
+    payment_id INTEGER REFERENCES payments(id),
+    paid_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoices_user ON invoices(user_id);
+CREATE INDEX IF NOT EXISTS idx_invoices_state ON invoices(state);
@@ -0,0 +1,15 @@
+-- Migration 013: Keycloak SSO sessions of logged in users
+-- Keeps the latest ID token for RP-initiated logout (id_token_hint) and lets
+-- Keycloak back-channel logout invalidate sessions stored in cookies
+
+CREATE TABLE IF NOT EXISTS auth_sessions (
+    sid TEXT PRIMARY KEY,              -- Keycloak session ID (sid claim)
+    keycloak_id TEXT NOT NULL,
+    id_token TEXT NOT NULL,            -- latest ID token, sent as id_token_hint on logout
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at DATETIME                -- set on logout; the cookie session is no longer accepted
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_keycloak_id ON auth_sessions(keycloak_id);
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_updated_at ON auth_sessions(updated_at);
@@ -0,0 +1,44 @@
+-- Migration 014: Expense reimbursements
+-- Members submit expenses with receipts, the council (admins) approves them, approved
+-- items are exported as a FIO payment order batch and the bank debit is matched back
+-- by VS in FIO sync
+
+-- Exported payment order files (kept so a batch can be downloaded again)
+CREATE TABLE IF NOT EXISTS reimbursement_batches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_by TEXT NOT NULL,              -- admin who exported the batch
+    total TEXT NOT NULL,                   -- Decimal as TEXT
+    xml TEXT NOT NULL,                     -- FIO payment order import file
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reimbursements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    state TEXT NOT NULL DEFAULT 'submitted' CHECK (state IN ('submitted', 'approved', 'rejected', 'exported', 'paid')),
+    amount TEXT NOT NULL,                  -- Decimal as TEXT
+    description TEXT NOT NULL,
+    account TEXT NOT NULL,                 -- payee account, "prefix-number/bank"
+    admin_comment TEXT,                    -- rejection reason
+    decided_by TEXT,                       -- admin who approved/rejected
+    decided_at DATETIME,
+    batch_id INTEGER REFERENCES reimbursement_batches(id),
+    payment_id INTEGER REFERENCES payments(id), -- outgoing bank payment
+    paid_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reimbursements_user ON reimbursements(user_id);
+CREATE INDEX IF NOT EXISTS idx_reimbursements_state ON reimbursements(state);
+
+-- Receipts are stored in the database (small scans/PDFs, one file to back up)
+CREATE TABLE IF NOT EXISTS reimbursement_receipts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    data BLOB NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reimbursement_receipts_reimbursement ON reimbursement_receipts(reimbursement_id);
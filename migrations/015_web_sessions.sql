@@ -0,0 +1,12 @@
+-- Migration 015: Server-side HTTP sessions (SESSION_STORE=sqlite)
+-- The cookie only carries a random session ID; user, roles and tokens stay here,
+-- so sessions can be dropped server-side and are not limited by the cookie size
+
+CREATE TABLE IF NOT EXISTS web_sessions (
+    id TEXT PRIMARY KEY,               -- random ID from the session cookie
+    data BLOB NOT NULL,                -- gob encoded session values
+    expires_at DATETIME NOT NULL,      -- UTC
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_web_sessions_expires_at ON web_sessions(expires_at);
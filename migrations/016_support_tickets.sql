@@ -0,0 +1,29 @@
+-- Migration 016: Member support tickets
+-- Requests arrive by email (inbound webhook) or from the portal form, admins reply
+-- from the portal; replies carry "[#id]" in the subject so answers thread back
+
+CREATE TABLE IF NOT EXISTS tickets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id),  -- matched by sender email, NULL for unknown senders
+    email TEXT NOT NULL,                   -- requester address, replies are sent here
+    subject TEXT NOT NULL,
+    state TEXT NOT NULL DEFAULT 'open' CHECK (state IN ('open', 'answered', 'closed')),
+    source TEXT NOT NULL CHECK (source IN ('email', 'portal')),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tickets_user ON tickets(user_id);
+CREATE INDEX IF NOT EXISTS idx_tickets_state ON tickets(state);
+
+CREATE TABLE IF NOT EXISTS ticket_messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ticket_id INTEGER NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    direction TEXT NOT NULL CHECK (direction IN ('in', 'out')), -- in = from the member, out = admin reply
+    author TEXT NOT NULL,                  -- sender address or admin username
+    body TEXT NOT NULL,
+    message_id TEXT UNIQUE,                -- Message-ID of inbound emails, retried webhooks are ignored
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticket_messages_ticket ON ticket_messages(ticket_id);
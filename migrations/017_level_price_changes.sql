@@ -0,0 +1,25 @@
+-- Migration 017: Planned membership fee changes
+-- New level amounts approved by the assembly are entered ahead with an effective month;
+-- notify_fee_changes emails affected members in advance and create_monthly_fees switches
+-- the amounts when it creates fees for the effective month
+
+CREATE TABLE IF NOT EXISTS level_price_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    level_id INTEGER NOT NULL REFERENCES levels(id),
+    new_amount TEXT NOT NULL,           -- Decimal as TEXT, like levels.amount
+    effective_from DATE NOT NULL,       -- first day of the first month billed with the new amount
+    note TEXT,                          -- e.g. assembly resolution, shown in the notification
+    created_by TEXT NOT NULL,
+    notified_at DATETIME,               -- all affected members notified
+    applied_at DATETIME,                -- amounts switched by create_monthly_fees
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(level_id, effective_from)
+);
+
+-- Members already notified about a change (notify_fee_changes can be re-run safely)
+CREATE TABLE IF NOT EXISTS level_price_change_notices (
+    change_id INTEGER NOT NULL REFERENCES level_price_changes(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    sent_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (change_id, user_id)
+);
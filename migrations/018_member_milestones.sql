@@ -0,0 +1,22 @@
+-- Migration 018: Member milestones and notification preferences
+-- celebrate_milestones congratulates members on membership anniversaries and their 100th
+-- payment; members can opt out of the email and of the mention in the community room
+
+-- Notifications without a row are enabled (default), so only changed settings are stored
+CREATE TABLE IF NOT EXISTS user_notification_preferences (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    notification TEXT NOT NULL,         -- notification ID (milestone_email, milestone_announce)
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, notification)
+);
+
+-- Recognized milestones (celebrate_milestones can be re-run safely)
+CREATE TABLE IF NOT EXISTS member_milestones (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    milestone TEXT NOT NULL,            -- anniversary_<years> or payments_<count>
+    emailed BOOLEAN NOT NULL DEFAULT FALSE,
+    announced BOOLEAN NOT NULL DEFAULT FALSE, -- posted to the Matrix room
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, milestone)
+);
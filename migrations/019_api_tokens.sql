@@ -0,0 +1,18 @@
+-- Migration 019: Personal API tokens
+-- Scripts (door controller, personal widgets) call the JSON API with
+-- "Authorization: Bearer <token>" instead of a browser session
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,                 -- label given by the member
+    token_hash TEXT NOT NULL UNIQUE,    -- SHA-256 (hex) of the token, the token itself is shown only once
+    token_prefix TEXT NOT NULL,         -- start of the token to recognize it in the list
+    scopes TEXT NOT NULL,               -- space separated: me:read me:write admin:read admin:write
+    expires_at DATETIME,                -- NULL = no expiry
+    last_used_at DATETIME,
+    revoked_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id);
@@ -0,0 +1,17 @@
+-- Migration 020: Public project pages with a contributor wall
+-- Members opt in to be thanked on the public page of a fundraising project
+-- (nickname + amount bracket); names are shown only after admin approval
+
+ALTER TABLE projects ADD COLUMN public BOOLEAN NOT NULL DEFAULT FALSE; -- /projects/{id} page and wall API
+
+CREATE TABLE IF NOT EXISTS project_wall_entries (
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    nickname TEXT NOT NULL,             -- displayed name, chosen by the member (admin may edit)
+    state TEXT NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'approved', 'hidden')),
+    moderated_by TEXT,                  -- admin username
+    moderated_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, user_id)
+);
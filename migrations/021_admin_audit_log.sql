@@ -0,0 +1,20 @@
+-- Migration 021: Admin audit log
+-- Every mutating call (POST/PUT/PATCH/DELETE) under /api/admin with actor,
+-- target, summary of the request and the result
+
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    actor_email TEXT NOT NULL,          -- kept when the user is deleted
+    api_token_id INTEGER,               -- personal API token used instead of a session
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    target TEXT NOT NULL DEFAULT '',    -- IDs from the URL and the request, e.g. "id=5 user_id=42"
+    payload TEXT NOT NULL DEFAULT '',   -- request body summary, secrets redacted, long values cut
+    status INTEGER NOT NULL,            -- HTTP status of the response
+    error TEXT NOT NULL DEFAULT '',     -- error message of a failed call
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_created ON admin_audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_actor ON admin_audit_log(actor_user_id);
@@ -0,0 +1,12 @@
+-- Migration 022: Remembered bank accounts for payment matching
+-- An admin assigning an unmatched payment can remember the sender's account;
+-- FIO sync then assigns payments from that account (without a member or project
+-- VS) to the member automatically
+
+CREATE TABLE IF NOT EXISTS payment_account_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    remote_account TEXT NOT NULL UNIQUE, -- same format as payments.remote_account ("account/bank code")
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by TEXT NOT NULL,            -- admin username
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
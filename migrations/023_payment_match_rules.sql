@@ -0,0 +1,33 @@
+-- Migration 023: Payment matching rules
+-- Rules assign incoming FIO payments with a wrong or missing VS to a member or a
+-- project by sender account, message, specific symbol and amount range.
+-- Replaces payment_account_rules (a remembered account becomes a rule with only
+-- the account condition).
+
+CREATE TABLE IF NOT EXISTS payment_match_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL DEFAULT '',            -- note for admins
+    priority INTEGER NOT NULL DEFAULT 100,    -- lower is evaluated first
+    remote_account TEXT NOT NULL DEFAULT '',  -- "account/bank code", '' = any
+    message_pattern TEXT NOT NULL DEFAULT '', -- regexp (Go syntax) on the message for the recipient, '' = any
+    specific_symbol TEXT NOT NULL DEFAULT '', -- '' = any
+    amount_min REAL,                          -- NULL = no lower bound
+    amount_max REAL,                          -- NULL = no upper bound
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    match_count INTEGER NOT NULL DEFAULT 0,   -- payments assigned by the rule
+    last_matched_at DATETIME,
+    created_by TEXT NOT NULL,                 -- admin username
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((user_id IS NULL) <> (project_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_match_rules_priority ON payment_match_rules(active, priority);
+
+INSERT INTO payment_match_rules (name, remote_account, user_id, created_by, created_at, updated_at)
+SELECT 'Zapamatovaný účet', remote_account, user_id, created_by, created_at, created_at
+FROM payment_account_rules;
+
+DROP TABLE IF EXISTS payment_account_rules;
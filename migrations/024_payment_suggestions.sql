@@ -0,0 +1,15 @@
+-- Migration 024: Suggested matches by sender account history
+-- A payment without VS from an account that only one member paid from before is
+-- suggested for that member (or assigned right away with FIO_AUTO_LINK_BY_ACCOUNT)
+
+CREATE TABLE IF NOT EXISTS payment_suggestions (
+    payment_id INTEGER PRIMARY KEY REFERENCES payments(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    payment_count INTEGER NOT NULL,     -- earlier payments of the member from the same account
+    state TEXT NOT NULL DEFAULT 'suggested' CHECK (state IN ('suggested', 'rejected')),
+    decided_by TEXT,                    -- admin username (rejected)
+    decided_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_remote_account ON payments(remote_account);
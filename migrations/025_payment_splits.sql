@@ -0,0 +1,20 @@
+-- Migration 025: Payment splits
+-- One bank payment covering several memberships (households) or a membership and
+-- a project donation is divided into allocations. A split payment counts in the
+-- balances only through its allocations.
+
+CREATE TABLE IF NOT EXISTS payment_splits (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    payment_id INTEGER NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+    amount TEXT NOT NULL,             -- same format as payments.amount
+    note TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL,         -- admin username
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((user_id IS NULL) <> (project_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_splits_payment ON payment_splits(payment_id);
+CREATE INDEX IF NOT EXISTS idx_payment_splits_user ON payment_splits(user_id);
+CREATE INDEX IF NOT EXISTS idx_payment_splits_project ON payment_splits(project_id);
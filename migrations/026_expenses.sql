@@ -0,0 +1,25 @@
+-- Migration 026: Expenses
+-- Outgoing FIO transactions (rent, energy, purchases, reimbursement payouts) are
+-- imported by sync_fio_payments instead of being skipped. Refunds of incoming
+-- payments stay in payments as reversals.
+
+CREATE TABLE IF NOT EXISTS expenses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,                   -- source, 'fio'
+    kind_id TEXT NOT NULL,                -- transaction ID in the source
+    date DATETIME NOT NULL,
+    amount TEXT NOT NULL,                 -- amount paid out (positive)
+    remote_account TEXT NOT NULL DEFAULT '',
+    remote_name TEXT NOT NULL DEFAULT '', -- counter account name
+    identification TEXT NOT NULL DEFAULT '', -- VS
+    message TEXT NOT NULL DEFAULT '',     -- message for the recipient / card payment details
+    category TEXT NOT NULL DEFAULT '',    -- tag set by admins ('' = untagged)
+    project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL,
+    staff_comment TEXT,
+    raw_data TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(kind, kind_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_expenses_date ON expenses(date);
+CREATE INDEX IF NOT EXISTS idx_expenses_project ON expenses(project_id);
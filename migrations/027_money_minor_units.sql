@@ -0,0 +1,55 @@
+-- Migration 027: Amounts in haléře
+-- Amounts were decimal TEXT ("450.00") parsed in Go and CAST to REAL in sums. They
+-- become INTEGER haléře (1/100 CZK): 450.00 Kč = 45000. SQLite cannot change a
+-- column type, so each column is renamed, re-added as INTEGER, filled and dropped
+-- (no amount column is indexed or part of a constraint).
+
+ALTER TABLE levels RENAME COLUMN amount TO amount_text;
+ALTER TABLE levels ADD COLUMN amount INTEGER NOT NULL DEFAULT 0;
+UPDATE levels SET amount = CAST(ROUND(CAST(amount_text AS REAL) * 100) AS INTEGER);
+ALTER TABLE levels DROP COLUMN amount_text;
+
+ALTER TABLE users RENAME COLUMN level_actual_amount TO level_actual_amount_text;
+ALTER TABLE users ADD COLUMN level_actual_amount INTEGER NOT NULL DEFAULT 0; -- 0 = level amount
+UPDATE users SET level_actual_amount = CAST(ROUND(CAST(level_actual_amount_text AS REAL) * 100) AS INTEGER);
+ALTER TABLE users DROP COLUMN level_actual_amount_text;
+
+ALTER TABLE payments RENAME COLUMN amount TO amount_text;
+ALTER TABLE payments ADD COLUMN amount INTEGER NOT NULL DEFAULT 0;
+UPDATE payments SET amount = CAST(ROUND(CAST(amount_text AS REAL) * 100) AS INTEGER);
+ALTER TABLE payments DROP COLUMN amount_text;
+
+ALTER TABLE fees RENAME COLUMN amount TO amount_text;
+ALTER TABLE fees ADD COLUMN amount INTEGER NOT NULL DEFAULT 0;
+UPDATE fees SET amount = CAST(ROUND(CAST(amount_text AS REAL) * 100) AS INTEGER);
+ALTER TABLE fees DROP COLUMN amount_text;
+
+ALTER TABLE invoices RENAME COLUMN amount TO amount_text;
+ALTER TABLE invoices ADD COLUMN amount INTEGER NOT NULL DEFAULT 0;
+UPDATE invoices SET amount = CAST(ROUND(CAST(amount_text AS REAL) * 100) AS INTEGER);
+ALTER TABLE invoices DROP COLUMN amount_text;
+
+ALTER TABLE reimbursement_batches RENAME COLUMN total TO total_text;
+ALTER TABLE reimbursement_batches ADD COLUMN total INTEGER NOT NULL DEFAULT 0;
+UPDATE reimbursement_batches SET total = CAST(ROUND(CAST(total_text AS REAL) * 100) AS INTEGER);
+ALTER TABLE reimbursement_batches DROP COLUMN total_text;
+
+ALTER TABLE reimbursements RENAME COLUMN amount TO amount_text;
+ALTER TABLE reimbursements ADD COLUMN amount INTEGER NOT NULL DEFAULT 0;
+UPDATE reimbursements SET amount = CAST(ROUND(CAST(amount_text AS REAL) * 100) AS INTEGER);
+ALTER TABLE reimbursements DROP COLUMN amount_text;
+
+ALTER TABLE level_price_changes RENAME COLUMN new_amount TO new_amount_text;
+ALTER TABLE level_price_changes ADD COLUMN new_amount INTEGER NOT NULL DEFAULT 0;
+UPDATE level_price_changes SET new_amount = CAST(ROUND(CAST(new_amount_text AS REAL) * 100) AS INTEGER);
+ALTER TABLE level_price_changes DROP COLUMN new_amount_text;
+
+ALTER TABLE payment_splits RENAME COLUMN amount TO amount_text;
+ALTER TABLE payment_splits ADD COLUMN amount INTEGER NOT NULL DEFAULT 0;
+UPDATE payment_splits SET amount = CAST(ROUND(CAST(amount_text AS REAL) * 100) AS INTEGER);
+ALTER TABLE payment_splits DROP COLUMN amount_text;
+
+ALTER TABLE expenses RENAME COLUMN amount TO amount_text;
+ALTER TABLE expenses ADD COLUMN amount INTEGER NOT NULL DEFAULT 0;
+UPDATE expenses SET amount = CAST(ROUND(CAST(amount_text AS REAL) * 100) AS INTEGER);
+ALTER TABLE expenses DROP COLUMN amount_text;
@@ -0,0 +1,21 @@
+-- Migration 028: Duplicate payments across import sources
+-- The same bank transaction can arrive twice under different kinds (API sync of one
+-- bank, statement import of another, ingest API). payments.content_hash identifies
+-- the content (date, amount, sender account, VS); a new payment with the hash of a
+-- payment from another source is stored unassigned and waits for an admin to merge
+-- it into the original or mark it as a different payment.
+
+ALTER TABLE payments ADD COLUMN content_hash TEXT NOT NULL DEFAULT ''; -- filled by the next import
+
+CREATE INDEX IF NOT EXISTS idx_payments_content_hash ON payments(content_hash);
+
+CREATE TABLE IF NOT EXISTS payment_duplicates (
+    payment_id INTEGER PRIMARY KEY REFERENCES payments(id) ON DELETE CASCADE, -- the later import
+    duplicate_of INTEGER NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    state TEXT NOT NULL DEFAULT 'suspected' CHECK (state IN ('suspected', 'merged', 'ignored')),
+    decided_by TEXT,                    -- admin username
+    decided_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_duplicates_original ON payment_duplicates(duplicate_of);
@@ -0,0 +1,6 @@
+-- Migration 029: Crypto payments via BTCPay Server
+-- Admins choose which membership levels and projects accept BTC/Lightning; settled
+-- invoices are stored as payments of kind 'btcpay' with the invoice ID as kind_id.
+
+ALTER TABLE levels ADD COLUMN btcpay BOOLEAN NOT NULL DEFAULT FALSE;   -- members of the level may pay fees in crypto
+ALTER TABLE projects ADD COLUMN btcpay BOOLEAN NOT NULL DEFAULT FALSE; -- project accepts crypto donations
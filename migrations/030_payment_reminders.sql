@@ -0,0 +1,11 @@
+-- Migration 030: Reminders of missed standing-order payments
+-- remind_missed_payments recognizes members paying by a standing order and emails
+-- them when the expected monthly payment is late; one reminder per expected payment.
+
+CREATE TABLE IF NOT EXISTS payment_reminders (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    due_date TEXT NOT NULL,             -- expected payment date (YYYY-MM-DD)
+    amount INTEGER NOT NULL,            -- expected amount in haléře
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, due_date)
+);
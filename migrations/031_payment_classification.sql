@@ -0,0 +1,14 @@
+-- Migration 031: Donations distinct from membership fees
+-- A payment is a membership fee or a donation. Without an explicit classification it
+-- follows its assignment (project → donation, member → fee); admins reclassify a
+-- member's payment as a donation, or split an overpayment into fee and donation
+-- parts. Donations do not count in the member's balance.
+
+ALTER TABLE payments ADD COLUMN classification TEXT NOT NULL DEFAULT ''
+    CHECK (classification IN ('', 'fee', 'donation')); -- '' = by assignment
+
+ALTER TABLE payment_splits ADD COLUMN classification TEXT NOT NULL DEFAULT 'fee'
+    CHECK (classification IN ('fee', 'donation'));
+
+-- Project allocations have always been donations
+UPDATE payment_splits SET classification = 'donation' WHERE project_id IS NOT NULL;
@@ -0,0 +1,10 @@
+-- Migration 032: Per-member QR payment format
+-- Members banking outside Czechia pick the QR code their banking app reads:
+-- Czech QR Platba (SPAYD), SEPA (EPC "GiroCode") or Slovak PAY by square.
+-- Members without a row get SPAYD.
+
+CREATE TABLE IF NOT EXISTS user_payment_settings (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    qr_format TEXT NOT NULL DEFAULT 'spayd' CHECK (qr_format IN ('spayd', 'epc', 'paybysquare')),
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
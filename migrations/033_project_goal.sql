@@ -0,0 +1,5 @@
+-- Migration 033: Funding goal of a project
+-- The public project page shows the progress towards the goal next to the QR code
+-- for donations. Haléře like other amounts (migration 027), 0 = no goal.
+
+ALTER TABLE projects ADD COLUMN goal INTEGER NOT NULL DEFAULT 0;
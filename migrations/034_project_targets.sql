@@ -0,0 +1,7 @@
+-- Migration 034: Deadline of a project funding target
+-- The target amount is projects.goal (migration 033). The bank sync notifies admins
+-- once when a project reaches it; target_reached_at records that, and is cleared
+-- when the goal changes.
+
+ALTER TABLE projects ADD COLUMN deadline DATE;               -- NULL = open-ended collection
+ALTER TABLE projects ADD COLUMN target_reached_at DATETIME;  -- when the goal was reached
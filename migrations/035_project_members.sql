@@ -0,0 +1,14 @@
+-- Migration 035: Members involved in projects
+-- Members join projects from their profile (and say what they do there); admins
+-- see who is involved, to contact them and for the annual report. Independent of
+-- the contributor wall (migration 020), which thanks donors publicly.
+
+CREATE TABLE IF NOT EXISTS project_members (
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL DEFAULT '',      -- what the member does in the project, free text
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_members_user ON project_members(user_id);
@@ -0,0 +1,9 @@
+-- Migration 036: Quarterly and annual billing cycles
+-- Members may pay per calendar quarter or year instead of monthly. The fee is
+-- then created once per period for all its months (fees.months); a member who
+-- joins or switches mid-period gets a fee for the rest of the period.
+
+ALTER TABLE user_payment_settings ADD COLUMN billing_cycle TEXT NOT NULL DEFAULT 'monthly'
+    CHECK (billing_cycle IN ('monthly', 'quarterly', 'annual'));
+
+ALTER TABLE fees ADD COLUMN months INTEGER NOT NULL DEFAULT 1; -- months covered from period_start
@@ -0,0 +1,19 @@
+-- Migration 037: Membership pause (fee holiday)
+-- A member may pause their membership for a while (parental leave, a long trip):
+-- create_monthly_fees skips months whose first day falls within the pause and
+-- the profile explains why no fees are created. The member stays 'accepted';
+-- the pause ends when an admin clears it or, without admin action, once the
+-- end date has passed (set by create_monthly_fees).
+
+CREATE TABLE IF NOT EXISTS membership_pauses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,               -- first paused day
+    end_date DATE,                          -- last paused day, NULL = until cleared
+    reason TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL,               -- admin email
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ended_at DATETIME                       -- cleared or expired; NULL = open
+);
+
+CREATE INDEX IF NOT EXISTS idx_membership_pauses_user ON membership_pauses(user_id, ended_at);
@@ -0,0 +1,38 @@
+-- Migration 038: Member level change requests and level history
+-- Members ask for another membership level from a future month in their profile, an
+-- admin approves or rejects the request. Approved changes are recorded in the level
+-- history; create_monthly_fees switches users.level_id when it creates fees for the
+-- effective month, so each fee is billed with the level effective for its period.
+
+CREATE TABLE IF NOT EXISTS level_change_requests (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    level_id INTEGER NOT NULL REFERENCES levels(id),   -- requested level
+    effective_from DATE NOT NULL,                      -- first day of the first month with the new level
+    note TEXT,                                         -- member's note for the admin
+    state TEXT NOT NULL DEFAULT 'requested' CHECK (state IN ('requested', 'approved', 'rejected')),
+    admin_comment TEXT,                                -- rejection reason
+    decided_by TEXT,                                   -- admin who approved/rejected
+    decided_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_level_change_requests_user ON level_change_requests(user_id, state);
+
+-- Levels of a member over time (the current one is the latest applied entry)
+CREATE TABLE IF NOT EXISTS level_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    level_id INTEGER NOT NULL REFERENCES levels(id),
+    effective_from DATE NOT NULL,
+    request_id INTEGER REFERENCES level_change_requests(id),
+    created_by TEXT NOT NULL,
+    applied_at DATETIME,                               -- users.level_id switched by create_monthly_fees
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_level_history_user ON level_history(user_id, effective_from);
+
+-- The history starts with the level each member has now
+INSERT INTO level_history (user_id, level_id, effective_from, created_by, applied_at)
+SELECT id, level_id, date_joined, 'migration', CURRENT_TIMESTAMP FROM users;
@@ -0,0 +1,11 @@
+-- Migration 039: Exemptions from automatic suspension for debt
+-- suspend_debtors suspends accepted members whose debt exceeds SUSPENSION_DEBT_MONTHS
+-- monthly fees. Members the board agreed with (instalments, hardship) are exempted
+-- by an admin until the exemption is removed.
+
+CREATE TABLE IF NOT EXISTS suspension_exemptions (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL,           -- admin email
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
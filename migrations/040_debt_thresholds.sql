@@ -0,0 +1,12 @@
+-- Migration 040: Debt reminder ladder
+-- Debt thresholds in monthly fees for the reminders sent by create_monthly_fees
+-- (notice, warning) and for suspend_debtors (suspension). Defaults come from
+-- DEBT_NOTICE_MONTHS, DEBT_WARNING_MONTHS and SUSPENSION_DEBT_MONTHS; a row here
+-- (set in admin settings) overrides the default for its step.
+
+CREATE TABLE IF NOT EXISTS debt_thresholds (
+    step TEXT PRIMARY KEY CHECK (step IN ('notice', 'warning', 'suspension')),
+    months INTEGER NOT NULL CHECK (months >= 0),   -- 0 = step disabled (not for suspension)
+    updated_by TEXT NOT NULL,           -- admin email
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
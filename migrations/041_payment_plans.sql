@@ -0,0 +1,20 @@
+-- Migration 041: Payment plans for members in debt
+-- An admin agrees with a member to pay off total_debt in monthly installments
+-- (on top of the regular fee) from start_date. Reminders then ask for the
+-- installment instead of the whole debt and suspend_debtors skips members who
+-- keep to the plan. ended_at is set when the plan is completed, replaced or
+-- cancelled by an admin.
+
+CREATE TABLE IF NOT EXISTS payment_plans (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    total_debt INTEGER NOT NULL CHECK (total_debt > 0),     -- haléře
+    installment INTEGER NOT NULL CHECK (installment > 0),   -- haléře
+    start_date DATE NOT NULL,           -- first installment due, then monthly
+    note TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL,           -- admin email
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ended_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_plans_user ON payment_plans(user_id, ended_at);
@@ -0,0 +1,17 @@
+-- Migration 042: One-off charges
+-- Charges for lockers, 3D printing, workshop materials etc. added by an admin to a
+-- member. They count in the membership balance like fees, so one payment with the
+-- member's VS covers both.
+
+CREATE TABLE IF NOT EXISTS charges (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    description TEXT NOT NULL,
+    amount INTEGER NOT NULL CHECK (amount > 0),   -- haléře
+    category TEXT NOT NULL CHECK (category IN ('locker', '3d_printing', 'materials', 'other')),
+    date DATE NOT NULL,
+    created_by TEXT NOT NULL,           -- admin email
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_charges_user ON charges(user_id, date);
@@ -0,0 +1,17 @@
+-- Migration 043: Balance adjustments (credit notes)
+-- Signed corrections of a member's balance entered by an admin: forgiving a month,
+-- fixing a wrong fee or crediting the member. Fees are never changed, an adjustment
+-- of a fee references it instead; a positive amount is in the member's favour.
+
+CREATE TABLE IF NOT EXISTS balance_adjustments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    fee_id INTEGER REFERENCES fees(id),  -- NULL = credit not tied to a fee
+    amount INTEGER NOT NULL CHECK (amount != 0),   -- haléře, positive = credit
+    reason TEXT NOT NULL,
+    created_by TEXT NOT NULL,           -- admin email
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_adjustments_user ON balance_adjustments(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_balance_adjustments_fee ON balance_adjustments(fee_id) WHERE fee_id IS NOT NULL;
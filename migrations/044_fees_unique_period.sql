@@ -0,0 +1,20 @@
+-- Migration 044: One fee per member and period
+-- create_monthly_fees checked for an existing fee before inserting it, so two runs at
+-- the same time could bill a member twice. The unique index makes the database reject
+-- the second fee; the job inserts with INSERT OR IGNORE and reports the conflict.
+--
+-- Fees billed twice before this migration are merged into the first one (adjustments
+-- of a removed duplicate move to it) so the index can be created.
+
+UPDATE balance_adjustments
+SET fee_id = (
+    SELECT MIN(f2.id) FROM fees f1
+    JOIN fees f2 ON f2.user_id = f1.user_id AND f2.period_start = f1.period_start
+    WHERE f1.id = balance_adjustments.fee_id
+)
+WHERE fee_id IS NOT NULL;
+
+DELETE FROM fees
+WHERE id NOT IN (SELECT MIN(id) FROM fees GROUP BY user_id, period_start);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fees_user_period ON fees(user_id, period_start);
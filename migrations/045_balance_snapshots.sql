@@ -0,0 +1,15 @@
+-- Migration 045: Monthly balance snapshots
+-- Balance of every member at the end of each month, recomputed nightly by
+-- snapshot_balances from the full history. Lists show the latest snapshot plus the
+-- entries dated after it instead of summing the whole history per member; the rows
+-- stay as history for reports and charts.
+
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    month DATE NOT NULL,                -- first day of the month
+    balance INTEGER NOT NULL,           -- haléře, at the end of the month
+    computed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_snapshots_month ON balance_snapshots(month);
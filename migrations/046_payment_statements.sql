@@ -0,0 +1,11 @@
+-- Migration 046: Yearly payment statements
+-- Members whose statement for a year was emailed by send_payment_statements, so the
+-- January job can run daily and send each statement only once. The statement itself
+-- is generated from the balance ledger whenever it is viewed or downloaded.
+
+CREATE TABLE IF NOT EXISTS payment_statements (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    year INTEGER NOT NULL,
+    sent_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, year)
+);
@@ -0,0 +1,11 @@
+-- Migration 047: Donation receipts
+-- Donors whose yearly donation receipt ("potvrzení o daru") was emailed from the
+-- donations report, so a repeated bulk send skips them. The receipt itself is
+-- generated from the donations of the year whenever it is downloaded.
+
+CREATE TABLE IF NOT EXISTS donation_receipts (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    year INTEGER NOT NULL,
+    sent_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, year)
+);
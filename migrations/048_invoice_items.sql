@@ -0,0 +1,12 @@
+-- Migration 048: Invoices for a fee or a charge
+-- Besides the proforma invoices for N months, a member can have an invoice issued
+-- for a fee that is already counted in the balance or for a one-off charge. Such an
+-- invoice is issued right away (the amount is given), from the same number series.
+
+ALTER TABLE invoices ADD COLUMN fee_id INTEGER REFERENCES fees(id) ON DELETE SET NULL;
+ALTER TABLE invoices ADD COLUMN charge_id INTEGER REFERENCES charges(id) ON DELETE SET NULL;
+ALTER TABLE invoices ADD COLUMN item TEXT;   -- invoiced item as printed, NULL for proforma invoices
+
+-- At most one invoice per fee and charge
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_fee ON invoices(fee_id) WHERE fee_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_charge ON invoices(charge_id) WHERE charge_id IS NOT NULL;
@@ -0,0 +1,23 @@
+-- Migration 049: Fakturoid invoices for company-billed members
+-- Fees of members flagged in their admin profile are invoiced in Fakturoid by the
+-- sync_fakturoid cron, which also reads back whether the invoices were paid.
+
+-- Company billing flag on the member's billing details
+ALTER TABLE billing_details ADD COLUMN fakturoid BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE billing_details ADD COLUMN fakturoid_since DATETIME;        -- fees created since the flag was set are invoiced
+ALTER TABLE billing_details ADD COLUMN fakturoid_subject_id INTEGER;    -- contact in Fakturoid, created with the first invoice
+
+-- Fakturoid invoice of a fee
+CREATE TABLE IF NOT EXISTS fakturoid_invoices (
+    fee_id INTEGER PRIMARY KEY REFERENCES fees(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    fakturoid_id INTEGER NOT NULL UNIQUE,
+    number TEXT NOT NULL,
+    status TEXT NOT NULL,                   -- open, sent, overdue, paid, cancelled, uncollectible
+    public_url TEXT NOT NULL DEFAULT '',    -- invoice page for the customer
+    paid_on DATE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    synced_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_fakturoid_invoices_user ON fakturoid_invoices(user_id);
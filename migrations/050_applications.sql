@@ -0,0 +1,21 @@
+-- Migration 050: Membership applications
+-- Applicants fill in a public form without an account; an admin approves the
+-- application, which creates the member (with a payments VS and a Keycloak account)
+-- and sends the welcome email, or rejects it.
+
+CREATE TABLE IF NOT EXISTS applications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    realname TEXT NOT NULL,
+    email TEXT NOT NULL,
+    motivation TEXT NOT NULL,
+    level_id INTEGER NOT NULL REFERENCES levels(id),  -- requested level
+    state TEXT NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'approved', 'rejected')),
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,  -- member created by the approval
+    admin_comment TEXT,                                      -- rejection reason
+    decided_by TEXT,                                         -- admin who approved/rejected
+    decided_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One pending application per email
+CREATE UNIQUE INDEX IF NOT EXISTS idx_applications_pending_email ON applications(email) WHERE state = 'pending';
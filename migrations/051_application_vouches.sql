@@ -0,0 +1,11 @@
+-- Migration 051: Vouches for membership applications
+-- The statutes require existing members to vouch for a new applicant; an application
+-- can be approved only once it has enough vouches (APPLICATION_VOUCHES).
+
+CREATE TABLE IF NOT EXISTS application_vouches (
+    application_id INTEGER NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,  -- vouching member
+    note TEXT,                                                        -- how they know the applicant
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (application_id, user_id)
+);
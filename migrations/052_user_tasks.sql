@@ -0,0 +1,23 @@
+-- Migration 052: Onboarding checklist of new members
+-- A row marks a completed onboarding task (the list of tasks lives in the portal,
+-- a missing row means the task is still open). The member is onboarded once all
+-- tasks are done.
+
+CREATE TABLE IF NOT EXISTS user_tasks (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    task TEXT NOT NULL,                  -- task ID (agreement, safety_briefing, chip, mailing_list)
+    completed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_by TEXT,                   -- admin who ticked the task off
+    PRIMARY KEY (user_id, task)
+);
+
+-- Existing members went through onboarding before the checklist existed
+INSERT OR IGNORE INTO user_tasks (user_id, task, completed_by)
+SELECT u.id, t.task, 'migration'
+FROM users u
+CROSS JOIN (
+    SELECT 'agreement' AS task UNION ALL
+    SELECT 'safety_briefing' UNION ALL
+    SELECT 'chip' UNION ALL
+    SELECT 'mailing_list'
+) t;
@@ -0,0 +1,20 @@
+-- Migration 053: Membership termination requested by the member
+-- The member cancels their membership in the profile with an end date (last day of
+-- a month). create_monthly_fees creates no fee for the months after it, and once
+-- the end date has passed complete_terminations switches the member to 'exmember'
+-- and disables their Keycloak account. The member or an admin can withdraw the
+-- request before then.
+
+CREATE TABLE IF NOT EXISTS membership_terminations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    end_date DATE NOT NULL,                 -- last day of membership
+    reason TEXT NOT NULL DEFAULT '',
+    state TEXT NOT NULL DEFAULT 'scheduled' CHECK (state IN ('scheduled', 'completed', 'cancelled')),
+    cancelled_by TEXT,                      -- email of the member or admin who withdrew it
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    decided_at DATETIME                     -- completed or cancelled
+);
+
+-- One scheduled termination per member
+CREATE UNIQUE INDEX IF NOT EXISTS idx_membership_terminations_scheduled ON membership_terminations(user_id) WHERE state = 'scheduled';
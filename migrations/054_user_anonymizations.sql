@@ -0,0 +1,13 @@
+-- Migration 054: Anonymization of former members (GDPR right to be forgotten)
+-- An admin (or the anonymize_member tool) scrubs a departed member's personal
+-- data: name, email, phone, alternative contact and the bank data of their
+-- payments. Payments, fees, charges and invoices stay with their amounts and
+-- dates so the accounting totals do not change. This table records who did it
+-- and what was scrubbed (counts only, no personal data).
+
+CREATE TABLE IF NOT EXISTS user_anonymizations (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    anonymized_by TEXT NOT NULL,            -- admin email or 'cli'
+    summary TEXT NOT NULL DEFAULT '{}',     -- JSON with the number of scrubbed records per table
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,16 @@
+-- Migration 055: Admin notes on member records
+-- Agreements with members ("pays double in December") and other context that
+-- otherwise lives only in people's heads. Visible to admins only; pinned notes
+-- are shown first on the admin user profile.
+
+CREATE TABLE IF NOT EXISTS user_notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    author TEXT NOT NULL,                   -- admin email
+    body TEXT NOT NULL,
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_notes_user ON user_notes(user_id);